@@ -16,7 +16,10 @@ import (
 	"nat-management-app/internal/api"
 	"nat-management-app/internal/database"
 	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
 	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
+	"nat-management-app/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -24,6 +27,11 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "version") {
+		fmt.Printf("NAT Management Application %s\n", version.Get())
+		return
+	}
+
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		fmt.Printf("⚠️ Warning: .env file not found, using environment variables\n")
@@ -32,9 +40,12 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	models.ConfigureDefaultRouterScopes(cfg.RoleDefaultScopes)
+
 	// Setup logger
-	logger := setupLogger(cfg.Debug)
+	logger := setupLogger(cfg.Debug, cfg.LogFormat)
 	logger.Info("🚀 Starting NAT Management Application with PostgreSQL...")
+	logger.Infof("🏷️ Build: %s", version.Get())
 
 	// Initialize PostgreSQL database connection
 	db, err := database.NewDB(logger)
@@ -46,11 +57,46 @@ func main() {
 	logger.Info("✅ Database connection established")
 
 	// Create services with database backend
-	routerService := services.NewRouterServiceDB(logger, db)
-	natService := services.NewNATService(logger, routerService)
-	authService := services.NewAuthServiceDB(logger, db)
+	routerService := services.NewRouterServiceDB(logger, db, services.RouterPoolConfig{
+		MaxConnections:   cfg.RouterOSPoolMax,
+		IdleTimeout:      cfg.RouterOSPoolIdleTimeout,
+		MaxLifetime:      cfg.RouterOSPoolMaxLifetime,
+		FailureThreshold: cfg.CircuitFailureThreshold,
+		CircuitTimeout:   cfg.CircuitTimeout,
+		DrainTimeout:     cfg.RouterOSPoolDrainTimeout,
+	})
+	routerService.SetPoolMaxIdlePerRouter(cfg.PoolMaxIdlePerRouter)
+	routerService.SetOperationTimeouts(cfg.RouterOSOpTimeouts)
+	routerService.SetBackupDir(cfg.RouterBackupDir)
+	natService := services.NewNATService(logger, routerService, cfg.NATCacheTTL)
+	natService.SetFuzzyMaxCandidates(cfg.FuzzyMaxCandidates)
+	natService.SetFuzzyMinSimilarity(cfg.FuzzyMinSimilarity)
+	natService.SetFuzzyAreaPatterns(cfg.FuzzyAreaPatterns)
+	natService.SetConnectivityTest(cfg.ConnectivityTestPorts, cfg.ConnectivityTestTimeout)
+	natService.SetHistoryRepository(database.NewPPPoEHistoryRepository(db))
+
+	// One throttle for both pooled and NAT service commands so each router has a single budget
+	routerThrottle := services.NewRouterThrottle(cfg.RouterCommandRate, cfg.RouterCommandBurst,
+		time.Duration(cfg.RouterCommandMaxWait)*time.Millisecond)
+	routerService.SetRouterThrottle(routerThrottle)
+	natService.SetRouterThrottle(routerThrottle)
+	if routerThrottle.Enabled() {
+		logger.Infof("🚦 RouterOS commands limited to %.1f/s per router (burst %d, max wait %dms)",
+			cfg.RouterCommandRate, cfg.RouterCommandBurst, cfg.RouterCommandMaxWait)
+	}
+
+	authService := services.NewAuthServiceDB(logger, db, services.JWTKeyConfig{
+		PrivateKeyPath: cfg.JWTPrivateKeyPath,
+		PrivateKeyPEM:  cfg.JWTPrivateKeyPEM,
+		AllowEphemeral: cfg.Debug,
+	})
+	authService.SetJWTLeeway(time.Duration(cfg.JWTLeeway) * time.Second)
+	authService.SetJWTLifetimes(cfg.JWTAccessTTL, cfg.JWTRefreshTTL)
 	userService := services.NewUserService(db, logger)
+	userService.SetPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordRequireMixed)
 	activityLogService := services.NewActivityLogService(db, logger)
+	activityLogService.StartRetention(cfg.LogRetentionDays, cfg.LogRetentionInterval)
+	featureFlagService := services.NewFeatureFlagService(db, logger)
 
 	// Create ONT WiFi extractor service
 	ontExtractorService := services.NewONTExtractorService(logger)
@@ -58,7 +104,35 @@ func main() {
 	// Create ONT WiFi repository
 	ontWiFiRepo := database.NewONTWiFiRepository(db)
 
-	// Note: Health Monitor feature disabled (not needed yet)
+	ontExtractorService.SetWiFiRepository(ontWiFiRepo)
+
+	// Refresh WiFi info of every router's ONT on a schedule and via POST /api/ont/wifi/extract-all
+	ontExtractorService.ConfigureExtractAll(natService, activityLogService, cfg.WiFiExtractONTUsername, cfg.WiFiExtractONTPassword)
+	if err := ontExtractorService.StartSchedule(cfg.WiFiExtractSchedule); err != nil {
+		logger.Warnf("⚠️ Scheduled WiFi extraction not started: %v", err)
+	}
+
+	// Keep the router map in sync with changes made by other instances or directly in SQL
+	routerChangeListener := services.NewRouterChangeListener(db, natService, cfg.RouterChangeListen, time.Duration(cfg.RouterReconcileInterval)*time.Second, logger)
+	routerChangeListener.Start()
+
+	// Run connectivity audits on the schedules in audit_schedules
+	auditService := services.NewAuditService(db, natService, logger)
+	auditService.Start()
+
+	// Create customer directory (local customers table, optionally backed by mapping-ftth)
+	customerRepo := database.NewCustomerRepository(db)
+	customerDirectory := services.NewCustomerDirectory(customerRepo, cfg.CustomerDirectoryURL, logger)
+
+	// Check router health in the background for /api/monitoring
+	healthMonitor := services.NewHealthMonitor(logger, routerService)
+	healthMonitor.SetHistoryRepository(database.NewRouterHealthRepository(db), cfg.HealthHistoryRetentionDays)
+	healthMonitor.SetAlertWebhook(cfg.AlertWebhookURL)
+	healthMonitor.Start()
+
+	// Push online client changes to /ws/clients subscribers
+	clientWatcher := services.NewClientWatcher(natService, cfg.NATCacheTTL, logger)
+	clientWatcher.Start()
 
 	// Setup Gin
 	if !cfg.Debug {
@@ -66,108 +140,58 @@ func main() {
 	}
 
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(middleware.RequestLogger(logger)) // Correlation id + request-scoped logger
+	router.Use(middleware.AccessLogger())        // One log line per request, with its request_id
 	router.Use(gin.Recovery())
 
 	// Create middleware dengan security enhancements
 	authMiddleware := middleware.NewAuthMiddleware(authService, logger)
 	secureAuthMiddleware := middleware.NewSecureAuthMiddleware(authService, logger)
+	secureAuthMiddleware.SetAutoRefresh(cfg.JWTAutoRefresh)
+	if err := secureAuthMiddleware.SetSecurityHeaders(cfg.ContentSecurityPolicy, cfg.FrameOptions); err != nil {
+		logger.Fatalf("❌ Invalid security header config: %v", err)
+	}
 
 	// Setup secure CORS dan security middleware
 	router.Use(secureAuthMiddleware.SecureCORSWithAuth())
 	router.Use(secureAuthMiddleware.SecurityLogger())
 	// Global rate limiting not needed - specific rate limits applied per route group
 
-	// Load HTML templates with error handling
-	templateRoot := "web/templates"
-	if _, err := os.Stat(templateRoot); os.IsNotExist(err) {
-		logger.Warn("📁 web/templates directory not found, trying alternative paths...")
-		if _, err := os.Stat("./templates"); err == nil {
-			templateRoot = "./templates"
-			logger.Info("📁 Using ./templates for templates (recursive)")
-		} else {
-			logger.Error("❌ No templates directory found! Application may not work correctly")
-		}
-	} else {
-		logger.Info("📁 Using web/templates for templates (recursive)")
-	}
-
-	if err := loadTemplates(router, templateRoot); err != nil {
-		logger.Fatalf("❌ Failed to load templates from %s: %v", templateRoot, err)
-	}
-
-	// Serve static files
-	staticPath := "web/static"
-	if _, err := os.Stat("web/static"); os.IsNotExist(err) {
-		logger.Warn("📁 web/static directory not found, trying alternative paths...")
-		if _, err := os.Stat("./static"); err == nil {
-			staticPath = "./static"
-			logger.Info("📁 Using ./static for static files")
-		} else {
-			logger.Error("❌ No static directory found! CSS/JS may not load correctly")
-		}
-	} else {
-		logger.Info("📁 Using web/static for static files")
+	// Web UI (templates, static assets and HTML pages); API-only deployments skip it entirely
+	if err := setupWebUI(router, cfg.EnableWebUI, authMiddleware.RequireAuth(), logger); err != nil {
+		logger.Fatalf("❌ %v", err)
 	}
-	router.Static("/static", staticPath)
 
-	// Serve SS assets (logos/screenshots), so /SS/logo.jpeg works on login page
-	assetsPath := "SS"
-	if _, err := os.Stat(assetsPath); os.IsNotExist(err) {
-		logger.Warn("📁 SS directory not found; logo may not load from /SS/logo.jpeg")
-	} else {
-		router.Static("/SS", assetsPath)
-		logger.Info("📁 Serving SS at /SS")
-	}
-	// Serve image assets (branding), so /image/logo.png works on login page
-	imagesPath := "image"
-	if _, err := os.Stat(imagesPath); os.IsNotExist(err) {
-		logger.Warn("📁 image directory not found; logo may not load from /image/logo.png")
-	} else {
-		router.Static("/image", imagesPath)
-		logger.Info("📁 Serving image at /image")
-	}
-	
 	// Create API handlers
-	natHandler := api.NewNATHandler(natService, userService, activityLogService, logger)
+	natHandler := api.NewNATHandler(natService, userService, activityLogService, customerDirectory, logger)
 	authHandler := api.NewAuthHandler(authService, routerService, userService, activityLogService, logger)
-	routerHandler := api.NewRouterHandler(routerService, natService, activityLogService, logger)
-	userHandler := api.NewUserHandler(userService, activityLogService, logger)
+	routerHandler := api.NewRouterHandler(routerService, natService, userService, activityLogService, logger)
+	userHandler := api.NewUserHandler(userService, natService, authService, activityLogService, logger)
 	activityLogHandler := api.NewActivityLogHandler(activityLogService, logger)
-	ontWiFiHandler := api.NewONTWiFiHandler(ontExtractorService, ontWiFiRepo, natService, activityLogService, logger)
-	// monitoringHandler removed - feature disabled
-
-	// Public routes (no authentication required)
-	router.GET("/login", loginHandler)
+	ontWiFiHandler := api.NewONTWiFiHandler(ontExtractorService, ontWiFiRepo, natService, userService, activityLogService, logger)
+	featureFlagHandler := api.NewFeatureFlagHandler(featureFlagService, activityLogService, logger)
+	accessControlHandler := api.NewAccessControlHandler(routerService, activityLogService, logger)
+	auditHandler := api.NewAuditHandler(auditService, activityLogService, logger)
+	healthHandler := api.NewHealthHandler(db, authService, routerService, cfg.HealthCanaryRouter, logger)
+	monitoringHandler := api.NewMonitoringHandler(healthMonitor, natService, userService, logger)
+	clientStreamHandler := api.NewClientStreamHandler(clientWatcher, natService, userService, logger)
 
 	// Health check endpoints (for monitoring and load balancers)
 	router.GET("/health", func(c *gin.Context) {
+		build := version.Get()
 		c.JSON(http.StatusOK, gin.H{
 			"status": "healthy",
 			"service": "NAT Management Application",
-			"version": "v4.2",
+			"version": build.Version,
+			"commit": build.Commit,
+			"build_time": build.BuildTime,
 		})
 	})
 
-	router.GET("/ready", func(c *gin.Context) {
-		// Check database connection
-		ctx := c.Request.Context()
-		if err := db.Pool.Ping(ctx); err != nil {
-			logger.Warnf("❌ Database ping failed in readiness check: %v", err)
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status": "not ready",
-				"reason": "database connection failed",
-				"error": err.Error(),
-			})
-			return
-		}
+	// Build metadata (public, like /health) for incident response
+	router.GET("/api/version", versionHandler)
 
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ready",
-			"database": "connected",
-			"service": "NAT Management Application",
-		})
-	})
+	router.GET("/ready", readyHandler(db.Pool.Ping, natService.RouterReadiness, logger))
 
 	// Authentication API routes with environment-aware rate limiting
 	authGroup := router.Group("/api/auth")
@@ -180,26 +204,59 @@ func main() {
 		authGroup.GET("/jwt-public-key", authHandler.GetJWTPublicKey)
 	}
 
-	// Protected routes (authentication required) - Support both session dan JWT
-	protected := router.Group("/")
-	protected.Use(authMiddleware.RequireAuth()) // Keep session-based untuk backward compatibility
+	// Protected API routes (JWT authentication required)
+	// WebSocket streams (JWT via header, cookie or ?access_token=)
+	wsGroup := router.Group("/ws")
+	wsGroup.Use(secureAuthMiddleware.RequireJWTAuth())
 	{
-		protected.GET("/", natManagementHandler) // Main NAT Management page
-		protected.GET("/nat", natManagementHandler) // Alternative route
-		protected.GET("/pppoe", pppoeCheckerHandler) // PPPoE Status Checker page
-		// Monitoring page removed - feature disabled
-		// ONT WiFi Extractor removed - functionality integrated into router cards
-		protected.GET("/routers", routerManagementHandler) // Router Management page (Admin only)
-		protected.GET("/users", userManagementHandler) // User Management page (Admin only)
-		protected.GET("/logs", activityLogsHandler) // Activity Logs page (Admin only)
+		wsGroup.GET("/clients", clientStreamHandler.StreamClients)
 	}
-	
-	// Protected API routes (JWT authentication required)
+
 	apiGroup := router.Group("/api")
 	apiGroup.Use(secureAuthMiddleware.RequireJWTAuth()) // Use JWT for API endpoints
+	apiGroup.Use(secureAuthMiddleware.RequireStepUp(cfg.StepUpRoutes)) // Second confirmation for configured destructive routes
 	{
 		// User info
 		apiGroup.GET("/auth/me", authHandler.Me)
+		apiGroup.POST("/auth/step-up", authHandler.StepUp)
+
+		// TOTP two-factor enrollment for the current user
+		apiGroup.POST("/auth/2fa/enroll", authHandler.EnrollTOTP)
+		apiGroup.POST("/auth/2fa/verify", authHandler.VerifyTOTP)
+
+		// Logged-in sessions fleet-wide (Administrator only)
+		apiGroup.GET("/admin/active-sessions", authHandler.ListActiveSessions)
+		apiGroup.DELETE("/admin/active-sessions/:session_id", authHandler.RevokeActiveSession)
+
+		// Feature flag administration (Administrator only)
+		apiGroup.GET("/feature-flags", featureFlagHandler.ListFlags)
+		apiGroup.PUT("/feature-flags/:name", featureFlagHandler.UpdateFlag)
+
+		// Role-based router access (Administrator only)
+		apiGroup.GET("/access-control/roles/:role/routers", accessControlHandler.GetRoleRouters)
+		apiGroup.PUT("/access-control/roles/:role/routers", accessControlHandler.SetRoleRouters)
+
+		// Connectivity audits and their schedules (Administrator only)
+		auditGroup := apiGroup.Group("/audits")
+		{
+			auditGroup.GET("", auditHandler.GetAudits)
+			auditGroup.POST("/run", auditHandler.RunAudit)
+			auditGroup.GET("/schedules", auditHandler.ListSchedules)
+			auditGroup.POST("/schedules", auditHandler.CreateSchedule)
+			auditGroup.PUT("/schedules/:id", auditHandler.UpdateSchedule)
+			auditGroup.DELETE("/schedules/:id", auditHandler.DeleteSchedule)
+		}
+
+		// Deep health check across DB, JWT, pool and canary router (Administrator only)
+		apiGroup.GET("/health/deep", healthHandler.DeepHealth)
+
+		// Background router health monitor, filtered by router access
+		monitoringGroup := apiGroup.Group("/monitoring")
+		{
+			monitoringGroup.GET("/health", monitoringHandler.GetAllHealth)
+			monitoringGroup.GET("/health/:id", monitoringHandler.GetHealth)
+			monitoringGroup.GET("/history/:id", monitoringHandler.GetHealthHistory)
+		}
 
 		// Router Management API routes (Administrator only)
 		routerGroup := apiGroup.Group("/routers")
@@ -210,8 +267,19 @@ func main() {
 			routerGroup.PUT("/:id", routerHandler.UpdateRouter)
 			routerGroup.DELETE("/:id", routerHandler.DeleteRouter)
 			routerGroup.POST("/:id/test", routerHandler.TestRouter)
+			routerGroup.POST("/:id/rotate-credentials", routerHandler.RotateRouterCredentials)
+			routerGroup.GET("/:id/logs", middleware.RequireFeature(featureFlagService, models.FeatureRouterLogs), routerHandler.GetRouterLogs)
+			routerGroup.GET("/:id/conntrack", routerHandler.GetConnTrack)
+			routerGroup.GET("/:id/impact", routerHandler.GetRouterImpact)
+			routerGroup.GET("/:id/circuit", routerHandler.GetRouterCircuit)
+			routerGroup.POST("/:id/circuit/reset", routerHandler.ResetRouterCircuit)
 			routerGroup.GET("/stats", routerHandler.GetRouterStats)
+			routerGroup.GET("/export", routerHandler.ExportRouters)
+			routerGroup.POST("/import", routerHandler.ImportRouters)
+			routerGroup.POST("/backup", routerHandler.BackupRouters)
+			routerGroup.GET("/pool/stats", routerHandler.GetPoolStats)
 			routerGroup.POST("/validate", routerHandler.ValidateRouter)
+			routerGroup.POST("/validate-batch", routerHandler.ValidateRouterBatch)
 			routerGroup.POST("/reload", routerHandler.ReloadConfiguration)
 			routerGroup.GET("/config", routerHandler.GetConfigurationInfo)
 		}
@@ -221,13 +289,18 @@ func main() {
 		{
 			userGroup.GET("", userHandler.ListUsers)
 			userGroup.POST("", userHandler.CreateUser)
+			userGroup.POST("/import", userHandler.ImportUsers)
 			userGroup.GET("/:id", userHandler.GetUser)
 			userGroup.PUT("/:id", userHandler.UpdateUser)
 			userGroup.DELETE("/:id", userHandler.DeleteUser)
 			userGroup.GET("/:id/routers", userHandler.GetUserRouters)
+			userGroup.GET("/:id/access/:router", userHandler.GetUserRouterAccess)
+			userGroup.GET("/:id/effective-routers", userHandler.GetUserEffectiveRouters)
 			userGroup.GET("/:id/stats", userHandler.GetUserStats)
+			userGroup.GET("/:id/export", userHandler.ExportUser)
 			userGroup.PATCH("/:id/activate", userHandler.ActivateUser)
 			userGroup.PATCH("/:id/password", userHandler.ChangeUserPassword)
+			userGroup.POST("/:id/revoke-sessions", userHandler.RevokeUserSessions)
 		}
 
 		// NAT Management API routes
@@ -235,17 +308,30 @@ func main() {
 		{
 			natGroup.GET("/configs", natHandler.GetNATConfigs)
 			natGroup.GET("/clients", natHandler.GetNATClients)
+			natGroup.GET("/clients/summary", natHandler.GetNATClientsSummary)
+			natGroup.GET("/clients/export", natHandler.ExportNATClients)
+			natGroup.GET("/conflicts", natHandler.GetNATConflicts)
+			natGroup.GET("/rules", natHandler.GetNATRules)
 			natGroup.POST("/update", natHandler.UpdateNATRule)
+			natGroup.POST("/update/preview", natHandler.PreviewNATUpdate)
+			natGroup.POST("/rollback", natHandler.RollbackNATRule)
+			natGroup.POST("/toggle", natHandler.ToggleNATRule)
 			natGroup.GET("/test", natHandler.TestNATConnections)
 			natGroup.GET("/status", natHandler.GetNATStatus)
+			natGroup.POST("/cache/invalidate", natHandler.InvalidateNATCache)
 		}
 
 		// PPPoE Status Checking API routes
 		pppoeGroup := apiGroup.Group("/pppoe")
 		{
 			pppoeGroup.POST("/check", natHandler.CheckPPPoEStatus)
+			pppoeGroup.POST("/check-batch", natHandler.CheckPPPoEStatusBatch)
 			pppoeGroup.GET("/check/:username", natHandler.CheckPPPoEStatusByGET)
+			pppoeGroup.GET("/history", natHandler.GetPPPoEHistory)
+			pppoeGroup.POST("/disconnect", natHandler.DisconnectPPPoE)
 			pppoeGroup.GET("/routers", natHandler.GetPPPoERouters)
+			pppoeGroup.GET("/customer/:username", natHandler.GetPPPoECustomer)
+			pppoeGroup.POST("/audit", natHandler.AuditPPPoE)
 			pppoeGroup.POST("/fuzzy-search", natHandler.FuzzySearchPPPoE)
 		}
 
@@ -253,6 +339,7 @@ func main() {
 		logsGroup := apiGroup.Group("/logs")
 		{
 			logsGroup.GET("", activityLogHandler.GetLogs)
+			logsGroup.GET("/export", activityLogHandler.ExportLogs)
 			logsGroup.GET("/:id", activityLogHandler.GetLogByID)
 			logsGroup.GET("/stats", activityLogHandler.GetLogStats)
 			logsGroup.POST("/cleanup", activityLogHandler.DeleteOldLogs)
@@ -263,7 +350,10 @@ func main() {
 		{
 			ontWiFiGroup.POST("/extract", ontWiFiHandler.ExtractWiFiInfo)
 			ontWiFiGroup.POST("/extract-from-nat", ontWiFiHandler.ExtractWiFiFromNAT)
+			ontWiFiGroup.POST("/bulk-extract", ontWiFiHandler.BulkExtractWiFi)
+			ontWiFiGroup.POST("/extract-all", ontWiFiHandler.ExtractAllWiFi)
 			ontWiFiGroup.GET("/history", ontWiFiHandler.GetWiFiHistory)
+			ontWiFiGroup.GET("/changes", ontWiFiHandler.GetWiFiChanges)
 			ontWiFiGroup.GET("/latest/:pppoe_username", ontWiFiHandler.GetLatestWiFiInfo)
 			ontWiFiGroup.GET("/search", ontWiFiHandler.SearchBySSID)
 			ontWiFiGroup.GET("/stats", ontWiFiHandler.GetWiFiStats)
@@ -310,6 +400,13 @@ func main() {
 		logger.Info("✅ Server gracefully stopped")
 	}
 
+	routerChangeListener.Stop()
+	auditService.Stop()
+	healthMonitor.Stop()
+	clientWatcher.Stop()
+	ontExtractorService.Stop()
+	activityLogService.Stop()
+
 	logger.Info("🔒 Closing RouterOS connection pool...")
 	routerService.Close()
 
@@ -361,8 +458,43 @@ func activityLogsHandler(c *gin.Context) {
 	})
 }
 
+// versionHandler serves /api/version with the build metadata of the binary
+func versionHandler(c *gin.Context) {
+	utils.RespondSuccess(c, version.Get())
+}
+
+// readyHandler serves /ready: 503 when the database can't be pinged, 200
+// otherwise. Deep mode adds router reachability from the last connection
+// test; routers being down degrades readiness but never fails it.
+func readyHandler(ping func(context.Context) error, routerReadiness func() models.RouterReadiness, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := ping(c.Request.Context()); err != nil {
+			logger.Warnf("❌ Database ping failed in readiness check: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status": "not ready",
+				"reason": "database connection failed",
+				"error":  err.Error(),
+			})
+			return
+		}
+
+		response := gin.H{
+			"status":   "ready",
+			"database": "connected",
+			"service":  "NAT Management Application",
+		}
+		if c.Query("deep") == "true" {
+			routers := routerReadiness()
+			response["routers"] = routers
+			response["degraded"] = routers.Degraded
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
 // setupLogger configures the logger
-func setupLogger(debug bool) *logrus.Logger {
+func setupLogger(debug bool, format string) *logrus.Logger {
 	logger := logrus.New()
 	
 	if debug {
@@ -371,26 +503,110 @@ func setupLogger(debug bool) *logrus.Logger {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
-	// Use custom formatter
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-		ForceColors:   true,
-	})
+	// JSON for log shippers (Loki/ELK), colored text otherwise
+	if format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+			ForceColors:   true,
+		})
+	}
 
 	return logger
 }
 
+// setupWebUI loads the HTML templates, serves the static asset directories and
+// registers the HTML pages behind requireAuth. With ENABLE_WEB_UI off it does
+// nothing, so API-only deployments start without templates; with it on, a
+// missing template set is an error.
+func setupWebUI(router *gin.Engine, enabled bool, requireAuth gin.HandlerFunc, logger *logrus.Logger) error {
+	if !enabled {
+		logger.Info("🧩 ENABLE_WEB_UI=false - running as JSON API only (templates and static files skipped)")
+		return nil
+	}
+
+	// Load HTML templates with error handling
+	templateRoot := "web/templates"
+	if _, err := os.Stat(templateRoot); os.IsNotExist(err) {
+		logger.Warn("📁 web/templates directory not found, trying alternative paths...")
+		if _, err := os.Stat("./templates"); err == nil {
+			templateRoot = "./templates"
+			logger.Info("📁 Using ./templates for templates (recursive)")
+		} else {
+			logger.Error("❌ No templates directory found! Application may not work correctly")
+		}
+	} else {
+		logger.Info("📁 Using web/templates for templates (recursive)")
+	}
+
+	if err := loadTemplates(router, templateRoot); err != nil {
+		return fmt.Errorf("failed to load templates from %s: %w", templateRoot, err)
+	}
+
+	// Serve static files
+	staticPath := "web/static"
+	if _, err := os.Stat("web/static"); os.IsNotExist(err) {
+		logger.Warn("📁 web/static directory not found, trying alternative paths...")
+		if _, err := os.Stat("./static"); err == nil {
+			staticPath = "./static"
+			logger.Info("📁 Using ./static for static files")
+		} else {
+			logger.Error("❌ No static directory found! CSS/JS may not load correctly")
+		}
+	} else {
+		logger.Info("📁 Using web/static for static files")
+	}
+	router.Static("/static", staticPath)
+
+	// Serve SS assets (logos/screenshots), so /SS/logo.jpeg works on login page
+	assetsPath := "SS"
+	if _, err := os.Stat(assetsPath); os.IsNotExist(err) {
+		logger.Warn("📁 SS directory not found; logo may not load from /SS/logo.jpeg")
+	} else {
+		router.Static("/SS", assetsPath)
+		logger.Info("📁 Serving SS at /SS")
+	}
+	// Serve image assets (branding), so /image/logo.png works on login page
+	imagesPath := "image"
+	if _, err := os.Stat(imagesPath); os.IsNotExist(err) {
+		logger.Warn("📁 image directory not found; logo may not load from /image/logo.png")
+	} else {
+		router.Static("/image", imagesPath)
+		logger.Info("📁 Serving image at /image")
+	}
+
+	// Public login page
+	router.GET("/login", loginHandler)
+
+	// Protected HTML pages (authentication required) - session based untuk backward compatibility
+	protected := router.Group("/")
+	protected.Use(requireAuth)
+	{
+		protected.GET("/", natManagementHandler)           // Main NAT Management page
+		protected.GET("/nat", natManagementHandler)        // Alternative route
+		protected.GET("/pppoe", pppoeCheckerHandler)       // PPPoE Status Checker page
+		protected.GET("/routers", routerManagementHandler) // Router Management page (Admin only)
+		protected.GET("/users", userManagementHandler)     // User Management page (Admin only)
+		protected.GET("/logs", activityLogsHandler)        // Activity Logs page (Admin only)
+	}
+	return nil
+}
+
 // loadTemplates safely loads HTML templates (recursive) including partials/layouts
-func loadTemplates(router *gin.Engine, templateRoot string) error {
+func loadTemplates(router *gin.Engine, templateRoot string) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Printf("❌ Panic while loading templates: %v\n", r)
+			err = fmt.Errorf("panic while loading templates: %v", r)
 		}
 	}()
 
 	// Collect all .html files under templateRoot (recursive)
 	var files []string
-	err := filepath.Walk(templateRoot, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(templateRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -441,7 +657,11 @@ func printStartupInfo(cfg *config.Config) {
 	fmt.Println("- head2/head123 (Branch 2 - LANE2, LANE4)")
 	fmt.Println("- head3/head123 (Branch 3 - BT JAYA/PK JAYA, SUKAWANGI)")
 	fmt.Println(separator)
-	fmt.Printf("🌐 Web Interface: http://localhost:%s\n", cfg.ServerPort)
+	if cfg.EnableWebUI {
+		fmt.Printf("🌐 Web Interface: http://localhost:%s\n", cfg.ServerPort)
+	} else {
+		fmt.Printf("🧩 API-only mode: http://localhost:%s/api\n", cfg.ServerPort)
+	}
 	fmt.Println("⚡ Press Ctrl+C to stop")
 	fmt.Println(separator)
 }
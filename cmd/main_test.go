@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// denyAll stands in for the session middleware in front of the HTML pages
+func denyAll(c *gin.Context) {
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+func serve(router *gin.Engine, path string) int {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code
+}
+
+func TestSetupWebUIDisabledNeedsNoTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// No web/templates or web/static here
+	t.Chdir(t.TempDir())
+
+	router := gin.New()
+	if err := setupWebUI(router, false, denyAll, quietLogger()); err != nil {
+		t.Fatalf("API-only setup: %v", err)
+	}
+	if routes := router.Routes(); len(routes) != 0 {
+		t.Fatalf("API-only setup registered %d routes, want none", len(routes))
+	}
+	for _, path := range []string{"/login", "/", "/static/app.js"} {
+		if code := serve(router, path); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404 without the web UI", path, code)
+		}
+	}
+
+	// The same directory fails with the web UI on, so the disabled path
+	// really skipped the templates
+	if err := setupWebUI(gin.New(), true, denyAll, quietLogger()); err == nil {
+		t.Fatal("web UI setup without templates succeeded")
+	}
+}
+
+func TestSetupWebUIEnabledServesPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "web", "templates"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "web", "templates", "login.html"), []byte("<p>{{.Title}}</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	if err := setupWebUI(router, true, denyAll, quietLogger()); err != nil {
+		t.Fatalf("web UI setup: %v", err)
+	}
+	if code := serve(router, "/login"); code != http.StatusOK {
+		t.Errorf("GET /login = %d, want 200", code)
+	}
+	// Pages other than the login page sit behind the auth middleware
+	if code := serve(router, "/nat"); code != http.StatusUnauthorized {
+		t.Errorf("GET /nat = %d, want 401 from the auth middleware", code)
+	}
+}
+
+func TestReadyReportsDegradedRouters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	checkedAt := time.Now()
+	allDown := func() models.RouterReadiness {
+		return models.RouterReadiness{Total: 2, Checked: true, CheckedAt: &checkedAt, Degraded: true}
+	}
+	router := gin.New()
+	router.GET("/ready", readyHandler(func(context.Context) error { return nil }, allDown, quietLogger()))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready?deep=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /ready?deep=true with every router down = %d, want 200", w.Code)
+	}
+	var body struct {
+		Degraded bool                   `json:"degraded"`
+		Routers  models.RouterReadiness `json:"routers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.Degraded || !body.Routers.Checked || body.Routers.Total != 2 {
+		t.Fatalf("body = %s, want degraded with 2 checked routers", w.Body.String())
+	}
+}
+
+func TestReadyFailsWithoutDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbDown := func(context.Context) error { return errors.New("connection refused") }
+	routerReadiness := func() models.RouterReadiness {
+		t.Error("router readiness read while the database is down")
+		return models.RouterReadiness{}
+	}
+	router := gin.New()
+	router.GET("/ready", readyHandler(dbDown, routerReadiness, quietLogger()))
+
+	for _, path := range []string{"/ready", "/ready?deep=true"} {
+		if code := serve(router, path); code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s with the database down = %d, want 503", path, code)
+		}
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/version", versionHandler)
+
+	get := func() version.Info {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/version = %d, want 200", w.Code)
+		}
+		var body struct {
+			Status string       `json:"status"`
+			Data   version.Info `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Status != "success" {
+			t.Fatalf("status = %q, want success", body.Status)
+		}
+		return body.Data
+	}
+
+	// A plain build reports dev
+	if info := get(); info.Version != "dev" || info.Commit != "dev" || info.BuildTime != "dev" || info.GoVersion == "" {
+		t.Fatalf("default build = %+v, want dev values", info)
+	}
+
+	// As set by -ldflags "-X ..."
+	saved := [3]string{version.Version, version.Commit, version.BuildTime}
+	t.Cleanup(func() { version.Version, version.Commit, version.BuildTime = saved[0], saved[1], saved[2] })
+	version.Version, version.Commit, version.BuildTime = "v4.3.0", "abc1234", "2026-10-01T08:00:00Z"
+
+	if info := get(); info.Version != "v4.3.0" || info.Commit != "abc1234" || info.BuildTime != "2026-10-01T08:00:00Z" {
+		t.Fatalf("injected build = %+v, want v4.3.0 abc1234 2026-10-01T08:00:00Z", info)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds NAT Management application configuration
@@ -12,6 +14,101 @@ type Config struct {
 	ServerPort string `json:"server_port"`
 	ServerHost string `json:"server_host"`
 	Debug      bool   `json:"debug"`
+
+	// Log output: "text" (colored, for terminals) or "json" (for Loki/ELK)
+	LogFormat string `json:"log_format"`
+
+	// Web UI (HTML pages, templates, static assets); false = pure JSON API
+	EnableWebUI bool `json:"enable_web_ui"`
+
+	// Health check configuration
+	HealthCanaryRouter string `json:"health_canary_router"` // Router tested by /api/health/deep (empty = skip)
+
+	// Customer directory integration
+	CustomerDirectoryURL string `json:"customer_directory_url"` // mapping-ftth customer endpoint (empty = local table only)
+
+	// Router change propagation between instances
+	RouterChangeListen      bool `json:"router_change_listen"`      // LISTEN for router_changes notifications (off by default: needs a direct or session-mode connection)
+	RouterReconcileInterval int  `json:"router_reconcile_interval"` // Seconds between full router reloads (0 = off)
+
+	// Dashboard default router scope overrides, role -> all|assigned|first
+	RoleDefaultScopes map[string]string `json:"role_default_scopes"`
+
+	// RouterOS connection pool
+	RouterOSPoolMax          int               `json:"routeros_pool_max"`           // Connections per router
+	RouterOSPoolIdleTimeout  time.Duration     `json:"routeros_pool_idle_timeout"`  // Close idle connections after
+	RouterOSPoolMaxLifetime  time.Duration     `json:"routeros_pool_max_lifetime"`  // Recycle connections after
+	RouterOSPoolDrainTimeout time.Duration     `json:"routeros_pool_drain_timeout"` // Wait on shutdown for in-use connections
+	PoolMaxIdlePerRouter     int               `json:"pool_max_idle_per_router"`    // Idle connections kept per router (0 = no cap)
+	RouterOSOpTimeouts       map[string]string `json:"routeros_op_timeouts"`        // Command timeout overrides, operation -> seconds
+
+	// Per-router circuit breaker on connection tests
+	CircuitFailureThreshold int           `json:"circuit_failure_threshold"` // Consecutive failures that open the circuit
+	CircuitTimeout          time.Duration `json:"circuit_timeout"`           // Wait before an open circuit lets a test through
+
+	// Per-router RouterOS command rate limit, protects the routers themselves
+	RouterCommandRate    float64 `json:"router_command_rate"`     // Commands per second per router (0 = off)
+	RouterCommandBurst   int     `json:"router_command_burst"`    // Commands a router may receive back to back
+	RouterCommandMaxWait int     `json:"router_command_max_wait"` // Milliseconds a command may queue before "router busy"
+
+	// RSA key signing JWTs. The PEM wins; a missing file is generated on first
+	// run. With neither set, debug mode falls back to a per-process key.
+	JWTPrivateKeyPath string `json:"jwt_private_key_path"`
+	JWTPrivateKeyPEM  string `json:"-"`
+
+	// Token lifetimes; login cookies expire together with their tokens
+	JWTAccessTTL  time.Duration `json:"jwt_access_ttl"`
+	JWTRefreshTTL time.Duration `json:"jwt_refresh_ttl"`
+
+	// Password policy for created and changed passwords
+	PasswordMinLength    int  `json:"password_min_length"`
+	PasswordRequireMixed bool `json:"password_require_mixed"` // At least one letter and one digit
+
+	// Renew expired access tokens from the refresh cookie instead of answering 401
+	JWTAutoRefresh bool `json:"jwt_auto_refresh"`
+
+	// Content-Security-Policy sent on every response (empty = built-in default
+	// allowing the web UI's CDNs), and X-Frame-Options: DENY or SAMEORIGIN
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	FrameOptions          string `json:"frame_options"`
+
+	// Clock skew tolerated on JWT exp/nbf/iat, in seconds (max 300)
+	JWTLeeway int `json:"jwt_leeway"`
+
+	// Step-up confirmation for destructive routes, "METHOD /path" entries (empty = off)
+	StepUpRoutes []string `json:"step_up_routes"`
+
+	// PPPoE fuzzy search
+	FuzzyMaxCandidates int      `json:"fuzzy_max_candidates"` // Usernames per router given full similarity scoring (0 = no cap)
+	FuzzyMinSimilarity float64  `json:"fuzzy_min_similarity"` // Lowest similarity (0-1) returned as a match
+	FuzzyAreaPatterns  []string `json:"fuzzy_area_patterns"`  // Area names found in usernames (empty = built-in list)
+
+	// PPPoE device connectivity test
+	ConnectivityTestPorts   []string      `json:"connectivity_test_ports"`   // TCP ports tried at once (empty = built-in list)
+	ConnectivityTestTimeout time.Duration `json:"connectivity_test_timeout"` // Per-port connection timeout
+
+	// How long NAT configs, online clients and connection tests are cached
+	NATCacheTTL time.Duration `json:"nat_cache_ttl"`
+
+	// Days of router health history kept for uptime reporting (0 = keep forever)
+	HealthHistoryRetentionDays int `json:"health_history_retention_days"`
+
+	// Directory POST /api/routers/backup writes router backups to
+	RouterBackupDir string `json:"router_backup_dir"`
+
+	// Router down/recovery alerts are POSTed here as JSON (empty = off)
+	AlertWebhookURL string `json:"alert_webhook_url"`
+
+	// Activity logs older than this many days are deleted every
+	// LogRetentionInterval (0 = keep forever)
+	LogRetentionDays     int           `json:"log_retention_days"`
+	LogRetentionInterval time.Duration `json:"log_retention_interval"`
+
+	// Cron expression for refreshing WiFi info of every router's ONT
+	// (empty = off), and the ONT login used for it (empty = admin/admin)
+	WiFiExtractSchedule    string `json:"wifi_extract_schedule"`
+	WiFiExtractONTUsername string `json:"-"`
+	WiFiExtractONTPassword string `json:"-"`
 }
 
 // Load loads configuration from environment variables
@@ -21,11 +118,76 @@ func Load() *Config {
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		ServerHost: getEnv("SERVER_HOST", "localhost"),
 		Debug:      getEnvBool("DEBUG", true),
+		LogFormat:  strings.ToLower(getEnv("LOG_FORMAT", "text")),
+
+		EnableWebUI: getEnvBool("ENABLE_WEB_UI", true),
+
+		HealthCanaryRouter: getEnv("HEALTH_CANARY_ROUTER", ""),
+
+		CustomerDirectoryURL: getEnv("CUSTOMER_DIRECTORY_URL", ""),
+
+		RouterChangeListen:      getEnvBool("ROUTER_CHANGE_LISTEN", false),
+		RouterReconcileInterval: getEnvInt("ROUTER_RECONCILE_INTERVAL", 300),
+
+		RoleDefaultScopes: getEnvMap("ROLE_DEFAULT_SCOPES"),
+
+		RouterOSPoolMax:          getEnvInt("ROUTEROS_POOL_MAX", 5),
+		RouterOSPoolIdleTimeout:  getEnvDuration("ROUTEROS_POOL_IDLE_TIMEOUT", 5*time.Minute),
+		RouterOSPoolMaxLifetime:  getEnvDuration("ROUTEROS_POOL_MAX_LIFETIME", 30*time.Minute),
+		RouterOSPoolDrainTimeout: getEnvDuration("ROUTEROS_POOL_DRAIN_TIMEOUT", 10*time.Second),
+		PoolMaxIdlePerRouter:     getEnvInt("POOL_MAX_IDLE_PER_ROUTER", 2),
+		RouterOSOpTimeouts:       getEnvMap("ROUTEROS_OP_TIMEOUTS"),
+
+		CircuitFailureThreshold: getEnvInt("CIRCUIT_FAILURE_THRESHOLD", 3),
+		CircuitTimeout:          getEnvDuration("CIRCUIT_TIMEOUT", 30*time.Second),
+
+		RouterCommandRate:    getEnvFloat("ROUTER_COMMAND_RATE", 10),
+		RouterCommandBurst:   getEnvInt("ROUTER_COMMAND_BURST", 20),
+		RouterCommandMaxWait: getEnvInt("ROUTER_COMMAND_MAX_WAIT", 2000),
+
+		JWTPrivateKeyPath: getEnv("JWT_PRIVATE_KEY_PATH", ""),
+		JWTPrivateKeyPEM:  getEnv("JWT_PRIVATE_KEY_PEM", ""),
+
+		JWTAccessTTL:  getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+		JWTRefreshTTL: getEnvDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
+
+		PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireMixed: getEnvBool("PASSWORD_REQUIRE_MIXED", true),
+
+		JWTAutoRefresh: getEnvBool("JWT_AUTO_REFRESH", false),
+		JWTLeeway:      getEnvInt("JWT_LEEWAY_SECONDS", 30),
+		StepUpRoutes:   getEnvList("STEP_UP_ROUTES"),
+
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", ""),
+		FrameOptions:          getEnv("X_FRAME_OPTIONS", "DENY"),
+
+		FuzzyMaxCandidates: getEnvInt("FUZZY_MAX_CANDIDATES", 2000),
+		FuzzyMinSimilarity: getEnvFloat("FUZZY_MIN_SIMILARITY", 0.3),
+		FuzzyAreaPatterns:  getEnvList("FUZZY_AREA_PATTERNS"),
+
+		ConnectivityTestPorts:   getEnvList("CONNECTIVITY_TEST_PORTS"),
+		ConnectivityTestTimeout: getEnvDuration("CONNECTIVITY_TEST_TIMEOUT", 2*time.Second),
+
+		NATCacheTTL: getEnvDuration("NAT_CACHE_TTL", 30*time.Second),
+
+		HealthHistoryRetentionDays: getEnvInt("HEALTH_HISTORY_RETENTION_DAYS", 30),
+
+		RouterBackupDir: getEnv("ROUTER_BACKUP_DIR", "./backups"),
+
+		AlertWebhookURL: getEnv("ALERT_WEBHOOK_URL", ""),
+
+		LogRetentionDays:     getEnvInt("LOG_RETENTION_DAYS", 90),
+		LogRetentionInterval: getEnvDuration("LOG_RETENTION_INTERVAL", 24*time.Hour),
+
+		WiFiExtractSchedule:    getEnv("WIFI_EXTRACT_SCHEDULE", ""),
+		WiFiExtractONTUsername: getEnv("WIFI_EXTRACT_ONT_USERNAME", ""),
+		WiFiExtractONTPassword: getEnv("WIFI_EXTRACT_ONT_PASSWORD", ""),
 	}
 
 	log.Printf("🚀 NAT Management App Configuration Loaded")
 	log.Printf("   Server: %s:%s", cfg.ServerHost, cfg.ServerPort)
 	log.Printf("   Debug Mode: %v", cfg.Debug)
+	log.Printf("   Web UI: %v", cfg.EnableWebUI)
 
 	return cfg
 }
@@ -46,3 +208,56 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration parses a Go duration ("2m", "500ms") or a plain number of
+// seconds. Unset, unparseable and non-positive values give defaultValue.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return defaultValue
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration
+	}
+	log.Printf("⚠️  Invalid %s=%q, using %v", key, value, defaultValue)
+	return defaultValue
+}
+
+// getEnvList parses a comma-separated list, dropping empty entries
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvMap parses "key=value,key=value" pairs; keys may contain spaces
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
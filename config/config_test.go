@@ -0,0 +1,16 @@
+package config
+
+import "testing"
+
+func TestRouterChangeListenIsOptIn(t *testing.T) {
+	// Transaction-mode poolers swallow notifications, so listening is opt-in
+	t.Setenv("ROUTER_CHANGE_LISTEN", "")
+	if Load().RouterChangeListen {
+		t.Fatal("ROUTER_CHANGE_LISTEN defaults to on, want off")
+	}
+
+	t.Setenv("ROUTER_CHANGE_LISTEN", "true")
+	if !Load().RouterChangeListen {
+		t.Fatal("ROUTER_CHANGE_LISTEN=true did not enable listening")
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessControlHandler handles role-based router access administration
+type AccessControlHandler struct {
+	routerService      *services.RouterServiceDB
+	activityLogService *services.ActivityLogService
+	logger             *logrus.Logger
+}
+
+// NewAccessControlHandler creates a new access control handler
+func NewAccessControlHandler(routerService *services.RouterServiceDB, activityLogService *services.ActivityLogService, logger *logrus.Logger) *AccessControlHandler {
+	return &AccessControlHandler{
+		routerService:      routerService,
+		activityLogService: activityLogService,
+		logger:             logger,
+	}
+}
+
+// accessControlAdminOnly answers non-admins on the access control endpoints
+const accessControlAdminOnly = "Only administrators can manage router access control"
+
+// GetRoleRouters handles GET /api/access-control/roles/:role/routers
+func (h *AccessControlHandler) GetRoleRouters(c *gin.Context) {
+	if _, ok := requireAdmin(c, accessControlAdminOnly); !ok {
+		return
+	}
+
+	role := c.Param("role")
+	roleRouters, err := h.routerService.GetRoleRouters(role)
+	if err != nil {
+		h.respondRoleRoutersError(c, role, err)
+		return
+	}
+
+	utils.RespondSuccess(c, roleRouters)
+}
+
+// SetRoleRouters handles PUT /api/access-control/roles/:role/routers
+func (h *AccessControlHandler) SetRoleRouters(c *gin.Context) {
+	user, ok := requireAdmin(c, accessControlAdminOnly)
+	if !ok {
+		return
+	}
+
+	var req models.RoleRoutersUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid",
+		})
+		return
+	}
+
+	role := c.Param("role")
+	before, err := h.routerService.GetRoleRouters(role)
+	if err != nil {
+		h.respondRoleRoutersError(c, role, err)
+		return
+	}
+
+	roleRouters, err := h.routerService.SetRoleRouters(role, req.Routers)
+	if err != nil {
+		h.respondRoleRoutersError(c, role, err)
+		return
+	}
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionUpdate,
+			ResourceType: models.ResourceAccessControl,
+			ResourceID:   role,
+			Description: fmt.Sprintf("Router access for role %s changed from [%s] to [%s]",
+				role, strings.Join(before.Routers, ", "), strings.Join(roleRouters.Routers, ", ")),
+			IPAddress: c.ClientIP(),
+			RequestID: middleware.GetRequestID(c),
+			UserAgent: c.GetHeader("User-Agent"),
+			Status:    models.StatusSuccess,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, "Router access updated", roleRouters)
+}
+
+// respondRoleRoutersError maps role router errors to HTTP responses
+func (h *AccessControlHandler) respondRoleRoutersError(c *gin.Context, role string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownRole):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "Role not found",
+		})
+	case errors.Is(err, services.ErrAdministratorAccess),
+		errors.Is(err, services.ErrWildcardWithRouters),
+		errors.Is(err, services.ErrUnknownRouters):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+	default:
+		h.logger.Errorf("Failed to manage router access for role %s: %v", role, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to manage router access",
+		})
+	}
+}
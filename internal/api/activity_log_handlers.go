@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,6 +11,7 @@ import (
 	"nat-management-app/internal/middleware"
 	"nat-management-app/internal/models"
 	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -48,44 +51,101 @@ func (h *ActivityLogHandler) GetLogs(c *gin.Context) {
 		return
 	}
 
-	// Parse query parameters
+	filter, err := parseActivityLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Pagination
+	limit := 50 // Default limit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	filter.Limit = limit
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	filter.Offset = offset
+
+	// Get logs
+	logs, total, err := h.logService.GetLogs(filter)
+	if err != nil {
+		h.logger.Errorf("Failed to get activity logs: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve activity logs",
+		})
+		return
+	}
+
+	// Calculate pagination
+	currentPage := (offset / limit) + 1
+	totalPages := (total + limit - 1) / limit
+
+	c.JSON(http.StatusOK, models.ActivityLogsResponse{
+		Status: "success",
+		Data:   logs,
+		Total:  total,
+		Pagination: &models.Pagination{
+			CurrentPage: currentPage,
+			PerPage:     limit,
+			TotalPages:  totalPages,
+			TotalItems:  total,
+		},
+		Meta: models.NewPaginationMeta(total, limit, offset),
+	})
+}
+
+// parseActivityLogFilter reads the user/action/resource/status/IP/date query
+// filters shared by GetLogs and ExportLogs. Action, resource and status
+// codes are matched in upper case.
+func parseActivityLogFilter(c *gin.Context) (*models.ActivityLogFilter, error) {
 	filter := &models.ActivityLogFilter{}
 
 	// User filter
 	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		if userID, err := strconv.Atoi(userIDStr); err == nil {
-			filter.UserID = &userID
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid user_id")
 		}
+		filter.UserID = &userID
 	}
 
 	// Username filter
-	filter.Username = c.Query("username")
+	filter.Username = strings.TrimSpace(c.Query("username"))
 
 	// Action type filter (single - backward compatibility)
-	filter.ActionType = c.Query("action_type")
+	filter.ActionType = strings.ToUpper(strings.TrimSpace(c.Query("action_type")))
 
 	// Action types filter (multi-select)
-	if actionTypesStr := c.Query("action_types"); actionTypesStr != "" {
-		filter.ActionTypes = strings.Split(actionTypesStr, ",")
-	}
+	filter.ActionTypes = splitLogFilterList(c.Query("action_types"))
 
 	// Resource type filter (single - backward compatibility)
-	filter.ResourceType = c.Query("resource_type")
+	filter.ResourceType = strings.ToUpper(strings.TrimSpace(c.Query("resource_type")))
 
 	// Resource types filter (multi-select)
-	if resourceTypesStr := c.Query("resource_types"); resourceTypesStr != "" {
-		filter.ResourceTypes = strings.Split(resourceTypesStr, ",")
-	}
+	filter.ResourceTypes = splitLogFilterList(c.Query("resource_types"))
 
 	// Status filter (single - backward compatibility)
-	filter.Status = c.Query("status")
+	filter.Status = strings.ToUpper(strings.TrimSpace(c.Query("status")))
 
 	// Statuses filter (multi-select)
-	if statusesStr := c.Query("statuses"); statusesStr != "" {
-		filter.Statuses = strings.Split(statusesStr, ",")
-	}
+	filter.Statuses = splitLogFilterList(c.Query("statuses"))
+
+	// Client IP filter
+	filter.IPAddress = strings.TrimSpace(c.Query("ip_address"))
 
-	// Date range filter
+	// Date range filter (start_date/end_date kept for backward compatibility)
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
 		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
 			filter.StartDate = startDate
@@ -99,49 +159,127 @@ func (h *ActivityLogHandler) GetLogs(c *gin.Context) {
 		}
 	}
 
-	// Pagination
-	limit := 50 // Default limit
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	// Time range filter, RFC3339 or YYYY-MM-DD
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := parseTimeOrDate(fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid from, expected RFC3339 or YYYY-MM-DD")
 		}
+		filter.StartDate = from
 	}
-	filter.Limit = limit
 
-	offset := 0
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := parseTimeOrDate(toStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid to, expected RFC3339 or YYYY-MM-DD")
 		}
+		if len(toStr) == len("2006-01-02") {
+			// A plain date includes the whole day
+			to = to.Add(24*time.Hour - time.Nanosecond)
+		}
+		filter.EndDate = to
 	}
-	filter.Offset = offset
 
-	// Get logs
-	logs, total, err := h.logService.GetLogs(filter)
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() && filter.StartDate.After(filter.EndDate) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+
+	return filter, nil
+}
+
+// splitLogFilterList splits a comma-separated multi-select filter into upper-case codes
+func splitLogFilterList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ExportLogs handles GET /api/logs/export?format=csv
+// Streams the logs matching the GetLogs filters (without pagination) as a
+// CSV download
+func (h *ActivityLogHandler) ExportLogs(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	if user.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Only administrators can export activity logs",
+		})
+		return
+	}
+
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Unsupported export format: " + format,
+		})
+		return
+	}
+
+	filter, err := parseActivityLogFilter(c)
 	if err != nil {
-		h.logger.Errorf("Failed to get activity logs: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Status:  "error",
-			Message: "Failed to retrieve activity logs",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Calculate pagination
-	currentPage := (offset / limit) + 1
-	totalPages := (total + limit - 1) / limit
+	filename := fmt.Sprintf("activity-logs-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
 
-	c.JSON(http.StatusOK, models.ActivityLogsResponse{
-		Status: "success",
-		Data:   logs,
-		Total:  total,
-		Pagination: &models.Pagination{
-			CurrentPage: currentPage,
-			PerPage:     limit,
-			TotalPages:  totalPages,
-			TotalItems:  total,
-		},
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{
+		"id", "created_at", "username", "user_role", "action_type", "resource_type",
+		"resource_id", "description", "status", "error_message", "ip_address", "user_agent",
+	})
+
+	rowCount := 0
+	err = h.logService.StreamLogs(c.Request.Context(), filter, func(log *models.ActivityLog) error {
+		writer.Write(utils.CSVSafeRow(
+			strconv.Itoa(log.ID),
+			log.CreatedAt.Format(time.RFC3339),
+			log.Username,
+			log.UserRole,
+			log.ActionType,
+			log.ResourceType,
+			log.ResourceID,
+			log.Description,
+			log.Status,
+			log.ErrorMessage,
+			log.IPAddress,
+			log.UserAgent,
+		))
+		rowCount++
+		// Push rows out regularly instead of holding them in the writer
+		if rowCount%500 == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+		return writer.Error()
 	})
+	writer.Flush()
+
+	if err != nil {
+		// Headers are already sent, the download just ends early
+		h.logger.Errorf("Activity log export failed after %d rows: %v", rowCount, err)
+		return
+	}
+
+	h.logger.Infof("📤 Activity log export by %s: %d rows", user.Username, rowCount)
 }
 
 // GetLogByID handles GET /api/logs/:id
@@ -185,10 +323,7 @@ func (h *ActivityLogHandler) GetLogByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   log,
-	})
+	utils.RespondSuccess(c, log)
 }
 
 // GetLogStats handles GET /api/logs/stats
@@ -222,10 +357,7 @@ func (h *ActivityLogHandler) GetLogStats(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   stats,
-	})
+	utils.RespondSuccess(c, stats)
 }
 
 // DeleteOldLogs handles POST /api/logs/cleanup
@@ -272,13 +404,9 @@ func (h *ActivityLogHandler) DeleteOldLogs(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Old logs deleted successfully",
-		"data": gin.H{
-			"deleted_count": deletedCount,
-			"days_kept":     req.DaysToKeep,
-		},
+	utils.RespondSuccessWithMessage(c, "Old logs deleted successfully", gin.H{
+		"deleted_count": deletedCount,
+		"days_kept":     req.DaysToKeep,
 	})
 }
 
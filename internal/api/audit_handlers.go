@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler handles scheduled connectivity audits (Administrator only)
+type AuditHandler struct {
+	auditService       *services.AuditService
+	activityLogService *services.ActivityLogService
+	logger             *logrus.Logger
+}
+
+// NewAuditHandler creates a new connectivity audit handler
+func NewAuditHandler(auditService *services.AuditService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService:       auditService,
+		activityLogService: activityLogService,
+		logger:             logger,
+	}
+}
+
+// auditAdminOnly is shown to non-admins calling the audit endpoints
+const auditAdminOnly = "Only administrators can manage connectivity audits"
+
+// logAudit records an audit administration action
+func (h *AuditHandler) logAudit(c *gin.Context, user *models.User, action, resourceID, description string) {
+	if h.activityLogService == nil {
+		return
+	}
+	userID := user.ID
+	h.activityLogService.CreateLog(&models.ActivityLogCreate{
+		UserID:       &userID,
+		Username:     user.Username,
+		UserRole:     string(user.Role),
+		ActionType:   action,
+		ResourceType: models.ResourceAudit,
+		ResourceID:   resourceID,
+		Description:  description,
+		IPAddress:    c.ClientIP(),
+		RequestID:    middleware.GetRequestID(c),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Status:       models.StatusSuccess,
+	})
+}
+
+// GetAudits handles GET /api/audits?date=YYYY-MM-DD - Runs started that day (default today)
+func (h *AuditHandler) GetAudits(c *gin.Context) {
+	if _, ok := requireAdmin(c, auditAdminOnly); !ok {
+		return
+	}
+
+	date := time.Now()
+	if dateParam := c.Query("date"); dateParam != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", dateParam, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		date = parsed
+	}
+
+	runs, err := h.auditService.GetRunsForDate(date)
+	if err != nil {
+		h.logger.Errorf("Failed to get audit runs: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve audit runs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuditRunsResponse{
+		Status: "success",
+		Date:   date.Format("2006-01-02"),
+		Data:   runs,
+		Total:  len(runs),
+	})
+}
+
+// RunAudit handles POST /api/audits/run - Trigger an audit now (runs in the background)
+func (h *AuditHandler) RunAudit(c *gin.Context) {
+	user, ok := requireAdmin(c, auditAdminOnly)
+	if !ok {
+		return
+	}
+
+	var req models.AuditRunRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Format request tidak valid",
+			})
+			return
+		}
+	}
+
+	routers := req.Routers
+	if req.ScheduleID != nil {
+		schedule, err := h.auditService.GetSchedule(*req.ScheduleID)
+		if err != nil {
+			h.respondScheduleError(c, err)
+			return
+		}
+		routers = schedule.Routers
+	}
+
+	run, err := h.auditService.TriggerAudit(req.ScheduleID, routers, user.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrAuditRunInProgress) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Status:  "error",
+				Message: "Audit sedang berjalan, coba lagi setelah selesai",
+			})
+			return
+		}
+		h.logger.Errorf("Failed to trigger audit: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to start audit",
+		})
+		return
+	}
+
+	h.logAudit(c, user, models.ActionTest, strconv.FormatInt(run.ID, 10), "Started connectivity audit run "+strconv.FormatInt(run.ID, 10))
+
+	utils.RespondAccepted(c, "Audit started", run)
+}
+
+// ListSchedules handles GET /api/audits/schedules
+func (h *AuditHandler) ListSchedules(c *gin.Context) {
+	if _, ok := requireAdmin(c, auditAdminOnly); !ok {
+		return
+	}
+
+	schedules, err := h.auditService.ListSchedules()
+	if err != nil {
+		h.logger.Errorf("Failed to list audit schedules: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve audit schedules",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, schedules, models.NewPaginationMeta(len(schedules), 0, 0))
+}
+
+// CreateSchedule handles POST /api/audits/schedules
+func (h *AuditHandler) CreateSchedule(c *gin.Context) {
+	user, ok := requireAdmin(c, auditAdminOnly)
+	if !ok {
+		return
+	}
+
+	var req models.AuditScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid",
+		})
+		return
+	}
+
+	schedule, err := h.auditService.CreateSchedule(&req, user.Username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logAudit(c, user, models.ActionCreate, strconv.Itoa(schedule.ID), "Created audit schedule: "+schedule.Name+" ("+schedule.CronExpr+")")
+
+	utils.RespondCreated(c, "Audit schedule created", schedule)
+}
+
+// UpdateSchedule handles PUT /api/audits/schedules/:id
+func (h *AuditHandler) UpdateSchedule(c *gin.Context) {
+	user, ok := requireAdmin(c, auditAdminOnly)
+	if !ok {
+		return
+	}
+
+	id, ok := h.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AuditScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid",
+		})
+		return
+	}
+
+	schedule, err := h.auditService.UpdateSchedule(id, &req)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+
+	h.logAudit(c, user, models.ActionUpdate, strconv.Itoa(id), "Updated audit schedule: "+schedule.Name+" ("+schedule.CronExpr+")")
+
+	utils.RespondSuccessWithMessage(c, "Audit schedule updated", schedule)
+}
+
+// DeleteSchedule handles DELETE /api/audits/schedules/:id
+func (h *AuditHandler) DeleteSchedule(c *gin.Context) {
+	user, ok := requireAdmin(c, auditAdminOnly)
+	if !ok {
+		return
+	}
+
+	id, ok := h.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.auditService.DeleteSchedule(id); err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+
+	h.logAudit(c, user, models.ActionDelete, strconv.Itoa(id), "Deleted audit schedule "+strconv.Itoa(id))
+
+	utils.RespondSuccessWithMessage(c, "Audit schedule deleted", nil)
+}
+
+// scheduleID parses the :id path parameter
+func (h *AuditHandler) scheduleID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid schedule ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondScheduleError maps schedule errors to 404/400
+func (h *AuditHandler) respondScheduleError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrAuditScheduleNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "Audit schedule not found",
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Status:  "error",
+		Message: err.Error(),
+	})
+}
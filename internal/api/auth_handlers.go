@@ -1,7 +1,9 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"nat-management-app/internal/middleware"
@@ -57,7 +59,7 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 	userAgent := c.GetHeader("User-Agent")
 
 	// Attempt JWT login
-	response, err := ah.authService.LoginWithJWT(req.Username, req.Password, ipAddress, userAgent)
+	response, err := ah.authService.LoginWithJWT(req.Username, req.Password, req.TOTPCode, ipAddress, userAgent)
 	if err != nil {
 		// Log failed login attempt with enhanced logging
 		if ah.activityLogService != nil {
@@ -68,6 +70,7 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 				ResourceType: models.ResourceAuth,
 				Description:  "Failed login attempt",
 				IPAddress:    ipAddress,
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    userAgent,
 				DeviceInfo:   deviceInfo,
 				Status:       models.StatusFailed,
@@ -75,6 +78,12 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 			})
 		}
 
+		// Password was right, the client should ask for the second factor
+		if errors.Is(err, services.ErrTOTPRequired) {
+			utils.RespondWithError(c, http.StatusUnauthorized, models.ErrTOTPRequired)
+			return
+		}
+
 		// Send user-friendly error response
 		errDetail := models.ErrInvalidCredentials.
 			WithDetails("Login failed for user: " + req.Username).
@@ -95,10 +104,10 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 
 				// Set access token cookie (short-lived)
 				c.SetSameSite(http.SameSiteLaxMode) // Use Lax for development compatibility
-				c.SetCookie("access_token", tokenPair.AccessToken, 900, "/", domain, secure, httpOnly) // 15 minutes
+				c.SetCookie("access_token", tokenPair.AccessToken, utils.CookieMaxAge(tokenPair.AccessTokenExpiresAt), "/", domain, secure, httpOnly)
 
 				// Set refresh token cookie (long-lived) - More secure
-				c.SetCookie("refresh_token", tokenPair.RefreshToken, 604800, "/", domain, secure, httpOnly) // 7 days
+				c.SetCookie("refresh_token", tokenPair.RefreshToken, utils.CookieMaxAge(tokenPair.RefreshTokenExpiresAt), "/", domain, secure, httpOnly)
 
 				ah.logger.Infof("🍪 JWT cookies set untuk user: %s", req.Username)
 			}
@@ -118,6 +127,7 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 					ResourceType: models.ResourceAuth,
 					Description:  "Successful login",
 					IPAddress:    ipAddress,
+					RequestID:    middleware.GetRequestID(c),
 					UserAgent:    userAgent,
 					DeviceInfo:   deviceInfo,
 					Status:       models.StatusSuccess,
@@ -180,6 +190,7 @@ func (ah *AuthHandler) Logout(c *gin.Context) {
 				ResourceType: models.ResourceAuth,
 				Description:  "User logged out",
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
@@ -222,6 +233,264 @@ func (ah *AuthHandler) Me(c *gin.Context) {
 	utils.RespondSuccess(c, data)
 }
 
+// StepUp handles POST /api/auth/step-up - re-confirms the password and issues a
+// short-lived token for step-up protected (destructive) endpoints
+func (ah *AuthHandler) StepUp(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.RespondUnauthorized(c)
+		return
+	}
+
+	var req models.StepUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(c, err)
+		return
+	}
+
+	token, err := ah.authService.IssueStepUpToken(user, req.Password, c.ClientIP())
+	status := models.StatusSuccess
+	errorMessage := ""
+	if err != nil {
+		status = models.StatusFailed
+		errorMessage = err.Error()
+	}
+
+	if ah.activityLogService != nil {
+		userID := user.ID
+		ah.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionStepUp,
+			ResourceType: models.ResourceAuth,
+			Description:  "Step-up confirmation for destructive operations",
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       status,
+			ErrorMessage: errorMessage,
+		})
+	}
+
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, models.ErrInvalidCredentials)
+		return
+	}
+
+	utils.RespondSuccess(c, token)
+}
+
+// EnrollTOTP handles POST /api/auth/2fa/enroll - generates a TOTP secret for
+// the caller. Login keeps working without a code until it is verified.
+func (ah *AuthHandler) EnrollTOTP(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.RespondUnauthorized(c)
+		return
+	}
+
+	enrollment, err := ah.authService.EnrollTOTP(user)
+	if err != nil {
+		if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Status:  "error",
+				Message: "Two-factor authentication is already enabled",
+			})
+			return
+		}
+		ah.logger.Errorf("Failed to start TOTP enrollment for %s: %v", user.Username, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to start two-factor enrollment",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, enrollment)
+}
+
+// VerifyTOTP handles POST /api/auth/2fa/verify - confirms enrollment with a
+// code from the authenticator app and enables two-factor login
+func (ah *AuthHandler) VerifyTOTP(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.RespondUnauthorized(c)
+		return
+	}
+
+	var req models.TOTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(c, err)
+		return
+	}
+
+	err := ah.authService.VerifyTOTP(user, req.Code)
+	if err != nil && !errors.Is(err, services.ErrTOTPInvalid) && !errors.Is(err, services.ErrTOTPNotEnrolled) && !errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+		ah.logger.Errorf("Failed to verify TOTP enrollment for %s: %v", user.Username, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to verify two-factor code",
+		})
+		return
+	}
+
+	status := models.StatusSuccess
+	errorMessage := ""
+	if err != nil {
+		status = models.StatusFailed
+		errorMessage = err.Error()
+	}
+	if ah.activityLogService != nil {
+		userID := user.ID
+		ah.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionUpdate,
+			ResourceType: models.ResourceAuth,
+			Description:  "Enabled two-factor authentication",
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       status,
+			ErrorMessage: errorMessage,
+		})
+	}
+
+	switch {
+	case errors.Is(err, services.ErrTOTPInvalid):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid two-factor code",
+		})
+		return
+	case errors.Is(err, services.ErrTOTPNotEnrolled):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Start enrollment with POST /api/auth/2fa/enroll first",
+		})
+		return
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Status:  "error",
+			Message: "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	utils.RespondSuccessWithMessage(c, "Two-factor authentication enabled", nil)
+}
+
+// requireAdmin returns the current user if they are an administrator,
+// otherwise it writes 401 (no user) or 403 with forbidden and returns false
+func requireAdmin(c *gin.Context, forbidden string) (*models.User, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return nil, false
+	}
+
+	if user.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: forbidden,
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// sessionsAdminOnly rejects non-admins on the session management endpoints
+const sessionsAdminOnly = "Only administrators can manage sessions"
+
+// ListActiveSessions handles GET /api/admin/active-sessions - logged-in sessions across all users
+func (ah *AuthHandler) ListActiveSessions(c *gin.Context) {
+	if _, ok := requireAdmin(c, sessionsAdminOnly); !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	sessions, total, err := ah.authService.ListActiveSessions(models.ActiveSessionFilter{
+		Username: c.Query("username"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		ah.logger.Errorf("Failed to list active sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to list active sessions",
+		})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Device = utils.ParseUserAgent(sessions[i].UserAgent)
+	}
+
+	utils.RespondSuccess(c, sessions, models.NewPaginationMeta(total, limit, offset))
+}
+
+// RevokeActiveSession handles DELETE /api/admin/active-sessions/:session_id
+func (ah *AuthHandler) RevokeActiveSession(c *gin.Context) {
+	admin, ok := requireAdmin(c, sessionsAdminOnly)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := ah.authService.RevokeSession(sessionID); err != nil {
+		if errors.Is(err, services.ErrActiveSessionNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Active session not found",
+			})
+			return
+		}
+		ah.logger.Errorf("Failed to revoke session: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to revoke session",
+		})
+		return
+	}
+
+	if ah.activityLogService != nil {
+		adminID := admin.ID
+		ah.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &adminID,
+			Username:     admin.Username,
+			UserRole:     string(admin.Role),
+			ActionType:   models.ActionDelete,
+			ResourceType: models.ResourceAuth,
+			ResourceID:   sessionID,
+			Description:  "Revoked active session",
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, "Session revoked", nil)
+}
+
 // getRouterAccessForUser gets the list of routers accessible to a user role from database
 func (ah *AuthHandler) getRouterAccessForUser(userRole string) ([]string, error) {
 	// Get routers from database via RouterService
@@ -244,13 +513,19 @@ func (ah *AuthHandler) getRouterAccessForUser(userRole string) ([]string, error)
 // Priority: 1. User-specific routers (user_routers table), 2. Role-based (router_access_control)
 func (ah *AuthHandler) getRouterAccessForUserID(userID int, userRole string) ([]string, error) {
 	// Try to get user-specific router access from user_routers table
-	userRouterNames, err := ah.userService.GetUserRouters(userID)
+	userRouterNames, noAccess, err := ah.userService.GetUserRouterAccess(userID)
 	if err != nil {
 		ah.logger.Warnf("Failed to get user-specific routers for user ID %d: %v", userID, err)
 		// Fallback to role-based access
 		return ah.getRouterAccessForUser(userRole)
 	}
 
+	// Explicitly restricted to no routers: role access doesn't apply
+	if noAccess {
+		ah.logger.Debugf("User ID %d is restricted to no routers", userID)
+		return []string{}, nil
+	}
+
 	// If user has specific router assignments, use those
 	if len(userRouterNames) > 0 {
 		ah.logger.Debugf("User ID %d has access to %d user-specific routers", userID, len(userRouterNames))
@@ -382,7 +657,7 @@ func (ah *AuthHandler) RefreshToken(c *gin.Context) {
 				
 				// Update access token cookie
 				c.SetSameSite(http.SameSiteLaxMode)
-				c.SetCookie("access_token", tokenPair.AccessToken, 900, "/", domain, secure, httpOnly) // 15 minutes
+				c.SetCookie("access_token", tokenPair.AccessToken, utils.CookieMaxAge(tokenPair.AccessTokenExpiresAt), "/", domain, secure, httpOnly)
 				
 				ah.logger.Infof("🔄 Access token refreshed dan cookie updated")
 			}
@@ -404,10 +679,10 @@ func (ah *AuthHandler) GetJWTPublicKey(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":     "success",
+	key := gin.H{
 		"public_key": publicKey,
 		"algorithm":  "RS256",
-	})
+	}
+	utils.RespondSuccessWithLegacy(c, "", key, key)
 }
 
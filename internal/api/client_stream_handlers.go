@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// clientStreamWriteTimeout bounds writing one message to a WebSocket subscriber
+const clientStreamWriteTimeout = 10 * time.Second
+
+// ClientStreamHandler pushes online client changes over WebSocket
+type ClientStreamHandler struct {
+	clientWatcher *services.ClientWatcher
+	natService    services.NATServiceInterface
+	userService   *services.UserService
+	logger        *logrus.Logger
+}
+
+// NewClientStreamHandler creates a new client stream handler
+func NewClientStreamHandler(clientWatcher *services.ClientWatcher, natService services.NATServiceInterface, userService *services.UserService, logger *logrus.Logger) *ClientStreamHandler {
+	return &ClientStreamHandler{
+		clientWatcher: clientWatcher,
+		natService:    natService,
+		userService:   userService,
+		logger:        logger,
+	}
+}
+
+// StreamClients handles GET /ws/clients
+// Upgrades to a WebSocket that first sends the online clients of the routers
+// the user can access, then every connection, disconnection and IP change
+// on those routers as the client cache refreshes
+func (h *ClientStreamHandler) StreamClients(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	// The cookie is sent with cross-site handshakes too, so browsers must
+	// come from this host
+	if origin := c.GetHeader("Origin"); origin != "" {
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Host != c.Request.Host {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Status:  "error",
+				Message: "Origin not allowed",
+			})
+			return
+		}
+	}
+
+	routers, _, err := services.ResolveUserRouters(h.userService, h.natService, user)
+	if err != nil {
+		h.logger.Warnf("Failed to get user-specific routers for user ID %d: %v", user.ID, err)
+	}
+	allowed := make(map[string]bool, len(routers))
+	for _, name := range routers {
+		allowed[name] = true
+	}
+
+	server := websocket.Server{
+		// Origin was checked above
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			h.serveClientStream(ws, user.Username, allowed)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveClientStream writes the snapshot and then the changes until the
+// subscriber goes away or the watcher stops
+func (h *ClientStreamHandler) serveClientStream(ws *websocket.Conn, username string, allowed map[string]bool) {
+	changes, snapshot := h.clientWatcher.Subscribe()
+	defer h.clientWatcher.Unsubscribe(changes)
+
+	clients := make(map[string][]models.NATClient)
+	for routerName, routerClients := range snapshot {
+		if allowed[routerName] {
+			clients[routerName] = routerClients
+		}
+	}
+	if err := h.send(ws, models.ClientStreamMessage{Type: models.ClientStreamSnapshot, Clients: clients, Timestamp: time.Now()}); err != nil {
+		return
+	}
+
+	h.logger.Infof("📡 Client stream opened for %s (%d routers)", username, len(allowed))
+	defer h.logger.Infof("📡 Client stream closed for %s", username)
+
+	// Nothing is expected from the subscriber; reading only notices it leaving
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case batch, ok := <-changes:
+			if !ok {
+				return
+			}
+			visible := []models.ClientChange{}
+			for _, change := range batch {
+				if allowed[change.Router] {
+					visible = append(visible, change)
+				}
+			}
+			if len(visible) == 0 {
+				continue
+			}
+			if err := h.send(ws, models.ClientStreamMessage{Type: models.ClientStreamChanges, Changes: visible, Timestamp: time.Now()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes one JSON message, giving up after clientStreamWriteTimeout
+func (h *ClientStreamHandler) send(ws *websocket.Conn, message models.ClientStreamMessage) error {
+	ws.SetWriteDeadline(time.Now().Add(clientStreamWriteTimeout))
+	if err := websocket.JSON.Send(ws, message); err != nil {
+		h.logger.Debugf("Client stream write failed: %v", err)
+		return err
+	}
+	return nil
+}
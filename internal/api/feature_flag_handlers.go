@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// featureFlagNamePattern restricts flag names to lowercase snake_case
+var featureFlagNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,99}$`)
+
+// FeatureFlagHandler handles feature flag administration
+type FeatureFlagHandler struct {
+	flagService        *services.FeatureFlagService
+	activityLogService *services.ActivityLogService
+	logger             *logrus.Logger
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(flagService *services.FeatureFlagService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		flagService:        flagService,
+		activityLogService: activityLogService,
+		logger:             logger,
+	}
+}
+
+// featureFlagsAdminOnly is the 403 message of the feature flag endpoints
+const featureFlagsAdminOnly = "Only administrators can manage feature flags"
+
+// ListFlags handles GET /api/feature-flags
+func (h *FeatureFlagHandler) ListFlags(c *gin.Context) {
+	if _, ok := requireAdmin(c, featureFlagsAdminOnly); !ok {
+		return
+	}
+
+	flags, err := h.flagService.ListFlags()
+	if err != nil {
+		h.logger.Errorf("Failed to list feature flags: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve feature flags",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, flags, models.NewPaginationMeta(len(flags), 0, 0))
+}
+
+// UpdateFlag handles PUT /api/feature-flags/:name
+func (h *FeatureFlagHandler) UpdateFlag(c *gin.Context) {
+	user, ok := requireAdmin(c, featureFlagsAdminOnly)
+	if !ok {
+		return
+	}
+
+	name := c.Param("name")
+	if !featureFlagNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid feature flag name",
+		})
+		return
+	}
+
+	var req models.FeatureFlagUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid",
+		})
+		return
+	}
+
+	flag, err := h.flagService.SetFlag(name, *req.Enabled, req.Description, user.Username)
+	if err != nil {
+		h.logger.Errorf("Failed to update feature flag %s: %v", name, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to update feature flag",
+		})
+		return
+	}
+
+	if h.activityLogService != nil {
+		state := "disabled"
+		if flag.Enabled {
+			state = "enabled"
+		}
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionUpdate,
+			ResourceType: models.ResourceFeatureFlag,
+			ResourceID:   name,
+			Description:  "Feature flag " + name + " " + state,
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, "Feature flag updated", flag)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// deepHealthTimeout bounds the whole deep health check
+const deepHealthTimeout = 10 * time.Second
+
+// errHealthCheckSkipped marks a check that isn't configured
+var errHealthCheckSkipped = errors.New("health check skipped")
+
+// HealthHandler handles stack-wide health diagnostics
+type HealthHandler struct {
+	db            *database.DB
+	authService   *services.AuthServiceDB
+	routerService *services.RouterServiceDB
+	canaryRouter  string
+	checks        []healthCheck
+	logger        *logrus.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db *database.DB, authService *services.AuthServiceDB, routerService *services.RouterServiceDB, canaryRouter string, logger *logrus.Logger) *HealthHandler {
+	h := &HealthHandler{
+		db:            db,
+		authService:   authService,
+		routerService: routerService,
+		canaryRouter:  canaryRouter,
+		logger:        logger,
+	}
+	h.checks = []healthCheck{
+		{name: "database", critical: true, run: h.checkDatabase},
+		{name: "jwt", critical: true, run: h.checkJWT},
+		{name: "connection_pool", critical: false, run: h.checkPool},
+		{name: "canary_router", critical: false, run: h.checkCanaryRouter},
+	}
+	return h
+}
+
+// healthCheck is a single named component check
+type healthCheck struct {
+	name     string
+	critical bool // a failing critical component makes the whole stack unhealthy
+	run      func(ctx context.Context) (map[string]interface{}, error)
+}
+
+// DeepHealth handles GET /api/health/deep - Administrator only
+func (h *HealthHandler) DeepHealth(c *gin.Context) {
+	if _, ok := requireAdmin(c, "Only administrators can run deep health checks"); !ok {
+		return
+	}
+
+	report := h.runChecks(c.Request.Context())
+
+	statusCode := http.StatusOK
+	if report.Status == models.HealthStatusUnhealthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	c.JSON(statusCode, report)
+}
+
+// runChecks runs all component checks concurrently within deepHealthTimeout
+func (h *HealthHandler) runChecks(parent context.Context) *models.DeepHealthResponse {
+	ctx, cancel := context.WithTimeout(parent, deepHealthTimeout)
+	defer cancel()
+
+	checks := h.checks
+	start := time.Now()
+	components := make(map[string]models.ComponentHealth)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+			result := h.runCheck(ctx, check)
+			mu.Lock()
+			components[check.name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	overall := models.HealthStatusHealthy
+	for _, check := range checks {
+		if components[check.name].Status != models.HealthStatusUnhealthy {
+			continue
+		}
+		if check.critical {
+			overall = models.HealthStatusUnhealthy
+			break
+		}
+		overall = models.HealthStatusDegraded
+	}
+
+	if overall != models.HealthStatusHealthy {
+		h.logger.Warnf("⚠️ Deep health check: %s", overall)
+	}
+
+	return &models.DeepHealthResponse{
+		Status:     overall,
+		Components: components,
+		DurationMs: time.Since(start).Milliseconds(),
+		CheckedAt:  time.Now(),
+	}
+}
+
+// runCheck runs one check, reporting a timeout if it outlives the context
+func (h *HealthHandler) runCheck(ctx context.Context, check healthCheck) models.ComponentHealth {
+	type outcome struct {
+		details map[string]interface{}
+		err     error
+	}
+
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		details, err := check.run(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		result := models.ComponentHealth{
+			Status:    models.HealthStatusHealthy,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Details:   out.details,
+		}
+		if out.err == errHealthCheckSkipped {
+			result.Status = models.HealthStatusSkipped
+		} else if out.err != nil {
+			result.Status = models.HealthStatusUnhealthy
+			result.Message = out.err.Error()
+		}
+		return result
+	case <-ctx.Done():
+		return models.ComponentHealth{
+			Status:    models.HealthStatusUnhealthy,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Message:   "check timed out",
+		}
+	}
+}
+
+// checkDatabase pings PostgreSQL and reports pool usage
+func (h *HealthHandler) checkDatabase(ctx context.Context) (map[string]interface{}, error) {
+	if err := h.db.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("database ping failed: %w", err)
+	}
+	stat := h.db.Pool.Stat()
+	return map[string]interface{}{
+		"total_conns":    stat.TotalConns(),
+		"idle_conns":     stat.IdleConns(),
+		"acquired_conns": stat.AcquiredConns(),
+	}, nil
+}
+
+// checkJWT signs and validates a throwaway token
+func (h *HealthHandler) checkJWT(ctx context.Context) (map[string]interface{}, error) {
+	if err := h.authService.CheckJWTSigning(); err != nil {
+		return nil, fmt.Errorf("JWT sign/validate failed: %w", err)
+	}
+	return nil, nil
+}
+
+// checkPool reports RouterOS connection pool stats, with the same fields as
+// GET /api/routers/pool/stats
+func (h *HealthHandler) checkPool(ctx context.Context) (map[string]interface{}, error) {
+	data, err := json.Marshal(h.routerService.GetPoolStats())
+	if err != nil {
+		return nil, err
+	}
+	var details map[string]interface{}
+	if err := json.Unmarshal(data, &details); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// checkCanaryRouter tests the configured canary router, if any
+func (h *HealthHandler) checkCanaryRouter(ctx context.Context) (map[string]interface{}, error) {
+	if h.canaryRouter == "" {
+		return nil, errHealthCheckSkipped
+	}
+
+	result, err := h.routerService.TestRouterByName(h.canaryRouter)
+	if err != nil {
+		return nil, fmt.Errorf("canary router %s: %w", h.canaryRouter, err)
+	}
+
+	details := map[string]interface{}{"router": h.canaryRouter}
+	if result.Status != "connected" {
+		return details, fmt.Errorf("canary router %s: %s", h.canaryRouter, result.Message)
+	}
+	details["version"] = result.Version
+	return details, nil
+}
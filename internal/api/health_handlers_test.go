@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// healthyCheck stands in for a component that is up
+func healthyCheck(ctx context.Context) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// newTestHealthHandler returns a health handler whose database is db and
+// whose other components all report healthy
+func newTestHealthHandler(db *database.DB) *HealthHandler {
+	h := NewHealthHandler(db, nil, nil, "", testLogger())
+	for i := range h.checks {
+		if h.checks[i].name != "database" {
+			h.checks[i].run = healthyCheck
+		}
+	}
+	return h
+}
+
+func getDeepHealth(t *testing.T, h *HealthHandler) (int, models.DeepHealthResponse) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/health/deep", withUser(&models.User{ID: 1, Username: "admin", Role: models.RoleAdministrator}), h.DeepHealth)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/deep", nil))
+	var report models.DeepHealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	return w.Code, report
+}
+
+func TestDeepHealthHealthyStack(t *testing.T) {
+	h := newTestHealthHandler(nil)
+	for i := range h.checks {
+		h.checks[i].run = healthyCheck
+	}
+
+	code, report := getDeepHealth(t, h)
+	if code != http.StatusOK || report.Status != models.HealthStatusHealthy {
+		t.Fatalf("GET /api/health/deep = %d %s, want 200 healthy", code, report.Status)
+	}
+	for _, name := range []string{"database", "jwt", "connection_pool", "canary_router"} {
+		if got := report.Components[name].Status; got != models.HealthStatusHealthy {
+			t.Errorf("%s = %s, want healthy", name, got)
+		}
+	}
+}
+
+func TestDeepHealthDatabaseDown(t *testing.T) {
+	// Nothing listens on the port the pool points at
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	pool, err := pgxpool.New(context.Background(), "postgres://app:secret@"+addr+"/nat?connect_timeout=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	code, report := getDeepHealth(t, newTestHealthHandler(&database.DB{Pool: pool}))
+	if code != http.StatusServiceUnavailable || report.Status != models.HealthStatusUnhealthy {
+		t.Fatalf("GET /api/health/deep = %d %s, want 503 unhealthy", code, report.Status)
+	}
+	if db := report.Components["database"]; db.Status != models.HealthStatusUnhealthy || db.Message == "" {
+		t.Fatalf("database component = %+v, want unhealthy with the ping error", db)
+	}
+	if got := report.Components["jwt"].Status; got != models.HealthStatusHealthy {
+		t.Errorf("jwt = %s, want healthy next to the failed database", got)
+	}
+}
+
+func TestDeepHealthNonCriticalFailureDegrades(t *testing.T) {
+	h := newTestHealthHandler(nil)
+	for i := range h.checks {
+		h.checks[i].run = healthyCheck
+		if h.checks[i].name == "canary_router" {
+			h.checks[i].run = func(ctx context.Context) (map[string]interface{}, error) {
+				return nil, errHealthCheckSkipped
+			}
+		}
+		if h.checks[i].name == "connection_pool" {
+			h.checks[i].run = func(ctx context.Context) (map[string]interface{}, error) {
+				return nil, context.DeadlineExceeded
+			}
+		}
+	}
+
+	code, report := getDeepHealth(t, h)
+	if code != http.StatusOK || report.Status != models.HealthStatusDegraded {
+		t.Fatalf("GET /api/health/deep = %d %s, want 200 degraded", code, report.Status)
+	}
+	if got := report.Components["canary_router"].Status; got != models.HealthStatusSkipped {
+		t.Errorf("canary_router = %s, want skipped", got)
+	}
+}
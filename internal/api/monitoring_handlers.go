@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MonitoringHandler serves the background router health monitor results
+type MonitoringHandler struct {
+	healthMonitor *services.HealthMonitor
+	natService    services.NATServiceInterface
+	userService   *services.UserService
+	logger        *logrus.Logger
+}
+
+// NewMonitoringHandler creates a new monitoring handler
+func NewMonitoringHandler(healthMonitor *services.HealthMonitor, natService services.NATServiceInterface, userService *services.UserService, logger *logrus.Logger) *MonitoringHandler {
+	return &MonitoringHandler{
+		healthMonitor: healthMonitor,
+		natService:    natService,
+		userService:   userService,
+		logger:        logger,
+	}
+}
+
+// allowedRouters returns the router names the caller may see, or false after
+// answering 401 when there is no authenticated user
+func (h *MonitoringHandler) allowedRouters(c *gin.Context) (map[string]bool, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return nil, false
+	}
+
+	routers, source, err := services.ResolveUserRouters(h.userService, h.natService, user)
+	if err != nil {
+		h.logger.Warnf("Failed to get user-specific routers for user ID %d: %v", user.ID, err)
+	}
+	h.logger.Debugf("User ID %d has access to %d routers for monitoring (%s)", user.ID, len(routers), source)
+
+	allowed := make(map[string]bool, len(routers))
+	for _, name := range routers {
+		allowed[name] = true
+	}
+	return allowed, true
+}
+
+// GetAllHealth handles GET /api/monitoring/health
+// Returns the latest health check of every router the caller can access
+func (h *MonitoringHandler) GetAllHealth(c *gin.Context) {
+	allowed, ok := h.allowedRouters(c)
+	if !ok {
+		return
+	}
+
+	statuses := []*services.HealthStatus{}
+	for _, status := range h.healthMonitor.GetAllHealth() {
+		if allowed[status.RouterName] {
+			statuses = append(statuses, status)
+		}
+	}
+
+	utils.RespondSuccess(c, statuses)
+}
+
+// GetHealth handles GET /api/monitoring/health/:id
+// Returns the latest health check of one router
+func (h *MonitoringHandler) GetHealth(c *gin.Context) {
+	allowed, ok := h.allowedRouters(c)
+	if !ok {
+		return
+	}
+
+	status, found := h.healthMonitor.GetHealth(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "No health data for this router yet",
+		})
+		return
+	}
+
+	if !allowed[status.RouterName] {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Access denied to this router",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, status)
+}
+
+// maxHealthHistoryRange bounds the history range (and so the response size)
+const maxHealthHistoryRange = 31 * 24 * time.Hour
+
+// GetHealthHistory handles GET /api/monitoring/history/:id?from=&to=
+// Returns the recorded health checks of one router, oldest first
+func (h *MonitoringHandler) GetHealthHistory(c *gin.Context) {
+	allowed, ok := h.allowedRouters(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseHealthHistoryRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	history, err := h.healthMonitor.GetHistory(c.Request.Context(), c.Param("id"), from, to)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "router not found") {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+			return
+		}
+		h.logger.Errorf("Failed to get health history for router %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to get health history",
+		})
+		return
+	}
+
+	if !allowed[history.RouterName] {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Access denied to this router",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, history)
+}
+
+// parseHealthHistoryRange validates the from/to query values, given as RFC3339
+// timestamps or YYYY-MM-DD dates. to defaults to now and from to 24 hours
+// before to.
+func parseHealthHistoryRange(fromParam, toParam string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toParam != "" {
+		parsed, err := parseTimeOrDate(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid to, expected RFC3339 or YYYY-MM-DD")
+		}
+		to = parsed
+		if len(toParam) == len("2006-01-02") {
+			// A plain date includes the whole day
+			to = to.Add(24*time.Hour - time.Nanosecond)
+		}
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if fromParam != "" {
+		parsed, err := parseTimeOrDate(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid from, expected RFC3339 or YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > maxHealthHistoryRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("Range too long (max %d days)", int(maxHealthHistoryRange.Hours()/24))
+	}
+
+	return from, to, nil
+}
+
+// parseTimeOrDate accepts an RFC3339 timestamp or a YYYY-MM-DD date (local midnight)
+func parseTimeOrDate(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
@@ -1,12 +1,22 @@
 package api
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"nat-management-app/internal/middleware"
 	"nat-management-app/internal/models"
 	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -14,22 +24,84 @@ import (
 
 // NATHandler contains the NAT API handlers
 type NATHandler struct {
-	natService         *services.NATService
-	userService        *services.UserService // Added for user-specific router access
+	natService         services.NATServiceInterface
+	userService        services.UserAccessLookup // User-specific router access
 	activityLogService *services.ActivityLogService
+	customerDirectory  services.CustomerLookup // Optional, resolves PPPoE usernames to customers
 	logger             *logrus.Logger
 }
 
 // NewNATHandler creates a new NAT API handler
-func NewNATHandler(natService *services.NATService, userService *services.UserService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *NATHandler {
+func NewNATHandler(natService services.NATServiceInterface, userService services.UserAccessLookup, activityLogService *services.ActivityLogService, customerDirectory services.CustomerLookup, logger *logrus.Logger) *NATHandler {
 	return &NATHandler{
 		natService:         natService,
 		userService:        userService,
 		activityLogService: activityLogService,
+		customerDirectory:  customerDirectory,
 		logger:             logger,
 	}
 }
 
+// scopedRoutersForUser narrows the user's accessible routers to ?scope= (all,
+// assigned, first), defaulting to the role's configured scope. Writes a 400 on
+// an unknown scope.
+func (h *NATHandler) scopedRoutersForUser(c *gin.Context) ([]string, models.RouterScope, bool) {
+	allowedRouters := h.getAllowedRoutersForUser(c)
+
+	scope := models.RouterScope(c.Query("scope"))
+	if scope == "" {
+		userRole, _ := middleware.GetUserRoleFromContext(c)
+		scope = models.DefaultRouterScope(userRole)
+	}
+	if !scope.IsValid() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Scope tidak valid (gunakan: all, assigned, first)",
+		})
+		return nil, scope, false
+	}
+
+	if scope == models.ScopeAll {
+		return allowedRouters, scope, true
+	}
+
+	// Assigned: explicit user_routers assignments within the accessible set
+	scoped := allowedRouters
+	if user, exists := middleware.GetUserFromContext(c); exists {
+		if assigned, err := h.userService.GetUserRouters(user.ID); err == nil && len(assigned) > 0 {
+			accessible := make(map[string]bool, len(allowedRouters))
+			for _, name := range allowedRouters {
+				accessible[name] = true
+			}
+			scoped = []string{}
+			for _, name := range assigned {
+				if accessible[name] {
+					scoped = append(scoped, name)
+				}
+			}
+		}
+	}
+
+	if scope == models.ScopeFirst && len(scoped) > 1 {
+		sorted := append([]string(nil), scoped...)
+		sort.Strings(sorted)
+		scoped = sorted[:1]
+	}
+
+	return scoped, scope, true
+}
+
+// configuredRouters drops router names the NAT service doesn't know about
+func (h *NATHandler) configuredRouters(routerNames []string) []string {
+	configured := make([]string, 0, len(routerNames))
+	for _, name := range routerNames {
+		if h.natService.HasRouter(name) {
+			configured = append(configured, name)
+		}
+	}
+	return configured
+}
+
 // getAllowedRoutersForUser gets allowed routers for a user
 // Priority: 1. User-specific routers (user_routers table), 2. Role-based (router_access_control)
 func (h *NATHandler) getAllowedRoutersForUser(c *gin.Context) []string {
@@ -44,23 +116,13 @@ func (h *NATHandler) getAllowedRoutersForUser(c *gin.Context) []string {
 		return []string{}
 	}
 
-	// Try to get user-specific routers from user_routers table
-	userRouterNames, err := h.userService.GetUserRouters(user.ID)
+	// User-specific routers from user_routers, falling back to role-based access
+	routers, source, err := services.ResolveUserRouters(h.userService, h.natService, user)
 	if err != nil {
 		h.logger.Warnf("Failed to get user-specific routers for user ID %d: %v", user.ID, err)
-		// Fallback to role-based access
-		return h.natService.GetAvailableRoutersWithFilter(models.GetRoleForRouterAccess(user.Role))
-	}
-
-	// If user has specific router assignments, use those
-	if len(userRouterNames) > 0 {
-		h.logger.Debugf("User ID %d has access to %d user-specific routers for NAT operations", user.ID, len(userRouterNames))
-		return userRouterNames
 	}
-
-	// Fallback to role-based access if no user-specific routers
-	h.logger.Debugf("User ID %d has no specific routers, falling back to role-based access for NAT", user.ID)
-	return h.natService.GetAvailableRoutersWithFilter(models.GetRoleForRouterAccess(user.Role))
+	h.logger.Debugf("User ID %d has access to %d routers for NAT operations (%s)", user.ID, len(routers), source)
+	return routers
 }
 
 // GetNATConfigs handles GET /api/nat/configs
@@ -75,11 +137,22 @@ func (h *NATHandler) GetNATConfigs(c *gin.Context) {
 		return
 	}
 
-	// Get all configs
-	allConfigs := h.natService.GetAllONTConfigs()
+	// Filter configs based on user-specific or role-based router access,
+	// narrowed to the requested (or role default) scope
+	allowedRouters, scope, ok := h.scopedRoutersForUser(c)
+	if !ok {
+		return
+	}
 
-	// Filter configs based on user-specific or role-based router access
-	allowedRouters := h.getAllowedRoutersForUser(c)
+	var allConfigs map[string]models.ONTConfig
+	if c.Query("fresh") == "true" {
+		// Targeted re-check: bypass the cache for the routers in scope
+		allConfigs = h.natService.RefreshONTConfigs(allowedRouters)
+	} else if scope == models.ScopeAll {
+		allConfigs = h.natService.GetAllONTConfigs()
+	} else {
+		allConfigs = h.natService.GetONTConfigsForRouters(allowedRouters)
+	}
 	filteredConfigs := make(map[string]models.ONTConfig)
 	
 	for routerName, config := range allConfigs {
@@ -105,6 +178,114 @@ func (h *NATHandler) GetNATConfigs(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetNATConflicts handles GET /api/nat/conflicts
+// Reports ONT rules on different routers that target the same IP
+func (h *NATHandler) GetNATConflicts(c *gin.Context) {
+	// Get user role from context (for authentication check)
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	// Only scan routers the user can access
+	allowedRouters := h.getAllowedRoutersForUser(c)
+	conflicts, scanned := h.natService.FindNATTargetConflicts(allowedRouters)
+
+	c.JSON(http.StatusOK, models.NATConflictsResponse{
+		Status:         "success",
+		Data:           conflicts,
+		RoutersScanned: scanned,
+	})
+}
+
+// GetNATRules handles GET /api/nat/rules?router=&comment=
+// Lists the router's dst-nat rules, or only the one whose comment equals comment
+func (h *NATHandler) GetNATRules(c *gin.Context) {
+	// Get user role from context (for authentication check)
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	routerName := c.Query("router")
+	if routerName == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Parameter router wajib diisi",
+		})
+		return
+	}
+
+	// Check if user has access to this router
+	hasAccess := false
+	for _, allowed := range h.getAllowedRoutersForUser(c) {
+		if routerName == allowed {
+			hasAccess = true
+			break
+		}
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Tidak memiliki akses ke router ini",
+		})
+		return
+	}
+	if !h.natService.HasRouter(routerName) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router tidak ditemukan",
+		})
+		return
+	}
+
+	var rules []models.ONTNATRule
+	var err error
+	if comment, filtered := c.GetQuery("comment"); filtered {
+		var rule *models.ONTNATRule
+		rule, err = h.natService.GetNATRuleByComment(routerName, comment)
+		if err == nil {
+			rules = []models.ONTNATRule{*rule}
+		}
+	} else {
+		rules, err = h.natService.ListNATRules(routerName)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrRouterBusy) {
+			utils.RespondRouterBusy(c, routerName)
+			return
+		}
+		if errors.Is(err, services.ErrNATRuleNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "NAT rule tidak ditemukan",
+			})
+			return
+		}
+		h.logger.Errorf("Failed to list NAT rules for %s: %v", routerName, err)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Status:  "error",
+			Message: "Gagal mengambil NAT rules: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NATRulesResponse{
+		Status: "success",
+		Router: routerName,
+		Data:   rules,
+		Total:  len(rules),
+	})
+}
+
 // GetNATClients handles GET /api/nat/clients
 func (h *NATHandler) GetNATClients(c *gin.Context) {
 	// Get user role from context (for authentication check)
@@ -117,38 +298,305 @@ func (h *NATHandler) GetNATClients(c *gin.Context) {
 		return
 	}
 
-	// Get all clients
-	allClients := h.natService.GetAllClients()
+	// Filter clients based on user-specific or role-based router access,
+	// narrowed to the requested (or role default) scope
+	allowedRouters, scope, ok := h.scopedRoutersForUser(c)
+	if !ok {
+		return
+	}
+
+	sortBy, descending, ok := clientSortFromQuery(c)
+	if !ok {
+		return
+	}
+
+	if wantsNDJSON(c) {
+		h.streamNATClients(c, allowedRouters, sortBy, descending)
+		return
+	}
+
+	filteredClients, routerStatus, filteredErrors := h.fetchScopedClients(c, allowedRouters, scope)
+
+	if c.Query("enrich") == "true" {
+		filteredClients = h.enrichClients(filteredClients)
+	}
+	for routerName, clients := range filteredClients {
+		filteredClients[routerName] = sortedClients(clients, sortBy, descending)
+	}
+
+	response := models.NATClientsResponse{
+		Status:       "success",
+		Data:         filteredClients,
+		RouterStatus: routerStatus,
+		Errors:       filteredErrors,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetNATClientsSummary handles GET /api/nat/clients/summary
+// Returns online client counts per router and in total, without the client
+// lists. Uses the same cached data and ?scope= as GET /api/nat/clients.
+func (h *NATHandler) GetNATClientsSummary(c *gin.Context) {
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	allowedRouters, scope, ok := h.scopedRoutersForUser(c)
+	if !ok {
+		return
+	}
+
+	clients, routerStatus, fetchErrors := h.fetchScopedClients(c, allowedRouters, scope)
+
+	counts := make(map[string]int, len(clients))
+	total := 0
+	for routerName, routerClients := range clients {
+		counts[routerName] = len(routerClients)
+		total += len(routerClients)
+	}
+
+	c.JSON(http.StatusOK, models.NATClientsSummaryResponse{
+		Status:       "success",
+		Data:         counts,
+		Total:        total,
+		RouterStatus: routerStatus,
+		Errors:       fetchErrors,
+	})
+}
+
+// ExportNATClients handles GET /api/nat/clients/export?format=csv
+// Streams the online clients of every router the user can access (narrowed by
+// ?scope= like GET /api/nat/clients) as a CSV download, ordered by router and
+// then by ?sort=/?order=
+func (h *NATHandler) ExportNATClients(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Unsupported export format: " + format,
+		})
+		return
+	}
+
+	allowedRouters, scope, ok := h.scopedRoutersForUser(c)
+	if !ok {
+		return
+	}
+
+	sortBy, descending, ok := clientSortFromQuery(c)
+	if !ok {
+		return
+	}
+
+	clients, _, fetchErrors := h.fetchScopedClients(c, allowedRouters, scope)
+	for routerName, errMsg := range fetchErrors {
+		h.logger.Warnf("⚠️ Client export for %s skips router %s: %s", user.Username, routerName, errMsg)
+	}
+
+	routerNames := make([]string, 0, len(clients))
+	for routerName := range clients {
+		routerNames = append(routerNames, routerName)
+	}
+	sort.Strings(routerNames)
+
+	filename := fmt.Sprintf("online-clients-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"router", "username", "ip_address", "caller_id", "uptime"})
+	rowCount := 0
+	for _, routerName := range routerNames {
+		for _, client := range sortedClients(clients[routerName], sortBy, descending) {
+			writer.Write(utils.CSVSafeRow(client.Router, client.Username, client.IPAddress, client.CallerID, client.Uptime))
+			rowCount++
+		}
+		writer.Flush()
+	}
+	exportErr := writer.Error()
+	if exportErr != nil {
+		// Headers are already sent, the download just ends early
+		h.logger.Errorf("Client export failed after %d rows: %v", rowCount, exportErr)
+	} else {
+		h.logger.Infof("📤 Online client export by %s: %d rows from %d routers", user.Username, rowCount, len(routerNames))
+	}
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		logEntry := &models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionExport,
+			ResourceType: models.ResourcePPPoE,
+			ResourceID:   "clients",
+			Description:  fmt.Sprintf("Exported %d online clients from %d routers", rowCount, len(routerNames)),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		}
+		if exportErr != nil {
+			logEntry.Status = models.StatusFailed
+			logEntry.ErrorMessage = exportErr.Error()
+		}
+		h.activityLogService.CreateLog(logEntry)
+	}
+}
+
+// fetchScopedClients returns the online clients of the routers in
+// allowedRouters (all configured routers come from the cache when scope is
+// all), with each router's fetch status and the errors of failed routers
+func (h *NATHandler) fetchScopedClients(c *gin.Context, allowedRouters []string, scope models.RouterScope) (map[string][]models.NATClient, map[string]string, map[string]string) {
+	var allClients map[string][]models.NATClient
+	var fetchErrors map[string]string
+	if scope == models.ScopeAll {
+		allClients, fetchErrors = h.natService.GetAllClients()
+	} else {
+		allClients, fetchErrors = h.natService.GetClientsForRouters(c.Request.Context(), h.configuredRouters(allowedRouters))
+	}
+
+	allowed := make(map[string]bool, len(allowedRouters))
+	for _, name := range allowedRouters {
+		allowed[name] = true
+	}
 
-	// Filter clients based on user-specific or role-based router access
-	allowedRouters := h.getAllowedRoutersForUser(c)
 	filteredClients := make(map[string][]models.NATClient)
-	
+	routerStatus := make(map[string]string)
+	filteredErrors := make(map[string]string)
 	for routerName, clients := range allClients {
-		// Check if user has access to this router
-		hasAccess := false
-		for _, allowed := range allowedRouters {
-			if routerName == allowed {
-				hasAccess = true
-				break
-			}
+		if !allowed[routerName] {
+			continue
 		}
-		
-		if hasAccess {
-			filteredClients[routerName] = clients
+		filteredClients[routerName] = clients
+		routerStatus[routerName] = models.RouterFetchOK
+		if errMsg, failed := fetchErrors[routerName]; failed {
+			routerStatus[routerName] = models.RouterFetchError
+			filteredErrors[routerName] = errMsg
 		}
 	}
 
-	response := models.NATClientsResponse{
-		Status: "success",
-		Data:   filteredClients,
+	return filteredClients, routerStatus, filteredErrors
+}
+
+// clientSortFromQuery reads ?sort=username|uptime|ip and ?order=asc|desc.
+// It writes a 400 response itself when either is invalid.
+func clientSortFromQuery(c *gin.Context) (string, bool, bool) {
+	sortBy := c.DefaultQuery("sort", models.ClientSortUsername)
+	if !models.IsValidClientSort(sortBy) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid sort: use username, uptime or ip",
+		})
+		return "", false, false
 	}
 
-	c.JSON(http.StatusOK, response)
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid order: use asc or desc",
+		})
+		return "", false, false
+	}
+
+	return sortBy, order == "desc", true
 }
 
-// UpdateNATRule handles POST /api/nat/update
-func (h *NATHandler) UpdateNATRule(c *gin.Context) {
+// sortedClients returns a sorted copy; the input may be a cached slice shared
+// with other requests and must not be reordered in place.
+func sortedClients(clients []models.NATClient, sortBy string, descending bool) []models.NATClient {
+	sorted := append([]models.NATClient(nil), clients...)
+	services.SortNATClients(sorted, sortBy, descending)
+	return sorted
+}
+
+// enrichClients attaches customer name/address from the customer directory.
+// Returns copies so the cached client slices are never modified; on lookup
+// failure the clients are returned unenriched.
+func (h *NATHandler) enrichClients(clientsByRouter map[string][]models.NATClient) map[string][]models.NATClient {
+	if h.customerDirectory == nil {
+		return clientsByRouter
+	}
+
+	usernames := []string{}
+	for _, clients := range clientsByRouter {
+		for _, client := range clients {
+			usernames = append(usernames, client.Username)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	customers := h.customerDirectory.ResolveMany(ctx, usernames)
+	if len(customers) == 0 {
+		return clientsByRouter
+	}
+
+	enriched := make(map[string][]models.NATClient, len(clientsByRouter))
+	for routerName, clients := range clientsByRouter {
+		copied := make([]models.NATClient, len(clients))
+		copy(copied, clients)
+		for i := range copied {
+			if customer, ok := customers[copied[i].Username]; ok {
+				copied[i].CustomerName = customer.FullName
+				copied[i].Address = customer.Address
+			}
+		}
+		enriched[routerName] = copied
+	}
+
+	return enriched
+}
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON
+func wantsNDJSON(c *gin.Context) bool {
+	return c.Query("format") == "ndjson" || strings.Contains(c.GetHeader("Accept"), "application/x-ndjson")
+}
+
+// streamNATClients writes one JSON line per router as the fan-out produces it
+func (h *NATHandler) streamNATClients(c *gin.Context, allowedRouters []string, sortBy string, descending bool) {
+	routerNames := h.configuredRouters(allowedRouters)
+
+	enrich := c.Query("enrich") == "true"
+	results := h.natService.StreamClients(c.Request.Context(), routerNames)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	for result := range results {
+		if enrich {
+			result.Clients = h.enrichClients(map[string][]models.NATClient{result.Router: result.Clients})[result.Router]
+		}
+		result.Clients = sortedClients(result.Clients, sortBy, descending)
+		if err := enc.Encode(result); err != nil {
+			h.logger.Warnf("⚠️ NDJSON client stream aborted: %v", err)
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// bindNATUpdateRequest parses a NAT update request, enforces router access and
+// fills in the default port. It writes the error response itself on failure.
+func (h *NATHandler) bindNATUpdateRequest(c *gin.Context) (*models.NATUpdateRequest, bool) {
 	// Get user role from context (for authentication check)
 	_, exists := middleware.GetUserRoleFromContext(c)
 	if !exists {
@@ -156,7 +604,7 @@ func (h *NATHandler) UpdateNATRule(c *gin.Context) {
 			Status:  "error",
 			Message: "Authentication required",
 		})
-		return
+		return nil, false
 	}
 
 	var req models.NATUpdateRequest
@@ -165,7 +613,7 @@ func (h *NATHandler) UpdateNATRule(c *gin.Context) {
 			Status:  "error",
 			Message: "Format request tidak valid",
 		})
-		return
+		return nil, false
 	}
 
 	// Validate required fields
@@ -174,7 +622,7 @@ func (h *NATHandler) UpdateNATRule(c *gin.Context) {
 			Status:  "error",
 			Message: "Router name dan IP address wajib diisi",
 		})
-		return
+		return nil, false
 	}
 
 	// Check if user has access to this router
@@ -192,51 +640,380 @@ func (h *NATHandler) UpdateNATRule(c *gin.Context) {
 			Status:  "error",
 			Message: "Tidak memiliki akses ke router ini",
 		})
-		return
+		return nil, false
 	}
 
-	// Default port if not provided
+	// Default port if not provided (router's configured ONT port, then 80)
 	if req.Port == "" {
-		req.Port = "80"
+		req.Port = h.natService.GetDefaultONTPort(req.Router)
 	}
 
+	return &req, true
+}
+
+// UpdateNATRule handles POST /api/nat/update
+func (h *NATHandler) UpdateNATRule(c *gin.Context) {
+	req, ok := h.bindNATUpdateRequest(c)
+	if !ok {
+		return
+	}
+
+	c.Set("router_name", req.Router)
+	log := middleware.GetRequestLogger(c)
+
 	// Update NAT rule
-	err := h.natService.UpdateONTNATRule(req.Router, req.IP, req.Port)
+	previous, err := h.natService.UpdateONTNATRule(middleware.RequestContext(c), req)
 	if err != nil {
-		h.logger.Errorf("Failed to update NAT rule for %s: %v", req.Router, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		respondNATUpdateError(c, log, req, err)
+		return
+	}
+
+	// Log NAT update with the previous and new target
+	if h.activityLogService != nil {
+		before, after := natRuleTargets(previous, req)
+		utils.NewActivityLogger(h.activityLogService, c).
+			SetAction(models.ActionNATUpdate, models.ResourceNATRule, req.Router,
+				fmt.Sprintf("Updated NAT rule for %s from %s:%s to %s:%s%s", req.Router, before.ToAddresses, before.ToPorts, req.IP, req.Port, natRuleExtras(req))).
+			AddBeforeState(before).
+			AddAfterState(after).
+			LogSuccess()
+	}
+
+	log.Infof("NAT rule for %s updated to %s:%s", req.Router, req.IP, req.Port)
+
+	response := models.NATUpdateResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("NAT rule untuk %s berhasil diupdate ke %s:%s", req.Router, req.IP, req.Port),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondNATUpdateError maps an UpdateONTNATRule error to an HTTP response
+func respondNATUpdateError(c *gin.Context, log *logrus.Entry, req *models.NATUpdateRequest, err error) {
+	var conflict *services.NATRuleConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, models.NATUpdateConflictResponse{
+			Status:      "error",
+			Message:     fmt.Sprintf("NAT rule untuk %s sudah diubah ke %s:%s oleh update lain", req.Router, conflict.CurrentIP, conflict.CurrentPort),
+			CurrentIP:   conflict.CurrentIP,
+			CurrentPort: conflict.CurrentPort,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrRouterBusy) {
+		log.Warnf("NAT rule update for %s throttled: %v", req.Router, err)
+		utils.RespondRouterBusy(c, req.Router)
+		return
+	}
+
+	log.Errorf("Failed to update NAT rule for %s: %v", req.Router, err)
+	statusCode := http.StatusInternalServerError
+	if strings.HasPrefix(err.Error(), "invalid ") {
+		statusCode = http.StatusBadRequest
+	}
+	c.JSON(statusCode, models.ErrorResponse{
+		Status:  "error",
+		Message: err.Error(),
+	})
+}
+
+// ToggleNATRule handles POST /api/nat/toggle
+// Enables or disables the router's ONT NAT rule, keeping its target
+func (h *NATHandler) ToggleNATRule(c *gin.Context) {
+	// Get user role from context (for authentication check)
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.NATToggleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid (router dan enabled wajib diisi)",
+		})
+		return
+	}
+
+	// Check if user has access to this router
+	hasAccess := false
+	for _, allowed := range h.getAllowedRoutersForUser(c) {
+		if req.Router == allowed {
+			hasAccess = true
+			break
+		}
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Tidak memiliki akses ke router ini",
+		})
+		return
+	}
+
+	if !h.natService.HasRouter(req.Router) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router tidak ditemukan",
+		})
+		return
+	}
+
+	c.Set("router_name", req.Router)
+	log := middleware.GetRequestLogger(c)
+
+	enabled := *req.Enabled
+	state, stateID := "disabled", "dinonaktifkan"
+	if enabled {
+		state, stateID = "enabled", "diaktifkan"
+	}
+
+	if err := h.natService.SetNATRuleEnabled(middleware.RequestContext(c), req.Router, enabled); err != nil {
+		if errors.Is(err, services.ErrRouterBusy) {
+			log.Warnf("NAT rule toggle for %s throttled: %v", req.Router, err)
+			utils.RespondRouterBusy(c, req.Router)
+			return
+		}
+		log.Errorf("Failed to set NAT rule for %s %s: %v", req.Router, state, err)
+		c.JSON(natRuleErrorStatus(err), models.ErrorResponse{
 			Status:  "error",
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Log NAT update
 	if h.activityLogService != nil {
-		user, exists := middleware.GetUserFromContext(c)
-		if exists {
-			userID := user.ID
-			h.activityLogService.CreateLog(&models.ActivityLogCreate{
-				UserID:       &userID,
-				Username:     user.Username,
-				UserRole:     string(user.Role),
-				ActionType:   models.ActionNATUpdate,
-				ResourceType: models.ResourceNATRule,
-				ResourceID:   req.Router,
-				Description:  fmt.Sprintf("Updated NAT rule for %s to %s:%s", req.Router, req.IP, req.Port),
-				IPAddress:    c.ClientIP(),
-				UserAgent:    c.GetHeader("User-Agent"),
-				Status:       models.StatusSuccess,
-			})
+		utils.NewActivityLogger(h.activityLogService, c).
+			SetAction(models.ActionNATToggle, models.ResourceNATRule, req.Router,
+				fmt.Sprintf("ONT NAT rule for %s %s", req.Router, state)).
+			AddMetadata("enabled", enabled).
+			LogSuccess()
+	}
+
+	utils.RespondSuccessWithMessage(c, fmt.Sprintf("NAT rule untuk %s berhasil %s", req.Router, stateID), gin.H{
+		"router":  req.Router,
+		"enabled": enabled,
+	})
+}
+
+// RollbackNATRule handles POST /api/nat/rollback
+// Restores the ONT NAT rule target recorded before the router's last NAT update
+func (h *NATHandler) RollbackNATRule(c *gin.Context) {
+	if _, exists := middleware.GetUserFromContext(c); !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.NATRollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router name wajib diisi",
+		})
+		return
+	}
+
+	// Check if user has access to this router
+	hasAccess := false
+	for _, allowed := range h.getAllowedRoutersForUser(c) {
+		if req.Router == allowed {
+			hasAccess = true
+			break
 		}
 	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Tidak memiliki akses ke router ini",
+		})
+		return
+	}
 
-	response := models.NATUpdateResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("NAT rule untuk %s berhasil diupdate ke %s:%s", req.Router, req.IP, req.Port),
+	// The previous state only exists in the activity log
+	if h.activityLogService == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Status:  "error",
+			Message: "Activity log tidak tersedia, rollback tidak dapat dilakukan",
+		})
+		return
+	}
+
+	c.Set("router_name", req.Router)
+	log := middleware.GetRequestLogger(c)
+
+	noHistory := models.ErrorResponse{
+		Status:  "error",
+		Message: fmt.Sprintf("Tidak ada perubahan NAT sebelumnya yang tercatat untuk router %s", req.Router),
+	}
+	entry, err := h.activityLogService.GetLatestNATRuleUpdate(req.Router)
+	if errors.Is(err, services.ErrNoNATRuleHistory) {
+		c.JSON(http.StatusNotFound, noHistory)
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to look up last NAT update for %s: %v", req.Router, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Gagal membaca riwayat perubahan NAT",
+		})
+		return
+	}
+
+	previous, ok := natRuleTargetFromMetadata(entry.Metadata["before"])
+	if !ok {
+		c.JSON(http.StatusNotFound, noHistory)
+		return
+	}
+
+	current, err := h.natService.GetONTNATRule(req.Router)
+	if errors.Is(err, services.ErrRouterBusy) {
+		utils.RespondRouterBusy(c, req.Router)
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to read NAT rule for %s before rollback: %v", req.Router, err)
+		c.JSON(natRuleErrorStatus(err), models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Gagal membaca NAT rule %s: %v", req.Router, err),
+		})
+		return
+	}
+
+	if current.ToAddresses == previous.ToAddresses && current.ToPorts == previous.ToPorts {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("NAT rule untuk %s sudah mengarah ke %s:%s, tidak ada yang perlu di-rollback", req.Router, previous.ToAddresses, previous.ToPorts),
+		})
+		return
+	}
+
+	// ExpectedIP guards against another update landing between the read and the write
+	update := &models.NATUpdateRequest{
+		Router:     req.Router,
+		IP:         previous.ToAddresses,
+		Port:       previous.ToPorts,
+		ExpectedIP: current.ToAddresses,
+	}
+	replaced, err := h.natService.UpdateONTNATRule(middleware.RequestContext(c), update)
+	if err != nil {
+		respondNATUpdateError(c, log, update, err)
+		return
+	}
+
+	before, after := natRuleTargets(replaced, update)
+	utils.NewActivityLogger(h.activityLogService, c).
+		SetAction(models.ActionNATRollback, models.ResourceNATRule, req.Router,
+			fmt.Sprintf("Rolled back NAT rule for %s from %s:%s to %s:%s (log #%d)", req.Router, before.ToAddresses, before.ToPorts, after.ToAddresses, after.ToPorts, entry.ID)).
+		AddBeforeState(before).
+		AddAfterState(after).
+		AddMetadata("rolled_back_log_id", entry.ID).
+		LogSuccess()
+
+	log.Infof("NAT rule for %s rolled back to %s:%s (log #%d)", req.Router, after.ToAddresses, after.ToPorts, entry.ID)
+	utils.RespondSuccessWithMessage(c, fmt.Sprintf("NAT rule untuk %s dikembalikan ke %s:%s", req.Router, after.ToAddresses, after.ToPorts), models.NATRollbackResult{
+		Router: req.Router,
+		LogID:  entry.ID,
+		Before: before,
+		After:  after,
+	})
+}
+
+// natRuleErrorStatus maps a failed NAT rule read or write to an HTTP status:
+// 404 when the router has no such rule, 502 when the router didn't answer.
+// ErrRouterBusy is answered with utils.RespondRouterBusy before this.
+func natRuleErrorStatus(err error) int {
+	if errors.Is(err, services.ErrNATRuleNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+// natRuleTargetFromMetadata decodes a before/after state stored in activity log metadata
+func natRuleTargetFromMetadata(state interface{}) (models.NATRuleTarget, bool) {
+	var target models.NATRuleTarget
+	if state == nil {
+		return target, false
+	}
+	data, err := json.Marshal(state)
+	if err != nil || json.Unmarshal(data, &target) != nil {
+		return target, false
+	}
+	return target, target.ToAddresses != "" && target.ToPorts != ""
+}
+
+// natRuleTargets returns the rule target before and after req was applied
+func natRuleTargets(previous *models.ONTNATRule, req *models.NATUpdateRequest) (models.NATRuleTarget, models.NATRuleTarget) {
+	var before models.NATRuleTarget
+	if previous != nil {
+		before = models.NATRuleTarget{
+			ToAddresses: previous.ToAddresses,
+			ToPorts:     previous.ToPorts,
+			Protocol:    previous.Protocol,
+			Comment:     previous.Comment,
+		}
+	}
+
+	after := before
+	after.ToAddresses = req.IP
+	after.ToPorts = req.Port
+	if req.Protocol != "" {
+		after.Protocol = strings.ToLower(req.Protocol)
+	}
+	if req.Comment != "" {
+		after.Comment = req.Comment
+	}
+	return before, after
+}
+
+// natRuleExtras describes the optional protocol/comment part of an update for logs
+func natRuleExtras(req *models.NATUpdateRequest) string {
+	extras := ""
+	if req.Protocol != "" {
+		extras += " protocol=" + req.Protocol
+	}
+	if req.Comment != "" {
+		extras += fmt.Sprintf(" comment=%q", req.Comment)
+	}
+	return extras
+}
+
+// PreviewNATUpdate handles POST /api/nat/update/preview
+// Shows the rule as it would look after the update without touching the router
+func (h *NATHandler) PreviewNATUpdate(c *gin.Context) {
+	req, ok := h.bindNATUpdateRequest(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.natService.PreviewONTNATRuleUpdate(req)
+	if errors.Is(err, services.ErrRouterBusy) {
+		utils.RespondRouterBusy(c, req.Router)
+		return
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, models.NATUpdatePreviewResponse{
+		Status: "success",
+		Data:   preview,
+	})
 }
 
 // TestNATConnections handles GET /api/nat/test
@@ -331,13 +1108,50 @@ func (h *NATHandler) GetNATStatus(c *gin.Context) {
 		healthStatus = "partial"
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":        "success",
+	status := gin.H{
 		"health":        healthStatus,
 		"total_routers": totalRouters,
 		"configured":    foundCount,
-		"message":       fmt.Sprintf("%d/%d router memiliki ONT NAT rules yang terkonfigurasi", foundCount, totalRouters),
-	})
+	}
+	utils.RespondSuccessWithLegacy(c, fmt.Sprintf("%d/%d router memiliki ONT NAT rules yang terkonfigurasi", foundCount, totalRouters), status, status)
+}
+
+// Overall deadlines for a PPPoE status check. Routers that haven't answered by
+// then are reported as timed out instead of delaying the whole response.
+const (
+	pppoeCheckTimeout             = 10 * time.Second
+	pppoeConnectivityCheckTimeout = 20 * time.Second // Device port probes take extra time
+)
+
+// InvalidateNATCache handles POST /api/nat/cache/invalidate (Administrator only)
+// Forces the next configs/clients/test request to read from the routers
+// instead of waiting for the cache TTL
+func (h *NATHandler) InvalidateNATCache(c *gin.Context) {
+	user, ok := requireAdmin(c, "Only administrators can invalidate the NAT cache")
+	if !ok {
+		return
+	}
+
+	h.natService.InvalidateCache()
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionUpdate,
+			ResourceType: models.ResourceNATRule,
+			Description:  "Invalidated NAT cache",
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	middleware.GetRequestLogger(c).Infof("NAT cache invalidated by %s", user.Username)
+	utils.RespondSuccessWithMessage(c, "NAT cache invalidated", nil)
 }
 
 // CheckPPPoEStatus handles POST /api/pppoe/check
@@ -392,14 +1206,21 @@ func (h *NATHandler) CheckPPPoEStatus(c *gin.Context) {
 		}
 	}
 
+	timeout := pppoeCheckTimeout
+	if req.TestConnectivity {
+		timeout = pppoeConnectivityCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(h.historyContext(c), timeout)
+	defer cancel()
+
 	// Check PPPoE status across accessible routers or specific router
 	var result *models.PPPoEStatusResponse
 	if req.Router != "" {
 		// Check specific router
-		result = h.natService.CheckPPPoEStatus(req.Username, req.TestConnectivity, req.Router)
+		result = h.natService.CheckPPPoEStatus(ctx, req.Username, req.TestConnectivity, req.Router)
 	} else {
 		// Check only accessible routers for this user
-		result = h.natService.CheckPPPoEStatusWithRouterFilter(req.Username, allowedRouters, req.TestConnectivity)
+		result = h.natService.CheckPPPoEStatusWithRouterFilter(ctx, req.Username, allowedRouters, req.TestConnectivity)
 	}
 
 	if result.Status == "error" {
@@ -407,7 +1228,10 @@ func (h *NATHandler) CheckPPPoEStatus(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("PPPoE status checked for user: %s (router: %s, connectivity test: %t) by role: %s - Online: %t", req.Username, req.Router, req.TestConnectivity, userRole, result.IsOnline)
+	h.attachCustomer(c, result)
+
+	c.Set("router_name", req.Router)
+	middleware.GetRequestLogger(c).Infof("PPPoE status checked for user: %s (router: %s, connectivity test: %t) by role: %s - Online: %t", req.Username, req.Router, req.TestConnectivity, userRole, result.IsOnline)
 
 	// Log PPPoE check
 	if h.activityLogService != nil {
@@ -423,6 +1247,7 @@ func (h *NATHandler) CheckPPPoEStatus(c *gin.Context) {
 				ResourceID:   req.Username,
 				Description:  fmt.Sprintf("Checked PPPoE status for: %s (Online: %t)", req.Username, result.IsOnline),
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
@@ -432,6 +1257,99 @@ func (h *NATHandler) CheckPPPoEStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// CheckPPPoEStatusBatch handles POST /api/pppoe/check-batch
+// Checks up to models.MaxPPPoEBatchSize usernames on the routers the user can
+// access and returns each username's status
+func (h *NATHandler) CheckPPPoEStatusBatch(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.PPPoEBatchCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Format request tidak valid: " + err.Error(),
+		})
+		return
+	}
+
+	// Blank entries are dropped and duplicates checked once
+	seen := make(map[string]bool, len(req.Usernames))
+	usernames := make([]string, 0, len(req.Usernames))
+	for _, username := range req.Usernames {
+		username = strings.TrimSpace(username)
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	if len(usernames) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Minimal satu username PPPoE harus diisi",
+		})
+		return
+	}
+	if len(usernames) > models.MaxPPPoEBatchSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Maksimal %d username per batch", models.MaxPPPoEBatchSize),
+		})
+		return
+	}
+
+	timeout := pppoeCheckTimeout
+	if req.TestConnectivity {
+		timeout = pppoeConnectivityCheckTimeout
+	}
+
+	allowedRouters := h.getAllowedRoutersForUser(c)
+	if allowedRouters == nil {
+		allowedRouters = []string{}
+	}
+	results := h.natService.CheckMultiplePPPoEStatus(h.historyContext(c), usernames, allowedRouters, req.TestConnectivity, timeout)
+
+	online := 0
+	for _, result := range results {
+		if result.IsOnline {
+			online++
+		}
+	}
+
+	middleware.GetRequestLogger(c).Infof("PPPoE batch check of %d usernames (connectivity test: %t) by %s - Online: %d", len(usernames), req.TestConnectivity, user.Username, online)
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionPPPoECheck,
+			ResourceType: models.ResourcePPPoE,
+			ResourceID:   "batch",
+			Description:  fmt.Sprintf("Checked PPPoE status for %d usernames (Online: %d)", len(usernames), online),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.PPPoEBatchCheckResponse{
+		Status:  "success",
+		Total:   len(usernames),
+		Online:  online,
+		Results: results,
+	})
+}
+
 // CheckPPPoEStatusByGET handles GET /api/pppoe/check/:username (alternative endpoint)
 func (h *NATHandler) CheckPPPoEStatusByGET(c *gin.Context) {
 	// Get user role from context
@@ -453,18 +1371,379 @@ func (h *NATHandler) CheckPPPoEStatusByGET(c *gin.Context) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(h.historyContext(c), pppoeCheckTimeout)
+	defer cancel()
+
 	// Check PPPoE status (no connectivity test for GET endpoint)
-	result := h.natService.CheckPPPoEStatus(username, false)
+	result := h.natService.CheckPPPoEStatus(ctx, username, false)
 	
 	if result.Status == "error" {
 		c.JSON(http.StatusBadRequest, result)
 		return
 	}
 
+	h.attachCustomer(c, result)
+
 	h.logger.Infof("PPPoE status checked via GET for user: %s - Online: %t", username, result.IsOnline)
 	c.JSON(http.StatusOK, result)
 }
 
+// DisconnectPPPoE handles POST /api/pppoe/disconnect
+// Drops a user's active PPPoE session so the CPE reconnects
+func (h *NATHandler) DisconnectPPPoE(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.PPPoEDisconnectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router dan username PPPoE wajib diisi",
+		})
+		return
+	}
+
+	// Check if user has access to this router
+	hasAccess := false
+	for _, allowed := range h.getAllowedRoutersForUser(c) {
+		if req.Router == allowed {
+			hasAccess = true
+			break
+		}
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Tidak memiliki akses ke router ini",
+		})
+		return
+	}
+
+	c.Set("router_name", req.Router)
+	log := middleware.GetRequestLogger(c)
+
+	err := h.natService.DisconnectPPPoEUser(req.Router, req.Username)
+	if errors.Is(err, services.ErrPPPoEUserNotOnline) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("User %s tidak sedang online di router %s, tidak ada sesi yang diputus", req.Username, req.Router),
+		})
+		return
+	}
+	if errors.Is(err, services.ErrRouterBusy) {
+		log.Warnf("PPPoE disconnect on %s throttled: %v", req.Router, err)
+		utils.RespondRouterBusy(c, req.Router)
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to disconnect PPPoE user %s on %s: %v", req.Username, req.Router, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Gagal memutus sesi PPPoE: %v", err),
+		})
+		return
+	}
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionPPPoEDisconnect,
+			ResourceType: models.ResourcePPPoE,
+			ResourceID:   req.Username,
+			Description:  fmt.Sprintf("Disconnected PPPoE session for %s on %s", req.Username, req.Router),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	log.Infof("PPPoE user %s disconnected on %s by %s", req.Username, req.Router, user.Username)
+	utils.RespondSuccessWithMessage(c, fmt.Sprintf("Sesi PPPoE %s di router %s telah diputus", req.Username, req.Router), req)
+}
+
+// historyContext is the request context carrying the caller's user ID, so the
+// PPPoE check is recorded in their search history
+func (h *NATHandler) historyContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if user, exists := middleware.GetUserFromContext(c); exists {
+		ctx = services.WithRequestUserID(ctx, user.ID)
+	}
+	return ctx
+}
+
+// PPPoE search history page size
+const (
+	defaultPPPoEHistoryLimit = 20
+	maxPPPoEHistoryLimit     = 100
+)
+
+// GetPPPoEHistory handles GET /api/pppoe/history
+// Returns the caller's most recent PPPoE checks. Administrators may pass
+// ?user_id= to see another user's searches; everyone else only sees their own.
+func (h *NATHandler) GetPPPoEHistory(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	userID := user.ID
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		requested, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "user_id tidak valid",
+			})
+			return
+		}
+		if requested != user.ID && user.Role != models.RoleAdministrator {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Status:  "error",
+				Message: "Hanya dapat melihat riwayat pencarian sendiri",
+			})
+			return
+		}
+		userID = requested
+	}
+
+	limit := defaultPPPoEHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxPPPoEHistoryLimit {
+		limit = maxPPPoEHistoryLimit
+	}
+
+	history, err := h.natService.GetPPPoEHistory(userID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to get PPPoE search history for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Gagal mengambil riwayat pencarian PPPoE",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, history)
+}
+
+// attachCustomer adds resolved customer details to a PPPoE status result.
+// Lookup failures leave the result as is.
+func (h *NATHandler) attachCustomer(c *gin.Context, result *models.PPPoEStatusResponse) {
+	if h.customerDirectory == nil {
+		return
+	}
+	result.Customer = h.customerDirectory.Lookup(c.Request.Context(), result.Username)
+}
+
+// GetPPPoECustomer handles GET /api/pppoe/customer/:username
+// Read-only lookup of customer details for a PPPoE username
+func (h *NATHandler) GetPPPoECustomer(c *gin.Context) {
+	// Get user role from context (for authentication check)
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	username := c.Param("username")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Username PPPoE harus diisi dalam URL",
+		})
+		return
+	}
+
+	// Customer details are personal data: below Administrator they are only
+	// served for PPPoE accounts on the caller's own routers. Other accounts
+	// look the same as unknown ones.
+	var customer *models.Customer
+	if h.customerDirectory != nil && (userRole == models.RoleAdministrator || h.pppoeOnAllowedRouters(c, username)) {
+		customer = h.customerDirectory.Lookup(c.Request.Context(), username)
+	}
+
+	if customer == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Status:  "error",
+			Message: "Data pelanggan tidak ditemukan untuk username ini",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CustomerResponse{
+		Status: "success",
+		Data:   customer,
+	})
+}
+
+// customerScopeSearchLimit bounds the search that places a PPPoE username on
+// the caller's routers; exact matches rank first
+const customerScopeSearchLimit = 10
+
+// pppoeOnAllowedRouters reports whether a PPPoE account, online or not, exists
+// under exactly this username on one of the caller's routers
+func (h *NATHandler) pppoeOnAllowedRouters(c *gin.Context, username string) bool {
+	allowedRouters := h.getAllowedRoutersForUser(c)
+	if len(allowedRouters) == 0 {
+		return false
+	}
+
+	allowed := make(map[string]bool, len(allowedRouters))
+	for _, name := range allowedRouters {
+		allowed[name] = true
+	}
+	result := h.natService.FuzzySearchPPPoEWithRouterFilter(username, "", customerScopeSearchLimit, allowedRouters, true)
+	if result == nil {
+		return false
+	}
+	for _, match := range result.Matches {
+		if match.Username == username && allowed[match.Router] {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPPPoEAuditBatch caps how many usernames one audit upload may contain
+const maxPPPoEAuditBatch = 500
+
+// AuditPPPoE handles POST /api/pppoe/audit
+// Accepts a CSV upload (field "file") of usernames with an optional expected
+// router column and streams back a CSV report
+func (h *NATHandler) AuditPPPoE(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "File CSV wajib diupload (field: file)",
+		})
+		return
+	}
+	defer file.Close()
+
+	entries, err := parsePPPoEAuditCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Only routers the user can access are checked
+	allowedRouters := h.getAllowedRoutersForUser(c)
+	rows := h.natService.AuditPPPoEUsernames(c.Request.Context(), entries, allowedRouters)
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"pppoe-audit-%s.csv\"", time.Now().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"username", "expected_router", "status", "router", "ip_address", "uptime", "note"})
+	online := 0
+	for _, row := range rows {
+		if row.Status == models.PPPoEAuditOnline {
+			online++
+		}
+		writer.Write(utils.CSVSafeRow(row.Username, row.ExpectedRouter, row.Status, row.Router, row.IPAddress, row.Uptime, row.Note))
+		writer.Flush()
+	}
+	if err := writer.Error(); err != nil {
+		h.logger.Warnf("⚠️ PPPoE audit report aborted: %v", err)
+	}
+
+	if h.activityLogService != nil {
+		userID := user.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &userID,
+			Username:     user.Username,
+			UserRole:     string(user.Role),
+			ActionType:   models.ActionPPPoECheck,
+			ResourceType: models.ResourcePPPoE,
+			ResourceID:   "audit",
+			Description:  fmt.Sprintf("Audited %d PPPoE usernames (Online: %d)", len(rows), online),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+}
+
+// parsePPPoEAuditCSV reads "username[,router]" lines, skipping blanks, a header
+// row and duplicates. Fails when the batch exceeds maxPPPoEAuditBatch.
+func parsePPPoEAuditCSV(r io.Reader) ([]models.PPPoEAuditEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	entries := []models.PPPoEAuditEntry{}
+	seen := make(map[models.PPPoEAuditEntry]bool)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV tidak valid pada baris %d: %v", line, err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		entry := models.PPPoEAuditEntry{Username: strings.TrimSpace(record[0])}
+		if len(record) > 1 {
+			entry.ExpectedRouter = strings.TrimSpace(record[1])
+		}
+		if entry.Username == "" || (line == 1 && strings.EqualFold(entry.Username, "username")) {
+			continue
+		}
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+
+		entries = append(entries, entry)
+		if len(entries) > maxPPPoEAuditBatch {
+			return nil, fmt.Errorf("Maksimal %d username per audit", maxPPPoEAuditBatch)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("File CSV tidak berisi username")
+	}
+	return entries, nil
+}
+
 // GetPPPoERouters handles GET /api/pppoe/routers
 func (h *NATHandler) GetPPPoERouters(c *gin.Context) {
 	// Get user role from context
@@ -483,10 +1762,7 @@ func (h *NATHandler) GetPPPoERouters(c *gin.Context) {
 
 	h.logger.Infof("📋 PPPoE Routers loaded for role %s: %d routers", userRole, len(allowedRouters))
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   allowedRouters,
-	})
+	utils.RespondSuccess(c, allowedRouters)
 }
 
 // FuzzySearchPPPoE handles POST /api/pppoe/fuzzy-search
@@ -548,7 +1824,7 @@ func (h *NATHandler) FuzzySearchPPPoE(c *gin.Context) {
 	}
 
 	// Perform fuzzy search with dynamic router filtering
-	result := h.natService.FuzzySearchPPPoEWithRouterFilter(req.Username, req.Router, req.Limit, allowedRouters)
+	result := h.natService.FuzzySearchPPPoEWithRouterFilter(req.Username, req.Router, req.Limit, allowedRouters, req.IncludeOffline)
 
 	if result.Status == "error" {
 		c.JSON(http.StatusBadRequest, result)
@@ -0,0 +1,545 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"nat-management-app/internal/middleware"
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/services/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// withUser authenticates the request as user the way the auth middleware does
+func withUser(user *models.User) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user", user)
+		c.Set("user_role", user.Role)
+		c.Set("username", user.Username)
+		c.Next()
+	}
+}
+
+// newTestNATHandler returns a NAT handler over three routers, two of them
+// allowed for Head Branch 1
+func newTestNATHandler(access *mocks.UserAccess) (*NATHandler, *mocks.NATService) {
+	natService := &mocks.NATService{
+		Routers: map[string]string{
+			"SAMSAT": string(models.RoleHeadBranch1),
+			"LANE1":  string(models.RoleHeadBranch1),
+			"LANE2":  string(models.RoleHeadBranch2),
+		},
+		ONTConfigs: map[string]models.ONTConfig{
+			"SAMSAT": {Found: true},
+			"LANE1":  {Found: true},
+			"LANE2":  {Found: true},
+		},
+	}
+	return NewNATHandler(natService, access, nil, nil, testLogger()), natService
+}
+
+func TestGetNATConfigsUserRouterAccess(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+
+	tests := []struct {
+		name   string
+		access *mocks.UserAccess
+		want   []string
+	}{
+		{"role routers", &mocks.UserAccess{}, []string{"LANE1", "SAMSAT"}},
+		{"user assignments replace the role", &mocks.UserAccess{Assignments: map[int][]string{7: {"LANE1"}}}, []string{"LANE1"}},
+		{"explicit no access", &mocks.UserAccess{NoAccess: map[int]bool{7: true}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestNATHandler(tt.access)
+			router := gin.New()
+			router.GET("/api/nat/configs", withUser(head), h.GetNATConfigs)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/configs?scope=all", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+
+			var response models.NATConfigsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for name := range response.Data {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("routers = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("routers = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetNATConfigsRouterScope(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	assigned := &mocks.UserAccess{Assignments: map[int][]string{7: {"SAMSAT", "LANE1"}}}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantFetch  []string // Routers the ONT config fetch covered
+		wantConfig []string // Routers in the response
+	}{
+		{"role default is assigned", "", []string{"LANE1", "SAMSAT"}, []string{"LANE1", "SAMSAT"}},
+		{"assigned limits the fetch", "?scope=assigned", []string{"LANE1", "SAMSAT"}, []string{"LANE1", "SAMSAT"}},
+		{"all fans out", "?scope=all", []string{"LANE1", "LANE2", "SAMSAT"}, []string{"LANE1", "SAMSAT"}},
+		{"first assigned router", "?scope=first", []string{"LANE1"}, []string{"LANE1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, natService := newTestNATHandler(assigned)
+			router := gin.New()
+			router.GET("/api/nat/configs", withUser(head), h.GetNATConfigs)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/configs"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+
+			if len(natService.ConfigFetches) != 1 {
+				t.Fatalf("config fetches = %v, want one", natService.ConfigFetches)
+			}
+			fetched := natService.ConfigFetches[0]
+			sort.Strings(fetched)
+			if strings.Join(fetched, ",") != strings.Join(tt.wantFetch, ",") {
+				t.Errorf("fetched %v, want %v", fetched, tt.wantFetch)
+			}
+
+			var response models.NATConfigsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for name := range response.Data {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.wantConfig, ",") {
+				t.Errorf("configs for %v, want %v", got, tt.wantConfig)
+			}
+		})
+	}
+
+	t.Run("role default configured as all", func(t *testing.T) {
+		models.ConfigureDefaultRouterScopes(map[string]string{string(models.RoleHeadBranch1): string(models.ScopeAll)})
+		t.Cleanup(func() {
+			models.ConfigureDefaultRouterScopes(map[string]string{string(models.RoleHeadBranch1): string(models.ScopeAssigned)})
+		})
+
+		h, natService := newTestNATHandler(assigned)
+		router := gin.New()
+		router.GET("/api/nat/configs", withUser(head), h.GetNATConfigs)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/configs", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		if len(natService.ConfigFetches) != 1 || len(natService.ConfigFetches[0]) != 3 {
+			t.Fatalf("config fetches = %v, want one over every router", natService.ConfigFetches)
+		}
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		h, natService := newTestNATHandler(assigned)
+		router := gin.New()
+		router.GET("/api/nat/configs", withUser(head), h.GetNATConfigs)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/configs?scope=everything", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", w.Code)
+		}
+		if len(natService.ConfigFetches) != 0 {
+			t.Fatalf("config fetches = %v, want none for a rejected scope", natService.ConfigFetches)
+		}
+	})
+}
+
+func TestToggleNATRuleErrorStatus(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"rule missing", fmt.Errorf("ONT %w in SAMSAT", services.ErrNATRuleNotFound), http.StatusNotFound},
+		{"router unreachable", errors.New("failed to read ONT NAT rule in SAMSAT: connection refused"), http.StatusBadGateway},
+		{"router busy", fmt.Errorf("SAMSAT: %w", services.ErrRouterBusy), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, natService := newTestNATHandler(&mocks.UserAccess{})
+			natService.SetNATRuleEnabledFunc = func(ctx context.Context, routerName string, enabled bool) error {
+				return tt.err
+			}
+			router := gin.New()
+			router.POST("/api/nat/toggle", withUser(head), h.ToggleNATRule)
+
+			body := strings.NewReader(`{"router":"SAMSAT","enabled":false}`)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/nat/toggle", body))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateNATRuleStaleExpectedIP(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+	natService.UpdateONTNATRuleFunc = func(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error) {
+		return nil, &services.NATRuleConflictError{
+			Router: req.Router, ExpectedIP: req.ExpectedIP, CurrentIP: "192.168.1.20", CurrentPort: "8080",
+		}
+	}
+	router := gin.New()
+	router.POST("/api/nat/update", withUser(head), h.UpdateNATRule)
+
+	body := strings.NewReader(`{"router":"SAMSAT","ip":"192.168.1.30","port":"80","expected_ip":"192.168.1.10"}`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/nat/update", body))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (body %s)", w.Code, w.Body.String())
+	}
+
+	var resp models.NATUpdateConflictResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.CurrentIP != "192.168.1.20" || resp.CurrentPort != "8080" {
+		t.Fatalf("response = %+v, want the rule's current target 192.168.1.20:8080", resp)
+	}
+	if len(natService.Updates) != 1 || natService.Updates[0].ExpectedIP != "192.168.1.10" {
+		t.Fatalf("update requests = %+v, want one carrying expected_ip", natService.Updates)
+	}
+}
+
+func TestGetPPPoECustomerScopedToUserRouters(t *testing.T) {
+	mapping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := strings.TrimPrefix(r.URL.Path, "/customers/")
+		json.NewEncoder(w).Encode(models.Customer{PPPoEUsername: username, FullName: "Customer " + username})
+	}))
+	defer mapping.Close()
+
+	// PPPoE accounts by router; the search only looks at the allowed routers
+	accounts := map[string]string{"alice": "SAMSAT", "dave": "LANE2"}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		access   *mocks.UserAccess
+		username string
+		want     int
+	}{
+		{"account on a role router", &models.User{ID: 7, Role: models.RoleHeadBranch1}, &mocks.UserAccess{}, "alice", http.StatusOK},
+		{"account on another branch's router", &models.User{ID: 7, Role: models.RoleHeadBranch1}, &mocks.UserAccess{}, "dave", http.StatusNotFound},
+		{"prefix of an account", &models.User{ID: 7, Role: models.RoleHeadBranch1}, &mocks.UserAccess{}, "ali", http.StatusNotFound},
+		{"user assignments narrow the role", &models.User{ID: 7, Role: models.RoleHeadBranch1}, &mocks.UserAccess{Assignments: map[int][]string{7: {"LANE1"}}}, "alice", http.StatusNotFound},
+		{"access lookup failure", &models.User{ID: 7, Role: models.RoleHeadBranch1}, &mocks.UserAccess{Err: errors.New("connection refused")}, "alice", http.StatusNotFound},
+		{"administrator", &models.User{ID: 1, Role: models.RoleAdministrator}, &mocks.UserAccess{}, "dave", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, natService := newTestNATHandler(tt.access)
+			h.customerDirectory = services.NewCustomerDirectory(nil, mapping.URL+"/customers/{username}", testLogger())
+			natService.FuzzySearchFunc = func(searchTerm, specificRouter string, limit int, allowedRouters []string, includeOffline bool) *models.PPPoEFuzzySearchResponse {
+				response := &models.PPPoEFuzzySearchResponse{Status: "success", SearchTerm: searchTerm}
+				for username, routerName := range accounts {
+					if !strings.HasPrefix(username, searchTerm) {
+						continue
+					}
+					for _, allowed := range allowedRouters {
+						if allowed == routerName {
+							response.Matches = append(response.Matches, models.PPPoEFuzzyMatch{Username: username, Router: routerName})
+						}
+					}
+				}
+				return response
+			}
+
+			router := gin.New()
+			router.GET("/api/pppoe/customer/:username", withUser(tt.user), h.GetPPPoECustomer)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/pppoe/customer/"+tt.username, nil))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK && !strings.Contains(w.Body.String(), "Customer "+tt.username) {
+				t.Fatalf("body %s has no customer details", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetNATClientsNDJSON(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+	natService.Clients = map[string][]models.NATClient{
+		"SAMSAT": {{Router: "SAMSAT", Username: "alice", Uptime: "3h"}, {Router: "SAMSAT", Username: "bob", Uptime: "1d2h"}},
+		"LANE1":  {{Router: "LANE1", Username: "carol", Uptime: "5m"}},
+		"LANE2":  {{Router: "LANE2", Username: "dave", Uptime: "1h"}}, // Head Branch 2 only
+	}
+	router := gin.New()
+	router.GET("/api/nat/clients", withUser(head), h.GetNATClients)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/clients?format=ndjson&scope=all&sort=uptime&order=desc", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	// One self-contained JSON document per allowed router
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one per allowed router:\n%s", len(lines), w.Body.String())
+	}
+	got := make(map[string][]string)
+	for i, line := range lines {
+		var result models.RouterClients
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line %d doesn't parse on its own: %v\n%s", i+1, err, line)
+		}
+		for _, client := range result.Clients {
+			got[result.Router] = append(got[result.Router], client.Username)
+		}
+	}
+	if strings.Join(got["SAMSAT"], ",") != "bob,alice" || strings.Join(got["LANE1"], ",") != "carol" {
+		t.Fatalf("clients = %v, want SAMSAT bob,alice (longest uptime first) and LANE1 carol", got)
+	}
+}
+
+func TestUpdateNATRuleDefaultPort(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+	natService.DefaultONTPorts = map[string]string{"SAMSAT": "8080"}
+	router := gin.New()
+	router.POST("/api/nat/update", withUser(head), h.UpdateNATRule)
+
+	for _, body := range []string{
+		`{"router":"SAMSAT","ip":"192.168.1.20"}`,
+		`{"router":"LANE1","ip":"192.168.1.20"}`,
+		`{"router":"LANE1","ip":"192.168.1.20","port":"8443"}`,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/nat/update", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST %s = %d (body %s)", body, w.Code, w.Body.String())
+		}
+	}
+
+	// The router's own port, then the global 80; an explicit port always wins
+	want := []string{"8080", "80", "8443"}
+	if len(natService.Updates) != len(want) {
+		t.Fatalf("got %d updates, want %d", len(natService.Updates), len(want))
+	}
+	for i, update := range natService.Updates {
+		if update.Port != want[i] {
+			t.Errorf("update %d (%s) used port %s, want %s", i, update.Router, update.Port, want[i])
+		}
+	}
+}
+
+func TestAuditPPPoECSVReport(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+
+	var gotEntries []models.PPPoEAuditEntry
+	var gotRouters []string
+	natService.AuditFunc = func(ctx context.Context, entries []models.PPPoEAuditEntry, allowedRouters []string) []models.PPPoEAuditRow {
+		gotEntries, gotRouters = entries, allowedRouters
+		return []models.PPPoEAuditRow{
+			{Username: "alice", Status: models.PPPoEAuditOnline, Router: "SAMSAT", IPAddress: "10.0.1.2", Uptime: "1h"},
+			{Username: "bob", ExpectedRouter: "LANE1", Status: models.PPPoEAuditOffline},
+		}
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "users.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("username,router\nalice\nbob,LANE1\nalice\n"))
+	form.Close()
+
+	router := gin.New()
+	router.POST("/api/pppoe/audit", withUser(head), h.AuditPPPoE)
+	req := httptest.NewRequest(http.MethodPost, "/api/pppoe/audit", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	// Header row and the duplicate alice are dropped
+	if len(gotEntries) != 2 || gotEntries[0].Username != "alice" || gotEntries[1] != (models.PPPoEAuditEntry{Username: "bob", ExpectedRouter: "LANE1"}) {
+		t.Fatalf("entries = %+v, want alice and bob@LANE1", gotEntries)
+	}
+	sort.Strings(gotRouters)
+	if strings.Join(gotRouters, ",") != "LANE1,SAMSAT" {
+		t.Fatalf("audited routers = %v, want only the user's LANE1 and SAMSAT", gotRouters)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"username", "expected_router", "status", "router", "ip_address", "uptime", "note"},
+		{"alice", "", "online", "SAMSAT", "10.0.1.2", "1h", ""},
+		{"bob", "LANE1", "offline", "", "", "", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("report = %v, want %v", records, want)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("report row %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+// stubCustomers is a customer lookup over a fixed set of customers
+type stubCustomers map[string]*models.Customer
+
+func (s stubCustomers) Lookup(ctx context.Context, pppoeUsername string) *models.Customer {
+	return s[pppoeUsername]
+}
+
+func (s stubCustomers) ResolveMany(ctx context.Context, pppoeUsernames []string) map[string]*models.Customer {
+	found := make(map[string]*models.Customer)
+	for _, username := range pppoeUsernames {
+		if customer, ok := s[username]; ok {
+			found[username] = customer
+		}
+	}
+	return found
+}
+
+func TestGetNATClientsEnrichment(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+	natService.Clients = map[string][]models.NATClient{
+		"SAMSAT": {{Router: "SAMSAT", Username: "alice"}, {Router: "SAMSAT", Username: "walkin"}},
+	}
+	h.customerDirectory = stubCustomers{
+		"alice": {PPPoEUsername: "alice", FullName: "Alice Rahma", Address: "Jl. Merdeka 1"},
+	}
+
+	router := gin.New()
+	router.GET("/api/nat/clients", withUser(head), h.GetNATClients)
+
+	fetch := func(query string) map[string]models.NATClient {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nat/clients"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var response models.NATClientsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		clients := make(map[string]models.NATClient)
+		for _, client := range response.Data["SAMSAT"] {
+			clients[client.Username] = client
+		}
+		return clients
+	}
+
+	clients := fetch("?enrich=true")
+	if alice := clients["alice"]; alice.CustomerName != "Alice Rahma" || alice.Address != "Jl. Merdeka 1" {
+		t.Errorf("alice = %+v, want her customer name and address", alice)
+	}
+	if walkin, ok := clients["walkin"]; !ok || walkin.CustomerName != "" || walkin.Address != "" {
+		t.Errorf("walkin = %+v (listed %v), want an unknown customer listed without details", walkin, ok)
+	}
+
+	// Enrichment works on copies, so the service's clients stay bare
+	if alice := natService.Clients["SAMSAT"][0]; alice.CustomerName != "" {
+		t.Errorf("service client modified: %+v", alice)
+	}
+	if alice := fetch("")["alice"]; alice.CustomerName != "" {
+		t.Errorf("alice without enrich = %+v, want no customer details", alice)
+	}
+}
+
+func TestUpdateNATRuleLogsShareRequestFields(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	h, natService := newTestNATHandler(&mocks.UserAccess{})
+	h.logger = logger
+	// Stands in for the NAT service, which logs through the context's logger
+	natService.UpdateONTNATRuleFunc = func(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error) {
+		services.RequestLogger(ctx, logrus.New()).WithField("router", req.Router).Info("service")
+		return &models.ONTNATRule{ToAddresses: "10.0.0.1", ToPorts: "80"}, nil
+	}
+
+	router := gin.New()
+	router.Use(middleware.RequestLogger(logger), middleware.AccessLogger())
+	router.POST("/api/nat/update", withUser(head), h.UpdateNATRule)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/nat/update", strings.NewReader(`{"router":"SAMSAT","ip":"10.0.0.2","port":"80"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	// Service, handler and access log lines
+	entries := hook.AllEntries()
+	if len(entries) < 3 {
+		t.Fatalf("got %d log lines, want the service, handler and access lines", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Data["request_id"] != "req-42" || entry.Data["user"] != "head1" {
+			t.Errorf("log %q has fields %v, want request_id req-42 and user head1", entry.Message, entry.Data)
+		}
+	}
+	for _, entry := range entries[:2] {
+		if entry.Data["router"] != "SAMSAT" {
+			t.Errorf("log %q has router %v, want SAMSAT", entry.Message, entry.Data["router"])
+		}
+	}
+}
@@ -1,14 +1,20 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"nat-management-app/internal/database"
+	"nat-management-app/internal/middleware"
 	"nat-management-app/internal/models"
 	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -19,6 +25,7 @@ type ONTWiFiHandler struct {
 	extractorService *services.ONTExtractorService
 	wifiRepo         *database.ONTWiFiRepository
 	natService       *services.NATService
+	userService      *services.UserService
 	activityLogger   *services.ActivityLogService
 	logger           *logrus.Logger
 }
@@ -28,6 +35,7 @@ func NewONTWiFiHandler(
 	extractorService *services.ONTExtractorService,
 	wifiRepo *database.ONTWiFiRepository,
 	natService *services.NATService,
+	userService *services.UserService,
 	activityLogger *services.ActivityLogService,
 	logger *logrus.Logger,
 ) *ONTWiFiHandler {
@@ -35,6 +43,7 @@ func NewONTWiFiHandler(
 		extractorService: extractorService,
 		wifiRepo:         wifiRepo,
 		natService:       natService,
+		userService:      userService,
 		activityLogger:   activityLogger,
 		logger:           logger,
 	}
@@ -70,12 +79,14 @@ func (h *ONTWiFiHandler) ExtractWiFiInfo(c *gin.Context) {
 		if h.activityLogger != nil {
 			_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
 				Username:     username,
-				ActionType:   "ONT_WIFI_EXTRACT",
+				ActionType:   models.ActionONTWiFiExtract,
 				ResourceType: "ONT",
 				ResourceID:   req.ONTURL,
 				Description:  fmt.Sprintf("Failed to extract WiFi info: %v", err),
 				Status:       models.StatusFailed,
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
+				Metadata:     extractionMetadata(req.Router),
 			})
 		}
 
@@ -92,22 +103,21 @@ func (h *ONTWiFiHandler) ExtractWiFiInfo(c *gin.Context) {
 	wifiInfo.Router = req.Router
 	wifiInfo.ExtractedBy = username
 
-	// Save to database
-	if err := h.wifiRepo.SaveWiFiInfo(c.Request.Context(), wifiInfo); err != nil {
-		h.logger.Errorf("Failed to save WiFi info to database: %v", err)
-		// Continue anyway - extraction was successful
-	}
+	// Save to database (a failed save doesn't undo the extraction)
+	changedFields := h.saveWiFiInfo(c.Request.Context(), wifiInfo)
 
 	// Log activity
 	if h.activityLogger != nil {
 		_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
 			Username:     username,
-			ActionType:   "ONT_WIFI_EXTRACT",
+			ActionType:   models.ActionONTWiFiExtract,
 			ResourceType: "ONT",
 			ResourceID:   req.ONTURL,
 			Description:  fmt.Sprintf("Successfully extracted WiFi info (SSID: %s)", wifiInfo.SSID),
 			Status:       models.StatusSuccess,
 			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			Metadata:     extractionMetadata(req.Router),
 		})
 	}
 
@@ -120,6 +130,8 @@ func (h *ONTWiFiHandler) ExtractWiFiInfo(c *gin.Context) {
 		Message:        "WiFi information extracted successfully",
 		ExtractionTime: extractionTime,
 		Timestamp:      time.Now(),
+		Changed:        len(changedFields) > 0,
+		ChangedFields:  changedFields,
 	})
 }
 
@@ -141,9 +153,13 @@ func (h *ONTWiFiHandler) ExtractWiFiFromNAT(c *gin.Context) {
 
 	// Get NAT configuration for the router
 	natConfig, err := h.natService.GetONTNATRule(req.Router)
+	if errors.Is(err, services.ErrRouterBusy) {
+		utils.RespondRouterBusy(c, req.Router)
+		return
+	}
 	if err != nil {
 		h.logger.Errorf("Failed to get NAT config: %v", err)
-		c.JSON(http.StatusNotFound, models.ONTWiFiExtractResponse{
+		c.JSON(natRuleErrorStatus(err), models.ONTWiFiExtractResponse{
 			Status:    "error",
 			Message:   fmt.Sprintf("NAT configuration not found: %v", err),
 			Timestamp: time.Now(),
@@ -171,6 +187,21 @@ func (h *ONTWiFiHandler) ExtractWiFiFromNAT(c *gin.Context) {
 	)
 	if err != nil {
 		h.logger.Errorf("WiFi extraction failed: %v", err)
+
+		// Log activity (counted as a failed attempt by the WiFi stats)
+		if h.activityLogger != nil {
+			_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
+				Username:     username,
+				ActionType:   models.ActionONTWiFiExtractFromNAT,
+				ResourceType: "ROUTER",
+				ResourceID:   req.Router,
+				Description:  fmt.Sprintf("Failed to extract WiFi info from NAT config: %v", err),
+				Status:       models.StatusFailed,
+				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
+			})
+		}
+
 		c.JSON(http.StatusInternalServerError, models.ONTWiFiExtractResponse{
 			Status:    "error",
 			Message:   fmt.Sprintf("WiFi extraction failed: %v", err),
@@ -185,20 +216,19 @@ func (h *ONTWiFiHandler) ExtractWiFiFromNAT(c *gin.Context) {
 	wifiInfo.ExtractedBy = username
 
 	// Save to database
-	if err := h.wifiRepo.SaveWiFiInfo(c.Request.Context(), wifiInfo); err != nil {
-		h.logger.Errorf("Failed to save WiFi info: %v", err)
-	}
+	changedFields := h.saveWiFiInfo(c.Request.Context(), wifiInfo)
 
 	// Log activity
 	if h.activityLogger != nil {
 		_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
 			Username:     username,
-			ActionType:   "ONT_WIFI_EXTRACT_FROM_NAT",
+			ActionType:   models.ActionONTWiFiExtractFromNAT,
 			ResourceType: "ROUTER",
 			ResourceID:   req.Router,
 			Description:  fmt.Sprintf("Extracted WiFi info from NAT config (SSID: %s)", wifiInfo.SSID),
 			Status:       models.StatusSuccess,
 			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
 		})
 	}
 
@@ -210,9 +240,217 @@ func (h *ONTWiFiHandler) ExtractWiFiFromNAT(c *gin.Context) {
 		Message:        "WiFi information extracted successfully",
 		ExtractionTime: extractionTime,
 		Timestamp:      time.Now(),
+		Changed:        len(changedFields) > 0,
+		ChangedFields:  changedFields,
 	})
 }
 
+// Bulk extraction limits: ONTs are slow and fragile, so only a few are
+// worked on at once and each gets a bounded run
+const (
+	maxONTBulkTargets    = 50
+	ontBulkConcurrency   = 3
+	ontBulkTargetTimeout = 60 * time.Second
+)
+
+// BulkExtractWiFi extracts WiFi information from several ONT devices
+// POST /api/ont/wifi/bulk-extract
+func (h *ONTWiFiHandler) BulkExtractWiFi(c *gin.Context) {
+	var req models.ONTWiFiBulkExtractRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ONTWiFiBulkExtractResponse{
+			Status:    "error",
+			Message:   fmt.Sprintf("Invalid request: %v", err),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if len(req.Targets) == 0 || len(req.Targets) > maxONTBulkTargets {
+		c.JSON(http.StatusBadRequest, models.ONTWiFiBulkExtractResponse{
+			Status:    "error",
+			Message:   fmt.Sprintf("Targets must contain 1 to %d ONTs", maxONTBulkTargets),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	for i, target := range req.Targets {
+		if strings.TrimSpace(target.ONTURL) == "" {
+			c.JSON(http.StatusBadRequest, models.ONTWiFiBulkExtractResponse{
+				Status:    "error",
+				Message:   fmt.Sprintf("Target %d has no ont_url", i+1),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	username := getUsernameFromContext(c)
+	clientIP := c.ClientIP()
+	requestID := middleware.GetRequestID(c)
+	ctx := c.Request.Context()
+
+	startTime := time.Now()
+	h.logger.Infof("Starting bulk WiFi extraction for %d ONTs (requested by: %s)", len(req.Targets), username)
+
+	results := make([]models.ONTWiFiBulkExtractResult, len(req.Targets))
+	sem := make(chan struct{}, ontBulkConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range req.Targets {
+		wg.Add(1)
+		go func(i int, target models.ONTWiFiExtractRequest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = h.extractBulkTarget(ctx, target, username, clientIP, requestID)
+		}(i, req.Targets[i])
+	}
+	wg.Wait()
+
+	response := models.ONTWiFiBulkExtractResponse{
+		Status:       "success",
+		TotalTargets: len(req.Targets),
+		Results:      results,
+		TotalTime:    time.Since(startTime),
+		Timestamp:    time.Now(),
+	}
+	for _, result := range results {
+		if result.Success {
+			response.Successful++
+		} else {
+			response.Failed++
+		}
+	}
+	switch {
+	case response.Failed == 0:
+		response.Message = fmt.Sprintf("WiFi information extracted from all %d ONTs", response.TotalTargets)
+	case response.Successful == 0:
+		response.Status = "error"
+		response.Message = fmt.Sprintf("WiFi extraction failed for all %d ONTs", response.TotalTargets)
+	default:
+		response.Status = "partial"
+		response.Message = fmt.Sprintf("WiFi information extracted from %d of %d ONTs", response.Successful, response.TotalTargets)
+	}
+
+	h.logger.Infof("Bulk WiFi extraction completed in %v: %d successful, %d failed",
+		response.TotalTime, response.Successful, response.Failed)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// extractBulkTarget extracts, saves and logs one bulk extraction target
+func (h *ONTWiFiHandler) extractBulkTarget(ctx context.Context, target models.ONTWiFiExtractRequest, username, clientIP, requestID string) models.ONTWiFiBulkExtractResult {
+	result := models.ONTWiFiBulkExtractResult{
+		ONTURL:        target.ONTURL,
+		PPPoEUsername: target.PPPoEUsername,
+	}
+
+	startTime := time.Now()
+	wifiInfo, err := h.extractorService.ExtractWiFiInfoContext(ctx, ontBulkTargetTimeout,
+		target.ONTURL, target.Username, target.Password, target.Debug)
+	result.ExtractTime = time.Since(startTime)
+
+	if err != nil {
+		h.logger.Errorf("Bulk WiFi extraction failed for %s: %v", target.ONTURL, err)
+		result.Error = err.Error()
+
+		if h.activityLogger != nil {
+			_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
+				Username:     username,
+				ActionType:   models.ActionONTWiFiExtract,
+				ResourceType: "ONT",
+				ResourceID:   target.ONTURL,
+				Description:  fmt.Sprintf("Failed to extract WiFi info (bulk): %v", err),
+				Status:       models.StatusFailed,
+				IPAddress:    clientIP,
+				RequestID:    requestID,
+				Metadata:     extractionMetadata(target.Router),
+			})
+		}
+		return result
+	}
+
+	// Add metadata
+	wifiInfo.PPPoEUsername = target.PPPoEUsername
+	wifiInfo.Router = target.Router
+	wifiInfo.ExtractedBy = username
+
+	// Save to database; a failed save doesn't undo the extraction
+	result.ChangedFields = h.saveWiFiInfo(ctx, wifiInfo)
+	result.Changed = len(result.ChangedFields) > 0
+
+	if h.activityLogger != nil {
+		_ = h.activityLogger.CreateLog(&models.ActivityLogCreate{
+			Username:     username,
+			ActionType:   models.ActionONTWiFiExtract,
+			ResourceType: "ONT",
+			ResourceID:   target.ONTURL,
+			Description:  fmt.Sprintf("Successfully extracted WiFi info (bulk, SSID: %s)", wifiInfo.SSID),
+			Status:       models.StatusSuccess,
+			IPAddress:    clientIP,
+			RequestID:    requestID,
+			Metadata:     extractionMetadata(target.Router),
+		})
+	}
+
+	result.Success = true
+	result.Data = wifiInfo
+	return result
+}
+
+// ExtractAllWiFi starts a run over every router's ONT right away, like the
+// WIFI_EXTRACT_SCHEDULE runs (Administrator only)
+// POST /api/ont/wifi/extract-all
+func (h *ONTWiFiHandler) ExtractAllWiFi(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if user.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can extract WiFi info from all ONTs",
+		})
+		return
+	}
+
+	if err := h.extractorService.TriggerExtractAll(user.Username); err != nil {
+		if errors.Is(err, services.ErrWiFiExtractRunInProgress) {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "A WiFi extraction run is already running, try again after it finishes",
+			})
+			return
+		}
+		h.logger.Errorf("Failed to start WiFi extraction for all ONTs: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.RespondAccepted(c, "WiFi extraction for all ONTs started; the summary is written to the activity log", nil)
+}
+
+// saveWiFiInfo stores an extraction through the extractor service and
+// returns the WiFi settings that changed. Failures are only logged.
+func (h *ONTWiFiHandler) saveWiFiInfo(ctx context.Context, wifiInfo *models.ONTWiFiInfo) []string {
+	changedFields, err := h.extractorService.SaveWiFiInfo(ctx, wifiInfo)
+	if err != nil {
+		h.logger.Errorf("Failed to save WiFi info to database: %v", err)
+	}
+	return changedFields
+}
+
 // GetWiFiHistory retrieves WiFi extraction history
 // GET /api/ont/wifi/history
 func (h *ONTWiFiHandler) GetWiFiHistory(c *gin.Context) {
@@ -249,10 +487,42 @@ func (h *ONTWiFiHandler) GetWiFiHistory(c *gin.Context) {
 		Status:  "success",
 		Data:    history,
 		Total:   total,
+		Meta:    models.NewPaginationMeta(total, req.Limit, req.Offset),
 		Message: fmt.Sprintf("Retrieved %d WiFi extraction records", len(history)),
 	})
 }
 
+// GetWiFiChanges lists the extractions where a customer's SSID or password
+// changed compared to the extraction before
+// GET /api/ont/wifi/changes?pppoe_username=
+func (h *ONTWiFiHandler) GetWiFiChanges(c *gin.Context) {
+	pppoeUsername := strings.TrimSpace(c.Query("pppoe_username"))
+	if pppoeUsername == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "pppoe_username is required",
+		})
+		return
+	}
+
+	limit := 50
+	if val, err := strconv.Atoi(c.Query("limit")); err == nil && val > 0 && val <= 500 {
+		limit = val
+	}
+
+	changes, err := h.wifiRepo.GetWiFiChanges(c.Request.Context(), pppoeUsername, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to get WiFi changes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to retrieve WiFi changes",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, changes)
+}
+
 // GetLatestWiFiInfo retrieves the most recent WiFi info for a PPPoE user
 // GET /api/ont/wifi/latest/:pppoe_username
 func (h *ONTWiFiHandler) GetLatestWiFiInfo(c *gin.Context) {
@@ -276,10 +546,7 @@ func (h *ONTWiFiHandler) GetLatestWiFiInfo(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   wifiInfo,
-	})
+	utils.RespondSuccess(c, wifiInfo)
 }
 
 // CheckAvailability checks if webautomation tools are available
@@ -333,18 +600,44 @@ func (h *ONTWiFiHandler) SearchBySSID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"data":    results,
-		"count":   len(results),
-		"message": fmt.Sprintf("Found %d results for SSID: %s", len(results), ssid),
-	})
+	utils.RespondSuccessWithLegacy(c, fmt.Sprintf("Found %d results for SSID: %s", len(results), ssid),
+		results, gin.H{"count": len(results)}, models.NewPaginationMeta(len(results), 0, 0))
 }
 
-// GetWiFiStats retrieves statistics about WiFi extraction records
-// GET /api/ont/wifi/stats
+// GetWiFiStats retrieves aggregated WiFi extraction statistics
+// GET /api/ont/wifi/stats?from=YYYY-MM-DD&to=YYYY-MM-DD (UTC days, default last 30)
 func (h *ONTWiFiHandler) GetWiFiStats(c *gin.Context) {
-	stats, err := h.wifiRepo.GetWiFiInfoStats(c.Request.Context())
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	filter, err := parseWiFiStatsRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Administrators see every record; others only their accessible routers
+	if user.Role != models.RoleAdministrator {
+		routers, _, err := services.ResolveUserRouters(h.userService, h.natService, user)
+		if err != nil {
+			h.logger.Warnf("Failed to get user-specific routers for user ID %d: %v", user.ID, err)
+		}
+		filter.Routers = routers
+		if filter.Routers == nil {
+			filter.Routers = []string{}
+		}
+	}
+
+	stats, err := h.wifiRepo.GetWiFiStats(c.Request.Context(), filter)
 	if err != nil {
 		h.logger.Errorf("Failed to get WiFi stats: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -354,10 +647,53 @@ func (h *ONTWiFiHandler) GetWiFiStats(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   stats,
-	})
+	utils.RespondSuccess(c, stats)
+}
+
+// maxWiFiStatsDays bounds the stats date range (and so the series length)
+const maxWiFiStatsDays = 366
+
+// parseWiFiStatsRange validates the from/to query values. Either may be
+// omitted: to defaults to today and from to 29 days before to.
+func parseWiFiStatsRange(fromParam, toParam string) (models.ONTWiFiStatsFilter, error) {
+	var filter models.ONTWiFiStatsFilter
+
+	now := time.Now().UTC()
+	filter.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toParam != "" {
+		to, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid to date, expected YYYY-MM-DD")
+		}
+		filter.To = to
+	}
+
+	filter.From = filter.To.AddDate(0, 0, -29)
+	if fromParam != "" {
+		from, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+
+	if filter.From.After(filter.To) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+	if days := int(filter.To.Sub(filter.From).Hours()/24) + 1; days > maxWiFiStatsDays {
+		return filter, fmt.Errorf("Date range too long: %d days (max %d)", days, maxWiFiStatsDays)
+	}
+
+	return filter, nil
+}
+
+// extractionMetadata records the router of an extraction attempt so the WiFi
+// stats success rate can be filtered by router access
+func extractionMetadata(router string) map[string]interface{} {
+	if router == "" {
+		return nil
+	}
+	return map[string]interface{}{"router": router}
 }
 
 // Helper function to get username from Gin context
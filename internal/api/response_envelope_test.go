@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/services/mocks"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubAuthService answers GetJWTPublicKey; any other method panics
+type stubAuthService struct {
+	services.AuthServiceInterface
+}
+
+func (stubAuthService) GetJWTPublicKey() (string, error) {
+	return "-----BEGIN PUBLIC KEY-----", nil
+}
+
+func (s *stubRouterService) GetConfigurationPath() string {
+	return "database"
+}
+
+// getJSON serves one GET request and decodes the JSON object it returns
+func getJSON(t *testing.T, router *gin.Engine, path string) map[string]interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, body %s", path, w.Code, w.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	if body["status"] != "success" {
+		t.Fatalf("GET %s: status field = %v", path, body["status"])
+	}
+	if _, ok := body["data"]; !ok {
+		t.Fatalf("GET %s: no data in %v", path, body)
+	}
+	return body
+}
+
+func TestSuccessEnvelope(t *testing.T) {
+	head := &models.User{ID: 7, Username: "head1", Role: models.RoleHeadBranch1}
+	natHandler, _ := newTestNATHandler(&mocks.UserAccess{})
+	routerHandler := NewRouterHandler(&stubRouterService{}, nil, nil, nil, testLogger())
+	authHandler := NewAuthHandler(stubAuthService{}, nil, nil, nil, testLogger())
+
+	router := gin.New()
+	router.GET("/api/pppoe/routers", withUser(head), natHandler.GetPPPoERouters)
+	router.GET("/api/nat/status", withUser(head), natHandler.GetNATStatus)
+	router.GET("/api/routers/config", withRole(models.RoleAdministrator), routerHandler.GetConfigurationInfo)
+	router.GET("/api/auth/jwt-public-key", authHandler.GetJWTPublicKey)
+
+	t.Run("payload under data", func(t *testing.T) {
+		body := getJSON(t, router, "/api/pppoe/routers")
+		routers, ok := body["data"].([]interface{})
+		if !ok || len(routers) != 2 {
+			t.Fatalf("data = %v, want the two allowed routers", body["data"])
+		}
+	})
+
+	// Endpoints that used to answer with top-level fields keep them next to data
+	legacy := []struct {
+		path   string
+		fields []string
+	}{
+		{"/api/nat/status", []string{"health", "total_routers", "configured"}},
+		{"/api/routers/config", []string{"config_file"}},
+		{"/api/auth/jwt-public-key", []string{"public_key", "algorithm"}},
+	}
+	for _, tt := range legacy {
+		t.Run(tt.path, func(t *testing.T) {
+			body := getJSON(t, router, tt.path)
+			data, ok := body["data"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("data = %v, want an object", body["data"])
+			}
+			for _, field := range tt.fields {
+				inData, ok := data[field]
+				if !ok {
+					t.Errorf("%s missing from data", field)
+				}
+				if top, ok := body[field]; !ok || top != inData {
+					t.Errorf("top-level %s = %v, want %v as in data", field, top, inData)
+				}
+			}
+		})
+	}
+}
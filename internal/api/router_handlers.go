@@ -1,12 +1,19 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"nat-management-app/internal/middleware"
 	"nat-management-app/internal/models"
 	"nat-management-app/internal/services"
+	"nat-management-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -15,22 +22,95 @@ import (
 // RouterHandler contains the router management API handlers
 type RouterHandler struct {
 	routerService      services.RouterServiceInterface
-	natService         *services.NATService
+	natService         services.NATServiceInterface
+	userService        *services.UserService
+	userAccess         services.UserAccessLookup  // Per-user router restrictions (nil = role only)
+	routerUsers        services.RouterUsersLookup // Users assigned to a router, for the impact preview
 	activityLogService *services.ActivityLogService
 	logger             *logrus.Logger
 }
 
 // NewRouterHandler creates a new router API handler
-func NewRouterHandler(routerService services.RouterServiceInterface, natService *services.NATService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *RouterHandler {
-	return &RouterHandler{
+func NewRouterHandler(routerService services.RouterServiceInterface, natService services.NATServiceInterface, userService *services.UserService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *RouterHandler {
+	h := &RouterHandler{
 		routerService:      routerService,
 		natService:         natService,
+		userService:        userService,
 		activityLogService: activityLogService,
 		logger:             logger,
 	}
+	if userService != nil {
+		h.userAccess = userService
+		h.routerUsers = userService
+	}
+	return h
+}
+
+// userRouterRestriction returns the routers the caller's own access limits
+// them to on top of their role (nil = the role decides alone, empty = no
+// routers). Fails closed with a 503 when the assignments can't be read.
+func (h *RouterHandler) userRouterRestriction(c *gin.Context) ([]string, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists || h.userAccess == nil {
+		return nil, true
+	}
+
+	restriction, err := services.UserRouterRestriction(h.userAccess, user)
+	if err != nil {
+		h.logger.Errorf("Failed to get user-specific routers for user ID %d: %v", user.ID, err)
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router access could not be checked, try again later",
+		})
+		return nil, false
+	}
+	return restriction, true
+}
+
+// requireUserRouter writes a 403 and returns false when the caller's own
+// router access excludes the router in the :id param. A router the role
+// can't see is left for the main call to report.
+func (h *RouterHandler) requireUserRouter(c *gin.Context, userRole string) bool {
+	restriction, ok := h.userRouterRestriction(c)
+	if !ok {
+		return false
+	}
+	if restriction == nil {
+		return true
+	}
+
+	router, err := h.routerService.GetRouter(c.Param("id"), userRole)
+	if err != nil {
+		return true
+	}
+	if restrictionAllows(restriction, router.Name) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, models.ErrorResponse{
+		Status:  "error",
+		Message: "Access denied to this router",
+	})
+	return false
+}
+
+// restrictionAllows reports whether routerName passes a userRouterRestriction
+func restrictionAllows(restriction []string, routerName string) bool {
+	if restriction == nil {
+		return true
+	}
+	for _, name := range restriction {
+		if name == routerName {
+			return true
+		}
+	}
+	return false
 }
 
-// GetRouters handles GET /api/routers - List all routers
+// maxRouterPageSize bounds the limit query parameter of GetRouters
+const maxRouterPageSize = 500
+
+// GetRouters handles GET /api/routers?limit=&offset=&search=&tag= - List routers
+// Without limit every matching router is returned in one page
 func (h *RouterHandler) GetRouters(c *gin.Context) {
 	// Get user role from context
 	userRole, exists := middleware.GetUserRoleFromContext(c)
@@ -42,8 +122,44 @@ func (h *RouterHandler) GetRouters(c *gin.Context) {
 		return
 	}
 
-	// Get routers with role-based filtering
-	routers, err := h.routerService.GetAllRouters(string(userRole))
+	filter := models.RouterListFilter{
+		Search: strings.TrimSpace(c.Query("search")),
+		Tag:    models.NormalizeRouterTag(c.Query("tag")),
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid limit, expected a positive integer",
+			})
+			return
+		}
+		if limit > maxRouterPageSize {
+			limit = maxRouterPageSize
+		}
+		filter.Limit = limit
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid offset, expected a non-negative integer",
+			})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	restriction, ok := h.userRouterRestriction(c)
+	if !ok {
+		return
+	}
+	filter.Names = restriction
+
+	// Get routers with role-based filtering applied before paging
+	routers, total, err := h.routerService.ListRouters(string(userRole), filter)
 	if err != nil {
 		h.logger.Errorf("Failed to get routers for role %s: %v", userRole, err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -56,11 +172,12 @@ func (h *RouterHandler) GetRouters(c *gin.Context) {
 	response := models.RouterListResponse{
 		Status:  "success",
 		Data:    routers,
-		Total:   len(routers),
+		Total:   total,
+		Meta:    models.NewPaginationMeta(total, filter.Limit, filter.Offset),
 		Message: "Routers retrieved successfully",
 	}
 
-	h.logger.Infof("Retrieved %d routers for user role: %s", len(routers), userRole)
+	h.logger.Infof("Retrieved %d of %d routers for user role: %s", len(routers), total, userRole)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -85,8 +202,16 @@ func (h *RouterHandler) GetRouter(c *gin.Context) {
 		return
 	}
 
+	restriction, ok := h.userRouterRestriction(c)
+	if !ok {
+		return
+	}
+
 	// Get router with access control
 	router, err := h.routerService.GetRouter(routerID, string(userRole))
+	if err == nil && !restrictionAllows(restriction, router.Name) {
+		err = errors.New("access denied to router")
+	}
 	if err != nil {
 		h.logger.Errorf("Failed to get router %s for role %s: %v", routerID, userRole, err)
 
@@ -193,6 +318,7 @@ func (h *RouterHandler) CreateRouter(c *gin.Context) {
 				ResourceID:   router.Name,
 				Description:  "Created router: " + router.Name,
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
@@ -293,6 +419,7 @@ func (h *RouterHandler) UpdateRouter(c *gin.Context) {
 				ResourceID:   router.Name,
 				Description:  "Updated router: " + router.Name,
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
@@ -382,6 +509,7 @@ func (h *RouterHandler) DeleteRouter(c *gin.Context) {
 				ResourceID:   routerID,
 				Description:  "Deleted router: " + routerID,
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
@@ -391,6 +519,115 @@ func (h *RouterHandler) DeleteRouter(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// RotateRouterCredentials handles POST /api/routers/:id/rotate-credentials - Rotate RouterOS credentials
+func (h *RouterHandler) RotateRouterCredentials(c *gin.Context) {
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	// Only administrators can rotate router credentials
+	if userRole != "Administrator" {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Insufficient permissions to rotate router credentials",
+		})
+		return
+	}
+
+	routerID := c.Param("id")
+	if routerID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Router ID is required",
+		})
+		return
+	}
+
+	var req models.RouterCredentialRotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.routerService.RotateRouterCredentials(routerID, &req, string(userRole))
+	if err != nil {
+		h.logger.Errorf("Failed to rotate credentials for router %s: %v", routerID, err)
+
+		var verifyErr *services.CredentialVerificationError
+		switch {
+		case errors.As(err, &verifyErr):
+			h.logCredentialRotation(c, verifyErr.Router, models.StatusFailed, "verification failed: "+verifyErr.Test.Message)
+			c.JSON(http.StatusUnprocessableEntity, models.RouterCredentialRotateResponse{
+				Status:       "error",
+				Verification: &verifyErr.Test,
+				Message:      "New credentials failed verification; nothing was saved. Retry with \"force\": true to save anyway.",
+			})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+		default:
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	// Reload NAT service routers so its direct connections use the new credentials
+	if h.natService != nil {
+		if reloadErr := h.natService.ReloadRouters(); reloadErr != nil {
+			h.logger.Warnf("Failed to reload NAT service after credential rotation: %v", reloadErr)
+		}
+	}
+
+	description := "username " + req.Username
+	if response.Verification == nil {
+		description += ", unverified"
+	} else if response.Verification.Status != "connected" {
+		description += ", forced past failed verification"
+	}
+	h.logCredentialRotation(c, response.Data.Name, models.StatusSuccess, description)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// logCredentialRotation records a credential rotation attempt; the password is never logged
+func (h *RouterHandler) logCredentialRotation(c *gin.Context, routerName, status, detail string) {
+	if h.activityLogService == nil {
+		return
+	}
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		return
+	}
+
+	currentUserID := currentUser.ID
+	h.activityLogService.CreateLog(&models.ActivityLogCreate{
+		UserID:       &currentUserID,
+		Username:     currentUser.Username,
+		UserRole:     string(currentUser.Role),
+		ActionType:   models.ActionUpdate,
+		ResourceType: models.ResourceRouter,
+		ResourceID:   routerName,
+		Description:  "Credential rotation for router: " + routerName + " (" + detail + ")",
+		IPAddress:    c.ClientIP(),
+		RequestID:    middleware.GetRequestID(c),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Status:       status,
+	})
+}
+
 // TestRouter handles POST /api/routers/:id/test - Test router connection
 func (h *RouterHandler) TestRouter(c *gin.Context) {
 	// Get user role from context
@@ -413,6 +650,10 @@ func (h *RouterHandler) TestRouter(c *gin.Context) {
 	}
 
 	// Test router connection
+	if !h.requireUserRouter(c, string(userRole)) {
+		return
+	}
+
 	testResult, err := h.routerService.TestRouter(routerID, string(userRole))
 	if err != nil {
 		h.logger.Errorf("Failed to test router %s for role %s: %v", routerID, userRole, err)
@@ -440,8 +681,8 @@ func (h *RouterHandler) TestRouter(c *gin.Context) {
 	c.JSON(http.StatusOK, testResult)
 }
 
-// GetRouterStats handles GET /api/routers/stats - Get router statistics
-func (h *RouterHandler) GetRouterStats(c *gin.Context) {
+// GetRouterLogs handles GET /api/routers/:id/logs - Get the router's own system logs
+func (h *RouterHandler) GetRouterLogs(c *gin.Context) {
 	// Get user role from context
 	userRole, exists := middleware.GetUserRoleFromContext(c)
 	if !exists {
@@ -452,26 +693,69 @@ func (h *RouterHandler) GetRouterStats(c *gin.Context) {
 		return
 	}
 
-	// Get router statistics with role-based filtering
-	stats, err := h.routerService.GetRouterStats(string(userRole))
-	if err != nil {
-		h.logger.Errorf("Failed to get router stats for role %s: %v", userRole, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+	routerID := c.Param("id")
+	if routerID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Status:  "error",
-			Message: "Failed to retrieve router statistics",
+			Message: "Router ID is required",
 		})
 		return
 	}
 
-	h.logger.Infof("Retrieved router stats for user role: %s (Total: %d, Active: %d)",
-		userRole, stats.TotalRouters, stats.ActiveRouters)
-	c.JSON(http.StatusOK, stats)
+	// Parse filters: ?topics=pppoe,error&severity=warning&limit=100
+	filter := models.RouterLogFilter{}
+	if topics := c.Query("topics"); topics != "" {
+		for _, topic := range strings.Split(topics, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				filter.Topics = append(filter.Topics, topic)
+			}
+		}
+	}
+	if severity := strings.TrimSpace(c.Query("severity")); severity != "" {
+		filter.Severity = severity
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filter.Limit = limit
+		}
+	}
+
+	if !h.requireUserRouter(c, string(userRole)) {
+		return
+	}
+
+	logs, err := h.routerService.GetRouterLogs(routerID, filter, string(userRole))
+	if err != nil {
+		h.logger.Errorf("Failed to get logs for router %s (role %s): %v", routerID, userRole, err)
+
+		if strings.HasPrefix(err.Error(), "router not found") {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+		} else if err.Error() == "access denied to router" {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Status:  "error",
+				Message: "Access denied to this router",
+			})
+		} else if errors.Is(err, services.ErrRouterBusy) {
+			utils.RespondRouterBusy(c, routerID)
+		} else {
+			c.JSON(http.StatusBadGateway, models.ErrorResponse{
+				Status:  "error",
+				Message: "Failed to read router logs",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
 }
 
-// ValidateRouter handles POST /api/routers/validate - Validate router configuration without saving
-func (h *RouterHandler) ValidateRouter(c *gin.Context) {
+// GetConnTrack handles GET /api/routers/:id/conntrack - Get connection tracking entries for a client IP
+func (h *RouterHandler) GetConnTrack(c *gin.Context) {
 	// Get user role from context
-	_, exists := middleware.GetUserRoleFromContext(c)
+	userRole, exists := middleware.GetUserRoleFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Status:  "error",
@@ -480,88 +764,65 @@ func (h *RouterHandler) ValidateRouter(c *gin.Context) {
 		return
 	}
 
-	var req models.RouterCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Errorf("Invalid router validation request: %v", err)
+	routerID := c.Param("id")
+	if routerID == "" {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Status:  "error",
-			Message: "Invalid request format: " + err.Error(),
+			Message: "Router ID is required",
 		})
 		return
 	}
 
-	// Basic validation (without creating the router)
-	var errors []models.RouterValidationError
-
-	if req.Name == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "name",
-			Message: "Router name is required",
-		})
-	}
-
-	if req.Host == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "host",
-			Message: "Router host is required",
-		})
-	}
-
-	if req.Port < 1 || req.Port > 65535 {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "port",
-			Message: "Port must be between 1 and 65535",
-			Value:   strconv.Itoa(req.Port),
-		})
-	}
-
-	if req.Username == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "username",
-			Message: "Username is required",
+	ip := strings.TrimSpace(c.Query("ip"))
+	if net.ParseIP(ip) == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "A valid ip query parameter is required",
 		})
+		return
 	}
 
-	if req.Password == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "password",
-			Message: "Password is required",
-		})
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
 	}
 
-	if req.TunnelEndpoint == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "tunnel_endpoint",
-			Message: "Tunnel endpoint is required",
-		})
+	if !h.requireUserRouter(c, string(userRole)) {
+		return
 	}
 
-	if req.PublicONTURL == "" {
-		errors = append(errors, models.RouterValidationError{
-			Field:   "public_ont_url",
-			Message: "Public ONT URL is required",
-		})
-	}
+	conntrack, err := h.routerService.GetConnTrack(routerID, ip, limit, string(userRole))
+	if err != nil {
+		h.logger.Errorf("Failed to get conntrack for %s on router %s (role %s): %v", ip, routerID, userRole, err)
 
-	if len(errors) > 0 {
-		response := models.RouterValidationResponse{
-			Status: "error",
-			Errors: errors,
+		if strings.HasPrefix(err.Error(), "router not found") {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+		} else if err.Error() == "access denied to router" {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Status:  "error",
+				Message: "Access denied to this router",
+			})
+		} else if errors.Is(err, services.ErrRouterBusy) {
+			utils.RespondRouterBusy(c, routerID)
+		} else {
+			c.JSON(http.StatusBadGateway, models.ErrorResponse{
+				Status:  "error",
+				Message: "Failed to read connection tracking",
+			})
 		}
-		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	// If validation passes
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Router configuration is valid",
-	})
+	c.JSON(http.StatusOK, conntrack)
 }
 
-// ReloadConfiguration handles POST /api/routers/reload - Reload router configuration from file
-func (h *RouterHandler) ReloadConfiguration(c *gin.Context) {
-	// Get user role from context
+// GetRouterImpact handles GET /api/routers/:id/impact - What disabling a router would affect (Administrator only)
+func (h *RouterHandler) GetRouterImpact(c *gin.Context) {
 	userRole, exists := middleware.GetUserRoleFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
@@ -570,34 +831,759 @@ func (h *RouterHandler) ReloadConfiguration(c *gin.Context) {
 		})
 		return
 	}
-
-	// Only administrators can reload configuration
-	if userRole != "Administrator" {
+	if userRole != models.RoleAdministrator {
 		c.JSON(http.StatusForbidden, models.ErrorResponse{
 			Status:  "error",
-			Message: "Insufficient permissions to reload configuration",
+			Message: "Only administrators can preview router impact",
 		})
 		return
 	}
 
-	// Reload configuration
-	err := h.routerService.ReloadConfiguration()
+	routerID := c.Param("id")
+	router, err := h.routerService.GetRouter(routerID, string(userRole))
 	if err != nil {
-		h.logger.Errorf("Failed to reload router configuration: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Status:  "error",
-			Message: "Failed to reload configuration: " + err.Error(),
-		})
-		return
-	}
-
-	h.logger.Infof("Router configuration reloaded by user role: %s", userRole)
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Router configuration reloaded successfully",
-		"config_file": h.routerService.GetConfigurationPath(),
-	})
-}
+		h.logger.Errorf("Failed to get router %s for impact preview: %v", routerID, err)
+		if err.Error() == "router not found" {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Status:  "error",
+				Message: "Failed to retrieve router",
+			})
+		}
+		return
+	}
+
+	impact := models.RouterImpact{
+		RouterID:   router.ID,
+		RouterName: router.Name,
+		Enabled:    router.Enabled,
+		Clients:    []models.NATClient{},
+		Users:      []models.RouterImpactUser{},
+	}
+
+	// Online clients, from the clients cache when it is fresh
+	impact.ClientsSource = "live"
+	if router.Enabled && h.natService.HasRouter(router.Name) {
+		clients, fromCache, err := h.natService.CachedRouterClients(router.Name)
+		if fromCache {
+			impact.ClientsSource = "cache"
+		}
+		if err != nil {
+			impact.ClientsError = err.Error()
+		} else if clients != nil {
+			impact.Clients = clients
+		}
+
+		if config, ok := h.natService.GetONTConfigsForRouters([]string{router.Name})[router.Name]; ok && config.Found {
+			impact.HasONTRule = true
+			impact.ONTRule = &config
+		}
+	} else {
+		impact.ClientsError = "Router is disabled or not loaded; online clients unknown"
+	}
+	impact.OnlineClients = len(impact.Clients)
+
+	// Users assigned to the router, flagging those for whom it's the only one
+	users, err := h.routerUsers.GetRouterUsers(router.Name)
+	if err != nil {
+		h.logger.Errorf("Failed to get users assigned to router %s: %v", router.Name, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve assigned users",
+		})
+		return
+	}
+	for _, user := range users {
+		impact.Users = append(impact.Users, models.RouterImpactUser{
+			ID:         user.ID,
+			Username:   user.Username,
+			FullName:   user.FullName,
+			Role:       user.Role,
+			OnlyRouter: len(user.Routers) == 1,
+		})
+	}
+	impact.AssignedUsers = len(impact.Users)
+
+	utils.RespondSuccess(c, impact)
+}
+
+// GetRouterCircuit handles GET /api/routers/:id/circuit - Circuit breaker state of a router
+func (h *RouterHandler) GetRouterCircuit(c *gin.Context) {
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	router, ok := h.circuitRouter(c, string(userRole))
+	if !ok {
+		return
+	}
+
+	circuit := h.routerService.GetRouterCircuit(router.Name)
+	circuit.RouterID = router.ID
+	utils.RespondSuccess(c, circuit)
+}
+
+// ResetRouterCircuit handles POST /api/routers/:id/circuit/reset - Force-close a router's circuit breaker (Administrator only)
+func (h *RouterHandler) ResetRouterCircuit(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Only administrators can reset circuit breakers",
+		})
+		return
+	}
+
+	router, ok := h.circuitRouter(c, string(currentUser.Role))
+	if !ok {
+		return
+	}
+
+	previous := h.routerService.GetRouterCircuit(router.Name)
+	circuit := h.routerService.ResetRouterCircuit(router.Name)
+	circuit.RouterID = router.ID
+
+	h.logger.Infof("Circuit breaker for router %s reset by %s (was %s)", router.Name, currentUser.Username, previous.State)
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionUpdate,
+			ResourceType: models.ResourceRouter,
+			ResourceID:   router.Name,
+			Description:  fmt.Sprintf("Reset circuit breaker for router %s (was %s, %d failures)", router.Name, previous.State, previous.Failures),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, "Circuit breaker reset", circuit)
+}
+
+// circuitRouter looks up the router in the :id param for the circuit endpoints,
+// writing the error response when it is missing or not accessible
+func (h *RouterHandler) circuitRouter(c *gin.Context, userRole string) (*models.RouterResponse, bool) {
+	if !h.requireUserRouter(c, userRole) {
+		return nil, false
+	}
+
+	routerID := c.Param("id")
+	router, err := h.routerService.GetRouter(routerID, userRole)
+	if err != nil {
+		h.logger.Errorf("Failed to get router %s for circuit breaker: %v", routerID, err)
+		if strings.HasPrefix(err.Error(), "router not found") {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Router not found",
+			})
+		} else if err.Error() == "access denied to router" {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Status:  "error",
+				Message: "Access denied to this router",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Status:  "error",
+				Message: "Failed to retrieve router",
+			})
+		}
+		return nil, false
+	}
+	return router, true
+}
+
+// GetPoolStats handles GET /api/routers/pool/stats (Administrator only)
+// Reports RouterOS connection pool usage per router
+func (h *RouterHandler) GetPoolStats(c *gin.Context) {
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+	if userRole != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Only administrators can view connection pool statistics",
+		})
+		return
+	}
+
+	utils.RespondSuccess(c, h.routerService.GetPoolStats())
+}
+
+// GetRouterStats handles GET /api/routers/stats?group_by=tag - Get router statistics
+func (h *RouterHandler) GetRouterStats(c *gin.Context) {
+	// Get user role from context
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "tag" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid group_by, expected tag",
+		})
+		return
+	}
+
+	restriction, ok := h.userRouterRestriction(c)
+	if !ok {
+		return
+	}
+
+	// Get router statistics with role-based filtering
+	stats, err := h.routerService.GetRouterStats(string(userRole), restriction, groupBy == "tag")
+	if err != nil {
+		h.logger.Errorf("Failed to get router stats for role %s: %v", userRole, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to retrieve router statistics",
+		})
+		return
+	}
+
+	h.logger.Infof("Retrieved router stats for user role: %s (Total: %d, Active: %d)",
+		userRole, stats.TotalRouters, stats.ActiveRouters)
+	c.JSON(http.StatusOK, stats)
+}
+
+// ValidateRouter handles POST /api/routers/validate - Validate router configuration without saving
+func (h *RouterHandler) ValidateRouter(c *gin.Context) {
+	// Get user role from context
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.RouterCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("Invalid router validation request: %v", err)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	// Basic validation (without creating the router)
+	errors := validateRouterFields(&req)
+
+	if len(errors) > 0 {
+		response := models.RouterValidationResponse{
+			Status: "error",
+			Errors: errors,
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// If validation passes
+	utils.RespondSuccessWithMessage(c, "Router configuration is valid", nil)
+}
+
+// maxRouterBatchValidate caps how many configs one batch validation may contain
+const maxRouterBatchValidate = 100
+
+// routerBatchTestWorkers bounds concurrent connectivity tests in batch validation
+const routerBatchTestWorkers = 5
+
+// ValidateRouterBatch handles POST /api/routers/validate-batch - Validate many router configurations without saving
+func (h *RouterHandler) ValidateRouterBatch(c *gin.Context) {
+	// Get user role from context
+	_, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	var req models.RouterBatchValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("Invalid router batch validation request: %v", err)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Routers) > maxRouterBatchValidate {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("At most %d routers can be validated at once", maxRouterBatchValidate),
+		})
+		return
+	}
+
+	results := make([]models.RouterBatchValidateResult, len(req.Routers))
+	seenNames := make(map[string]int)
+	for i := range req.Routers {
+		router := &req.Routers[i]
+		errors := validateRouterFields(router)
+
+		key := models.RouterNameKey(router.Name)
+		if first, dup := seenNames[key]; dup && key != "" {
+			errors = append(errors, models.RouterValidationError{
+				Field:   "name",
+				Message: fmt.Sprintf("Duplicate router name (same as item %d)", first),
+				Value:   router.Name,
+			})
+		} else {
+			seenNames[key] = i
+		}
+
+		results[i] = models.RouterBatchValidateResult{
+			Index:  i,
+			Name:   router.Name,
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		}
+	}
+
+	// Optional connectivity tests, only for configs that passed field validation
+	if req.TestConnectivity {
+		sem := make(chan struct{}, routerBatchTestWorkers)
+		var wg sync.WaitGroup
+		for i := range results {
+			if !results[i].Valid {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				router := req.Routers[i]
+				test := h.routerService.TestRouterConfig(services.ConnectionConfig{
+					Host:          router.Host,
+					Port:          router.Port,
+					Username:      router.Username,
+					Password:      router.Password,
+					UseTLS:        router.UseTLS,
+					TLSSkipVerify: router.TLSSkipVerify,
+				})
+				results[i].Connection = &test
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	response := models.RouterBatchValidateResponse{
+		Status:  "success",
+		Results: results,
+	}
+	for _, result := range results {
+		if result.Valid {
+			response.Valid++
+		} else {
+			response.Invalid++
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// validateRouterFields runs the field-level checks shared by single and batch validation
+func validateRouterFields(req *models.RouterCreateRequest) []models.RouterValidationError {
+	var errors []models.RouterValidationError
+
+	req.Name = models.NormalizeRouterName(req.Name)
+	if req.Name == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "name",
+			Message: "Router name is required",
+		})
+	} else if err := models.ValidateRouterName(req.Name); err != nil {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "name",
+			Message: err.Error(),
+			Value:   req.Name,
+		})
+	}
+
+	if req.Host == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "host",
+			Message: "Router host is required",
+		})
+	}
+
+	if req.Port < 1 || req.Port > 65535 {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "port",
+			Message: "Port must be between 1 and 65535",
+			Value:   strconv.Itoa(req.Port),
+		})
+	}
+
+	if req.Username == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "username",
+			Message: "Username is required",
+		})
+	}
+
+	if req.Password == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "password",
+			Message: "Password is required",
+		})
+	}
+
+	if req.TunnelEndpoint == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "tunnel_endpoint",
+			Message: "Tunnel endpoint is required",
+		})
+	}
+
+	if err := models.ValidateONTCommentPatterns(req.ONTCommentPatterns); err != nil {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "ont_comment_patterns",
+			Message: err.Error(),
+		})
+	}
+
+	req.Tags = models.NormalizeRouterTags(req.Tags)
+	if err := models.ValidateRouterTags(req.Tags); err != nil {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "tags",
+			Message: err.Error(),
+		})
+	}
+
+	if req.PublicONTURL == "" {
+		errors = append(errors, models.RouterValidationError{
+			Field:   "public_ont_url",
+			Message: "Public ONT URL is required",
+		})
+	}
+
+	return errors
+}
+
+// ExportRouters handles GET /api/routers/export?include_passwords= - Export routers
+// Returns the accessible routers in import form; passwords only for administrators that ask
+func (h *RouterHandler) ExportRouters(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	includePasswords := false
+	if raw := c.Query("include_passwords"); raw != "" {
+		var err error
+		includePasswords, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid include_passwords value",
+			})
+			return
+		}
+	}
+	if includePasswords && currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Only administrators can export router passwords",
+		})
+		return
+	}
+
+	restriction, ok := h.userRouterRestriction(c)
+	if !ok {
+		return
+	}
+
+	routers, err := h.routerService.ExportRouters(string(currentUser.Role), includePasswords)
+	if err != nil {
+		h.logger.Errorf("Failed to export routers for %s: %v", currentUser.Username, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to export routers",
+		})
+		return
+	}
+	if restriction != nil {
+		allowed := routers[:0]
+		for _, router := range routers {
+			if restrictionAllows(restriction, router.Name) {
+				allowed = append(allowed, router)
+			}
+		}
+		routers = allowed
+	}
+
+	h.logger.Infof("📦 %d routers exported by %s (passwords included: %v)", len(routers), currentUser.Username, includePasswords)
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionExport,
+			ResourceType: models.ResourceRouter,
+			ResourceID:   "export",
+			Description:  fmt.Sprintf("Exported %d routers (passwords included: %v)", len(routers), includePasswords),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.RouterExportResponse{
+		Status:           "success",
+		Routers:          routers,
+		Total:            len(routers),
+		IncludePasswords: includePasswords,
+		ExportedAt:       time.Now(),
+	})
+}
+
+// ImportRouters handles POST /api/routers/import - Bulk create routers (Administrator only)
+// Existing routers are skipped unless overwrite is set
+func (h *RouterHandler) ImportRouters(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Insufficient permissions to import routers",
+		})
+		return
+	}
+
+	var req models.RouterImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Routers) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: "No routers to import",
+		})
+		return
+	}
+	if len(req.Routers) > services.MaxRouterImportRows {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("At most %d routers per import", services.MaxRouterImportRows),
+		})
+		return
+	}
+
+	response := models.RouterImportResponse{Status: "success"}
+	for i := range req.Routers {
+		item := &req.Routers[i]
+		label := fmt.Sprintf("item %d", i+1)
+		if item.Name != "" {
+			label += " (" + item.Name + ")"
+		}
+
+		outcome, err := h.routerService.ImportRouter(item, req.Overwrite, string(currentUser.Role))
+		if err != nil {
+			response.Failed++
+			response.FailedItems = append(response.FailedItems, label+": "+err.Error())
+			continue
+		}
+		if outcome == services.ImportSkipped {
+			response.Skipped++
+			continue
+		}
+		response.Imported++
+	}
+	response.Message = fmt.Sprintf("Imported %d of %d routers", response.Imported, len(req.Routers))
+
+	// One reload for the whole batch
+	if response.Imported > 0 && h.natService != nil {
+		if reloadErr := h.natService.ReloadRouters(); reloadErr != nil {
+			h.logger.Warnf("Failed to reload NAT service after router import: %v", reloadErr)
+		}
+	}
+
+	h.logger.Infof("📥 Router import by %s: %d imported, %d skipped, %d failed",
+		currentUser.Username, response.Imported, response.Skipped, response.Failed)
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		status := models.StatusSuccess
+		if response.Failed > 0 {
+			status = models.StatusFailed
+		}
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionCreate,
+			ResourceType: models.ResourceRouter,
+			ResourceID:   "import",
+			Description:  fmt.Sprintf("Imported routers: %d imported, %d skipped, %d failed", response.Imported, response.Skipped, response.Failed),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       status,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// BackupRouters handles POST /api/routers/backup - Write all routers to a backup file (Administrator only)
+func (h *RouterHandler) BackupRouters(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Insufficient permissions to back up routers",
+		})
+		return
+	}
+
+	// The body is optional, an empty one backs up without passwords
+	var req models.RouterBackupRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Status:  "error",
+				Message: "Invalid request data: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	response, err := h.routerService.BackupRouters(req.BackupLocation, req.IncludePasswords, string(currentUser.Role))
+	if err != nil {
+		h.logger.Errorf("Failed to back up routers: %v", err)
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid backup file name") {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to back up routers: " + err.Error(),
+		})
+		return
+	}
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionExport,
+			ResourceType: models.ResourceRouter,
+			ResourceID:   "backup",
+			Description:  fmt.Sprintf("Backed up %d routers to %s (passwords included: %v)", response.RouterCount, response.BackupLocation, req.IncludePasswords),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ReloadConfiguration handles POST /api/routers/reload - Reload router configuration from file
+func (h *RouterHandler) ReloadConfiguration(c *gin.Context) {
+	// Get user role from context
+	userRole, exists := middleware.GetUserRoleFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Status:  "error",
+			Message: "Authentication required",
+		})
+		return
+	}
+
+	// Only administrators can reload configuration
+	if userRole != "Administrator" {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Status:  "error",
+			Message: "Insufficient permissions to reload configuration",
+		})
+		return
+	}
+
+	// Reload configuration
+	err := h.routerService.ReloadConfiguration()
+	if err != nil {
+		h.logger.Errorf("Failed to reload router configuration: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to reload configuration: " + err.Error(),
+		})
+		return
+	}
+
+	h.logger.Infof("Router configuration reloaded by user role: %s", userRole)
+	config := gin.H{"config_file": h.routerService.GetConfigurationPath()}
+	utils.RespondSuccessWithLegacy(c, "Router configuration reloaded successfully", config, config)
+}
 
 // GetConfigurationInfo handles GET /api/routers/config - Get configuration file information
 func (h *RouterHandler) GetConfigurationInfo(c *gin.Context) {
@@ -622,9 +1608,6 @@ func (h *RouterHandler) GetConfigurationInfo(c *gin.Context) {
 
 	configPath := h.routerService.GetConfigurationPath()
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":      "success",
-		"config_file": configPath,
-		"message":     "Configuration information retrieved successfully",
-	})
+	config := gin.H{"config_file": configPath}
+	utils.RespondSuccessWithLegacy(c, "Configuration information retrieved successfully", config, config)
 }
\ No newline at end of file
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/services/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// stubRouterService records calls to the router service methods the tests
+// override; any other method panics on the nil embedded interface
+type stubRouterService struct {
+	services.RouterServiceInterface
+
+	routers    map[string]string // router ID -> name
+	disabled   map[string]bool   // router ID -> disabled
+	logFilter  models.RouterLogFilter
+	listFilter models.RouterListFilter
+	rotateErr  error
+	rotated    []models.RouterCredentialRotateRequest
+
+	mu     sync.Mutex
+	tested []string // Hosts passed to TestRouterConfig
+}
+
+func (s *stubRouterService) GetRouter(routerID string, userRole string) (*models.RouterResponse, error) {
+	name, ok := s.routers[routerID]
+	if !ok {
+		return nil, errors.New("router not found")
+	}
+	return &models.RouterResponse{ID: routerID, Name: name, Enabled: !s.disabled[routerID]}, nil
+}
+
+func (s *stubRouterService) ListRouters(userRole string, filter models.RouterListFilter) ([]models.RouterResponse, int, error) {
+	s.listFilter = filter
+	return []models.RouterResponse{}, 0, nil
+}
+
+func (s *stubRouterService) GetRouterLogs(routerID string, filter models.RouterLogFilter, userRole string) (*models.RouterLogsResponse, error) {
+	s.logFilter = filter
+	return &models.RouterLogsResponse{Status: "success", RouterID: routerID, Data: []models.RouterLogEntry{}}, nil
+}
+
+func (s *stubRouterService) RotateRouterCredentials(routerID string, req *models.RouterCredentialRotateRequest, userRole string) (*models.RouterCredentialRotateResponse, error) {
+	if s.rotateErr != nil {
+		return nil, s.rotateErr
+	}
+	s.rotated = append(s.rotated, *req)
+	test := models.RouterConnectionTest{Status: "connected"}
+	return &models.RouterCredentialRotateResponse{
+		Status:       "success",
+		Data:         models.RouterResponse{ID: routerID, Name: s.routers[routerID]},
+		Verification: &test,
+	}, nil
+}
+
+func (s *stubRouterService) TestRouterConfig(config services.ConnectionConfig) models.RouterConnectionTest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tested = append(s.tested, config.Host)
+	return models.RouterConnectionTest{Status: "connected"}
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// withRole sets the authenticated role the way the auth middleware does
+func withRole(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_role", role)
+		c.Next()
+	}
+}
+
+func TestGetRouterLogsSeverityIsSeparateFromTopics(t *testing.T) {
+	stub := &stubRouterService{}
+	h := NewRouterHandler(stub, nil, nil, nil, testLogger())
+
+	router := gin.New()
+	router.GET("/api/routers/:id/logs", withRole(models.RoleAdministrator), h.GetRouterLogs)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/routers/r1/logs?topics=pppoe,+ppp&severity=error&limit=20", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	filter := stub.logFilter
+	if len(filter.Topics) != 2 || filter.Topics[0] != "pppoe" || filter.Topics[1] != "ppp" {
+		t.Errorf("Topics = %q, want [pppoe ppp]", filter.Topics)
+	}
+	if filter.Severity != "error" {
+		t.Errorf("Severity = %q, want error", filter.Severity)
+	}
+	if filter.Limit != 20 {
+		t.Errorf("Limit = %d, want 20", filter.Limit)
+	}
+}
+
+func TestRouterEndpointsApplyUserRouterAccess(t *testing.T) {
+	head := &models.User{ID: 4, Username: "head1", Role: models.RoleHeadBranch1}
+
+	tests := []struct {
+		name       string
+		access     *mocks.UserAccess
+		path       string
+		wantStatus int
+	}{
+		{"role decides without assignments", &mocks.UserAccess{}, "/api/routers/r1/logs", http.StatusOK},
+		{"assigned router", &mocks.UserAccess{Assignments: map[int][]string{4: {"SAMSAT"}}}, "/api/routers/r1/logs", http.StatusOK},
+		{"router outside the assignments", &mocks.UserAccess{Assignments: map[int][]string{4: {"LANE1"}}}, "/api/routers/r1/logs", http.StatusForbidden},
+		{"no router access", &mocks.UserAccess{NoAccess: map[int]bool{4: true}}, "/api/routers/r1/logs", http.StatusForbidden},
+		{"no router access on router detail", &mocks.UserAccess{NoAccess: map[int]bool{4: true}}, "/api/routers/r1", http.StatusForbidden},
+		{"lookup error fails closed", &mocks.UserAccess{Err: errors.New("db down")}, "/api/routers/r1/logs", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubRouterService{routers: map[string]string{"r1": "SAMSAT"}}
+			h := NewRouterHandler(stub, nil, nil, nil, testLogger())
+			h.userAccess = tt.access
+
+			router := gin.New()
+			router.GET("/api/routers/:id", withUser(head), h.GetRouter)
+			router.GET("/api/routers/:id/logs", withUser(head), h.GetRouterLogs)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetRoutersPassesUserRestriction(t *testing.T) {
+	head := &models.User{ID: 4, Username: "head1", Role: models.RoleHeadBranch1}
+
+	tests := []struct {
+		name   string
+		access *mocks.UserAccess
+		want   []string
+	}{
+		{"role only", &mocks.UserAccess{}, nil},
+		{"assignments", &mocks.UserAccess{Assignments: map[int][]string{4: {"LANE1"}}}, []string{"LANE1"}},
+		{"no router access", &mocks.UserAccess{NoAccess: map[int]bool{4: true}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubRouterService{}
+			h := NewRouterHandler(stub, nil, nil, nil, testLogger())
+			h.userAccess = tt.access
+
+			router := gin.New()
+			router.GET("/api/routers", withUser(head), h.GetRouters)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/routers", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			names := stub.listFilter.Names
+			if (names == nil) != (tt.want == nil) || len(names) != len(tt.want) {
+				t.Fatalf("Names = %#v, want %#v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("Names = %#v, want %#v", names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// stubRouterUsers returns canned router assignments
+type stubRouterUsers map[string][]services.UserWithRouters
+
+func (s stubRouterUsers) GetRouterUsers(routerName string) ([]services.UserWithRouters, error) {
+	return s[routerName], nil
+}
+
+func TestGetRouterImpactCountsClientsAndUsers(t *testing.T) {
+	natService := &mocks.NATService{
+		Routers: map[string]string{"SAMSAT": ""},
+		Clients: map[string][]models.NATClient{
+			"SAMSAT": {
+				{Router: "SAMSAT", Username: "alice", IPAddress: "10.0.0.2"},
+				{Router: "SAMSAT", Username: "bob", IPAddress: "10.0.0.3"},
+				{Router: "SAMSAT", Username: "carol", IPAddress: "10.0.0.4"},
+			},
+		},
+		ONTConfigs: map[string]models.ONTConfig{"SAMSAT": {Found: true}},
+	}
+	users := stubRouterUsers{"SAMSAT": {
+		{User: models.User{ID: 2, Username: "head1", Role: models.RoleHeadBranch1}, Routers: []string{"SAMSAT"}},
+		{User: models.User{ID: 3, Username: "head1b", Role: models.RoleHeadBranch1}, Routers: []string{"SAMSAT", "LANE1"}},
+	}}
+	stub := &stubRouterService{
+		routers:  map[string]string{"r1": "SAMSAT", "r2": "SAMSAT"},
+		disabled: map[string]bool{"r2": true},
+	}
+	h := NewRouterHandler(stub, natService, nil, nil, testLogger())
+	h.routerUsers = users
+
+	router := gin.New()
+	router.GET("/api/routers/:id/impact", withRole(models.RoleAdministrator), h.GetRouterImpact)
+
+	impact := func(id string) models.RouterImpact {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/routers/"+id+"/impact", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET impact of %s: status = %d, body %s", id, w.Code, w.Body.String())
+		}
+		var body struct {
+			Data models.RouterImpact `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Data
+	}
+
+	got := impact("r1")
+	if got.RouterName != "SAMSAT" || !got.Enabled {
+		t.Fatalf("router = %s (enabled %v), want enabled SAMSAT", got.RouterName, got.Enabled)
+	}
+	if got.OnlineClients != 3 || len(got.Clients) != 3 || got.ClientsSource != "live" || got.ClientsError != "" {
+		t.Errorf("clients: %d online, %d listed, source %q, error %q; want 3 live", got.OnlineClients, len(got.Clients), got.ClientsSource, got.ClientsError)
+	}
+	if got.AssignedUsers != 2 || len(got.Users) != 2 {
+		t.Fatalf("assigned users = %d, want 2", got.AssignedUsers)
+	}
+	only := map[string]bool{}
+	for _, user := range got.Users {
+		only[user.Username] = user.OnlyRouter
+	}
+	if !only["head1"] || only["head1b"] {
+		t.Errorf("only_router = %v, want true for head1 alone", only)
+	}
+	if !got.HasONTRule {
+		t.Error("has_ont_rule = false, want the ONT rule reported")
+	}
+
+	// A disabled router isn't queried, but its users still count
+	got = impact("r2")
+	if got.OnlineClients != 0 || got.ClientsError == "" || got.AssignedUsers != 2 {
+		t.Errorf("disabled router: %d online, error %q, %d users; want 0 online with an error and 2 users", got.OnlineClients, got.ClientsError, got.AssignedUsers)
+	}
+}
+
+func TestRotateRouterCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		rotateErr error
+		want      int
+		wantSaved bool
+	}{
+		{"verified and saved", nil, http.StatusOK, true},
+		{"verification failed", &services.CredentialVerificationError{
+			Router: "SAMSAT",
+			Test:   models.RouterConnectionTest{Status: "failed", Message: "invalid user name or password"},
+		}, http.StatusUnprocessableEntity, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubRouterService{routers: map[string]string{"r1": "SAMSAT"}, rotateErr: tt.rotateErr}
+			h := NewRouterHandler(stub, nil, nil, nil, testLogger())
+			router := gin.New()
+			router.POST("/api/routers/:id/rotate-credentials", withRole(models.RoleAdministrator), h.RotateRouterCredentials)
+
+			w := httptest.NewRecorder()
+			body := strings.NewReader(`{"username":"api","password":"new-secret"}`)
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/routers/r1/rotate-credentials", body))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.want, w.Body.String())
+			}
+			if saved := len(stub.rotated) == 1; saved != tt.wantSaved {
+				t.Fatalf("saved = %v, want %v", saved, tt.wantSaved)
+			}
+			if strings.Contains(w.Body.String(), "new-secret") {
+				t.Fatalf("response leaks the password: %s", w.Body.String())
+			}
+
+			var response models.RouterCredentialRotateResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Verification == nil {
+				t.Fatal("response has no verification result")
+			}
+			if !tt.wantSaved && response.Verification.Message != "invalid user name or password" {
+				t.Errorf("verification = %+v, want the failed probe", response.Verification)
+			}
+		})
+	}
+}
+
+func TestValidateRouterBatchReportsEachItem(t *testing.T) {
+	stub := &stubRouterService{}
+	h := NewRouterHandler(stub, nil, nil, nil, testLogger())
+	router := gin.New()
+	router.POST("/api/routers/validate-batch", withRole(models.RoleAdministrator), h.ValidateRouterBatch)
+
+	valid := func(name, host string) map[string]any {
+		return map[string]any{
+			"name": name, "host": host, "port": 8728, "username": "admin", "password": "secret",
+			"tunnel_endpoint": "tunnel.example.net", "public_ont_url": "http://ont.example.net",
+		}
+	}
+	badPort := valid("LANE1", "192.0.2.2")
+	badPort["port"] = 70000
+	missing := valid("LANE2", "")
+	delete(missing, "password")
+	body, err := json.Marshal(map[string]any{
+		"routers": []map[string]any{
+			valid("SAMSAT", "192.0.2.1"),
+			badPort,
+			missing,
+			valid("samsat", "192.0.2.4"),
+			valid("LANE3", "192.0.2.5"),
+		},
+		"test_connectivity": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/routers/validate-batch", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var response models.RouterBatchValidateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Valid != 2 || response.Invalid != 3 || len(response.Results) != 5 {
+		t.Fatalf("valid/invalid = %d/%d over %d results, want 2/3 over 5", response.Valid, response.Invalid, len(response.Results))
+	}
+
+	fields := func(result models.RouterBatchValidateResult) string {
+		var names []string
+		for _, e := range result.Errors {
+			names = append(names, e.Field)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	want := []string{"", "port", "host,password", "name", ""}
+	for i, result := range response.Results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if got := fields(result); got != want[i] {
+			t.Errorf("item %d error fields = %q, want %q", i, got, want[i])
+		}
+		if result.Valid != (want[i] == "") {
+			t.Errorf("item %d valid = %v, want %v", i, result.Valid, want[i] == "")
+		}
+		// Only configs that passed field validation are test-connected
+		if tested := result.Connection != nil; tested != result.Valid {
+			t.Errorf("item %d connection tested = %v, want %v", i, tested, result.Valid)
+		}
+	}
+
+	sort.Strings(stub.tested)
+	if strings.Join(stub.tested, ",") != "192.0.2.1,192.0.2.5" {
+		t.Errorf("tested hosts = %v, want only the two valid configs", stub.tested)
+	}
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"nat-management-app/internal/middleware"
 	"nat-management-app/internal/models"
@@ -10,25 +15,43 @@ import (
 	"nat-management-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 )
 
 // UserHandler handles user management HTTP requests
 type UserHandler struct {
 	userService        *services.UserService
+	userImporter       services.UserImporter // Bulk import, the user service outside tests
+	natService         *services.NATService
+	authService        services.AuthServiceInterface
 	activityLogService *services.ActivityLogService
 	logger             *logrus.Logger
 }
 
 // NewUserHandler creates a new UserHandler instance
-func NewUserHandler(userService *services.UserService, activityLogService *services.ActivityLogService, logger *logrus.Logger) *UserHandler {
+func NewUserHandler(userService *services.UserService, natService *services.NATService, authService services.AuthServiceInterface, activityLogService *services.ActivityLogService, logger *logrus.Logger) *UserHandler {
 	return &UserHandler{
 		userService:        userService,
+		userImporter:       userService,
+		natService:         natService,
+		authService:        authService,
 		activityLogService: activityLogService,
 		logger:             logger,
 	}
 }
 
+// revokeUserSessions logs a user out everywhere. Failures are only logged,
+// the caller's own operation has already succeeded.
+func (h *UserHandler) revokeUserSessions(userID int, reason string) {
+	count, err := h.authService.RevokeAllUserTokens(userID)
+	if err != nil {
+		h.logger.Errorf("Failed to revoke sessions of user %d (%s): %v", userID, reason, err)
+		return
+	}
+	h.logger.Infof("🚫 Revoked %d sessions of user %d (%s)", count, userID, reason)
+}
+
 // CreateUser handles POST /api/users
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req services.CreateUserRequest
@@ -45,7 +68,30 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	// Create user
 	user, err := h.userService.CreateUser(&req)
 	if err != nil {
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			respondPasswordPolicyError(c, "password", policyErr)
+			return
+		}
 		h.logger.Errorf("Error creating user: %v", err)
+		var deletedErr *services.DeletedUserConflictError
+		if errors.As(err, &deletedErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":          "error",
+				"message":         deletedErr.Error(),
+				"deleted_user_id": deletedErr.UserID,
+				"field":           deletedErr.Field,
+				"options":         []string{services.DeletedUserRestore, services.DeletedUserClear},
+			})
+			return
+		}
+		if errors.Is(err, services.ErrNoAccessWithRouters) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Failed to create user: " + err.Error(),
@@ -67,19 +113,241 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 				ActionType:   models.ActionCreate,
 				ResourceType: models.ResourceUser,
 				ResourceID:   strconv.Itoa(user.ID),
-				Description:  "Created user: " + user.Username,
+				Description:  "Created user: " + user.Username + restoredNote(req.DeletedUserPolicy),
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
 		}
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"status":  "success",
-		"message": "User created successfully",
-		"data":    user,
-	})
+	utils.RespondCreated(c, "User created successfully", user)
+}
+
+// respondPasswordPolicyError answers 400 with the broken rule attached to field
+func respondPasswordPolicyError(c *gin.Context, field string, policyErr *services.PasswordPolicyError) {
+	utils.RespondWithError(c, http.StatusBadRequest, models.NewErrorDetail(
+		models.ErrCodeValidationFailed,
+		"Password does not meet the password policy",
+	).WithFieldError(field, policyErr.Message, policyErr.Constraint))
+}
+
+// restoredNote describes how a soft-deleted user's username/email was handled, for activity logs
+func restoredNote(policy string) string {
+	switch policy {
+	case services.DeletedUserRestore:
+		return " (restored deleted user if one matched)"
+	case services.DeletedUserClear:
+		return " (released deleted user's username/email if one matched)"
+	}
+	return ""
+}
+
+// ImportUsers handles POST /api/users/import - Administrator only
+// Accepts a JSON UserImportRequest, or a CSV upload (field "file") with a
+// username,password,full_name,email,routers header (routers separated by ";")
+// and an "overwrite" form value. Each row is imported on its own, so one bad
+// row doesn't stop the rest.
+func (h *UserHandler) ImportUsers(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can import users",
+		})
+		return
+	}
+
+	var req services.UserImportRequest
+	if c.ContentType() == binding.MIMEMultipartPOSTForm {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "CSV file is required (field 'file')",
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Failed to read uploaded file",
+			})
+			return
+		}
+		defer f.Close()
+
+		req.Users, err = parseUserImportCSV(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if raw := c.PostForm("overwrite"); raw != "" {
+			req.Overwrite, _ = strconv.ParseBool(raw)
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warnf("Invalid user import request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Users) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "No users to import",
+		})
+		return
+	}
+	if len(req.Users) > services.MaxUserImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("At most %d users per import", services.MaxUserImportRows),
+		})
+		return
+	}
+
+	result := services.UserImportResponse{}
+	for i := range req.Users {
+		row := &req.Users[i]
+		label := fmt.Sprintf("row %d", i+1)
+		if row.Username != "" {
+			label += " (" + row.Username + ")"
+		}
+
+		// Same rules as POST /api/users
+		if err := binding.Validator.ValidateStruct(row); err != nil {
+			result.Failed++
+			result.FailedItems = append(result.FailedItems, label+": invalid data: "+err.Error())
+			continue
+		}
+
+		outcome, userID, err := h.userImporter.ImportUser(row, req.Overwrite)
+		if err != nil {
+			result.Failed++
+			result.FailedItems = append(result.FailedItems, label+": "+err.Error())
+			continue
+		}
+		if outcome == services.ImportSkipped {
+			result.Skipped++
+			continue
+		}
+		// An overwrite can reset the password: sessions from before must end
+		if outcome == services.ImportOverwritten {
+			h.revokeUserSessions(userID, "overwritten by import")
+		}
+		result.Imported++
+	}
+
+	h.logger.Infof("📥 User import by %s: %d imported, %d skipped, %d failed",
+		currentUser.Username, result.Imported, result.Skipped, result.Failed)
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		status := models.StatusSuccess
+		if result.Failed > 0 {
+			status = models.StatusFailed
+		}
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionCreate,
+			ResourceType: models.ResourceUser,
+			ResourceID:   "import",
+			Description:  fmt.Sprintf("Imported users: %d imported, %d skipped, %d failed", result.Imported, result.Skipped, result.Failed),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       status,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, fmt.Sprintf("Imported %d of %d users", result.Imported, len(req.Users)), result)
+}
+
+// parseUserImportCSV reads users from a CSV with a header row naming the
+// columns. Blank lines are skipped.
+func parseUserImportCSV(r io.Reader) ([]services.CreateUserRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV on line 1: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"username", "password", "full_name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing column '%s'", required)
+		}
+	}
+
+	users := []services.CreateUserRequest{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV on line %d: %v", line, err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		user := services.CreateUserRequest{
+			Username:          field("username"),
+			Password:          field("password"),
+			FullName:          field("full_name"),
+			Email:             field("email"),
+			DeletedUserPolicy: field("deleted_user_policy"),
+		}
+		if user.Username == "" && user.Password == "" && user.FullName == "" && user.Email == "" {
+			continue
+		}
+		for _, router := range strings.Split(field("routers"), ";") {
+			if router = strings.TrimSpace(router); router != "" {
+				user.Routers = append(user.Routers, router)
+			}
+		}
+		user.NoRouterAccess, _ = strconv.ParseBool(field("no_router_access"))
+
+		users = append(users, user)
+		if len(users) > services.MaxUserImportRows {
+			return nil, fmt.Errorf("At most %d users per import", services.MaxUserImportRows)
+		}
+	}
+
+	return users, nil
 }
 
 // GetUser handles GET /api/users/:id
@@ -103,10 +371,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   user,
-	})
+	utils.RespondSuccess(c, user)
 }
 
 // ListUsers handles GET /api/users
@@ -142,16 +407,35 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"status": "success",
-			"data":   users,
-			"total":  len(users),
-		})
+		utils.RespondSuccess(c, users, models.NewPaginationMeta(len(users), 0, 0))
 		return
 	}
 
+	// Soft-deleted users are only listed for administrators on request
+	includeInactive := false
+	if raw := c.Query("include_inactive"); raw != "" {
+		includeInactive, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid include_inactive value",
+			})
+			return
+		}
+	}
+	if includeInactive {
+		currentUser, exists := middleware.GetUserFromContext(c)
+		if !exists || currentUser.Role != models.RoleAdministrator {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Only administrators can list inactive users",
+			})
+			return
+		}
+	}
+
 	// List all users with pagination
-	users, total, err := h.userService.ListUsers(limit, offset)
+	users, total, err := h.userService.ListUsers(limit, offset, includeInactive)
 	if err != nil {
 		h.logger.Errorf("Error listing users: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -161,13 +445,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   users,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
-	})
+	utils.RespondSuccessWithLegacy(c, "", users, gin.H{"limit": limit, "offset": offset}, models.NewPaginationMeta(total, limit, offset))
 }
 
 // UpdateUser handles PUT /api/users/:id
@@ -220,6 +498,20 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		h.logger.Errorf("Error updating user %d: %v", userID, err)
 
 		activityLog.SetAction(models.ActionUpdate, models.ResourceUser, strconv.Itoa(userID), "Failed to update user")
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			activityLog.LogFailed(err.Error())
+			respondPasswordPolicyError(c, "password", policyErr)
+			return
+		}
+		if errors.Is(err, services.ErrNoAccessWithRouters) {
+			activityLog.LogFailed(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
 		activityLog.LogError("Update failed: " + err.Error())
 
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -231,17 +523,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	h.logger.Infof("✅ User %d updated successfully", userID)
 
+	// A deactivated user's existing tokens must stop working now
+	if existingUser.IsActive && !user.IsActive {
+		h.revokeUserSessions(userID, "deactivated")
+	}
+
 	// Capture after state and log success with duration
 	activityLog.SetAction(models.ActionUpdate, models.ResourceUser, strconv.Itoa(userID), "Updated user: "+user.Username)
 	activityLog.AddAfterState(user)
 	activityLog.AddMetadata("updated_fields", getUpdatedFields(existingUser, user))
 	activityLog.LogSuccess()
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "User updated successfully",
-		"data":    user,
-	})
+	utils.RespondSuccessWithMessage(c, "User updated successfully", user)
 }
 
 // getUpdatedFields compares before and after user states to identify changed fields
@@ -298,6 +591,8 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	h.logger.Infof("✅ User %d deleted successfully", userID)
 
+	h.revokeUserSessions(userID, "deleted")
+
 	// Log user deletion
 	if h.activityLogService != nil {
 		currentUser, exists := middleware.GetUserFromContext(c)
@@ -312,15 +607,86 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 				ResourceID:   strconv.Itoa(userID),
 				Description:  "Deleted user ID: " + strconv.Itoa(userID),
 				IPAddress:    c.ClientIP(),
+				RequestID:    middleware.GetRequestID(c),
 				UserAgent:    c.GetHeader("User-Agent"),
 				Status:       models.StatusSuccess,
 			})
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "User deleted successfully",
+	utils.RespondSuccessWithMessage(c, "User deleted successfully", nil)
+}
+
+// RevokeUserSessions handles POST /api/users/:id/revoke-sessions - Administrator only
+// Logs the user out of every session, e.g. after a credential leak
+func (h *UserHandler) RevokeUserSessions(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can revoke user sessions",
+		})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "User not found",
+		})
+		return
+	}
+
+	count, err := h.authService.RevokeAllUserTokens(userID)
+	if err != nil {
+		h.logger.Errorf("Failed to revoke sessions of user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to revoke sessions",
+		})
+		return
+	}
+
+	h.logger.Infof("🚫 %s revoked %d sessions of user %s", currentUser.Username, count, user.Username)
+
+	if h.activityLogService != nil {
+		adminID := currentUser.ID
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &adminID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionLogout,
+			ResourceType: models.ResourceUser,
+			ResourceID:   strconv.Itoa(userID),
+			Description:  fmt.Sprintf("Revoked %d sessions of user: %s", count, user.Username),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       models.StatusSuccess,
+		})
+	}
+
+	utils.RespondSuccessWithMessage(c, "User sessions revoked", gin.H{
+		"user_id":          userID,
+		"revoked_sessions": count,
 	})
 }
 
@@ -345,10 +711,132 @@ func (h *UserHandler) GetUserRouters(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   routers,
-	})
+	utils.RespondSuccess(c, routers)
+}
+
+// GetUserRouterAccess handles GET /api/users/:id/access/:router - Administrator only
+// Explains whether the user can access the router and which rule decided it
+func (h *UserHandler) GetUserRouterAccess(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can inspect user access",
+		})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+		h.logger.Errorf("Error getting user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get user",
+		})
+		return
+	}
+
+	decision := services.ExplainRouterAccess(h.userService, h.natService, &user.User, c.Param("router"))
+
+	utils.RespondSuccess(c, decision)
+}
+
+// GetUserEffectiveRouters handles GET /api/users/:id/effective-routers - Administrator only
+// Lists every router the user is granted with the grant reason, for access reviews
+func (h *UserHandler) GetUserEffectiveRouters(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can inspect user access",
+		})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "User not found",
+			})
+			return
+		}
+		h.logger.Errorf("Error getting user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get user",
+		})
+		return
+	}
+
+	routers, err := h.userService.GetEffectiveRouters(&user.User)
+	if err != nil {
+		h.logger.Errorf("Error getting effective routers for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get effective routers",
+		})
+		return
+	}
+
+	total := len(routers)
+	page := routers[min(offset, total):min(offset+limit, total)]
+
+	utils.RespondSuccess(c, page, models.NewPaginationMeta(total, limit, offset))
 }
 
 // GetRouterUsers handles GET /api/routers/:name/users
@@ -365,11 +853,7 @@ func (h *UserHandler) GetRouterUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   users,
-		"total":  len(users),
-	})
+	utils.RespondSuccess(c, users, models.NewPaginationMeta(len(users), 0, 0))
 }
 
 // GetUserStats handles GET /api/users/:id/stats
@@ -393,10 +877,84 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   stats,
-	})
+	utils.RespondSuccess(c, stats)
+}
+
+// ExportUser handles GET /api/users/:id/export - Administrator only
+// Streams the user's data bundle for data-subject requests
+func (h *UserHandler) ExportUser(c *gin.Context) {
+	currentUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	if currentUser.Role != models.RoleAdministrator {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Only administrators can export user data",
+		})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%d-export.json\"", userID))
+
+	exportErr := h.userService.StreamUserExport(c.Request.Context(), userID, c.Writer)
+	if exportErr != nil {
+		h.logger.Errorf("Error exporting user %d: %v", userID, exportErr)
+		// Headers are gone once streaming has started; only report clean failures
+		if !c.Writer.Written() {
+			statusCode := http.StatusInternalServerError
+			message := "Failed to export user data"
+			if exportErr.Error() == "user not found" {
+				statusCode = http.StatusNotFound
+				message = "User not found"
+			}
+			c.JSON(statusCode, gin.H{
+				"status":  "error",
+				"message": message,
+			})
+		}
+	} else {
+		h.logger.Infof("📦 User %d data exported by %s", userID, currentUser.Username)
+	}
+
+	if h.activityLogService != nil {
+		currentUserID := currentUser.ID
+		status := models.StatusSuccess
+		errorMessage := ""
+		if exportErr != nil {
+			status = models.StatusFailed
+			errorMessage = exportErr.Error()
+		}
+		h.activityLogService.CreateLog(&models.ActivityLogCreate{
+			UserID:       &currentUserID,
+			Username:     currentUser.Username,
+			UserRole:     string(currentUser.Role),
+			ActionType:   models.ActionExport,
+			ResourceType: models.ResourceUser,
+			ResourceID:   strconv.Itoa(userID),
+			Description:  "Exported data for user ID: " + strconv.Itoa(userID),
+			IPAddress:    c.ClientIP(),
+			RequestID:    middleware.GetRequestID(c),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Status:       status,
+			ErrorMessage: errorMessage,
+		})
+	}
 }
 
 // ActivateUser handles PATCH /api/users/:id/activate
@@ -439,11 +997,7 @@ func (h *UserHandler) ActivateUser(c *gin.Context) {
 
 	h.logger.Infof("✅ User %d activated successfully", userID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "User activated successfully",
-		"data":    updatedUser,
-	})
+	utils.RespondSuccessWithMessage(c, "User activated successfully", updatedUser)
 }
 
 // ChangeUserPassword handles PATCH /api/users/:id/password
@@ -458,7 +1012,7 @@ func (h *UserHandler) ChangeUserPassword(c *gin.Context) {
 	}
 
 	var req struct {
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -479,6 +1033,12 @@ func (h *UserHandler) ChangeUserPassword(c *gin.Context) {
 		return
 	}
 
+	var policyErr *services.PasswordPolicyError
+	if err := h.userService.ValidatePassword(req.NewPassword, user.Username); errors.As(err, &policyErr) {
+		respondPasswordPolicyError(c, "new_password", policyErr)
+		return
+	}
+
 	// Update password
 	updateReq := services.UpdateUserRequest{
 		FullName: user.FullName,
@@ -500,8 +1060,5 @@ func (h *UserHandler) ChangeUserPassword(c *gin.Context) {
 
 	h.logger.Infof("✅ Password changed for user %d", userID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Password changed successfully",
-	})
+	utils.RespondSuccessWithMessage(c, "Password changed successfully", nil)
 }
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubImporter imports users by username: known ones are overwritten, the
+// rest created
+type stubImporter struct {
+	existing map[string]int
+}
+
+func (s stubImporter) ImportUser(req *services.CreateUserRequest, overwrite bool) (services.ImportOutcome, int, error) {
+	id, ok := s.existing[req.Username]
+	if !ok {
+		return services.ImportCreated, 100, nil
+	}
+	if !overwrite {
+		return services.ImportSkipped, 0, nil
+	}
+	return services.ImportOverwritten, id, nil
+}
+
+// revokingAuthService records the users whose sessions were revoked
+type revokingAuthService struct {
+	services.AuthServiceInterface
+	revoked []int
+}
+
+func (s *revokingAuthService) RevokeAllUserTokens(userID int) (int, error) {
+	s.revoked = append(s.revoked, userID)
+	return 1, nil
+}
+
+func TestImportUsersOverwriteRevokesSessions(t *testing.T) {
+	admin := &models.User{ID: 1, Username: "admin", Role: models.RoleAdministrator}
+	body := `{"users": [
+		{"username": "head1", "password": "N3w-passw0rd!", "full_name": "Head 1", "email": "head1@example.com"},
+		{"username": "newbie", "password": "N3w-passw0rd!", "full_name": "Newbie", "email": "newbie@example.com"}
+	], "overwrite": %s}`
+
+	for _, overwrite := range []string{"true", "false"} {
+		t.Run("overwrite="+overwrite, func(t *testing.T) {
+			auth := &revokingAuthService{}
+			h := NewUserHandler(nil, nil, auth, nil, testLogger())
+			h.userImporter = stubImporter{existing: map[string]int{"head1": 7}}
+
+			router := gin.New()
+			router.POST("/api/users/import", withUser(admin), h.ImportUsers)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/users/import", strings.NewReader(strings.Replace(body, "%s", overwrite, 1)))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			// Only the overwritten user is logged out; a new user has no sessions
+			want := []int{7}
+			if overwrite == "false" {
+				want = nil
+			}
+			if len(auth.revoked) != len(want) || (len(want) > 0 && auth.revoked[0] != want[0]) {
+				t.Fatalf("revoked sessions of users %v, want %v", auth.revoked, want)
+			}
+		})
+	}
+}
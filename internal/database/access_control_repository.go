@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -244,3 +245,40 @@ func (r *AccessControlRepository) GetAll(ctx context.Context) ([]RouterAccessCon
 
 	return rules, nil
 }
+
+// SetRoleRouters replaces the routers a role can access in one transaction.
+// Routers the role keeps retain their permissions and description; new ones
+// get read/write like the seeded branch roles.
+func (r *AccessControlRepository) SetRoleRouters(ctx context.Context, role string, routerNames []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM router_access_control
+		WHERE role = $1 AND NOT (router_name = ANY($2::text[]))
+	`, role, routerNames)
+	if err != nil {
+		return fmt.Errorf("failed to remove access control for role %s: %w", role, err)
+	}
+
+	for _, routerName := range routerNames {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO router_access_control (role, router_name, permissions, description, updated_at)
+			VALUES ($1, $2, ARRAY['read', 'write'], $3, $4)
+			ON CONFLICT (role, router_name) DO NOTHING
+		`, role, routerName, fmt.Sprintf("Access to %s router", routerName), time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("failed to grant %s to role %s: %w", routerName, role, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit access control for role %s: %w", role, err)
+	}
+
+	r.db.Logger.Infof("✅ Access control for role %s set to %d router(s)", role, len(routerNames))
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"nat-management-app/internal/models"
+)
+
+// CustomerRepository handles database operations for customer records
+type CustomerRepository struct {
+	db *DB
+}
+
+// NewCustomerRepository creates a new customer repository
+func NewCustomerRepository(db *DB) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+// GetByPPPoEUsernames retrieves customers for the given PPPoE usernames, keyed by username.
+// Usernames without a matching record are simply absent from the result.
+func (r *CustomerRepository) GetByPPPoEUsernames(ctx context.Context, usernames []string) (map[string]*models.Customer, error) {
+	customers := make(map[string]*models.Customer)
+	if len(usernames) == 0 {
+		return customers, nil
+	}
+
+	query := `
+		SELECT id, pppoe_username, full_name, COALESCE(address, ''), COALESCE(phone, ''), created_at, updated_at
+		FROM customers
+		WHERE pppoe_username = ANY($1)
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query customers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		customer := &models.Customer{}
+		if err := rows.Scan(
+			&customer.ID,
+			&customer.PPPoEUsername,
+			&customer.FullName,
+			&customer.Address,
+			&customer.Phone,
+			&customer.CreatedAt,
+			&customer.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		customers[customer.PPPoEUsername] = customer
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customers: %w", err)
+	}
+
+	return customers, nil
+}
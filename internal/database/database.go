@@ -72,6 +72,9 @@ func NewDB(logger *logrus.Logger) (*DB, error) {
 	// Transaction pooler doesn't support prepared statements well
 	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
 
+	// Pin every session to UTC so timestamptz values read back the same regardless of server timezone
+	poolConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
 	// Create connection pool with longer timeout for Neon serverless cold start
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testConn connects to TEST_DATABASE_URL with the given session TimeZone in a
+// throwaway schema, skipping the test when no database is configured
+func testConn(t *testing.T, sessionZone string) *pgx.Conn {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	config.RuntimeParams["timezone"] = sessionZone
+
+	ctx := context.Background()
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s", schema, schema)); err != nil {
+		conn.Close(ctx)
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(ctx, fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		conn.Close(ctx)
+	})
+	return conn
+}
+
+func readMigration(t *testing.T, name string) string {
+	t.Helper()
+	sql, err := os.ReadFile("../../migrations/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(sql)
+}
+
+func TestMigration009ConvertsFromServerZone(t *testing.T) {
+	// The session zone differs from the server zone on purpose
+	conn := testConn(t, "America/New_York")
+	ctx := context.Background()
+
+	setup := `
+		CREATE TABLE ont_wifi_info (id INT, extracted_at TIMESTAMP);
+		INSERT INTO ont_wifi_info VALUES (1, '2025-01-15 10:00:00');
+		SET app.legacy_timezone = 'Asia/Jakarta';`
+	if _, err := conn.Exec(ctx, setup); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if _, err := conn.Exec(ctx, readMigration(t, "009_timestamptz_utc.sql")); err != nil {
+		t.Fatalf("migration: %v", err)
+	}
+
+	var dataType string
+	if err := conn.QueryRow(ctx, `SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'ont_wifi_info' AND column_name = 'extracted_at'`).Scan(&dataType); err != nil {
+		t.Fatal(err)
+	}
+	if dataType != "timestamp with time zone" {
+		t.Fatalf("extracted_at is %q after the migration", dataType)
+	}
+
+	// 10:00 in Jakarta (UTC+7) is 03:00 UTC, whatever the session zone
+	var extractedAt time.Time
+	if err := conn.QueryRow(ctx, "SELECT extracted_at FROM ont_wifi_info WHERE id = 1").Scan(&extractedAt); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC)
+	if !extractedAt.Equal(want) {
+		t.Fatalf("extracted_at = %v, want %v", extractedAt.UTC(), want)
+	}
+}
+
+func TestMigration009RequiresServerZone(t *testing.T) {
+	conn := testConn(t, "UTC")
+	ctx := context.Background()
+
+	if _, err := conn.Exec(ctx, "CREATE TABLE ont_wifi_info (id INT, extracted_at TIMESTAMP)"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	_, err := conn.Exec(ctx, readMigration(t, "009_timestamptz_utc.sql"))
+	if err == nil || !strings.Contains(err.Error(), "app.legacy_timezone") {
+		t.Fatalf("migration without app.legacy_timezone: err = %v", err)
+	}
+}
+
+// testDB wraps a pool on the scratch schema of testConn for repository tests
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	conn := testConn(t, "UTC")
+	var schema string
+	if err := conn.QueryRow(context.Background(), "SELECT current_schema()").Scan(&schema); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := pgxpool.ParseConfig(os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return &DB{Pool: pool}
+}
+
+func TestClaimTOTPStepRejectsReplay(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if _, err := db.Pool.Exec(ctx, `CREATE TABLE users (id INT PRIMARY KEY, totp_last_step BIGINT);
+		INSERT INTO users (id) VALUES (1)`); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	repo := NewUserRepository(db)
+
+	steps := []struct {
+		step int64
+		want bool
+	}{
+		{100, true},  // First code
+		{100, false}, // Same code again
+		{99, false},  // An older code
+		{101, true},  // The next code
+	}
+	for _, s := range steps {
+		claimed, err := repo.ClaimTOTPStep(ctx, 1, s.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != s.want {
+			t.Fatalf("ClaimTOTPStep(%d) = %v, want %v", s.step, claimed, s.want)
+		}
+	}
+}
+
+func TestRevokedSessionRepository(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if _, err := db.Pool.Exec(ctx, readMigration(t, "025_create_revoked_sessions.sql")); err != nil {
+		t.Fatalf("migration: %v", err)
+	}
+	repo := NewRevokedSessionRepository(db)
+
+	if err := repo.Add(ctx, []string{"live", "expired"}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// Revoking again never shortens a revocation
+	if err := repo.Add(ctx, []string{"live"}, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool.Exec(ctx, `UPDATE revoked_sessions SET expires_at = NOW() - INTERVAL '1 minute' WHERE session_id = 'expired'`); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := repo.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("DeleteExpired removed %d sessions, want 1", deleted)
+	}
+
+	for sessionID, want := range map[string]bool{"live": true, "expired": false, "never": false} {
+		revoked, err := repo.Exists(ctx, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != want {
+			t.Errorf("Exists(%q) = %v, want %v", sessionID, revoked, want)
+		}
+	}
+}
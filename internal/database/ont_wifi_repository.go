@@ -2,10 +2,14 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"nat-management-app/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // ONTWiFiRepository handles database operations for ONT WiFi information
@@ -42,7 +46,7 @@ func (r *ONTWiFiRepository) SaveWiFiInfo(ctx context.Context, info *models.ONTWi
 		info.ONTModel,
 		info.ExtractedAt,
 		info.ExtractedBy,
-		time.Now(),
+		time.Now().UTC(),
 	).Scan(&info.ID)
 
 	if err != nil {
@@ -121,6 +125,87 @@ func (r *ONTWiFiRepository) GetLatestWiFiInfoByPPPoE(ctx context.Context, pppoeU
 	return info, nil
 }
 
+// FindLatestWiFiInfoByPPPoE is GetLatestWiFiInfoByPPPoE returning nil when
+// the PPPoE username has no WiFi info yet
+func (r *ONTWiFiRepository) FindLatestWiFiInfoByPPPoE(ctx context.Context, pppoeUsername string) (*models.ONTWiFiInfo, error) {
+	info, err := r.GetLatestWiFiInfoByPPPoE(ctx, pppoeUsername)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return info, err
+}
+
+// GetWiFiChanges returns a customer's extractions whose SSID or password
+// differs from the extraction right before it, newest first
+func (r *ONTWiFiRepository) GetWiFiChanges(ctx context.Context, pppoeUsername string, limit int) ([]models.ONTWiFiChange, error) {
+	query := `
+		SELECT id, pppoe_username, router, ssid, password, security, encryption,
+			authentication, ont_url, ont_model, extracted_at, extracted_by, created_at,
+			prev_id, prev_ssid, prev_password, prev_extracted_at
+		FROM (
+			SELECT *,
+				LAG(id) OVER w AS prev_id,
+				LAG(ssid) OVER w AS prev_ssid,
+				LAG(password) OVER w AS prev_password,
+				LAG(extracted_at) OVER w AS prev_extracted_at
+			FROM ont_wifi_info
+			WHERE pppoe_username = $1
+			WINDOW w AS (ORDER BY extracted_at, id)
+		) history
+		WHERE prev_id IS NOT NULL
+			AND (ssid IS DISTINCT FROM prev_ssid OR password IS DISTINCT FROM prev_password)
+		ORDER BY extracted_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, pppoeUsername, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query WiFi changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.ONTWiFiChange{}
+	for rows.Next() {
+		var change models.ONTWiFiChange
+		if err := rows.Scan(
+			&change.ID,
+			&change.PPPoEUsername,
+			&change.Router,
+			&change.SSID,
+			&change.Password,
+			&change.Security,
+			&change.Encryption,
+			&change.Authentication,
+			&change.ONTURL,
+			&change.ONTModel,
+			&change.ExtractedAt,
+			&change.ExtractedBy,
+			&change.CreatedAt,
+			&change.PreviousID,
+			&change.PreviousSSID,
+			&change.PreviousPassword,
+			&change.PreviousExtractedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan WiFi change: %w", err)
+		}
+
+		change.ChangedFields = []string{}
+		if change.SSID != change.PreviousSSID {
+			change.ChangedFields = append(change.ChangedFields, "ssid")
+		}
+		if change.Password != change.PreviousPassword {
+			change.ChangedFields = append(change.ChangedFields, "password")
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating WiFi changes: %w", err)
+	}
+
+	return changes, nil
+}
+
 // GetWiFiHistory retrieves WiFi extraction history with optional filters
 func (r *ONTWiFiRepository) GetWiFiHistory(ctx context.Context, req models.ONTWiFiHistoryRequest) ([]models.ONTWiFiInfo, int, error) {
 	// Build query with filters
@@ -287,49 +372,157 @@ func (r *ONTWiFiRepository) DeleteOldWiFiInfo(ctx context.Context, olderThan tim
 	return rowsAffected, nil
 }
 
-// GetWiFiInfoStats returns statistics about WiFi info records
-func (r *ONTWiFiRepository) GetWiFiInfoStats(ctx context.Context) (map[string]interface{}, error) {
-	query := `
-		SELECT
-			COUNT(*) as total_records,
-			COUNT(DISTINCT pppoe_username) as unique_users,
-			COUNT(DISTINCT router) as unique_routers,
-			COUNT(DISTINCT ont_model) as unique_models,
-			MAX(extracted_at) as latest_extraction,
-			MIN(extracted_at) as oldest_extraction
-		FROM ont_wifi_info
-	`
+// GetWiFiStats aggregates WiFi extraction records and extraction attempts
+// (from activity_logs) over filter's date range
+func (r *ONTWiFiRepository) GetWiFiStats(ctx context.Context, filter models.ONTWiFiStatsFilter) (*models.ONTWiFiStats, error) {
+	start := filter.From
+	end := filter.To.AddDate(0, 0, 1)
+
+	// $1/$2 bound the range; $3 is the router list for non-administrators
+	args := []interface{}{start, end}
+	wifiWhere := "extracted_at >= $1 AND extracted_at < $2"
+	logWhere := "created_at >= $1 AND created_at < $2"
+	if filter.Routers != nil {
+		args = append(args, filter.Routers)
+		wifiWhere += " AND router = ANY($3::text[])"
+		// ONT_WIFI_EXTRACT keeps the router in metadata, the from-NAT variant in resource_id
+		logWhere += " AND COALESCE(metadata->>'router', CASE WHEN resource_type = 'ROUTER' THEN resource_id END) = ANY($3::text[])"
+	}
 
-	var stats struct {
-		TotalRecords      int
-		UniqueUsers       int
-		UniqueRouters     int
-		UniqueModels      int
-		LatestExtraction  *time.Time
-		OldestExtraction  *time.Time
+	stats := &models.ONTWiFiStats{
+		From:          filter.From.Format("2006-01-02"),
+		To:            filter.To.Format("2006-01-02"),
+		PerRouter:     []models.ONTWiFiRouterStats{},
+		SecurityTypes: []models.ONTWiFiSecurityStats{},
+		Series:        []models.ONTWiFiStatsPoint{},
 	}
 
-	err := r.db.Pool.QueryRow(ctx, query).Scan(
-		&stats.TotalRecords,
-		&stats.UniqueUsers,
+	totalsQuery := `
+		SELECT
+			COUNT(*),
+			COUNT(DISTINCT NULLIF(pppoe_username, '')),
+			COUNT(DISTINCT NULLIF(router, '')),
+			COUNT(DISTINCT NULLIF(ont_model, '')),
+			MAX(extracted_at),
+			MIN(extracted_at)
+		FROM ont_wifi_info
+		WHERE ` + wifiWhere
+	err := r.db.Pool.QueryRow(ctx, totalsQuery, args...).Scan(
+		&stats.TotalExtractions,
+		&stats.UniqueCustomers,
 		&stats.UniqueRouters,
 		&stats.UniqueModels,
 		&stats.LatestExtraction,
 		&stats.OldestExtraction,
 	)
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to get WiFi info stats: %w", err)
 	}
 
-	result := map[string]interface{}{
-		"total_records":      stats.TotalRecords,
-		"unique_users":       stats.UniqueUsers,
-		"unique_routers":     stats.UniqueRouters,
-		"unique_models":      stats.UniqueModels,
-		"latest_extraction":  stats.LatestExtraction,
-		"oldest_extraction":  stats.OldestExtraction,
+	routerQuery := `
+		SELECT COALESCE(NULLIF(router, ''), '(none)'), COUNT(*), COUNT(DISTINCT NULLIF(pppoe_username, ''))
+		FROM ont_wifi_info
+		WHERE ` + wifiWhere + `
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`
+	rows, err := r.db.Pool.Query(ctx, routerQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WiFi stats per router: %w", err)
+	}
+	for rows.Next() {
+		var row models.ONTWiFiRouterStats
+		if err := rows.Scan(&row.Router, &row.Extractions, &row.UniqueCustomers); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan WiFi router stats: %w", err)
+		}
+		stats.PerRouter = append(stats.PerRouter, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating WiFi router stats: %w", err)
+	}
+
+	securityQuery := `
+		SELECT COALESCE(NULLIF(security, ''), 'unknown'), COUNT(*)
+		FROM ont_wifi_info
+		WHERE ` + wifiWhere + `
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT 10`
+	rows, err = r.db.Pool.Query(ctx, securityQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WiFi security stats: %w", err)
+	}
+	for rows.Next() {
+		var row models.ONTWiFiSecurityStats
+		if err := rows.Scan(&row.Security, &row.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan WiFi security stats: %w", err)
+		}
+		stats.SecurityTypes = append(stats.SecurityTypes, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating WiFi security stats: %w", err)
+	}
+
+	// Daily series (UTC days); every day in range is present, zero-filled
+	seriesQuery := `
+		WITH days AS (
+			SELECT generate_series(($1::timestamptz AT TIME ZONE 'UTC')::date,
+				($2::timestamptz AT TIME ZONE 'UTC')::date - 1, interval '1 day')::date AS day
+		), extractions AS (
+			SELECT (extracted_at AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS total
+			FROM ont_wifi_info
+			WHERE ` + wifiWhere + `
+			GROUP BY 1
+		), attempts AS (
+			SELECT (created_at AT TIME ZONE 'UTC')::date AS day,
+				COUNT(*) FILTER (WHERE status = '` + models.StatusSuccess + `') AS successful,
+				COUNT(*) FILTER (WHERE status <> '` + models.StatusSuccess + `') AS failed
+			FROM activity_logs
+			WHERE action_type IN ('` + models.ActionONTWiFiExtract + `', '` + models.ActionONTWiFiExtractFromNAT + `')
+				AND ` + logWhere + `
+			GROUP BY 1
+		)
+		SELECT to_char(d.day, 'YYYY-MM-DD'), COALESCE(e.total, 0), COALESCE(a.successful, 0), COALESCE(a.failed, 0)
+		FROM days d
+		LEFT JOIN extractions e ON e.day = d.day
+		LEFT JOIN attempts a ON a.day = d.day
+		ORDER BY d.day`
+	rows, err = r.db.Pool.Query(ctx, seriesQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WiFi stats series: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var point models.ONTWiFiStatsPoint
+		if err := rows.Scan(&point.Date, &point.Extractions, &point.Successful, &point.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan WiFi stats series: %w", err)
+		}
+		point.Attempts = point.Successful + point.Failed
+		point.SuccessRate = successRate(point.Successful, point.Attempts)
+
+		stats.Successful += point.Successful
+		stats.Failed += point.Failed
+		stats.Series = append(stats.Series, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating WiFi stats series: %w", err)
 	}
 
-	return result, nil
+	stats.Attempts = stats.Successful + stats.Failed
+	stats.SuccessRate = successRate(stats.Successful, stats.Attempts)
+
+	return stats, nil
+}
+
+// successRate returns successful/attempts as a percentage rounded to one
+// decimal, or nil when there were no attempts
+func successRate(successful, attempts int) *float64 {
+	if attempts == 0 {
+		return nil
+	}
+	rate := math.Round(float64(successful)*1000/float64(attempts)) / 10
+	return &rate
 }
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+func TestGetWiFiStatsAggregates(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	setup := `
+		CREATE TABLE ont_wifi_info (
+			id SERIAL PRIMARY KEY, pppoe_username TEXT, router TEXT, security TEXT,
+			ont_model TEXT, extracted_at TIMESTAMPTZ NOT NULL
+		);
+		INSERT INTO ont_wifi_info (pppoe_username, router, security, ont_model, extracted_at) VALUES
+			('alice', 'SAMSAT', 'WPA2-PSK', 'F609', '2025-03-01 10:00Z'),
+			('bob',   'SAMSAT', 'WPA2-PSK', 'F609', '2025-03-01 12:00Z'),
+			('alice', 'LANE1',  'WPA-PSK',  'F670', '2025-03-03 08:00Z'),
+			('carol', NULL,     NULL,       NULL,   '2025-03-03 09:00Z'),
+			('dave',  'SAMSAT', 'WPA2-PSK', 'F609', '2025-03-05 10:00Z');
+
+		CREATE TABLE activity_logs (
+			id SERIAL PRIMARY KEY, action_type TEXT NOT NULL, resource_type TEXT, resource_id TEXT,
+			status TEXT, metadata JSONB, created_at TIMESTAMPTZ NOT NULL
+		);`
+	if _, err := db.Pool.Exec(ctx, setup); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	attempts := []struct {
+		action, resourceType, resourceID, status, metadata, at string
+	}{
+		{models.ActionONTWiFiExtract, "", "", models.StatusSuccess, `{"router":"SAMSAT"}`, "2025-03-01 09:00Z"},
+		{models.ActionONTWiFiExtract, "", "", models.StatusSuccess, `{"router":"SAMSAT"}`, "2025-03-01 11:00Z"},
+		{models.ActionONTWiFiExtract, "", "", models.StatusFailed, `{"router":"LANE1"}`, "2025-03-01 13:00Z"},
+		{models.ActionONTWiFiExtractFromNAT, models.ResourceRouter, "LANE1", models.StatusSuccess, `{}`, "2025-03-03 08:00Z"},
+		{models.ActionLogin, "", "", models.StatusSuccess, `{}`, "2025-03-02 08:00Z"},
+	}
+	for _, a := range attempts {
+		if _, err := db.Pool.Exec(ctx, `
+			INSERT INTO activity_logs (action_type, resource_type, resource_id, status, metadata, created_at)
+			VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5::jsonb, $6::timestamptz)
+		`, a.action, a.resourceType, a.resourceID, a.status, a.metadata, a.at); err != nil {
+			t.Fatalf("seed activity log: %v", err)
+		}
+	}
+
+	repo := NewONTWiFiRepository(db)
+	filter := models.ONTWiFiStatsFilter{
+		From: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	stats, err := repo.GetWiFiStats(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dave's extraction on the 5th is outside the range
+	if stats.TotalExtractions != 4 || stats.UniqueCustomers != 3 || stats.UniqueRouters != 2 || stats.UniqueModels != 2 {
+		t.Errorf("totals = %d extractions, %d customers, %d routers, %d models; want 4, 3, 2, 2",
+			stats.TotalExtractions, stats.UniqueCustomers, stats.UniqueRouters, stats.UniqueModels)
+	}
+
+	perRouter := make(map[string]models.ONTWiFiRouterStats)
+	for _, row := range stats.PerRouter {
+		perRouter[row.Router] = row
+	}
+	if len(stats.PerRouter) != 3 || stats.PerRouter[0].Router != "SAMSAT" {
+		t.Errorf("per router = %+v, want SAMSAT first of 3", stats.PerRouter)
+	}
+	if r := perRouter["SAMSAT"]; r.Extractions != 2 || r.UniqueCustomers != 2 {
+		t.Errorf("SAMSAT = %+v, want 2 extractions by 2 customers", r)
+	}
+	if perRouter["LANE1"].Extractions != 1 || perRouter["(none)"].Extractions != 1 {
+		t.Errorf("per router = %+v, want one each for LANE1 and (none)", stats.PerRouter)
+	}
+
+	security := make(map[string]int)
+	for _, row := range stats.SecurityTypes {
+		security[row.Security] = row.Count
+	}
+	if len(stats.SecurityTypes) != 3 || stats.SecurityTypes[0].Security != "WPA2-PSK" ||
+		security["WPA2-PSK"] != 2 || security["WPA-PSK"] != 1 || security["unknown"] != 1 {
+		t.Errorf("security types = %+v, want WPA2-PSK 2 first, then WPA-PSK 1 and unknown 1", stats.SecurityTypes)
+	}
+
+	// The login is not an extraction attempt
+	if stats.Attempts != 4 || stats.Successful != 3 || stats.Failed != 1 || stats.SuccessRate == nil || *stats.SuccessRate != 75 {
+		t.Errorf("attempts = %d (%d ok, %d failed, rate %v), want 4 (3, 1, 75)", stats.Attempts, stats.Successful, stats.Failed, stats.SuccessRate)
+	}
+
+	// Every day of the range is present, the empty one zero-filled
+	want := []struct {
+		date                            string
+		extractions, successful, failed int
+		rate                            float64 // -1 = no attempts
+	}{
+		{"2025-03-01", 2, 2, 1, 66.7},
+		{"2025-03-02", 0, 0, 0, -1},
+		{"2025-03-03", 2, 1, 0, 100},
+	}
+	if len(stats.Series) != len(want) {
+		t.Fatalf("series = %+v, want %d days", stats.Series, len(want))
+	}
+	for i, w := range want {
+		p := stats.Series[i]
+		if p.Date != w.date || p.Extractions != w.extractions || p.Successful != w.successful || p.Failed != w.failed ||
+			p.Attempts != w.successful+w.failed {
+			t.Errorf("series[%d] = %+v, want %+v", i, p, w)
+		}
+		if (w.rate < 0) != (p.SuccessRate == nil) || (p.SuccessRate != nil && *p.SuccessRate != w.rate) {
+			t.Errorf("series[%d] success rate = %v, want %v", i, p.SuccessRate, w.rate)
+		}
+	}
+
+	// A non-administrator only sees their routers' records and attempts
+	filter.Routers = []string{"SAMSAT"}
+	stats, err = repo.GetWiFiStats(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalExtractions != 2 || stats.UniqueCustomers != 2 || len(stats.PerRouter) != 1 {
+		t.Errorf("SAMSAT-only totals = %d extractions, %d customers, %d routers; want 2, 2, 1",
+			stats.TotalExtractions, stats.UniqueCustomers, len(stats.PerRouter))
+	}
+	if stats.Attempts != 2 || stats.Successful != 2 {
+		t.Errorf("SAMSAT-only attempts = %d (%d ok), want 2 (2)", stats.Attempts, stats.Successful)
+	}
+}
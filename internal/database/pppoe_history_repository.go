@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"nat-management-app/internal/models"
+)
+
+// PPPoEHistoryRepository handles database operations for PPPoE search history
+type PPPoEHistoryRepository struct {
+	db *DB
+}
+
+// NewPPPoEHistoryRepository creates a new PPPoE search history repository
+func NewPPPoEHistoryRepository(db *DB) *PPPoEHistoryRepository {
+	return &PPPoEHistoryRepository{db: db}
+}
+
+// Create records one PPPoE status check
+func (r *PPPoEHistoryRepository) Create(ctx context.Context, entry *models.PPPoESearchHistory) error {
+	query := `
+		INSERT INTO pppoe_search_history (user_id, username, router_name, is_online, ip_address)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''))
+		RETURNING id, search_timestamp
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query,
+		entry.UserID,
+		entry.Username,
+		entry.RouterName,
+		entry.IsOnline,
+		entry.IPAddress,
+	).Scan(&entry.ID, &entry.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to record PPPoE search: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUser returns a user's most recent PPPoE searches, newest first
+func (r *PPPoEHistoryRepository) GetByUser(ctx context.Context, userID, limit int) ([]models.PPPoESearchHistory, error) {
+	query := `
+		SELECT id, user_id, username, COALESCE(router_name, ''), COALESCE(is_online, false),
+		       COALESCE(ip_address, ''), search_timestamp
+		FROM pppoe_search_history
+		WHERE user_id = $1
+		ORDER BY search_timestamp DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query PPPoE search history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.PPPoESearchHistory{}
+	for rows.Next() {
+		var entry models.PPPoESearchHistory
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Username,
+			&entry.RouterName,
+			&entry.IsOnline,
+			&entry.IPAddress,
+			&entry.Timestamp,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan PPPoE search history: %w", err)
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PPPoE search history: %w", err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"nat-management-app/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RefreshTokenRepository handles database operations for JWT refresh tokens.
+// Tokens are looked up by their SHA-256 hash; the token itself is never stored.
+type RefreshTokenRepository struct {
+	db *DB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// hashRefreshToken returns the stored form of a refresh token
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Create stores a newly issued refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_hash, user_id, session_id, ip_address, user_agent, expires_at, created_at, last_used)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		hashRefreshToken(token.Token),
+		token.UserID,
+		token.SessionID,
+		token.IPAddress,
+		token.UserAgent,
+		token.ExpiresAt,
+		token.CreatedAt,
+		token.LastUsed,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByToken returns the unexpired stored record of a refresh token, or nil
+// when it is unknown, revoked or expired
+func (r *RefreshTokenRepository) GetByToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	query := `
+		SELECT user_id, session_id, COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+		       expires_at, created_at, last_used
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	stored := &models.RefreshToken{Token: token}
+	err := r.db.Pool.QueryRow(ctx, query, hashRefreshToken(token)).Scan(
+		&stored.UserID,
+		&stored.SessionID,
+		&stored.IPAddress,
+		&stored.UserAgent,
+		&stored.ExpiresAt,
+		&stored.CreatedAt,
+		&stored.LastUsed,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return stored, nil
+}
+
+// TouchLastUsed records that a refresh token was just used
+func (r *RefreshTokenRepository) TouchLastUsed(ctx context.Context, token string, usedAt time.Time) error {
+	query := `UPDATE refresh_tokens SET last_used = $2 WHERE token_hash = $1`
+
+	if _, err := r.db.Pool.Exec(ctx, query, hashRefreshToken(token), usedAt); err != nil {
+		return fmt.Errorf("failed to update refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// ListActive returns every unexpired refresh token, oldest login first. The
+// Token field is left empty since only hashes are stored.
+func (r *RefreshTokenRepository) ListActive(ctx context.Context) ([]models.RefreshToken, error) {
+	query := `
+		SELECT user_id, session_id, COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+		       expires_at, created_at, last_used
+		FROM refresh_tokens
+		WHERE expires_at > NOW()
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.RefreshToken{}
+	for rows.Next() {
+		var token models.RefreshToken
+		if err := rows.Scan(
+			&token.UserID,
+			&token.SessionID,
+			&token.IPAddress,
+			&token.UserAgent,
+			&token.ExpiresAt,
+			&token.CreatedAt,
+			&token.LastUsed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refresh tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteByUser removes every refresh token of a user and returns the IDs of
+// the sessions they belonged to
+func (r *RefreshTokenRepository) DeleteByUser(ctx context.Context, userID int) ([]string, error) {
+	rows, err := r.db.Pool.Query(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 RETURNING session_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	sessionIDs := []string{}
+	for rows.Next() {
+		var sessionID string
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted session: %w", err)
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete refresh tokens: %w", err)
+	}
+
+	return sessionIDs, nil
+}
+
+// DeleteBySession removes the refresh token of one session
+func (r *RefreshTokenRepository) DeleteBySession(ctx context.Context, sessionID string) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete refresh token: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpired removes refresh tokens past their expiry
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteByToken removes one refresh token, e.g. on logout
+func (r *RefreshTokenRepository) DeleteByToken(ctx context.Context, token string) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE token_hash = $1`, hashRefreshToken(token)); err != nil {
+		return fmt.Errorf("failed to delete refresh token: %w", err)
+	}
+
+	return nil
+}
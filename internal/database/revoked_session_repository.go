@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RevokedSessionRepository handles database operations for revoked sessions,
+// whose access tokens must be rejected until they expire
+type RevokedSessionRepository struct {
+	db *DB
+}
+
+// NewRevokedSessionRepository creates a new revoked session repository
+func NewRevokedSessionRepository(db *DB) *RevokedSessionRepository {
+	return &RevokedSessionRepository{db: db}
+}
+
+// Add revokes sessions until expiresAt. Revoking a session again extends it.
+func (r *RevokedSessionRepository) Add(ctx context.Context, sessionIDs []string, expiresAt time.Time) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO revoked_sessions (session_id, expires_at)
+		SELECT unnest($1::text[]), $2::timestamptz
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at)
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, sessionIDs, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// Exists reports whether a session is revoked
+func (r *RevokedSessionRepository) Exists(ctx context.Context, sessionID string) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM revoked_sessions WHERE session_id = $1)`, sessionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked sessions: %w", err)
+	}
+
+	return exists, nil
+}
+
+// DeleteExpired removes sessions whose access tokens have all expired
+func (r *RevokedSessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM revoked_sessions WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired revoked sessions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+// RouterHealthRepository handles database operations for router health history
+type RouterHealthRepository struct {
+	db *DB
+}
+
+// NewRouterHealthRepository creates a new router health history repository
+func NewRouterHealthRepository(db *DB) *RouterHealthRepository {
+	return &RouterHealthRepository{db: db}
+}
+
+// Create records one health check
+func (r *RouterHealthRepository) Create(ctx context.Context, point *models.RouterHealthPoint) error {
+	query := `
+		INSERT INTO router_health_history (router_id, status, success, response_time_ms, cpu_usage, ram_usage, error_message, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		point.RouterID,
+		point.Status,
+		point.Success,
+		point.ResponseTimeMs,
+		point.CPUUsage,
+		point.RAMUsage,
+		point.ErrorMessage,
+		point.CheckedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record router health: %w", err)
+	}
+
+	return nil
+}
+
+// GetRange returns a router's health checks between from and to, oldest first
+func (r *RouterHealthRepository) GetRange(ctx context.Context, routerID string, from, to time.Time) ([]models.RouterHealthPoint, error) {
+	query := `
+		SELECT router_id, status, success, response_time_ms, cpu_usage, ram_usage,
+		       COALESCE(error_message, ''), checked_at
+		FROM router_health_history
+		WHERE router_id = $1 AND checked_at >= $2 AND checked_at <= $3
+		ORDER BY checked_at ASC, id ASC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, routerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query router health history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.RouterHealthPoint{}
+	for rows.Next() {
+		var point models.RouterHealthPoint
+		if err := rows.Scan(
+			&point.RouterID,
+			&point.Status,
+			&point.Success,
+			&point.ResponseTimeMs,
+			&point.CPUUsage,
+			&point.RAMUsage,
+			&point.ErrorMessage,
+			&point.CheckedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan router health history: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating router health history: %w", err)
+	}
+
+	return points, nil
+}
+
+// GetCounts returns how many checks of a router were recorded and how many of
+// them failed, used to seed uptime after a restart
+func (r *RouterHealthRepository) GetCounts(ctx context.Context, routerID string) (int64, int64, error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT success)
+		FROM router_health_history
+		WHERE router_id = $1
+	`
+
+	var checks, fails int64
+	if err := r.db.Pool.QueryRow(ctx, query, routerID).Scan(&checks, &fails); err != nil {
+		return 0, 0, fmt.Errorf("failed to count router health history: %w", err)
+	}
+
+	return checks, fails, nil
+}
+
+// DeleteOlderThan removes health checks older than the given number of days
+func (r *RouterHealthRepository) DeleteOlderThan(ctx context.Context, daysToKeep int) (int64, error) {
+	query := `
+		DELETE FROM router_health_history
+		WHERE checked_at < NOW() - INTERVAL '1 day' * $1
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, daysToKeep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old router health history: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
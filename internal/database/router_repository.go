@@ -10,6 +10,12 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// routerColumns is the column list shared by every router SELECT, in scanRouter order
+const routerColumns = `id, name, host, port, username, password,
+		       tunnel_endpoint, public_ont_url, enabled, description,
+		       created_at, updated_at, COALESCE(default_ont_port, 0),
+		       critical, priority, ont_comment_patterns, use_tls, tls_skip_verify, tags`
+
 // RouterRepository handles database operations for routers
 type RouterRepository struct {
 	db *DB
@@ -26,8 +32,9 @@ func (r *RouterRepository) Create(ctx context.Context, router *models.Router) er
 		INSERT INTO routers (
 			id, name, host, port, username, password,
 			tunnel_endpoint, public_ont_url, enabled, description,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			created_at, updated_at, default_ont_port, critical, priority, ont_comment_patterns,
+			use_tls, tls_skip_verify, tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, 0), $14, $15, $16::text[], $17, $18, $19::text[])
 	`
 
 	_, err := r.db.Pool.Exec(ctx, query,
@@ -43,6 +50,13 @@ func (r *RouterRepository) Create(ctx context.Context, router *models.Router) er
 		router.Description,
 		router.CreatedAt,
 		router.UpdatedAt,
+		router.DefaultONTPort,
+		router.Critical,
+		router.Priority,
+		textArray(router.ONTCommentPatterns),
+		router.UseTLS,
+		router.TLSSkipVerify,
+		textArray(router.Tags),
 	)
 
 	if err != nil {
@@ -56,28 +70,12 @@ func (r *RouterRepository) Create(ctx context.Context, router *models.Router) er
 // GetByID retrieves a router by ID
 func (r *RouterRepository) GetByID(ctx context.Context, id string) (*models.Router, error) {
 	query := `
-		SELECT id, name, host, port, username, password,
-		       tunnel_endpoint, public_ont_url, enabled, description,
-		       created_at, updated_at
+		SELECT ` + routerColumns + `
 		FROM routers
 		WHERE id = $1
 	`
 
-	router := &models.Router{}
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
-		&router.ID,
-		&router.Name,
-		&router.Host,
-		&router.Port,
-		&router.Username,
-		&router.Password,
-		&router.TunnelEndpoint,
-		&router.PublicONTURL,
-		&router.Enabled,
-		&router.Description,
-		&router.CreatedAt,
-		&router.UpdatedAt,
-	)
+	router, err := scanRouter(r.db.Pool.QueryRow(ctx, query, id))
 
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("router not found: %s", id)
@@ -92,28 +90,12 @@ func (r *RouterRepository) GetByID(ctx context.Context, id string) (*models.Rout
 // GetByName retrieves a router by name
 func (r *RouterRepository) GetByName(ctx context.Context, name string) (*models.Router, error) {
 	query := `
-		SELECT id, name, host, port, username, password,
-		       tunnel_endpoint, public_ont_url, enabled, description,
-		       created_at, updated_at
+		SELECT ` + routerColumns + `
 		FROM routers
 		WHERE name = $1
 	`
 
-	router := &models.Router{}
-	err := r.db.Pool.QueryRow(ctx, query, name).Scan(
-		&router.ID,
-		&router.Name,
-		&router.Host,
-		&router.Port,
-		&router.Username,
-		&router.Password,
-		&router.TunnelEndpoint,
-		&router.PublicONTURL,
-		&router.Enabled,
-		&router.Description,
-		&router.CreatedAt,
-		&router.UpdatedAt,
-	)
+	router, err := scanRouter(r.db.Pool.QueryRow(ctx, query, name))
 
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("router not found: %s", name)
@@ -125,12 +107,30 @@ func (r *RouterRepository) GetByName(ctx context.Context, name string) (*models.
 	return router, nil
 }
 
+// FindByNameKey retrieves the router whose name matches ignoring case, or nil
+func (r *RouterRepository) FindByNameKey(ctx context.Context, name string) (*models.Router, error) {
+	query := `
+		SELECT ` + routerColumns + `
+		FROM routers
+		WHERE LOWER(name) = LOWER($1)
+	`
+
+	router, err := scanRouter(r.db.Pool.QueryRow(ctx, query, name))
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get router: %w", err)
+	}
+
+	return router, nil
+}
+
 // GetAll retrieves all routers
 func (r *RouterRepository) GetAll(ctx context.Context) ([]models.Router, error) {
 	query := `
-		SELECT id, name, host, port, username, password,
-		       tunnel_endpoint, public_ont_url, enabled, description,
-		       created_at, updated_at
+		SELECT ` + routerColumns + `
 		FROM routers
 		ORDER BY name ASC
 	`
@@ -143,25 +143,11 @@ func (r *RouterRepository) GetAll(ctx context.Context) ([]models.Router, error)
 
 	var routers []models.Router
 	for rows.Next() {
-		var router models.Router
-		err := rows.Scan(
-			&router.ID,
-			&router.Name,
-			&router.Host,
-			&router.Port,
-			&router.Username,
-			&router.Password,
-			&router.TunnelEndpoint,
-			&router.PublicONTURL,
-			&router.Enabled,
-			&router.Description,
-			&router.CreatedAt,
-			&router.UpdatedAt,
-		)
+		router, err := scanRouter(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan router: %w", err)
 		}
-		routers = append(routers, router)
+		routers = append(routers, *router)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -171,12 +157,62 @@ func (r *RouterRepository) GetAll(ctx context.Context) ([]models.Router, error)
 	return routers, nil
 }
 
+// GetPage retrieves one page of routers matching filter, ordered by name, and
+// the total number of matches. A non-nil names restricts the result to those
+// router names before paging.
+func (r *RouterRepository) GetPage(ctx context.Context, filter models.RouterListFilter, names []string) ([]models.Router, int, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if names != nil {
+		args = append(args, names)
+		where += fmt.Sprintf(" AND name = ANY($%d)", len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR host ILIKE $%d OR description ILIKE $%d)", len(args), len(args), len(args))
+	}
+	if filter.Tag != "" {
+		args = append(args, []string{filter.Tag})
+		where += fmt.Sprintf(" AND tags @> $%d::text[]", len(args))
+	}
+
+	var total int
+	if err := r.db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM routers"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count routers: %w", err)
+	}
+
+	query := `SELECT ` + routerColumns + ` FROM routers` + where + ` ORDER BY name ASC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get routers: %w", err)
+	}
+	defer rows.Close()
+
+	routers := []models.Router{}
+	for rows.Next() {
+		router, err := scanRouter(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan router: %w", err)
+		}
+		routers = append(routers, *router)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating routers: %w", err)
+	}
+
+	return routers, total, nil
+}
+
 // GetEnabled retrieves all enabled routers
 func (r *RouterRepository) GetEnabled(ctx context.Context) ([]models.Router, error) {
 	query := `
-		SELECT id, name, host, port, username, password,
-		       tunnel_endpoint, public_ont_url, enabled, description,
-		       created_at, updated_at
+		SELECT ` + routerColumns + `
 		FROM routers
 		WHERE enabled = true
 		ORDER BY name ASC
@@ -190,25 +226,11 @@ func (r *RouterRepository) GetEnabled(ctx context.Context) ([]models.Router, err
 
 	var routers []models.Router
 	for rows.Next() {
-		var router models.Router
-		err := rows.Scan(
-			&router.ID,
-			&router.Name,
-			&router.Host,
-			&router.Port,
-			&router.Username,
-			&router.Password,
-			&router.TunnelEndpoint,
-			&router.PublicONTURL,
-			&router.Enabled,
-			&router.Description,
-			&router.CreatedAt,
-			&router.UpdatedAt,
-		)
+		router, err := scanRouter(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan router: %w", err)
 		}
-		routers = append(routers, router)
+		routers = append(routers, *router)
 	}
 
 	return routers, nil
@@ -220,11 +242,13 @@ func (r *RouterRepository) Update(ctx context.Context, router *models.Router) er
 		UPDATE routers
 		SET name = $2, host = $3, port = $4, username = $5, password = $6,
 		    tunnel_endpoint = $7, public_ont_url = $8, enabled = $9,
-		    description = $10, updated_at = $11
+		    description = $10, updated_at = $11, default_ont_port = NULLIF($12, 0),
+		    critical = $13, priority = $14, ont_comment_patterns = $15::text[],
+		    use_tls = $16, tls_skip_verify = $17, tags = $18::text[]
 		WHERE id = $1
 	`
 
-	router.UpdatedAt = time.Now()
+	router.UpdatedAt = time.Now().UTC()
 
 	result, err := r.db.Pool.Exec(ctx, query,
 		router.ID,
@@ -238,6 +262,13 @@ func (r *RouterRepository) Update(ctx context.Context, router *models.Router) er
 		router.Enabled,
 		router.Description,
 		router.UpdatedAt,
+		router.DefaultONTPort,
+		router.Critical,
+		router.Priority,
+		textArray(router.ONTCommentPatterns),
+		router.UseTLS,
+		router.TLSSkipVerify,
+		textArray(router.Tags),
 	)
 
 	if err != nil {
@@ -253,6 +284,27 @@ func (r *RouterRepository) Update(ctx context.Context, router *models.Router) er
 	return nil
 }
 
+// UpdateCredentials replaces a router's RouterOS username and password
+func (r *RouterRepository) UpdateCredentials(ctx context.Context, id, username, password string) error {
+	query := `
+		UPDATE routers
+		SET username = $2, password = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, id, username, password, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update router credentials: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("router not found: %s", id)
+	}
+
+	r.db.Logger.Infof("🔑 Router credentials updated in database (ID: %s)", id)
+	return nil
+}
+
 // Delete removes a router from the database
 func (r *RouterRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM routers WHERE id = $1`
@@ -271,9 +323,9 @@ func (r *RouterRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// Exists checks if a router with the given name exists
+// Exists checks if a router with the given name exists, ignoring case
 func (r *RouterRepository) Exists(ctx context.Context, name string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM routers WHERE name = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM routers WHERE LOWER(name) = LOWER($1))`
 
 	var exists bool
 	err := r.db.Pool.QueryRow(ctx, query, name).Scan(&exists)
@@ -309,3 +361,41 @@ func (r *RouterRepository) CountEnabled(ctx context.Context) (int, error) {
 
 	return count, nil
 }
+
+// scanRouter scans a row selected with routerColumns into a Router
+func scanRouter(row pgx.Row) (*models.Router, error) {
+	router := &models.Router{}
+	err := row.Scan(
+		&router.ID,
+		&router.Name,
+		&router.Host,
+		&router.Port,
+		&router.Username,
+		&router.Password,
+		&router.TunnelEndpoint,
+		&router.PublicONTURL,
+		&router.Enabled,
+		&router.Description,
+		&router.CreatedAt,
+		&router.UpdatedAt,
+		&router.DefaultONTPort,
+		&router.Critical,
+		&router.Priority,
+		&router.ONTCommentPatterns,
+		&router.UseTLS,
+		&router.TLSSkipVerify,
+		&router.Tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// textArray stores nil as an empty array (the TEXT[] columns are NOT NULL)
+func textArray(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TokenBlacklistRepository handles database operations for revoked JWTs,
+// identified by their JTI claim
+type TokenBlacklistRepository struct {
+	db *DB
+}
+
+// NewTokenBlacklistRepository creates a new token blacklist repository
+func NewTokenBlacklistRepository(db *DB) *TokenBlacklistRepository {
+	return &TokenBlacklistRepository{db: db}
+}
+
+// Add blacklists a token until expiresAt. Revoking it again is a no-op.
+func (r *TokenBlacklistRepository) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO token_blacklist (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, jti, expiresAt); err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+
+	return nil
+}
+
+// Exists reports whether a token is blacklisted
+func (r *TokenBlacklistRepository) Exists(ctx context.Context, jti string) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM token_blacklist WHERE jti = $1)`, jti).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token blacklist: %w", err)
+	}
+
+	return exists, nil
+}
+
+// DeleteExpired removes entries whose tokens have expired
+func (r *TokenBlacklistRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM token_blacklist WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired blacklist entries: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -163,7 +163,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 		WHERE id = $1
 	`
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = time.Now().UTC()
 
 	result, err := r.db.Pool.Exec(ctx, query,
 		user.ID,
@@ -194,7 +194,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int) error {
 	query := `UPDATE users SET last_login_at = $1 WHERE id = $2`
 
-	now := time.Now()
+	now := time.Now().UTC()
 	result, err := r.db.Pool.Exec(ctx, query, now, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update last login: %w", err)
@@ -208,6 +208,68 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int) error
 	return nil
 }
 
+// GetTOTP returns a user's TOTP secret (empty if never enrolled) and whether
+// login requires a code
+func (r *UserRepository) GetTOTP(ctx context.Context, userID int) (string, bool, error) {
+	query := `SELECT COALESCE(totp_secret, ''), totp_enabled FROM users WHERE id = $1`
+
+	var secret string
+	var enabled bool
+	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(&secret, &enabled)
+	if err == pgx.ErrNoRows {
+		return "", false, fmt.Errorf("user not found: %d", userID)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get TOTP settings: %w", err)
+	}
+
+	return secret, enabled, nil
+}
+
+// SetTOTPSecret stores a new, not yet verified TOTP secret
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = false, totp_last_step = NULL, updated_at = $2 WHERE id = $3`
+
+	result, err := r.db.Pool.Exec(ctx, query, secret, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
+// EnableTOTP requires a TOTP code at login from now on
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID int) error {
+	query := `UPDATE users SET totp_enabled = true, updated_at = $1 WHERE id = $2 AND totp_secret IS NOT NULL`
+
+	result, err := r.db.Pool.Exec(ctx, query, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
+// ClaimTOTPStep records step as the user's last accepted TOTP step. It returns
+// false, recording nothing, when a code from this step or a later one was
+// already accepted.
+func (r *UserRepository) ClaimTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)`
+
+	result, err := r.db.Pool.Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record TOTP step: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
 // Delete removes a user from the database
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// refreshingAuthService knows one valid access token, one expired one and one
+// refresh token that mints the valid access token; any other method panics
+type refreshingAuthService struct {
+	services.AuthServiceInterface
+}
+
+func (refreshingAuthService) ValidateJWTToken(token string) (*models.User, error) {
+	switch token {
+	case "fresh-access":
+		return &models.User{ID: 1, Username: "admin", Role: models.RoleAdministrator}, nil
+	case "expired-access":
+		return nil, fmt.Errorf("token tidak valid: %w", jwt.ErrTokenExpired)
+	}
+	return nil, errors.New("token tidak valid")
+}
+
+func (refreshingAuthService) RefreshToken(refreshToken, ipAddress, userAgent string) (*models.AuthResponse, error) {
+	if refreshToken != "good-refresh" {
+		return nil, errors.New("refresh token tidak valid")
+	}
+	return &models.AuthResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"tokens": &models.TokenPair{AccessToken: "fresh-access", AccessTokenExpiresAt: time.Now().Add(15 * time.Minute)},
+		},
+	}, nil
+}
+
+func TestRequireJWTAuthAutoRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sam := &SecureAuthMiddleware{authService: refreshingAuthService{}, logger: quietLogger(), frameOptions: "DENY"}
+	sam.SetAutoRefresh(true)
+
+	router := gin.New()
+	router.GET("/api/me", sam.RequireJWTAuth(), func(c *gin.Context) {
+		user, _ := GetUserFromContext(c)
+		c.String(http.StatusOK, user.Username)
+	})
+
+	tests := []struct {
+		name          string
+		cookies       map[string]string
+		authorization string
+		want          int
+		refreshed     bool
+	}{
+		{"expired cookie is refreshed", map[string]string{"access_token": "expired-access", "refresh_token": "good-refresh"}, "", http.StatusOK, true},
+		{"missing cookie is refreshed", map[string]string{"refresh_token": "good-refresh"}, "", http.StatusOK, true},
+		{"missing refresh token", map[string]string{"access_token": "expired-access"}, "", http.StatusUnauthorized, false},
+		{"invalid refresh token", map[string]string{"access_token": "expired-access", "refresh_token": "stolen"}, "", http.StatusUnauthorized, false},
+		{"tampered access token", map[string]string{"access_token": "forged", "refresh_token": "good-refresh"}, "", http.StatusUnauthorized, false},
+		{"bearer clients manage their own tokens", map[string]string{"refresh_token": "good-refresh"}, "Bearer expired-access", http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("GET /api/me = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			refreshed := strings.Contains(w.Header().Get("Set-Cookie"), "access_token=fresh-access")
+			if refreshed != tt.refreshed {
+				t.Fatalf("Set-Cookie = %q, want refreshed access cookie: %v", w.Header().Values("Set-Cookie"), tt.refreshed)
+			}
+		})
+	}
+}
+
+func TestRequireJWTAuthWithoutAutoRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sam := &SecureAuthMiddleware{authService: refreshingAuthService{}, logger: quietLogger(), frameOptions: "DENY"}
+
+	router := gin.New()
+	router.GET("/api/me", sam.RequireJWTAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "expired-access"})
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "good-refresh"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expired cookie with auto-refresh off = %d, want 401", w.Code)
+	}
+}
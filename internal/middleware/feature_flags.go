@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature hides a route behind a feature flag. While the flag is off the
+// route answers 404 as if it didn't exist.
+func RequireFeature(flags services.FeatureChecker, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.IsEnabled(name) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Status:  "error",
+				Message: "Endpoint not found",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
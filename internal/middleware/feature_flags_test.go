@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticFlags is a fixed set of feature flags; unknown flags are off
+type staticFlags map[string]bool
+
+func (f staticFlags) IsEnabled(name string) bool {
+	return f[name]
+}
+
+func TestRequireFeature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		flags staticFlags
+		want  int
+	}{
+		{"flag on", staticFlags{"ont_wifi": true}, http.StatusOK},
+		{"flag off", staticFlags{"ont_wifi": false}, http.StatusNotFound},
+		{"unknown flag", staticFlags{}, http.StatusNotFound},
+		{"other flag on", staticFlags{"audit": true}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			router := gin.New()
+			router.GET("/api/ont/wifi", RequireFeature(tt.flags, "ont_wifi"), func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ont/wifi", nil))
+			if w.Code != tt.want {
+				t.Fatalf("GET /api/ont/wifi = %d, want %d", w.Code, tt.want)
+			}
+			if reached != (tt.want == http.StatusOK) {
+				t.Fatalf("handler reached = %v with status %d", reached, w.Code)
+			}
+		})
+	}
+}
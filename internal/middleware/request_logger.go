@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader carries the correlation id in and out of the API
+const RequestIDHeader = "X-Request-ID"
+
+// RequestLogger assigns every request a correlation id and makes a
+// request-scoped logger available via GetRequestLogger
+func RequestLogger(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+
+		c.Set("request_id", requestID)
+		c.Set("request_logger_base", logger)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// AccessLogger logs every request once it's done through the request-scoped
+// logger, so access lines carry the request id and follow LOG_FORMAT.
+// Must run after RequestLogger.
+func AccessLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
+			path += "?" + redactAccessToken(raw)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := GetRequestLogger(c).WithFields(logrus.Fields{
+			"status":     status,
+			"method":     c.Request.Method,
+			"path":       path,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"client_ip":  c.ClientIP(),
+			"bytes":      c.Writer.Size(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+
+		switch {
+		case status >= 500:
+			entry.Error("request")
+		case status >= 400:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+	}
+}
+
+// GetRequestID returns the correlation id of the current request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// GetRequestLogger derives a log entry with the request id, username and,
+// when the route targets one, the router name. Fields are read at call time
+// so the username is present once auth middleware has run.
+func GetRequestLogger(c *gin.Context) *logrus.Entry {
+	logger, ok := c.Value("request_logger_base").(*logrus.Logger)
+	if !ok {
+		logger = logrus.StandardLogger()
+	}
+
+	fields := logrus.Fields{}
+	if requestID := GetRequestID(c); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	if username := c.GetString("username"); username != "" {
+		fields["user"] = username
+	}
+	if routerName := requestRouterName(c); routerName != "" {
+		fields["router"] = routerName
+	}
+
+	return logger.WithFields(fields)
+}
+
+// RequestContext returns the request's context carrying the request-scoped
+// logger, for passing into service calls
+func RequestContext(c *gin.Context) context.Context {
+	return services.WithRequestLogger(c.Request.Context(), GetRequestLogger(c))
+}
+
+// requestRouterName picks the router name from the route, if any
+func requestRouterName(c *gin.Context) string {
+	if name := c.Param("router"); name != "" {
+		return name
+	}
+	if name := c.Query("router"); name != "" {
+		return name
+	}
+	return c.GetString("router_name")
+}
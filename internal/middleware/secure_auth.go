@@ -24,8 +24,14 @@ type SecureAuthMiddleware struct {
 	logger          *logrus.Logger
 	rateLimiter     *rate.Limiter
 	rateLimitConfig *config.RateLimitConfig
+	autoRefresh     bool // Mint a new access token from the refresh cookie instead of answering 401
+	csp             string
+	frameOptions    string
 }
 
+// DefaultContentSecurityPolicy allows the CDNs the bundled web UI loads assets from
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://cdnjs.cloudflare.com; font-src 'self' https://cdnjs.cloudflare.com; img-src 'self' data:; connect-src 'self'"
+
 // NewSecureAuthMiddleware creates enhanced JWT-based auth middleware
 func NewSecureAuthMiddleware(authService services.AuthServiceInterface, logger *logrus.Logger) *SecureAuthMiddleware {
 	rateLimitConfig := config.LoadRateLimitConfig()
@@ -36,7 +42,36 @@ func NewSecureAuthMiddleware(authService services.AuthServiceInterface, logger *
 		// Use per-second rate derived from requests-per-minute; burst capped to the same minute allowance
 		rateLimiter:     rate.NewLimiter(rate.Limit(float64(rateLimitConfig.RequestsPerMinute)/60.0), rateLimitConfig.RequestsPerMinute),
 		rateLimitConfig: rateLimitConfig,
+		csp:             DefaultContentSecurityPolicy,
+		frameOptions:    "DENY",
+	}
+}
+
+// SetAutoRefresh enables transparent access token refresh for cookie-based
+// requests. Off by default.
+func (sam *SecureAuthMiddleware) SetAutoRefresh(enabled bool) {
+	sam.autoRefresh = enabled
+}
+
+// SetSecurityHeaders overrides the Content-Security-Policy (empty keeps the
+// default) and X-Frame-Options, which must be DENY or SAMEORIGIN.
+func (sam *SecureAuthMiddleware) SetSecurityHeaders(csp, frameOptions string) error {
+	frameOptions = strings.ToUpper(strings.TrimSpace(frameOptions))
+	switch frameOptions {
+	case "":
+		frameOptions = "DENY"
+	case "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("invalid X-Frame-Options %q, expected DENY or SAMEORIGIN", frameOptions)
+	}
+
+	if csp = strings.TrimSpace(csp); csp == "" {
+		csp = DefaultContentSecurityPolicy
 	}
+
+	sam.csp = csp
+	sam.frameOptions = frameOptions
+	return nil
 }
 
 // RequireJWTAuth middleware untuk JWT authentication dengan security enhancements
@@ -45,6 +80,11 @@ func (sam *SecureAuthMiddleware) RequireJWTAuth() gin.HandlerFunc {
 		// Rate limiting handled via RateLimitByIP middleware per route group
 
 		user, err := sam.getCurrentUserFromJWT(c)
+		if err != nil && sam.autoRefresh {
+			if refreshed, ok := sam.refreshFromCookie(c); ok {
+				user, err = refreshed, nil
+			}
+		}
 		if err != nil {
 			sam.handleUnauthorized(c, "JWT Authentication required: "+err.Error())
 			return
@@ -78,6 +118,12 @@ func (sam *SecureAuthMiddleware) getCurrentUserFromJWT(c *gin.Context) (*models.
 		}
 	}
 
+	// Browsers can't set headers on a WebSocket handshake, so it may carry
+	// the token as ?access_token= instead (redacted from the access logs)
+	if tokenString == "" && isWebSocketUpgrade(c) {
+		tokenString = c.Query("access_token")
+	}
+
 	if tokenString == "" {
 		return nil, errors.New("missing authorization token")
 	}
@@ -92,14 +138,60 @@ func (sam *SecureAuthMiddleware) getCurrentUserFromJWT(c *gin.Context) (*models.
 	return user, nil
 }
 
+// refreshFromCookie mints a new access token from the refresh_token cookie and
+// sets it as the access_token cookie. Only cookie-based (browser) requests are
+// refreshed: a request that sent an Authorization header manages its own tokens
+// and gets the 401. The access cookie shares the token's 15 minute lifetime, so
+// an expired token usually shows up as a missing cookie; both are refreshed,
+// while a present but otherwise invalid token is not.
+func (sam *SecureAuthMiddleware) refreshFromCookie(c *gin.Context) (*models.User, bool) {
+	if c.GetHeader("Authorization") != "" {
+		return nil, false
+	}
+
+	if accessToken, err := c.Cookie("access_token"); err == nil && accessToken != "" {
+		if _, err := sam.authService.ValidateJWTToken(accessToken); !services.IsTokenExpired(err) {
+			return nil, false
+		}
+	}
+
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		return nil, false
+	}
+
+	response, err := sam.authService.RefreshToken(refreshToken, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		sam.logger.Warnf("🔄 Auto-refresh failed from IP %s: %v", c.ClientIP(), err)
+		return nil, false
+	}
+
+	data, _ := response.Data.(map[string]interface{})
+	tokenPair, ok := data["tokens"].(*models.TokenPair)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := sam.authService.ValidateJWTToken(tokenPair.AccessToken)
+	if err != nil {
+		return nil, false
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("access_token", tokenPair.AccessToken, utils.CookieMaxAge(tokenPair.AccessTokenExpiresAt), "/", "", false, true) // same as login
+
+	sam.logger.Infof("🔄 Access token auto-refreshed for %s", user.Username)
+	return user, true
+}
+
 // setSecurityHeaders sets important security headers
 func (sam *SecureAuthMiddleware) setSecurityHeaders(c *gin.Context) {
 	// Security headers untuk melindungi aplikasi
 	c.Header("X-Content-Type-Options", "nosniff")
-	c.Header("X-Frame-Options", "DENY")
+	c.Header("X-Frame-Options", sam.frameOptions)
 	c.Header("X-XSS-Protection", "1; mode=block")
 	c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://cdnjs.cloudflare.com; font-src 'self' https://cdnjs.cloudflare.com; img-src 'self' data:; connect-src 'self'")
+	c.Header("Content-Security-Policy", sam.csp)
 
 	// Remove server information
 	c.Header("Server", "")
@@ -129,10 +221,30 @@ func (sam *SecureAuthMiddleware) handleUnauthorized(c *gin.Context, message stri
 // isAPIRequest checks if request is API call
 func (sam *SecureAuthMiddleware) isAPIRequest(c *gin.Context) bool {
 	return strings.HasPrefix(c.Request.URL.Path, "/api/") ||
+		strings.HasPrefix(c.Request.URL.Path, "/ws/") ||
 		strings.Contains(c.GetHeader("Accept"), "application/json") ||
 		strings.Contains(c.GetHeader("Content-Type"), "application/json")
 }
 
+// isWebSocketUpgrade reports whether the request is a WebSocket handshake
+func isWebSocketUpgrade(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
+
+// redactAccessToken hides an access_token query value in a logged path
+func redactAccessToken(path string) string {
+	i := strings.Index(path, "access_token=")
+	if i < 0 {
+		return path
+	}
+	start := i + len("access_token=")
+	end := strings.IndexByte(path[start:], '&')
+	if end < 0 {
+		return path[:start] + "REDACTED"
+	}
+	return path[:start] + "REDACTED" + path[start+end:]
+}
+
 // SecureCORSWithAuth implements secure CORS policy
 func (sam *SecureAuthMiddleware) SecureCORSWithAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -241,8 +353,8 @@ func (sam *SecureAuthMiddleware) SecureCORSWithAuth() gin.HandlerFunc {
 
 		// Set CORS headers (security-first)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Accept, Content-Type, Authorization, X-Requested-With, X-CSRF-Token")
-		c.Header("Access-Control-Expose-Headers", "X-Total-Count, X-Rate-Limit-Remaining")
+		c.Header("Access-Control-Allow-Headers", "Origin, Accept, Content-Type, Authorization, X-Requested-With, X-CSRF-Token, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Total-Count, X-Rate-Limit-Remaining, X-Request-ID")
 		c.Header("Access-Control-Max-Age", "86400") // 24 hours cache
 		c.Header("Vary", "Origin")                  // ensure caches respect per-origin responses
 
@@ -346,7 +458,7 @@ func (sam *SecureAuthMiddleware) SecurityLogger() gin.HandlerFunc {
 			param.ClientIP,
 			param.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
 			param.Method,
-			param.Path,
+			redactAccessToken(param.Path),
 			param.Request.Proto,
 			param.StatusCode,
 			param.Latency,
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers carrying the step-up confirmation for protected routes
+const (
+	StepUpTokenHeader    = "X-Step-Up-Token"
+	StepUpPasswordHeader = "X-Confirm-Password"
+)
+
+// RequireStepUp guards destructive routes behind a second confirmation. Routes are
+// "METHOD /path" entries matched against the registered gin route (e.g.
+// "DELETE /api/routers/:id"); every other request passes through untouched.
+// A protected request must carry a step-up token from POST /api/auth/step-up or
+// re-enter the password. Must run after RequireJWTAuth.
+func (sam *SecureAuthMiddleware) RequireStepUp(routes []string) gin.HandlerFunc {
+	protected := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if key := stepUpRouteKey(route); key != "" {
+			protected[key] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(protected) == 0 || !protected[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			utils.RespondUnauthorized(c)
+			c.Abort()
+			return
+		}
+
+		token := strings.TrimSpace(c.GetHeader(StepUpTokenHeader))
+		password := c.GetHeader(StepUpPasswordHeader)
+		if err := sam.authService.VerifyStepUp(user, token, password); err != nil {
+			sam.logger.Warnf("🔒 Step-up rejected for %s on %s %s from %s: %v",
+				user.Username, c.Request.Method, c.FullPath(), c.ClientIP(), err)
+			utils.RespondWithError(c, http.StatusForbidden, models.NewErrorDetail(
+				models.ErrCodeStepUpRequired,
+				"This operation requires step-up confirmation",
+			).WithSuggestion("Re-enter your password via POST /api/auth/step-up and send the token in the "+StepUpTokenHeader+" header"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// stepUpRouteKey normalizes "delete  /api/routers/:id" to "DELETE /api/routers/:id"
+func stepUpRouteKey(route string) string {
+	fields := strings.Fields(route)
+	if len(fields) != 2 {
+		return ""
+	}
+	return strings.ToUpper(fields[0]) + " " + fields[1]
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// stepUpAuthService accepts the step-up token it issued for a user, or that
+// user's password; any other method panics
+type stepUpAuthService struct {
+	services.AuthServiceInterface
+	password string
+}
+
+func (s stepUpAuthService) IssueStepUpToken(user *models.User, password, ipAddress string) (*models.StepUpToken, error) {
+	if password != s.password {
+		return nil, errors.New("invalid credentials")
+	}
+	return &models.StepUpToken{Token: "step-up:" + user.Username}, nil
+}
+
+func (s stepUpAuthService) VerifyStepUp(user *models.User, stepUpToken, password string) error {
+	switch {
+	case stepUpToken != "":
+		if stepUpToken != "step-up:"+user.Username {
+			return errors.New("step-up token tidak valid")
+		}
+		return nil
+	case password == "":
+		return errors.New("step-up confirmation required")
+	case password != s.password:
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
+
+func TestRequireStepUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := stepUpAuthService{password: "hunter2"}
+	sam := &SecureAuthMiddleware{authService: auth, logger: quietLogger()}
+	user := &models.User{ID: 7, Username: "admin", Role: models.RoleAdministrator}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", user)
+		c.Next()
+	})
+	router.Use(sam.RequireStepUp([]string{"delete /api/routers/:id"}))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.DELETE("/api/routers/:id", ok)
+	router.GET("/api/routers/:id", ok)
+
+	token, err := auth.IssueStepUpToken(user, "hunter2", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"unprotected route", http.MethodGet, nil, http.StatusNoContent},
+		{"no confirmation", http.MethodDelete, nil, http.StatusForbidden},
+		{"wrong password", http.MethodDelete, map[string]string{StepUpPasswordHeader: "nope"}, http.StatusForbidden},
+		{"forged token", http.MethodDelete, map[string]string{StepUpTokenHeader: "step-up:someone"}, http.StatusForbidden},
+		{"step-up token", http.MethodDelete, map[string]string{StepUpTokenHeader: token.Token}, http.StatusNoContent},
+		{"re-entered password", http.MethodDelete, map[string]string{StepUpPasswordHeader: "hunter2"}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/routers/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("%s /api/routers/1 = %d, want %d (%s)", tt.method, w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireStepUpNeedsUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sam := &SecureAuthMiddleware{authService: stepUpAuthService{}, logger: quietLogger()}
+
+	router := gin.New()
+	router.Use(sam.RequireStepUp([]string{"DELETE /api/routers/:id"}))
+	router.DELETE("/api/routers/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/routers/1", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("protected route without a user = %d, want 401", w.Code)
+	}
+}
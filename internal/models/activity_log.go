@@ -49,6 +49,7 @@ type ActivityLogCreate struct {
 	DurationMs   *int                   `json:"duration_ms"`   // Operation duration in milliseconds
 	DeviceInfo   *DeviceInfo            `json:"device_info"`   // Device context
 	Metadata     map[string]interface{} `json:"metadata"`
+	RequestID    string                 `json:"request_id"` // Stored in metadata as request_id
 }
 
 // ActivityLogFilter represents filters for querying logs
@@ -61,6 +62,7 @@ type ActivityLogFilter struct {
 	ResourceTypes []string  `json:"resource_types"`   // Multi-select resource types
 	Status        string    `json:"status"`
 	Statuses      []string  `json:"statuses"`         // Multi-select statuses
+	IPAddress     string    `json:"ip_address"`
 	StartDate     time.Time `json:"start_date"`
 	EndDate       time.Time `json:"end_date"`
 	Limit         int       `json:"limit"`
@@ -69,10 +71,11 @@ type ActivityLogFilter struct {
 
 // ActivityLogsResponse represents the API response for logs listing
 type ActivityLogsResponse struct {
-	Status     string        `json:"status"`
-	Data       []ActivityLog `json:"data"`
-	Total      int           `json:"total"`
-	Pagination *Pagination   `json:"pagination,omitempty"`
+	Status     string          `json:"status"`
+	Data       []ActivityLog   `json:"data"`
+	Total      int             `json:"total"`
+	Pagination *Pagination     `json:"pagination,omitempty"`
+	Meta       *PaginationMeta `json:"meta,omitempty"`
 }
 
 // Pagination represents pagination metadata
@@ -85,15 +88,26 @@ type Pagination struct {
 
 // Action type constants
 const (
-	ActionLogin       = "LOGIN"
-	ActionLogout      = "LOGOUT"
-	ActionCreate      = "CREATE"
-	ActionUpdate      = "UPDATE"
-	ActionDelete      = "DELETE"
-	ActionNATUpdate   = "NAT_UPDATE"
-	ActionPPPoECheck  = "PPPOE_CHECK"
-	ActionTest        = "TEST"
-	ActionView        = "VIEW"
+	ActionLogin           = "LOGIN"
+	ActionLogout          = "LOGOUT"
+	ActionCreate          = "CREATE"
+	ActionUpdate          = "UPDATE"
+	ActionDelete          = "DELETE"
+	ActionNATUpdate       = "NAT_UPDATE"
+	ActionNATRollback     = "NAT_ROLLBACK"
+	ActionNATToggle       = "NAT_TOGGLE"
+	ActionPPPoECheck      = "PPPOE_CHECK"
+	ActionPPPoEDisconnect = "PPPOE_DISCONNECT"
+	ActionTest            = "TEST"
+	ActionView            = "VIEW"
+	ActionExport          = "EXPORT"
+	ActionStepUp          = "STEP_UP"
+
+	// ONT WiFi extraction attempts (counted by the WiFi stats success rate)
+	ActionONTWiFiExtract        = "ONT_WIFI_EXTRACT"
+	ActionONTWiFiExtractFromNAT = "ONT_WIFI_EXTRACT_FROM_NAT"
+	// Summary of one run over every router's ONT (not counted as an attempt)
+	ActionONTWiFiExtractAll = "ONT_WIFI_EXTRACT_ALL"
 )
 
 // Resource type constants
@@ -103,6 +117,9 @@ const (
 	ResourceNATRule  = "NAT_RULE"
 	ResourcePPPoE    = "PPPOE"
 	ResourceAuth     = "AUTH"
+	ResourceFeatureFlag = "FEATURE_FLAG"
+	ResourceAudit       = "AUDIT"
+	ResourceAccessControl = "ACCESS_CONTROL"
 )
 
 // Status constants
@@ -131,15 +148,19 @@ func (a *ActivityLog) UnmarshalMetadata(data []byte) error {
 // GetActionTypeLabel returns human-readable label for action type
 func GetActionTypeLabel(actionType string) string {
 	labels := map[string]string{
-		ActionLogin:      "Login",
-		ActionLogout:     "Logout",
-		ActionCreate:     "Create",
-		ActionUpdate:     "Update",
-		ActionDelete:     "Delete",
-		ActionNATUpdate:  "NAT Update",
-		ActionPPPoECheck: "PPPoE Check",
-		ActionTest:       "Test",
-		ActionView:       "View",
+		ActionLogin:           "Login",
+		ActionLogout:          "Logout",
+		ActionCreate:          "Create",
+		ActionUpdate:          "Update",
+		ActionDelete:          "Delete",
+		ActionNATUpdate:       "NAT Update",
+		ActionNATRollback:     "NAT Rollback",
+		ActionPPPoECheck:      "PPPoE Check",
+		ActionPPPoEDisconnect: "PPPoE Disconnect",
+		ActionTest:            "Test",
+		ActionView:            "View",
+		ActionExport:          "Export",
+		ActionStepUp:          "Step-up Confirmation",
 	}
 	if label, ok := labels[actionType]; ok {
 		return label
@@ -155,6 +176,9 @@ func GetResourceTypeLabel(resourceType string) string {
 		ResourceNATRule: "NAT Rule",
 		ResourcePPPoE:   "PPPoE",
 		ResourceAuth:    "Authentication",
+		ResourceFeatureFlag: "Feature Flag",
+		ResourceAudit:       "Connectivity Audit",
+		ResourceAccessControl: "Access Control",
 	}
 	if label, ok := labels[resourceType]; ok {
 		return label
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// AuditSchedule is a cron schedule for recurring connectivity audits
+type AuditSchedule struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	CronExpr  string     `json:"cron_expr"` // minute hour day-of-month month day-of-week (server time)
+	Routers   []string   `json:"routers"`   // Empty = every configured router
+	Enabled   bool       `json:"enabled"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// AuditScheduleRequest represents request to create or update an audit schedule
+type AuditScheduleRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	CronExpr string   `json:"cron_expr" binding:"required"`
+	Routers  []string `json:"routers"`
+	Enabled  *bool    `json:"enabled"` // Defaults to true
+}
+
+// AuditRunRequest represents request to trigger an audit run manually
+type AuditRunRequest struct {
+	ScheduleID *int     `json:"schedule_id,omitempty"` // Use the schedule's routers
+	Routers    []string `json:"routers,omitempty"`     // Or audit these routers (empty = all)
+}
+
+// Audit run statuses
+const (
+	AuditRunRunning   = "running"
+	AuditRunCompleted = "completed"
+	AuditRunFailed    = "failed"
+)
+
+// AuditRun is one connectivity audit run
+type AuditRun struct {
+	ID          int64         `json:"id"`
+	ScheduleID  *int          `json:"schedule_id,omitempty"`
+	TriggeredBy string        `json:"triggered_by"` // "schedule:<name>" or the admin's username
+	Status      string        `json:"status"`
+	Routers     []string      `json:"routers"`
+	Total       int           `json:"total"`
+	Reachable   int           `json:"reachable"`
+	Error       string        `json:"error,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  *time.Time    `json:"finished_at,omitempty"`
+	Results     []AuditResult `json:"results,omitempty"`
+}
+
+// AuditResult is one customer's reachability in an audit run
+type AuditResult struct {
+	Router         string    `json:"router"`
+	Username       string    `json:"pppoe_username"`
+	IPAddress      string    `json:"ip_address"`
+	Reachable      bool      `json:"reachable"`
+	Port           string    `json:"port,omitempty"`
+	ResponseTimeMs int       `json:"response_time_ms"`
+	Error          string    `json:"error,omitempty"` // Set when the router itself couldn't be queried
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// AuditRunsResponse represents response for GET /api/audits
+type AuditRunsResponse struct {
+	Status string     `json:"status"`
+	Date   string     `json:"date"`
+	Data   []AuditRun `json:"data"`
+	Total  int        `json:"total"`
+}
@@ -44,6 +44,7 @@ type UserSession struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code,omitempty"` // Required once two-factor authentication is enabled
 }
 
 // AuthResponse represents authentication responses
@@ -81,6 +82,38 @@ type RefreshToken struct {
 	LastUsed  time.Time `json:"last_used"`
 }
 
+// StepUpRequest re-confirms the caller's password before a destructive operation
+type StepUpRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// StepUpToken is a short-lived confirmation accepted by step-up protected routes
+type StepUpToken struct {
+	Token     string    `json:"step_up_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ActiveSession is a logged-in session backed by a live refresh token
+type ActiveSession struct {
+	SessionID    string      `json:"session_id"`
+	UserID       int         `json:"user_id"`
+	Username     string      `json:"username"`
+	Role         Role        `json:"role"`
+	IPAddress    string      `json:"ip_address"`
+	UserAgent    string      `json:"user_agent"`
+	Device       *DeviceInfo `json:"device,omitempty"`
+	LoginAt      time.Time   `json:"login_at"`
+	LastActivity time.Time   `json:"last_activity"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+}
+
+// ActiveSessionFilter selects active sessions for the admin listing
+type ActiveSessionFilter struct {
+	Username string // Case-insensitive substring match
+	Limit    int
+	Offset   int
+}
+
 // RefreshTokenRequest represents a request to refresh access token
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
@@ -121,12 +154,79 @@ func GetNATRouterAccess(role Role) []string {
 	return []string{} // No access by default
 }
 
+// RouterScope is which of a user's accessible routers a dashboard fetch covers
+type RouterScope string
+
+const (
+	ScopeAll      RouterScope = "all"      // Every accessible router
+	ScopeAssigned RouterScope = "assigned" // Routers assigned to the user (user_routers), else role access
+	ScopeFirst    RouterScope = "first"    // Only the first assigned router, alphabetically
+)
+
+// defaultRouterScopes is the dashboard scope used when a request doesn't pass ?scope=
+var defaultRouterScopes = map[Role]RouterScope{
+	RoleAdministrator: ScopeAll,
+	RoleHeadBranch1:   ScopeAssigned,
+	RoleHeadBranch2:   ScopeAssigned,
+	RoleHeadBranch3:   ScopeAssigned,
+}
+
+// IsValid checks if the router scope is known
+func (s RouterScope) IsValid() bool {
+	switch s {
+	case ScopeAll, ScopeAssigned, ScopeFirst:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultRouterScope returns the dashboard default scope for a role
+func DefaultRouterScope(role Role) RouterScope {
+	if scope, exists := defaultRouterScopes[role]; exists {
+		return scope
+	}
+	return ScopeAssigned
+}
+
+// ConfigureDefaultRouterScopes overrides per-role default scopes at startup.
+// Unknown roles and scopes are ignored.
+func ConfigureDefaultRouterScopes(overrides map[string]string) {
+	for role, scope := range overrides {
+		r, s := Role(role), RouterScope(scope)
+		if r.IsValid() && s.IsValid() {
+			defaultRouterScopes[r] = s
+		}
+	}
+}
+
 // GetRoleForRouterAccess returns the role string for router access control
 // This bridges the gap between auth roles and router access control roles
 func GetRoleForRouterAccess(role Role) string {
 	return string(role)
 }
 
+// RouterAccessDecision explains whether a user can access a router and why
+type RouterAccessDecision struct {
+	UserID           int      `json:"user_id"`
+	Username         string   `json:"username"`
+	Role             string   `json:"role"`
+	Router           string   `json:"router"`
+	Allowed          bool     `json:"allowed"`
+	Source           string   `json:"source"` // "user_assignment" or "role": which rule produced EffectiveRouters
+	Reason           string   `json:"reason"`
+	RouterConfigured bool     `json:"router_configured"` // Router exists and is enabled for NAT operations
+	EffectiveRouters []string `json:"effective_routers"`
+}
+
+// EffectiveRouter is one router a user is granted, with every grant that applies
+type EffectiveRouter struct {
+	Name      string   `json:"name"`
+	Reason    string   `json:"reason"`     // Most specific grant: user_assignment, role or wildcard
+	Grants    []string `json:"grants"`     // All grants for this router
+	NATAccess bool     `json:"nat_access"` // Usable for NAT/PPPoE operations (assignments override role grants)
+}
+
 // IsValid checks if the role is valid
 func (r Role) IsValid() bool {
 	switch r {
@@ -167,3 +267,14 @@ func (r Role) CanAccessNATManagement() bool {
 	// All roles can access NAT management, but filtered by their router access
 	return true
 }
+
+// TOTPEnrollment is a freshly generated TOTP secret, waiting to be verified
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`      // Base32, for manual entry
+	OTPAuthURI string `json:"otpauth_uri"` // otpauth://totp/... for a QR code
+}
+
+// TOTPVerifyRequest confirms a TOTP enrollment with a code from the app
+type TOTPVerifyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
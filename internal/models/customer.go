@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Customer represents a customer (pelanggan) record keyed by PPPoE username.
+// Shared with mapping-ftth: its customer endpoint returns this same shape.
+type Customer struct {
+	ID            int       `json:"id"`
+	PPPoEUsername string    `json:"pppoe_username"`
+	FullName      string    `json:"full_name"`
+	Address       string    `json:"address,omitempty"`
+	Phone         string    `json:"phone,omitempty"`
+	Latitude      *float64  `json:"latitude,omitempty"`  // Only known to mapping-ftth
+	Longitude     *float64  `json:"longitude,omitempty"` // Only known to mapping-ftth
+	Source        string    `json:"source,omitempty"`    // "local" or "mapping-ftth"
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CustomerResponse represents the response for customer lookup API
+type CustomerResponse struct {
+	Status string    `json:"status"`
+	Data   *Customer `json:"data"`
+}
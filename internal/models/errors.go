@@ -10,6 +10,8 @@ const (
 	ErrCodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
 	ErrCodeSessionExpired     ErrorCode = "SESSION_EXPIRED"
 	ErrCodeTokenExpired       ErrorCode = "TOKEN_EXPIRED"
+	ErrCodeStepUpRequired     ErrorCode = "STEP_UP_REQUIRED"
+	ErrCodeTOTPRequired       ErrorCode = "TOTP_REQUIRED"
 
 	// Validation Errors
 	ErrCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
@@ -35,6 +37,7 @@ const (
 	ErrCodeRouterOffline      ErrorCode = "ROUTER_OFFLINE"
 	ErrCodeConnectionFailed   ErrorCode = "CONNECTION_FAILED"
 	ErrCodeCircuitBreakerOpen ErrorCode = "CIRCUIT_BREAKER_OPEN"
+	ErrCodeRouterBusy         ErrorCode = "ROUTER_BUSY"
 
 	// Generic Error
 	ErrCodeInternalError ErrorCode = "INTERNAL_ERROR"
@@ -150,6 +153,11 @@ var (
 		"Invalid username or password",
 	).WithSuggestion("Please check your credentials and try again")
 
+	ErrTOTPRequired = NewErrorDetail(
+		ErrCodeTOTPRequired,
+		"Two-factor authentication code required",
+	).WithSuggestion("Send the 6-digit code from your authenticator app as totp_code")
+
 	ErrSessionExpired = NewErrorDetail(
 		ErrCodeSessionExpired,
 		"Your session has expired",
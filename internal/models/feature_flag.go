@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// FeatureFlag represents a runtime feature flag
+type FeatureFlag struct {
+	Name        string    `json:"name"`
+	Enabled     bool      `json:"enabled"`
+	Description string    `json:"description,omitempty"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FeatureFlagUpdateRequest represents request to toggle a feature flag
+type FeatureFlagUpdateRequest struct {
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	Description string `json:"description"`
+}
+
+// Known feature flags. All default to off until enabled in the feature_flags table.
+const (
+	FeatureRouterLogs = "router_logs" // GET /api/routers/:id/logs
+)
+
+// KnownFeatureFlags lists flags with their descriptions so they show up before first toggle
+var KnownFeatureFlags = map[string]string{
+	FeatureRouterLogs: "Read RouterOS system logs via /api/routers/:id/logs",
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Component health status values
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+	HealthStatusSkipped   = "skipped"
+	HealthStatusDegraded  = "degraded"
+)
+
+// ComponentHealth represents the result of checking a single component
+type ComponentHealth struct {
+	Status    string                 `json:"status"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// DeepHealthResponse represents the component-by-component report of /api/health/deep
+type DeepHealthResponse struct {
+	Status     string                     `json:"status"` // healthy, degraded, unhealthy
+	Components map[string]ComponentHealth `json:"components"`
+	DurationMs int64                      `json:"duration_ms"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// RouterHealthPoint is one recorded background health check of a router
+type RouterHealthPoint struct {
+	RouterID       string    `json:"router_id"`
+	Status         string    `json:"status"` // healthy, degraded, down, unknown
+	Success        bool      `json:"success"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	CPUUsage       float64   `json:"cpu_usage"`
+	RAMUsage       float64   `json:"ram_usage"`
+	ErrorMessage   string    `json:"error_message,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// RouterHealthHistory is a router's health checks within a time range
+type RouterHealthHistory struct {
+	RouterID      string              `json:"router_id"`
+	RouterName    string              `json:"router_name"`
+	From          time.Time           `json:"from"`
+	To            time.Time           `json:"to"`
+	UptimePercent float64             `json:"uptime_percent"` // Successful checks in the range
+	Points        []RouterHealthPoint `json:"points"`
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
 
 // NATRouterConfig represents NAT-specific router configuration
 type NATRouterConfig struct {
@@ -11,6 +16,11 @@ type NATRouterConfig struct {
 	Password       string `json:"password"`
 	TunnelEndpoint string `json:"tunnel_endpoint"`
 	PublicONTURL   string `json:"public_ont_url"`
+	DefaultONTPort int    `json:"default_ont_port,omitempty"` // Fallback port for NAT updates (0 = use 80)
+	UseTLS         bool   `json:"use_tls,omitempty"`          // Connect over API-SSL
+	TLSSkipVerify  bool   `json:"tls_skip_verify,omitempty"`  // Accept self-signed router certificates
+	// ONTCommentPatterns identify the managed ONT rule (empty = DefaultONTCommentPattern)
+	ONTCommentPatterns []string `json:"ont_comment_patterns,omitempty"`
 }
 
 // ONTNATRule represents the specific ONT NAT rule data
@@ -32,6 +42,7 @@ type ONTNATRule struct {
 	Packets        int64  `json:"packets"`
 	TunnelEndpoint string `json:"tunnel_endpoint"`
 	PublicONTURL   string `json:"public_ont_url"`
+	IsONTRule      bool   `json:"is_ont_rule,omitempty"` // Matches the router's ONT comment patterns
 }
 
 // ONTConfig represents current ONT configuration for a router
@@ -60,13 +71,45 @@ type NATClient struct {
 	CallerID  string `json:"caller_id"`
 	Uptime    string `json:"uptime"`
 	Encoding  string `json:"encoding"`
+
+	// UptimeSeconds is Uptime parsed to seconds (0 if RouterOS sent something unparseable)
+	UptimeSeconds int64 `json:"uptime_seconds"`
+
+	// Customer enrichment (only populated with ?enrich=true and a matching record)
+	CustomerName string `json:"customer_name,omitempty"`
+	Address      string `json:"address,omitempty"`
+}
+
+// Sort orders accepted by GET /api/nat/clients?sort=
+const (
+	ClientSortUsername = "username" // Default
+	ClientSortUptime   = "uptime"   // Numeric, parsed from the RouterOS duration
+	ClientSortIP       = "ip"
+)
+
+// IsValidClientSort reports whether sort is a supported client sort order
+func IsValidClientSort(sort string) bool {
+	switch sort {
+	case ClientSortUsername, ClientSortUptime, ClientSortIP:
+		return true
+	}
+	return false
 }
 
 // NATUpdateRequest represents a request to update NAT rule
 type NATUpdateRequest struct {
-	Router string `json:"router" binding:"required"`
-	IP     string `json:"ip" binding:"required"`
-	Port   string `json:"port"`
+	Router     string `json:"router" binding:"required"`
+	IP         string `json:"ip" binding:"required"`
+	Port       string `json:"port"`
+	ExpectedIP string `json:"expected_ip,omitempty"` // Optional: the to-address the caller last saw; a mismatch returns 409
+	Protocol   string `json:"protocol,omitempty"`    // Optional: new rule protocol (tcp, udp, ...); omitted = unchanged
+	Comment    string `json:"comment,omitempty"`     // Optional: new rule comment, must keep the ONT marker; omitted = unchanged
+}
+
+// NATToggleRequest represents request to enable or disable the ONT NAT rule
+type NATToggleRequest struct {
+	Router  string `json:"router" binding:"required"`
+	Enabled *bool  `json:"enabled" binding:"required"`
 }
 
 // NATConfigsResponse represents the response for NAT configs API
@@ -77,8 +120,66 @@ type NATConfigsResponse struct {
 
 // NATClientsResponse represents the response for NAT clients API
 type NATClientsResponse struct {
-	Status string                     `json:"status"`
-	Data   map[string][]NATClient     `json:"data"`
+	Status       string                     `json:"status"`
+	Data         map[string][]NATClient     `json:"data"`
+	RouterStatus map[string]string          `json:"router_status"`    // Router -> RouterFetchOK / RouterFetchError
+	Errors       map[string]string          `json:"errors,omitempty"` // Router -> error message, for failed routers only
+}
+
+// NATClientsSummaryResponse is the response of GET /api/nat/clients/summary
+type NATClientsSummaryResponse struct {
+	Status       string            `json:"status"`
+	Data         map[string]int    `json:"data"` // Router -> online client count
+	Total        int               `json:"total"`
+	RouterStatus map[string]string `json:"router_status"`
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// Per-router outcome of a client fetch, as reported in NATClientsResponse.RouterStatus
+// and NATClientsSummaryResponse.RouterStatus
+const (
+	RouterFetchOK    = "ok"
+	RouterFetchError = "error"
+)
+
+// Client change events pushed by GET /ws/clients
+const (
+	ClientConnected    = "connected"
+	ClientDisconnected = "disconnected"
+	ClientIPChanged    = "ip_changed"
+)
+
+// ClientChange is one online client that connected, disconnected or changed IP
+type ClientChange struct {
+	Event      string     `json:"event"`
+	Router     string     `json:"router"`
+	Username   string     `json:"username"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	PreviousIP string     `json:"previous_ip,omitempty"`
+	Client     *NATClient `json:"client,omitempty"` // Current session, not set on disconnect
+	Timestamp  time.Time  `json:"timestamp"`
+}
+
+// Message types sent over GET /ws/clients
+const (
+	ClientStreamSnapshot = "snapshot"
+	ClientStreamChanges  = "changes"
+)
+
+// ClientStreamMessage is one WebSocket message of GET /ws/clients: the
+// clients when the stream opens, then batches of changes
+type ClientStreamMessage struct {
+	Type      string                 `json:"type"`
+	Clients   map[string][]NATClient `json:"clients,omitempty"`
+	Changes   []ClientChange         `json:"changes,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// RouterClients is one router's slice of a streamed client listing (one NDJSON line)
+type RouterClients struct {
+	Router  string      `json:"router"`
+	Clients []NATClient `json:"clients"`
+	Error   string      `json:"error,omitempty"`
 }
 
 // NATUpdateResponse represents the response for NAT update API
@@ -87,6 +188,72 @@ type NATUpdateResponse struct {
 	Message string `json:"message"`
 }
 
+// NATUpdateConflictResponse is returned (409) when the rule changed since the caller read it
+type NATUpdateConflictResponse struct {
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	CurrentIP   string `json:"current_ip"`
+	CurrentPort string `json:"current_port"`
+}
+
+// NATRuleTarget is where a NAT rule forwards to, as recorded in activity logs
+type NATRuleTarget struct {
+	ToAddresses string `json:"to_addresses"`
+	ToPorts     string `json:"to_ports"`
+	Protocol    string `json:"protocol,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// NATRollbackRequest asks to restore a router's ONT NAT rule to its state
+// before the last recorded update
+type NATRollbackRequest struct {
+	Router string `json:"router" binding:"required"`
+}
+
+// NATRollbackResult describes an applied NAT rule rollback
+type NATRollbackResult struct {
+	Router     string        `json:"router"`
+	LogID      int           `json:"rolled_back_log_id"` // Activity log entry of the undone update
+	Before     NATRuleTarget `json:"before"`             // Target replaced by the rollback
+	After      NATRuleTarget `json:"after"`              // Restored target
+}
+
+// NATUpdatePreview shows an ONT NAT rule before and after a proposed update
+type NATUpdatePreview struct {
+	Router      string      `json:"router"`
+	Before      *ONTNATRule `json:"before"`
+	After       *ONTNATRule `json:"after"`
+	Changes     []string    `json:"changes"`      // Fields that would change, e.g. "to_addresses"
+	WouldChange bool        `json:"would_change"` // False when the rule already matches
+}
+
+// NATUpdatePreviewResponse represents the response for NAT update preview API
+type NATUpdatePreviewResponse struct {
+	Status string            `json:"status"`
+	Data   *NATUpdatePreview `json:"data"`
+}
+
+// NATTargetConflict is a to-addresses value shared by more than one router's ONT rule
+type NATTargetConflict struct {
+	IPAddress string   `json:"ip_address"`
+	Routers   []string `json:"routers"`
+}
+
+// NATConflictsResponse represents the response for NAT conflicts API
+type NATConflictsResponse struct {
+	Status         string              `json:"status"`
+	Data           []NATTargetConflict `json:"data"`
+	RoutersScanned int                 `json:"routers_scanned"`
+}
+
+// NATRulesResponse represents the response for NAT rules API
+type NATRulesResponse struct {
+	Status string       `json:"status"`
+	Router string       `json:"router"`
+	Data   []ONTNATRule `json:"data"`
+	Total  int          `json:"total"`
+}
+
 // NATTestResponse represents the response for NAT test API
 type NATTestResponse struct {
 	Status string                           `json:"status"`
@@ -101,6 +268,19 @@ type RouterConnectionTest struct {
 	Board       string    `json:"board,omitempty"`
 	Message     string    `json:"message,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
+	FailureKind string    `json:"failure_kind,omitempty"` // e.g. "api_disabled", "auth_failed", "timeout"
+	Remediation string    `json:"remediation,omitempty"`  // Operator hint for the failure kind
+}
+
+// RouterReadiness is the router part of GET /ready?deep=true, taken from the
+// cached connection tests
+type RouterReadiness struct {
+	Checked   bool       `json:"checked"` // false until a connection test has run
+	Total     int        `json:"total"`
+	Connected int        `json:"connected"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Stale     bool       `json:"stale"`    // the result is older than the NAT cache TTL
+	Degraded  bool       `json:"degraded"` // routers are configured but none is connected
 }
 
 // PPPoEStatusRequest represents a request to check PPPoE status
@@ -110,6 +290,29 @@ type PPPoEStatusRequest struct {
 	TestConnectivity bool   `json:"test_connectivity,omitempty"` // Optional: perform TCP connectivity test
 }
 
+// MaxPPPoEBatchSize bounds the usernames of one batch PPPoE check
+const MaxPPPoEBatchSize = 100
+
+// PPPoEBatchCheckRequest is the body of POST /api/pppoe/check-batch
+type PPPoEBatchCheckRequest struct {
+	Usernames        []string `json:"usernames" binding:"required"`
+	TestConnectivity bool     `json:"test_connectivity,omitempty"`
+}
+
+// PPPoEBatchCheckResponse maps each checked username to its status
+type PPPoEBatchCheckResponse struct {
+	Status  string                          `json:"status"`
+	Total   int                             `json:"total"`
+	Online  int                             `json:"online"`
+	Results map[string]*PPPoEStatusResponse `json:"results"`
+}
+
+// PPPoEDisconnectRequest asks to drop a user's active PPPoE session on a router
+type PPPoEDisconnectRequest struct {
+	Router   string `json:"router" binding:"required"`
+	Username string `json:"username" binding:"required"`
+}
+
 // PPPoEStatusResult represents PPPoE status for a single router
 type PPPoEStatusResult struct {
 	Router             string        `json:"router"`
@@ -117,6 +320,7 @@ type PPPoEStatusResult struct {
 	IPAddress          string        `json:"ip_address,omitempty"`
 	CallerID           string        `json:"caller_id,omitempty"`
 	Uptime             string        `json:"uptime,omitempty"`
+	UptimeSeconds      int64         `json:"uptime_seconds,omitempty"`
 	Encoding           string        `json:"encoding,omitempty"`
 	SessionTime        string        `json:"session_time,omitempty"`
 	LastSeen           time.Time     `json:"last_seen,omitempty"`
@@ -127,8 +331,18 @@ type PPPoEStatusResult struct {
 	ReachablePort      string        `json:"reachable_port,omitempty"`
 	ConnectivityTime   time.Duration `json:"connectivity_time,omitempty"`
 	ConnectivityStatus string        `json:"connectivity_status,omitempty"` // "reachable", "unreachable", "not_tested"
+
+	CheckStatus string `json:"check_status"` // One of the PPPoECheck* values
 }
 
+// PPPoE status check outcomes per router
+const (
+	PPPoECheckOnline  = "online"
+	PPPoECheckOffline = "offline"
+	PPPoECheckError   = "error"
+	PPPoECheckTimeout = "timeout" // The router didn't answer before the check deadline
+)
+
 // PPPoEStatusResponse represents the response for PPPoE status check
 type PPPoEStatusResponse struct {
 	Status      string                         `json:"status"`
@@ -137,35 +351,66 @@ type PPPoEStatusResponse struct {
 	OnlineCount int                           `json:"online_count"`
 	Data        map[string]PPPoEStatusResult  `json:"data"`
 	Message     string                        `json:"message,omitempty"`
+	TimedOut    []string                      `json:"timed_out,omitempty"` // Routers that didn't answer in time
 	Timestamp   time.Time                     `json:"timestamp"`
+	Customer    *Customer                     `json:"customer,omitempty"` // Resolved customer details, when known
 }
 
-// PPPoESearchHistory represents historical PPPoE search for future enhancement
+// PPPoESearchHistory is one recorded PPPoE status check
 type PPPoESearchHistory struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	UserID    int       `json:"user_id"`
-	Result    string    `json:"result"`
-	Timestamp time.Time `json:"timestamp"`
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`              // PPPoE username searched
+	UserID     int       `json:"user_id"`               // User who ran the check
+	RouterName string    `json:"router_name,omitempty"` // Router(s) where the user was online, or the one checked
+	IsOnline   bool      `json:"is_online"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// PPPoEAuditEntry is one line of an uploaded PPPoE audit CSV
+type PPPoEAuditEntry struct {
+	Username       string
+	ExpectedRouter string // Optional: only look on this router
+}
+
+// PPPoE audit row statuses
+const (
+	PPPoEAuditOnline   = "online"
+	PPPoEAuditOffline  = "offline"
+	PPPoEAuditNoAccess = "no_access"
+	PPPoEAuditError    = "error"
+)
+
+// PPPoEAuditRow is one line of a PPPoE audit report
+type PPPoEAuditRow struct {
+	Username       string
+	ExpectedRouter string
+	Status         string // online, offline, no_access, error
+	Router         string // Router(s) the session was found on, ";"-separated
+	IPAddress      string
+	Uptime         string
+	Note           string
 }
 
 // PPPoEFuzzySearchRequest represents a request for fuzzy search
 type PPPoEFuzzySearchRequest struct {
-	Username string `json:"username" binding:"required"`
-	Router   string `json:"router,omitempty"` // Optional: specific router to search
-	Limit    int    `json:"limit,omitempty"`  // Optional: max results (default 5)
+	Username       string `json:"username" binding:"required"`
+	Router         string `json:"router,omitempty"`          // Optional: specific router to search
+	Limit          int    `json:"limit,omitempty"`           // Optional: max results (default 5)
+	IncludeOffline bool   `json:"include_offline,omitempty"` // Optional: also match PPPoE secrets without an active session
 }
 
 // PPPoEFuzzyMatch represents a fuzzy search match
 type PPPoEFuzzyMatch struct {
-	Username   string  `json:"username"`
-	Router     string  `json:"router"`
-	IPAddress  string  `json:"ip_address"`
-	CallerID   string  `json:"caller_id"`
-	Uptime     string  `json:"uptime"`
-	Profile    string  `json:"profile"`
-	Similarity float64 `json:"similarity"` // 0.0 to 1.0 similarity score (internal use)
-	IsOnline   bool    `json:"is_online"`
+	Username      string  `json:"username"`
+	Router        string  `json:"router"`
+	IPAddress     string  `json:"ip_address"`
+	CallerID      string  `json:"caller_id"`
+	Uptime        string  `json:"uptime"`
+	UptimeSeconds int64   `json:"uptime_seconds,omitempty"`
+	Profile       string  `json:"profile"`
+	Similarity    float64 `json:"similarity"` // 0.0 to 1.0 similarity score (internal use)
+	IsOnline      bool    `json:"is_online"`
 }
 
 // PPPoEFuzzySearchResponse represents fuzzy search response
@@ -184,18 +429,26 @@ type PPPoEFuzzySearchResponse struct {
 
 // Router represents a router configuration with full metadata
 type Router struct {
-	ID              string    `json:"id" binding:"required"`
-	Name            string    `json:"name" binding:"required"`
-	Host            string    `json:"host" binding:"required"`
-	Port            int       `json:"port" binding:"required,min=1,max=65535"`
-	Username        string    `json:"username" binding:"required"`
-	Password        string    `json:"password" binding:"required"`
-	TunnelEndpoint  string    `json:"tunnel_endpoint" binding:"required"`
-	PublicONTURL    string    `json:"public_ont_url" binding:"required"`
-	Enabled         bool      `json:"enabled"`
-	Description     string    `json:"description"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID             string `json:"id" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	Host           string `json:"host" binding:"required"`
+	Port           int    `json:"port" binding:"required,min=1,max=65535"`
+	Username       string `json:"username" binding:"required"`
+	Password       string `json:"password" binding:"required"`
+	TunnelEndpoint string `json:"tunnel_endpoint" binding:"required"`
+	PublicONTURL   string `json:"public_ont_url" binding:"required"`
+	Enabled        bool   `json:"enabled"`
+	Description    string `json:"description"`
+	DefaultONTPort int    `json:"default_ont_port,omitempty"` // ONT web UI port used when a NAT update omits the port
+	Critical       bool   `json:"critical"`                   // Monitored on a shorter interval with a lower fail threshold
+	Priority       int    `json:"priority"`                   // Higher sorts first in monitoring output
+	UseTLS         bool   `json:"use_tls"`                    // Connect over API-SSL (usually port 8729)
+	TLSSkipVerify  bool   `json:"tls_skip_verify"`            // Accept self-signed router certificates
+	// ONTCommentPatterns identify the managed ONT NAT rule (empty = DefaultONTCommentPattern)
+	ONTCommentPatterns []string  `json:"ont_comment_patterns,omitempty"`
+	Tags               []string  `json:"tags"` // Grouping tags (region, branch, ...), normalized lowercase
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 // RouterStorageConfig represents the complete router storage configuration
@@ -208,6 +461,18 @@ type RouterStorageConfig struct {
 	Metadata      RouterStorageMetadata  `json:"metadata"`
 }
 
+// RoleRouters is the router list a role can access ("*" = every router)
+type RoleRouters struct {
+	Role     string   `json:"role"`
+	Routers  []string `json:"routers"`
+	Wildcard bool     `json:"wildcard"`
+}
+
+// RoleRoutersUpdateRequest replaces a role's router list
+type RoleRoutersUpdateRequest struct {
+	Routers []string `json:"routers" binding:"required"` // Router names, or ["*"] for every router; [] removes all access
+}
+
 // RouterAccessControl represents role-based access control for routers
 type RouterAccessControl struct {
 	Roles map[string]RouterRole `json:"roles"`
@@ -228,6 +493,40 @@ type RouterStorageMetadata struct {
 	BackupLocation string    `json:"backup_location"`
 }
 
+// MaxRouterNameLength matches routers.name / user_routers.router_name (VARCHAR(100))
+const MaxRouterNameLength = 100
+
+// routerNamePattern allows letters, digits, spaces and - _ . / ( ). Quotes,
+// '=', ',', ';' and control characters are rejected: router names end up in
+// RouterOS API words and NAT comment matching, and "*" means "all routers"
+// in access control.
+var routerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _./()-]*$`)
+
+// NormalizeRouterName trims a router name and collapses internal whitespace,
+// so "LANE2 " and "LANE2" or "BT  JAYA" and "BT JAYA" are the same name
+func NormalizeRouterName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// RouterNameKey is the case-insensitive form used for uniqueness checks
+func RouterNameKey(name string) string {
+	return strings.ToLower(NormalizeRouterName(name))
+}
+
+// ValidateRouterName checks a normalized router name's length and charset
+func ValidateRouterName(name string) error {
+	if name == "" {
+		return fmt.Errorf("router name is required")
+	}
+	if len(name) > MaxRouterNameLength {
+		return fmt.Errorf("router name must be at most %d characters", MaxRouterNameLength)
+	}
+	if !routerNamePattern.MatchString(name) {
+		return fmt.Errorf("router name may only contain letters, digits, spaces and - _ . / ( ), starting with a letter or digit")
+	}
+	return nil
+}
+
 // RouterCreateRequest represents request to create a new router
 type RouterCreateRequest struct {
 	Name           string `json:"name" binding:"required"`
@@ -239,6 +538,14 @@ type RouterCreateRequest struct {
 	PublicONTURL   string `json:"public_ont_url" binding:"required"`
 	Description    string `json:"description"`
 	Enabled        bool   `json:"enabled"`
+	DefaultONTPort int    `json:"default_ont_port" binding:"omitempty,min=1,max=65535"`
+	Critical       bool   `json:"critical"`
+	Priority       int    `json:"priority" binding:"min=0,max=100"`
+	UseTLS         bool   `json:"use_tls"`         // Connect over API-SSL (usually port 8729)
+	TLSSkipVerify  bool   `json:"tls_skip_verify"` // Accept self-signed router certificates
+	// ONTCommentPatterns: substrings or /regex/ marking the ONT rule (empty = default)
+	ONTCommentPatterns []string `json:"ont_comment_patterns"`
+	Tags               []string `json:"tags"` // Grouping tags, matched case-insensitively
 }
 
 // RouterUpdateRequest represents request to update an existing router
@@ -252,6 +559,14 @@ type RouterUpdateRequest struct {
 	PublicONTURL   string `json:"public_ont_url" binding:"required"`
 	Description    string `json:"description"`
 	Enabled        bool   `json:"enabled"`
+	DefaultONTPort int    `json:"default_ont_port" binding:"omitempty,min=1,max=65535"`
+	Critical       bool   `json:"critical"`
+	Priority       int    `json:"priority" binding:"min=0,max=100"`
+	UseTLS         bool   `json:"use_tls"`         // Connect over API-SSL (usually port 8729)
+	TLSSkipVerify  bool   `json:"tls_skip_verify"` // Accept self-signed router certificates
+	// ONTCommentPatterns: substrings or /regex/ marking the ONT rule (empty = default)
+	ONTCommentPatterns []string `json:"ont_comment_patterns"`
+	Tags               []string `json:"tags"` // Grouping tags, matched case-insensitively
 }
 
 // RouterTestRequest represents request to test router connection
@@ -261,17 +576,24 @@ type RouterTestRequest struct {
 
 // RouterResponse represents a single router response (without sensitive data)
 type RouterResponse struct {
-	ID             string    `json:"id"`
-	Name           string    `json:"name"`
-	Host           string    `json:"host"`
-	Port           int       `json:"port"`
-	Username       string    `json:"username"`
-	TunnelEndpoint string    `json:"tunnel_endpoint"`
-	PublicONTURL   string    `json:"public_ont_url"`
-	Enabled        bool      `json:"enabled"`
-	Description    string    `json:"description"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	Host               string    `json:"host"`
+	Port               int       `json:"port"`
+	Username           string    `json:"username"`
+	TunnelEndpoint     string    `json:"tunnel_endpoint"`
+	PublicONTURL       string    `json:"public_ont_url"`
+	Enabled            bool      `json:"enabled"`
+	Description        string    `json:"description"`
+	DefaultONTPort     int       `json:"default_ont_port,omitempty"`
+	Critical           bool      `json:"critical"`
+	Priority           int       `json:"priority"`
+	UseTLS             bool      `json:"use_tls"`
+	TLSSkipVerify      bool      `json:"tls_skip_verify"`
+	ONTCommentPatterns []string  `json:"ont_comment_patterns,omitempty"`
+	Tags               []string  `json:"tags"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 	// Password is intentionally excluded for security
 }
 
@@ -281,6 +603,16 @@ type RouterListResponse struct {
 	Data    []RouterResponse `json:"data"`
 	Message string           `json:"message,omitempty"`
 	Total   int              `json:"total"`
+	Meta    *PaginationMeta  `json:"meta,omitempty"`
+}
+
+// RouterListFilter narrows and pages the router list
+type RouterListFilter struct {
+	Search string   // Case-insensitive match on name, host or description
+	Tag    string   // Normalized tag the router must carry
+	Names  []string // Only these routers, on top of the role (nil = no restriction)
+	Limit  int      // Page size (0 = all matches)
+	Offset int
 }
 
 // RouterDetailResponse represents response for single router API
@@ -304,6 +636,28 @@ type RouterUpdateResponse struct {
 	Message string         `json:"message"`
 }
 
+// RouterCredentialRotateRequest represents request for rotating a router's RouterOS credentials
+type RouterCredentialRotateRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Verify   *bool  `json:"verify,omitempty"` // Test-connect with the new credentials first (default true)
+	Force    bool   `json:"force"`            // Save even if the verification probe fails
+}
+
+// ShouldVerify reports whether the new credentials must be probed before saving
+func (r *RouterCredentialRotateRequest) ShouldVerify() bool {
+	return r.Verify == nil || *r.Verify
+}
+
+// RouterCredentialRotateResponse represents response for router credential rotation API
+type RouterCredentialRotateResponse struct {
+	Status             string                `json:"status"`
+	Data               RouterResponse        `json:"data"`
+	Verification       *RouterConnectionTest `json:"verification,omitempty"`
+	DrainedConnections int                   `json:"drained_connections"`
+	Message            string                `json:"message"`
+}
+
 // RouterDeleteResponse represents response for router deletion API
 type RouterDeleteResponse struct {
 	Status  string `json:"status"`
@@ -332,6 +686,29 @@ type RouterValidationResponse struct {
 	Errors []RouterValidationError `json:"errors"`
 }
 
+// RouterBatchValidateRequest represents a request to validate many router configs
+type RouterBatchValidateRequest struct {
+	Routers          []RouterCreateRequest `json:"routers" binding:"required,min=1"`
+	TestConnectivity bool                  `json:"test_connectivity,omitempty"` // Also try to log in to each valid config
+}
+
+// RouterBatchValidateResult is the validation outcome for one item of a batch
+type RouterBatchValidateResult struct {
+	Index      int                     `json:"index"`
+	Name       string                  `json:"name"`
+	Valid      bool                    `json:"valid"`
+	Errors     []RouterValidationError `json:"errors,omitempty"`
+	Connection *RouterConnectionTest   `json:"connection,omitempty"` // Only with test_connectivity
+}
+
+// RouterBatchValidateResponse represents the response for batch router validation
+type RouterBatchValidateResponse struct {
+	Status  string                      `json:"status"`
+	Valid   int                         `json:"valid"`
+	Invalid int                         `json:"invalid"`
+	Results []RouterBatchValidateResult `json:"results"`
+}
+
 // RouterImportRequest represents request to import routers from JSON
 type RouterImportRequest struct {
 	Routers   []RouterCreateRequest `json:"routers" binding:"required"`
@@ -348,22 +725,134 @@ type RouterImportResponse struct {
 	FailedItems []string `json:"failed_items,omitempty"`
 }
 
-// RouterExportResponse represents response for router export API
+// RouterExportResponse represents response for router export API. Routers
+// are in RouterImportRequest form so an export can be imported elsewhere.
 type RouterExportResponse struct {
-	Status    string           `json:"status"`
-	Routers   []RouterResponse `json:"routers"`
-	Total     int              `json:"total"`
-	ExportedAt time.Time       `json:"exported_at"`
+	Status           string                `json:"status"`
+	Routers          []RouterCreateRequest `json:"routers"`
+	Total            int                   `json:"total"`
+	IncludePasswords bool                  `json:"include_passwords"`
+	ExportedAt       time.Time             `json:"exported_at"`
 }
 
 // RouterStatsResponse represents response for router statistics API
 type RouterStatsResponse struct {
-	Status          string                           `json:"status"`
-	TotalRouters    int                              `json:"total_routers"`
-	ActiveRouters   int                              `json:"active_routers"`
-	DisabledRouters int                              `json:"disabled_routers"`
-	ConnectionTests map[string]RouterConnectionTest  `json:"connection_tests"`
-	LastUpdated     time.Time                        `json:"last_updated"`
+	Status          string                          `json:"status"`
+	TotalRouters    int                             `json:"total_routers"`
+	ActiveRouters   int                             `json:"active_routers"`
+	DisabledRouters int                             `json:"disabled_routers"`
+	ConnectionTests map[string]RouterConnectionTest `json:"connection_tests"`
+	LastUpdated     time.Time                       `json:"last_updated"`
+	// ByTag counts routers per tag when grouping is requested; a router
+	// counts once under each of its tags, untagged routers under Untagged
+	ByTag    map[string]RouterTagStats `json:"by_tag,omitempty"`
+	Untagged *RouterTagStats           `json:"untagged,omitempty"`
+}
+
+// RouterTagStats counts the routers of one tag
+type RouterTagStats struct {
+	TotalRouters    int `json:"total_routers"`
+	ActiveRouters   int `json:"active_routers"`
+	DisabledRouters int `json:"disabled_routers"`
+}
+
+// Add counts one router
+func (s *RouterTagStats) Add(enabled bool) {
+	s.TotalRouters++
+	if enabled {
+		s.ActiveRouters++
+	} else {
+		s.DisabledRouters++
+	}
+}
+
+// RouterLogEntry represents a single entry from the router's /log
+type RouterLogEntry struct {
+	ID      string   `json:"id"`
+	Time    string   `json:"time"`
+	Topics  []string `json:"topics"`
+	Message string   `json:"message"`
+}
+
+// RouterLogFilter represents filters for reading router logs
+type RouterLogFilter struct {
+	Topics   []string // Entry must carry at least one of these topics (e.g. pppoe, ppp)
+	Severity string   // Entry must also carry this severity topic (e.g. error, warning)
+	Limit    int      // Most recent N entries
+}
+
+// RouterLogsResponse represents response for router logs API
+type RouterLogsResponse struct {
+	Status     string           `json:"status"`
+	RouterID   string           `json:"router_id"`
+	RouterName string           `json:"router_name"`
+	Data       []RouterLogEntry `json:"data"`
+	Total      int              `json:"total"`
+}
+
+// ConnTrackEntry represents a single entry from the router's /ip/firewall/connection table
+type ConnTrackEntry struct {
+	ID         string `json:"id"`
+	Protocol   string `json:"protocol"`
+	SrcAddress string `json:"src_address"`
+	DstAddress string `json:"dst_address"`
+	ReplySrc   string `json:"reply_src_address,omitempty"`
+	ReplyDst   string `json:"reply_dst_address,omitempty"`
+	State      string `json:"state,omitempty"`
+	Timeout    string `json:"timeout"`
+	OrigBytes  string `json:"orig_bytes,omitempty"`
+	ReplyBytes string `json:"reply_bytes,omitempty"`
+	Assured    bool   `json:"assured"`
+	SrcNAT     bool   `json:"srcnat"`
+	DstNAT     bool   `json:"dstnat"`
+}
+
+// ConnTrackResponse represents response for the router conntrack API
+type ConnTrackResponse struct {
+	Status     string           `json:"status"`
+	RouterID   string           `json:"router_id"`
+	RouterName string           `json:"router_name"`
+	IP         string           `json:"ip"`
+	Data       []ConnTrackEntry `json:"data"`
+	Total      int              `json:"total"`
+	Truncated  bool             `json:"truncated"`
+}
+
+// RouterImpactUser is a user assigned to a router considered for disabling
+type RouterImpactUser struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	FullName   string `json:"full_name"`
+	Role       Role   `json:"role"`
+	OnlyRouter bool   `json:"only_router"` // The user has no other assigned router
+}
+
+// RouterImpact summarizes what disabling a router would affect
+type RouterImpact struct {
+	RouterID      string             `json:"router_id"`
+	RouterName    string             `json:"router_name"`
+	Enabled       bool               `json:"enabled"`
+	OnlineClients int                `json:"online_clients"`
+	Clients       []NATClient        `json:"clients"`
+	ClientsSource string             `json:"clients_source"`          // "cache" or "live"
+	ClientsError  string             `json:"clients_error,omitempty"` // Router could not be queried
+	AssignedUsers int                `json:"assigned_users"`
+	Users         []RouterImpactUser `json:"users"`
+	HasONTRule    bool               `json:"has_ont_rule"`
+	ONTRule       *ONTConfig         `json:"ont_rule,omitempty"`
+}
+
+// RouterCircuit is the circuit breaker state of a router. While OPEN, connection
+// tests are refused until HalfOpenIn has passed and one test is let through.
+type RouterCircuit struct {
+	RouterID         string     `json:"router_id,omitempty"`
+	RouterName       string     `json:"router_name"`
+	State            string     `json:"state"`                // CLOSED, OPEN or HALF-OPEN
+	Failures         int        `json:"failures"`             // Consecutive failures
+	FailureThreshold int        `json:"failure_threshold"`    // Failures that open the circuit
+	HalfOpenIn       int        `json:"half_open_in_seconds"` // Seconds until an OPEN circuit allows a test (0 otherwise)
+	LastFailureTime  *time.Time `json:"last_failure_time,omitempty"`
+	LastSuccessTime  *time.Time `json:"last_success_time,omitempty"`
 }
 
 // RouterBackupRequest represents request to backup router configurations
@@ -384,51 +873,95 @@ type RouterBackupResponse struct {
 
 // ToRouter converts a RouterCreateRequest to a Router with generated metadata
 func (req *RouterCreateRequest) ToRouter(id string) Router {
-	now := time.Now()
+	now := time.Now().UTC()
 	return Router{
-		ID:             id,
-		Name:           req.Name,
-		Host:           req.Host,
-		Port:           req.Port,
-		Username:       req.Username,
-		Password:       req.Password,
-		TunnelEndpoint: req.TunnelEndpoint,
-		PublicONTURL:   req.PublicONTURL,
-		Enabled:        req.Enabled,
-		Description:    req.Description,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+		ID:                 id,
+		Name:               req.Name,
+		Host:               req.Host,
+		Port:               req.Port,
+		Username:           req.Username,
+		Password:           req.Password,
+		TunnelEndpoint:     req.TunnelEndpoint,
+		PublicONTURL:       req.PublicONTURL,
+		Enabled:            req.Enabled,
+		Description:        req.Description,
+		DefaultONTPort:     req.DefaultONTPort,
+		Critical:           req.Critical,
+		Priority:           req.Priority,
+		UseTLS:             req.UseTLS,
+		TLSSkipVerify:      req.TLSSkipVerify,
+		ONTCommentPatterns: req.ONTCommentPatterns,
+		Tags:               req.Tags,
+		CreatedAt:          now,
+		UpdatedAt:          now,
 	}
 }
 
 // ToResponse converts a Router to RouterResponse (excluding sensitive data)
 func (r *Router) ToResponse() RouterResponse {
 	return RouterResponse{
-		ID:             r.ID,
-		Name:           r.Name,
-		Host:           r.Host,
-		Port:           r.Port,
-		Username:       r.Username,
-		TunnelEndpoint: r.TunnelEndpoint,
-		PublicONTURL:   r.PublicONTURL,
-		Enabled:        r.Enabled,
-		Description:    r.Description,
-		CreatedAt:      r.CreatedAt,
-		UpdatedAt:      r.UpdatedAt,
+		ID:                 r.ID,
+		Name:               r.Name,
+		Host:               r.Host,
+		Port:               r.Port,
+		Username:           r.Username,
+		TunnelEndpoint:     r.TunnelEndpoint,
+		PublicONTURL:       r.PublicONTURL,
+		Enabled:            r.Enabled,
+		Description:        r.Description,
+		DefaultONTPort:     r.DefaultONTPort,
+		Critical:           r.Critical,
+		Priority:           r.Priority,
+		UseTLS:             r.UseTLS,
+		TLSSkipVerify:      r.TLSSkipVerify,
+		ONTCommentPatterns: r.ONTCommentPatterns,
+		Tags:               r.Tags,
+		CreatedAt:          r.CreatedAt,
+		UpdatedAt:          r.UpdatedAt,
 	}
 }
 
 // ToNATRouterConfig converts a Router to NATRouterConfig for backward compatibility
 func (r *Router) ToNATRouterConfig() NATRouterConfig {
 	return NATRouterConfig{
-		Name:           r.Name,
-		Host:           r.Host,
-		Port:           r.Port,
-		Username:       r.Username,
-		Password:       r.Password,
-		TunnelEndpoint: r.TunnelEndpoint,
-		PublicONTURL:   r.PublicONTURL,
+		Name:               r.Name,
+		Host:               r.Host,
+		Port:               r.Port,
+		Username:           r.Username,
+		Password:           r.Password,
+		TunnelEndpoint:     r.TunnelEndpoint,
+		PublicONTURL:       r.PublicONTURL,
+		DefaultONTPort:     r.DefaultONTPort,
+		UseTLS:             r.UseTLS,
+		TLSSkipVerify:      r.TLSSkipVerify,
+		ONTCommentPatterns: r.ONTCommentPatterns,
+	}
+}
+
+// ToCreateRequest converts a Router to the request that would recreate it,
+// with the password left empty unless includePassword is set
+func (r *Router) ToCreateRequest(includePassword bool) RouterCreateRequest {
+	req := RouterCreateRequest{
+		Name:               r.Name,
+		Host:               r.Host,
+		Port:               r.Port,
+		Username:           r.Username,
+		TunnelEndpoint:     r.TunnelEndpoint,
+		PublicONTURL:       r.PublicONTURL,
+		Description:        r.Description,
+		Enabled:            r.Enabled,
+		DefaultONTPort:     r.DefaultONTPort,
+		Critical:           r.Critical,
+		Priority:           r.Priority,
+		UseTLS:             r.UseTLS,
+		TLSSkipVerify:      r.TLSSkipVerify,
+		ONTCommentPatterns: r.ONTCommentPatterns,
+		Tags:               r.Tags,
 	}
+	if includePassword {
+		req.Password = r.Password
+	}
+	return req
 }
 
 // UpdateFromRequest updates router fields from RouterUpdateRequest
@@ -442,7 +975,14 @@ func (r *Router) UpdateFromRequest(req *RouterUpdateRequest) {
 	r.PublicONTURL = req.PublicONTURL
 	r.Description = req.Description
 	r.Enabled = req.Enabled
-	r.UpdatedAt = time.Now()
+	r.DefaultONTPort = req.DefaultONTPort
+	r.Critical = req.Critical
+	r.Priority = req.Priority
+	r.UseTLS = req.UseTLS
+	r.TLSSkipVerify = req.TLSSkipVerify
+	r.ONTCommentPatterns = req.ONTCommentPatterns
+	r.Tags = req.Tags
+	r.UpdatedAt = time.Now().UTC()
 }
 
 // ============================================================================
@@ -466,6 +1006,38 @@ type ONTWiFiInfo struct {
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
 
+// ChangedFields lists the WiFi settings (ssid, password, security,
+// encryption, authentication) that differ from prev
+func (i *ONTWiFiInfo) ChangedFields(prev *ONTWiFiInfo) []string {
+	changed := []string{}
+	for _, field := range []struct {
+		name          string
+		current, prev string
+	}{
+		{"ssid", i.SSID, prev.SSID},
+		{"password", i.Password, prev.Password},
+		{"security", i.Security, prev.Security},
+		{"encryption", i.Encryption, prev.Encryption},
+		{"authentication", i.Authentication, prev.Authentication},
+	} {
+		if field.current != field.prev {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// ONTWiFiChange is a history entry whose SSID or password differs from the
+// customer's previous extraction
+type ONTWiFiChange struct {
+	ONTWiFiInfo
+	PreviousID          int       `json:"previous_id"`
+	PreviousSSID        string    `json:"previous_ssid"`
+	PreviousPassword    string    `json:"previous_password"`
+	PreviousExtractedAt time.Time `json:"previous_extracted_at"`
+	ChangedFields       []string  `json:"changed_fields"` // "ssid" and/or "password"
+}
+
 // ONTWiFiExtractRequest represents request to extract WiFi info from ONT
 type ONTWiFiExtractRequest struct {
 	ONTURL        string `json:"ont_url" binding:"required"`
@@ -487,11 +1059,15 @@ type ONTWiFiExtractFromNATRequest struct {
 
 // ONTWiFiExtractResponse represents response for WiFi extraction
 type ONTWiFiExtractResponse struct {
-	Status       string        `json:"status"`
-	Data         *ONTWiFiInfo  `json:"data,omitempty"`
-	Message      string        `json:"message"`
+	Status         string        `json:"status"`
+	Data           *ONTWiFiInfo  `json:"data,omitempty"`
+	Message        string        `json:"message"`
 	ExtractionTime time.Duration `json:"extraction_time,omitempty"` // Time taken for extraction
-	Timestamp    time.Time     `json:"timestamp"`
+	Timestamp      time.Time     `json:"timestamp"`
+	// Changed is set when the customer's previous extraction had different
+	// WiFi settings, listed in ChangedFields
+	Changed       bool     `json:"changed"`
+	ChangedFields []string `json:"changed_fields,omitempty"`
 }
 
 // ONTWiFiHistoryRequest represents request to get WiFi extraction history
@@ -507,9 +1083,60 @@ type ONTWiFiHistoryResponse struct {
 	Status  string          `json:"status"`
 	Data    []ONTWiFiInfo   `json:"data"`
 	Total   int             `json:"total"`
+	Meta    *PaginationMeta `json:"meta,omitempty"`
 	Message string          `json:"message,omitempty"`
 }
 
+// ONTWiFiStatsFilter scopes GET /api/ont/wifi/stats to a UTC date range and,
+// for non-administrators, to the routers they can access
+type ONTWiFiStatsFilter struct {
+	From    time.Time // First day (UTC midnight), inclusive
+	To      time.Time // Last day (UTC midnight), inclusive
+	Routers []string  // nil = every record, including ones without a router
+}
+
+// ONTWiFiStats represents aggregated WiFi extraction statistics
+type ONTWiFiStats struct {
+	From             string                 `json:"from"` // YYYY-MM-DD
+	To               string                 `json:"to"`   // YYYY-MM-DD
+	TotalExtractions int                    `json:"total_extractions"`
+	UniqueCustomers  int                    `json:"unique_customers"` // Distinct PPPoE usernames
+	UniqueRouters    int                    `json:"unique_routers"`
+	UniqueModels     int                    `json:"unique_models"`
+	LatestExtraction *time.Time             `json:"latest_extraction,omitempty"`
+	OldestExtraction *time.Time             `json:"oldest_extraction,omitempty"`
+	Attempts         int                    `json:"attempts"` // Extraction attempts from the activity log
+	Successful       int                    `json:"successful"`
+	Failed           int                    `json:"failed"`
+	SuccessRate      *float64               `json:"success_rate"` // Percent; null without attempts
+	PerRouter        []ONTWiFiRouterStats   `json:"per_router"`
+	SecurityTypes    []ONTWiFiSecurityStats `json:"security_types"` // Most common first, top 10
+	Series           []ONTWiFiStatsPoint    `json:"series"`         // One point per day
+}
+
+// ONTWiFiRouterStats counts extractions for one router
+type ONTWiFiRouterStats struct {
+	Router          string `json:"router"` // "(none)" for records saved without a router
+	Extractions     int    `json:"extractions"`
+	UniqueCustomers int    `json:"unique_customers"`
+}
+
+// ONTWiFiSecurityStats counts extractions per WiFi security type
+type ONTWiFiSecurityStats struct {
+	Security string `json:"security"` // "unknown" when the ONT didn't report one
+	Count    int    `json:"count"`
+}
+
+// ONTWiFiStatsPoint is one day of the extraction time series
+type ONTWiFiStatsPoint struct {
+	Date        string   `json:"date"` // YYYY-MM-DD (UTC)
+	Extractions int      `json:"extractions"`
+	Attempts    int      `json:"attempts"`
+	Successful  int      `json:"successful"`
+	Failed      int      `json:"failed"`
+	SuccessRate *float64 `json:"success_rate"`
+}
+
 // ONTWiFiAvailabilityResponse represents response for checking webautomation availability
 type ONTWiFiAvailabilityResponse struct {
 	Status          string   `json:"status"`
@@ -526,11 +1153,14 @@ type ONTWiFiBulkExtractRequest struct {
 
 // ONTWiFiBulkExtractResult represents result for single extraction in bulk operation
 type ONTWiFiBulkExtractResult struct {
-	ONTURL       string        `json:"ont_url"`
-	Success      bool          `json:"success"`
-	Data         *ONTWiFiInfo  `json:"data,omitempty"`
-	Error        string        `json:"error,omitempty"`
-	ExtractTime  time.Duration `json:"extract_time"`
+	ONTURL        string        `json:"ont_url"`
+	PPPoEUsername string        `json:"pppoe_username,omitempty"`
+	Success       bool          `json:"success"`
+	Data          *ONTWiFiInfo  `json:"data,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	ExtractTime   time.Duration `json:"extract_time"`
+	Changed       bool          `json:"changed"`
+	ChangedFields []string      `json:"changed_fields,omitempty"`
 }
 
 // ONTWiFiBulkExtractResponse represents response for bulk WiFi extraction
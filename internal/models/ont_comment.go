@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultONTCommentPattern marks the NAT rule used for remote ONT access on
+// routers without their own patterns
+const DefaultONTCommentPattern = "REMOTE ONT PELANGGAN"
+
+// MaxONTCommentPatterns caps the patterns a router may configure
+const MaxONTCommentPatterns = 10
+
+// ONTCommentMatcher recognizes the managed ONT NAT rule by its comment. Each
+// pattern is a case-insensitive substring, or a regular expression when
+// written as /expr/ (matched case-insensitively too).
+type ONTCommentMatcher struct {
+	substrings []string
+	regexps    []*regexp.Regexp
+}
+
+// NewONTCommentMatcher compiles patterns; an empty list means the default pattern
+func NewONTCommentMatcher(patterns []string) (*ONTCommentMatcher, error) {
+	if len(patterns) == 0 {
+		patterns = []string{DefaultONTCommentPattern}
+	}
+
+	matcher := &ONTCommentMatcher{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if expr, ok := ontCommentRegexp(pattern); ok {
+			re, err := regexp.Compile("(?i)" + expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ONT comment pattern %q: %v", pattern, err)
+			}
+			matcher.regexps = append(matcher.regexps, re)
+			continue
+		}
+		if pattern != "" {
+			matcher.substrings = append(matcher.substrings, strings.ToUpper(pattern))
+		}
+	}
+	return matcher, nil
+}
+
+// Matches reports whether comment marks the managed ONT rule
+func (m *ONTCommentMatcher) Matches(comment string) bool {
+	upper := strings.ToUpper(comment)
+	for _, substring := range m.substrings {
+		if strings.Contains(upper, substring) {
+			return true
+		}
+	}
+	for _, re := range m.regexps {
+		if re.MatchString(comment) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExactComments returns the substring patterns, usable as exact-match hints
+// for a server-side filtered query before falling back to a full scan
+func (m *ONTCommentMatcher) ExactComments() []string {
+	return m.substrings
+}
+
+// String describes the accepted patterns for error messages
+func (m *ONTCommentMatcher) String() string {
+	parts := make([]string, 0, len(m.substrings)+len(m.regexps))
+	for _, substring := range m.substrings {
+		parts = append(parts, fmt.Sprintf("%q", substring))
+	}
+	for _, re := range m.regexps {
+		parts = append(parts, "/"+strings.TrimPrefix(re.String(), "(?i)")+"/")
+	}
+	return strings.Join(parts, " or ")
+}
+
+// ValidateONTCommentPatterns checks a router's configured patterns
+func ValidateONTCommentPatterns(patterns []string) error {
+	if len(patterns) > MaxONTCommentPatterns {
+		return fmt.Errorf("at most %d ONT comment patterns are allowed", MaxONTCommentPatterns)
+	}
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("ONT comment patterns must not be empty")
+		}
+		if expr, ok := ontCommentRegexp(strings.TrimSpace(pattern)); ok && expr == "" {
+			return fmt.Errorf("ONT comment pattern %q has an empty expression", pattern)
+		}
+	}
+	_, err := NewONTCommentMatcher(patterns)
+	return err
+}
+
+// ontCommentRegexp returns the expression of a /expr/ pattern
+func ontCommentRegexp(pattern string) (string, bool) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return pattern[1 : len(pattern)-1], true
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package models
+
+// PaginationMeta represents offset-based paging hints shared by all list endpoints
+type PaginationMeta struct {
+	Total   int  `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasNext bool `json:"has_next"`
+	HasPrev bool `json:"has_prev"`
+}
+
+// NewPaginationMeta computes paging hints for a page of results.
+// A limit of 0 means the whole result set was returned in one page.
+func NewPaginationMeta(total, limit, offset int) *PaginationMeta {
+	if offset < 0 {
+		offset = 0
+	}
+
+	meta := &PaginationMeta{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasPrev: offset > 0,
+	}
+
+	if limit > 0 {
+		meta.HasNext = offset+limit < total
+	}
+
+	return meta
+}
+
+// SuccessResponse is the envelope shared by success responses: the payload is
+// always under data and list paging under meta. Total repeats meta.total at the
+// top level because existing clients read it there.
+type SuccessResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message,omitempty"`
+	Data    interface{}     `json:"data"`
+	Total   *int            `json:"total,omitempty"`
+	Meta    *PaginationMeta `json:"meta,omitempty"`
+}
+
+// NewSuccessResponse wraps data in the success envelope. Only the first meta is used.
+func NewSuccessResponse(message string, data interface{}, meta ...*PaginationMeta) SuccessResponse {
+	response := SuccessResponse{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+	}
+	if len(meta) > 0 && meta[0] != nil {
+		response.Meta = meta[0]
+		response.Total = &meta[0].Total
+	}
+	return response
+}
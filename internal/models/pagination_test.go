@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestNewPaginationMeta(t *testing.T) {
+	tests := []struct {
+		name                 string
+		total, limit, offset int
+		wantNext, wantPrev   bool
+		wantOffset           int
+	}{
+		{"first page", 25, 10, 0, true, false, 0},
+		{"middle page", 25, 10, 10, true, true, 10},
+		{"last page", 25, 10, 20, false, true, 20},
+		{"last page filled exactly", 20, 10, 10, false, true, 10},
+		{"single page", 5, 10, 0, false, false, 0},
+		{"empty result", 0, 10, 0, false, false, 0},
+		{"past the end", 25, 10, 30, false, true, 30},
+		{"no limit returns everything", 25, 0, 0, false, false, 0},
+		{"negative offset is clamped", 25, 10, -5, true, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := NewPaginationMeta(tt.total, tt.limit, tt.offset)
+			if meta.HasNext != tt.wantNext || meta.HasPrev != tt.wantPrev {
+				t.Errorf("has_next/has_prev = %v/%v, want %v/%v", meta.HasNext, meta.HasPrev, tt.wantNext, tt.wantPrev)
+			}
+			if meta.Total != tt.total || meta.Limit != tt.limit || meta.Offset != tt.wantOffset {
+				t.Errorf("meta = %+v, want total %d, limit %d, offset %d", meta, tt.total, tt.limit, tt.wantOffset)
+			}
+		})
+	}
+}
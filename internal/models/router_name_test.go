@@ -0,0 +1,68 @@
+package models
+
+import "testing"
+
+func TestRouterNameNormalization(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantNormalized string
+		wantKey        string
+	}{
+		{"trailing space", "LANE2 ", "LANE2", "lane2"},
+		{"surrounding whitespace", "\tLANE2\n", "LANE2", "lane2"},
+		{"inner whitespace collapsed", "Hub  Branch\t1", "Hub Branch 1", "hub branch 1"},
+		{"case only differs", "lane2", "lane2", "lane2"},
+		{"already clean", "SAMSAT", "SAMSAT", "samsat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeRouterName(tt.input); got != tt.wantNormalized {
+				t.Errorf("NormalizeRouterName(%q) = %q, want %q", tt.input, got, tt.wantNormalized)
+			}
+			if got := RouterNameKey(tt.input); got != tt.wantKey {
+				t.Errorf("RouterNameKey(%q) = %q, want %q", tt.input, got, tt.wantKey)
+			}
+		})
+	}
+
+	// Near-duplicates share a key, so uniqueness checks catch them
+	for _, variant := range []string{"LANE2", "lane2", " Lane2 ", "LANE2\t"} {
+		if RouterNameKey(variant) != RouterNameKey("LANE2") {
+			t.Errorf("RouterNameKey(%q) differs from LANE2", variant)
+		}
+	}
+}
+
+func TestValidateRouterName(t *testing.T) {
+	long := make([]byte, MaxRouterNameLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"plain", "SAMSAT", false},
+		{"allowed punctuation", "Hub-1 (core)/A_b.c", false},
+		{"empty", "", true},
+		{"too long", string(long), true},
+		{"leading dash", "-LANE2", true},
+		{"double quote", `LANE"2`, true},
+		{"semicolon", "LANE2;drop", true},
+		{"equals sign", "LANE2=x", true},
+		{"comma", "LANE2,LANE3", true},
+		{"wildcard", "*", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRouterName(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRouterName(%q) = %v, want error %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
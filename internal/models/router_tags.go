@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxRouterTags caps the tags a router may carry
+const MaxRouterTags = 20
+
+// MaxRouterTagLength bounds a single tag
+const MaxRouterTagLength = 50
+
+// routerTagPattern allows lowercase letters, digits, spaces and - _ . /
+var routerTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9 _./-]*$`)
+
+// NormalizeRouterTag trims and lowercases a tag and collapses internal
+// whitespace, so "Jakarta " and "JAKARTA" are the same tag
+func NormalizeRouterTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeRouterTags normalizes each tag and drops duplicates, keeping order
+func NormalizeRouterTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeRouterTag(tag)
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ValidateRouterTags checks a router's normalized tags
+func ValidateRouterTags(tags []string) error {
+	if len(tags) > MaxRouterTags {
+		return fmt.Errorf("at most %d tags are allowed", MaxRouterTags)
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("tags must not be empty")
+		}
+		if len(tag) > MaxRouterTagLength {
+			return fmt.Errorf("tag %q must be at most %d characters", tag, MaxRouterTagLength)
+		}
+		if !routerTagPattern.MatchString(tag) {
+			return fmt.Errorf("tag %q may only contain letters, digits, spaces and - _ . /, starting with a letter or digit", tag)
+		}
+	}
+	return nil
+}
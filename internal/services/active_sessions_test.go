@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestRevokeSessionRemovesItFromActiveSessions(t *testing.T) {
+	db := migratedTestDB(t)
+	as := NewAuthServiceDB(quietLogger(), db, JWTKeyConfig{AllowEphemeral: true})
+
+	admin, err := as.userRepo.GetByUsername(context.Background(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := as.jwtService.GenerateTokenPair(admin, "10.0.0.1", "curl/8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := as.jwtService.GenerateTokenPair(admin, "10.0.0.2", "curl/8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := func() map[string]models.ActiveSession {
+		t.Helper()
+		sessions, total, err := as.ListActiveSessions(models.ActiveSessionFilter{Username: "ADM"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != len(sessions) {
+			t.Fatalf("total = %d with %d sessions and no limit", total, len(sessions))
+		}
+		bySession := make(map[string]models.ActiveSession, len(sessions))
+		for _, session := range sessions {
+			bySession[session.SessionID] = session
+		}
+		return bySession
+	}
+
+	before := listed()
+	session, ok := before[revoked.SessionID]
+	if !ok || session.Username != "admin" || session.IPAddress != "10.0.0.2" {
+		t.Fatalf("sessions = %+v, want %s for admin from 10.0.0.2", before, revoked.SessionID)
+	}
+	if _, err := as.ValidateJWTToken(revoked.AccessToken); err != nil {
+		t.Fatalf("access token before revocation: %v", err)
+	}
+
+	if err := as.RevokeSession(revoked.SessionID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	after := listed()
+	if _, ok := after[revoked.SessionID]; ok {
+		t.Fatal("revoked session still listed")
+	}
+	if _, ok := after[kept.SessionID]; !ok {
+		t.Fatal("the other session disappeared with the revoked one")
+	}
+	if _, err := as.ValidateJWTToken(revoked.AccessToken); err == nil {
+		t.Fatal("access token of the revoked session still validates")
+	}
+
+	// A second revocation finds nothing to revoke
+	if err := as.RevokeSession(revoked.SessionID); !errors.Is(err, ErrActiveSessionNotFound) {
+		t.Fatalf("revoking twice: err = %v, want ErrActiveSessionNotFound", err)
+	}
+}
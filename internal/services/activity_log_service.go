@@ -3,30 +3,95 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"nat-management-app/internal/database"
 	"nat-management-app/internal/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrNoNATRuleHistory is returned when a router has no NAT update with a recorded before-state
+var ErrNoNATRuleHistory = errors.New("no previous NAT rule state recorded")
+
 // ActivityLogService handles activity log operations
 type ActivityLogService struct {
 	db     *database.DB
 	logger *logrus.Logger
+
+	// Scheduled retention cleanup, see StartRetention
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 // NewActivityLogService creates a new activity log service
 func NewActivityLogService(db *database.DB, logger *logrus.Logger) *ActivityLogService {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ActivityLogService{
 		db:     db,
 		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// StartRetention deletes logs older than retentionDays now and then every
+// interval in the background. retentionDays <= 0 keeps logs forever.
+func (s *ActivityLogService) StartRetention(retentionDays int, interval time.Duration) {
+	if retentionDays <= 0 || interval <= 0 {
+		s.logger.Info("Activity log retention disabled, logs are kept until cleaned up manually")
+		return
+	}
+
+	s.wg.Add(1)
+	go s.retentionLoop(retentionDays, interval)
+	s.logger.Infof("✅ Activity log retention started (keep %d days, every %v)", retentionDays, interval)
+}
+
+// Stop stops the retention cleanup and waits for a running cleanup to exit
+func (s *ActivityLogService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *ActivityLogService) retentionLoop(retentionDays int, interval time.Duration) {
+	defer s.wg.Done()
+
+	s.runRetention(retentionDays)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.runRetention(retentionDays)
+		}
 	}
 }
 
+func (s *ActivityLogService) runRetention(retentionDays int) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Minute)
+	defer cancel()
+
+	deleted, err := s.deleteLogsOlderThan(ctx, retentionDays)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.logger.Warnf("⚠️ Scheduled activity log cleanup failed: %v", err)
+		}
+		return
+	}
+	s.logger.Infof("🧹 Scheduled activity log cleanup deleted %d logs older than %d days", deleted, retentionDays)
+}
+
 // CreateLog creates a new activity log entry
 func (s *ActivityLogService) CreateLog(log *models.ActivityLogCreate) error {
 	// Set default status if not provided
@@ -37,6 +102,15 @@ func (s *ActivityLogService) CreateLog(log *models.ActivityLogCreate) error {
 	// Marshal metadata to JSON
 	var metadataJSON []byte
 	var err error
+	if log.RequestID != "" {
+		// Correlates the entry with the request's log lines
+		metadata := make(map[string]interface{}, len(log.Metadata)+1)
+		for key, value := range log.Metadata {
+			metadata[key] = value
+		}
+		metadata["request_id"] = log.RequestID
+		log.Metadata = metadata
+	}
 	if log.Metadata != nil {
 		metadataJSON, err = json.Marshal(log.Metadata)
 		if err != nil {
@@ -96,83 +170,141 @@ func (s *ActivityLogService) CreateLog(log *models.ActivityLogCreate) error {
 	return nil
 }
 
-// GetLogs retrieves activity logs with optional filters
-func (s *ActivityLogService) GetLogs(filter *models.ActivityLogFilter) ([]models.ActivityLog, int, error) {
-	// Build query with filters
-	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString(`
-		SELECT
+// activityLogColumns lists the columns scanned by scanActivityLog
+const activityLogColumns = `
 			id, user_id, username, user_role, action_type, resource_type,
 			resource_id, description, ip_address, user_agent,
-			status, error_message, metadata, created_at
-		FROM activity_logs
-		WHERE 1=1
-	`)
-
-	countQueryBuilder := strings.Builder{}
-	countQueryBuilder.WriteString("SELECT COUNT(*) FROM activity_logs WHERE 1=1")
+			status, error_message, metadata, created_at`
 
+// activityLogFilterClause returns the " AND ..." conditions for filter and
+// their arguments, numbered from $1
+func activityLogFilterClause(filter *models.ActivityLogFilter) (string, []interface{}) {
+	clause := strings.Builder{}
 	args := []interface{}{}
 	argCount := 1
 
 	// Add filters
 	if filter.UserID != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND user_id = $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND user_id = $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND user_id = $%d", argCount))
 		args = append(args, *filter.UserID)
 		argCount++
 	}
 
 	if filter.Username != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND username ILIKE $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND username ILIKE $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND username ILIKE $%d", argCount))
 		args = append(args, "%"+filter.Username+"%")
 		argCount++
 	}
 
 	if filter.ActionType != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND action_type = $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND action_type = $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND action_type = $%d", argCount))
 		args = append(args, filter.ActionType)
 		argCount++
 	}
 
 	if filter.ResourceType != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND resource_type = $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND resource_type = $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND resource_type = $%d", argCount))
 		args = append(args, filter.ResourceType)
 		argCount++
 	}
 
 	if filter.Status != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND status = $%d", argCount))
 		args = append(args, filter.Status)
 		argCount++
 	}
 
+	if len(filter.ActionTypes) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND action_type = ANY($%d)", argCount))
+		args = append(args, filter.ActionTypes)
+		argCount++
+	}
+
+	if len(filter.ResourceTypes) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND resource_type = ANY($%d)", argCount))
+		args = append(args, filter.ResourceTypes)
+		argCount++
+	}
+
+	if len(filter.Statuses) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND status = ANY($%d)", argCount))
+		args = append(args, filter.Statuses)
+		argCount++
+	}
+
+	if filter.IPAddress != "" {
+		clause.WriteString(fmt.Sprintf(" AND ip_address = $%d", argCount))
+		args = append(args, filter.IPAddress)
+		argCount++
+	}
+
 	if !filter.StartDate.IsZero() {
-		queryBuilder.WriteString(fmt.Sprintf(" AND created_at >= $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND created_at >= $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND created_at >= $%d", argCount))
 		args = append(args, filter.StartDate)
 		argCount++
 	}
 
 	if !filter.EndDate.IsZero() {
-		queryBuilder.WriteString(fmt.Sprintf(" AND created_at <= $%d", argCount))
-		countQueryBuilder.WriteString(fmt.Sprintf(" AND created_at <= $%d", argCount))
+		clause.WriteString(fmt.Sprintf(" AND created_at <= $%d", argCount))
 		args = append(args, filter.EndDate)
-		argCount++
 	}
 
+	return clause.String(), args
+}
+
+// scanActivityLog reads one row selected with activityLogColumns
+func (s *ActivityLogService) scanActivityLog(rows pgx.Rows) (models.ActivityLog, error) {
+	var log models.ActivityLog
+	var metadataJSON []byte
+
+	err := rows.Scan(
+		&log.ID,
+		&log.UserID,
+		&log.Username,
+		&log.UserRole,
+		&log.ActionType,
+		&log.ResourceType,
+		&log.ResourceID,
+		&log.Description,
+		&log.IPAddress,
+		&log.UserAgent,
+		&log.Status,
+		&log.ErrorMessage,
+		&metadataJSON,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return log, err
+	}
+
+	// Unmarshal metadata
+	if metadataJSON != nil {
+		err = json.Unmarshal(metadataJSON, &log.Metadata)
+		if err != nil {
+			s.logger.Warnf("Failed to unmarshal metadata for log %d: %v", log.ID, err)
+		}
+	}
+
+	return log, nil
+}
+
+// GetLogs retrieves activity logs with optional filters
+func (s *ActivityLogService) GetLogs(filter *models.ActivityLogFilter) ([]models.ActivityLog, int, error) {
+	conditions, args := activityLogFilterClause(filter)
+	argCount := len(args) + 1
+
 	// Get total count
 	var totalCount int
-	err := s.db.Pool.QueryRow(context.Background(), countQueryBuilder.String(), args...).Scan(&totalCount)
+	err := s.db.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM activity_logs WHERE 1=1"+conditions, args...).Scan(&totalCount)
 	if err != nil {
 		s.logger.Errorf("Failed to count activity logs: %v", err)
 		return nil, 0, err
 	}
 
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString("SELECT" + activityLogColumns + " FROM activity_logs WHERE 1=1")
+	queryBuilder.WriteString(conditions)
+
 	// Add ordering
 	queryBuilder.WriteString(" ORDER BY created_at DESC")
 
@@ -186,7 +318,6 @@ func (s *ActivityLogService) GetLogs(filter *models.ActivityLogFilter) ([]models
 	if filter.Offset > 0 {
 		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", argCount))
 		args = append(args, filter.Offset)
-		argCount++
 	}
 
 	// Execute query
@@ -199,45 +330,46 @@ func (s *ActivityLogService) GetLogs(filter *models.ActivityLogFilter) ([]models
 
 	logs := []models.ActivityLog{}
 	for rows.Next() {
-		var log models.ActivityLog
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&log.ID,
-			&log.UserID,
-			&log.Username,
-			&log.UserRole,
-			&log.ActionType,
-			&log.ResourceType,
-			&log.ResourceID,
-			&log.Description,
-			&log.IPAddress,
-			&log.UserAgent,
-			&log.Status,
-			&log.ErrorMessage,
-			&metadataJSON,
-			&log.CreatedAt,
-		)
-
+		log, err := s.scanActivityLog(rows)
 		if err != nil {
 			s.logger.Errorf("Failed to scan activity log row: %v", err)
 			continue
 		}
-
-		// Unmarshal metadata
-		if metadataJSON != nil {
-			err = json.Unmarshal(metadataJSON, &log.Metadata)
-			if err != nil {
-				s.logger.Warnf("Failed to unmarshal metadata for log %d: %v", log.ID, err)
-			}
-		}
-
 		logs = append(logs, log)
 	}
 
 	return logs, totalCount, nil
 }
 
+// StreamLogs calls fn for every log matching filter, newest first, ignoring
+// Limit and Offset. Rows are read as they arrive instead of being collected,
+// so large exports don't have to fit in memory. An error from fn stops the scan.
+func (s *ActivityLogService) StreamLogs(ctx context.Context, filter *models.ActivityLogFilter, fn func(*models.ActivityLog) error) error {
+	conditions, args := activityLogFilterClause(filter)
+	query := "SELECT" + activityLogColumns + " FROM activity_logs WHERE 1=1" + conditions + " ORDER BY created_at DESC"
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query activity logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := s.scanActivityLog(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan activity log row: %w", err)
+		}
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating activity logs: %w", err)
+	}
+	return nil
+}
+
 // GetLogByID retrieves a single activity log by ID
 func (s *ActivityLogService) GetLogByID(id int) (*models.ActivityLog, error) {
 	query := `
@@ -299,6 +431,50 @@ func (s *ActivityLogService) GetLogByID(id int) (*models.ActivityLog, error) {
 	return &log, nil
 }
 
+// GetLatestNATRuleUpdate returns the most recent successful NAT update of
+// routerName that captured the rule's before-state
+func (s *ActivityLogService) GetLatestNATRuleUpdate(routerName string) (*models.ActivityLog, error) {
+	query := `
+		SELECT id, user_id, username, action_type, resource_type, resource_id,
+		       description, status, metadata, created_at
+		FROM activity_logs
+		WHERE action_type = $1 AND resource_type = $2 AND resource_id = $3
+		  AND status = $4 AND metadata->'before' IS NOT NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	var log models.ActivityLog
+	var metadataJSON []byte
+
+	err := s.db.Pool.QueryRow(context.Background(), query,
+		models.ActionNATUpdate, models.ResourceNATRule, routerName, models.StatusSuccess,
+	).Scan(
+		&log.ID,
+		&log.UserID,
+		&log.Username,
+		&log.ActionType,
+		&log.ResourceType,
+		&log.ResourceID,
+		&log.Description,
+		&log.Status,
+		&metadataJSON,
+		&log.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNoNATRuleHistory
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest NAT update for %s: %w", routerName, err)
+	}
+
+	if err := json.Unmarshal(metadataJSON, &log.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata for log %d: %w", log.ID, err)
+	}
+
+	return &log, nil
+}
+
 // GetLogStats retrieves statistics about activity logs
 func (s *ActivityLogService) GetLogStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -359,18 +535,25 @@ func (s *ActivityLogService) GetLogStats() (map[string]interface{}, error) {
 
 // DeleteOldLogs deletes logs older than specified days (for maintenance)
 func (s *ActivityLogService) DeleteOldLogs(daysToKeep int) (int, error) {
+	rowsAffected, err := s.deleteLogsOlderThan(context.Background(), daysToKeep)
+	if err != nil {
+		s.logger.Errorf("Failed to delete old logs: %v", err)
+		return 0, err
+	}
+
+	s.logger.Infof("Deleted %d old activity logs (older than %d days)", rowsAffected, daysToKeep)
+	return rowsAffected, nil
+}
+
+func (s *ActivityLogService) deleteLogsOlderThan(ctx context.Context, daysToKeep int) (int, error) {
 	query := `
 		DELETE FROM activity_logs
 		WHERE created_at < NOW() - INTERVAL '1 day' * $1
 	`
 
-	result, err := s.db.Pool.Exec(context.Background(), query, daysToKeep)
+	result, err := s.db.Pool.Exec(ctx, query, daysToKeep)
 	if err != nil {
-		s.logger.Errorf("Failed to delete old logs: %v", err)
 		return 0, err
 	}
-
-	rowsAffected := result.RowsAffected()
-	s.logger.Infof("Deleted %d old activity logs (older than %d days)", rowsAffected, daysToKeep)
-	return int(rowsAffected), nil
+	return int(result.RowsAffected()), nil
 }
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAuditRunInProgress is returned when an audit is triggered while another one is running
+var ErrAuditRunInProgress = errors.New("a connectivity audit is already running")
+
+// ErrAuditScheduleNotFound is returned for an unknown schedule ID
+var ErrAuditScheduleNotFound = errors.New("audit schedule not found")
+
+// auditRunTimeout bounds a single audit run
+const auditRunTimeout = 2 * time.Hour
+
+// auditSchedulerTick is how often the scheduler checks for due schedules
+const auditSchedulerTick = 20 * time.Second
+
+// AuditService runs connectivity audits over online PPPoE customers, on cron
+// schedules stored in audit_schedules or on demand, and persists per-customer
+// results. Only one run executes at a time; overlapping triggers are skipped.
+type AuditService struct {
+	db         *database.DB
+	natService *NATService
+	logger     *logrus.Logger
+
+	running atomic.Bool
+	cancel  context.CancelFunc
+	ctx     context.Context
+	wg      sync.WaitGroup
+}
+
+// NewAuditService creates a new connectivity audit service
+func NewAuditService(db *database.DB, natService *NATService, logger *logrus.Logger) *AuditService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AuditService{
+		db:         db,
+		natService: natService,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start begins checking schedules in the background
+func (s *AuditService) Start() {
+	s.wg.Add(1)
+	go s.schedulerLoop()
+	s.logger.Info("✅ Connectivity audit scheduler started")
+}
+
+// Stop stops the scheduler, cancels a running audit and waits for it to exit
+func (s *AuditService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("Connectivity audit scheduler stopped")
+}
+
+// schedulerLoop fires due schedules once per minute
+func (s *AuditService) schedulerLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(auditSchedulerTick)
+	defer ticker.Stop()
+
+	lastMinute := time.Now().Truncate(time.Minute)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			minute := now.Truncate(time.Minute)
+			if !minute.After(lastMinute) {
+				continue
+			}
+			lastMinute = minute
+			s.runDueSchedules(minute)
+		}
+	}
+}
+
+// runDueSchedules triggers every enabled schedule whose cron matches minute
+func (s *AuditService) runDueSchedules(minute time.Time) {
+	schedules, err := s.ListSchedules()
+	if err != nil {
+		s.logger.Warnf("⚠️ Failed to load audit schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		cron, err := parseCronSchedule(schedule.CronExpr)
+		if err != nil {
+			s.logger.Warnf("⚠️ Audit schedule %s has an invalid cron expression %q: %v", schedule.Name, schedule.CronExpr, err)
+			continue
+		}
+		if !cron.Matches(minute) {
+			continue
+		}
+
+		scheduleID := schedule.ID
+		if _, err := s.TriggerAudit(&scheduleID, schedule.Routers, "schedule:"+schedule.Name); err != nil {
+			s.logger.Warnf("⚠️ Skipping scheduled audit %s: %v", schedule.Name, err)
+		}
+	}
+}
+
+// TriggerAudit starts an audit run in the background and returns the created
+// run (status "running"). Returns ErrAuditRunInProgress if one is already running.
+func (s *AuditService) TriggerAudit(scheduleID *int, routers []string, triggeredBy string) (*models.AuditRun, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAuditRunInProgress
+	}
+
+	if routers == nil {
+		routers = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+
+	run := &models.AuditRun{
+		ScheduleID:  scheduleID,
+		TriggeredBy: triggeredBy,
+		Status:      models.AuditRunRunning,
+		Routers:     routers,
+	}
+	err := s.db.Pool.QueryRow(ctx, `
+		INSERT INTO audit_runs (schedule_id, triggered_by, status, routers)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, started_at
+	`, scheduleID, triggeredBy, run.Status, routers).Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		s.running.Store(false)
+		return nil, fmt.Errorf("failed to create audit run: %w", err)
+	}
+
+	if scheduleID != nil {
+		if _, err := s.db.Pool.Exec(ctx, `UPDATE audit_schedules SET last_run_at = $2 WHERE id = $1`, *scheduleID, run.StartedAt); err != nil {
+			s.logger.Warnf("⚠️ Failed to update last_run_at for audit schedule %d: %v", *scheduleID, err)
+		}
+	}
+
+	s.logger.Infof("🔍 Connectivity audit run %d started by %s", run.ID, triggeredBy)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Store(false)
+		s.executeRun(run.ID, routers)
+	}()
+
+	return run, nil
+}
+
+// executeRun performs the audit and stores its results
+func (s *AuditService) executeRun(runID int64, routers []string) {
+	ctx, cancel := context.WithTimeout(s.ctx, auditRunTimeout)
+	defer cancel()
+
+	results := s.natService.AuditConnectivity(ctx, routers)
+
+	reachable := 0
+	for _, result := range results {
+		if result.Reachable {
+			reachable++
+		}
+	}
+
+	status := models.AuditRunCompleted
+	runErr := ""
+	if err := s.saveResults(ctx, runID, results); err != nil {
+		status = models.AuditRunFailed
+		runErr = err.Error()
+		s.logger.Errorf("❌ Failed to save results for audit run %d: %v", runID, err)
+	} else if ctx.Err() != nil {
+		status = models.AuditRunFailed
+		runErr = "audit interrupted: " + ctx.Err().Error()
+	}
+
+	// Record the outcome even if the run's own context is done
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer finishCancel()
+
+	_, err := s.db.Pool.Exec(finishCtx, `
+		UPDATE audit_runs
+		SET status = $2, total = $3, reachable = $4, error = NULLIF($5, ''), finished_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, runID, status, len(results), reachable, runErr)
+	if err != nil {
+		s.logger.Errorf("❌ Failed to finish audit run %d: %v", runID, err)
+		return
+	}
+
+	s.logger.Infof("✅ Connectivity audit run %d %s: %d/%d reachable", runID, status, reachable, len(results))
+}
+
+// saveResults bulk-inserts a run's results
+func (s *AuditService) saveResults(ctx context.Context, runID int64, results []models.AuditResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []interface{}{
+			runID, r.Router, r.Username, r.IPAddress, r.Reachable, r.Port, r.ResponseTimeMs, nullIfEmpty(r.Error), r.CheckedAt,
+		})
+	}
+
+	_, err := s.db.Pool.CopyFrom(ctx,
+		pgx.Identifier{"audit_results"},
+		[]string{"run_id", "router", "pppoe_username", "ip_address", "reachable", "port", "response_time_ms", "error", "checked_at"},
+		pgx.CopyFromRows(rows),
+	)
+	return err
+}
+
+// nullIfEmpty maps "" to NULL for optional text columns
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// GetRunsForDate returns the runs started on date (server time), newest first, with their results
+func (s *AuditService) GetRunsForDate(date time.Time) ([]models.AuditRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, schedule_id, triggered_by, status, routers, total, reachable,
+		       COALESCE(error, ''), started_at, finished_at
+		FROM audit_runs
+		WHERE started_at >= $1 AND started_at < $2
+		ORDER BY started_at DESC
+	`, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.AuditRun{}
+	for rows.Next() {
+		var run models.AuditRun
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &run.TriggeredBy, &run.Status, &run.Routers,
+			&run.Total, &run.Reachable, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit runs: %w", err)
+	}
+
+	for i := range runs {
+		results, err := s.getResults(ctx, runs[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		runs[i].Results = results
+	}
+
+	return runs, nil
+}
+
+// getResults returns one run's results ordered by router and username
+func (s *AuditService) getResults(ctx context.Context, runID int64) ([]models.AuditResult, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT router, COALESCE(pppoe_username, ''), COALESCE(ip_address, ''), reachable,
+		       COALESCE(port, ''), COALESCE(response_time_ms, 0), COALESCE(error, ''), checked_at
+		FROM audit_results
+		WHERE run_id = $1
+		ORDER BY router ASC, pppoe_username ASC
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.AuditResult{}
+	for rows.Next() {
+		var r models.AuditResult
+		if err := rows.Scan(&r.Router, &r.Username, &r.IPAddress, &r.Reachable, &r.Port, &r.ResponseTimeMs, &r.Error, &r.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit result: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// auditScheduleColumns is the column list shared by schedule SELECTs, in scanAuditSchedule order
+const auditScheduleColumns = `id, name, cron_expr, routers, enabled, last_run_at, COALESCE(created_by, ''), created_at, updated_at`
+
+// scanAuditSchedule scans a row selected with auditScheduleColumns
+func scanAuditSchedule(row pgx.Row) (*models.AuditSchedule, error) {
+	schedule := &models.AuditSchedule{}
+	err := row.Scan(&schedule.ID, &schedule.Name, &schedule.CronExpr, &schedule.Routers, &schedule.Enabled,
+		&schedule.LastRunAt, &schedule.CreatedBy, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns all audit schedules ordered by name
+func (s *AuditService) ListSchedules() ([]models.AuditSchedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+auditScheduleColumns+` FROM audit_schedules ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.AuditSchedule{}
+	for rows.Next() {
+		schedule, err := scanAuditSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns one audit schedule
+func (s *AuditService) GetSchedule(id int) (*models.AuditSchedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schedule, err := scanAuditSchedule(s.db.Pool.QueryRow(ctx, `SELECT `+auditScheduleColumns+` FROM audit_schedules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrAuditScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// validateScheduleRequest normalizes req and checks its cron expression
+func validateScheduleRequest(req *models.AuditScheduleRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.CronExpr = strings.TrimSpace(req.CronExpr)
+	if req.Name == "" {
+		return fmt.Errorf("schedule name is required")
+	}
+	if _, err := parseCronSchedule(req.CronExpr); err != nil {
+		return fmt.Errorf("invalid cron expression: %w", err)
+	}
+	if req.Routers == nil {
+		req.Routers = []string{}
+	}
+	return nil
+}
+
+// CreateSchedule stores a new audit schedule
+func (s *AuditService) CreateSchedule(req *models.AuditScheduleRequest, createdBy string) (*models.AuditSchedule, error) {
+	if err := validateScheduleRequest(req); err != nil {
+		return nil, err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schedule, err := scanAuditSchedule(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO audit_schedules (name, cron_expr, routers, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+auditScheduleColumns,
+		req.Name, req.CronExpr, req.Routers, enabled, createdBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit schedule: %w", err)
+	}
+
+	s.logger.Infof("📅 Audit schedule created: %s (%s) by %s", schedule.Name, schedule.CronExpr, createdBy)
+	return schedule, nil
+}
+
+// UpdateSchedule replaces an audit schedule's settings
+func (s *AuditService) UpdateSchedule(id int, req *models.AuditScheduleRequest) (*models.AuditSchedule, error) {
+	if err := validateScheduleRequest(req); err != nil {
+		return nil, err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schedule, err := scanAuditSchedule(s.db.Pool.QueryRow(ctx, `
+		UPDATE audit_schedules
+		SET name = $2, cron_expr = $3, routers = $4, enabled = $5
+		WHERE id = $1
+		RETURNING `+auditScheduleColumns,
+		id, req.Name, req.CronExpr, req.Routers, enabled))
+	if err == pgx.ErrNoRows {
+		return nil, ErrAuditScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update audit schedule: %w", err)
+	}
+
+	s.logger.Infof("📅 Audit schedule updated: %s (%s, enabled: %t)", schedule.Name, schedule.CronExpr, schedule.Enabled)
+	return schedule, nil
+}
+
+// DeleteSchedule removes an audit schedule; its past runs are kept
+func (s *AuditService) DeleteSchedule(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.db.Pool.Exec(ctx, `DELETE FROM audit_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete audit schedule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAuditScheduleNotFound
+	}
+
+	s.logger.Infof("🗑️ Audit schedule deleted: %d", id)
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestTriggerAuditSkipsWhileRunning(t *testing.T) {
+	// The overlap check comes before any database work
+	s := NewAuditService(nil, nil, quietLogger())
+	s.running.Store(true)
+
+	if _, err := s.TriggerAudit(nil, nil, "admin"); !errors.Is(err, ErrAuditRunInProgress) {
+		t.Fatalf("trigger during a run: err = %v, want ErrAuditRunInProgress", err)
+	}
+}
+
+func TestTriggerAuditPersistsResults(t *testing.T) {
+	db := migratedTestDB(t)
+
+	release := make(chan struct{})
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		<-release
+		return [][]string{
+			reSentence("name", "alice", "address", "127.0.0.1", "uptime", "1h"),
+			reSentence("name", "bob", "uptime", "5m"),
+			{"!done"},
+		}
+	})
+	ns := newTestNATService(t, fr)
+	s := NewAuditService(db, ns, quietLogger())
+
+	run, err := s.TriggerAudit(nil, []string{"FAKE"}, "admin")
+	if err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	if run.Status != models.AuditRunRunning {
+		t.Fatalf("new run status = %q, want %q", run.Status, models.AuditRunRunning)
+	}
+
+	// The first run is waiting on the router, so a second trigger overlaps it
+	if _, err := s.TriggerAudit(nil, []string{"FAKE"}, "schedule:nightly"); !errors.Is(err, ErrAuditRunInProgress) {
+		t.Fatalf("overlapping trigger: err = %v, want ErrAuditRunInProgress", err)
+	}
+
+	close(release)
+	s.wg.Wait()
+	t.Cleanup(s.Stop)
+
+	runs, err := s.GetRunsForDate(run.StartedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d runs, want only the first one", len(runs))
+	}
+	got := runs[0]
+	if got.ID != run.ID || got.Status != models.AuditRunCompleted || got.Total != 2 || got.FinishedAt == nil {
+		t.Fatalf("run = %+v, want run %d completed with 2 results", got, run.ID)
+	}
+
+	results := make(map[string]models.AuditResult)
+	for _, result := range got.Results {
+		results[result.Username] = result
+	}
+	if r, ok := results["alice"]; !ok || r.Router != "FAKE" || r.IPAddress != "127.0.0.1" {
+		t.Errorf("alice result = %+v, want FAKE 127.0.0.1", r)
+	}
+	if r, ok := results["bob"]; !ok || r.Reachable {
+		t.Errorf("bob result = %+v, want unreachable without an address", r)
+	}
+
+	// The run is over, so the next trigger is accepted
+	if _, err := s.TriggerAudit(nil, []string{"FAKE"}, "admin"); err != nil {
+		t.Fatalf("trigger after the run: %v", err)
+	}
+}
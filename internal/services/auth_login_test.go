@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckCredentialsTimingDoesNotRevealUsers(t *testing.T) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("nat-management-dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := &AuthServiceDB{logger: quietLogger(), dummyHash: dummyHash}
+
+	attempts := map[string]func() error{
+		"unknown user": func() error {
+			_, err := as.checkCredentials(nil, errors.New("user not found"), "ghost", "wrong-password", "test")
+			return err
+		},
+		"wrong password": func() error {
+			user := &models.User{Username: "head1", Password: string(hash), IsActive: true}
+			_, err := as.checkCredentials(user, nil, "head1", "wrong-password", "test")
+			return err
+		},
+		"inactive user": func() error {
+			user := &models.User{Username: "head2", Password: string(hash)}
+			_, err := as.checkCredentials(user, nil, "head2", "correct-password", "test")
+			return err
+		},
+	}
+
+	medians := make(map[string]time.Duration)
+	var message string
+	for name, attempt := range attempts {
+		var durations []time.Duration
+		for i := 0; i < 5; i++ {
+			start := time.Now()
+			err := attempt()
+			durations = append(durations, time.Since(start))
+			if err == nil {
+				t.Fatalf("%s: login accepted", name)
+			}
+			if message == "" {
+				message = err.Error()
+			} else if err.Error() != message {
+				t.Fatalf("%s: error %q differs from %q", name, err.Error(), message)
+			}
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		medians[name] = durations[len(durations)/2]
+	}
+
+	// Every failure costs one bcrypt compare; without the dummy compare an
+	// unknown user fails orders of magnitude faster
+	reference := medians["wrong password"]
+	for name, median := range medians {
+		if median < reference/2 || median > reference*2 {
+			t.Errorf("%s took %v, wrong password took %v: timing reveals the difference", name, median, reference)
+		}
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,22 +27,30 @@ type AuthServiceDB struct {
 	sessions   map[string]*models.UserSession // JWT-based, sessions kept in memory temporarily
 	jwtService *JWTService
 	db         *database.DB
+	dummyHash  []byte // compared against when the user doesn't exist, keeps login timing uniform
 }
 
 // NewAuthServiceDB creates a new database-backed AuthService instance
-func NewAuthServiceDB(logger *logrus.Logger, db *database.DB) *AuthServiceDB {
+func NewAuthServiceDB(logger *logrus.Logger, db *database.DB, jwtKey JWTKeyConfig) *AuthServiceDB {
 	// Initialize JWT service
-	jwtService, err := NewJWTService(logger)
+	jwtService, err := NewJWTService(logger, db, jwtKey)
 	if err != nil {
 		logger.Fatalf("Failed to initialize JWT service: %v", err)
 	}
 
+	// Dummy hash uses the default cost so a failed lookup costs the same as a real compare
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("nat-management-dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Fatalf("Failed to initialize dummy password hash: %v", err)
+	}
+
 	service := &AuthServiceDB{
 		logger:     logger,
 		userRepo:   database.NewUserRepository(db),
 		sessions:   make(map[string]*models.UserSession),
 		jwtService: jwtService,
 		db:         db,
+		dummyHash:  dummyHash,
 	}
 
 	// Start session cleanup for backward compatibility
@@ -57,32 +68,13 @@ func (as *AuthServiceDB) Login(username, password, ipAddress, userAgent string)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Find user by username from database
-	user, err := as.userRepo.GetByUsername(ctx, username)
-	if err != nil {
-		as.logger.Warnf("🔒 Login failed: user not found: %s", username)
-		return &models.AuthResponse{
-			Status:  "error",
-			Message: "Username atau password salah",
-		}, errors.New("invalid credentials")
-	}
-
-	if !user.IsActive {
-		as.logger.Warnf("🔒 Login failed: user inactive: %s", username)
-		return &models.AuthResponse{
-			Status:  "error",
-			Message: "User tidak aktif",
-		}, errors.New("user inactive")
-	}
-
-	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	// Verify credentials (uniform response and timing on failure)
+	user, err := as.verifyCredentials(ctx, username, password, "🔒 Login failed")
 	if err != nil {
-		as.logger.Warnf("🔒 Login failed: invalid password for: %s", username)
 		return &models.AuthResponse{
 			Status:  "error",
 			Message: "Username atau password salah",
-		}, errors.New("invalid credentials")
+		}, err
 	}
 
 	// Create session
@@ -155,39 +147,32 @@ func (as *AuthServiceDB) Login(username, password, ipAddress, userAgent string)
 }
 
 // LoginWithJWT authenticates user dan generate JWT token pair
-func (as *AuthServiceDB) LoginWithJWT(username, password, ipAddress, userAgent string) (*models.AuthResponse, error) {
+func (as *AuthServiceDB) LoginWithJWT(username, password, totpCode, ipAddress, userAgent string) (*models.AuthResponse, error) {
 	as.mutex.Lock()
 	defer as.mutex.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Find user by username from database
-	user, err := as.userRepo.GetByUsername(ctx, username)
+	// Verify credentials (uniform response and timing on failure)
+	user, err := as.verifyCredentials(ctx, username, password, "🔒 JWT Login failed")
 	if err != nil {
-		as.logger.Warnf("🔒 JWT Login failed: user not found: %s", username)
 		return &models.AuthResponse{
 			Status:  "error",
 			Message: "Username atau password salah",
-		}, errors.New("invalid credentials")
-	}
-
-	if !user.IsActive {
-		as.logger.Warnf("🔒 JWT Login failed: user inactive: %s", username)
-		return &models.AuthResponse{
-			Status:  "error",
-			Message: "User tidak aktif",
-		}, errors.New("user inactive")
+		}, err
 	}
 
-	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		as.logger.Warnf("🔒 JWT Login failed: invalid password for: %s", username)
+	// Second factor, only for users who completed TOTP enrollment
+	if err := as.checkTOTP(ctx, user, totpCode); err != nil {
+		message := "Kode autentikasi dua langkah salah"
+		if errors.Is(err, ErrTOTPRequired) {
+			message = "Kode autentikasi dua langkah diperlukan"
+		}
 		return &models.AuthResponse{
 			Status:  "error",
-			Message: "Username atau password salah",
-		}, errors.New("invalid credentials")
+			Message: message,
+		}, err
 	}
 
 	// Generate JWT token pair
@@ -251,6 +236,187 @@ func (as *AuthServiceDB) LoginWithJWT(username, password, ipAddress, userAgent s
 	}, nil
 }
 
+// verifyCredentials looks up the user and checks the password. Every failure path
+// runs exactly one bcrypt compare and returns the same error, so neither the
+// response nor its timing reveals whether the username exists or is active.
+func (as *AuthServiceDB) verifyCredentials(ctx context.Context, username, password, logPrefix string) (*models.User, error) {
+	user, err := as.userRepo.GetByUsername(ctx, username)
+	return as.checkCredentials(user, err, username, password, logPrefix)
+}
+
+// checkCredentials is verifyCredentials after the lookup; lookupErr is the
+// error looking up username returned, if any
+func (as *AuthServiceDB) checkCredentials(user *models.User, lookupErr error, username, password, logPrefix string) (*models.User, error) {
+	if lookupErr != nil {
+		// Burn the same bcrypt cost as a real compare
+		_ = bcrypt.CompareHashAndPassword(as.dummyHash, []byte(password))
+		as.logger.Warnf("%s: user not found: %s", logPrefix, username)
+		return nil, errors.New("invalid credentials")
+	}
+
+	// Verify password before checking active status so inactive accounts take the same path
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		as.logger.Warnf("%s: invalid password for: %s", logPrefix, username)
+		return nil, errors.New("invalid credentials")
+	}
+
+	if !user.IsActive {
+		as.logger.Warnf("%s: user inactive: %s", logPrefix, username)
+		return nil, errors.New("invalid credentials")
+	}
+
+	return user, nil
+}
+
+// TOTP two-factor errors
+var (
+	ErrTOTPRequired       = errors.New("two-factor code required")
+	ErrTOTPInvalid        = errors.New("invalid two-factor code")
+	ErrTOTPReused         = fmt.Errorf("%w: code already used", ErrTOTPInvalid) // Also matches ErrTOTPInvalid
+	ErrTOTPNotEnrolled    = errors.New("two-factor enrollment not started")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+)
+
+// checkTOTP requires a valid code from users with TOTP enabled
+func (as *AuthServiceDB) checkTOTP(ctx context.Context, user *models.User, code string) error {
+	secret, enabled, err := as.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	if strings.TrimSpace(code) == "" {
+		as.logger.Infof("🔐 TOTP code required for: %s", user.Username)
+		return ErrTOTPRequired
+	}
+	if err := as.acceptTOTP(ctx, user.ID, secret, code); err != nil {
+		as.logger.Warnf("🔒 JWT Login failed: %v for: %s", err, user.Username)
+		return err
+	}
+	return nil
+}
+
+// acceptTOTP validates code and claims its time step, so the same code (or an
+// older one) is refused afterwards
+func (as *AuthServiceDB) acceptTOTP(ctx context.Context, userID int, secret, code string) error {
+	step, ok := matchTOTPStep(secret, code, time.Now())
+	if !ok {
+		return ErrTOTPInvalid
+	}
+	claimed, err := as.userRepo.ClaimTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrTOTPReused
+	}
+	return nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It is only enforced at
+// login after VerifyTOTP confirms the app produces matching codes.
+func (as *AuthServiceDB) EnrollTOTP(user *models.User) (*models.TOTPEnrollment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, enabled, err := as.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	key, err := generateTOTPKey(user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	if err := as.userRepo.SetTOTPSecret(ctx, user.ID, key.Secret()); err != nil {
+		return nil, err
+	}
+
+	as.logger.Infof("🔐 TOTP enrollment started for: %s", user.Username)
+	return &models.TOTPEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+	}, nil
+}
+
+// VerifyTOTP confirms enrollment with a code from the app and turns on the
+// login requirement
+func (as *AuthServiceDB) VerifyTOTP(user *models.User, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	secret, enabled, err := as.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if secret == "" {
+		return ErrTOTPNotEnrolled
+	}
+	if err := as.acceptTOTP(ctx, user.ID, secret, code); err != nil {
+		return err
+	}
+
+	if err := as.userRepo.EnableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	as.logger.Infof("🔐 TOTP enabled for: %s", user.Username)
+	return nil
+}
+
+// IssueStepUpToken re-verifies the password of an authenticated user and issues
+// a short-lived step-up token for destructive operations
+func (as *AuthServiceDB) IssueStepUpToken(user *models.User, password, ipAddress string) (*models.StepUpToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verified, err := as.verifyCredentials(ctx, user.Username, password, "🔒 Step-up failed")
+	if err != nil {
+		return nil, err
+	}
+	if verified.ID != user.ID {
+		return nil, errors.New("invalid credentials")
+	}
+
+	token, err := as.jwtService.GenerateStepUpToken(verified, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	as.logger.Infof("🔐 Step-up token issued untuk user: %s from %s", user.Username, ipAddress)
+	return token, nil
+}
+
+// VerifyStepUp accepts either a step-up token or a re-entered password for user
+func (as *AuthServiceDB) VerifyStepUp(user *models.User, stepUpToken, password string) error {
+	if stepUpToken != "" {
+		return as.jwtService.ValidateStepUpToken(stepUpToken, user.ID)
+	}
+	if password == "" {
+		return errors.New("step-up confirmation required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verified, err := as.verifyCredentials(ctx, user.Username, password, "🔒 Step-up failed")
+	if err != nil {
+		return err
+	}
+	if verified.ID != user.ID {
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
+
 // Logout removes a user session
 func (as *AuthServiceDB) Logout(sessionID string) error {
 	as.mutex.Lock()
@@ -359,16 +525,100 @@ func (as *AuthServiceDB) LogoutJWT(accessToken, refreshToken string) error {
 	return nil
 }
 
-// RevokeAllUserTokens revokes semua token untuk user
-func (as *AuthServiceDB) RevokeAllUserTokens(userID int) error {
+// RevokeAllUserTokens revokes semua token untuk user, returning how many
+// sessions were logged out
+func (as *AuthServiceDB) RevokeAllUserTokens(userID int) (int, error) {
 	return as.jwtService.RevokeAllTokensForUser(userID)
 }
 
+// ErrActiveSessionNotFound is returned when revoking a session that isn't live
+var ErrActiveSessionNotFound = errors.New("active session not found")
+
+// ListActiveSessions returns live sessions fleet-wide, most recently active
+// first, with the total before pagination
+func (as *AuthServiceDB) ListActiveSessions(filter models.ActiveSessionFilter) ([]models.ActiveSession, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users, err := as.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[int]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	live, err := as.jwtService.ListSessions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	search := strings.ToLower(strings.TrimSpace(filter.Username))
+	sessions := []models.ActiveSession{}
+	for _, session := range live {
+		if user, ok := byID[session.UserID]; ok {
+			session.Username = user.Username
+			session.Role = user.Role
+		}
+		if search != "" && !strings.Contains(strings.ToLower(session.Username), search) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastActivity.Equal(sessions[j].LastActivity) {
+			return sessions[i].LastActivity.After(sessions[j].LastActivity)
+		}
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+
+	total := len(sessions)
+	if filter.Offset >= total {
+		return []models.ActiveSession{}, total, nil
+	}
+	sessions = sessions[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(sessions) {
+		sessions = sessions[:filter.Limit]
+	}
+
+	return sessions, total, nil
+}
+
+// RevokeSession logs out a single session: its refresh token is dropped and
+// access tokens issued for it stop validating
+func (as *AuthServiceDB) RevokeSession(sessionID string) error {
+	revoked, err := as.jwtService.RevokeSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrActiveSessionNotFound
+	}
+	return nil
+}
+
 // GetJWTPublicKey returns JWT public key untuk external validation
 func (as *AuthServiceDB) GetJWTPublicKey() (string, error) {
 	return as.jwtService.GetPublicKeyPEM()
 }
 
+// SetJWTLeeway sets the clock skew tolerated when validating token time claims
+func (as *AuthServiceDB) SetJWTLeeway(leeway time.Duration) {
+	as.jwtService.SetLeeway(leeway)
+}
+
+// SetJWTLifetimes sets how long access and refresh tokens stay valid
+func (as *AuthServiceDB) SetJWTLifetimes(accessTTL, refreshTTL time.Duration) {
+	as.jwtService.SetTokenLifetimes(accessTTL, refreshTTL)
+}
+
+// CheckJWTSigning verifies that tokens can be signed and validated (used by deep health check)
+func (as *AuthServiceDB) CheckJWTSigning() error {
+	return as.jwtService.SelfTest()
+}
+
 // generateSessionID generates a random session ID
 func (as *AuthServiceDB) generateSessionID() string {
 	bytes := make([]byte, 32)
@@ -2,9 +2,12 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
+	"nat-management-app/internal/models"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -230,6 +233,35 @@ func (rcb *RouterCircuitBreaker) GetStats() map[string]map[string]interface{} {
 	return stats
 }
 
+// Snapshot returns the circuit breaker state of a router, including how long
+// an open circuit still refuses calls
+func (rcb *RouterCircuitBreaker) Snapshot(routerName string) models.RouterCircuit {
+	state := rcb.getOrCreateState(routerName)
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	circuit := models.RouterCircuit{
+		RouterName:       routerName,
+		State:            state.state.String(),
+		Failures:         state.failures,
+		FailureThreshold: rcb.breaker.failureThreshold,
+	}
+	if state.state == StateOpen {
+		if remaining := rcb.breaker.timeout - time.Since(state.lastFailureTime); remaining > 0 {
+			circuit.HalfOpenIn = int(math.Ceil(remaining.Seconds()))
+		}
+	}
+	if !state.lastFailureTime.IsZero() {
+		lastFailure := state.lastFailureTime
+		circuit.LastFailureTime = &lastFailure
+	}
+	if !state.lastSuccessTime.IsZero() {
+		lastSuccess := state.lastSuccessTime
+		circuit.LastSuccessTime = &lastSuccess
+	}
+	return circuit
+}
+
 // Reset resets the circuit breaker for a specific router
 func (rcb *RouterCircuitBreaker) Reset(routerName string) {
 	state := rcb.getOrCreateState(routerName)
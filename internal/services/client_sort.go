@@ -0,0 +1,62 @@
+package services
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/utils/duration"
+)
+
+// SortNATClients sorts clients in place by username, uptime or IP address.
+// Clients whose uptime/IP can't be parsed always sort last, and ties fall
+// back to username so the order is stable between refreshes.
+func SortNATClients(clients []models.NATClient, sortBy string, descending bool) {
+	// compare returns the primary-key ordering and whether both keys were usable
+	compare := func(a, b models.NATClient) (int, bool, bool) {
+		return strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username)), true, true
+	}
+
+	switch sortBy {
+	case models.ClientSortUptime:
+		compare = func(a, b models.NATClient) (int, bool, bool) {
+			da, okA := duration.ParseRouterOS(a.Uptime)
+			db, okB := duration.ParseRouterOS(b.Uptime)
+			switch {
+			case da < db:
+				return -1, okA, okB
+			case da > db:
+				return 1, okA, okB
+			}
+			return 0, okA, okB
+		}
+	case models.ClientSortIP:
+		compare = func(a, b models.NATClient) (int, bool, bool) {
+			ipA, errA := netip.ParseAddr(a.IPAddress)
+			ipB, errB := netip.ParseAddr(b.IPAddress)
+			if errA != nil || errB != nil {
+				return 0, errA == nil, errB == nil
+			}
+			return ipA.Compare(ipB), true, true
+		}
+	}
+
+	sort.SliceStable(clients, func(i, j int) bool {
+		a, b := clients[i], clients[j]
+		cmp, okA, okB := compare(a, b)
+		if okA != okB {
+			return okA
+		}
+		if okA && cmp != 0 {
+			if descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		if ua, ub := strings.ToLower(a.Username), strings.ToLower(b.Username); ua != ub {
+			return ua < ub
+		}
+		return a.Username < b.Username
+	})
+}
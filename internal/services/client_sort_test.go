@@ -0,0 +1,58 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestSortNATClients(t *testing.T) {
+	clients := []models.NATClient{
+		{Username: "carol", Uptime: "3h", IPAddress: "10.0.0.10"},
+		{Username: "Alice", Uptime: "1d2h", IPAddress: "10.0.0.9"},
+		{Username: "bob", Uptime: "45m", IPAddress: "bogus"},
+		{Username: "dave", Uptime: "garbage", IPAddress: "10.0.0.2"},
+	}
+
+	tests := []struct {
+		name       string
+		sortBy     string
+		descending bool
+		want       []string
+	}{
+		{"username ignores case", models.ClientSortUsername, false, []string{"Alice", "bob", "carol", "dave"}},
+		{"username descending", models.ClientSortUsername, true, []string{"dave", "carol", "bob", "Alice"}},
+		// 1d2h is longer than 3h even though it sorts first as a string
+		{"uptime is numeric", models.ClientSortUptime, false, []string{"bob", "carol", "Alice", "dave"}},
+		{"uptime descending keeps unparsed last", models.ClientSortUptime, true, []string{"Alice", "carol", "bob", "dave"}},
+		{"ip is numeric", models.ClientSortIP, false, []string{"dave", "Alice", "carol", "bob"}},
+		{"ip descending keeps unparsed last", models.ClientSortIP, true, []string{"carol", "Alice", "dave", "bob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := slices.Clone(clients)
+			SortNATClients(sorted, tt.sortBy, tt.descending)
+
+			got := make([]string, len(sorted))
+			for i, client := range sorted {
+				got[i] = client.Username
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sorted by %s = %v, want %v", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortNATClientsTiesFallBackToUsername(t *testing.T) {
+	clients := []models.NATClient{
+		{Username: "zed", Uptime: "1h"},
+		{Username: "amy", Uptime: "60m"},
+	}
+	SortNATClients(clients, models.ClientSortUptime, true)
+	if clients[0].Username != "amy" {
+		t.Fatalf("equal uptimes sorted as %s, %s; want amy first", clients[0].Username, clients[1].Username)
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"nat-management-app/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clientWatcherBuffer is how many pending deltas a subscriber may fall behind
+// by before it is dropped
+const clientWatcherBuffer = 16
+
+// ClientWatcher polls the cached online clients and tells subscribers what
+// changed since the last poll. It only polls while someone is subscribed.
+type ClientWatcher struct {
+	natService NATServiceInterface
+	interval   time.Duration
+	logger     *logrus.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan []models.ClientChange]bool
+	// previous is the last snapshot, router -> username -> client (nil = none yet)
+	previous map[string]map[string]models.NATClient
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClientWatcher creates a watcher polling every interval. Polling faster
+// than the NAT cache TTL finds nothing new, so that is the natural interval.
+func NewClientWatcher(natService NATServiceInterface, interval time.Duration, logger *logrus.Logger) *ClientWatcher {
+	if interval <= 0 {
+		interval = defaultNATCacheTTL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ClientWatcher{
+		natService:  natService,
+		interval:    interval,
+		logger:      logger,
+		subscribers: make(map[chan []models.ClientChange]bool),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start begins polling in the background
+func (cw *ClientWatcher) Start() {
+	cw.wg.Add(1)
+	go cw.pollLoop()
+	cw.logger.Infof("✅ Client watcher started (every %v while subscribed)", cw.interval)
+}
+
+// Stop stops polling and closes every subscriber's channel
+func (cw *ClientWatcher) Stop() {
+	cw.cancel()
+	cw.wg.Wait()
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	for ch := range cw.subscribers {
+		close(ch)
+		delete(cw.subscribers, ch)
+	}
+}
+
+// Subscribe returns a channel receiving each non-empty batch of changes, and
+// the snapshot the first batch will be relative to. The channel is closed by
+// Unsubscribe, by Stop, or when the subscriber falls too far behind.
+func (cw *ClientWatcher) Subscribe() (chan []models.ClientChange, map[string][]models.NATClient) {
+	ch := make(chan []models.ClientChange, clientWatcherBuffer)
+
+	// The first subscriber sets the baseline (from the cache, usually)
+	cw.mu.Lock()
+	needBaseline := cw.previous == nil
+	cw.mu.Unlock()
+	var baseline map[string]map[string]models.NATClient
+	if needBaseline {
+		clients, _ := cw.natService.GetAllClients()
+		baseline = indexClients(clients)
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.ctx.Err() != nil {
+		close(ch)
+		return ch, map[string][]models.NATClient{}
+	}
+	if cw.previous == nil {
+		cw.previous = baseline
+	}
+	cw.subscribers[ch] = true
+
+	snapshot := make(map[string][]models.NATClient, len(cw.previous))
+	for routerName, byUser := range cw.previous {
+		clients := make([]models.NATClient, 0, len(byUser))
+		for _, username := range sortedKeys(byUser) {
+			clients = append(clients, byUser[username])
+		}
+		snapshot[routerName] = clients
+	}
+	return ch, snapshot
+}
+
+// Unsubscribe removes ch and closes it, if it is still subscribed
+func (cw *ClientWatcher) Unsubscribe(ch chan []models.ClientChange) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.subscribers[ch] {
+		delete(cw.subscribers, ch)
+		close(ch)
+	}
+	if len(cw.subscribers) == 0 {
+		// The next subscriber starts from a fresh snapshot
+		cw.previous = nil
+	}
+}
+
+// pollLoop polls on every tick that has subscribers
+func (cw *ClientWatcher) pollLoop() {
+	defer cw.wg.Done()
+
+	ticker := time.NewTicker(cw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cw.ctx.Done():
+			return
+		case <-ticker.C:
+			cw.mu.Lock()
+			idle := len(cw.subscribers) == 0
+			cw.mu.Unlock()
+			if !idle {
+				cw.poll()
+			}
+		}
+	}
+}
+
+// poll diffs the current clients against the previous snapshot and
+// broadcasts the changes. Routers that failed to answer keep their previous
+// snapshot, so an unreachable router doesn't show up as everyone
+// disconnecting.
+func (cw *ClientWatcher) poll() {
+	clients, fetchErrors := cw.natService.GetAllClients()
+
+	current := indexClients(clients)
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	previous := cw.previous
+	for routerName := range fetchErrors {
+		if old, ok := previous[routerName]; ok {
+			current[routerName] = old
+		}
+	}
+	cw.previous = current
+	if previous == nil {
+		return
+	}
+
+	changes := diffClients(previous, current, time.Now())
+	if len(changes) == 0 {
+		return
+	}
+
+	for ch := range cw.subscribers {
+		select {
+		case ch <- changes:
+		default:
+			cw.logger.Warn("⚠️ Dropping a client stream subscriber that fell behind")
+			delete(cw.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// indexClients keys each router's clients by username
+func indexClients(clients map[string][]models.NATClient) map[string]map[string]models.NATClient {
+	indexed := make(map[string]map[string]models.NATClient, len(clients))
+	for routerName, routerClients := range clients {
+		byUser := make(map[string]models.NATClient, len(routerClients))
+		for _, client := range routerClients {
+			byUser[client.Username] = client
+		}
+		indexed[routerName] = byUser
+	}
+	return indexed
+}
+
+// diffClients lists the connections, disconnections and IP changes between
+// two snapshots, ordered by router and username
+func diffClients(previous, current map[string]map[string]models.NATClient, now time.Time) []models.ClientChange {
+	changes := []models.ClientChange{}
+	for _, routerName := range sortedKeys(previous, current) {
+		before, after := previous[routerName], current[routerName]
+		for _, username := range sortedKeys(before, after) {
+			old, wasOnline := before[username]
+			client, isOnline := after[username]
+			switch {
+			case !wasOnline && isOnline:
+				c := client
+				changes = append(changes, models.ClientChange{Event: models.ClientConnected, Router: routerName, Username: username, IPAddress: client.IPAddress, Client: &c, Timestamp: now})
+			case wasOnline && !isOnline:
+				changes = append(changes, models.ClientChange{Event: models.ClientDisconnected, Router: routerName, Username: username, PreviousIP: old.IPAddress, Timestamp: now})
+			case wasOnline && isOnline && old.IPAddress != client.IPAddress:
+				c := client
+				changes = append(changes, models.ClientChange{Event: models.ClientIPChanged, Router: routerName, Username: username, IPAddress: client.IPAddress, PreviousIP: old.IPAddress, Client: &c, Timestamp: now})
+			}
+		}
+	}
+	return changes
+}
+
+// sortedKeys returns the keys of all the maps, deduplicated and sorted
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+func TestDiffClients(t *testing.T) {
+	now := time.Now()
+	previous := indexClients(map[string][]models.NATClient{
+		"LANE1": {
+			{Router: "LANE1", Username: "alice", IPAddress: "10.0.0.2"},
+			{Router: "LANE1", Username: "bob", IPAddress: "10.0.0.3"},
+			{Router: "LANE1", Username: "carol", IPAddress: "10.0.0.4"},
+		},
+		"LANE2": {
+			{Router: "LANE2", Username: "dave", IPAddress: "10.1.0.2"},
+		},
+	})
+	current := indexClients(map[string][]models.NATClient{
+		"LANE1": {
+			{Router: "LANE1", Username: "alice", IPAddress: "10.0.0.2"},
+			{Router: "LANE1", Username: "carol", IPAddress: "10.0.0.9"},
+			{Router: "LANE1", Username: "erin", IPAddress: "10.0.0.5"},
+		},
+		"SAMSAT": {
+			{Router: "SAMSAT", Username: "frank", IPAddress: "10.2.0.2"},
+		},
+	})
+
+	want := []models.ClientChange{
+		{Event: models.ClientDisconnected, Router: "LANE1", Username: "bob", PreviousIP: "10.0.0.3"},
+		{Event: models.ClientIPChanged, Router: "LANE1", Username: "carol", IPAddress: "10.0.0.9", PreviousIP: "10.0.0.4"},
+		{Event: models.ClientConnected, Router: "LANE1", Username: "erin", IPAddress: "10.0.0.5"},
+		{Event: models.ClientDisconnected, Router: "LANE2", Username: "dave", PreviousIP: "10.1.0.2"},
+		{Event: models.ClientConnected, Router: "SAMSAT", Username: "frank", IPAddress: "10.2.0.2"},
+	}
+
+	got := diffClients(previous, current, now)
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+	}
+	for i, change := range got {
+		w := want[i]
+		if change.Event != w.Event || change.Router != w.Router || change.Username != w.Username ||
+			change.IPAddress != w.IPAddress || change.PreviousIP != w.PreviousIP || !change.Timestamp.Equal(now) {
+			t.Errorf("change %d = %+v, want %+v", i, change, w)
+		}
+		// Connections and IP changes carry the current client, disconnections don't
+		if hasClient := change.Client != nil; hasClient != (w.Event != models.ClientDisconnected) {
+			t.Errorf("change %d (%s) client = %v", i, change.Event, change.Client)
+		} else if hasClient && change.Client.IPAddress != w.IPAddress {
+			t.Errorf("change %d client address = %s, want %s", i, change.Client.IPAddress, w.IPAddress)
+		}
+	}
+
+	// Identical snapshots diff to an empty list, not nil
+	if changes := diffClients(current, current, now); changes == nil || len(changes) != 0 {
+		t.Fatalf("diff of identical snapshots = %+v, want an empty list", changes)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed 5-field cron expression:
+// minute hour day-of-month month day-of-week. Each field supports
+// "*", numbers, lists (1,15), ranges (1-5) and steps (*/15, 0-30/10).
+type cronSchedule struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+	// Like cron, when both day fields are restricted either may match
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// parseCronSchedule parses a 5-field cron expression
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	s := &cronSchedule{}
+	if err := parseCronField(fields[0], 0, 59, s.minutes[:]); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if err := parseCronField(fields[1], 0, 23, s.hours[:]); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if err := parseCronField(fields[2], 1, 31, s.days[:]); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if err := parseCronField(fields[3], 1, 12, s.months[:]); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// Accept 7 as Sunday too
+	weekdays := make([]bool, 8)
+	if err := parseCronField(fields[4], 0, 7, weekdays); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	copy(s.weekdays[:], weekdays[:7])
+	if weekdays[7] {
+		s.weekdays[0] = true
+	}
+
+	s.daysRestricted = fields[2] != "*"
+	s.weekdaysRestricted = fields[4] != "*"
+	return s, nil
+}
+
+// parseCronField marks the values selected by one field in set
+func parseCronField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loStr, hiStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return fmt.Errorf("invalid value %q", loStr)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return fmt.Errorf("invalid value %q", hiStr)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("value out of range %d-%d in %q", min, max, part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the schedule fires in t's minute
+func (s *cronSchedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[t.Month()] {
+		return false
+	}
+
+	dayMatch := s.days[t.Day()]
+	weekdayMatch := s.weekdays[t.Weekday()]
+	if s.daysRestricted && s.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
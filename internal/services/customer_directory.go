@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// customerDirectoryTimeout bounds a single lookup against the mapping backend
+const customerDirectoryTimeout = 3 * time.Second
+
+// Customer record sources
+const (
+	CustomerSourceLocal   = "local"
+	CustomerSourceMapping = "mapping-ftth"
+)
+
+// CustomerDirectory resolves PPPoE usernames to customer (pelanggan) details.
+// The local customers table is checked first; single lookups then fall back to
+// the mapping-ftth backend when a URL is configured. Every failure is soft:
+// callers get "not found" and the error is only logged.
+type CustomerDirectory struct {
+	repo       *database.CustomerRepository
+	mappingURL string // e.g. http://mapping-ftth:8081/api/pelanggan/pppoe/{username}
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewCustomerDirectory creates a new customer directory. mappingURL may be empty
+// to use the local table only; "{username}" in it is replaced per lookup.
+func NewCustomerDirectory(repo *database.CustomerRepository, mappingURL string, logger *logrus.Logger) *CustomerDirectory {
+	return &CustomerDirectory{
+		repo:       repo,
+		mappingURL: mappingURL,
+		httpClient: &http.Client{Timeout: customerDirectoryTimeout},
+		logger:     logger,
+	}
+}
+
+// Lookup resolves one PPPoE username. Returns nil when no customer is known.
+func (d *CustomerDirectory) Lookup(ctx context.Context, pppoeUsername string) *models.Customer {
+	if pppoeUsername == "" {
+		return nil
+	}
+
+	if customers := d.ResolveMany(ctx, []string{pppoeUsername}); customers[pppoeUsername] != nil {
+		return customers[pppoeUsername]
+	}
+
+	if d.mappingURL == "" {
+		return nil
+	}
+
+	customer, err := d.fetchFromMapping(ctx, pppoeUsername)
+	if err != nil {
+		d.logger.Warnf("⚠️ Customer lookup for %s via mapping-ftth failed: %v", pppoeUsername, err)
+		return nil
+	}
+	return customer
+}
+
+// ResolveMany resolves many PPPoE usernames from the local table only, so
+// bulk listings never fan out into one HTTP call per client
+func (d *CustomerDirectory) ResolveMany(ctx context.Context, pppoeUsernames []string) map[string]*models.Customer {
+	if d.repo == nil {
+		return map[string]*models.Customer{}
+	}
+
+	customers, err := d.repo.GetByPPPoEUsernames(ctx, pppoeUsernames)
+	if err != nil {
+		d.logger.Warnf("⚠️ Failed to resolve customers from local table: %v", err)
+		return map[string]*models.Customer{}
+	}
+
+	for _, customer := range customers {
+		customer.Source = CustomerSourceLocal
+	}
+	return customers
+}
+
+// fetchFromMapping queries the mapping-ftth backend for one customer
+func (d *CustomerDirectory) fetchFromMapping(ctx context.Context, pppoeUsername string) (*models.Customer, error) {
+	endpoint := strings.ReplaceAll(d.mappingURL, "{username}", url.PathEscape(pppoeUsername))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mapping-ftth returned HTTP %d", resp.StatusCode)
+	}
+
+	var customer models.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		return nil, fmt.Errorf("invalid mapping-ftth response: %w", err)
+	}
+	if customer.PPPoEUsername == "" {
+		customer.PPPoEUsername = pppoeUsername
+	}
+	customer.Source = CustomerSourceMapping
+	return &customer, nil
+}
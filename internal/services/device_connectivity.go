@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultConnectivityTestPorts are common ports of ONTs, modems and customer devices
+var defaultConnectivityTestPorts = []string{"80", "8080", "443", "22", "23", "8081"}
+
+// defaultConnectivityTestTimeout bounds one port's connection attempt
+const defaultConnectivityTestTimeout = 2 * time.Second
+
+// SetConnectivityTest sets the TCP ports and per-port timeout of the PPPoE
+// connectivity test. Invalid ports are skipped; an empty list or a
+// non-positive timeout keeps the default.
+func (ns *NATService) SetConnectivityTest(ports []string, timeout time.Duration) {
+	valid := make([]string, 0, len(ports))
+	for _, port := range ports {
+		port = strings.TrimSpace(port)
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			ns.logger.Warnf("⚠️ Ignoring invalid connectivity test port %q", port)
+			continue
+		}
+		valid = append(valid, port)
+	}
+	if len(valid) == 0 {
+		valid = defaultConnectivityTestPorts
+	}
+	if timeout <= 0 {
+		timeout = defaultConnectivityTestTimeout
+	}
+	ns.connectivityTestPorts = valid
+	ns.connectivityTestTimeout = timeout
+}
+
+// testDeviceConnectivity tests if the device at given IP is actually reachable
+// via TCP. All ports are tried at once and the first one that accepts a
+// connection wins, so an unreachable device costs one timeout, not one per port.
+// The test also ends when ctx is done.
+func (ns *NATService) testDeviceConnectivity(ctx context.Context, ipAddress string) (bool, string, time.Duration) {
+	startTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, ns.connectivityTestTimeout)
+	defer cancel()
+
+	// Buffered so the losing dials can finish after we've returned
+	reachable := make(chan string, len(ns.connectivityTestPorts))
+	dialer := net.Dialer{}
+	for _, port := range ns.connectivityTestPorts {
+		go func(port string) {
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ipAddress, port))
+			if err != nil {
+				ns.logger.Debugf("⚠️  Port %s on %s unreachable: %v", port, ipAddress, err)
+				reachable <- ""
+				return
+			}
+			conn.Close()
+			reachable <- port
+		}(port)
+	}
+
+	for range ns.connectivityTestPorts {
+		if port := <-reachable; port != "" {
+			duration := time.Since(startTime)
+			ns.logger.Debugf("✅ Device %s reachable on port %s (took %v)", ipAddress, port, duration)
+			return true, port, duration
+		}
+	}
+
+	duration := time.Since(startTime)
+	ns.logger.Warnf("❌ Device %s unreachable on all tested ports (took %v)", ipAddress, duration)
+	return false, "", duration
+}
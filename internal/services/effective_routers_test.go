@@ -0,0 +1,46 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestMergeRouterGrants(t *testing.T) {
+	// LANE1 is assigned and granted to the role, twice over; the wildcard row
+	// comes first but never outranks a specific grant
+	assigned := []string{"LANE1", "LANE1"}
+	roleRouters := []string{"*", "LANE1", "SAMSAT", "SAMSAT"}
+	wildcardRouters := []string{"LANE1", "LANE2", "SAMSAT"}
+
+	got := mergeRouterGrants(assigned, roleRouters, wildcardRouters)
+
+	want := []models.EffectiveRouter{
+		{Name: "LANE1", Reason: "user_assignment", Grants: []string{"user_assignment", "role", "wildcard"}, NATAccess: true},
+		{Name: "LANE2", Reason: "wildcard", Grants: []string{"wildcard"}},
+		{Name: "SAMSAT", Reason: "role", Grants: []string{"role", "wildcard"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("routers = %+v, want one entry each for %d routers", got, len(want))
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Reason != want[i].Reason || got[i].NATAccess != want[i].NATAccess ||
+			!slices.Equal(got[i].Grants, want[i].Grants) {
+			t.Errorf("routers[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMergeRouterGrantsRoleOnly(t *testing.T) {
+	// Without assignments the role's routers are usable for NAT operations
+	got := mergeRouterGrants(nil, []string{"SAMSAT", "LANE1"}, nil)
+	if len(got) != 2 || got[0].Name != "LANE1" || got[1].Name != "SAMSAT" {
+		t.Fatalf("routers = %+v, want LANE1 and SAMSAT sorted", got)
+	}
+	for _, router := range got {
+		if router.Reason != "role" || !router.NATAccess {
+			t.Errorf("%s = %+v, want a role grant with NAT access", router.Name, router)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeRouter is a minimal RouterOS API server for tests. It accepts any
+// login and answers every other command with whatever respond returns.
+type fakeRouter struct {
+	listener net.Listener
+	respond  func(command []string) [][]string
+
+	mu          sync.Mutex
+	commands    [][]string // Every command received, logins excluded
+	rejectLogin bool       // Answer /login with a !trap
+}
+
+// hangUp is a reply that makes the fake router drop the connection instead
+// of answering
+var hangUp = [][]string{{"<hang up>"}}
+
+// newFakeRouter starts a fake router that is shut down with the test.
+// respond gets the command words and returns the reply sentences; a nil
+// reply means a bare !done.
+func newFakeRouter(t *testing.T, respond func(command []string) [][]string) *fakeRouter {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fake router listen: %v", err)
+	}
+	fr := &fakeRouter{listener: listener, respond: respond}
+	go fr.serve()
+	t.Cleanup(func() { listener.Close() })
+	return fr
+}
+
+// config returns the connection settings of the fake router
+func (fr *fakeRouter) config() ConnectionConfig {
+	addr := fr.listener.Addr().(*net.TCPAddr)
+	return ConnectionConfig{Host: addr.IP.String(), Port: addr.Port, Username: "admin", Password: "secret"}
+}
+
+// setRejectLogin makes later logins fail as with a wrong password
+func (fr *fakeRouter) setRejectLogin(reject bool) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.rejectLogin = reject
+}
+
+// received returns the commands received so far
+func (fr *fakeRouter) received() [][]string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return append([][]string(nil), fr.commands...)
+}
+
+func (fr *fakeRouter) serve() {
+	for {
+		conn, err := fr.listener.Accept()
+		if err != nil {
+			return
+		}
+		go fr.handle(conn)
+	}
+}
+
+func (fr *fakeRouter) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		command, err := readSentence(r)
+		if err != nil {
+			return
+		}
+		var reply [][]string
+		fr.mu.Lock()
+		rejectLogin := fr.rejectLogin
+		fr.mu.Unlock()
+		if command[0] == "/login" && rejectLogin {
+			reply = [][]string{{"!trap", "=message=invalid user name or password (6)"}, {"!done"}}
+		} else if command[0] != "/login" {
+			fr.mu.Lock()
+			fr.commands = append(fr.commands, command)
+			fr.mu.Unlock()
+			if fr.respond != nil {
+				reply = fr.respond(command)
+			}
+		}
+		if len(reply) == 1 && len(reply[0]) == 1 && reply[0][0] == hangUp[0][0] {
+			return
+		}
+		if len(reply) == 0 {
+			reply = [][]string{{"!done"}}
+		}
+		for _, sentence := range reply {
+			if err := writeSentence(conn, sentence); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readSentence reads API words up to the empty word ending a sentence
+func readSentence(r *bufio.Reader) ([]string, error) {
+	var words []string
+	for {
+		length, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			if len(words) == 0 {
+				continue
+			}
+			return words, nil
+		}
+		word := make([]byte, length)
+		if _, err := io.ReadFull(r, word); err != nil {
+			return nil, err
+		}
+		words = append(words, string(word))
+	}
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var extra int
+	var length int
+	switch {
+	case first&0x80 == 0:
+		return int(first), nil
+	case first&0xC0 == 0x80:
+		extra, length = 1, int(first&0x3F)
+	case first&0xE0 == 0xC0:
+		extra, length = 2, int(first&0x1F)
+	case first&0xF0 == 0xE0:
+		extra, length = 3, int(first&0x0F)
+	default:
+		extra, length = 4, 0
+	}
+	for i := 0; i < extra; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+func writeSentence(w io.Writer, words []string) error {
+	var buf []byte
+	for _, word := range append(words, "") {
+		buf = append(buf, encodeLength(len(word))...)
+		buf = append(buf, word...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func encodeLength(l int) []byte {
+	switch {
+	case l < 0x80:
+		return []byte{byte(l)}
+	case l < 0x4000:
+		return []byte{byte(l>>8) | 0x80, byte(l)}
+	case l < 0x200000:
+		return []byte{byte(l>>16) | 0xC0, byte(l >> 8), byte(l)}
+	default:
+		return []byte{byte(l>>24) | 0xE0, byte(l >> 16), byte(l >> 8), byte(l)}
+	}
+}
+
+// reSentence builds a !re reply sentence from key=value pairs
+func reSentence(pairs ...string) []string {
+	sentence := []string{"!re"}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		sentence = append(sentence, "="+pairs[i]+"="+pairs[i+1])
+	}
+	return sentence
+}
+
+// newTestPool returns a quiet connection pool that is closed with the test
+func newTestPool(t *testing.T) *RouterOSConnectionPool {
+	t.Helper()
+	pool := NewRouterOSConnectionPool(quietLogger(), 2, time.Minute, time.Hour)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// poolRouterService hands out pool connections to fake routers by name, the
+// only part of RouterServiceInterface NATService needs to reach a router
+type poolRouterService struct {
+	RouterServiceInterface
+	pool    *RouterOSConnectionPool
+	routers map[string]*fakeRouter
+}
+
+func (s *poolRouterService) GetRouterConnection(ctx context.Context, name string) (*RouterOSConnection, error) {
+	return s.pool.GetConnectionContext(ctx, name, s.routers[name].config())
+}
+
+// newTestNATService returns a NATService with one router, "FAKE", served by fr
+func newTestNATService(t *testing.T, fr *fakeRouter) *NATService {
+	t.Helper()
+	return newTestNATServiceFor(t, map[string]*fakeRouter{"FAKE": fr})
+}
+
+// newTestNATServiceFor returns a NATService with a router per fake router
+func newTestNATServiceFor(t *testing.T, routers map[string]*fakeRouter) *NATService {
+	t.Helper()
+	ns := &NATService{
+		logger:        quietLogger(),
+		routerService: &poolRouterService{pool: newTestPool(t), routers: routers},
+		cacheTTL:      defaultNATCacheTTL,
+	}
+	configs := make(map[string]models.NATRouterConfig, len(routers))
+	for name, fr := range routers {
+		config := fr.config()
+		configs[name] = models.NATRouterConfig{Name: name, Host: config.Host, Port: config.Port, Username: config.Username, Password: config.Password}
+	}
+	ns.setRouters(configs)
+	return ns
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FeatureFlagService handles runtime feature flags backed by the feature_flags table
+type FeatureFlagService struct {
+	db     *database.DB
+	logger *logrus.Logger
+
+	// Flags are cached in memory so route checks don't hit the DB on every request
+	mu       sync.RWMutex
+	cache    map[string]bool
+	loadedAt time.Time
+	cacheTTL time.Duration
+}
+
+// NewFeatureFlagService creates a new feature flag service
+func NewFeatureFlagService(db *database.DB, logger *logrus.Logger) *FeatureFlagService {
+	return &FeatureFlagService{
+		db:       db,
+		logger:   logger,
+		cache:    make(map[string]bool),
+		cacheTTL: 30 * time.Second,
+	}
+}
+
+// IsEnabled reports whether a flag is on. Unknown flags and lookup failures count as off.
+func (s *FeatureFlagService) IsEnabled(name string) bool {
+	s.mu.RLock()
+	fresh := time.Since(s.loadedAt) < s.cacheTTL
+	enabled := s.cache[name]
+	s.mu.RUnlock()
+
+	if fresh {
+		return enabled
+	}
+
+	if err := s.refresh(); err != nil {
+		s.logger.Warnf("⚠️ Failed to refresh feature flags, keeping cached values: %v", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cache[name]
+}
+
+// refresh reloads all flags into the cache
+func (s *FeatureFlagService) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT name, enabled FROM feature_flags`)
+	if err != nil {
+		return fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	cache := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		cache[name] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating feature flags: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// ListFlags returns all stored flags plus known flags that have never been toggled
+func (s *FeatureFlagService) ListFlags() ([]models.FeatureFlag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT name, enabled, COALESCE(description, ''), COALESCE(updated_by, ''), updated_at
+		FROM feature_flags
+		ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.FeatureFlag{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var flag models.FeatureFlag
+		if err := rows.Scan(&flag.Name, &flag.Enabled, &flag.Description, &flag.UpdatedBy, &flag.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		seen[flag.Name] = true
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feature flags: %w", err)
+	}
+
+	for name, description := range models.KnownFeatureFlags {
+		if !seen[name] {
+			flags = append(flags, models.FeatureFlag{Name: name, Description: description})
+		}
+	}
+
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// SetFlag creates or updates a flag and refreshes the cache immediately
+func (s *FeatureFlagService) SetFlag(name string, enabled bool, description, updatedBy string) (*models.FeatureFlag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if description == "" {
+		description = models.KnownFeatureFlags[name]
+	}
+
+	flag := &models.FeatureFlag{Name: name}
+	err := s.db.Pool.QueryRow(ctx, `
+		INSERT INTO feature_flags (name, enabled, description, updated_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (name) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    description = COALESCE(EXCLUDED.description, feature_flags.description),
+		    updated_by = EXCLUDED.updated_by
+		RETURNING enabled, COALESCE(description, ''), COALESCE(updated_by, ''), updated_at
+	`, name, enabled, description, updatedBy).Scan(&flag.Enabled, &flag.Description, &flag.UpdatedBy, &flag.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+
+	if err := s.refresh(); err != nil {
+		s.logger.Warnf("⚠️ Failed to refresh feature flags after update: %v", err)
+	}
+
+	s.logger.Infof("🚩 Feature flag %s set to %v by %s", name, enabled, updatedBy)
+	return flag, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultFuzzyMaxCandidates caps how many usernames per router get full similarity scoring
+const defaultFuzzyMaxCandidates = 2000
+
+// defaultFuzzyMinSimilarity is the score a username needs to be returned as a match
+const defaultFuzzyMinSimilarity = 0.3
+
+// defaultFuzzyAreaPatterns are the area names used in usernames (e.g. ahmadkukun, budikukun)
+var defaultFuzzyAreaPatterns = []string{
+	"kukun", "cipanas", "sukatani", "darussalam", "samsat", "cikarang",
+	"sukawangi", "jaya", "lane4", "lane", "bt", "pk", "kp",
+}
+
+// fuzzyPrefilter is a cheap first pass over usernames before calculateSimilarity.
+// It only drops names that share nothing with the search term (no substring,
+// no common area and no common n-gram), which can't reach the similarity
+// threshold for realistic inputs, and ranks the rest so the best go through full scoring first.
+type fuzzyPrefilter struct {
+	term   string
+	area   string
+	n      int
+	ngrams map[string]struct{}
+}
+
+// fuzzyCandidate is a username that passed the prefilter, with its cheap rank
+type fuzzyCandidate struct {
+	index int // Position in the router reply
+	rank  int
+}
+
+func newFuzzyPrefilter(searchTerm string, areaPatterns []string) *fuzzyPrefilter {
+	term := strings.ToLower(searchTerm)
+	f := &fuzzyPrefilter{term: term, n: 3, ngrams: make(map[string]struct{})}
+	if len(term) < 5 {
+		f.n = 2
+	}
+
+	for _, area := range areaPatterns {
+		if strings.Contains(term, area) {
+			f.area = area
+			break
+		}
+	}
+
+	for i := 0; i+f.n <= len(term); i++ {
+		f.ngrams[term[i:i+f.n]] = struct{}{}
+	}
+	return f
+}
+
+// rank returns how promising username is (higher is better) and false if it can be skipped
+func (f *fuzzyPrefilter) rank(username string) (int, bool) {
+	name := strings.ToLower(username)
+
+	if strings.HasPrefix(name, f.term) || strings.Contains(name, f.term) || strings.Contains(f.term, name) {
+		return 1 << 20, true
+	}
+
+	rank := 0
+	if f.area != "" && strings.Contains(name, f.area) {
+		rank += 1 << 10
+	}
+	for i := 0; i+f.n <= len(name); i++ {
+		if _, ok := f.ngrams[name[i:i+f.n]]; ok {
+			rank++
+		}
+	}
+
+	// Very short terms have almost no n-grams; let the full scoring decide
+	if len(f.ngrams) == 0 {
+		return rank, true
+	}
+	return rank, rank > 0
+}
+
+// limitFuzzyCandidates keeps the maxCandidates best-ranked candidates (all if maxCandidates <= 0)
+func limitFuzzyCandidates(candidates []fuzzyCandidate, maxCandidates int) []fuzzyCandidate {
+	if maxCandidates <= 0 || len(candidates) <= maxCandidates {
+		return candidates
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].rank > candidates[j].rank
+	})
+	return candidates[:maxCandidates]
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fuzzyTestUsernames builds usernames the way ISPs name PPPoE accounts:
+// a customer name, an area and sometimes a number
+func fuzzyTestUsernames(count int) []string {
+	names := []string{"ahmad", "budi", "siti", "dewi", "agus", "rina", "joko", "wati", "eko", "yanto"}
+	areas := []string{"kukun", "cipanas", "sukatani", "samsat", "lane4", "cikarang", ""}
+	suffixes := []string{"", "2", "01"}
+
+	var usernames []string
+	for i := 0; len(usernames) < count; i++ {
+		for _, name := range names {
+			for _, area := range areas {
+				for _, suffix := range suffixes {
+					if i > 0 {
+						suffix += fmt.Sprint(i)
+					}
+					usernames = append(usernames, name+area+suffix)
+				}
+			}
+		}
+	}
+	return usernames[:count]
+}
+
+func newFuzzyTestService() *NATService {
+	return &NATService{
+		logger:             quietLogger(),
+		fuzzyMaxCandidates: defaultFuzzyMaxCandidates,
+		fuzzyMinSimilarity: defaultFuzzyMinSimilarity,
+		fuzzyAreaPatterns:  defaultFuzzyAreaPatterns,
+	}
+}
+
+func TestFuzzyPrefilterKeepsMatches(t *testing.T) {
+	ns := newFuzzyTestService()
+	usernames := fuzzyTestUsernames(210)
+	terms := []string{"ahmadkukun", "ahmad", "budi", "kukun", "ahmadkukn", "sitisamsat", "dewi cipanas", "rina01", "jok", "ek", "AGUSLANE4"}
+
+	for _, term := range terms {
+		prefilter := newFuzzyPrefilter(term, ns.fuzzyAreaPatterns)
+		for _, username := range usernames {
+			similarity := ns.calculateSimilarity(term, username)
+			if _, ok := prefilter.rank(username); !ok && similarity >= ns.fuzzyMinSimilarity {
+				t.Errorf("prefilter dropped %s for %q (similarity %.2f)", username, term, similarity)
+			}
+		}
+	}
+}
+
+func TestFuzzyPrefilterRanksExactMatchesFirst(t *testing.T) {
+	prefilter := newFuzzyPrefilter("ahmadkukun", defaultFuzzyAreaPatterns)
+	var candidates []fuzzyCandidate
+	usernames := []string{"budikukun", "zzzz", "ahmadkukun2", "ahmadcipanas"}
+	for i, username := range usernames {
+		if rank, ok := prefilter.rank(username); ok {
+			candidates = append(candidates, fuzzyCandidate{index: i, rank: rank})
+		}
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("%d candidates passed, want all but zzzz", len(candidates))
+	}
+
+	best := limitFuzzyCandidates(candidates, 1)
+	if len(best) != 1 || usernames[best[0].index] != "ahmadkukun2" {
+		t.Fatalf("best candidate = %v, want ahmadkukun2", best)
+	}
+	if all := limitFuzzyCandidates(candidates, 0); len(all) != 3 {
+		t.Fatalf("no cap kept %d candidates, want 3", len(all))
+	}
+}
+
+// BenchmarkFuzzyScoring compares the prefiltered, capped scoring of a router
+// with 50000 sessions against scoring every username
+func BenchmarkFuzzyScoring(b *testing.B) {
+	ns := newFuzzyTestService()
+	usernames := fuzzyTestUsernames(50000)
+	const term = "ahmadkukun"
+
+	b.Run("prefiltered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			prefilter := newFuzzyPrefilter(term, ns.fuzzyAreaPatterns)
+			var candidates []fuzzyCandidate
+			for index, username := range usernames {
+				if rank, ok := prefilter.rank(username); ok {
+					candidates = append(candidates, fuzzyCandidate{index: index, rank: rank})
+				}
+			}
+			for _, candidate := range limitFuzzyCandidates(candidates, ns.fuzzyMaxCandidates) {
+				ns.calculateSimilarity(term, usernames[candidate.index])
+			}
+		}
+	})
+
+	b.Run("full scoring", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, username := range usernames {
+				ns.calculateSimilarity(term, username)
+			}
+		}
+	})
+}
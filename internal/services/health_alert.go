@@ -0,0 +1,73 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// alertWebhookTimeout bounds a single alert delivery
+const alertWebhookTimeout = 5 * time.Second
+
+// HealthAlert is the JSON body posted to ALERT_WEBHOOK_URL when a router goes
+// down or recovers
+type HealthAlert struct {
+	RouterName   string     `json:"router_name"`
+	Status       string     `json:"status"` // down, or healthy/degraded on recovery
+	DownSince    *time.Time `json:"down_since,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	Text         string     `json:"text"` // Human-readable summary, shown as-is by Slack-style webhooks
+}
+
+// SetAlertWebhook enables posting down/recovery alerts to url (empty = off)
+func (hm *HealthMonitor) SetAlertWebhook(url string) {
+	hm.alertWebhookURL = url
+	hm.alertClient = &http.Client{Timeout: alertWebhookTimeout}
+}
+
+// sendAlert delivers alert in the background so a slow or unreachable
+// webhook never stalls the health checks. Failures are only logged.
+func (hm *HealthMonitor) sendAlert(alert *HealthAlert) {
+	if hm.alertWebhookURL == "" || alert == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(hm.ctx, alertWebhookTimeout)
+		defer cancel()
+
+		if err := hm.postAlert(ctx, alert); err != nil {
+			hm.logger.Warnf("⚠️ Failed to send %s alert for router %s: %v", alert.Status, alert.RouterName, err)
+			return
+		}
+		hm.logger.Debugf("📣 Sent %s alert for router %s", alert.Status, alert.RouterName)
+	}()
+}
+
+// postAlert posts one alert to the webhook
+func (hm *HealthMonitor) postAlert(ctx context.Context, alert *HealthAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hm.alertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := hm.alertClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +17,8 @@ import (
 type HealthStatus struct {
 	RouterID          string     `json:"router_id"`
 	RouterName        string     `json:"router_name"`
+	Critical          bool       `json:"critical"`
+	Priority          int        `json:"priority"`
 	Status            string     `json:"status"` // healthy, degraded, down
 	LastChecked       time.Time  `json:"last_checked"`
 	LastSeen          time.Time  `json:"last_seen"`
@@ -47,13 +51,22 @@ type CachedHealth struct {
 // HealthMonitor monitors router health in background
 type HealthMonitor struct {
 	logger         *logrus.Logger
-	routerService  *RouterServiceDB
+	routerService  HealthRouterSource
 	cache          *HealthCache
 	states         map[string]*RouterState
 	statesMu       sync.RWMutex
 	checkInterval  time.Duration
 	cacheTTL       time.Duration
 	failThreshold  int
+	// Critical routers get their own, tighter schedule
+	criticalInterval      time.Duration
+	criticalFailThreshold int
+	// historyRepo records every check (nil = not recorded)
+	historyRepo          HealthHistoryStore
+	historyRetentionDays int
+	// Down/recovery alerts are posted here (empty = off)
+	alertWebhookURL string
+	alertClient     *http.Client
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -71,7 +84,7 @@ type RouterState struct {
 }
 
 // NewHealthMonitor creates a new health monitor instance
-func NewHealthMonitor(logger *logrus.Logger, routerService *RouterServiceDB) *HealthMonitor {
+func NewHealthMonitor(logger *logrus.Logger, routerService HealthRouterSource) *HealthMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &HealthMonitor{
@@ -84,6 +97,8 @@ func NewHealthMonitor(logger *logrus.Logger, routerService *RouterServiceDB) *He
 		checkInterval: 30 * time.Second,  // Check every 30 seconds (match UI refresh)
 		cacheTTL:      5 * time.Minute,   // Cache for 5 minutes
 		failThreshold: 3,                 // Declare down after 3 consecutive fails
+		criticalInterval:      10 * time.Second, // Critical routers checked 3x as often
+		criticalFailThreshold: 1,                // and declared down on the first failure
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -102,6 +117,11 @@ func (hm *HealthMonitor) Start() {
 	// Start cache cleanup
 	go hm.cacheCleanupWorker()
 
+	// Start history retention cleanup
+	if hm.historyRepo != nil && hm.historyRetentionDays > 0 {
+		go hm.historyCleanupWorker()
+	}
+
 	hm.logger.Info("✅ Health Monitor started successfully")
 }
 
@@ -112,9 +132,12 @@ func (hm *HealthMonitor) Stop() {
 }
 
 // healthWorker runs periodic health checks
+// Normal routers follow checkInterval, critical routers follow criticalInterval
 func (hm *HealthMonitor) healthWorker() {
 	ticker := time.NewTicker(hm.checkInterval)
 	defer ticker.Stop()
+	criticalTicker := time.NewTicker(hm.criticalInterval)
+	defer criticalTicker.Stop()
 
 	for {
 		select {
@@ -122,14 +145,21 @@ func (hm *HealthMonitor) healthWorker() {
 			hm.logger.Info("Health worker stopped")
 			return
 		case <-ticker.C:
-			hm.checkAllRouters()
+			hm.checkRouters(func(r models.RouterResponse) bool { return !r.Critical })
+		case <-criticalTicker.C:
+			hm.checkRouters(func(r models.RouterResponse) bool { return r.Critical })
 		}
 	}
 }
 
 // checkAllRouters checks health of all routers
 func (hm *HealthMonitor) checkAllRouters() {
-	hm.logger.Debug("🔍 Starting health check for all routers...")
+	hm.checkRouters(func(models.RouterResponse) bool { return true })
+}
+
+// checkRouters checks health of the routers selected by match
+func (hm *HealthMonitor) checkRouters(match func(models.RouterResponse) bool) {
+	hm.logger.Debug("🔍 Starting health check for routers...")
 
 	// Get all routers (admin role to see all)
 	routers, err := hm.routerService.GetAllRouters("Administrator")
@@ -143,6 +173,10 @@ func (hm *HealthMonitor) checkAllRouters() {
 	// Check each router concurrently
 	var wg sync.WaitGroup
 	for _, router := range routers {
+		if !match(router) {
+			continue
+		}
+
 		wg.Add(1)
 		go func(r models.RouterResponse) {
 			defer wg.Done()
@@ -151,7 +185,7 @@ func (hm *HealthMonitor) checkAllRouters() {
 				return
 			}
 
-			hm.checkRouter(r.ID, r.Name)
+			hm.checkRouter(r)
 		}(router)
 	}
 
@@ -160,11 +194,15 @@ func (hm *HealthMonitor) checkAllRouters() {
 }
 
 // checkRouter performs health check on a single router
-func (hm *HealthMonitor) checkRouter(routerID, routerName string) {
+func (hm *HealthMonitor) checkRouter(router models.RouterResponse) {
+	routerID, routerName := router.ID, router.Name
 	startTime := time.Now()
 
 	// Try to test connection (this reuses connection pool)
-	_, err := hm.routerService.TestRouter(routerID, "Administrator")
+	testResult, err := hm.routerService.TestRouter(routerID, "Administrator")
+	if err == nil && testResult.TestResult.Status != "connected" {
+		err = fmt.Errorf("%s", testResult.TestResult.Message)
+	}
 
 	responseTime := time.Since(startTime).Milliseconds()
 
@@ -190,18 +228,29 @@ func (hm *HealthMonitor) checkRouter(routerID, routerName string) {
 	if err != nil {
 		// Connection failed
 		hm.logger.Warnf("Router %s health check failed: %v", routerName, err)
-		hm.updateState(routerID, routerName, false, responseTime, err.Error(), 0, 0, 0, 0)
+		hm.updateState(router, false, responseTime, err.Error(), 0, 0, 0, 0)
 	} else {
 		// Connection successful
 		hm.logger.Debugf("Router %s is healthy (response: %dms)", routerName, responseTime)
-		hm.updateState(routerID, routerName, true, responseTime, "", activeConns, cpuUsage, ramUsage, ramTotal)
+		hm.updateState(router, true, responseTime, "", activeConns, cpuUsage, ramUsage, ramTotal)
 	}
 }
 
 // updateState updates router state and cache
-func (hm *HealthMonitor) updateState(routerID, routerName string, success bool, responseTime int64, errorMsg string, activeConns int, cpuUsage, ramUsage, ramTotal float64) {
+func (hm *HealthMonitor) updateState(router models.RouterResponse, success bool, responseTime int64, errorMsg string, activeConns int, cpuUsage, ramUsage, ramTotal float64) {
+	routerID, routerName := router.ID, router.Name
+
+	// A router's first check restores its counts from history. The query runs
+	// before taking statesMu so it never holds up other routers' updates.
+	hm.statesMu.RLock()
+	_, known := hm.states[routerID]
+	hm.statesMu.RUnlock()
+	var historyChecks, historyFails int64
+	if !known {
+		historyChecks, historyFails = hm.historyCounts(routerID)
+	}
+
 	hm.statesMu.Lock()
-	defer hm.statesMu.Unlock()
 
 	state, exists := hm.states[routerID]
 	if !exists {
@@ -209,6 +258,8 @@ func (hm *HealthMonitor) updateState(routerID, routerName string, success bool,
 			CurrentStatus: "unknown",
 			LastSeenAt:    time.Now(),
 			LastCheckTime: time.Now(),
+			CheckCount:    historyChecks,
+			FailCount:     historyFails,
 		}
 		hm.states[routerID] = state
 	}
@@ -217,14 +268,20 @@ func (hm *HealthMonitor) updateState(routerID, routerName string, success bool,
 	state.CheckCount++
 	state.LastCheckTime = now
 
+	// Set on a transition to or from down, delivered once the lock is released
+	var alert *HealthAlert
+
 	if success {
 		// Reset consecutive failures
 		state.ConsecutiveFails = 0
 		state.LastSeenAt = now
 
 		// Calculate uptime if was down
-		if state.CurrentStatus == "down" && state.DownSince != nil {
-			downtime := now.Sub(*state.DownSince)
+		wasDown := state.CurrentStatus == "down" && state.DownSince != nil
+		var downSince time.Time
+		if wasDown {
+			downSince = *state.DownSince
+			downtime := now.Sub(downSince)
 			state.TotalUptime += downtime
 			state.DownSince = nil
 
@@ -238,19 +295,43 @@ func (hm *HealthMonitor) updateState(routerID, routerName string, success bool,
 			state.CurrentStatus = "healthy"
 		}
 
+		if wasDown {
+			alert = &HealthAlert{
+				RouterName: routerName,
+				Status:     state.CurrentStatus,
+				DownSince:  &downSince,
+				Text: fmt.Sprintf("🟢 Router %s recovered after %v",
+					routerName, now.Sub(downSince).Round(time.Second)),
+			}
+		}
+
 	} else {
 		// Increment failure count
 		state.ConsecutiveFails++
 		state.FailCount++
 
-		// Declare down only after threshold
-		if state.ConsecutiveFails >= hm.failThreshold && state.CurrentStatus != "down" {
+		// Declare down only after threshold (lower for critical routers)
+		threshold := hm.failThreshold
+		if router.Critical {
+			threshold = hm.criticalFailThreshold
+		}
+
+		if state.ConsecutiveFails >= threshold && state.CurrentStatus != "down" {
 			state.CurrentStatus = "down"
 			downSince := now
 			state.DownSince = &downSince
 
 			hm.logger.Errorf("🔴 Router %s is DOWN (failed %d consecutive checks)",
 				routerName, state.ConsecutiveFails)
+
+			alert = &HealthAlert{
+				RouterName:   routerName,
+				Status:       state.CurrentStatus,
+				DownSince:    &downSince,
+				ErrorMessage: errorMsg,
+				Text: fmt.Sprintf("🔴 Router %s is DOWN (failed %d consecutive checks): %s",
+					routerName, state.ConsecutiveFails, errorMsg),
+			}
 		}
 	}
 
@@ -264,6 +345,8 @@ func (hm *HealthMonitor) updateState(routerID, routerName string, success bool,
 	healthStatus := &HealthStatus{
 		RouterID:          routerID,
 		RouterName:        routerName,
+		Critical:          router.Critical,
+		Priority:          router.Priority,
 		Status:            state.CurrentStatus,
 		LastChecked:       now,
 		LastSeen:          state.LastSeenAt,
@@ -279,10 +362,102 @@ func (hm *HealthMonitor) updateState(routerID, routerName string, success bool,
 		RAMUsage:          ramUsage,
 		RAMTotal:          ramTotal,
 	}
+	hm.statesMu.Unlock()
 
 	// Update cache
 	hm.cache.Set(routerID, healthStatus, hm.cacheTTL)
-	hm.logger.Debugf("💾 Cached health data for %s: %s (uptime: %.2f%%)", routerName, state.CurrentStatus, uptimePercent)
+	hm.logger.Debugf("💾 Cached health data for %s: %s (uptime: %.2f%%)", routerName, healthStatus.Status, uptimePercent)
+
+	hm.recordHistory(healthStatus, success)
+	hm.sendAlert(alert)
+}
+
+// historyCounts returns a router's recorded check and failure counts, so
+// uptime survives a restart (zero without history). Must be called without
+// statesMu held.
+func (hm *HealthMonitor) historyCounts(routerID string) (checks, fails int64) {
+	if hm.historyRepo == nil {
+		return 0, 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	checks, fails, err := hm.historyRepo.GetCounts(ctx, routerID)
+	if err != nil {
+		hm.logger.Warnf("⚠️ Failed to load health history for router %s: %v", routerID, err)
+		return 0, 0
+	}
+	return checks, fails
+}
+
+// recordHistory stores a finished check in router_health_history. A failed
+// insert is logged and never affects the check itself.
+func (hm *HealthMonitor) recordHistory(status *HealthStatus, success bool) {
+	if hm.historyRepo == nil {
+		return
+	}
+
+	point := &models.RouterHealthPoint{
+		RouterID:       status.RouterID,
+		Status:         status.Status,
+		Success:        success,
+		ResponseTimeMs: status.ResponseTime,
+		CPUUsage:       status.CPUUsage,
+		RAMUsage:       status.RAMUsage,
+		ErrorMessage:   status.ErrorMessage,
+		CheckedAt:      status.LastChecked,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := hm.historyRepo.Create(ctx, point); err != nil {
+		hm.logger.Warnf("⚠️ Failed to record health check for router %s: %v", status.RouterName, err)
+	}
+}
+
+// GetHistory returns the recorded health checks of a router between from and
+// to, with the share of successful checks in that range
+func (hm *HealthMonitor) GetHistory(ctx context.Context, routerID string, from, to time.Time) (*models.RouterHealthHistory, error) {
+	router, err := hm.routerService.GetRouter(routerID, "Administrator")
+	if err != nil {
+		return nil, err
+	}
+
+	history := &models.RouterHealthHistory{
+		RouterID:   router.ID,
+		RouterName: router.Name,
+		From:       from,
+		To:         to,
+		Points:     []models.RouterHealthPoint{},
+	}
+	if hm.historyRepo == nil {
+		return history, nil
+	}
+
+	points, err := hm.historyRepo.GetRange(ctx, router.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	history.Points = points
+
+	if len(points) > 0 {
+		succeeded := 0
+		for _, point := range points {
+			if point.Success {
+				succeeded++
+			}
+		}
+		history.UptimePercent = float64(succeeded) / float64(len(points)) * 100
+	}
+
+	return history, nil
+}
+
+// SetHistoryRepository enables recording every health check, keeping
+// retentionDays of history (0 = keep forever). Call before Start.
+func (hm *HealthMonitor) SetHistoryRepository(repo HealthHistoryStore, retentionDays int) {
+	hm.historyRepo = repo
+	hm.historyRetentionDays = retentionDays
 }
 
 // GetHealth returns cached health status for a router
@@ -290,7 +465,7 @@ func (hm *HealthMonitor) GetHealth(routerID string) (*HealthStatus, bool) {
 	return hm.cache.Get(routerID)
 }
 
-// GetAllHealth returns health status for all routers
+// GetAllHealth returns health status for all routers, highest priority first
 func (hm *HealthMonitor) GetAllHealth() []*HealthStatus {
 	hm.cache.mu.RLock()
 	defer hm.cache.mu.RUnlock()
@@ -302,6 +477,16 @@ func (hm *HealthMonitor) GetAllHealth() []*HealthStatus {
 		}
 	}
 
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Priority != statuses[j].Priority {
+			return statuses[i].Priority > statuses[j].Priority
+		}
+		if statuses[i].Critical != statuses[j].Critical {
+			return statuses[i].Critical
+		}
+		return statuses[i].RouterName < statuses[j].RouterName
+	})
+
 	return statuses
 }
 
@@ -348,6 +533,37 @@ func (hm *HealthMonitor) cacheCleanupWorker() {
 	}
 }
 
+// historyCleanupWorker removes health history past the retention period
+func (hm *HealthMonitor) historyCleanupWorker() {
+	hm.cleanupOldHistory()
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hm.ctx.Done():
+			return
+		case <-ticker.C:
+			hm.cleanupOldHistory()
+		}
+	}
+}
+
+func (hm *HealthMonitor) cleanupOldHistory() {
+	ctx, cancel := context.WithTimeout(hm.ctx, 30*time.Second)
+	defer cancel()
+
+	deleted, err := hm.historyRepo.DeleteOlderThan(ctx, hm.historyRetentionDays)
+	if err != nil {
+		hm.logger.Warnf("⚠️ Failed to clean up router health history: %v", err)
+		return
+	}
+	if deleted > 0 {
+		hm.logger.Infof("🧹 Deleted %d router health checks older than %d days", deleted, hm.historyRetentionDays)
+	}
+}
+
 func (hm *HealthMonitor) cleanupExpiredCache() {
 	hm.cache.mu.Lock()
 	defer hm.cache.mu.Unlock()
@@ -373,24 +589,40 @@ func (hm *HealthMonitor) getRouterMetrics(routerID string) (*RouterMetrics, erro
 	metrics := &RouterMetrics{}
 
 	// Get router connection from service
-	conn, err := hm.routerService.GetRouterConnection(routerID)
+	conn, err := hm.routerService.GetRouterConnectionByID(routerID)
 	if err != nil {
 		return nil, err
 	}
+	// A failed command may leave the connection out of sync, so drop it then
+	broken := false
+	defer func() {
+		if broken {
+			conn.pool.CloseConnection(conn)
+		} else {
+			conn.pool.ReleaseConnection(conn)
+		}
+	}()
 
 	// Get active PPPoE connections count
-	reply, err := conn.Client.Run("/ppp/active/print")
+	reply, err := conn.RunOp(OpRead, "/ppp/active/print")
 	if err == nil {
 		metrics.ActiveConnections = len(reply.Re)
 		hm.logger.Debugf("🔍 Router %s has %d active PPPoE connections", routerID, metrics.ActiveConnections)
 	} else {
+		broken = isConnectionBroken(err)
 		hm.logger.Warnf("⚠️ Failed to get PPPoE connections for %s: %v", routerID, err)
 	}
 
 	// Get system resources (CPU and RAM)
-	reply, err = conn.Client.Run("/system/resource/print")
-	if err == nil && len(reply.Re) > 0 {
-		resource := reply.Re[0].Map
+	reply, err = conn.RunOp(OpLight, "/system/resource/print")
+	if err != nil {
+		broken = broken || isConnectionBroken(err)
+		hm.logger.Warnf("⚠️ Failed to get system resources for %s: %v", routerID, err)
+	} else {
+		resource, err := firstRow(reply, "/system/resource/print", "cpu-load", "total-memory", "free-memory")
+		if err != nil {
+			return nil, err
+		}
 
 		// Parse CPU usage
 		if cpuLoad, ok := resource["cpu-load"]; ok {
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+// slowHealthHistory has recorded counts per router; GetCounts for blockedID
+// waits until release is closed
+type slowHealthHistory struct {
+	counts    map[string][2]int64
+	blockedID string
+	started   chan struct{}
+	release   chan struct{}
+
+	mu      sync.Mutex
+	created int
+}
+
+func (s *slowHealthHistory) Create(ctx context.Context, point *models.RouterHealthPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created++
+	return nil
+}
+
+func (s *slowHealthHistory) GetRange(ctx context.Context, routerID string, from, to time.Time) ([]models.RouterHealthPoint, error) {
+	return nil, nil
+}
+
+func (s *slowHealthHistory) GetCounts(ctx context.Context, routerID string) (int64, int64, error) {
+	if routerID == s.blockedID {
+		close(s.started)
+		<-s.release
+	}
+	return s.counts[routerID][0], s.counts[routerID][1], nil
+}
+
+func (s *slowHealthHistory) DeleteOlderThan(ctx context.Context, daysToKeep int) (int64, error) {
+	return 0, nil
+}
+
+func TestHealthHistoryQueryDoesNotBlockOtherRouters(t *testing.T) {
+	history := &slowHealthHistory{
+		counts:    map[string][2]int64{"slow": {10, 2}, "fast": {4, 1}},
+		blockedID: "slow",
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+	hm := NewHealthMonitor(quietLogger(), nil)
+	hm.SetHistoryRepository(history, 0)
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		hm.updateState(models.RouterResponse{ID: "slow", Name: "SLOW"}, true, 10, "", 0, 0, 0, 0)
+	}()
+	<-history.started
+
+	// Another router's check goes through while the history query hangs
+	fastDone := make(chan struct{})
+	go func() {
+		defer close(fastDone)
+		hm.updateState(models.RouterResponse{ID: "fast", Name: "FAST"}, false, 0, "timeout", 0, 0, 0, 0)
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(time.Second):
+		close(history.release)
+		t.Fatal("update of another router waited for the history query")
+	}
+
+	close(history.release)
+	<-slowDone
+
+	// Both routers continue from their recorded counts
+	want := map[string][2]int64{"slow": {11, 2}, "fast": {5, 2}}
+	for routerID, counts := range want {
+		status, ok := hm.GetHealth(routerID)
+		if !ok {
+			t.Fatalf("no health cached for %s", routerID)
+		}
+		if status.CheckCount != counts[0] || status.FailCount != counts[1] {
+			t.Errorf("%s: %d checks, %d fails; want %d, %d", routerID, status.CheckCount, status.FailCount, counts[0], counts[1])
+		}
+	}
+}
+
+// unreachableRouters is a health router source whose routers all fail their
+// connection test; it counts the tests per router ID
+type unreachableRouters struct {
+	routers []models.RouterResponse
+
+	mu     sync.Mutex
+	checks map[string]int
+}
+
+func (u *unreachableRouters) GetAllRouters(userRole string) ([]models.RouterResponse, error) {
+	return u.routers, nil
+}
+
+func (u *unreachableRouters) GetRouter(routerID string, userRole string) (*models.RouterResponse, error) {
+	return nil, errors.New("router not found")
+}
+
+func (u *unreachableRouters) TestRouter(routerID string, userRole string) (*models.RouterTestResponse, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.checks[routerID]++
+	return nil, errors.New("connection timed out")
+}
+
+func (u *unreachableRouters) GetRouterConnectionByID(routerID string) (*RouterOSConnection, error) {
+	return nil, errors.New("connection timed out")
+}
+
+func (u *unreachableRouters) count(routerID string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.checks[routerID]
+}
+
+func TestCriticalRoutersCheckedOnShorterInterval(t *testing.T) {
+	source := &unreachableRouters{
+		routers: []models.RouterResponse{{ID: "core", Name: "CORE", Critical: true}, {ID: "branch", Name: "BRANCH"}},
+		checks:  make(map[string]int),
+	}
+	hm := NewHealthMonitor(quietLogger(), source)
+	hm.checkInterval = 200 * time.Millisecond
+	hm.criticalInterval = 20 * time.Millisecond
+	go hm.healthWorker()
+	t.Cleanup(hm.Stop)
+
+	// Wait for the first normal check
+	for deadline := time.Now().Add(2 * time.Second); source.count("branch") == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("normal router never checked")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// By then the critical router had several checks of its own
+	if core := source.count("core"); core < 5 {
+		t.Fatalf("critical router checked %d times before the first normal check, want about 10", core)
+	}
+}
+
+func TestCriticalRoutersAlertAfterFewerFailures(t *testing.T) {
+	var mu sync.Mutex
+	alerted := make(map[string]bool)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert HealthAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("decode alert: %v", err)
+		}
+		mu.Lock()
+		alerted[alert.RouterName] = true
+		mu.Unlock()
+	}))
+	t.Cleanup(webhook.Close)
+	isAlerted := func(name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return alerted[name]
+	}
+	// Alerts are delivered in the background
+	waitForAlert := func(name string) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); !isAlerted(name); {
+			if time.Now().After(deadline) {
+				t.Fatalf("no down alert for %s", name)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	core := models.RouterResponse{ID: "core", Name: "CORE", Critical: true}
+	branch := models.RouterResponse{ID: "branch", Name: "BRANCH"}
+	source := &unreachableRouters{routers: []models.RouterResponse{core, branch}, checks: make(map[string]int)}
+	hm := NewHealthMonitor(quietLogger(), source)
+	hm.SetAlertWebhook(webhook.URL)
+	t.Cleanup(hm.Stop)
+
+	status := func(routerID string) string {
+		t.Helper()
+		health, ok := hm.GetHealth(routerID)
+		if !ok {
+			t.Fatalf("no health cached for %s", routerID)
+		}
+		return health.Status
+	}
+
+	for failures := 1; failures <= hm.failThreshold; failures++ {
+		hm.checkRouter(core)
+		hm.checkRouter(branch)
+
+		wantCore := failures >= hm.criticalFailThreshold
+		wantBranch := failures >= hm.failThreshold
+		if got := status("core") == "down"; got != wantCore {
+			t.Errorf("after %d failure(s): critical router down = %v, want %v", failures, got, wantCore)
+		}
+		if got := status("branch") == "down"; got != wantBranch {
+			t.Errorf("after %d failure(s): normal router down = %v, want %v", failures, got, wantBranch)
+		}
+
+		// The critical router alerts on its first failure, the normal one
+		// only once it reaches its own threshold
+		if failures == hm.criticalFailThreshold {
+			waitForAlert("CORE")
+			if isAlerted("BRANCH") {
+				t.Errorf("normal router alerted after %d failure(s)", failures)
+			}
+		}
+	}
+	waitForAlert("BRANCH")
+}
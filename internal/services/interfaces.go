@@ -1,18 +1,33 @@
 package services
 
 import (
+	"context"
+	"time"
+
 	"nat-management-app/internal/models"
 )
 
 // RouterServiceInterface defines the interface for router management operations
 type RouterServiceInterface interface {
 	GetAllRouters(userRole string) ([]models.RouterResponse, error)
+	ListRouters(userRole string, filter models.RouterListFilter) ([]models.RouterResponse, int, error)
 	GetRouter(routerID string, userRole string) (*models.RouterResponse, error)
 	CreateRouter(req *models.RouterCreateRequest, userRole string) (*models.RouterResponse, error)
 	UpdateRouter(routerID string, req *models.RouterUpdateRequest, userRole string) (*models.RouterResponse, error)
 	DeleteRouter(routerID string, userRole string) error
+	RotateRouterCredentials(routerID string, req *models.RouterCredentialRotateRequest, userRole string) (*models.RouterCredentialRotateResponse, error)
 	TestRouter(routerID string, userRole string) (*models.RouterTestResponse, error)
-	GetRouterStats(userRole string) (*models.RouterStatsResponse, error)
+	TestRouterConfig(config ConnectionConfig) models.RouterConnectionTest
+	GetRouterStats(userRole string, onlyRouters []string, groupByTag bool) (*models.RouterStatsResponse, error)
+	GetRouterLogs(routerID string, filter models.RouterLogFilter, userRole string) (*models.RouterLogsResponse, error)
+	GetConnTrack(routerID, ip string, limit int, userRole string) (*models.ConnTrackResponse, error)
+	GetRouterConnection(ctx context.Context, name string) (*RouterOSConnection, error)
+	GetPoolStats() PoolStats
+	GetRouterCircuit(routerName string) models.RouterCircuit
+	ResetRouterCircuit(routerName string) models.RouterCircuit
+	ExportRouters(userRole string, includePasswords bool) ([]models.RouterCreateRequest, error)
+	ImportRouter(req *models.RouterCreateRequest, overwrite bool, userRole string) (ImportOutcome, error)
+	BackupRouters(fileName string, includePasswords bool, userRole string) (*models.RouterBackupResponse, error)
 	GetRoutersForNATService() (map[string]models.NATRouterConfig, error)
 	ReloadConfiguration() error
 	GetConfigurationPath() string
@@ -21,12 +36,110 @@ type RouterServiceInterface interface {
 // AuthServiceInterface defines the interface for authentication operations
 type AuthServiceInterface interface {
 	Login(username, password, ipAddress, userAgent string) (*models.AuthResponse, error)
-	LoginWithJWT(username, password, ipAddress, userAgent string) (*models.AuthResponse, error)
+	LoginWithJWT(username, password, totpCode, ipAddress, userAgent string) (*models.AuthResponse, error)
 	Logout(sessionID string) error
 	LogoutJWT(accessToken, refreshToken string) error
 	ValidateSession(sessionID string) (*models.User, error)
 	ValidateJWTToken(tokenString string) (*models.User, error)
 	RefreshToken(refreshToken, ipAddress, userAgent string) (*models.AuthResponse, error)
-	RevokeAllUserTokens(userID int) error
+	RevokeAllUserTokens(userID int) (int, error)
+	ListActiveSessions(filter models.ActiveSessionFilter) ([]models.ActiveSession, int, error)
+	RevokeSession(sessionID string) error
 	GetJWTPublicKey() (string, error)
+	IssueStepUpToken(user *models.User, password, ipAddress string) (*models.StepUpToken, error)
+	VerifyStepUp(user *models.User, stepUpToken, password string) error
+	EnrollTOTP(user *models.User) (*models.TOTPEnrollment, error)
+	VerifyTOTP(user *models.User, code string) error
+}
+
+// UserAccessLookup is the part of the user service that router access checks
+// need, so handlers resolving a user's routers can run against a mock
+type UserAccessLookup interface {
+	GetUserRouters(userID int) ([]string, error)
+	GetUserRouterAccess(userID int) ([]string, bool, error)
+}
+
+// RouterUsersLookup is the part of the user service that the router impact
+// preview needs, so it can run against a mock
+type RouterUsersLookup interface {
+	GetRouterUsers(routerName string) ([]UserWithRouters, error)
+}
+
+// UserImporter is the part of the user service that the bulk user import
+// needs, so the import handler can run against a mock
+type UserImporter interface {
+	ImportUser(req *CreateUserRequest, overwrite bool) (ImportOutcome, int, error)
+}
+
+// WiFiInfoStore is where extracted ONT WiFi info is kept, so the change
+// detection in ONTExtractorService.SaveWiFiInfo can run without a database
+type WiFiInfoStore interface {
+	SaveWiFiInfo(ctx context.Context, info *models.ONTWiFiInfo) error
+	FindLatestWiFiInfoByPPPoE(ctx context.Context, pppoeUsername string) (*models.ONTWiFiInfo, error)
+}
+
+// HealthHistoryStore is where router health checks are recorded, so the
+// health monitor can run against a fake instead of router_health_history
+type HealthHistoryStore interface {
+	Create(ctx context.Context, point *models.RouterHealthPoint) error
+	GetRange(ctx context.Context, routerID string, from, to time.Time) ([]models.RouterHealthPoint, error)
+	GetCounts(ctx context.Context, routerID string) (int64, int64, error)
+	DeleteOlderThan(ctx context.Context, daysToKeep int) (int64, error)
+}
+
+// FeatureChecker reports whether a feature flag is on, so flag-gated routes
+// can be tested without the feature_flags table
+type FeatureChecker interface {
+	IsEnabled(name string) bool
+}
+
+// HealthRouterSource is the part of the router service the health monitor
+// uses, so its check schedule can be tested without a database
+type HealthRouterSource interface {
+	GetAllRouters(userRole string) ([]models.RouterResponse, error)
+	GetRouter(routerID string, userRole string) (*models.RouterResponse, error)
+	TestRouter(routerID string, userRole string) (*models.RouterTestResponse, error)
+	GetRouterConnectionByID(routerID string) (*RouterOSConnection, error)
+}
+
+// CustomerLookup resolves PPPoE usernames to customers, so client enrichment
+// can be tested without the customers table
+type CustomerLookup interface {
+	Lookup(ctx context.Context, pppoeUsername string) *models.Customer
+	ResolveMany(ctx context.Context, pppoeUsernames []string) map[string]*models.Customer
+}
+
+// NATServiceInterface defines the NAT, client and PPPoE operations used by the
+// handlers, so they can run against a mock instead of real routers
+type NATServiceInterface interface {
+	ReloadRouters() error
+	InvalidateCache()
+	HasRouter(routerName string) bool
+	GetAvailableRoutersWithFilter(userRole string) []string
+	GetDefaultONTPort(routerName string) string
+
+	GetAllONTConfigs() map[string]models.ONTConfig
+	GetONTConfigsForRouters(routerNames []string) map[string]models.ONTConfig
+	RefreshONTConfigs(routerNames []string) map[string]models.ONTConfig
+	GetONTNATRule(routerName string) (*models.ONTNATRule, error)
+	ListNATRules(routerName string) ([]models.ONTNATRule, error)
+	GetNATRuleByComment(routerName, comment string) (*models.ONTNATRule, error)
+	UpdateONTNATRule(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error)
+	PreviewONTNATRuleUpdate(req *models.NATUpdateRequest) (*models.NATUpdatePreview, error)
+	SetNATRuleEnabled(ctx context.Context, routerName string, enabled bool) error
+	FindNATTargetConflicts(routerNames []string) ([]models.NATTargetConflict, int)
+
+	GetAllClients() (map[string][]models.NATClient, map[string]string)
+	GetClientsForRouters(ctx context.Context, routerNames []string) (map[string][]models.NATClient, map[string]string)
+	CachedRouterClients(routerName string) (clients []models.NATClient, fromCache bool, err error)
+	StreamClients(ctx context.Context, routerNames []string) <-chan models.RouterClients
+	TestAllConnections() map[string]models.RouterConnectionTest
+
+	CheckPPPoEStatus(ctx context.Context, username string, testConnectivity bool, specificRouter ...string) *models.PPPoEStatusResponse
+	CheckPPPoEStatusWithRouterFilter(ctx context.Context, username string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse
+	CheckMultiplePPPoEStatus(ctx context.Context, usernames []string, allowedRouters []string, testConnectivity bool, checkTimeout time.Duration) map[string]*models.PPPoEStatusResponse
+	GetPPPoEHistory(userID int, limit int) ([]models.PPPoESearchHistory, error)
+	DisconnectPPPoEUser(routerName, username string) error
+	FuzzySearchPPPoEWithRouterFilter(searchTerm string, specificRouter string, limit int, allowedRouters []string, includeOffline bool) *models.PPPoEFuzzySearchResponse
+	AuditPPPoEUsernames(ctx context.Context, entries []models.PPPoEAuditEntry, allowedRouters []string) []models.PPPoEAuditRow
 }
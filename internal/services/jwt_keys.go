@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JWTKeyConfig says where the RSA signing key comes from. PrivateKeyPEM wins
+// over PrivateKeyPath; a missing file at PrivateKeyPath is generated once and
+// reused afterwards. With neither set, an ephemeral key is only allowed when
+// AllowEphemeral is true (debug mode).
+type JWTKeyConfig struct {
+	PrivateKeyPath string
+	PrivateKeyPEM  string
+	AllowEphemeral bool
+}
+
+// loadSigningKey returns the RSA key used to sign and verify tokens
+func loadSigningKey(cfg JWTKeyConfig, logger *logrus.Logger) (*rsa.PrivateKey, error) {
+	if cfg.PrivateKeyPEM != "" {
+		key, err := parseRSAPrivateKey([]byte(unescapePEM(cfg.PrivateKeyPEM)))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_PEM tidak valid: %v", err)
+		}
+		logger.Info("🔑 JWT signing key loaded from JWT_PRIVATE_KEY_PEM")
+		return key, nil
+	}
+
+	if cfg.PrivateKeyPath != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err == nil {
+			key, err := parseRSAPrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("JWT private key %s tidak valid: %v", cfg.PrivateKeyPath, err)
+			}
+			logger.Infof("🔑 JWT signing key loaded from %s", cfg.PrivateKeyPath)
+			return key, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("gagal membaca JWT private key %s: %v", cfg.PrivateKeyPath, err)
+		}
+
+		// First run: create the key and keep it for the next start
+		key, err := generateRSAKeyPair()
+		if err != nil {
+			return nil, fmt.Errorf("gagal generate RSA key pair: %v", err)
+		}
+		if err := writeRSAPrivateKey(cfg.PrivateKeyPath, key); err != nil {
+			return nil, fmt.Errorf("gagal menyimpan JWT private key %s: %v", cfg.PrivateKeyPath, err)
+		}
+		logger.Infof("🔑 JWT signing key generated and saved to %s", cfg.PrivateKeyPath)
+		return key, nil
+	}
+
+	if !cfg.AllowEphemeral {
+		return nil, errors.New("JWT signing key not configured: set JWT_PRIVATE_KEY_PATH or JWT_PRIVATE_KEY_PEM")
+	}
+
+	key, err := generateRSAKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("gagal generate RSA key pair: %v", err)
+	}
+	logger.Warn("⚠️ JWT signing key is ephemeral (debug mode): all tokens become invalid on restart")
+	return key, nil
+}
+
+// parseRSAPrivateKey accepts PKCS#1 ("RSA PRIVATE KEY") and PKCS#8 ("PRIVATE KEY") PEM
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+// writeRSAPrivateKey saves key as PKCS#1 PEM readable only by the owner
+func writeRSAPrivateKey(path string, key *rsa.PrivateKey) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	return os.WriteFile(path, data, 0600)
+}
+
+// unescapePEM turns literal "\n" sequences into newlines, for keys passed as a
+// single-line environment variable
+func unescapePEM(value string) string {
+	if strings.Contains(value, "\n") {
+		return value
+	}
+	return strings.ReplaceAll(value, `\n`, "\n")
+}
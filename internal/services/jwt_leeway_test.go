@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseTokenLeeway(t *testing.T) {
+	privateKey, err := generateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	js := &JWTService{logger: quietLogger(), privateKey: privateKey, publicKey: &privateKey.PublicKey}
+
+	sign := func(issuedAt, notBefore, expiresAt time.Time) string {
+		t.Helper()
+		claims := &JWTClaims{
+			UserID:    1,
+			Username:  "admin",
+			TokenType: "access",
+			RegisteredClaims: jwt.RegisteredClaims{
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				NotBefore: jwt.NewNumericDate(notBefore),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	now := time.Now()
+	// Expired 10s ago, or issued by a host whose clock runs 10s ahead
+	expired := sign(now.Add(-time.Hour), now.Add(-time.Hour), now.Add(-10*time.Second))
+	early := sign(now.Add(10*time.Second), now.Add(10*time.Second), now.Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		leeway time.Duration
+		valid  bool
+	}{
+		{"default leeway covers the skew", DefaultJWTLeeway, true},
+		{"leeway shorter than the skew", 5 * time.Second, false},
+		{"no leeway", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js.SetLeeway(tt.leeway)
+			for label, token := range map[string]string{"expired": expired, "not yet valid": early} {
+				parsed, err := js.parseToken(token)
+				if valid := err == nil && parsed.Valid; valid != tt.valid {
+					t.Errorf("%s token with leeway %v: valid = %v (err %v), want %v", label, tt.leeway, valid, err, tt.valid)
+				}
+			}
+		})
+	}
+}
+
+func TestSetLeewayClamps(t *testing.T) {
+	js := &JWTService{logger: quietLogger()}
+	for _, tt := range []struct{ in, want time.Duration }{
+		{-time.Second, 0},
+		{time.Minute, time.Minute},
+		{time.Hour, MaxJWTLeeway},
+	} {
+		js.SetLeeway(tt.in)
+		if js.leeway != tt.want {
+			t.Errorf("SetLeeway(%v) = %v, want %v", tt.in, js.leeway, tt.want)
+		}
+	}
+}
+
+func TestRevocationOutlivesLeeway(t *testing.T) {
+	js := &JWTService{logger: quietLogger(), accessTTL: 15 * time.Minute}
+	js.SetLeeway(30 * time.Second)
+
+	// An access token issued just before revocation still validates for
+	// accessTTL plus the leeway, so the blacklist entry must last that long
+	want := time.Now().Add(15*time.Minute + 30*time.Second)
+	if got := js.revocationExpiry(); got.Before(want) {
+		t.Fatalf("revoked until %v, want at least %v", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"nat-management-app/internal/database"
 	"nat-management-app/internal/models"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -22,11 +24,18 @@ type JWTService struct {
 	logger           *logrus.Logger
 	privateKey       *rsa.PrivateKey
 	publicKey        *rsa.PublicKey
-	refreshTokens    map[string]*models.RefreshToken
-	mutex            sync.RWMutex
+	refreshRepo      *database.RefreshTokenRepository // Refresh tokens live in the database, shared by every instance
 	rateLimiter      *rate.Limiter
-	blacklistedTokens map[string]time.Time
+	blacklistRepo    *database.TokenBlacklistRepository // Revoked JTIs, shared by every instance
+	blacklistedTokens map[string]time.Time // Local copy of revoked JTIs -> token expiry, used when the database is unreachable
 	blacklistMutex   sync.RWMutex
+	revokedSessionRepo *database.RevokedSessionRepository // Revoked session IDs, shared by every instance
+	revokedSessions  map[string]time.Time // Local copy: session ID -> when its last access token expires (guarded by blacklistMutex)
+	lastSeen         map[string]time.Time // session ID -> last authenticated request
+	lastSeenMutex    sync.Mutex
+	leeway           time.Duration // Clock skew tolerated on exp/nbf/iat when validating
+	accessTTL        time.Duration // Access token lifetime
+	refreshTTL       time.Duration // Refresh token (and so session) lifetime
 }
 
 // JWTClaims represents custom JWT claims dengan security enhancements
@@ -53,21 +62,31 @@ type RefreshToken struct {
 	LastUsed  time.Time `json:"last_used"`
 }
 
+// tokenStoreTimeout bounds a single refresh token or blacklist database operation
+const tokenStoreTimeout = 5 * time.Second
+
 // NewJWTService creates a new JWT service dengan keamanan tinggi
-func NewJWTService(logger *logrus.Logger) (*JWTService, error) {
-	// Generate RSA key pair untuk signing
-	privateKey, err := generateRSAKeyPair()
+func NewJWTService(logger *logrus.Logger, db *database.DB, keyConfig JWTKeyConfig) (*JWTService, error) {
+	// Load (or create) the RSA key untuk signing
+	privateKey, err := loadSigningKey(keyConfig, logger)
 	if err != nil {
-		return nil, fmt.Errorf("gagal generate RSA key pair: %v", err)
+		return nil, err
 	}
 
 	service := &JWTService{
 		logger:            logger,
 		privateKey:        privateKey,
 		publicKey:         &privateKey.PublicKey,
-		refreshTokens:     make(map[string]*models.RefreshToken),
+		refreshRepo:       database.NewRefreshTokenRepository(db),
+		blacklistRepo:     database.NewTokenBlacklistRepository(db),
+		revokedSessionRepo: database.NewRevokedSessionRepository(db),
 		rateLimiter:       rate.NewLimiter(rate.Every(time.Minute), 10), // 10 login per menit
 		blacklistedTokens: make(map[string]time.Time),
+		revokedSessions:   make(map[string]time.Time),
+		lastSeen:          make(map[string]time.Time),
+		leeway:            DefaultJWTLeeway,
+		accessTTL:         DefaultAccessTokenTTL,
+		refreshTTL:        DefaultRefreshTokenTTL,
 	}
 
 	// Start cleanup goroutines
@@ -78,6 +97,59 @@ func NewJWTService(logger *logrus.Logger) (*JWTService, error) {
 	return service, nil
 }
 
+// DefaultJWTLeeway is the clock skew tolerated unless SetLeeway changes it
+const DefaultJWTLeeway = 30 * time.Second
+
+// MaxJWTLeeway caps the leeway; a longer one would noticeably extend token lifetimes
+const MaxJWTLeeway = 5 * time.Minute
+
+// SetLeeway sets how much clock skew between issuing and validating hosts is
+// tolerated on the exp, nbf and iat claims (clamped to 0..MaxJWTLeeway)
+func (js *JWTService) SetLeeway(leeway time.Duration) {
+	if leeway < 0 {
+		leeway = 0
+	}
+	if leeway > MaxJWTLeeway {
+		leeway = MaxJWTLeeway
+	}
+	js.leeway = leeway
+	js.logger.Infof("⏱️ JWT clock skew leeway: %v", leeway)
+}
+
+// Token lifetimes used unless SetTokenLifetimes changes them
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// SetTokenLifetimes sets how long access and refresh tokens stay valid. A
+// non-positive value keeps the default.
+func (js *JWTService) SetTokenLifetimes(accessTTL, refreshTTL time.Duration) {
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTokenTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	if refreshTTL < accessTTL {
+		js.logger.Warnf("⚠️ JWT refresh TTL (%v) is shorter than the access TTL (%v)", refreshTTL, accessTTL)
+	}
+	js.accessTTL = accessTTL
+	js.refreshTTL = refreshTTL
+	js.logger.Infof("⏱️ JWT lifetimes: access %v, refresh %v", accessTTL, refreshTTL)
+}
+
+// parseToken verifies an RS256 token signed by this service, applying the leeway
+func (js *JWTService) parseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return js.publicKey, nil
+	}, jwt.WithLeeway(js.leeway))
+}
+
 // generateRSAKeyPair generates a secure RSA key pair
 func generateRSAKeyPair() (*rsa.PrivateKey, error) {
 	// Generate 2048-bit RSA key untuk keamanan yang baik
@@ -90,9 +162,6 @@ func generateRSAKeyPair() (*rsa.PrivateKey, error) {
 
 // GenerateTokenPair creates access dan refresh token pair dengan keamanan tinggi
 func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent string) (*models.TokenPair, error) {
-	js.mutex.Lock()
-	defer js.mutex.Unlock()
-
 	// Check rate limiting
 	if !js.rateLimiter.Allow() {
 		return nil, errors.New("terlalu banyak permintaan login, coba lagi nanti")
@@ -101,7 +170,7 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 	sessionID := js.generateSecureSessionID()
 	now := time.Now()
 
-	// Generate Access Token (short-lived: 15 menit)
+	// Generate Access Token (short-lived)
 	accessClaims := &JWTClaims{
 		UserID:    user.ID,
 		Username:  user.Username,
@@ -111,7 +180,7 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 		UserAgent: userAgent,
 		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(js.accessTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "nat-management-app",
@@ -127,7 +196,7 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 		return nil, fmt.Errorf("gagal generate access token: %v", err)
 	}
 
-	// Generate Refresh Token (long-lived: 7 hari)
+	// Generate Refresh Token (long-lived)
 	refreshClaims := &JWTClaims{
 		UserID:    user.ID,
 		Username:  user.Username,
@@ -137,7 +206,7 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 		UserAgent: userAgent,
 		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(7 * 24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(js.refreshTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "nat-management-app",
@@ -158,13 +227,17 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 		Token:     refreshTokenString,
 		UserID:    user.ID,
 		SessionID: sessionID,
-		ExpiresAt: now.Add(7 * 24 * time.Hour),
+		ExpiresAt: now.Add(js.refreshTTL),
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
 		CreatedAt: now,
 		LastUsed:  now,
 	}
-	js.refreshTokens[refreshTokenString] = refreshTokenData
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+	if err := js.refreshRepo.Create(ctx, refreshTokenData); err != nil {
+		return nil, fmt.Errorf("gagal menyimpan refresh token: %v", err)
+	}
 
 	js.logger.Infof("🔐 JWT token pair generated untuk user: %s (session: %s)", user.Username, sessionID)
 
@@ -178,23 +251,41 @@ func (js *JWTService) GenerateTokenPair(user *models.User, ipAddress, userAgent
 	}, nil
 }
 
-// ValidateAccessToken validates access token dan return claims
-func (js *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
-	// Check if token is blacklisted
-	if js.isTokenBlacklisted(tokenString) {
-		return nil, errors.New("token sudah di-blacklist")
+// SelfTest signs a throwaway token and verifies it with the public key.
+// Nothing is stored, so it is safe to call from health checks.
+func (js *JWTService) SelfTest() error {
+	now := time.Now()
+	claims := &JWTClaims{
+		Username:  "healthcheck",
+		TokenType: "healthcheck",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "nat-management-app",
+			ID:        js.generateSecureJTI(),
+		},
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(js.privateKey)
+	if err != nil {
+		return fmt.Errorf("gagal sign token: %v", err)
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
 		return js.publicKey, nil
 	})
+	if err != nil || !token.Valid {
+		return fmt.Errorf("token tidak valid: %v", err)
+	}
 
+	return nil
+}
+
+// ValidateAccessToken validates access token dan return claims
+func (js *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
+	token, err := js.parseToken(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("token tidak valid: %v", err)
+		return nil, fmt.Errorf("token tidak valid: %w", err)
 	}
 
 	claims, ok := token.Claims.(*JWTClaims)
@@ -202,6 +293,11 @@ func (js *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error
 		return nil, errors.New("token claims tidak valid")
 	}
 
+	// Check if token is blacklisted
+	if js.isTokenBlacklisted(claims.ID) {
+		return nil, errors.New("token sudah di-blacklist")
+	}
+
 	// Validate token type
 	if claims.TokenType != "access" {
 		return nil, errors.New("bukan access token")
@@ -212,27 +308,91 @@ func (js *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error
 		return nil, errors.New("token issuer tidak valid")
 	}
 
+	if js.isSessionRevoked(claims.SessionID) {
+		return nil, errors.New("session sudah direvoke")
+	}
+	js.touchSession(claims.SessionID)
+
 	return claims, nil
 }
 
+// StepUpTokenTTL is how long a step-up confirmation stays valid
+const StepUpTokenTTL = 5 * time.Minute
+
+// GenerateStepUpToken issues a short-lived token proving the user just re-entered
+// their password. It is only accepted by step-up protected routes, never as an
+// access token.
+func (js *JWTService) GenerateStepUpToken(user *models.User, ipAddress string) (*models.StepUpToken, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		TokenType: "step_up",
+		IPAddress: ipAddress,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(StepUpTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "nat-management-app",
+			Subject:   fmt.Sprintf("user:%d", user.ID),
+			ID:        js.generateSecureJTI(),
+			Audience:  []string{"nat-management"},
+		},
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(js.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("gagal generate step-up token: %v", err)
+	}
+
+	return &models.StepUpToken{
+		Token:     tokenString,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ValidateStepUpToken checks that a step-up token is valid and belongs to userID
+func (js *JWTService) ValidateStepUpToken(tokenString string, userID int) error {
+	token, err := js.parseToken(tokenString)
+	if err != nil {
+		return fmt.Errorf("step-up token tidak valid: %v", err)
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return errors.New("step-up token claims tidak valid")
+	}
+	if js.isTokenBlacklisted(claims.ID) {
+		return errors.New("token sudah di-blacklist")
+	}
+	if claims.TokenType != "step_up" {
+		return errors.New("bukan step-up token")
+	}
+	if claims.Issuer != "nat-management-app" {
+		return errors.New("token issuer tidak valid")
+	}
+	if claims.UserID != userID {
+		return errors.New("step-up token milik user lain")
+	}
+
+	return nil
+}
+
+// IsTokenExpired reports whether a token validation error means the token has expired
+func IsTokenExpired(err error) bool {
+	return errors.Is(err, jwt.ErrTokenExpired)
+}
+
 // RefreshAccessToken generates new access token dari refresh token
 func (js *JWTService) RefreshAccessToken(refreshTokenString, ipAddress, userAgent string) (*models.TokenPair, error) {
-	js.mutex.Lock()
-	defer js.mutex.Unlock()
-
 	// Check rate limiting
 	if !js.rateLimiter.Allow() {
 		return nil, errors.New("terlalu banyak permintaan refresh, coba lagi nanti")
 	}
 
 	// Validate refresh token
-	refreshToken, err := jwt.ParseWithClaims(refreshTokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return js.publicKey, nil
-	})
-
+	refreshToken, err := js.parseToken(refreshTokenString)
 	if err != nil {
 		return nil, fmt.Errorf("refresh token tidak valid: %v", err)
 	}
@@ -247,8 +407,13 @@ func (js *JWTService) RefreshAccessToken(refreshTokenString, ipAddress, userAgen
 	}
 
 	// Check if refresh token exists in storage
-	storedToken, exists := js.refreshTokens[refreshTokenString]
-	if !exists {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+	storedToken, err := js.refreshRepo.GetByToken(ctx, refreshTokenString)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca refresh token: %v", err)
+	}
+	if storedToken == nil {
 		return nil, errors.New("refresh token tidak ditemukan")
 	}
 
@@ -259,10 +424,12 @@ func (js *JWTService) RefreshAccessToken(refreshTokenString, ipAddress, userAgen
 	}
 
 	// Update last used
-	storedToken.LastUsed = time.Now()
+	now := time.Now()
+	if err := js.refreshRepo.TouchLastUsed(ctx, refreshTokenString, now); err != nil {
+		js.logger.Warnf("⚠️ Failed to update refresh token last use: %v", err)
+	}
 
 	// Create new access token dengan session yang sama
-	now := time.Now()
 	newAccessClaims := &JWTClaims{
 		UserID:    refreshClaims.UserID,
 		Username:  refreshClaims.Username,
@@ -272,7 +439,7 @@ func (js *JWTService) RefreshAccessToken(refreshTokenString, ipAddress, userAgen
 		UserAgent: userAgent,
 		SessionID: refreshClaims.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(js.accessTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "nat-management-app",
@@ -300,48 +467,160 @@ func (js *JWTService) RefreshAccessToken(refreshTokenString, ipAddress, userAgen
 	}, nil
 }
 
-// RevokeToken menambahkan token ke blacklist
+// RevokeToken menambahkan token ke blacklist, by its JTI until it expires.
+// Tokens that don't verify can never be accepted, so they are ignored.
 func (js *JWTService) RevokeToken(tokenString string) error {
-	js.blacklistMutex.Lock()
-	defer js.blacklistMutex.Unlock()
-
-	// Parse token untuk get expiration
+	// Parse token untuk get JTI dan expiration (an expired token is fine here)
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		return js.publicKey, nil
-	})
-
+	}, jwt.WithoutClaimsValidation())
 	if err != nil {
-		// Even if parsing fails, add to blacklist
-		js.blacklistedTokens[tokenString] = time.Now().Add(24 * time.Hour)
+		js.logger.Debugf("Skipping revocation of unverifiable token: %v", err)
 		return nil
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok {
-		js.blacklistedTokens[tokenString] = claims.ExpiresAt.Time
-	} else {
-		js.blacklistedTokens[tokenString] = time.Now().Add(24 * time.Hour)
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || claims.ID == "" {
+		return errors.New("token tidak memiliki JTI")
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	js.blacklistMutex.Lock()
+	js.blacklistedTokens[claims.ID] = expiresAt
+	js.blacklistMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+	if err := js.blacklistRepo.Add(ctx, claims.ID, expiresAt); err != nil {
+		return err
+	}
+
+	// A revoked refresh token must stop working on every instance
+	if claims.TokenType == "refresh" {
+		if err := js.refreshRepo.DeleteByToken(ctx, tokenString); err != nil {
+			js.logger.Warnf("⚠️ Failed to delete revoked refresh token: %v", err)
+		}
 	}
 
 	js.logger.Infof("🚫 Token revoked dan ditambahkan ke blacklist")
 	return nil
 }
 
-// RevokeAllTokensForUser revoke semua token untuk user tertentu
-func (js *JWTService) RevokeAllTokensForUser(userID int) error {
-	js.mutex.Lock()
-	defer js.mutex.Unlock()
-
-	count := 0
-	for tokenString, refreshToken := range js.refreshTokens {
-		if refreshToken.UserID == userID {
-			delete(js.refreshTokens, tokenString)
-			js.RevokeToken(tokenString)
-			count++
-		}
+// revocationExpiry is how long a revoked session must stay blacklisted: its
+// access tokens are accepted until accessTTL plus the validation leeway
+func (js *JWTService) revocationExpiry() time.Time {
+	return time.Now().Add(js.accessTTL + js.leeway)
+}
+
+// RevokeAllTokensForUser revoke semua token untuk user tertentu: refresh
+// tokens are deleted and access tokens of those sessions stop validating.
+// Returns the number of sessions revoked.
+func (js *JWTService) RevokeAllTokensForUser(userID int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+
+	sessionIDs, err := js.refreshRepo.DeleteByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	count := len(sessionIDs)
+
+	// Once the sessions' access tokens expire the entries are no longer needed
+	revokedUntil := js.revocationExpiry()
+	js.blacklistMutex.Lock()
+	for _, sessionID := range sessionIDs {
+		js.revokedSessions[sessionID] = revokedUntil
+	}
+	js.blacklistMutex.Unlock()
+
+	js.lastSeenMutex.Lock()
+	for _, sessionID := range sessionIDs {
+		delete(js.lastSeen, sessionID)
+	}
+	js.lastSeenMutex.Unlock()
+
+	// Other instances only learn of the revocation from the database
+	if err := js.revokedSessionRepo.Add(ctx, sessionIDs, revokedUntil); err != nil {
+		return count, err
 	}
 
 	js.logger.Infof("🚫 Revoked %d refresh tokens untuk user ID: %d", count, userID)
-	return nil
+	return count, nil
+}
+
+// ListSessions returns one entry per live refresh token session. Username and
+// role are not stored with the token and are left for the caller to fill in.
+func (js *JWTService) ListSessions() ([]models.ActiveSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+
+	tokens, err := js.refreshRepo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	js.lastSeenMutex.Lock()
+	defer js.lastSeenMutex.Unlock()
+
+	sessions := make([]models.ActiveSession, 0, len(tokens))
+	for _, token := range tokens {
+		lastActivity := token.LastUsed
+		if seen, ok := js.lastSeen[token.SessionID]; ok && seen.After(lastActivity) {
+			lastActivity = seen
+		}
+
+		sessions = append(sessions, models.ActiveSession{
+			SessionID:    token.SessionID,
+			UserID:       token.UserID,
+			IPAddress:    token.IPAddress,
+			UserAgent:    token.UserAgent,
+			LoginAt:      token.CreatedAt,
+			LastActivity: lastActivity,
+			ExpiresAt:    token.ExpiresAt,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession removes a session's refresh token and rejects the access tokens
+// already issued for it. Returns false if no live session has that ID.
+func (js *JWTService) RevokeSession(sessionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+
+	deleted, err := js.refreshRepo.DeleteBySession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	// Once the session's access tokens expire the entry is no longer needed
+	revokedUntil := js.revocationExpiry()
+	js.blacklistMutex.Lock()
+	js.revokedSessions[sessionID] = revokedUntil
+	js.blacklistMutex.Unlock()
+
+	js.lastSeenMutex.Lock()
+	delete(js.lastSeen, sessionID)
+	js.lastSeenMutex.Unlock()
+
+	// Other instances only learn of the revocation from the database
+	if err := js.revokedSessionRepo.Add(ctx, []string{sessionID}, revokedUntil); err != nil {
+		return true, err
+	}
+
+	js.logger.Infof("🚫 Session %s revoked", sessionID)
+	return true, nil
 }
 
 // GetUserFromToken extracts user info from valid token
@@ -375,14 +654,60 @@ func (js *JWTService) generateSecureJTI() string {
 	return fmt.Sprintf("%x", bytes)
 }
 
-func (js *JWTService) isTokenBlacklisted(tokenString string) bool {
+// isTokenBlacklisted checks a JTI against the local copy, then the shared
+// table. If the database can't be reached only local revocations apply.
+func (js *JWTService) isTokenBlacklisted(jti string) bool {
 	js.blacklistMutex.RLock()
-	defer js.blacklistMutex.RUnlock()
+	_, exists := js.blacklistedTokens[jti]
+	js.blacklistMutex.RUnlock()
+	if exists {
+		return true
+	}
 
-	_, exists := js.blacklistedTokens[tokenString]
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+	exists, err := js.blacklistRepo.Exists(ctx, jti)
+	if err != nil {
+		js.logger.Warnf("⚠️ Failed to check token blacklist: %v", err)
+		return false
+	}
 	return exists
 }
 
+// isSessionRevoked checks a session against the local copy, then the shared
+// table. If the database can't be reached only local revocations apply.
+func (js *JWTService) isSessionRevoked(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+
+	js.blacklistMutex.RLock()
+	_, revoked := js.revokedSessions[sessionID]
+	js.blacklistMutex.RUnlock()
+	if revoked {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+	defer cancel()
+	revoked, err := js.revokedSessionRepo.Exists(ctx, sessionID)
+	if err != nil {
+		js.logger.Warnf("⚠️ Failed to check revoked sessions: %v", err)
+		return false
+	}
+	return revoked
+}
+
+func (js *JWTService) touchSession(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	js.lastSeenMutex.Lock()
+	js.lastSeen[sessionID] = time.Now()
+	js.lastSeenMutex.Unlock()
+}
+
 // Cleanup goroutines
 
 func (js *JWTService) cleanupExpiredTokens() {
@@ -390,23 +715,32 @@ func (js *JWTService) cleanupExpiredTokens() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		js.mutex.Lock()
-		
-		expiredCount := 0
-		now := time.Now()
-		
-		for tokenString, refreshToken := range js.refreshTokens {
-			if now.After(refreshToken.ExpiresAt) {
-				delete(js.refreshTokens, tokenString)
-				expiredCount++
-			}
-		}
-		
-		if expiredCount > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+
+		expiredCount, err := js.refreshRepo.DeleteExpired(ctx)
+		if err != nil {
+			js.logger.Warnf("⚠️ Failed to clean up expired refresh tokens: %v", err)
+		} else if expiredCount > 0 {
 			js.logger.Infof("🧹 Cleaned up %d expired refresh tokens", expiredCount)
 		}
-		
-		js.mutex.Unlock()
+
+		// Forget activity of sessions that no longer have a refresh token
+		tokens, err := js.refreshRepo.ListActive(ctx)
+		cancel()
+		if err != nil {
+			continue
+		}
+		live := make(map[string]bool, len(tokens))
+		for _, refreshToken := range tokens {
+			live[refreshToken.SessionID] = true
+		}
+		js.lastSeenMutex.Lock()
+		for sessionID := range js.lastSeen {
+			if !live[sessionID] {
+				delete(js.lastSeen, sessionID)
+			}
+		}
+		js.lastSeenMutex.Unlock()
 	}
 }
 
@@ -415,22 +749,36 @@ func (js *JWTService) cleanupBlacklist() {
 	defer ticker.Stop()
 
 	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), tokenStoreTimeout)
+		expiredCount, err := js.blacklistRepo.DeleteExpired(ctx)
+		cancel()
+		if err != nil {
+			js.logger.Warnf("⚠️ Failed to clean up token blacklist: %v", err)
+		} else if expiredCount > 0 {
+			js.logger.Infof("🧹 Cleaned up %d expired blacklisted tokens", expiredCount)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), tokenStoreTimeout)
+		expiredCount, err = js.revokedSessionRepo.DeleteExpired(ctx)
+		cancel()
+		if err != nil {
+			js.logger.Warnf("⚠️ Failed to clean up revoked sessions: %v", err)
+		} else if expiredCount > 0 {
+			js.logger.Infof("🧹 Cleaned up %d expired revoked sessions", expiredCount)
+		}
+
 		js.blacklistMutex.Lock()
-		
-		expiredCount := 0
 		now := time.Now()
-		
-		for tokenString, expiresAt := range js.blacklistedTokens {
+		for jti, expiresAt := range js.blacklistedTokens {
 			if now.After(expiresAt) {
-				delete(js.blacklistedTokens, tokenString)
-				expiredCount++
+				delete(js.blacklistedTokens, jti)
 			}
 		}
-		
-		if expiredCount > 0 {
-			js.logger.Infof("🧹 Cleaned up %d expired blacklisted tokens", expiredCount)
+		for sessionID, expiresAt := range js.revokedSessions {
+			if now.After(expiresAt) {
+				delete(js.revokedSessions, sessionID)
+			}
 		}
-		
 		js.blacklistMutex.Unlock()
 	}
 }
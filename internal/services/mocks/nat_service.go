@@ -0,0 +1,304 @@
+// Package mocks provides handwritten test doubles for the service interfaces.
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+)
+
+// NATService is a services.NATServiceInterface backed by plain data. Set the
+// maps for canned answers, or a *Func field to override one method. Calls that
+// take a router list only return entries for those routers, so access
+// filtering in handlers can be checked without real routers.
+type NATService struct {
+	Routers     map[string]string // router name -> role allowed ("" = every role)
+	ONTConfigs  map[string]models.ONTConfig
+	ONTRules    map[string]*models.ONTNATRule
+	NATRules    map[string][]models.ONTNATRule // All dst-nat rules; defaults to the ONT rule
+	Clients     map[string][]models.NATClient
+	Connections map[string]models.RouterConnectionTest
+	History     []models.PPPoESearchHistory
+	// DefaultONTPorts are routers' own ONT ports; others get the global 80
+	DefaultONTPorts map[string]string
+
+	UpdateONTNATRuleFunc  func(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error)
+	CheckPPPoEFunc        func(username string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse
+	FuzzySearchFunc       func(searchTerm, specificRouter string, limit int, allowedRouters []string, includeOffline bool) *models.PPPoEFuzzySearchResponse
+	AuditFunc             func(ctx context.Context, entries []models.PPPoEAuditEntry, allowedRouters []string) []models.PPPoEAuditRow
+	DisconnectFunc        func(routerName, username string) error
+	SetNATRuleEnabledFunc func(ctx context.Context, routerName string, enabled bool) error
+
+	// ConfigFetches records the routers each ONT config fetch covered
+	ConfigFetches [][]string
+	// Updates records every NAT update request that reached the service
+	Updates []models.NATUpdateRequest
+	// Invalidations counts InvalidateCache calls
+	Invalidations int
+}
+
+var _ services.NATServiceInterface = (*NATService)(nil)
+
+// ReloadRouters is a no-op
+func (m *NATService) ReloadRouters() error {
+	return nil
+}
+
+// InvalidateCache counts the call in Invalidations
+func (m *NATService) InvalidateCache() {
+	m.Invalidations++
+}
+
+// HasRouter reports whether the router is in Routers
+func (m *NATService) HasRouter(routerName string) bool {
+	_, ok := m.Routers[routerName]
+	return ok
+}
+
+// GetAvailableRoutersWithFilter returns the routers open to userRole
+func (m *NATService) GetAvailableRoutersWithFilter(userRole string) []string {
+	var names []string
+	for name, role := range m.Routers {
+		if role == "" || role == userRole {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetDefaultONTPort returns the router's canned ONT port, or 80
+func (m *NATService) GetDefaultONTPort(routerName string) string {
+	if port, ok := m.DefaultONTPorts[routerName]; ok {
+		return port
+	}
+	return "80"
+}
+
+// GetAllONTConfigs returns every canned ONT config
+func (m *NATService) GetAllONTConfigs() map[string]models.ONTConfig {
+	return m.GetONTConfigsForRouters(keys(m.ONTConfigs))
+}
+
+// GetONTConfigsForRouters returns the canned ONT configs of routerNames and
+// records the fetch in ConfigFetches
+func (m *NATService) GetONTConfigsForRouters(routerNames []string) map[string]models.ONTConfig {
+	m.ConfigFetches = append(m.ConfigFetches, append([]string(nil), routerNames...))
+	result := make(map[string]models.ONTConfig)
+	for _, name := range routerNames {
+		if config, ok := m.ONTConfigs[name]; ok {
+			result[name] = config
+		}
+	}
+	return result
+}
+
+// RefreshONTConfigs behaves like GetONTConfigsForRouters
+func (m *NATService) RefreshONTConfigs(routerNames []string) map[string]models.ONTConfig {
+	return m.GetONTConfigsForRouters(routerNames)
+}
+
+// GetONTNATRule returns the canned rule of routerName
+func (m *NATService) GetONTNATRule(routerName string) (*models.ONTNATRule, error) {
+	if rule, ok := m.ONTRules[routerName]; ok {
+		return rule, nil
+	}
+	return nil, fmt.Errorf("router %s tidak ditemukan", routerName)
+}
+
+// ListNATRules returns the canned rules of routerName, or just its ONT rule
+func (m *NATService) ListNATRules(routerName string) ([]models.ONTNATRule, error) {
+	if rules, ok := m.NATRules[routerName]; ok {
+		return rules, nil
+	}
+	rule, err := m.GetONTNATRule(routerName)
+	if err != nil {
+		return nil, err
+	}
+	return []models.ONTNATRule{*rule}, nil
+}
+
+// GetNATRuleByComment returns the listed rule whose comment equals comment
+// ignoring case, or the ONT rule for an empty comment
+func (m *NATService) GetNATRuleByComment(routerName, comment string) (*models.ONTNATRule, error) {
+	if comment == "" {
+		return m.GetONTNATRule(routerName)
+	}
+	rules, err := m.ListNATRules(routerName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if strings.EqualFold(rules[i].Comment, comment) {
+			return &rules[i], nil
+		}
+	}
+	return nil, services.ErrNATRuleNotFound
+}
+
+// UpdateONTNATRule records the request and calls UpdateONTNATRuleFunc if set,
+// otherwise it returns the canned rule (or an empty one) as the previous state
+func (m *NATService) UpdateONTNATRule(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error) {
+	m.Updates = append(m.Updates, *req)
+	if m.UpdateONTNATRuleFunc != nil {
+		return m.UpdateONTNATRuleFunc(ctx, req)
+	}
+	if rule, ok := m.ONTRules[req.Router]; ok {
+		previous := *rule
+		return &previous, nil
+	}
+	return &models.ONTNATRule{Router: req.Router}, nil
+}
+
+// SetNATRuleEnabled calls SetNATRuleEnabledFunc if set, otherwise it flips
+// Disabled on the canned ONT rule of routerName
+func (m *NATService) SetNATRuleEnabled(ctx context.Context, routerName string, enabled bool) error {
+	if m.SetNATRuleEnabledFunc != nil {
+		return m.SetNATRuleEnabledFunc(ctx, routerName, enabled)
+	}
+	rule, err := m.GetONTNATRule(routerName)
+	if err != nil {
+		return err
+	}
+	rule.Disabled = !enabled
+	return nil
+}
+
+// PreviewONTNATRuleUpdate diffs the request against the canned rule
+func (m *NATService) PreviewONTNATRuleUpdate(req *models.NATUpdateRequest) (*models.NATUpdatePreview, error) {
+	rule, err := m.GetONTNATRule(req.Router)
+	if err != nil {
+		return nil, err
+	}
+	after := *rule
+	after.ToAddresses = req.IP
+	if req.Port != "" {
+		after.ToPorts = req.Port
+	}
+
+	var changes []string
+	if after.ToAddresses != rule.ToAddresses {
+		changes = append(changes, "to_addresses")
+	}
+	if after.ToPorts != rule.ToPorts {
+		changes = append(changes, "to_ports")
+	}
+
+	return &models.NATUpdatePreview{
+		Router:      req.Router,
+		Before:      rule,
+		After:       &after,
+		Changes:     changes,
+		WouldChange: len(changes) > 0,
+	}, nil
+}
+
+// FindNATTargetConflicts reports no conflicts
+func (m *NATService) FindNATTargetConflicts(routerNames []string) ([]models.NATTargetConflict, int) {
+	return []models.NATTargetConflict{}, len(routerNames)
+}
+
+// GetAllClients returns every canned client list
+func (m *NATService) GetAllClients() (map[string][]models.NATClient, map[string]string) {
+	return m.GetClientsForRouters(context.Background(), keys(m.Clients))
+}
+
+// GetClientsForRouters returns the canned clients of routerNames
+func (m *NATService) GetClientsForRouters(ctx context.Context, routerNames []string) (map[string][]models.NATClient, map[string]string) {
+	clients := make(map[string][]models.NATClient)
+	for _, name := range routerNames {
+		if list, ok := m.Clients[name]; ok {
+			clients[name] = list
+		}
+	}
+	return clients, map[string]string{}
+}
+
+// CachedRouterClients returns the canned clients of one router, as if read live
+func (m *NATService) CachedRouterClients(routerName string) ([]models.NATClient, bool, error) {
+	return m.Clients[routerName], false, nil
+}
+
+// StreamClients sends the canned clients of routerNames one router at a time
+func (m *NATService) StreamClients(ctx context.Context, routerNames []string) <-chan models.RouterClients {
+	clients, _ := m.GetClientsForRouters(ctx, routerNames)
+	ch := make(chan models.RouterClients, len(clients))
+	for name, list := range clients {
+		ch <- models.RouterClients{Router: name, Clients: list}
+	}
+	close(ch)
+	return ch
+}
+
+// TestAllConnections returns the canned connection results
+func (m *NATService) TestAllConnections() map[string]models.RouterConnectionTest {
+	return m.Connections
+}
+
+// CheckPPPoEStatus searches every router
+func (m *NATService) CheckPPPoEStatus(ctx context.Context, username string, testConnectivity bool, specificRouter ...string) *models.PPPoEStatusResponse {
+	return m.CheckPPPoEStatusWithRouterFilter(ctx, username, specificRouter, testConnectivity)
+}
+
+// CheckPPPoEStatusWithRouterFilter calls CheckPPPoEFunc, or reports the user as not found
+func (m *NATService) CheckPPPoEStatusWithRouterFilter(ctx context.Context, username string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse {
+	if m.CheckPPPoEFunc != nil {
+		return m.CheckPPPoEFunc(username, allowedRouters, testConnectivity)
+	}
+	return &models.PPPoEStatusResponse{Status: "not_found", Username: username}
+}
+
+// CheckMultiplePPPoEStatus runs CheckPPPoEStatusWithRouterFilter for every username
+func (m *NATService) CheckMultiplePPPoEStatus(ctx context.Context, usernames []string, allowedRouters []string, testConnectivity bool, checkTimeout time.Duration) map[string]*models.PPPoEStatusResponse {
+	results := make(map[string]*models.PPPoEStatusResponse, len(usernames))
+	for _, username := range usernames {
+		results[username] = m.CheckPPPoEStatusWithRouterFilter(ctx, username, allowedRouters, testConnectivity)
+	}
+	return results
+}
+
+// GetPPPoEHistory returns userID's entries from History, up to limit
+func (m *NATService) GetPPPoEHistory(userID int, limit int) ([]models.PPPoESearchHistory, error) {
+	history := []models.PPPoESearchHistory{}
+	for _, entry := range m.History {
+		if entry.UserID == userID && len(history) < limit {
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+// DisconnectPPPoEUser calls DisconnectFunc, or reports the user as not online
+func (m *NATService) DisconnectPPPoEUser(routerName, username string) error {
+	if m.DisconnectFunc != nil {
+		return m.DisconnectFunc(routerName, username)
+	}
+	return fmt.Errorf("%w: %s on %s", services.ErrPPPoEUserNotOnline, username, routerName)
+}
+
+// FuzzySearchPPPoEWithRouterFilter calls FuzzySearchFunc, or returns no matches
+func (m *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specificRouter string, limit int, allowedRouters []string, includeOffline bool) *models.PPPoEFuzzySearchResponse {
+	if m.FuzzySearchFunc != nil {
+		return m.FuzzySearchFunc(searchTerm, specificRouter, limit, allowedRouters, includeOffline)
+	}
+	return &models.PPPoEFuzzySearchResponse{Status: "success", SearchTerm: searchTerm}
+}
+
+// AuditPPPoEUsernames calls AuditFunc, or returns no rows
+func (m *NATService) AuditPPPoEUsernames(ctx context.Context, entries []models.PPPoEAuditEntry, allowedRouters []string) []models.PPPoEAuditRow {
+	if m.AuditFunc != nil {
+		return m.AuditFunc(ctx, entries, allowedRouters)
+	}
+	return []models.PPPoEAuditRow{}
+}
+
+func keys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"nat-management-app/internal/services"
+)
+
+// UserAccess is a services.UserAccessLookup backed by plain data
+type UserAccess struct {
+	Assignments map[int][]string // user ID -> user_routers rows
+	NoAccess    map[int]bool     // user ID -> users.no_router_access
+	Err         error            // Returned by every lookup when set
+}
+
+var _ services.UserAccessLookup = (*UserAccess)(nil)
+
+// GetUserRouters returns the user's assigned routers
+func (m *UserAccess) GetUserRouters(userID int) ([]string, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]string{}, m.Assignments[userID]...), nil
+}
+
+// GetUserRouterAccess returns the user's assigned routers and no-access flag
+func (m *UserAccess) GetUserRouterAccess(userID int) ([]string, bool, error) {
+	if m.Err != nil {
+		return nil, false, m.Err
+	}
+	if m.NoAccess[userID] {
+		return []string{}, true, nil
+	}
+	return append([]string{}, m.Assignments[userID]...), false, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import "testing"
+
+func TestGetAllClientsReportsFailingRouters(t *testing.T) {
+	up := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{
+			reSentence("name", "alice", "address", "10.0.0.2", "uptime", "1h"),
+			reSentence("name", "bob", "address", "10.0.0.3", "uptime", "5m"),
+			{"!done"},
+		}
+	})
+	down := newFakeRouter(t, nil)
+	down.setRejectLogin(true)
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{"UP": up, "DOWN": down})
+
+	clients, errs := ns.GetAllClients()
+
+	if len(clients["UP"]) != 2 || errs["UP"] != "" {
+		t.Fatalf("UP: %d clients, error %q; want 2 clients and no error", len(clients["UP"]), errs["UP"])
+	}
+	// A failing router is an empty list plus an error, never "no clients" alone
+	if list, ok := clients["DOWN"]; !ok || list == nil || len(list) != 0 {
+		t.Fatalf("DOWN clients = %#v, want an empty list", list)
+	}
+	if errs["DOWN"] == "" {
+		t.Fatal("DOWN has no error, want the login failure")
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want only DOWN", errs)
+	}
+
+	// The cached result keeps the error map
+	if _, cachedErrs := ns.GetAllClients(); cachedErrs["DOWN"] != errs["DOWN"] {
+		t.Fatalf("cached errors = %v, want %v", cachedErrs, errs)
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+// reloadingRouterService hands out a complete router set per fetch: every
+// router of fetch n has host 10.0.n.x
+type reloadingRouterService struct {
+	RouterServiceInterface
+
+	mu      sync.Mutex
+	fetches int
+}
+
+func (s *reloadingRouterService) ReloadConfiguration() error {
+	return nil
+}
+
+func (s *reloadingRouterService) GetRoutersForNATService() (map[string]models.NATRouterConfig, error) {
+	s.mu.Lock()
+	s.fetches++
+	fetch := s.fetches
+	s.mu.Unlock()
+
+	routers := make(map[string]models.NATRouterConfig)
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("R%d", i)
+		routers[name] = models.NATRouterConfig{Name: name, Host: fmt.Sprintf("10.0.%d.%d", fetch, i)}
+	}
+	return routers, nil
+}
+
+func (s *reloadingRouterService) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func TestReloadRoutersCoalescesConcurrentReloads(t *testing.T) {
+	routerService := &reloadingRouterService{}
+	ns := NewNATService(quietLogger(), routerService, 0)
+	initial := routerService.fetchCount()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ns.ReloadRouters(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The first reload may start before the others arrive; the rest share one
+	if fetches := routerService.fetchCount() - initial; fetches < 1 || fetches > 2 {
+		t.Fatalf("20 concurrent reloads fetched the routers %d times, want 1 or 2", fetches)
+	}
+
+	// The final map is one complete fetch, the latest
+	last := routerService.fetchCount()
+	routers := ns.routerMap()
+	if len(routers) != 5 {
+		t.Fatalf("final map has %d routers, want 5", len(routers))
+	}
+	for name, config := range routers {
+		var fetch, index int
+		if _, err := fmt.Sscanf(config.Host, "10.0.%d.%d", &fetch, &index); err != nil || fetch != last {
+			t.Fatalf("%s has host %s, want one from fetch %d", name, config.Host, last)
+		}
+	}
+}
+
+// Run with -race: readers take router snapshots while reloads swap the map
+func TestReloadRoutersWhileReading(t *testing.T) {
+	ns := NewNATService(quietLogger(), &reloadingRouterService{}, 0)
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if names := ns.GetAvailableRouters(); len(names) != 5 {
+					t.Errorf("GetAvailableRouters returned %d routers, want 5", len(names))
+					return
+				}
+				if !ns.HasRouter("R3") {
+					t.Error("R3 missing during reload")
+					return
+				}
+
+				// Every snapshot is one complete fetch, never a mix
+				fetch := -1
+				for name, config := range ns.routerMap() {
+					var f, index int
+					if _, err := fmt.Sscanf(config.Host, "10.0.%d.%d", &f, &index); err != nil {
+						t.Errorf("%s has host %q", name, config.Host)
+						return
+					}
+					if fetch >= 0 && f != fetch {
+						t.Errorf("snapshot mixes fetches %d and %d", fetch, f)
+						return
+					}
+					fetch = f
+				}
+			}
+		}()
+	}
+
+	var reloads sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		reloads.Add(1)
+		go func() {
+			defer reloads.Done()
+			for j := 0; j < 3; j++ {
+				if err := ns.ReloadRouters(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	reloads.Wait()
+	close(done)
+	readers.Wait()
+}
+
+func TestSetRoutersDropsLocksOfRemovedRouters(t *testing.T) {
+	ns := &NATService{logger: quietLogger()}
+	ns.setRouters(map[string]models.NATRouterConfig{"R1": {Name: "R1"}, "R2": {Name: "R2"}})
+	ns.lockRouter("R1")()
+	ns.lockRouter("R2")()
+
+	ns.setRouters(map[string]models.NATRouterConfig{"R1": {Name: "R1"}})
+
+	if _, ok := ns.routerLocks.Load("R1"); !ok {
+		t.Error("lock of the remaining router R1 was dropped")
+	}
+	if _, ok := ns.routerLocks.Load("R2"); ok {
+		t.Error("lock of the removed router R2 is still held")
+	}
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+func TestGetONTNATRuleNotFound(t *testing.T) {
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{reSentence(".id", "*1", "chain", "dstnat", "action", "dst-nat", "comment", "web server"), {"!done"}}
+	})
+	ns := newTestNATService(t, fr)
+
+	_, err := ns.GetONTNATRule("FAKE")
+	if !errors.Is(err, ErrNATRuleNotFound) {
+		t.Fatalf("err = %v, want ErrNATRuleNotFound", err)
+	}
+
+	err = ns.SetNATRuleEnabled(context.Background(), "FAKE", false)
+	if !errors.Is(err, ErrNATRuleNotFound) || !strings.Contains(err.Error(), "FAKE") {
+		t.Fatalf("err = %v, want ErrNATRuleNotFound naming the router", err)
+	}
+}
+
+func TestSetNATRuleEnabledRouterFailureIsNotNotFound(t *testing.T) {
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		return hangUp
+	})
+	ns := newTestNATService(t, fr)
+
+	err := ns.SetNATRuleEnabled(context.Background(), "FAKE", false)
+	if err == nil {
+		t.Fatal("expected an error from a router that hangs up")
+	}
+	if errors.Is(err, ErrNATRuleNotFound) || strings.Contains(err.Error(), "not found") {
+		t.Fatalf("err = %v, a router failure must not read as a missing rule", err)
+	}
+}
+
+func TestNATRuleReadsDoNotReuseBrokenConnection(t *testing.T) {
+	reads := map[string]func(ns *NATService) error{
+		"ListNATRules": func(ns *NATService) error {
+			_, err := ns.ListNATRules("FAKE")
+			return err
+		},
+		"GetONTNATRule": func(ns *NATService) error {
+			_, err := ns.GetONTNATRule("FAKE")
+			return err
+		},
+	}
+
+	for name, read := range reads {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			fr := newFakeRouter(t, func(command []string) [][]string {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if calls == 1 {
+					return hangUp
+				}
+				return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern), {"!done"}}
+			})
+			ns := newTestNATService(t, fr)
+
+			// The filtered query breaks the connection; the full scan must not
+			// be sent over the client runCommand just closed
+			err := read(ns)
+			if err == nil || !isConnectionBroken(err) {
+				t.Fatalf("err = %v, want the broken connection", err)
+			}
+			if errors.Is(err, net.ErrClosed) {
+				t.Fatalf("err = %v, the closed client was used again", err)
+			}
+
+			// The next read dials a fresh connection
+			if err := read(ns); err != nil {
+				t.Fatalf("read after reconnect: %v", err)
+			}
+		})
+	}
+}
+
+func TestConcurrentNATUpdatesConflict(t *testing.T) {
+	var mu sync.Mutex
+	toAddress, sets := "192.168.1.10", 0
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		if command[0] == "/ip/firewall/nat/set" {
+			time.Sleep(20 * time.Millisecond) // Widen the window for the other update
+			mu.Lock()
+			defer mu.Unlock()
+			sets++
+			for _, word := range command[1:] {
+				if value, ok := strings.CutPrefix(word, "=to-addresses="); ok {
+					toAddress = value
+				}
+			}
+			return [][]string{{"!done"}}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern,
+			"to-addresses", toAddress, "to-ports", "80"), {"!done"}}
+	})
+	ns := newTestNATService(t, fr)
+
+	// Both operators saw the rule at .10 and pick a different new target
+	targets := []string{"192.168.1.20", "192.168.1.30"}
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, ip := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = ns.UpdateONTNATRule(context.Background(), &models.NATUpdateRequest{
+				Router: "FAKE", IP: ip, Port: "80", ExpectedIP: "192.168.1.10",
+			})
+		}()
+	}
+	wg.Wait()
+
+	winner, loser := 0, 1
+	if errs[0] != nil {
+		winner, loser = 1, 0
+	}
+	if errs[winner] != nil {
+		t.Fatalf("both updates failed: %v, %v", errs[0], errs[1])
+	}
+	var conflict *NATRuleConflictError
+	if !errors.As(errs[loser], &conflict) || !errors.Is(errs[loser], ErrNATRuleConflict) {
+		t.Fatalf("second update: err = %v, want a conflict", errs[loser])
+	}
+	if conflict.CurrentIP != targets[winner] {
+		t.Fatalf("conflict reports %s, want the first update's %s", conflict.CurrentIP, targets[winner])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sets != 1 || toAddress != targets[winner] {
+		t.Fatalf("router got %d set(s) and points to %s, want one set to %s", sets, toAddress, targets[winner])
+	}
+}
+
+func TestNATUpdateWithStaleExpectedIP(t *testing.T) {
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		if command[0] == "/ip/firewall/nat/set" {
+			return nil
+		}
+		return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern,
+			"to-addresses", "192.168.1.20", "to-ports", "8080"), {"!done"}}
+	})
+	ns := newTestNATService(t, fr)
+
+	_, err := ns.UpdateONTNATRule(context.Background(), &models.NATUpdateRequest{
+		Router: "FAKE", IP: "192.168.1.30", Port: "80", ExpectedIP: "192.168.1.10",
+	})
+	var conflict *NATRuleConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("err = %v, want a *NATRuleConflictError", err)
+	}
+	if conflict.ExpectedIP != "192.168.1.10" || conflict.CurrentIP != "192.168.1.20" || conflict.CurrentPort != "8080" {
+		t.Fatalf("conflict = %+v, want expected .10, current .20:8080", conflict)
+	}
+	for _, command := range fr.received() {
+		if command[0] == "/ip/firewall/nat/set" {
+			t.Fatalf("stale update still sent %v", command)
+		}
+	}
+}
+
+func TestGetONTNATRuleCustomCommentPatterns(t *testing.T) {
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		// Filtered queries find nothing, so the full scan decides
+		if len(command) > 2 {
+			return nil
+		}
+		return [][]string{
+			reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern, "to-addresses", "192.168.1.1"),
+			reSentence(".id", "*2", "action", "dst-nat", "comment", "web server", "to-addresses", "192.168.1.2"),
+			reSentence(".id", "*3", "action", "dst-nat", "comment", "ont-42", "to-addresses", "192.168.1.3"),
+			{"!done"},
+		}
+	})
+	ns := newTestNATService(t, fr)
+	config, _ := ns.routerConfig("FAKE")
+	config.ONTCommentPatterns = []string{"ONT ACCESS", `/^ont-\d+$/`}
+	ns.setRouters(map[string]models.NATRouterConfig{"FAKE": config})
+
+	rule, err := ns.GetONTNATRule("FAKE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The default marker is just another comment on a router with its own patterns
+	if rule.ID != "*3" || rule.ToAddresses != "192.168.1.3" || !rule.IsONTRule {
+		t.Fatalf("rule = %+v, want *3 matched by the custom regexp", rule)
+	}
+
+	filtered := 0
+	for _, command := range fr.received() {
+		if len(command) > 2 && command[2] == "?comment=ONT ACCESS" {
+			filtered++
+		}
+	}
+	if filtered != 1 {
+		t.Fatalf("commands = %v, want one filtered query for the substring pattern", fr.received())
+	}
+}
+
+func TestPreviewONTNATRuleUpdateNeverWrites(t *testing.T) {
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		if !strings.HasSuffix(command[0], "/print") {
+			return nil
+		}
+		return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern,
+			"to-addresses", "192.168.1.10", "to-ports", "80", "protocol", "tcp"), {"!done"}}
+	})
+	ns := newTestNATService(t, fr)
+
+	preview, err := ns.PreviewONTNATRuleUpdate(&models.NATUpdateRequest{
+		Router: "FAKE", IP: "192.168.1.20", Port: "8080", Protocol: "udp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.WouldChange || preview.Before.ToAddresses != "192.168.1.10" || preview.After.ToAddresses != "192.168.1.20" ||
+		preview.After.ToPorts != "8080" || preview.After.Protocol != "udp" {
+		t.Fatalf("preview = %+v before %+v after %+v", preview, preview.Before, preview.After)
+	}
+	for _, want := range []string{"to_addresses", "to_ports", "protocol"} {
+		if !slices.Contains(preview.Changes, want) {
+			t.Errorf("changes = %v, missing %s", preview.Changes, want)
+		}
+	}
+
+	// Previewing the current target changes nothing
+	same, err := ns.PreviewONTNATRuleUpdate(&models.NATUpdateRequest{Router: "FAKE", IP: "192.168.1.10", Port: "80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.WouldChange || len(same.Changes) != 0 {
+		t.Fatalf("no-op preview = %+v, want no changes", same)
+	}
+
+	for _, command := range fr.received() {
+		if !strings.HasSuffix(command[0], "/print") {
+			t.Fatalf("preview sent a write: %v", command)
+		}
+	}
+	if len(fr.received()) == 0 {
+		t.Fatal("preview never read the rule from the router")
+	}
+}
+
+// ontRuleRouter serves an ONT rule forwarding to toAddress
+func ontRuleRouter(t *testing.T, toAddress string) *fakeRouter {
+	return newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern,
+			"to-addresses", toAddress, "to-ports", "80"), {"!done"}}
+	})
+}
+
+func TestFindNATTargetConflicts(t *testing.T) {
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{
+		"LANE1":  ontRuleRouter(t, "192.168.1.50"),
+		"LANE2":  ontRuleRouter(t, "192.168.1.50"),
+		"SAMSAT": ontRuleRouter(t, "192.168.1.60"),
+		"HIDDEN": ontRuleRouter(t, "192.168.1.60"),
+	})
+
+	// HIDDEN isn't among the caller's routers, so SAMSAT has no conflict
+	conflicts, scanned := ns.FindNATTargetConflicts([]string{"SAMSAT", "LANE2", "LANE1", "GHOST"})
+	if scanned != 3 {
+		t.Errorf("scanned = %d, want the 3 configured routers", scanned)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("conflicts = %+v, want only 192.168.1.50", conflicts)
+	}
+	if conflicts[0].IPAddress != "192.168.1.50" || !slices.Equal(conflicts[0].Routers, []string{"LANE1", "LANE2"}) {
+		t.Fatalf("conflict = %+v, want 192.168.1.50 on LANE1 and LANE2", conflicts[0])
+	}
+
+	conflicts, _ = ns.FindNATTargetConflicts([]string{"SAMSAT", "HIDDEN"})
+	if len(conflicts) != 1 || conflicts[0].IPAddress != "192.168.1.60" {
+		t.Fatalf("conflicts = %+v, want 192.168.1.60 on SAMSAT and HIDDEN", conflicts)
+	}
+}
+
+func TestGetONTNATRuleFilteredQuery(t *testing.T) {
+	// A router with thousands of rules, only one of them the ONT rule
+	allRules := make([][]string, 0, 3001)
+	for i := 0; i < 3000; i++ {
+		allRules = append(allRules, reSentence(".id", fmt.Sprintf("*%X", i+2), "action", "dst-nat",
+			"comment", fmt.Sprintf("customer %d", i), "to-addresses", "10.1.0.1"))
+	}
+	ontRule := reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern, "to-addresses", "192.168.1.10")
+
+	for _, supported := range []bool{true, false} {
+		t.Run(fmt.Sprintf("filter supported %v", supported), func(t *testing.T) {
+			fr := newFakeRouter(t, func(command []string) [][]string {
+				if len(command) > 2 {
+					if !supported {
+						return [][]string{{"!trap", "=message=unknown parameter"}, {"!done"}}
+					}
+					if command[2] == "?comment="+models.DefaultONTCommentPattern {
+						return [][]string{ontRule, {"!done"}}
+					}
+					return nil
+				}
+				return append(append([][]string{}, allRules...), ontRule, []string{"!done"})
+			})
+			ns := newTestNATService(t, fr)
+
+			rule, err := ns.GetONTNATRule("FAKE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule.ID != "*1" || rule.ToAddresses != "192.168.1.10" {
+				t.Fatalf("rule = %+v, want *1 to 192.168.1.10", rule)
+			}
+
+			fullScans := 0
+			for _, command := range fr.received() {
+				if len(command) == 2 {
+					fullScans++
+				}
+			}
+			if supported && fullScans != 0 {
+				t.Fatalf("commands = %d, want the filtered query alone", len(fr.received()))
+			}
+			if !supported && fullScans != 1 {
+				t.Fatalf("%d full scans after the filter was refused, want 1", fullScans)
+			}
+		})
+	}
+}
+
+func TestGetDefaultONTPort(t *testing.T) {
+	ns := &NATService{logger: quietLogger()}
+	ns.setRouters(map[string]models.NATRouterConfig{
+		"CUSTOM":  {Name: "CUSTOM", DefaultONTPort: 8080},
+		"DEFAULT": {Name: "DEFAULT"},
+	})
+
+	for router, want := range map[string]string{"CUSTOM": "8080", "DEFAULT": "80", "GHOST": "80"} {
+		if got := ns.GetDefaultONTPort(router); got != want {
+			t.Errorf("GetDefaultONTPort(%s) = %s, want %s", router, got, want)
+		}
+	}
+}
+
+func TestNATUpdateLeavesOmittedFieldsAlone(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      models.NATUpdateRequest
+		wantSet  []string
+		wantKept []string
+	}{
+		{"target only", models.NATUpdateRequest{IP: "192.168.1.20", Port: "80"},
+			[]string{"=to-addresses=192.168.1.20", "=to-ports=80"}, []string{"=protocol=", "=comment="}},
+		{"protocol", models.NATUpdateRequest{IP: "192.168.1.20", Port: "80", Protocol: "UDP"},
+			[]string{"=protocol=udp"}, []string{"=comment="}},
+		{"comment", models.NATUpdateRequest{IP: "192.168.1.20", Port: "80", Comment: models.DefaultONTCommentPattern + " - Blok A"},
+			[]string{"=comment=" + models.DefaultONTCommentPattern + " - Blok A"}, []string{"=protocol="}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := newFakeRouter(t, func(command []string) [][]string {
+				if command[0] == "/ip/firewall/nat/set" {
+					return nil
+				}
+				return [][]string{reSentence(".id", "*1", "action", "dst-nat", "comment", models.DefaultONTCommentPattern,
+					"to-addresses", "192.168.1.10", "to-ports", "80", "protocol", "tcp"), {"!done"}}
+			})
+			ns := newTestNATService(t, fr)
+
+			req := tt.req
+			req.Router = "FAKE"
+			if _, err := ns.UpdateONTNATRule(context.Background(), &req); err != nil {
+				t.Fatal(err)
+			}
+			var set []string
+			for _, command := range fr.received() {
+				if command[0] == "/ip/firewall/nat/set" {
+					set = command
+				}
+			}
+			for _, want := range tt.wantSet {
+				if !slices.Contains(set, want) {
+					t.Errorf("set = %v, missing %s", set, want)
+				}
+			}
+			for _, word := range set {
+				for _, prefix := range tt.wantKept {
+					if strings.HasPrefix(word, prefix) {
+						t.Errorf("set = %v touches %s although it was omitted", set, prefix)
+					}
+				}
+			}
+		})
+	}
+}
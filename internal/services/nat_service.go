@@ -1,92 +1,271 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"nat-management-app/internal/database"
 	"nat-management-app/internal/models"
+	"nat-management-app/internal/utils/duration"
 
 	"github.com/go-routeros/routeros"
+	"github.com/go-routeros/routeros/proto"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// ontNATRuleProplist limits the fields returned when reading NAT rules
+	ontNATRuleProplist = "=.proplist=.id,chain,action,src-address,dst-address,src-port,dst-port,to-addresses,to-ports,protocol,comment,disabled,bytes,packets"
+)
+
 // CachedData represents cached response with timestamp
 type CachedData struct {
 	Data      interface{}
 	Timestamp time.Time
 }
 
+// cachedClients is the clientsCache payload: per-router clients plus the
+// errors for routers that couldn't be queried (those have an empty slice)
+type cachedClients struct {
+	Clients map[string][]models.NATClient
+	Errors  map[string]string
+}
+
+// cachedONTConfigs is the configsCache payload. Maps are never modified once
+// cached; merges build a new payload. FetchedAt lets errored entries expire
+// sooner than the cache as a whole.
+type cachedONTConfigs struct {
+	Configs   map[string]models.ONTConfig
+	FetchedAt map[string]time.Time
+}
+
+// errorCacheTTL is how long a failed ONT config read is served from cache
+const errorCacheTTL = 5 * time.Second
+
+// isStaleError reports whether the router's cached config is an error older than ttl
+func (c *cachedONTConfigs) isStaleError(routerName string, ttl time.Duration) bool {
+	config, ok := c.Configs[routerName]
+	return ok && config.Error != "" && time.Since(c.FetchedAt[routerName]) >= ttl
+}
+
+// staleErrors lists routers whose cached config is an error older than ttl
+func (c *cachedONTConfigs) staleErrors(ttl time.Duration) []string {
+	var names []string
+	for name := range c.Configs {
+		if c.isStaleError(name, ttl) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // NATService handles NAT management operations
 type NATService struct {
 	logger        *logrus.Logger
-	mutex         sync.RWMutex
-	routers       map[string]models.NATRouterConfig
+	// routers is swapped wholesale on reload and never modified in place, so
+	// a loaded map is a consistent snapshot readers can use without locking
+	routers       atomic.Pointer[map[string]models.NATRouterConfig]
 	routerService RouterServiceInterface
 	// 🔥 CACHE OPTIMIZATION: Response caching untuk faster subsequent requests
 	configsCache  *CachedData
 	clientsCache  *CachedData
 	testCache     *CachedData
+	// lastTest keeps the latest connection test for readiness probes; unlike
+	// testCache it survives invalidateCache, since NAT writes don't change
+	// whether routers are reachable
+	lastTest      *CachedData
 	cacheMutex    sync.RWMutex
 	cacheTTL      time.Duration
+	// Reload coalescing: reloadMu serializes refreshes, reloadRequested counts
+	// callers and reloadCompleted records which request a finished refresh covered
+	reloadMu        sync.Mutex
+	reloadRequested atomic.Uint64
+	reloadCompleted uint64
+	lastReloadErr   error
+	// routerLocks holds a *sync.Mutex per router name for serializing NAT writes
+	routerLocks sync.Map
+	// fuzzyMaxCandidates caps full similarity scoring per router (0 = no cap)
+	fuzzyMaxCandidates int
+	// fuzzyMinSimilarity is the lowest similarity returned by fuzzy search
+	fuzzyMinSimilarity float64
+	// fuzzyAreaPatterns are the lowercase area names scored by patternMatchScore
+	fuzzyAreaPatterns []string
+	// connectivityTestPorts are the TCP ports testDeviceConnectivity tries
+	connectivityTestPorts []string
+	// connectivityTestTimeout bounds each port's connection attempt
+	connectivityTestTimeout time.Duration
+	// throttle rate limits RouterOS commands per router (nil = off)
+	throttle *RouterThrottle
+	// historyRepo records PPPoE status checks (nil = not recorded)
+	historyRepo *database.PPPoEHistoryRepository
 }
 
+// defaultNATCacheTTL is used when NewNATService gets no cache TTL
+const defaultNATCacheTTL = 30 * time.Second
+
+// reloadDebounce lets a burst of admin changes settle into a single refresh
+const reloadDebounce = 200 * time.Millisecond
+
 // NewNATService creates a new NAT service instance with dynamic router loading
-func NewNATService(logger *logrus.Logger, routerService RouterServiceInterface) *NATService {
+func NewNATService(logger *logrus.Logger, routerService RouterServiceInterface, cacheTTL time.Duration) *NATService {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultNATCacheTTL
+	}
 	service := &NATService{
-		logger:        logger,
-		routerService: routerService,
-		routers:       make(map[string]models.NATRouterConfig),
-		cacheTTL:      30 * time.Second, // 🔥 Cache for 30 seconds
+		logger:             logger,
+		routerService:      routerService,
+		cacheTTL:           cacheTTL, // 🔥 Cache configs, clients and tests for this long
+		fuzzyMaxCandidates: defaultFuzzyMaxCandidates,
+		fuzzyMinSimilarity: defaultFuzzyMinSimilarity,
+		fuzzyAreaPatterns:  defaultFuzzyAreaPatterns,
+
+		connectivityTestPorts:   defaultConnectivityTestPorts,
+		connectivityTestTimeout: defaultConnectivityTestTimeout,
 	}
+	service.setRouters(make(map[string]models.NATRouterConfig))
 
 	// Load router configurations from dynamic storage
 	if err := service.loadRoutersFromDynamicStorage(); err != nil {
 		logger.Errorf("Failed to load routers from dynamic storage: %v", err)
 		// Fall back to empty routers map - admin can add routers via UI
-		service.routers = make(map[string]models.NATRouterConfig)
 	}
 
 	return service
 }
 
 // loadRoutersFromDynamicStorage loads router configurations from RouterService
+// The new map is built completely before being swapped in, so readers see
+// either the old or the new set of routers, never a mix
 func (ns *NATService) loadRoutersFromDynamicStorage() error {
-	ns.mutex.Lock()
-	defer ns.mutex.Unlock()
-
 	// Get routers from RouterService in NAT format
 	dynamicRouters, err := ns.routerService.GetRoutersForNATService()
 	if err != nil {
 		return fmt.Errorf("failed to get routers from RouterService: %v", err)
 	}
 
-	// Update the routers map
-	ns.routers = dynamicRouters
-	ns.logger.Infof("✅ Loaded %d routers from dynamic storage", len(ns.routers))
+	// Swap the routers map
+	ns.setRouters(dynamicRouters)
 
+	ns.logger.Infof("✅ Loaded %d routers from dynamic storage", len(dynamicRouters))
 	return nil
 }
 
+// routerMap returns the current router snapshot. Callers that look at more
+// than one router should take it once so they see a single consistent set.
+// The map must not be modified.
+func (ns *NATService) routerMap() map[string]models.NATRouterConfig {
+	if routers := ns.routers.Load(); routers != nil {
+		return *routers
+	}
+	return nil
+}
+
+// setRouters swaps in a new router set and drops the write locks of routers
+// that are gone. A write still holding a dropped lock finishes on it; the
+// router is no longer configured, so later writes fail anyway.
+func (ns *NATService) setRouters(routers map[string]models.NATRouterConfig) {
+	ns.routers.Store(&routers)
+	ns.routerLocks.Range(func(name, _ any) bool {
+		if _, ok := routers[name.(string)]; !ok {
+			ns.routerLocks.Delete(name)
+		}
+		return true
+	})
+}
+
+// routerConfig returns one router's configuration from the current snapshot
+func (ns *NATService) routerConfig(routerName string) (models.NATRouterConfig, bool) {
+	config, exists := ns.routerMap()[routerName]
+	return config, exists
+}
+
 // ReloadRouters reloads router configurations from storage
+// Concurrent calls are coalesced: callers that arrive while a refresh is
+// pending share its result instead of each refetching
 func (ns *NATService) ReloadRouters() error {
-	ns.logger.Infof("🔄 Reloading router configurations...")
+	ticket := ns.reloadRequested.Add(1)
 
+	ns.reloadMu.Lock()
+	defer ns.reloadMu.Unlock()
+
+	// A refresh that started after our request already covered it
+	if ns.reloadCompleted >= ticket {
+		return ns.lastReloadErr
+	}
+
+	time.Sleep(reloadDebounce)
+	covered := ns.reloadRequested.Load()
+
+	ns.logger.Infof("🔄 Reloading router configurations (%d request(s) coalesced)...", covered-ns.reloadCompleted)
+	err := ns.reloadRoutersNow()
+
+	ns.reloadCompleted = covered
+	ns.lastReloadErr = err
+	return err
+}
+
+// reloadRoutersNow performs a single refresh of router configurations
+func (ns *NATService) reloadRoutersNow() error {
 	// Reload RouterService configuration first
 	if err := ns.routerService.ReloadConfiguration(); err != nil {
 		return fmt.Errorf("failed to reload RouterService configuration: %v", err)
 	}
 
 	// Load updated router configurations
-	return ns.loadRoutersFromDynamicStorage()
+	if err := ns.loadRoutersFromDynamicStorage(); err != nil {
+		return err
+	}
+
+	// Cached responses were built from the old router set
+	ns.invalidateCache()
+	return nil
+}
+
+// ConnectRouter borrows a pooled connection to a router from RouterService.
+// When the pool can't provide one it falls back to dialing directly with retry
+// logic. Hand the connection back with releaseRouter when done.
+func (ns *NATService) ConnectRouter(routerName string) (*RouterOSConnection, error) {
+	return ns.connectRouter(context.Background(), routerName)
+}
+
+// connectRouter is ConnectRouter that stops dialing and retrying when ctx is done
+func (ns *NATService) connectRouter(ctx context.Context, routerName string) (*RouterOSConnection, error) {
+	if _, exists := ns.routerConfig(routerName); !exists {
+		return nil, fmt.Errorf("router %s not configured", routerName)
+	}
+
+	if ns.routerService != nil {
+		conn, err := ns.routerService.GetRouterConnection(ctx, routerName)
+		if err == nil {
+			return conn, nil
+		}
+		ns.logger.Debugf("♻️ No pooled connection for %s, dialing directly: %v", routerName, err)
+	}
+
+	return ns.dialRouter(ctx, routerName)
 }
 
-// ConnectRouter establishes connection to a specific router with retry logic
-func (ns *NATService) ConnectRouter(routerName string) (*routeros.Client, error) {
-	config, exists := ns.routers[routerName]
+// releaseRouter returns a pooled connection to its pool, or closes a direct one
+func (ns *NATService) releaseRouter(conn *RouterOSConnection) {
+	if conn.pool != nil {
+		conn.pool.ReleaseConnection(conn)
+		return
+	}
+	conn.Client.Close()
+}
+
+// dialRouter establishes connection to a specific router with retry logic,
+// giving up as soon as ctx is done
+func (ns *NATService) dialRouter(ctx context.Context, routerName string) (*RouterOSConnection, error) {
+	config, exists := ns.routerConfig(routerName)
 	if !exists {
 		return nil, fmt.Errorf("router %s not configured", routerName)
 	}
@@ -103,7 +282,8 @@ func (ns *NATService) ConnectRouter(routerName string) (*routeros.Client, error)
 			attempt, maxRetries, routerName, config.Host, config.Port, timeout)
 
 		// Test TCP connection first
-		conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", config.Host, config.Port), timeout)
+		dialer := &net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
 		if err != nil {
 			lastErr = err
 			ns.logger.Warnf("⚠️  Attempt %d: TCP connection to %s failed: %v", attempt, routerName, err)
@@ -111,201 +291,751 @@ func (ns *NATService) ConnectRouter(routerName string) (*routeros.Client, error)
 			if attempt < maxRetries {
 				backoff := time.Duration(attempt) * 1 * time.Second // Reduced from 2s to 1s
 				ns.logger.Debugf("⏳ Waiting %v before retry...", backoff)
-				time.Sleep(backoff)
+				select {
+				case <-ctx.Done():
+					return nil, newRouterConnectError(routerName, ctx.Err(), false)
+				case <-time.After(backoff):
+				}
 				continue
 			}
 
-			return nil, fmt.Errorf("failed to connect to %s after %d attempts: %v", routerName, maxRetries, lastErr)
+			return nil, newRouterConnectError(routerName, fmt.Errorf("TCP connection failed after %d attempts: %w", maxRetries, lastErr), false)
 		}
 		conn.Close()
 
 		// Try RouterOS API connection
-		client, err := routeros.Dial(fmt.Sprintf("%s:%d", config.Host, config.Port), config.Username, config.Password)
+		var client *routeros.Client
+		netConn, err := dialRouterAPIContext(ctx, config.Host, config.Port, config.UseTLS, config.TLSSkipVerify, timeout)
+		if err == nil {
+			client, err = loginRouterAPI(ctx, netConn, config.Username, config.Password, timeout)
+		}
 		if err != nil {
 			lastErr = err
 			ns.logger.Warnf("⚠️  Attempt %d: RouterOS API auth to %s failed: %v", attempt, routerName, err)
 
+			// A disabled API or bad credentials won't fix themselves on retry
+			connectErr := newRouterConnectError(routerName, err, true)
+			if connectErr.Kind == FailureAPIDisabled || connectErr.Kind == FailureAuthFailed {
+				return nil, connectErr
+			}
+
 			if attempt < maxRetries {
 				backoff := time.Duration(attempt) * 1 * time.Second // Reduced from 2s to 1s
 				ns.logger.Debugf("⏳ Waiting %v before retry...", backoff)
-				time.Sleep(backoff)
+				select {
+				case <-ctx.Done():
+					return nil, newRouterConnectError(routerName, ctx.Err(), true)
+				case <-time.After(backoff):
+				}
 				continue
 			}
 
-			return nil, fmt.Errorf("RouterOS API auth to %s failed after %d attempts: %v", routerName, maxRetries, lastErr)
+			return nil, newRouterConnectError(routerName, fmt.Errorf("RouterOS API auth failed after %d attempts: %w", maxRetries, lastErr), true)
 		}
 
 		ns.logger.Infof("✅ Successfully connected to %s on attempt %d", routerName, attempt)
-		return client, nil
+		now := time.Now()
+		return &RouterOSConnection{
+			Client:     client,
+			RouterName: routerName,
+			LastUsed:   now,
+			InUse:      true,
+			Created:    now,
+			netConn:    netConn,
+		}, nil
 	}
 
 	return nil, fmt.Errorf("unexpected error: failed to connect to %s", routerName)
 }
 
-// GetONTNATRule retrieves the ONT NAT rule with comment 'REMOTE ONT PELANGGAN'
+// ontCommentMatcher returns the matcher for the router's ONT rule comment
+// patterns ("REMOTE ONT PELANGGAN" unless the router configures its own)
+func (ns *NATService) ontCommentMatcher(routerName string) (*models.ONTCommentMatcher, error) {
+	config, _ := ns.routerConfig(routerName)
+	patterns := config.ONTCommentPatterns
+
+	return models.NewONTCommentMatcher(patterns)
+}
+
+// GetONTNATRule retrieves the ONT NAT rule, identified by the router's
+// comment patterns (default: comment containing 'REMOTE ONT PELANGGAN')
 func (ns *NATService) GetONTNATRule(routerName string) (*models.ONTNATRule, error) {
+	matcher, err := ns.ontCommentMatcher(routerName)
+	if err != nil {
+		return nil, err
+	}
+
 	client, err := ns.ConnectRouter(routerName)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
-	// Get firewall NAT rules
-	reply, err := client.Run("/ip/firewall/nat/print", "=.proplist=.id,chain,action,src-address,dst-address,src-port,dst-port,to-addresses,to-ports,protocol,comment,disabled,bytes,packets")
+	// Try server-side filtered queries first so routers with thousands of rules
+	// only send back the matching ones (exact comment match only)
+	for _, comment := range matcher.ExactComments() {
+		reply, err := ns.runCommand(routerName, client, "/ip/firewall/nat/print", ontNATRuleProplist, "?comment="+comment)
+		if errors.Is(err, ErrRouterBusy) {
+			return nil, err
+		}
+		// runCommand already closed a broken connection; don't scan on it
+		if isConnectionBroken(err) {
+			return nil, fmt.Errorf("failed to get NAT rules: %w", err)
+		}
+		if err != nil {
+			ns.logger.Debugf("Filtered NAT query unsupported on %s, falling back to full scan: %v", routerName, err)
+			break
+		}
+		if len(reply.Re) > 0 {
+			ns.logger.Debugf("Filtered NAT query on %s returned %d rule(s)", routerName, len(reply.Re))
+			if rule := ns.findONTNATRule(routerName, matcher, reply.Re); rule != nil {
+				return rule, nil
+			}
+		}
+	}
+
+	// Fallback: fetch all rules and match comment case-insensitively (comment may carry extra text)
+	reply, err := ns.runCommand(routerName, client, "/ip/firewall/nat/print", ontNATRuleProplist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NAT rules: %w", err)
+	}
+	ns.logger.Debugf("Full NAT scan on %s processed %d rule(s)", routerName, len(reply.Re))
+
+	if rule := ns.findONTNATRule(routerName, matcher, reply.Re); rule != nil {
+		return rule, nil
+	}
+
+	return nil, fmt.Errorf("ONT %w", ErrNATRuleNotFound)
+}
+
+// ErrNATRuleNotFound is matched (via errors.Is) when the router answered but
+// has no rule matching the lookup
+var ErrNATRuleNotFound = errors.New("NAT rule not found")
+
+// natRuleLookupError wraps a failed rule lookup on routerName, keeping
+// "not found" apart from the router failing to answer
+func natRuleLookupError(routerName string, err error) error {
+	if errors.Is(err, ErrNATRuleNotFound) {
+		return fmt.Errorf("%w in %s", err, routerName)
+	}
+	return fmt.Errorf("failed to read ONT NAT rule in %s: %w", routerName, err)
+}
+
+// findONTNATRule returns the first sentence whose comment matches the router's ONT rule patterns
+func (ns *NATService) findONTNATRule(routerName string, matcher *models.ONTCommentMatcher, sentences []*proto.Sentence) *models.ONTNATRule {
+	config, _ := ns.routerConfig(routerName)
+	for _, re := range sentences {
+		if !matcher.Matches(re.Map["comment"]) {
+			continue
+		}
+
+		rule := natRuleFromSentence(routerName, config, re)
+		rule.IsONTRule = true
+		return &rule
+	}
+
+	return nil
+}
+
+// natRuleFromSentence converts a /ip/firewall/nat/print reply sentence
+func natRuleFromSentence(routerName string, config models.NATRouterConfig, re *proto.Sentence) models.ONTNATRule {
+	rule := models.ONTNATRule{
+		Router:         routerName,
+		ID:             re.Map[".id"],
+		Chain:          re.Map["chain"],
+		Action:         re.Map["action"],
+		SrcAddress:     re.Map["src-address"],
+		DstAddress:     re.Map["dst-address"],
+		SrcPort:        re.Map["src-port"],
+		DstPort:        re.Map["dst-port"],
+		ToAddresses:    re.Map["to-addresses"],
+		ToPorts:        re.Map["to-ports"],
+		Protocol:       re.Map["protocol"],
+		Comment:        re.Map["comment"],
+		Disabled:       re.Map["disabled"] == "true",
+		TunnelEndpoint: config.TunnelEndpoint,
+		PublicONTURL:   config.PublicONTURL,
+	}
+
+	// Parse bytes and packets
+	if bytes, err := strconv.ParseInt(re.Map["bytes"], 10, 64); err == nil {
+		rule.Bytes = bytes
+	}
+	if packets, err := strconv.ParseInt(re.Map["packets"], 10, 64); err == nil {
+		rule.Packets = packets
+	}
+
+	return rule
+}
+
+// ListNATRules returns every dst-nat rule on the router in router order. The
+// rule matching the router's ONT comment patterns is flagged IsONTRule.
+func (ns *NATService) ListNATRules(routerName string) ([]models.ONTNATRule, error) {
+	matcher, err := ns.ontCommentMatcher(routerName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ns.ConnectRouter(routerName)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.releaseRouter(client)
+
+	// Let the router filter to dst-nat; the action check below covers a full scan
+	reply, err := ns.runCommand(routerName, client, "/ip/firewall/nat/print", ontNATRuleProplist, "?action=dst-nat")
+	if err != nil && !errors.Is(err, ErrRouterBusy) && !isConnectionBroken(err) {
+		ns.logger.Debugf("Filtered NAT query unsupported on %s, falling back to full scan: %v", routerName, err)
+		reply, err = ns.runCommand(routerName, client, "/ip/firewall/nat/print", ontNATRuleProplist)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get NAT rules: %v", err)
+		if errors.Is(err, ErrRouterBusy) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get NAT rules: %w", err)
 	}
 
-	// Find rule with comment "REMOTE ONT PELANGGAN"
+	config, _ := ns.routerConfig(routerName)
+	rules := []models.ONTNATRule{}
+	ontRuleFound := false
 	for _, re := range reply.Re {
-		comment := re.Map["comment"]
-		if strings.Contains(strings.ToUpper(comment), "REMOTE ONT PELANGGAN") {
-			rule := &models.ONTNATRule{
-				Router:         routerName,
-				ID:             re.Map[".id"],
-				Chain:          re.Map["chain"],
-				Action:         re.Map["action"],
-				SrcAddress:     re.Map["src-address"],
-				DstAddress:     re.Map["dst-address"],
-				SrcPort:        re.Map["src-port"],
-				DstPort:        re.Map["dst-port"],
-				ToAddresses:    re.Map["to-addresses"],
-				ToPorts:        re.Map["to-ports"],
-				Protocol:       re.Map["protocol"],
-				Comment:        re.Map["comment"],
-				Disabled:       re.Map["disabled"] == "true",
-				TunnelEndpoint: ns.routers[routerName].TunnelEndpoint,
-				PublicONTURL:   ns.routers[routerName].PublicONTURL,
-			}
+		if re.Map["action"] != "dst-nat" {
+			continue
+		}
+		rule := natRuleFromSentence(routerName, config, re)
+		// Only the first match is the managed rule, as in GetONTNATRule
+		if !ontRuleFound && matcher.Matches(rule.Comment) {
+			rule.IsONTRule = true
+			ontRuleFound = true
+		}
+		rules = append(rules, rule)
+	}
 
-			// Parse bytes and packets
-			if bytes, err := strconv.ParseInt(re.Map["bytes"], 10, 64); err == nil {
-				rule.Bytes = bytes
-			}
-			if packets, err := strconv.ParseInt(re.Map["packets"], 10, 64); err == nil {
-				rule.Packets = packets
-			}
+	return rules, nil
+}
 
-			return rule, nil
+// GetNATRuleByComment returns the dst-nat rule whose comment equals comment,
+// ignoring case. An empty comment returns the ONT rule, as GetONTNATRule does.
+func (ns *NATService) GetNATRuleByComment(routerName, comment string) (*models.ONTNATRule, error) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return ns.GetONTNATRule(routerName)
+	}
+
+	rules, err := ns.ListNATRules(routerName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if strings.EqualFold(strings.TrimSpace(rules[i].Comment), comment) {
+			return &rules[i], nil
+		}
+	}
+
+	return nil, ErrNATRuleNotFound
+}
+
+// GetDefaultONTPort returns the port used when a NAT update doesn't specify one:
+// the router's configured DefaultONTPort, or 80 when unset
+func (ns *NATService) GetDefaultONTPort(routerName string) string {
+	config, exists := ns.routerConfig(routerName)
+
+	if exists && config.DefaultONTPort > 0 {
+		return strconv.Itoa(config.DefaultONTPort)
+	}
+	return "80"
+}
+
+// ErrNATRuleConflict is matched (via errors.Is) when a NAT rule changed underneath an update
+var ErrNATRuleConflict = errors.New("NAT rule changed by another update")
+
+// NATRuleConflictError reports the rule's current target when an update's expectation is stale
+type NATRuleConflictError struct {
+	Router      string
+	ExpectedIP  string
+	CurrentIP   string
+	CurrentPort string
+}
+
+func (e *NATRuleConflictError) Error() string {
+	return fmt.Sprintf("NAT rule in %s now points to %s:%s (expected %s)", e.Router, e.CurrentIP, e.CurrentPort, e.ExpectedIP)
+}
+
+// Is lets errors.Is(err, ErrNATRuleConflict) match
+func (e *NATRuleConflictError) Is(target error) bool {
+	return target == ErrNATRuleConflict
+}
+
+// natRuleProtocols are the protocols accepted for the ONT rule. The rule
+// forwards to a port, and RouterOS only allows to-ports with these.
+var natRuleProtocols = map[string]bool{
+	"tcp": true, "udp": true, "udp-lite": true, "sctp": true, "dccp": true,
+}
+
+// validateNATRuleUpdate checks the optional protocol/comment of a rule update
+func (ns *NATService) validateNATRuleUpdate(req *models.NATUpdateRequest) error {
+	if req.Protocol != "" && !natRuleProtocols[strings.ToLower(req.Protocol)] {
+		return fmt.Errorf("invalid protocol: %s (allowed: tcp, udp, udp-lite, sctp, dccp)", req.Protocol)
+	}
+	// The rule is found by its comment; dropping the marker would orphan it
+	if req.Comment != "" {
+		matcher, err := ns.ontCommentMatcher(req.Router)
+		if err != nil {
+			return err
+		}
+		if !matcher.Matches(req.Comment) {
+			return fmt.Errorf("invalid comment: must match %s", matcher)
 		}
 	}
+	return nil
+}
 
-	return nil, fmt.Errorf("ONT NAT rule not found")
+// applyNATRuleUpdate returns rule as it looks after req, plus the changed
+// fields as RouterOS property names
+func applyNATRuleUpdate(rule models.ONTNATRule, req *models.NATUpdateRequest) (models.ONTNATRule, []string) {
+	changes := []string{}
+	if rule.ToAddresses != req.IP {
+		rule.ToAddresses = req.IP
+		changes = append(changes, "to-addresses")
+	}
+	if rule.ToPorts != req.Port {
+		rule.ToPorts = req.Port
+		changes = append(changes, "to-ports")
+	}
+	if protocol := strings.ToLower(req.Protocol); protocol != "" && rule.Protocol != protocol {
+		rule.Protocol = protocol
+		changes = append(changes, "protocol")
+	}
+	if req.Comment != "" && rule.Comment != req.Comment {
+		rule.Comment = req.Comment
+		changes = append(changes, "comment")
+	}
+	return rule, changes
+}
+
+// lockRouter serializes NAT writes to one router and returns the unlock func
+func (ns *NATService) lockRouter(routerName string) func() {
+	lock, _ := ns.routerLocks.LoadOrStore(routerName, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
 }
 
-// UpdateONTNATRule updates the ONT NAT rule with new IP and port
-// IMPORTANT: Only updates to-addresses, does NOT create new NAT rule
-func (ns *NATService) UpdateONTNATRule(routerName, newIP, newPort string) error {
+// UpdateONTNATRule updates the ONT NAT rule with new IP and port, and
+// optionally its protocol and comment, in a single set call
+// IMPORTANT: Only updates the existing rule, does NOT create new NAT rule
+// ctx carries the request-scoped logger so the update correlates with the API call.
+// Updates to the same router are serialized. When req.ExpectedIP is set and the
+// rule no longer points there, a *NATRuleConflictError is returned instead.
+// On success the rule as it was before the update is returned.
+func (ns *NATService) UpdateONTNATRule(ctx context.Context, req *models.NATUpdateRequest) (*models.ONTNATRule, error) {
+	routerName, newIP, newPort, expectedIP := req.Router, req.IP, req.Port, req.ExpectedIP
+	log := RequestLogger(ctx, ns.logger).WithField("router", routerName)
+
 	if !ns.validateIP(newIP) {
-		return fmt.Errorf("invalid IP address: %s", newIP)
+		return nil, fmt.Errorf("invalid IP address: %s", newIP)
 	}
 
 	if !ns.validatePort(newPort) {
-		return fmt.Errorf("invalid port: %s", newPort)
+		return nil, fmt.Errorf("invalid port: %s", newPort)
 	}
 
-	// Get current rule
+	if err := ns.validateNATRuleUpdate(req); err != nil {
+		return nil, err
+	}
+
+	unlock := ns.lockRouter(routerName)
+	defer unlock()
+
+	// Get current rule (fresh read, inside the lock)
 	currentRule, err := ns.GetONTNATRule(routerName)
 	if err != nil {
-		return fmt.Errorf("ONT NAT rule not found in %s: %v", routerName, err)
+		log.Warnf("⚠️ ONT NAT rule lookup failed: %v", err)
+		return nil, natRuleLookupError(routerName, err)
+	}
+
+	if expectedIP != "" && currentRule.ToAddresses != expectedIP {
+		log.Warnf("⚠️ NAT update conflict: rule points to %s, caller expected %s", currentRule.ToAddresses, expectedIP)
+		return nil, &NATRuleConflictError{
+			Router:      routerName,
+			ExpectedIP:  expectedIP,
+			CurrentIP:   currentRule.ToAddresses,
+			CurrentPort: currentRule.ToPorts,
+		}
 	}
 
 	// Connect and update
 	client, err := ns.ConnectRouter(routerName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
-	// Update existing NAT rule - only change to-addresses and to-ports
-	_, err = client.Run("/ip/firewall/nat/set", "=.id="+currentRule.ID, "=to-addresses="+newIP, "=to-ports="+newPort)
+	// Update existing NAT rule - to-addresses and to-ports always, protocol
+	// and comment only when given
+	after, changes := applyNATRuleUpdate(*currentRule, req)
+	args := []string{"/ip/firewall/nat/set", "=.id=" + currentRule.ID, "=to-addresses=" + newIP, "=to-ports=" + newPort}
+	if req.Protocol != "" {
+		args = append(args, "=protocol="+after.Protocol)
+	}
+	if req.Comment != "" {
+		args = append(args, "=comment="+after.Comment)
+	}
+
+	_, err = ns.runCommand(routerName, client, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update NAT rule: %v", err)
+		return nil, fmt.Errorf("failed to update NAT rule: %w", err)
 	}
 
 	// 🔥 Invalidate cache after update
 	ns.invalidateCache()
 
-	ns.logger.Infof("✓ ONT NAT rule updated in %s: %s:%s", routerName, newIP, newPort)
+	log.WithFields(logrus.Fields{
+		"changes": changes,
+		"before":  fmt.Sprintf("%s:%s/%s %q", currentRule.ToAddresses, currentRule.ToPorts, currentRule.Protocol, currentRule.Comment),
+		"after":   fmt.Sprintf("%s:%s/%s %q", after.ToAddresses, after.ToPorts, after.Protocol, after.Comment),
+	}).Infof("✓ ONT NAT rule updated in %s: %s:%s", routerName, newIP, newPort)
+	return currentRule, nil
+}
+
+// SetNATRuleEnabled enables or disables the ONT NAT rule without touching its
+// target, to cut remote ONT access temporarily. A rule already in the wanted
+// state is left alone. Serialized with other NAT writes to the router.
+func (ns *NATService) SetNATRuleEnabled(ctx context.Context, routerName string, enabled bool) error {
+	log := RequestLogger(ctx, ns.logger).WithField("router", routerName)
+
+	unlock := ns.lockRouter(routerName)
+	defer unlock()
+
+	currentRule, err := ns.GetONTNATRule(routerName)
+	if err != nil {
+		log.Warnf("⚠️ ONT NAT rule lookup failed: %v", err)
+		return natRuleLookupError(routerName, err)
+	}
+
+	if currentRule.Disabled == !enabled {
+		log.Infof("ONT NAT rule in %s already %s", routerName, natRuleState(enabled))
+		return nil
+	}
+
+	client, err := ns.ConnectRouter(routerName)
+	if err != nil {
+		return err
+	}
+	defer ns.releaseRouter(client)
+
+	disabled := "yes"
+	if enabled {
+		disabled = "no"
+	}
+	_, err = ns.runCommand(routerName, client, "/ip/firewall/nat/set", "=.id="+currentRule.ID, "=disabled="+disabled)
+	if err != nil {
+		return fmt.Errorf("failed to update NAT rule: %w", err)
+	}
+
+	ns.invalidateCache()
+
+	log.Infof("✓ ONT NAT rule in %s %s (%s:%s)", routerName, natRuleState(enabled), currentRule.ToAddresses, currentRule.ToPorts)
 	return nil
 }
 
+// natRuleState names a rule's enabled state for log messages
+func natRuleState(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// PreviewONTNATRuleUpdate projects an ONT NAT rule update without applying it
+// Only reads from the router; the RouterOS set command is never run
+func (ns *NATService) PreviewONTNATRuleUpdate(req *models.NATUpdateRequest) (*models.NATUpdatePreview, error) {
+	routerName := req.Router
+	if !ns.validateIP(req.IP) {
+		return nil, fmt.Errorf("invalid IP address: %s", req.IP)
+	}
+
+	if !ns.validatePort(req.Port) {
+		return nil, fmt.Errorf("invalid port: %s", req.Port)
+	}
+
+	if err := ns.validateNATRuleUpdate(req); err != nil {
+		return nil, err
+	}
+
+	currentRule, err := ns.GetONTNATRule(routerName)
+	if err != nil {
+		return nil, natRuleLookupError(routerName, err)
+	}
+
+	after, ruleChanges := applyNATRuleUpdate(*currentRule, req)
+
+	// Preview reports changes using the JSON field names
+	changes := make([]string, 0, len(ruleChanges))
+	for _, change := range ruleChanges {
+		changes = append(changes, strings.ReplaceAll(change, "-", "_"))
+	}
+
+	return &models.NATUpdatePreview{
+		Router:      routerName,
+		Before:      currentRule,
+		After:       &after,
+		Changes:     changes,
+		WouldChange: len(changes) > 0,
+	}, nil
+}
+
 // GetAllONTConfigs retrieves ONT NAT configurations from all routers
 // ⚡ OPTIMIZED: Parallel execution with goroutines for faster response
-// 🔥 CACHE OPTIMIZATION: Return cached data if still fresh (30s TTL)
+// 🔥 CACHE OPTIMIZATION: Return cached data if still fresh (30s TTL); errored
+// routers are re-checked after errorCacheTTL so a recovered router shows up quickly
 func (ns *NATService) GetAllONTConfigs() map[string]models.ONTConfig {
 	// Check cache first
-	ns.cacheMutex.RLock()
-	if ns.configsCache != nil && time.Since(ns.configsCache.Timestamp) < ns.cacheTTL {
-		cached := ns.configsCache.Data.(map[string]models.ONTConfig)
-		ns.cacheMutex.RUnlock()
-		ns.logger.Debugf("⚡ Returning cached ONT configs (age: %v)", time.Since(ns.configsCache.Timestamp))
-		return cached
+	if cache, age := ns.freshONTConfigCache(); cache != nil {
+		retry := cache.staleErrors(errorCacheTTL)
+		if len(retry) == 0 {
+			ns.logger.Debugf("⚡ Returning cached ONT configs (age: %v)", age)
+			return cache.Configs
+		}
+		ns.logger.Debugf("🔁 Re-checking %d errored router(s) in cached ONT configs", len(retry))
+		return ns.mergeONTConfigCache(cache, ns.fetchONTConfigs(retry))
 	}
-	ns.cacheMutex.RUnlock()
 
 	// Cache miss or expired - fetch fresh data
+	routers := ns.routerMap()
+	routerNames := make([]string, 0, len(routers))
+	for routerName := range routers {
+		routerNames = append(routerNames, routerName)
+	}
+
+	ns.logger.Debugf("🚀 Starting parallel ONT config fetch for %d routers", len(routerNames))
+	startTime := time.Now()
+
+	configs := ns.fetchONTConfigs(routerNames)
+
+	elapsed := time.Since(startTime)
+	ns.logger.Infof("✅ Parallel ONT config fetch completed in %v for %d routers", elapsed, len(configs))
+
+	// Update cache
+	now := time.Now()
+	fetchedAt := make(map[string]time.Time, len(configs))
+	for name := range configs {
+		fetchedAt[name] = now
+	}
+	ns.cacheMutex.Lock()
+	ns.configsCache = &CachedData{
+		Data:      &cachedONTConfigs{Configs: configs, FetchedAt: fetchedAt},
+		Timestamp: now,
+	}
+	ns.cacheMutex.Unlock()
+
+	return configs
+}
+
+// GetONTConfigsForRouters fetches ONT configs for only the given routers,
+// serving from the all-routers cache while it's fresh (errored entries only
+// for errorCacheTTL)
+func (ns *NATService) GetONTConfigsForRouters(routerNames []string) map[string]models.ONTConfig {
+	cache, _ := ns.freshONTConfigCache()
+
+	configs := make(map[string]models.ONTConfig)
+	var fetch []string
+	for _, routerName := range routerNames {
+		if cache != nil {
+			if config, ok := cache.Configs[routerName]; ok && !cache.isStaleError(routerName, errorCacheTTL) {
+				configs[routerName] = config
+				continue
+			}
+		}
+		if !ns.HasRouter(routerName) {
+			continue
+		}
+		fetch = append(fetch, routerName)
+	}
+
+	fetched := ns.fetchONTConfigs(fetch)
+	for name, config := range fetched {
+		configs[name] = config
+	}
+	if cache != nil && len(fetched) > 0 {
+		ns.mergeONTConfigCache(cache, fetched)
+	}
+	return configs
+}
+
+// RefreshONTConfigs re-reads the given routers' ONT configs, bypassing the
+// cache (?fresh=true), and writes the results back into a still-fresh cache
+func (ns *NATService) RefreshONTConfigs(routerNames []string) map[string]models.ONTConfig {
+	var fetch []string
+	for _, routerName := range routerNames {
+		if ns.HasRouter(routerName) {
+			fetch = append(fetch, routerName)
+		}
+	}
+
+	fetched := ns.fetchONTConfigs(fetch)
+	if cache, _ := ns.freshONTConfigCache(); cache != nil && len(fetched) > 0 {
+		ns.mergeONTConfigCache(cache, fetched)
+	}
+	ns.logger.Debugf("🔄 Refreshed ONT configs for %d router(s), cache bypassed", len(fetched))
+	return fetched
+}
+
+// fetchONTConfigs reads the ONT configs of the given routers in parallel
+func (ns *NATService) fetchONTConfigs(routerNames []string) map[string]models.ONTConfig {
 	configs := make(map[string]models.ONTConfig)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	ns.logger.Debugf("🚀 Starting parallel ONT config fetch for %d routers", len(ns.routers))
-	startTime := time.Now()
+	for _, routerName := range routerNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			config := ns.getONTConfig(name)
+
+			mu.Lock()
+			configs[name] = config
+			mu.Unlock()
+		}(routerName)
+	}
+
+	wg.Wait()
+	return configs
+}
+
+// freshONTConfigCache returns the ONT config cache and its age, or nil when
+// it's empty or older than cacheTTL
+func (ns *NATService) freshONTConfigCache() (*cachedONTConfigs, time.Duration) {
+	ns.cacheMutex.RLock()
+	defer ns.cacheMutex.RUnlock()
+
+	if ns.configsCache == nil {
+		return nil, 0
+	}
+	age := time.Since(ns.configsCache.Timestamp)
+	if age >= ns.cacheTTL {
+		return nil, 0
+	}
+	return ns.configsCache.Data.(*cachedONTConfigs), age
+}
+
+// mergeONTConfigCache returns base with fetched applied on top. If base is
+// still the cached payload it is replaced by the merged copy; the cache keeps
+// its original timestamp so successful entries still expire after cacheTTL.
+func (ns *NATService) mergeONTConfigCache(base *cachedONTConfigs, fetched map[string]models.ONTConfig) map[string]models.ONTConfig {
+	now := time.Now()
+	merged := &cachedONTConfigs{
+		Configs:   make(map[string]models.ONTConfig, len(base.Configs)+len(fetched)),
+		FetchedAt: make(map[string]time.Time, len(base.Configs)+len(fetched)),
+	}
+	for name, config := range base.Configs {
+		merged.Configs[name] = config
+		merged.FetchedAt[name] = base.FetchedAt[name]
+	}
+	for name, config := range fetched {
+		merged.Configs[name] = config
+		merged.FetchedAt[name] = now
+	}
+
+	ns.cacheMutex.Lock()
+	if ns.configsCache != nil && ns.configsCache.Data == base {
+		ns.configsCache = &CachedData{Data: merged, Timestamp: ns.configsCache.Timestamp}
+	}
+	ns.cacheMutex.Unlock()
+
+	return merged.Configs
+}
+
+// getONTConfig reads one router's ONT NAT rule as an ONTConfig
+func (ns *NATService) getONTConfig(routerName string) models.ONTConfig {
+	rule, err := ns.GetONTNATRule(routerName)
+	if err != nil {
+		return models.ONTConfig{
+			Found:   false,
+			Error:   err.Error(),
+			Message: "ONT NAT rule not found",
+		}
+	}
+
+	status := "enabled"
+	if rule.Disabled {
+		status = "disabled"
+	}
 
-	for routerName := range ns.routers {
-		wg.Add(1)
-		go func(name string) {
-			defer wg.Done()
+	return models.ONTConfig{
+		Found:          true,
+		CurrentIP:      rule.ToAddresses,
+		CurrentPort:    rule.ToPorts,
+		DstAddress:     rule.DstAddress,
+		DstPort:        rule.DstPort,
+		Protocol:       rule.Protocol,
+		Status:         status,
+		Comment:        rule.Comment,
+		TunnelEndpoint: rule.TunnelEndpoint,
+		PublicONTURL:   rule.PublicONTURL,
+		Bytes:          rule.Bytes,
+		Packets:        rule.Packets,
+	}
+}
 
-			rule, err := ns.GetONTNATRule(name)
+// FindNATTargetConflicts reports to-addresses values that more than one of the
+// given routers' ONT rules point at. Uses the (cached) ONT configs.
+func (ns *NATService) FindNATTargetConflicts(routerNames []string) ([]models.NATTargetConflict, int) {
+	configs := ns.GetAllONTConfigs()
 
-			mu.Lock()
-			defer mu.Unlock()
+	byIP := make(map[string][]string)
+	scanned := 0
+	for _, name := range routerNames {
+		config, exists := configs[name]
+		if !exists {
+			continue
+		}
+		scanned++
+		if !config.Found || config.CurrentIP == "" {
+			continue
+		}
+		byIP[config.CurrentIP] = append(byIP[config.CurrentIP], name)
+	}
 
-			if err != nil {
-				configs[name] = models.ONTConfig{
-					Found:   false,
-					Error:   err.Error(),
-					Message: "ONT NAT rule not found",
-				}
-				return
-			}
+	conflicts := []models.NATTargetConflict{}
+	for ip, routers := range byIP {
+		if len(routers) < 2 {
+			continue
+		}
+		sort.Strings(routers)
+		conflicts = append(conflicts, models.NATTargetConflict{
+			IPAddress: ip,
+			Routers:   routers,
+		})
+	}
 
-			status := "enabled"
-			if rule.Disabled {
-				status = "disabled"
-			}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].IPAddress < conflicts[j].IPAddress
+	})
 
-			configs[name] = models.ONTConfig{
-				Found:          true,
-				CurrentIP:      rule.ToAddresses,
-				CurrentPort:    rule.ToPorts,
-				DstAddress:     rule.DstAddress,
-				DstPort:        rule.DstPort,
-				Protocol:       rule.Protocol,
-				Status:         status,
-				Comment:        rule.Comment,
-				TunnelEndpoint: rule.TunnelEndpoint,
-				PublicONTURL:   rule.PublicONTURL,
-				Bytes:          rule.Bytes,
-				Packets:        rule.Packets,
-			}
-		}(routerName)
+	if len(conflicts) > 0 {
+		ns.logger.Warnf("⚠️ Found %d NAT target conflict(s) across %d routers", len(conflicts), scanned)
 	}
 
-	wg.Wait()
-	elapsed := time.Since(startTime)
-	ns.logger.Infof("✅ Parallel ONT config fetch completed in %v for %d routers", elapsed, len(configs))
+	return conflicts, scanned
+}
 
-	// Update cache
-	ns.cacheMutex.Lock()
-	ns.configsCache = &CachedData{
-		Data:      configs,
-		Timestamp: time.Now(),
+// CachedRouterClients returns a router's online clients from the clients cache
+// while it is fresh, otherwise it queries the router. fromCache reports which.
+func (ns *NATService) CachedRouterClients(routerName string) (clients []models.NATClient, fromCache bool, err error) {
+	ns.cacheMutex.RLock()
+	if ns.clientsCache != nil && time.Since(ns.clientsCache.Timestamp) < ns.cacheTTL {
+		cached := ns.clientsCache.Data.(cachedClients)
+		if list, ok := cached.Clients[routerName]; ok && cached.Errors[routerName] == "" {
+			ns.cacheMutex.RUnlock()
+			return list, true, nil
+		}
 	}
-	ns.cacheMutex.Unlock()
+	ns.cacheMutex.RUnlock()
 
-	return configs
+	clients, err = ns.GetRouterClients(routerName)
+	return clients, false, err
 }
 
 // GetRouterClients retrieves online clients from a specific router
@@ -314,12 +1044,12 @@ func (ns *NATService) GetRouterClients(routerName string) ([]models.NATClient, e
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
 	// Get PPPoE active connections
-	reply, err := client.Run("/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding")
+	reply, err := ns.runCommand(routerName, client, "/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get active connections: %v", err)
+		return nil, fmt.Errorf("failed to get active connections: %w", err)
 	}
 
 	var clients []models.NATClient
@@ -331,10 +1061,15 @@ func (ns *NATService) GetRouterClients(routerName string) ([]models.NATClient, e
 			CallerID:  re.Map["caller-id"],
 			Uptime:    re.Map["uptime"],
 			Encoding:  re.Map["encoding"],
+
+			UptimeSeconds: duration.RouterOSSeconds(re.Map["uptime"]),
 		}
 		clients = append(clients, client)
 	}
 
+	// RouterOS reply order is arbitrary; keep a stable default order
+	SortNATClients(clients, models.ClientSortUsername, false)
+
 	ns.logger.Infof("Retrieved %d clients from %s", len(clients), routerName)
 	return clients, nil
 }
@@ -342,26 +1077,30 @@ func (ns *NATService) GetRouterClients(routerName string) ([]models.NATClient, e
 // GetAllClients retrieves online clients from all routers
 // ⚡ OPTIMIZED: Parallel execution with goroutines for faster response
 // 🔥 CACHE OPTIMIZATION: Return cached data if still fresh (30s TTL)
-func (ns *NATService) GetAllClients() map[string][]models.NATClient {
+// Routers that fail get an empty slice in clients and their error in errors,
+// so callers can tell "no clients" from "couldn't reach router".
+func (ns *NATService) GetAllClients() (map[string][]models.NATClient, map[string]string) {
 	// Check cache first
 	ns.cacheMutex.RLock()
 	if ns.clientsCache != nil && time.Since(ns.clientsCache.Timestamp) < ns.cacheTTL {
-		cached := ns.clientsCache.Data.(map[string][]models.NATClient)
+		cached := ns.clientsCache.Data.(cachedClients)
 		ns.cacheMutex.RUnlock()
 		ns.logger.Debugf("⚡ Returning cached clients (age: %v)", time.Since(ns.clientsCache.Timestamp))
-		return cached
+		return cached.Clients, cached.Errors
 	}
 	ns.cacheMutex.RUnlock()
 
 	// Cache miss or expired - fetch fresh data
 	allClients := make(map[string][]models.NATClient)
+	fetchErrors := make(map[string]string)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	ns.logger.Debugf("🚀 Starting parallel client fetch for %d routers", len(ns.routers))
+	routers := ns.routerMap()
+	ns.logger.Debugf("🚀 Starting parallel client fetch for %d routers", len(routers))
 	startTime := time.Now()
 
-	for routerName := range ns.routers {
+	for routerName := range routers {
 		wg.Add(1)
 		go func(name string) {
 			defer wg.Done()
@@ -374,6 +1113,7 @@ func (ns *NATService) GetAllClients() map[string][]models.NATClient {
 			if err != nil {
 				ns.logger.Errorf("Failed to get clients from %s: %v", name, err)
 				allClients[name] = []models.NATClient{}
+				fetchErrors[name] = err.Error()
 				return
 			}
 			allClients[name] = clients
@@ -388,33 +1128,107 @@ func (ns *NATService) GetAllClients() map[string][]models.NATClient {
 		totalClients += len(clients)
 	}
 
-	ns.logger.Infof("✅ Parallel client fetch completed in %v: %d clients from %d routers", elapsed, totalClients, len(allClients))
+	ns.logger.Infof("✅ Parallel client fetch completed in %v: %d clients from %d routers (%d failed)", elapsed, totalClients, len(allClients), len(fetchErrors))
 
 	// Update cache
 	ns.cacheMutex.Lock()
 	ns.clientsCache = &CachedData{
-		Data:      allClients,
+		Data:      cachedClients{Clients: allClients, Errors: fetchErrors},
 		Timestamp: time.Now(),
 	}
 	ns.cacheMutex.Unlock()
 
-	return allClients
+	return allClients, fetchErrors
+}
+
+// natStreamWorkers bounds how many routers StreamClients queries at once
+const natStreamWorkers = 8
+
+// StreamClients fetches online clients for the given routers and sends one
+// result per router as soon as it's ready, so callers can write incrementally.
+// The channel is closed when every router is done or ctx is cancelled.
+func (ns *NATService) StreamClients(ctx context.Context, routerNames []string) <-chan models.RouterClients {
+	results := make(chan models.RouterClients)
+
+	// Serve from cache while it's fresh, same as GetAllClients
+	ns.cacheMutex.RLock()
+	var cached cachedClients
+	if ns.clientsCache != nil && time.Since(ns.clientsCache.Timestamp) < ns.cacheTTL {
+		cached = ns.clientsCache.Data.(cachedClients)
+	}
+	ns.cacheMutex.RUnlock()
+
+	names := make(chan string)
+	go func() {
+		defer close(names)
+		for _, name := range routerNames {
+			select {
+			case names <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < natStreamWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				result := models.RouterClients{Router: name}
+				if clients, ok := cached.Clients[name]; ok {
+					result.Clients = clients
+					result.Error = cached.Errors[name]
+				} else if clients, err := ns.GetRouterClients(name); err != nil {
+					ns.logger.Errorf("Failed to get clients from %s: %v", name, err)
+					result.Clients = []models.NATClient{}
+					result.Error = err.Error()
+				} else {
+					result.Clients = clients
+				}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// GetClientsForRouters collects online clients for only the given routers,
+// with per-router errors reported the same way as GetAllClients
+func (ns *NATService) GetClientsForRouters(ctx context.Context, routerNames []string) (map[string][]models.NATClient, map[string]string) {
+	clients := make(map[string][]models.NATClient, len(routerNames))
+	fetchErrors := make(map[string]string)
+	for result := range ns.StreamClients(ctx, routerNames) {
+		clients[result.Router] = result.Clients
+		if result.Error != "" {
+			fetchErrors[result.Router] = result.Error
+		}
+	}
+	return clients, fetchErrors
 }
 
 // TestRouterConnection tests connection to a specific router
 func (ns *NATService) TestRouterConnection(routerName string) models.RouterConnectionTest {
 	client, err := ns.ConnectRouter(routerName)
 	if err != nil {
-		return models.RouterConnectionTest{
-			Status:    "disconnected",
-			Message:   err.Error(),
-			Timestamp: time.Now(),
-		}
+		return failedConnectionTest(err)
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
 	// Get system info
-	identityReply, err := client.Run("/system/identity/print")
+	identityReply, err := ns.runCommand(routerName, client, "/system/identity/print")
 	if err != nil {
 		return models.RouterConnectionTest{
 			Status:    "disconnected",
@@ -423,7 +1237,7 @@ func (ns *NATService) TestRouterConnection(routerName string) models.RouterConne
 		}
 	}
 
-	resourceReply, err := client.Run("/system/resource/print")
+	resourceReply, err := ns.runCommand(routerName, client, "/system/resource/print")
 	if err != nil {
 		return models.RouterConnectionTest{
 			Status:    "disconnected",
@@ -432,20 +1246,29 @@ func (ns *NATService) TestRouterConnection(routerName string) models.RouterConne
 		}
 	}
 
-	var routerName_actual, version, board string
-	if len(identityReply.Re) > 0 {
-		routerName_actual = identityReply.Re[0].Map["name"]
+	identity, err := firstRow(identityReply, "/system/identity/print", "name")
+	if err != nil {
+		return models.RouterConnectionTest{
+			Status:    "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		}
 	}
-	if len(resourceReply.Re) > 0 {
-		version = resourceReply.Re[0].Map["version"]
-		board = resourceReply.Re[0].Map["board-name"]
+
+	resource, err := firstRow(resourceReply, "/system/resource/print", "version")
+	if err != nil {
+		return models.RouterConnectionTest{
+			Status:    "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		}
 	}
 
 	return models.RouterConnectionTest{
 		Status:     "connected",
-		RouterName: routerName_actual,
-		Version:    version,
-		Board:      board,
+		RouterName: mapString(identity, "name", routerName),
+		Version:    mapString(resource, "version", "unknown"),
+		Board:      mapString(resource, "board-name", "unknown"),
 		Message:    "Connection successful",
 		Timestamp:  time.Now(),
 	}
@@ -470,10 +1293,11 @@ func (ns *NATService) TestAllConnections() map[string]models.RouterConnectionTes
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	ns.logger.Debugf("🚀 Starting parallel connection test for %d routers", len(ns.routers))
+	routers := ns.routerMap()
+	ns.logger.Debugf("🚀 Starting parallel connection test for %d routers", len(routers))
 	startTime := time.Now()
 
-	for routerName := range ns.routers {
+	for routerName := range routers {
 		wg.Add(1)
 		go func(name string) {
 			defer wg.Done()
@@ -504,11 +1328,39 @@ func (ns *NATService) TestAllConnections() map[string]models.RouterConnectionTes
 		Data:      results,
 		Timestamp: time.Now(),
 	}
+	ns.lastTest = ns.testCache
 	ns.cacheMutex.Unlock()
 
 	return results
 }
 
+// RouterReadiness summarizes the latest TestAllConnections result for
+// readiness probes. It never tests routers, not even in the background, so
+// probes can't add load to the routers; a result older than the cache TTL is
+// reported as stale instead.
+func (ns *NATService) RouterReadiness() models.RouterReadiness {
+	ns.cacheMutex.RLock()
+	cache := ns.lastTest
+	ns.cacheMutex.RUnlock()
+
+	readiness := models.RouterReadiness{Total: len(ns.routerMap())}
+	if cache == nil {
+		return readiness
+	}
+
+	checkedAt := cache.Timestamp
+	readiness.Checked = true
+	readiness.CheckedAt = &checkedAt
+	readiness.Stale = time.Since(checkedAt) >= ns.cacheTTL
+	for _, result := range cache.Data.(map[string]models.RouterConnectionTest) {
+		if result.Status == "connected" {
+			readiness.Connected++
+		}
+	}
+	readiness.Degraded = readiness.Total > 0 && readiness.Connected == 0
+	return readiness
+}
+
 // validateIP validates IP address format
 func (ns *NATService) validateIP(ip string) bool {
 	parts := strings.Split(ip, ".")
@@ -531,22 +1383,25 @@ func (ns *NATService) validatePort(port string) bool {
 	return true
 }
 
-// CheckPPPoEStatus checks if a specific PPPoE username is online across all routers or specific router
 // CheckPPPoEStatusWithRouterFilter checks PPPoE status only on allowed routers
-func (ns *NATService) CheckPPPoEStatusWithRouterFilter(username string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse {
-	return ns.checkPPPoEStatusInternal(username, "", allowedRouters, testConnectivity)
+func (ns *NATService) CheckPPPoEStatusWithRouterFilter(ctx context.Context, username string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse {
+	return ns.checkPPPoEStatusInternal(ctx, username, "", allowedRouters, testConnectivity)
 }
 
-func (ns *NATService) CheckPPPoEStatus(username string, testConnectivity bool, specificRouter ...string) *models.PPPoEStatusResponse {
+// CheckPPPoEStatus checks if a specific PPPoE username is online across all routers or specific router
+func (ns *NATService) CheckPPPoEStatus(ctx context.Context, username string, testConnectivity bool, specificRouter ...string) *models.PPPoEStatusResponse {
 	if len(specificRouter) > 0 && specificRouter[0] != "" {
-		return ns.checkPPPoEStatusInternal(username, specificRouter[0], nil, testConnectivity)
+		return ns.checkPPPoEStatusInternal(ctx, username, specificRouter[0], nil, testConnectivity)
 	}
 	// Check all routers (no filtering)
-	return ns.checkPPPoEStatusInternal(username, "", nil, testConnectivity)
+	return ns.checkPPPoEStatusInternal(ctx, username, "", nil, testConnectivity)
 }
 
-// checkPPPoEStatusInternal is the internal implementation that supports router filtering
-func (ns *NATService) checkPPPoEStatusInternal(username, specificRouter string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse {
+// checkPPPoEStatusInternal is the internal implementation that supports router
+// filtering. Routers are checked in parallel by natStreamWorkers workers; those
+// still pending when ctx is done are reported with PPPoECheckTimeout so one
+// slow router can't hold up the answers from the others.
+func (ns *NATService) checkPPPoEStatusInternal(ctx context.Context, username, specificRouter string, allowedRouters []string, testConnectivity bool) *models.PPPoEStatusResponse {
 	response := &models.PPPoEStatusResponse{
 		Status:      "success",
 		Username:    username,
@@ -563,10 +1418,11 @@ func (ns *NATService) checkPPPoEStatusInternal(username, specificRouter string,
 	}
 
 	// Determine which routers to check
+	routers := ns.routerMap()
 	var routersToCheck []string
 	if specificRouter != "" {
 		// Check specific router only
-		if _, exists := ns.routers[specificRouter]; !exists {
+		if _, exists := routers[specificRouter]; !exists {
 			response.Status = "error"
 			response.Message = fmt.Sprintf("Router %s tidak ditemukan", specificRouter)
 			return response
@@ -575,27 +1431,75 @@ func (ns *NATService) checkPPPoEStatusInternal(username, specificRouter string,
 	} else if allowedRouters != nil {
 		// Check only allowed routers (role-based filtering)
 		for _, routerName := range allowedRouters {
-			if _, exists := ns.routers[routerName]; exists {
+			if _, exists := routers[routerName]; exists {
 				routersToCheck = append(routersToCheck, routerName)
 			}
 		}
 	} else {
 		// Check all routers
-		for routerName := range ns.routers {
+		for routerName := range routers {
 			routersToCheck = append(routersToCheck, routerName)
 		}
 	}
 
-	// Check specified routers
-	for _, routerName := range routersToCheck {
-		result := ns.checkPPPoEOnRouterWithConnectivity(routerName, username, testConnectivity)
-		response.Data[routerName] = result
+	// Check specified routers. Results are buffered so workers never block
+	// on a caller that has already given up.
+	names := make(chan string)
+	results := make(chan models.PPPoEStatusResult, len(routersToCheck))
+	go func() {
+		defer close(names)
+		for _, routerName := range routersToCheck {
+			select {
+			case names <- routerName:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < natStreamWorkers && i < len(routersToCheck); i++ {
+		go func() {
+			for routerName := range names {
+				results <- ns.checkPPPoEOnRouterWithConnectivity(ctx, routerName, username, testConnectivity)
+			}
+		}()
+	}
+
+collect:
+	for range routersToCheck {
+		select {
+		case result := <-results:
+			if result.CheckStatus == models.PPPoECheckError && contextDone(ctx) != nil {
+				// Cut off by the deadline: reported with the timeouts below
+				continue
+			}
+			response.Data[result.Router] = result
+			if result.IsOnline {
+				response.IsOnline = true
+				response.OnlineCount++
+			}
+		case <-ctx.Done():
+			break collect
+		}
+	}
 
-		if result.IsOnline {
-			response.IsOnline = true
-			response.OnlineCount++
+	for _, routerName := range routersToCheck {
+		if _, done := response.Data[routerName]; done {
+			continue
+		}
+		response.TimedOut = append(response.TimedOut, routerName)
+		response.Data[routerName] = models.PPPoEStatusResult{
+			Router:             routerName,
+			ConnectivityStatus: "not_tested",
+			CheckStatus:        models.PPPoECheckTimeout,
+			LastSeen:           time.Now(),
+			Message:            "Router tidak merespon sebelum batas waktu pengecekan",
 		}
 	}
+	if len(response.TimedOut) > 0 {
+		sort.Strings(response.TimedOut)
+		ns.logger.Warnf("⏱️  PPPoE check for %s timed out on %d router(s): %s", username, len(response.TimedOut), strings.Join(response.TimedOut, ", "))
+	}
 
 	if response.IsOnline {
 		if len(routersToCheck) == 1 {
@@ -611,60 +1515,216 @@ func (ns *NATService) checkPPPoEStatusInternal(username, specificRouter string,
 		}
 	}
 
+	if len(response.TimedOut) > 0 && !response.IsOnline {
+		response.Message += fmt.Sprintf(" (%d router tidak merespon)", len(response.TimedOut))
+	}
+
 	ns.logger.Infof("PPPoE Status Check: %s - Online: %t (%d routers)", username, response.IsOnline, response.OnlineCount)
+	ns.recordPPPoESearch(ctx, response, specificRouter)
 	return response
 }
 
-// checkPPPoEOnRouter checks PPPoE status on a specific router
-func (ns *NATService) checkPPPoEOnRouter(routerName, username string) models.PPPoEStatusResult {
-	return ns.checkPPPoEOnRouterWithConnectivity(routerName, username, false)
+// recordPPPoESearch stores a finished check in the search history of the user
+// carried by ctx. Checks without a requesting user aren't recorded, and a
+// failed insert never fails the check itself.
+func (ns *NATService) recordPPPoESearch(ctx context.Context, response *models.PPPoEStatusResponse, specificRouter string) {
+	userID, ok := RequestUserID(ctx)
+	if ns.historyRepo == nil || !ok {
+		return
+	}
+
+	entry := &models.PPPoESearchHistory{
+		UserID:     userID,
+		Username:   response.Username,
+		RouterName: specificRouter,
+		IsOnline:   response.IsOnline,
+	}
+	if response.IsOnline {
+		var routers []string
+		for routerName, result := range response.Data {
+			if result.IsOnline {
+				routers = append(routers, routerName)
+				if entry.IPAddress == "" {
+					entry.IPAddress = result.IPAddress
+				}
+			}
+		}
+		sort.Strings(routers)
+		entry.RouterName = strings.Join(routers, ",")
+	}
+
+	// The request context may already be close to its deadline
+	dbCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := ns.historyRepo.Create(dbCtx, entry); err != nil {
+		ns.logger.Warnf("⚠️ Failed to record PPPoE search for user %d: %v", userID, err)
+	}
 }
 
-// testDeviceConnectivity tests if the device at given IP is actually reachable via TCP
-func (ns *NATService) testDeviceConnectivity(ipAddress string) (bool, string, time.Duration) {
-	// Common ports for ONT/modem/customer devices
-	testPorts := []string{"80", "8080", "443", "22", "23", "8081"}
+// AuditPPPoEUsernames checks many PPPoE usernames at once. Each accessible
+// router's active sessions are fetched once (bounded, cache-aware) and matched
+// locally, instead of querying every router per username. Rows keep input order.
+func (ns *NATService) AuditPPPoEUsernames(ctx context.Context, entries []models.PPPoEAuditEntry, allowedRouters []string) []models.PPPoEAuditRow {
+	allowed := make(map[string]bool, len(allowedRouters))
+	routerNames := []string{}
+	for _, name := range allowedRouters {
+		allowed[name] = true
+		if ns.HasRouter(name) {
+			routerNames = append(routerNames, name)
+		}
+	}
+
+	sessions := make(map[string][]models.NATClient)
+	routerErrors := make(map[string]string)
+	for result := range ns.StreamClients(ctx, routerNames) {
+		if result.Error != "" {
+			routerErrors[result.Router] = result.Error
+			continue
+		}
+		for _, client := range result.Clients {
+			sessions[client.Username] = append(sessions[client.Username], client)
+		}
+	}
 
-	startTime := time.Now()
+	rows := make([]models.PPPoEAuditRow, 0, len(entries))
+	for _, entry := range entries {
+		row := models.PPPoEAuditRow{
+			Username:       entry.Username,
+			ExpectedRouter: entry.ExpectedRouter,
+			Status:         models.PPPoEAuditOffline,
+		}
 
-	for _, port := range testPorts {
-		address := fmt.Sprintf("%s:%s", ipAddress, port)
+		if entry.ExpectedRouter != "" && !allowed[entry.ExpectedRouter] {
+			row.Status = models.PPPoEAuditNoAccess
+			row.Note = "Tidak memiliki akses ke router ini"
+			rows = append(rows, row)
+			continue
+		}
 
-		// Test TCP connection with 2-second timeout per port
-		conn, err := net.DialTimeout("tcp", address, 2*time.Second)
-		if err == nil {
-			conn.Close()
-			duration := time.Since(startTime)
-			ns.logger.Debugf("✅ Device %s reachable on port %s (took %v)", ipAddress, port, duration)
-			return true, port, duration
+		var found []models.NATClient
+		for _, client := range sessions[entry.Username] {
+			if entry.ExpectedRouter == "" || client.Router == entry.ExpectedRouter {
+				found = append(found, client)
+			}
+		}
+
+		if len(found) > 0 {
+			routers := make([]string, 0, len(found))
+			for _, client := range found {
+				routers = append(routers, client.Router)
+			}
+			row.Status = models.PPPoEAuditOnline
+			row.Router = strings.Join(routers, ";")
+			row.IPAddress = found[0].IPAddress
+			row.Uptime = found[0].Uptime
+		} else if entry.ExpectedRouter != "" && routerErrors[entry.ExpectedRouter] != "" {
+			row.Status = models.PPPoEAuditError
+			row.Router = entry.ExpectedRouter
+			row.Note = routerErrors[entry.ExpectedRouter]
+		} else if entry.ExpectedRouter == "" && len(routerErrors) > 0 {
+			row.Note = fmt.Sprintf("%d router tidak dapat dicek", len(routerErrors))
+		}
+
+		rows = append(rows, row)
+	}
+
+	ns.logger.Infof("📋 PPPoE audit: %d usernames across %d routers (%d unreachable)", len(entries), len(routerNames), len(routerErrors))
+	return rows
+}
+
+// AuditConnectivity tests every online PPPoE session on the given routers
+// (empty = all configured) for device reachability. Sessions come from
+// StreamClients and devices are tested with natStreamWorkers workers. A router
+// that can't be queried yields a single result carrying its error.
+func (ns *NATService) AuditConnectivity(ctx context.Context, routerNames []string) []models.AuditResult {
+	if len(routerNames) == 0 {
+		routerNames = ns.GetAvailableRouters()
+	}
+
+	var results []models.AuditResult
+	var targets []models.NATClient
+	for result := range ns.StreamClients(ctx, routerNames) {
+		if result.Error != "" {
+			results = append(results, models.AuditResult{
+				Router:    result.Router,
+				Error:     result.Error,
+				CheckedAt: time.Now(),
+			})
+			continue
+		}
+		targets = append(targets, result.Clients...)
+	}
+
+	jobs := make(chan models.NATClient)
+	go func() {
+		defer close(jobs)
+		for _, client := range targets {
+			select {
+			case jobs <- client:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < natStreamWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for client := range jobs {
+				result := models.AuditResult{
+					Router:    client.Router,
+					Username:  client.Username,
+					IPAddress: client.IPAddress,
+				}
+				if client.IPAddress != "" {
+					reachable, port, duration := ns.testDeviceConnectivity(ctx, client.IPAddress)
+					result.Reachable = reachable
+					result.Port = port
+					result.ResponseTimeMs = int(duration.Milliseconds())
+				}
+				result.CheckedAt = time.Now()
 
-		ns.logger.Debugf("⚠️  Port %s on %s unreachable: %v", port, ipAddress, err)
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}
+		}()
 	}
+	wg.Wait()
 
-	duration := time.Since(startTime)
-	ns.logger.Warnf("❌ Device %s unreachable on all tested ports (took %v)", ipAddress, duration)
-	return false, "", duration
+	ns.logger.Infof("📋 Connectivity audit: %d sessions on %d routers", len(targets), len(routerNames))
+	return results
+}
+
+// checkPPPoEOnRouter checks PPPoE status on a specific router
+func (ns *NATService) checkPPPoEOnRouter(routerName, username string) models.PPPoEStatusResult {
+	return ns.checkPPPoEOnRouterWithConnectivity(context.Background(), routerName, username, false)
 }
 
-// checkPPPoEOnRouterWithConnectivity checks PPPoE status with optional connectivity test
-func (ns *NATService) checkPPPoEOnRouterWithConnectivity(routerName, username string, testConnectivity bool) models.PPPoEStatusResult {
+// checkPPPoEOnRouterWithConnectivity checks PPPoE status with optional
+// connectivity test. The dial, the query and the test all stop when ctx is
+// done, so a router that misses the deadline doesn't keep holding a connection.
+func (ns *NATService) checkPPPoEOnRouterWithConnectivity(ctx context.Context, routerName, username string, testConnectivity bool) models.PPPoEStatusResult {
 	result := models.PPPoEStatusResult{
 		Router:             routerName,
 		IsOnline:           false,
 		ConnectivityStatus: "not_tested",
+		CheckStatus:        models.PPPoECheckError,
 		LastSeen:           time.Now(),
 	}
 
-	client, err := ns.ConnectRouter(routerName)
+	client, err := ns.connectRouter(ctx, routerName)
 	if err != nil {
 		result.Message = fmt.Sprintf("Gagal koneksi ke router: %v", err)
 		return result
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
 	// Get PPPoE active connections
-	reply, err := client.Run("/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding", fmt.Sprintf("?name=%s", username))
+	reply, err := ns.runCommandContext(ctx, routerName, client, "/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding", fmt.Sprintf("?name=%s", username))
 	if err != nil {
 		result.Message = fmt.Sprintf("Gagal mengambil data PPPoE: %v", err)
 		return result
@@ -677,6 +1737,7 @@ func (ns *NATService) checkPPPoEOnRouterWithConnectivity(routerName, username st
 			result.IPAddress = re.Map["address"]
 			result.CallerID = re.Map["caller-id"]
 			result.Uptime = re.Map["uptime"]
+			result.UptimeSeconds = duration.RouterOSSeconds(re.Map["uptime"])
 			result.Encoding = re.Map["encoding"]
 			result.SessionTime = re.Map["uptime"] // Same as uptime for active sessions
 			result.Message = "User aktif (PPPoE session active)"
@@ -684,7 +1745,7 @@ func (ns *NATService) checkPPPoEOnRouterWithConnectivity(routerName, username st
 			// Perform connectivity test if requested
 			if testConnectivity && result.IPAddress != "" {
 				ns.logger.Infof("🔍 Testing device connectivity for %s at %s", username, result.IPAddress)
-				reachable, port, duration := ns.testDeviceConnectivity(result.IPAddress)
+				reachable, port, duration := ns.testDeviceConnectivity(ctx, result.IPAddress)
 
 				result.DeviceReachable = reachable
 				result.ReachablePort = port
@@ -703,42 +1764,126 @@ func (ns *NATService) checkPPPoEOnRouterWithConnectivity(routerName, username st
 		}
 	}
 
-	if !result.IsOnline {
+	if result.IsOnline {
+		result.CheckStatus = models.PPPoECheckOnline
+	} else {
+		result.CheckStatus = models.PPPoECheckOffline
 		result.Message = "User tidak ditemukan di router ini"
 	}
 
 	return result
 }
 
-// GetPPPoEHistory gets recent PPPoE searches (placeholder for future implementation)
-func (ns *NATService) GetPPPoEHistory(userID int, limit int) []models.PPPoESearchHistory {
-	// Placeholder - could be implemented with database storage later
-	return []models.PPPoESearchHistory{}
+// ErrPPPoEUserNotOnline is returned by DisconnectPPPoEUser when the user has
+// no active session on the router, so nothing was disconnected
+var ErrPPPoEUserNotOnline = errors.New("PPPoE user not online")
+
+// DisconnectPPPoEUser removes a user's active PPPoE session(s) on a router so
+// the CPE reconnects, e.g. to pick up a new IP
+func (ns *NATService) DisconnectPPPoEUser(routerName, username string) error {
+	if !ns.HasRouter(routerName) {
+		return fmt.Errorf("router %s not configured", routerName)
+	}
+
+	client, err := ns.ConnectRouter(routerName)
+	if err != nil {
+		return err
+	}
+	defer ns.releaseRouter(client)
+
+	reply, err := ns.runCommand(routerName, client, "/ppp/active/print", "=.proplist=.id,name", "?name="+username)
+	if err != nil {
+		return fmt.Errorf("failed to find PPPoE session: %w", err)
+	}
+
+	var ids []string
+	for _, re := range reply.Re {
+		if re.Map["name"] == username && re.Map[".id"] != "" {
+			ids = append(ids, re.Map[".id"])
+		}
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: %s on %s", ErrPPPoEUserNotOnline, username, routerName)
+	}
+
+	for _, id := range ids {
+		if _, err := ns.runCommand(routerName, client, "/ppp/active/remove", "=.id="+id); err != nil {
+			return fmt.Errorf("failed to disconnect PPPoE session %s: %w", id, err)
+		}
+	}
+
+	// Online clients changed
+	ns.invalidateCache()
+
+	ns.logger.Infof("🔌 Disconnected PPPoE user %s on %s (%d session(s))", username, routerName, len(ids))
+	return nil
+}
+
+// GetPPPoEHistory returns a user's most recent PPPoE searches, newest first
+func (ns *NATService) GetPPPoEHistory(userID int, limit int) ([]models.PPPoESearchHistory, error) {
+	if ns.historyRepo == nil {
+		return []models.PPPoESearchHistory{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return ns.historyRepo.GetByUser(ctx, userID, limit)
 }
 
-// CheckMultiplePPPoEStatus checks status for multiple usernames
-func (ns *NATService) CheckMultiplePPPoEStatus(usernames []string) map[string]*models.PPPoEStatusResponse {
-	results := make(map[string]*models.PPPoEStatusResponse)
+// pppoeBatchConcurrency bounds how many usernames of a batch check run at once
+const pppoeBatchConcurrency = 5
+
+// CheckMultiplePPPoEStatus checks several usernames on the allowed routers
+// (nil = all routers), a few at a time. Each check gets its own checkTimeout
+// so one slow username doesn't eat into the others' time.
+func (ns *NATService) CheckMultiplePPPoEStatus(ctx context.Context, usernames []string, allowedRouters []string, testConnectivity bool, checkTimeout time.Duration) map[string]*models.PPPoEStatusResponse {
+	results := make(map[string]*models.PPPoEStatusResponse, len(usernames))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
+	sem := make(chan struct{}, pppoeBatchConcurrency)
 	for _, username := range usernames {
-		results[username] = ns.CheckPPPoEStatus(username, false) // No connectivity test for bulk checks
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			var result *models.PPPoEStatusResponse
+			if allowedRouters == nil {
+				result = ns.CheckPPPoEStatus(checkCtx, username, testConnectivity)
+			} else {
+				result = ns.CheckPPPoEStatusWithRouterFilter(checkCtx, username, allowedRouters, testConnectivity)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[username] = result
+		}(username)
 	}
+	wg.Wait()
 
 	return results
 }
 
 // GetAvailableRouters returns list of available router names
 func (ns *NATService) GetAvailableRouters() []string {
-	ns.mutex.RLock()
-	defer ns.mutex.RUnlock()
-
 	var routers []string
-	for routerName := range ns.routers {
+	for routerName := range ns.routerMap() {
 		routers = append(routers, routerName)
 	}
 	return routers
 }
 
+// HasRouter reports whether a router is currently configured for NAT operations
+func (ns *NATService) HasRouter(routerName string) bool {
+	_, exists := ns.routerConfig(routerName)
+	return exists
+}
+
 // GetAvailableRoutersWithFilter returns list of router names accessible to a user role
 func (ns *NATService) GetAvailableRoutersWithFilter(userRole string) []string {
 	if ns.routerService == nil {
@@ -753,11 +1898,12 @@ func (ns *NATService) GetAvailableRoutersWithFilter(userRole string) []string {
 		return []string{} // Return empty list on error
 	}
 
+	routers := ns.routerMap()
 	var routerNames []string
 	for _, router := range allRouters {
 		// Only include routers that are enabled and exist in our routers map
 		if router.Enabled {
-			if _, exists := ns.routers[router.Name]; exists {
+			if _, exists := routers[router.Name]; exists {
 				routerNames = append(routerNames, router.Name)
 			}
 		}
@@ -770,7 +1916,7 @@ func (ns *NATService) GetAvailableRoutersWithFilter(userRole string) []string {
 func (ns *NATService) RefreshRouterIfNeeded() error {
 	// This can be called before operations to ensure we have the latest router configurations
 	// For now, we'll implement a simple approach - in production this could be optimized with caching
-	if len(ns.routers) == 0 {
+	if len(ns.routerMap()) == 0 {
 		ns.logger.Infof("🔄 No routers loaded, refreshing from storage...")
 		return ns.loadRoutersFromDynamicStorage()
 	}
@@ -779,7 +1925,8 @@ func (ns *NATService) RefreshRouterIfNeeded() error {
 
 // FuzzySearchPPPoEWithRouterFilter performs fuzzy search with router access filtering
 // ⚡ OPTIMIZED: Parallel execution for faster multi-router search
-func (ns *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specificRouter string, limit int, allowedRouters []string) *models.PPPoEFuzzySearchResponse {
+// With includeOffline, PPPoE secrets without an active session are matched too.
+func (ns *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specificRouter string, limit int, allowedRouters []string, includeOffline bool) *models.PPPoEFuzzySearchResponse {
 	response := &models.PPPoEFuzzySearchResponse{
 		Status:     "success",
 		SearchTerm: searchTerm,
@@ -798,6 +1945,7 @@ func (ns *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specif
 	}
 
 	// Determine which routers to search
+	routers := ns.routerMap()
 	var routersToSearch []string
 	if specificRouter != "" {
 		// Check if specific router is allowed
@@ -809,14 +1957,14 @@ func (ns *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specif
 			}
 		}
 		if hasAccess {
-			if _, exists := ns.routers[specificRouter]; exists {
+			if _, exists := routers[specificRouter]; exists {
 				routersToSearch = []string{specificRouter}
 			}
 		}
 	} else {
 		// Search only allowed routers
 		for _, routerName := range allowedRouters {
-			if _, exists := ns.routers[routerName]; exists {
+			if _, exists := routers[routerName]; exists {
 				routersToSearch = append(routersToSearch, routerName)
 			}
 		}
@@ -835,7 +1983,7 @@ func (ns *NATService) FuzzySearchPPPoEWithRouterFilter(searchTerm string, specif
 		go func(name string) {
 			defer wg.Done()
 
-			matches := ns.searchPPPoEInRouter(name, searchTerm)
+			matches := ns.searchPPPoEInRouter(name, searchTerm, includeOffline)
 
 			mu.Lock()
 			allMatches = append(allMatches, matches...)
@@ -887,16 +2035,17 @@ func (ns *NATService) FuzzySearchPPPoE(searchTerm string, specificRouter string,
 	}
 
 	// Determine which routers to search
+	routers := ns.routerMap()
 	var routersToSearch []string
 	if specificRouter != "" {
-		if _, exists := ns.routers[specificRouter]; !exists {
+		if _, exists := routers[specificRouter]; !exists {
 			response.Status = "error"
 			response.Message = fmt.Sprintf("Router %s tidak ditemukan", specificRouter)
 			return response
 		}
 		routersToSearch = []string{specificRouter}
 	} else {
-		for routerName := range ns.routers {
+		for routerName := range routers {
 			routersToSearch = append(routersToSearch, routerName)
 		}
 	}
@@ -905,7 +2054,7 @@ func (ns *NATService) FuzzySearchPPPoE(searchTerm string, specificRouter string,
 	var allMatches []models.PPPoEFuzzyMatch
 
 	for _, routerName := range routersToSearch {
-		matches := ns.searchPPPoEInRouter(routerName, searchTerm)
+		matches := ns.searchPPPoEInRouter(routerName, searchTerm, false)
 		allMatches = append(allMatches, matches...)
 	}
 
@@ -930,8 +2079,92 @@ func (ns *NATService) FuzzySearchPPPoE(searchTerm string, specificRouter string,
 	return response
 }
 
+// SetFuzzyMaxCandidates sets how many prefiltered usernames per router get
+// full similarity scoring during fuzzy search (0 = no cap)
+func (ns *NATService) SetFuzzyMaxCandidates(maxCandidates int) {
+	if maxCandidates < 0 {
+		maxCandidates = 0
+	}
+	ns.fuzzyMaxCandidates = maxCandidates
+}
+
+// SetFuzzyMinSimilarity sets the similarity (0-1) a username needs to be a
+// fuzzy search match; values outside that range keep the default
+func (ns *NATService) SetFuzzyMinSimilarity(minSimilarity float64) {
+	if minSimilarity < 0 || minSimilarity > 1 {
+		ns.logger.Warnf("⚠️ Invalid fuzzy min similarity %v, using %v", minSimilarity, defaultFuzzyMinSimilarity)
+		minSimilarity = defaultFuzzyMinSimilarity
+	}
+	ns.fuzzyMinSimilarity = minSimilarity
+}
+
+// SetFuzzyAreaPatterns replaces the area names fuzzy search recognizes in
+// usernames (e.g. "kukun" in ahmadkukun); an empty list keeps the defaults
+func (ns *NATService) SetFuzzyAreaPatterns(areas []string) {
+	patterns := make([]string, 0, len(areas))
+	for _, area := range areas {
+		if area = strings.ToLower(strings.TrimSpace(area)); area != "" {
+			patterns = append(patterns, area)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = defaultFuzzyAreaPatterns
+	}
+	ns.fuzzyAreaPatterns = patterns
+}
+
+// SetHistoryRepository enables recording PPPoE status checks per user
+func (ns *NATService) SetHistoryRepository(repo *database.PPPoEHistoryRepository) {
+	ns.historyRepo = repo
+}
+
+// SetRouterThrottle rate limits the RouterOS commands this service sends per router
+func (ns *NATService) SetRouterThrottle(throttle *RouterThrottle) {
+	ns.throttle = throttle
+}
+
+// runCommand runs a RouterOS command once routerName's throttle allows it.
+// Pooled connections get the pool's per-operation deadline, and are dropped
+// from the pool when the connection itself failed (network error, timeout,
+// EOF). A RouterOS error reply leaves the connection usable, so callers may
+// retry with another command on it.
+func (ns *NATService) runCommand(routerName string, conn *RouterOSConnection, sentence ...string) (*routeros.Reply, error) {
+	return ns.runCommandContext(context.Background(), routerName, conn, sentence...)
+}
+
+// runCommandContext is runCommand that stops waiting for the router when ctx
+// is done. The interrupted connection can't be reused: a pooled one is closed
+// and a direct one fails the caller's next command.
+func (ns *NATService) runCommandContext(ctx context.Context, routerName string, conn *RouterOSConnection, sentence ...string) (*routeros.Reply, error) {
+	if conn.pool == nil {
+		if err := ns.throttle.Wait(routerName); err != nil {
+			ns.logger.Warnf("🚦 %v", err)
+			return nil, err
+		}
+		return conn.runOpContext(ctx, commandOperation(sentence), sentence...)
+	}
+
+	reply, err := conn.RunOpContext(ctx, commandOperation(sentence), sentence...)
+	if errors.Is(err, ErrRouterBusy) {
+		ns.logger.Warnf("🚦 %v", err)
+	} else if isConnectionBroken(err) {
+		conn.pool.CloseConnection(conn)
+	}
+	return reply, err
+}
+
+// commandOperation classifies a command sentence for its pool timeout
+func commandOperation(sentence []string) OperationType {
+	if len(sentence) > 0 && strings.HasSuffix(sentence[0], "/print") {
+		return OpRead
+	}
+	return OpWrite
+}
+
 // searchPPPoEInRouter searches for similar usernames in a specific router
-func (ns *NATService) searchPPPoEInRouter(routerName, searchTerm string) []models.PPPoEFuzzyMatch {
+// Active sessions are always searched; includeOffline adds the secrets of
+// users who are not connected, matched with IsOnline false and no IP/uptime.
+func (ns *NATService) searchPPPoEInRouter(routerName, searchTerm string, includeOffline bool) []models.PPPoEFuzzyMatch {
 	var matches []models.PPPoEFuzzyMatch
 
 	client, err := ns.ConnectRouter(routerName)
@@ -939,17 +2172,17 @@ func (ns *NATService) searchPPPoEInRouter(routerName, searchTerm string) []model
 		ns.logger.Errorf("Failed to connect to router %s for fuzzy search: %v", routerName, err)
 		return matches
 	}
-	defer client.Close()
+	defer ns.releaseRouter(client)
 
 	// Get all active PPPoE connections
-	activeReply, err := client.Run("/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding,service")
+	activeReply, err := ns.runCommand(routerName, client, "/ppp/active/print", "=.proplist=name,address,caller-id,uptime,encoding,service")
 	if err != nil {
 		ns.logger.Errorf("Failed to get PPPoE active connections from %s: %v", routerName, err)
 		return matches
 	}
 
 	// Get PPPoE secrets to get profile names
-	secretsReply, err := client.Run("/ppp/secret/print", "=.proplist=name,profile")
+	secretsReply, err := ns.runCommand(routerName, client, "/ppp/secret/print", "=.proplist=name,profile")
 	if err != nil {
 		ns.logger.Errorf("Failed to get PPPoE secrets from %s: %v", routerName, err)
 		// Continue with active connections only if secrets fail
@@ -966,17 +2199,50 @@ func (ns *NATService) searchPPPoEInRouter(routerName, searchTerm string) []model
 		}
 	}
 
-	// Process each active connection and calculate similarity
-	for _, re := range activeReply.Re {
+	// Offline users are the secrets without an active session, appended after
+	// the sessions so an index below online is an active session
+	entries := activeReply.Re
+	online := len(entries)
+	if includeOffline {
+		active := make(map[string]bool, online)
+		for _, re := range activeReply.Re {
+			active[re.Map["name"]] = true
+		}
+		for _, secretRe := range secretsReply.Re {
+			if name := secretRe.Map["name"]; name != "" && !active[name] {
+				entries = append(entries, secretRe)
+			}
+		}
+	}
+
+	// Cheap prefilter first, so only plausible names get the expensive scoring
+	prefilter := newFuzzyPrefilter(searchTerm, ns.fuzzyAreaPatterns)
+	var candidates []fuzzyCandidate
+	for i, re := range entries {
 		username := re.Map["name"]
 		if username == "" {
 			continue
 		}
+		if rank, ok := prefilter.rank(username); ok {
+			candidates = append(candidates, fuzzyCandidate{index: i, rank: rank})
+		}
+	}
+
+	scored := limitFuzzyCandidates(candidates, ns.fuzzyMaxCandidates)
+	if len(scored) < len(candidates) {
+		ns.logger.Warnf("⚠️ Fuzzy search in %s: %d of %d usernames passed the prefilter, scoring the best %d",
+			routerName, len(candidates), len(entries), len(scored))
+	}
+
+	// Calculate similarity for each remaining candidate
+	for _, candidate := range scored {
+		re := entries[candidate.index]
+		username := re.Map["name"]
 
 		similarity := ns.calculateSimilarity(searchTerm, username)
 		
-		// Only include if similarity is above threshold (0.3 = 30%)
-		if similarity >= 0.3 {
+		// Only include if similarity reaches the threshold (default 0.3 = 30%)
+		if similarity >= ns.fuzzyMinSimilarity {
 			// Get profile from secrets map, fallback to service field or default
 			profile := profileMap[username]
 			if profile == "" {
@@ -989,12 +2255,15 @@ func (ns *NATService) searchPPPoEInRouter(routerName, searchTerm string) []model
 			match := models.PPPoEFuzzyMatch{
 				Username:   username,
 				Router:     routerName,
-				IPAddress:  re.Map["address"],
-				CallerID:   re.Map["caller-id"],
-				Uptime:     re.Map["uptime"],
 				Profile:    profile,
 				Similarity: similarity,
-				IsOnline:   true,
+			}
+			if candidate.index < online {
+				match.IPAddress = re.Map["address"]
+				match.CallerID = re.Map["caller-id"]
+				match.Uptime = re.Map["uptime"]
+				match.UptimeSeconds = duration.RouterOSSeconds(re.Map["uptime"])
+				match.IsOnline = true
 			}
 			matches = append(matches, match)
 		}
@@ -1128,16 +2397,10 @@ func (ns *NATService) longestCommonSubsequence(s1, s2 string) int {
 
 // patternMatchScore calculates score for area-based name patterns (e.g., ahmadkukun, budikukun)
 func (ns *NATService) patternMatchScore(s1, s2 string) float64 {
-	// Extract potential area names (common suffixes/prefixes)
-	commonAreas := []string{
-		"kukun", "cipanas", "sukatani", "darussalam", "samsat", "cikarang", 
-		"sukawangi", "jaya", "lane4", "lane", "bt", "pk", "kp",
-	}
-
 	score := 0.0
 	
 	// Check if both strings contain the same area pattern
-	for _, area := range commonAreas {
+	for _, area := range ns.fuzzyAreaPatterns {
 		s1HasArea := strings.Contains(s1, area)
 		s2HasArea := strings.Contains(s2, area)
 		
@@ -1192,6 +2455,12 @@ func max(a, b int) int {
 	return b
 }
 
+// InvalidateCache drops cached NAT configs, clients and connection tests so
+// the next request reads fresh data from the routers
+func (ns *NATService) InvalidateCache() {
+	ns.invalidateCache()
+}
+
 // invalidateCache clears all caches when data is modified
 // 🔥 CACHE OPTIMIZATION: Force refresh after updates
 func (ns *NATService) invalidateCache() {
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetAllONTConfigsRetriesErrorsFirst(t *testing.T) {
+	up := ontRuleRouter(t, "192.168.1.10")
+	down := ontRuleRouter(t, "192.168.1.20")
+	down.setRejectLogin(true)
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{"UP": up, "DOWN": down})
+	ns.cacheTTL = time.Minute
+
+	configs := ns.GetAllONTConfigs()
+	if !configs["UP"].Found || configs["DOWN"].Found || configs["DOWN"].Error == "" {
+		t.Fatalf("first fetch: UP %+v, DOWN %+v; want UP found and DOWN errored", configs["UP"], configs["DOWN"])
+	}
+	upReads := len(up.received())
+
+	// Within errorCacheTTL the error is served from cache like everything else
+	if configs := ns.GetAllONTConfigs(); configs["DOWN"].Found || len(up.received()) != upReads {
+		t.Fatalf("second fetch re-read routers: DOWN %+v, UP reads %d -> %d", configs["DOWN"], upReads, len(up.received()))
+	}
+
+	// Once the error is older than errorCacheTTL only the errored router is
+	// re-read; UP's entry stays cached for the full cacheTTL
+	down.setRejectLogin(false)
+	ns.cacheMutex.Lock()
+	ns.configsCache.Data.(*cachedONTConfigs).FetchedAt["DOWN"] = time.Now().Add(-2 * errorCacheTTL)
+	ns.cacheMutex.Unlock()
+
+	configs = ns.GetAllONTConfigs()
+	if !configs["DOWN"].Found || configs["DOWN"].CurrentIP != "192.168.1.20" {
+		t.Fatalf("after the error expired: DOWN %+v, want the recovered rule", configs["DOWN"])
+	}
+	if len(up.received()) != upReads {
+		t.Fatalf("UP was re-read (%d -> %d) although its entry is fresh", upReads, len(up.received()))
+	}
+	if configs := ns.GetAllONTConfigs(); !configs["DOWN"].Found {
+		t.Fatalf("recovered DOWN wasn't written back to the cache: %+v", configs["DOWN"])
+	}
+}
+
+func TestRefreshONTConfigsBypassesCache(t *testing.T) {
+	fr := ontRuleRouter(t, "192.168.1.10")
+	ns := newTestNATService(t, fr)
+	ns.cacheTTL = time.Minute
+
+	ns.GetAllONTConfigs()
+	reads := len(fr.received())
+	ns.GetAllONTConfigs()
+	if len(fr.received()) != reads {
+		t.Fatal("cached fetch read the router again")
+	}
+
+	// ?fresh=true reads the router even though the cache is fresh
+	refreshed := ns.RefreshONTConfigs([]string{"FAKE", "GHOST"})
+	if len(fr.received()) == reads {
+		t.Fatal("refresh served the cache instead of reading the router")
+	}
+	if len(refreshed) != 1 || !refreshed["FAKE"].Found {
+		t.Fatalf("refreshed = %+v, want FAKE only (GHOST isn't configured)", refreshed)
+	}
+}
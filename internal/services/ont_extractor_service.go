@@ -1,12 +1,16 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"nat-management-app/internal/models"
@@ -14,12 +18,41 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// maxConcurrentExtractions bounds concurrent extractor runs; each one drives a
+// headless browser
+const maxConcurrentExtractions = 6
+
+// ontExtractorOutputFiles are the JSON files the extractors may write into
+// their working directory, in order of preference
+var ontExtractorOutputFiles = []string{
+	"zte_f477v2_wifi_info.json",
+	"zte_wifi_info.json",
+	"wifi_info.json",
+}
+
 // ONTExtractorService handles ONT WiFi information extraction via webautomation tools
 type ONTExtractorService struct {
 	logger           *logrus.Logger
 	webautomationDir string
 	nodeCommand      string
 	defaultTimeout   time.Duration
+
+	// Bounds how many extractor browsers run at once across single, bulk
+	// and extract-all runs
+	runSlot chan struct{}
+
+	// Where extractions are stored, see SetWiFiRepository
+	wifiRepo WiFiInfoStore
+
+	// Extract-all runs, see ConfigureExtractAll
+	natService  *NATService
+	activityLog *ActivityLogService
+	ontUsername string
+	ontPassword string
+	running     atomic.Bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
 }
 
 // NewONTExtractorService creates a new ONT extractor service instance
@@ -51,16 +84,26 @@ func NewONTExtractorService(logger *logrus.Logger) *ONTExtractorService {
 		}
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ONTExtractorService{
 		logger:           logger,
 		webautomationDir: webautomationDir,
 		nodeCommand:      nodeCmd,
 		defaultTimeout:   90 * time.Second, // 90s timeout for extraction
+		runSlot:          make(chan struct{}, maxConcurrentExtractions),
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 }
 
 // ExtractWiFiInfo extracts WiFi information from an ONT device
 func (oes *ONTExtractorService) ExtractWiFiInfo(ontURL, username, password string, debug bool) (*models.ONTWiFiInfo, error) {
+	return oes.ExtractWiFiInfoContext(context.Background(), oes.defaultTimeout, ontURL, username, password, debug)
+}
+
+// ExtractWiFiInfoContext extracts WiFi information from an ONT device. The
+// timeout covers waiting for a free run slot as well as the extractor itself.
+func (oes *ONTExtractorService) ExtractWiFiInfoContext(ctx context.Context, timeout time.Duration, ontURL, username, password string, debug bool) (*models.ONTWiFiInfo, error) {
 	oes.logger.Infof("🔍 Starting WiFi extraction for ONT: %s", ontURL)
 
 	// Validate inputs
@@ -87,21 +130,27 @@ func (oes *ONTExtractorService) ExtractWiFiInfo(ontURL, username, password strin
 		return nil, fmt.Errorf("webautomation directory not found: %s", oes.webautomationDir)
 	}
 
-	// IMPORTANT: Delete old JSON files before extraction to prevent stale data
-	jsonFilesToClean := []string{
-		filepath.Join(oes.webautomationDir, "zte_f477v2_wifi_info.json"),
-		filepath.Join(oes.webautomationDir, "zte_wifi_info.json"),
-		filepath.Join(oes.webautomationDir, "wifi_info.json"),
-	}
-	for _, jsonFile := range jsonFilesToClean {
-		if _, err := os.Stat(jsonFile); err == nil {
-			oes.logger.Infof("🗑️  Removing old JSON file: %s", jsonFile)
-			if err := os.Remove(jsonFile); err != nil {
-				oes.logger.Warnf("Failed to remove old JSON file %s: %v", jsonFile, err)
-			}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case oes.runSlot <- struct{}{}:
+		defer func() { <-oes.runSlot }()
+	case <-runCtx.Done():
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("extraction timed out after %v waiting for a free extractor", timeout)
 		}
+		return nil, fmt.Errorf("extraction cancelled while waiting for a free extractor: %w", runCtx.Err())
 	}
 
+	// Each run gets its own working directory, so concurrent extractors
+	// can't read each other's (or stale) JSON files
+	runDir, err := os.MkdirTemp("", "ont-extract-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+
 	// Prepare arguments
 	args := []string{launcherScript, ontURL, username, password}
 	if debug {
@@ -110,15 +159,21 @@ func (oes *ONTExtractorService) ExtractWiFiInfo(ontURL, username, password strin
 
 	// Execute command with timeout
 	oes.logger.Infof("Executing: %s %s", oes.nodeCommand, strings.Join(args, " "))
-	oes.logger.Infof("Working directory: %s", oes.webautomationDir)
+	oes.logger.Infof("Working directory: %s", runDir)
 
-	cmd := exec.Command(oes.nodeCommand, args...)
-	cmd.Dir = oes.webautomationDir // Set working directory to webautomation folder
+	cmd := exec.CommandContext(runCtx, oes.nodeCommand, args...)
+	// The extractors write their JSON into the working directory; node
+	// still resolves the launcher's modules from webautomationDir
+	cmd.Dir = runDir
 
 	// Capture both stdout and stderr
 	output, err := cmd.CombinedOutput()
 	outputStr := string(output)
 
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		oes.logger.Errorf("❌ WiFi extraction timed out after %v", timeout)
+		return nil, fmt.Errorf("extraction timed out after %v", timeout)
+	}
 	if err != nil {
 		oes.logger.Errorf("❌ WiFi extraction failed: %v", err)
 		oes.logger.Errorf("Command output: %s", outputStr)
@@ -128,7 +183,7 @@ func (oes *ONTExtractorService) ExtractWiFiInfo(ontURL, username, password strin
 	oes.logger.Infof("Extraction output: %s", outputStr)
 
 	// Parse output - look for JSON files created by extractors
-	wifiInfo, parseErr := oes.parseExtractionOutput(outputStr, ontURL)
+	wifiInfo, parseErr := oes.parseExtractionOutput(runDir, outputStr, ontURL)
 	if parseErr != nil {
 		oes.logger.Errorf("Failed to parse extraction output: %v", parseErr)
 		return nil, fmt.Errorf("failed to parse extraction results: %v", parseErr)
@@ -138,21 +193,16 @@ func (oes *ONTExtractorService) ExtractWiFiInfo(ontURL, username, password strin
 	return wifiInfo, nil
 }
 
-// parseExtractionOutput parses the output from webautomation tool
-func (oes *ONTExtractorService) parseExtractionOutput(output, ontURL string) (*models.ONTWiFiInfo, error) {
+// parseExtractionOutput parses the output of an extractor run in runDir
+func (oes *ONTExtractorService) parseExtractionOutput(runDir, output, ontURL string) (*models.ONTWiFiInfo, error) {
 	// Try to read JSON output files created by extractors
 	// Priority: zte_f477v2_wifi_info.json > zte_wifi_info.json > wifi_info.json
 
-	jsonFiles := []string{
-		filepath.Join(oes.webautomationDir, "zte_f477v2_wifi_info.json"),
-		filepath.Join(oes.webautomationDir, "zte_wifi_info.json"),
-		filepath.Join(oes.webautomationDir, "wifi_info.json"),
-	}
-
 	var wifiData map[string]interface{}
 	var usedFile string
 
-	for _, jsonFile := range jsonFiles {
+	for _, name := range ontExtractorOutputFiles {
+		jsonFile := filepath.Join(runDir, name)
 		data, err := os.ReadFile(jsonFile)
 		if err != nil {
 			continue // File doesn't exist or can't be read, try next
@@ -183,7 +233,7 @@ func (oes *ONTExtractorService) parseExtractionOutput(output, ontURL string) (*m
 		Authentication: getString(wifiData, "authentication"),
 		ONTURL:         ontURL,
 		ONTModel:       getString(wifiData, "ont_model"),
-		ExtractedAt:    time.Now(),
+		ExtractedAt:    time.Now().UTC(),
 	}
 
 	// Validate extracted data
@@ -201,7 +251,7 @@ func (oes *ONTExtractorService) parseConsoleOutput(output, ontURL string) (*mode
 
 	wifiInfo := &models.ONTWiFiInfo{
 		ONTURL:      ontURL,
-		ExtractedAt: time.Now(),
+		ExtractedAt: time.Now().UTC(),
 	}
 
 	for _, line := range lines {
@@ -246,6 +296,41 @@ func (oes *ONTExtractorService) ExtractWiFiInfoFromNATConfig(natConfig models.ON
 	return oes.ExtractWiFiInfo(natConfig.PublicONTURL, username, password, false)
 }
 
+// SetWiFiRepository sets where SaveWiFiInfo stores extractions
+func (oes *ONTExtractorService) SetWiFiRepository(repo WiFiInfoStore) {
+	oes.wifiRepo = repo
+}
+
+// SaveWiFiInfo stores an extraction and returns the WiFi settings that differ
+// from the customer's previous extraction. Extractions without a PPPoE
+// username are stored without a comparison. A failed lookup of the previous
+// extraction is only logged.
+func (oes *ONTExtractorService) SaveWiFiInfo(ctx context.Context, wifiInfo *models.ONTWiFiInfo) ([]string, error) {
+	if oes.wifiRepo == nil {
+		return nil, fmt.Errorf("WiFi info storage not configured")
+	}
+
+	var changedFields []string
+	if wifiInfo.PPPoEUsername != "" {
+		previous, err := oes.wifiRepo.FindLatestWiFiInfoByPPPoE(ctx, wifiInfo.PPPoEUsername)
+		if err != nil {
+			oes.logger.Warnf("Failed to load previous WiFi info for %s: %v", wifiInfo.PPPoEUsername, err)
+		} else if previous != nil {
+			changedFields = wifiInfo.ChangedFields(previous)
+		}
+	}
+
+	if err := oes.wifiRepo.SaveWiFiInfo(ctx, wifiInfo); err != nil {
+		return nil, err
+	}
+
+	if len(changedFields) > 0 {
+		oes.logger.Infof("🔄 WiFi settings of %s changed since the last extraction: %s",
+			wifiInfo.PPPoEUsername, strings.Join(changedFields, ", "))
+	}
+	return changedFields, nil
+}
+
 // CheckWebautomationAvailability checks if webautomation tools are available
 func (oes *ONTExtractorService) CheckWebautomationAvailability() error {
 	// Check Node.js
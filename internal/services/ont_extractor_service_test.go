@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestExtractor(t *testing.T) *ONTExtractorService {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ont-extractor-launcher.js"), []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &ONTExtractorService{
+		logger:           quietLogger(),
+		webautomationDir: dir,
+		nodeCommand:      "node",
+		defaultTimeout:   time.Second,
+		runSlot:          make(chan struct{}, 1),
+	}
+}
+
+func TestExtractWiFiInfoContextTimeoutCoversSlotWait(t *testing.T) {
+	oes := newTestExtractor(t)
+	oes.runSlot <- struct{}{} // every slot busy
+
+	start := time.Now()
+	_, err := oes.ExtractWiFiInfoContext(context.Background(), 50*time.Millisecond, "http://192.0.2.1", "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("waited %v for a slot, want about the 50ms timeout", elapsed)
+	}
+}
+
+func TestExtractWiFiInfoContextCancelledWhileWaiting(t *testing.T) {
+	oes := newTestExtractor(t)
+	oes.runSlot <- struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := oes.ExtractWiFiInfoContext(ctx, time.Minute, "http://192.0.2.1", "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("err = %v, want cancellation", err)
+	}
+}
+
+func TestParseExtractionOutputReadsRunDir(t *testing.T) {
+	oes := newTestExtractor(t)
+	runDir := t.TempDir()
+
+	// A stale file in the shared directory must not be picked up
+	stale := `{"ssid":"stale","password":"stale"}`
+	if err := os.WriteFile(filepath.Join(oes.webautomationDir, "wifi_info.json"), []byte(stale), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fresh := `{"ssid":"home","password":"secret","ont_model":"ZTE ZXHN F477V2"}`
+	if err := os.WriteFile(filepath.Join(runDir, "zte_f477v2_wifi_info.json"), []byte(fresh), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := oes.parseExtractionOutput(runDir, "", "http://192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SSID != "home" || info.Password != "secret" || info.ONTModel != "ZTE ZXHN F477V2" {
+		t.Fatalf("parsed %+v, want the run directory's file", info)
+	}
+}
+
+func TestParseExtractionOutputFallsBackToConsole(t *testing.T) {
+	oes := newTestExtractor(t)
+
+	info, err := oes.parseExtractionOutput(t.TempDir(), "SSID: office\nPassword: hunter2\n", "http://192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SSID != "office" || info.Password != "hunter2" {
+		t.Fatalf("parsed %+v from console output", info)
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+// ErrWiFiExtractRunInProgress is returned when extract-all is triggered while a run is going
+var ErrWiFiExtractRunInProgress = errors.New("a WiFi extraction run is already running")
+
+// ErrWiFiExtractAllDisabled is returned when extract-all was never configured
+var ErrWiFiExtractAllDisabled = errors.New("WiFi extraction for all ONTs is not configured")
+
+const (
+	// wifiExtractAllConcurrency bounds how many ONTs one run works on at once
+	wifiExtractAllConcurrency = 3
+	// wifiExtractAllTargetTimeout bounds a single ONT's extraction
+	wifiExtractAllTargetTimeout = 60 * time.Second
+	// wifiExtractAllRunTimeout bounds a whole run
+	wifiExtractAllRunTimeout = 2 * time.Hour
+)
+
+// ConfigureExtractAll enables extracting WiFi info from the ONT behind every
+// router's ONT NAT rule. ontUsername/ontPassword log in to the ONTs (empty
+// uses the extractor's admin/admin default). Results are stored through
+// SaveWiFiInfo.
+func (oes *ONTExtractorService) ConfigureExtractAll(natService *NATService, activityLog *ActivityLogService, ontUsername, ontPassword string) {
+	oes.natService = natService
+	oes.activityLog = activityLog
+	oes.ontUsername = ontUsername
+	oes.ontPassword = ontPassword
+}
+
+// StartSchedule runs extract-all whenever the 5-field cron expression
+// matches. An empty expression leaves scheduled runs off.
+func (oes *ONTExtractorService) StartSchedule(cronExpr string) error {
+	if cronExpr == "" {
+		oes.logger.Info("Scheduled WiFi extraction disabled (WIFI_EXTRACT_SCHEDULE not set)")
+		return nil
+	}
+	if oes.natService == nil {
+		return ErrWiFiExtractAllDisabled
+	}
+
+	schedule, err := parseCronSchedule(cronExpr)
+	if err != nil {
+		return fmt.Errorf("invalid WiFi extraction schedule %q: %w", cronExpr, err)
+	}
+
+	oes.wg.Add(1)
+	go oes.scheduleLoop(schedule)
+	oes.logger.Infof("✅ Scheduled WiFi extraction started (%s)", cronExpr)
+	return nil
+}
+
+// Stop stops the schedule, cancels a running extract-all and waits for it to exit
+func (oes *ONTExtractorService) Stop() {
+	oes.cancel()
+	oes.wg.Wait()
+}
+
+// scheduleLoop checks the schedule once per minute
+func (oes *ONTExtractorService) scheduleLoop(schedule *cronSchedule) {
+	defer oes.wg.Done()
+
+	ticker := time.NewTicker(auditSchedulerTick)
+	defer ticker.Stop()
+
+	lastMinute := time.Now().Truncate(time.Minute)
+	for {
+		select {
+		case <-oes.ctx.Done():
+			return
+		case now := <-ticker.C:
+			minute := now.Truncate(time.Minute)
+			if !minute.After(lastMinute) {
+				continue
+			}
+			lastMinute = minute
+			if !schedule.Matches(minute) {
+				continue
+			}
+			if err := oes.TriggerExtractAll("schedule"); err != nil {
+				oes.logger.Warnf("⚠️ Skipping scheduled WiFi extraction: %v", err)
+			}
+		}
+	}
+}
+
+// TriggerExtractAll starts an extract-all run in the background. Returns
+// ErrWiFiExtractRunInProgress if one is already running.
+func (oes *ONTExtractorService) TriggerExtractAll(triggeredBy string) error {
+	if oes.natService == nil {
+		return ErrWiFiExtractAllDisabled
+	}
+	if !oes.running.CompareAndSwap(false, true) {
+		return ErrWiFiExtractRunInProgress
+	}
+
+	oes.logger.Infof("📡 WiFi extraction for all ONTs started by %s", triggeredBy)
+
+	oes.wg.Add(1)
+	go func() {
+		defer oes.wg.Done()
+		defer oes.running.Store(false)
+		oes.extractAll(triggeredBy)
+	}()
+	return nil
+}
+
+// wifiExtractTarget is the ONT currently behind one router's ONT NAT rule
+type wifiExtractTarget struct {
+	router        string
+	ontURL        string
+	pppoeUsername string
+}
+
+// extractAll extracts and stores WiFi info for every router's ONT, then logs a summary
+func (oes *ONTExtractorService) extractAll(triggeredBy string) {
+	ctx, cancel := context.WithTimeout(oes.ctx, wifiExtractAllRunTimeout)
+	defer cancel()
+
+	startTime := time.Now()
+	targets, skipped := oes.extractAllTargets()
+
+	var mu sync.Mutex
+	successful, failed := 0, 0
+	var failures []string
+
+	sem := make(chan struct{}, wifiExtractAllConcurrency)
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target wifiExtractTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := oes.extractAndSave(ctx, target, triggeredBy)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				failures = append(failures, fmt.Sprintf("%s: %v", target.router, err))
+				return
+			}
+			successful++
+		}(target)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(startTime)
+	description := fmt.Sprintf("Extracted WiFi info from %d of %d ONTs (%d failed, %d routers without ONT NAT rule) in %v",
+		successful, len(targets), failed, skipped, elapsed.Round(time.Second))
+	oes.logger.Infof("📡 %s", description)
+
+	if oes.activityLog == nil {
+		return
+	}
+	status := models.StatusSuccess
+	errorMessage := ""
+	if failed > 0 {
+		status = models.StatusFailed
+		sort.Strings(failures)
+		errorMessage = fmt.Sprintf("%d ONTs failed: %v", failed, failures)
+	}
+	durationMs := int(elapsed.Milliseconds())
+	_ = oes.activityLog.CreateLog(&models.ActivityLogCreate{
+		Username:     triggeredBy,
+		ActionType:   models.ActionONTWiFiExtractAll,
+		ResourceType: "ONT",
+		ResourceID:   "all",
+		Description:  description,
+		Status:       status,
+		ErrorMessage: errorMessage,
+		DurationMs:   &durationMs,
+		Metadata: map[string]interface{}{
+			"targets":    len(targets),
+			"successful": successful,
+			"failed":     failed,
+			"skipped":    skipped,
+		},
+	})
+}
+
+// extractAllTargets lists the ONT of every router with an ONT NAT rule that
+// has a public URL, and how many routers were skipped for lacking one
+func (oes *ONTExtractorService) extractAllTargets() ([]wifiExtractTarget, int) {
+	configs := oes.natService.GetAllONTConfigs()
+
+	routers := make([]string, 0, len(configs))
+	for router := range configs {
+		routers = append(routers, router)
+	}
+	sort.Strings(routers)
+
+	targets := []wifiExtractTarget{}
+	skipped := 0
+	for _, router := range routers {
+		config := configs[router]
+		if !config.Found || config.PublicONTURL == "" {
+			skipped++
+			continue
+		}
+		targets = append(targets, wifiExtractTarget{
+			router:        router,
+			ontURL:        config.PublicONTURL,
+			pppoeUsername: oes.pppoeUsernameForIP(router, config.CurrentIP),
+		})
+	}
+	return targets, skipped
+}
+
+// pppoeUsernameForIP finds the online PPPoE client holding ip on router, or ""
+func (oes *ONTExtractorService) pppoeUsernameForIP(router, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	clients, _, err := oes.natService.CachedRouterClients(router)
+	if err != nil {
+		oes.logger.Debugf("Could not resolve PPPoE user for %s on %s: %v", ip, router, err)
+		return ""
+	}
+	for _, client := range clients {
+		if client.IPAddress == ip {
+			return client.Username
+		}
+	}
+	return ""
+}
+
+// extractAndSave extracts one target's WiFi info and stores it
+func (oes *ONTExtractorService) extractAndSave(ctx context.Context, target wifiExtractTarget, triggeredBy string) error {
+	wifiInfo, err := oes.ExtractWiFiInfoContext(ctx, wifiExtractAllTargetTimeout, target.ontURL, oes.ontUsername, oes.ontPassword, false)
+	if err != nil {
+		return err
+	}
+
+	wifiInfo.PPPoEUsername = target.pppoeUsername
+	wifiInfo.Router = target.router
+	wifiInfo.ExtractedBy = triggeredBy
+
+	_, err = oes.SaveWiFiInfo(ctx, wifiInfo)
+	return err
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+// fakeWiFiStore is a WiFiInfoStore keeping the latest extraction per PPPoE user
+type fakeWiFiStore struct {
+	latest  map[string]*models.ONTWiFiInfo
+	saved   []*models.ONTWiFiInfo
+	lookups []string
+	saveErr error
+}
+
+func (s *fakeWiFiStore) SaveWiFiInfo(ctx context.Context, info *models.ONTWiFiInfo) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.saved = append(s.saved, info)
+	return nil
+}
+
+func (s *fakeWiFiStore) FindLatestWiFiInfoByPPPoE(ctx context.Context, pppoeUsername string) (*models.ONTWiFiInfo, error) {
+	s.lookups = append(s.lookups, pppoeUsername)
+	return s.latest[pppoeUsername], nil
+}
+
+func TestSaveWiFiInfoReportsChangedFields(t *testing.T) {
+	store := &fakeWiFiStore{latest: map[string]*models.ONTWiFiInfo{
+		"alice": {SSID: "home", Password: "old"},
+	}}
+	oes := newTestExtractor(t)
+	oes.SetWiFiRepository(store)
+
+	changed, err := oes.SaveWiFiInfo(context.Background(), &models.ONTWiFiInfo{PPPoEUsername: "alice", SSID: "home", Password: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != "password" {
+		t.Fatalf("changed = %v, want [password]", changed)
+	}
+	if len(store.saved) != 1 {
+		t.Fatalf("saved %d extractions, want 1", len(store.saved))
+	}
+
+	// Without a PPPoE username there is nothing to compare with
+	changed, err = oes.SaveWiFiInfo(context.Background(), &models.ONTWiFiInfo{SSID: "other"})
+	if err != nil || changed != nil {
+		t.Fatalf("changed = %v, err = %v, want no comparison", changed, err)
+	}
+	if len(store.lookups) != 1 {
+		t.Fatalf("lookups = %v, want only alice's", store.lookups)
+	}
+
+	store.saveErr = errors.New("database down")
+	if _, err := oes.SaveWiFiInfo(context.Background(), &models.ONTWiFiInfo{PPPoEUsername: "alice"}); !errors.Is(err, store.saveErr) {
+		t.Fatalf("err = %v, want the save error", err)
+	}
+}
+
+func TestExtractAndSaveComparesWithPreviousExtraction(t *testing.T) {
+	oes := newTestExtractor(t)
+	// A launcher printing the extractor's console format
+	launcher := "#!/bin/sh\nprintf 'SSID: home\\nPassword: changed\\n'\n"
+	if err := os.WriteFile(filepath.Join(oes.webautomationDir, "ont-extractor-launcher.js"), []byte(launcher), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oes.nodeCommand = "sh"
+
+	store := &fakeWiFiStore{latest: map[string]*models.ONTWiFiInfo{
+		"alice": {SSID: "home", Password: "old"},
+	}}
+	oes.SetWiFiRepository(store)
+
+	target := wifiExtractTarget{router: "SAMSAT", ontURL: "http://192.0.2.1", pppoeUsername: "alice"}
+	if err := oes.extractAndSave(context.Background(), target, "schedule"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.lookups) != 1 || store.lookups[0] != "alice" {
+		t.Fatalf("lookups = %v, want the previous extraction of alice", store.lookups)
+	}
+	if len(store.saved) != 1 {
+		t.Fatalf("saved %d extractions, want 1", len(store.saved))
+	}
+	saved := store.saved[0]
+	if saved.Router != "SAMSAT" || saved.ExtractedBy != "schedule" || saved.Password != "changed" {
+		t.Fatalf("saved %+v", saved)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy is the set of rules new passwords must satisfy
+type PasswordPolicy struct {
+	MinLength    int  // Minimum number of characters
+	RequireMixed bool // At least one letter and one digit
+}
+
+// DefaultPasswordPolicy is used until SetPasswordPolicy changes it
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, RequireMixed: true}
+
+// PasswordPolicyError explains which rule a password broke. Constraint is a
+// short machine-readable name (min_length, mixed, not_username).
+type PasswordPolicyError struct {
+	Constraint string
+	Message    string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+// Validate checks password against the policy. username may be empty.
+func (p PasswordPolicy) Validate(password, username string) error {
+	if len([]rune(password)) < p.MinLength {
+		return &PasswordPolicyError{
+			Constraint: fmt.Sprintf("min_length:%d", p.MinLength),
+			Message:    fmt.Sprintf("Password must be at least %d characters", p.MinLength),
+		}
+	}
+
+	if p.RequireMixed {
+		var hasLetter, hasDigit bool
+		for _, r := range password {
+			switch {
+			case unicode.IsLetter(r):
+				hasLetter = true
+			case unicode.IsDigit(r):
+				hasDigit = true
+			}
+		}
+		if !hasLetter || !hasDigit {
+			return &PasswordPolicyError{
+				Constraint: "mixed",
+				Message:    "Password must contain at least one letter and one digit",
+			}
+		}
+	}
+
+	if username != "" && strings.EqualFold(strings.TrimSpace(password), strings.TrimSpace(username)) {
+		return &PasswordPolicyError{
+			Constraint: "not_username",
+			Message:    "Password must not be the same as the username",
+		}
+	}
+
+	return nil
+}
+
+// SetPasswordPolicy sets the rules applied to created and changed passwords.
+// A minimum length below 1 keeps the default.
+func (s *UserService) SetPasswordPolicy(minLength int, requireMixed bool) {
+	if minLength < 1 {
+		minLength = DefaultPasswordPolicy.MinLength
+	}
+	s.passwordPolicy = PasswordPolicy{MinLength: minLength, RequireMixed: requireMixed}
+	s.logger.Infof("🔑 Password policy: min %d characters, letters and digits required: %v", minLength, requireMixed)
+}
+
+// ValidatePassword checks a new password for username against the configured policy
+func (s *UserService) ValidatePassword(password, username string) error {
+	return s.passwordPolicy.Validate(password, username)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestAuditPPPoEUsernamesMatchesSessions(t *testing.T) {
+	north := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{
+			reSentence("name", "alice", "address", "10.0.1.2", "uptime", "1h"),
+			reSentence("name", "bob", "address", "10.0.1.3", "uptime", "5m"),
+			{"!done"},
+		}
+	})
+	south := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{
+			reSentence("name", "carol", "address", "10.0.2.2", "uptime", "2d"),
+			{"!done"},
+		}
+	})
+	down := newFakeRouter(t, func(command []string) [][]string {
+		return hangUp
+	})
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{"NORTH": north, "SOUTH": south, "DOWN": down, "HIDDEN": south})
+
+	entries := []models.PPPoEAuditEntry{
+		{Username: "alice"},
+		{Username: "carol"},
+		{Username: "bob", ExpectedRouter: "SOUTH"},
+		{Username: "dave", ExpectedRouter: "DOWN"},
+		{Username: "carol", ExpectedRouter: "HIDDEN"},
+	}
+	rows := ns.AuditPPPoEUsernames(context.Background(), entries, []string{"NORTH", "SOUTH", "DOWN"})
+
+	want := []models.PPPoEAuditRow{
+		{Username: "alice", Status: models.PPPoEAuditOnline, Router: "NORTH", IPAddress: "10.0.1.2", Uptime: "1h"},
+		{Username: "carol", Status: models.PPPoEAuditOnline, Router: "SOUTH", IPAddress: "10.0.2.2", Uptime: "2d"},
+		{Username: "bob", ExpectedRouter: "SOUTH", Status: models.PPPoEAuditOffline},
+		{Username: "dave", ExpectedRouter: "DOWN", Status: models.PPPoEAuditError, Router: "DOWN"},
+		{Username: "carol", ExpectedRouter: "HIDDEN", Status: models.PPPoEAuditNoAccess},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, row := range rows {
+		w := want[i]
+		if row.Username != w.Username || row.ExpectedRouter != w.ExpectedRouter || row.Status != w.Status ||
+			row.Router != w.Router || row.IPAddress != w.IPAddress || row.Uptime != w.Uptime {
+			t.Errorf("row %d = %+v, want %+v", i, row, w)
+		}
+	}
+	if rows[3].Note == "" {
+		t.Error("error row has no note explaining the failure")
+	}
+}
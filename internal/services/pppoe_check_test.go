@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+func TestCheckPPPoEStatusSlowRouterTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	slow := newFakeRouter(t, func(command []string) [][]string {
+		<-release
+		return [][]string{{"!done"}}
+	})
+	t.Cleanup(func() { close(release) })
+
+	online := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{reSentence("name", "alice", "address", "10.0.0.2", "uptime", "1h"), {"!done"}}
+	})
+	offline := newFakeRouter(t, func(command []string) [][]string {
+		return [][]string{{"!done"}}
+	})
+
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{"SLOW": slow, "ONLINE": online, "OFFLINE": offline})
+	pool := ns.routerService.(*poolRouterService).pool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	response := ns.checkPPPoEStatusInternal(ctx, "alice", "", nil, false)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check took %v, want about the 200ms deadline", elapsed)
+	}
+
+	if !response.IsOnline || response.OnlineCount != 1 {
+		t.Fatalf("online = %v (%d), want alice online on one router", response.IsOnline, response.OnlineCount)
+	}
+	want := map[string]string{
+		"ONLINE":  models.PPPoECheckOnline,
+		"OFFLINE": models.PPPoECheckOffline,
+		"SLOW":    models.PPPoECheckTimeout,
+	}
+	for router, status := range want {
+		if got := response.Data[router].CheckStatus; got != status {
+			t.Errorf("%s check status = %q, want %q", router, got, status)
+		}
+	}
+	if ip := response.Data["ONLINE"].IPAddress; ip != "10.0.0.2" {
+		t.Errorf("ONLINE address = %q, want 10.0.0.2", ip)
+	}
+
+	// The slow router's worker gives up with the deadline instead of holding
+	// its connection until the router answers
+	for deadline := time.Now().Add(time.Second); pool.InUse() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connection(s) still borrowed after the deadline", pool.InUse())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// requestLoggerKey is the context key for the request-scoped log entry
+type requestLoggerKey struct{}
+
+// WithRequestLogger returns a copy of ctx carrying the request-scoped log entry
+func WithRequestLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, requestLoggerKey{}, entry)
+}
+
+// RequestLogger returns the log entry carried by ctx, or a plain entry from
+// fallback when the call didn't originate from an HTTP request
+func RequestLogger(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(requestLoggerKey{}).(*logrus.Entry); ok && entry != nil {
+			return entry
+		}
+	}
+	return logrus.NewEntry(fallback)
+}
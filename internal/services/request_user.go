@@ -0,0 +1,20 @@
+package services
+
+import "context"
+
+// requestUserIDKey is the context key for the ID of the user making the request
+type requestUserIDKey struct{}
+
+// WithRequestUserID returns a copy of ctx carrying the requesting user's ID
+func WithRequestUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, requestUserIDKey{}, userID)
+}
+
+// RequestUserID returns the requesting user's ID carried by ctx, if any
+func RequestUserID(ctx context.Context) (int, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	userID, ok := ctx.Value(requestUserIDKey{}).(int)
+	return userID, ok
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+)
+
+func TestSetRoleRoutersChangesAccess(t *testing.T) {
+	db := migratedTestDB(t)
+	rs := NewRouterServiceDB(quietLogger(), db, RouterPoolConfig{})
+	t.Cleanup(rs.Close)
+
+	repo := database.NewRouterRepository(db)
+	now := time.Now()
+	for _, name := range []string{"SAMSAT", "LANE1", "LANE2"} {
+		router := &models.Router{
+			ID: strings.ToLower(name) + "-test", Name: name, Host: "192.0.2.1", Port: 8728,
+			Username: "admin", Password: "secret", Enabled: true, Tags: []string{},
+			CreatedAt: now, UpdatedAt: now,
+		}
+		if err := repo.Create(context.Background(), router); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+
+	role := string(models.RoleHeadBranch1)
+	visible := func() string {
+		t.Helper()
+		routers, err := rs.GetAllRouters(role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, router := range routers {
+			names = append(names, router.Name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+
+	// Seed data gives Head Branch 1 SAMSAT and LANE1
+	if got := visible(); got != "LANE1,SAMSAT" {
+		t.Fatalf("seeded access = %q, want LANE1,SAMSAT", got)
+	}
+
+	steps := []struct {
+		routers []string
+		want    string
+	}{
+		{[]string{"LANE2"}, "LANE2"},
+		{[]string{"*"}, "LANE1,LANE2,SAMSAT"},
+		{[]string{}, ""},
+	}
+	for _, step := range steps {
+		if _, err := rs.SetRoleRouters(role, step.routers); err != nil {
+			t.Fatalf("set %v: %v", step.routers, err)
+		}
+		// The next request already sees the new list
+		if got := visible(); got != step.want {
+			t.Fatalf("after setting %v: access = %q, want %q", step.routers, got, step.want)
+		}
+	}
+
+	// Other roles keep their own lists
+	routers, err := rs.GetAllRouters(string(models.RoleAdministrator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routers) != 3 {
+		t.Fatalf("administrator sees %d routers, want all 3", len(routers))
+	}
+}
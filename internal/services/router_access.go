@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+)
+
+// RouterAccessSource says which rule granted a user's router list
+type RouterAccessSource string
+
+const (
+	AccessSourceUserAssignment RouterAccessSource = "user_assignment" // user_routers rows
+	AccessSourceRole           RouterAccessSource = "role"            // router_access_control for the user's role
+	AccessSourceWildcard       RouterAccessSource = "wildcard"        // router_access_control '*' row for the user's role
+	AccessSourceNoAccess       RouterAccessSource = "no_access"       // users.no_router_access: deliberately no routers
+	AccessSourceLookupFailed   RouterAccessSource = "lookup_failed"   // Assignments couldn't be read, so no routers
+)
+
+// ResolveUserRouters returns the routers a user can operate on and where the
+// list came from: none if the user is explicitly restricted to no routers,
+// the user's own assignments if they have any, otherwise their role's routers.
+// If the assignments can't be read it fails closed: no routers, with the
+// lookup error so the caller can log it.
+func ResolveUserRouters(userService UserAccessLookup, natService NATServiceInterface, user *models.User) ([]string, RouterAccessSource, error) {
+	restriction, err := UserRouterRestriction(userService, user)
+	switch {
+	case err != nil:
+		return []string{}, AccessSourceLookupFailed, err
+	case restriction == nil:
+		return natService.GetAvailableRoutersWithFilter(models.GetRoleForRouterAccess(user.Role)), AccessSourceRole, nil
+	case len(restriction) == 0:
+		return restriction, AccessSourceNoAccess, nil
+	default:
+		return restriction, AccessSourceUserAssignment, nil
+	}
+}
+
+// UserRouterRestriction returns the routers a user's own access limits them
+// to on top of their role: nil when the role decides alone, the user's
+// assignments, or none with no_router_access. Lookup errors are returned for
+// the caller to fail closed on.
+func UserRouterRestriction(userService UserAccessLookup, user *models.User) ([]string, error) {
+	userRouterNames, noAccess, err := userService.GetUserRouterAccess(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if noAccess {
+		return []string{}, nil
+	}
+	if len(userRouterNames) > 0 {
+		return userRouterNames, nil
+	}
+	return nil, nil
+}
+
+// ExplainRouterAccess reports whether user can reach routerName and why,
+// using the same resolution as ResolveUserRouters
+func ExplainRouterAccess(userService UserAccessLookup, natService NATServiceInterface, user *models.User, routerName string) models.RouterAccessDecision {
+	decision := models.RouterAccessDecision{
+		UserID:           user.ID,
+		Username:         user.Username,
+		Role:             string(user.Role),
+		Router:           routerName,
+		RouterConfigured: natService.HasRouter(routerName),
+	}
+
+	routers, source, err := ResolveUserRouters(userService, natService, user)
+	decision.Source = string(source)
+	decision.EffectiveRouters = routers
+	if decision.EffectiveRouters == nil {
+		decision.EffectiveRouters = []string{}
+	}
+
+	for _, name := range routers {
+		if name == routerName {
+			decision.Allowed = true
+			break
+		}
+	}
+
+	switch {
+	case err != nil:
+		decision.Reason = "User assignments could not be read, so access is denied"
+	case source == AccessSourceNoAccess:
+		decision.Reason = "User is explicitly restricted to no routers (role access is not used)"
+	case decision.Allowed && source == AccessSourceUserAssignment:
+		decision.Reason = "Router is assigned to the user"
+	case decision.Allowed:
+		decision.Reason = "User has no router assignments; router is allowed for role " + decision.Role
+	case source == AccessSourceUserAssignment:
+		decision.Reason = "User has router assignments that don't include this router (role access is not used when assignments exist)"
+	case !decision.RouterConfigured:
+		decision.Reason = "Router is not configured or is disabled"
+	default:
+		decision.Reason = "User has no router assignments and role " + decision.Role + " does not allow this router"
+	}
+
+	return decision
+}
+
+// GetEffectiveRouters lists every router a user is granted, sorted by name,
+// with each grant that applies. A router granted several ways appears once,
+// with Reason set to the most specific grant (user assignment, then role,
+// then wildcard). NATAccess mirrors ResolveUserRouters: when the user has
+// assignments, role grants don't apply to NAT/PPPoE operations.
+func (s *UserService) GetEffectiveRouters(user *models.User) ([]models.EffectiveRouter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	assigned, noAccess, err := s.GetUserRouterAccess(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user routers: %w", err)
+	}
+	if noAccess {
+		return []models.EffectiveRouter{}, nil
+	}
+
+	roleRouters, err := database.NewAccessControlRepository(s.db).GetRouterNamesByRole(ctx, models.GetRoleForRouterAccess(user.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	// A '*' row grants every router in the table
+	var wildcardRouters []string
+	for _, name := range roleRouters {
+		if name != "*" {
+			continue
+		}
+		allRouters, err := database.NewRouterRepository(s.db).GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, router := range allRouters {
+			wildcardRouters = append(wildcardRouters, router.Name)
+		}
+		break
+	}
+
+	return mergeRouterGrants(assigned, roleRouters, wildcardRouters), nil
+}
+
+// mergeRouterGrants combines a user's assignments, their role's routers and
+// the routers a role wildcard covers into one entry per router, sorted by name
+func mergeRouterGrants(assigned, roleRouters, wildcardRouters []string) []models.EffectiveRouter {
+	byName := make(map[string]*models.EffectiveRouter)
+	grant := func(name string, source RouterAccessSource) {
+		entry, exists := byName[name]
+		if !exists {
+			entry = &models.EffectiveRouter{Name: name, Reason: string(source)}
+			byName[name] = entry
+		}
+		for _, existing := range entry.Grants {
+			if existing == string(source) {
+				return
+			}
+		}
+		entry.Grants = append(entry.Grants, string(source))
+	}
+
+	for _, name := range assigned {
+		grant(name, AccessSourceUserAssignment)
+	}
+	for _, name := range roleRouters {
+		if name != "*" {
+			grant(name, AccessSourceRole)
+		}
+	}
+	for _, name := range wildcardRouters {
+		grant(name, AccessSourceWildcard)
+	}
+
+	hasAssignments := len(assigned) > 0
+	routers := make([]models.EffectiveRouter, 0, len(byName))
+	for _, entry := range byName {
+		entry.NATAccess = !hasAssignments || entry.Reason == string(AccessSourceUserAssignment)
+		routers = append(routers, *entry)
+	}
+	sort.Slice(routers, func(i, j int) bool { return routers[i].Name < routers[j].Name })
+
+	return routers
+}
@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"nat-management-app/internal/models"
+	"nat-management-app/internal/services"
+	"nat-management-app/internal/services/mocks"
+)
+
+func TestResolveUserRouters(t *testing.T) {
+	natService := &mocks.NATService{Routers: map[string]string{
+		"SAMSAT": string(models.RoleHeadBranch1),
+		"LANE1":  string(models.RoleHeadBranch1),
+		"LANE2":  string(models.RoleHeadBranch2),
+	}}
+	user := &models.User{ID: 3, Username: "head1", Role: models.RoleHeadBranch1}
+
+	tests := []struct {
+		name       string
+		access     *mocks.UserAccess
+		want       []string
+		wantSource services.RouterAccessSource
+		wantErr    bool
+	}{
+		{"no assignments means role default", &mocks.UserAccess{}, []string{"LANE1", "SAMSAT"}, services.AccessSourceRole, false},
+		{"assignments override the role", &mocks.UserAccess{Assignments: map[int][]string{3: {"LANE2"}}}, []string{"LANE2"}, services.AccessSourceUserAssignment, false},
+		{"explicit no access", &mocks.UserAccess{NoAccess: map[int]bool{3: true}}, []string{}, services.AccessSourceNoAccess, false},
+		{"lookup error fails closed", &mocks.UserAccess{Err: errors.New("connection refused")}, []string{}, services.AccessSourceLookupFailed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routers, source, err := services.ResolveUserRouters(tt.access, natService, user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if source != tt.wantSource {
+				t.Errorf("source = %s, want %s", source, tt.wantSource)
+			}
+			if routers == nil {
+				t.Fatal("routers is nil, want an empty list at least")
+			}
+			sort.Strings(routers)
+			if len(routers) != len(tt.want) {
+				t.Fatalf("routers = %v, want %v", routers, tt.want)
+			}
+			for i := range routers {
+				if routers[i] != tt.want[i] {
+					t.Fatalf("routers = %v, want %v", routers, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainRouterAccessLookupError(t *testing.T) {
+	natService := &mocks.NATService{Routers: map[string]string{"SAMSAT": ""}}
+	user := &models.User{ID: 3, Username: "head1", Role: models.RoleHeadBranch1}
+
+	decision := services.ExplainRouterAccess(&mocks.UserAccess{Err: errors.New("timeout")}, natService, user, "SAMSAT")
+	if decision.Allowed {
+		t.Fatal("access allowed although the assignments couldn't be read")
+	}
+	if decision.Source != string(services.AccessSourceLookupFailed) {
+		t.Errorf("source = %s", decision.Source)
+	}
+}
+
+func TestExplainRouterAccess(t *testing.T) {
+	natService := &mocks.NATService{Routers: map[string]string{
+		"SAMSAT": string(models.RoleHeadBranch1),
+		"LANE1":  string(models.RoleHeadBranch1),
+		"LANE2":  string(models.RoleHeadBranch2),
+	}}
+	user := &models.User{ID: 3, Username: "head1", Role: models.RoleHeadBranch1}
+	assignedLane2 := &mocks.UserAccess{Assignments: map[int][]string{3: {"LANE2"}}}
+
+	tests := []struct {
+		name        string
+		access      *mocks.UserAccess
+		router      string
+		wantAllowed bool
+		wantSource  services.RouterAccessSource
+		wantReason  string
+	}{
+		{"user grant", assignedLane2, "LANE2", true, services.AccessSourceUserAssignment, "Router is assigned to the user"},
+		{"role grant", &mocks.UserAccess{}, "SAMSAT", true, services.AccessSourceRole,
+			"User has no router assignments; router is allowed for role Head Branch 1"},
+		{"assignments shadow the role", assignedLane2, "SAMSAT", false, services.AccessSourceUserAssignment,
+			"User has router assignments that don't include this router (role access is not used when assignments exist)"},
+		{"other role's router", &mocks.UserAccess{}, "LANE2", false, services.AccessSourceRole,
+			"User has no router assignments and role Head Branch 1 does not allow this router"},
+		{"unknown router", &mocks.UserAccess{}, "GHOST", false, services.AccessSourceRole, "Router is not configured or is disabled"},
+		{"no access", &mocks.UserAccess{NoAccess: map[int]bool{3: true}}, "SAMSAT", false, services.AccessSourceNoAccess,
+			"User is explicitly restricted to no routers (role access is not used)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := services.ExplainRouterAccess(tt.access, natService, user, tt.router)
+			if decision.Allowed != tt.wantAllowed || decision.Source != string(tt.wantSource) {
+				t.Fatalf("decision = %+v, want allowed %v via %s", decision, tt.wantAllowed, tt.wantSource)
+			}
+			if decision.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", decision.Reason, tt.wantReason)
+			}
+			if decision.Router != tt.router || decision.Username != "head1" || decision.EffectiveRouters == nil {
+				t.Errorf("decision = %+v, want it to describe head1 on %s", decision, tt.router)
+			}
+		})
+	}
+}
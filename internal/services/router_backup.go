@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+// MaxRouterImportRows bounds one router import
+const MaxRouterImportRows = 500
+
+// routerBackupVersion is written into every backup file
+const routerBackupVersion = "1.0"
+
+// SetBackupDir sets the directory POST /api/routers/backup writes to
+func (rs *RouterServiceDB) SetBackupDir(dir string) {
+	rs.backupDir = dir
+}
+
+// ExportRouters returns every router userRole can access in import form.
+// Passwords are only included for administrators that ask for them.
+func (rs *RouterServiceDB) ExportRouters(userRole string, includePasswords bool) ([]models.RouterCreateRequest, error) {
+	if includePasswords && userRole != "Administrator" {
+		return nil, fmt.Errorf("insufficient permissions to export router passwords")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	routers, err := rs.routerRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routers: %w", err)
+	}
+
+	allowedRouters, err := rs.getAllowedRouters(ctx, userRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowed routers: %w", err)
+	}
+
+	exported := []models.RouterCreateRequest{}
+	for _, router := range routers {
+		if rs.hasRouterAccess(router.Name, allowedRouters) {
+			exported = append(exported, router.ToCreateRequest(includePasswords))
+		}
+	}
+	return exported, nil
+}
+
+// ImportRouter imports one router. A new name goes through CreateRouter; an
+// existing router (name matched ignoring case) is skipped, or with overwrite
+// updated through UpdateRouter. An overwrite without a password keeps the
+// stored one, so exports without passwords can be re-imported.
+func (rs *RouterServiceDB) ImportRouter(req *models.RouterCreateRequest, overwrite bool, userRole string) (ImportOutcome, error) {
+	if userRole != "Administrator" {
+		return "", fmt.Errorf("insufficient permissions to import routers")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	existing, err := rs.routerRepo.FindByNameKey(ctx, models.NormalizeRouterName(req.Name))
+	cancel()
+	if err != nil {
+		return "", fmt.Errorf("failed to check router existence: %w", err)
+	}
+
+	if existing == nil {
+		if _, err := rs.CreateRouter(req, userRole); err != nil {
+			return "", err
+		}
+		return ImportCreated, nil
+	}
+
+	if !overwrite {
+		return ImportSkipped, nil
+	}
+
+	password := req.Password
+	if password == "" {
+		password = existing.Password
+	}
+	_, err = rs.UpdateRouter(existing.ID, &models.RouterUpdateRequest{
+		Name:               existing.Name,
+		Host:               req.Host,
+		Port:               req.Port,
+		Username:           req.Username,
+		Password:           password,
+		TunnelEndpoint:     req.TunnelEndpoint,
+		PublicONTURL:       req.PublicONTURL,
+		Description:        req.Description,
+		Enabled:            req.Enabled,
+		DefaultONTPort:     req.DefaultONTPort,
+		Critical:           req.Critical,
+		Priority:           req.Priority,
+		UseTLS:             req.UseTLS,
+		TLSSkipVerify:      req.TLSSkipVerify,
+		ONTCommentPatterns: req.ONTCommentPatterns,
+		Tags:               req.Tags,
+	}, userRole)
+	if err != nil {
+		return "", err
+	}
+	return ImportOverwritten, nil
+}
+
+// BackupRouters writes every router and the role access rules as JSON to
+// fileName (a plain file name, empty = timestamped) in the backup directory.
+// Passwords are blanked unless includePasswords is set.
+func (rs *RouterServiceDB) BackupRouters(fileName string, includePasswords bool, userRole string) (*models.RouterBackupResponse, error) {
+	if userRole != "Administrator" {
+		return nil, fmt.Errorf("insufficient permissions to back up routers")
+	}
+	if rs.backupDir == "" {
+		return nil, fmt.Errorf("router backups are not configured")
+	}
+
+	now := time.Now()
+	fileName = strings.TrimSpace(fileName)
+	if fileName == "" {
+		fileName = "routers-" + now.Format("20060102-150405") + ".json"
+	}
+	if filepath.Base(fileName) != fileName || strings.HasPrefix(fileName, ".") {
+		return nil, fmt.Errorf("invalid backup file name: must be a plain file name")
+	}
+	if !strings.HasSuffix(fileName, ".json") {
+		fileName += ".json"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	routers, err := rs.routerRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routers: %w", err)
+	}
+	rules, err := rs.accessControlRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access control rules: %w", err)
+	}
+
+	activeRouters := 0
+	for i := range routers {
+		if !includePasswords {
+			routers[i].Password = ""
+		}
+		if routers[i].Enabled {
+			activeRouters++
+		}
+	}
+	if routers == nil {
+		routers = []models.Router{}
+	}
+
+	roles := make(map[string]models.RouterRole)
+	for _, rule := range rules {
+		role := roles[rule.Role]
+		role.Routers = append(role.Routers, rule.RouterName)
+		if role.Description == "" {
+			role.Description = rule.Description
+		}
+		if len(role.Permissions) == 0 {
+			role.Permissions = rule.Permissions
+		}
+		roles[rule.Role] = role
+	}
+
+	location := filepath.Join(rs.backupDir, fileName)
+	backup := models.RouterStorageConfig{
+		Version:       routerBackupVersion,
+		LastUpdated:   now,
+		Description:   "NAT Management router backup",
+		Routers:       routers,
+		AccessControl: models.RouterAccessControl{Roles: roles},
+		Metadata: models.RouterStorageMetadata{
+			TotalRouters:   len(routers),
+			ActiveRouters:  activeRouters,
+			LastBackup:     now,
+			BackupLocation: location,
+		},
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := os.MkdirAll(rs.backupDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	// Owner-only, the file may hold router passwords
+	if err := os.WriteFile(location, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	rs.logger.Infof("💾 Router backup written to %s (%d routers, passwords included: %v)", location, len(routers), includePasswords)
+	return &models.RouterBackupResponse{
+		Status:         "success",
+		Message:        "Router backup created successfully",
+		BackupLocation: location,
+		BackupSize:     int64(len(data)),
+		RouterCount:    len(routers),
+		BackupTime:     now,
+	}, nil
+}
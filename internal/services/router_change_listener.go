@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"nat-management-app/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// routerChangesChannel is the NOTIFY channel written by migration 012
+const routerChangesChannel = "router_changes"
+
+// routerListenRetryDelay is how long to wait before re-establishing LISTEN
+const routerListenRetryDelay = 30 * time.Second
+
+// routerChangeEvent is the NOTIFY payload for a router write
+type routerChangeEvent struct {
+	Op   string `json:"op"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// RouterChangeListener keeps the NAT router map in sync with the routers table
+// when it's changed by another app instance or directly in SQL. It LISTENs for
+// router_changes notifications and also reconciles on a fixed interval, which
+// covers setups where LISTEN isn't available. Behind a transaction-mode pooler
+// LISTEN doesn't fail, notifications just never arrive, so listening is only
+// enabled on request (ROUTER_CHANGE_LISTEN).
+type RouterChangeListener struct {
+	db                *database.DB
+	natService        *NATService
+	listenEnabled     bool
+	reconcileInterval time.Duration // 0 disables periodic reconcile
+	logger            *logrus.Logger
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+}
+
+// NewRouterChangeListener creates a new router change listener
+func NewRouterChangeListener(db *database.DB, natService *NATService, listenEnabled bool, reconcileInterval time.Duration, logger *logrus.Logger) *RouterChangeListener {
+	return &RouterChangeListener{
+		db:                db,
+		natService:        natService,
+		listenEnabled:     listenEnabled,
+		reconcileInterval: reconcileInterval,
+		logger:            logger,
+	}
+}
+
+// Start begins listening and reconciling in the background
+func (l *RouterChangeListener) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+
+	if l.listenEnabled {
+		l.wg.Add(1)
+		go l.listenLoop(ctx)
+	}
+
+	if l.reconcileInterval > 0 {
+		l.wg.Add(1)
+		go l.reconcileLoop(ctx)
+	}
+
+	l.logger.Infof("✅ Router change listener started (listen: %t, reconcile every %v)", l.listenEnabled, l.reconcileInterval)
+}
+
+// Stop stops the listener and waits for its goroutines to exit
+func (l *RouterChangeListener) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+	l.logger.Info("Router change listener stopped")
+}
+
+// listenLoop keeps a LISTEN session open, re-establishing it after failures
+func (l *RouterChangeListener) listenLoop(ctx context.Context) {
+	defer l.wg.Done()
+
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		l.logger.Warnf("⚠️ LISTEN %s unavailable, relying on periodic reconcile: %v", routerChangesChannel, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(routerListenRetryDelay):
+		}
+	}
+}
+
+// listen holds one pooled connection in LISTEN mode until it fails or ctx ends
+func (l *RouterChangeListener) listen(ctx context.Context) error {
+	conn, err := l.db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+routerChangesChannel); err != nil {
+		return err
+	}
+	l.logger.Infof("👂 Listening for router changes on %s", routerChangesChannel)
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			// Don't hand a connection stuck in LISTEN back to the pool
+			conn.Conn().Close(context.Background())
+			return err
+		}
+
+		var event routerChangeEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			l.logger.Warnf("⚠️ Ignoring malformed router change payload: %q", notification.Payload)
+			continue
+		}
+
+		l.logger.Infof("🔔 Router %s changed externally (%s), reloading", event.Name, event.Op)
+		if err := l.natService.ReloadRouters(); err != nil {
+			l.logger.Errorf("Failed to reload routers after change notification: %v", err)
+		}
+	}
+}
+
+// reconcileLoop periodically reloads routers as a safety net for missed notifications
+func (l *RouterChangeListener) reconcileLoop(ctx context.Context) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.natService.ReloadRouters(); err != nil {
+				l.logger.Errorf("Periodic router reconcile failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadConnTrackFiltersOnTheRouter(t *testing.T) {
+	router := newFakeRouter(t, func(command []string) [][]string {
+		if command[0] != "/ip/firewall/connection/print" {
+			return nil
+		}
+		return [][]string{
+			reSentence(".id", "*1", "protocol", "icmp", "src-address", "10.10.1.25", "dst-address", "8.8.8.8"),
+			reSentence(".id", "*2", "protocol", "tcp", "src-address", "192.0.2.7", "dst-address", "10.10.1.25", "tcp-state", "established"),
+			reSentence(".id", "*3", "protocol", "udp", "src-address", "10.10.1.250", "dst-address", "1.1.1.1"),
+			{"!done"},
+		}
+	})
+	pool := newTestPool(t)
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	defer pool.ReleaseConnection(conn)
+
+	entries, truncated, err := readConnTrack(conn, net.ParseIP("10.10.1.25"), 10)
+	if err != nil {
+		t.Fatalf("readConnTrack: %v", err)
+	}
+
+	// The filter goes to the router as query words: src OR dst
+	commands := router.received()
+	if len(commands) != 1 {
+		t.Fatalf("commands = %v, want one print", commands)
+	}
+	got := strings.Join(commands[0], " ")
+	for _, word := range []string{"?src-address=10.10.1.25", "?dst-address=10.10.1.25", "?#|"} {
+		if !strings.Contains(got, word) {
+			t.Errorf("command %q is missing %s", got, word)
+		}
+	}
+
+	// 10.10.1.250 is not 10.10.1.25, whatever the router sent back
+	if truncated || len(entries) != 2 || entries[0].ID != "*1" || entries[1].ID != "*2" {
+		t.Fatalf("entries = %+v (truncated %v), want *1 and *2", entries, truncated)
+	}
+	if entries[1].State != "established" {
+		t.Errorf("state = %q, want established", entries[1].State)
+	}
+
+	entries, truncated, err = readConnTrack(conn, net.ParseIP("10.10.1.25"), 1)
+	if err != nil || len(entries) != 1 || !truncated {
+		t.Fatalf("limit 1: entries = %d, truncated = %v, err = %v", len(entries), truncated, err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/database"
+	"nat-management-app/internal/models"
+)
+
+func TestRotateRouterCredentials(t *testing.T) {
+	db := migratedTestDB(t)
+	rs := NewRouterServiceDB(quietLogger(), db, RouterPoolConfig{})
+	t.Cleanup(rs.Close)
+
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		if command[0] == "/system/resource/print" {
+			return [][]string{reSentence("version", "7.14", "uptime", "1h"), {"!done"}}
+		}
+		return nil
+	})
+	cfg := fr.config()
+	repo := database.NewRouterRepository(db)
+	now := time.Now()
+	if err := repo.Create(context.Background(), &models.Router{
+		ID: "fake-test", Name: "FAKE", Host: cfg.Host, Port: cfg.Port,
+		Username: "admin", Password: "old-secret", Enabled: true, Tags: []string{},
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := func() (string, string) {
+		t.Helper()
+		router, err := repo.GetByID(context.Background(), "fake-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return router.Username, router.Password
+	}
+
+	// Verify then save
+	response, err := rs.RotateRouterCredentials("fake-test", &models.RouterCredentialRotateRequest{Username: "api", Password: "new-secret"}, "Administrator")
+	if err != nil {
+		t.Fatalf("rotate with working credentials: %v", err)
+	}
+	if response.Verification == nil || response.Verification.Status != "connected" {
+		t.Fatalf("verification = %+v, want connected", response.Verification)
+	}
+	if user, pass := stored(); user != "api" || pass != "new-secret" {
+		t.Fatalf("stored credentials = %s/%s, want api/new-secret", user, pass)
+	}
+
+	// The router rejects the next password, so nothing is saved
+	fr.setRejectLogin(true)
+	_, err = rs.RotateRouterCredentials("fake-test", &models.RouterCredentialRotateRequest{Username: "api", Password: "wrong"}, "Administrator")
+	var verifyErr *CredentialVerificationError
+	if !errors.As(err, &verifyErr) || !errors.Is(err, ErrCredentialVerificationFailed) {
+		t.Fatalf("rotate with rejected credentials: err = %v, want a verification error", err)
+	}
+	if verifyErr.Router != "FAKE" || verifyErr.Test.Status == "connected" {
+		t.Fatalf("verification error = %+v, want a failed probe of FAKE", verifyErr)
+	}
+	if user, pass := stored(); user != "api" || pass != "new-secret" {
+		t.Fatalf("stored credentials after a failed verification = %s/%s, want them unchanged", user, pass)
+	}
+
+	// The override saves anyway
+	response, err = rs.RotateRouterCredentials("fake-test", &models.RouterCredentialRotateRequest{Username: "api", Password: "wrong", Force: true}, "Administrator")
+	if err != nil {
+		t.Fatalf("forced rotate: %v", err)
+	}
+	if response.Verification == nil || response.Verification.Status == "connected" {
+		t.Fatalf("forced verification = %+v, want the failed probe reported", response.Verification)
+	}
+	if _, pass := stored(); pass != "wrong" {
+		t.Fatalf("stored password after a forced rotation = %s, want wrong", pass)
+	}
+}
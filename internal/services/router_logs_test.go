@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+var sampleRouterLogs = [][]string{
+	reSentence(".id", "*1", "time", "10:00:01", "topics", "pppoe,ppp,info", "message", "<pppoe-alice>: connected"),
+	reSentence(".id", "*2", "time", "10:00:02", "topics", "pppoe,ppp,error", "message", "<pppoe-bob>: authentication failed"),
+	reSentence(".id", "*3", "time", "10:00:03", "topics", "system,error,critical", "message", "login failure for user admin"),
+	reSentence(".id", "*4", "time", "10:00:04", "topics", "dhcp,info", "message", "lease assigned"),
+	reSentence(".id", "*5", "time", "10:00:05", "topics", "ppp,error", "message", "<pppoe-carol>: terminating"),
+	{"!done"},
+}
+
+func TestReadRouterLogs(t *testing.T) {
+	router := newFakeRouter(t, func(command []string) [][]string {
+		if command[0] == "/log/print" {
+			return sampleRouterLogs
+		}
+		return nil
+	})
+	pool := newTestPool(t)
+
+	tests := []struct {
+		name   string
+		filter models.RouterLogFilter
+		want   []string
+	}{
+		{"no filter", models.RouterLogFilter{}, []string{"*1", "*2", "*3", "*4", "*5"}},
+		{"any topic", models.RouterLogFilter{Topics: []string{"pppoe", "dhcp"}}, []string{"*1", "*2", "*4"}},
+		{"topic is case insensitive", models.RouterLogFilter{Topics: []string{"DHCP"}}, []string{"*4"}},
+		{"severity only", models.RouterLogFilter{Severity: "error"}, []string{"*2", "*3", "*5"}},
+		{"topics and severity", models.RouterLogFilter{Topics: []string{"pppoe"}, Severity: "error"}, []string{"*2"}},
+		{"severity matching nothing", models.RouterLogFilter{Topics: []string{"dhcp"}, Severity: "error"}, nil},
+		{"limit keeps the newest", models.RouterLogFilter{Limit: 2}, []string{"*4", "*5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := pool.GetConnection("fake", router.config())
+			if err != nil {
+				t.Fatalf("GetConnection: %v", err)
+			}
+			defer pool.ReleaseConnection(conn)
+
+			entries, err := readRouterLogs(conn, tt.filter)
+			if err != nil {
+				t.Fatalf("readRouterLogs: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got entries %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got entries %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadRouterLogsParsesEntries(t *testing.T) {
+	router := newFakeRouter(t, func([]string) [][]string { return sampleRouterLogs })
+	pool := newTestPool(t)
+
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	defer pool.ReleaseConnection(conn)
+
+	entries, err := readRouterLogs(conn, models.RouterLogFilter{Topics: []string{"system"}})
+	if err != nil {
+		t.Fatalf("readRouterLogs: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Time != "10:00:03" || entry.Message != "login failure for user admin" || len(entry.Topics) != 3 || entry.Topics[2] != "critical" {
+		t.Fatalf("parsed entry %+v", entry)
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"nat-management-app/internal/models"
+)
+
+func TestRouterNearDuplicateNamesRejected(t *testing.T) {
+	db := migratedTestDB(t)
+	rs := NewRouterServiceDB(quietLogger(), db, RouterPoolConfig{})
+	t.Cleanup(rs.Close)
+
+	newRouter := func(name string) *models.RouterCreateRequest {
+		return &models.RouterCreateRequest{
+			Name: name, Host: "192.0.2.1", Port: 8728, Username: "admin", Password: "secret",
+			TunnelEndpoint: "tunnel.example.net", PublicONTURL: "http://ont.example.net",
+		}
+	}
+
+	created, err := rs.CreateRouter(newRouter("  Hub  Lane2 "), "Administrator")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Name != "Hub Lane2" {
+		t.Fatalf("stored name = %q, want the normalized Hub Lane2", created.Name)
+	}
+
+	for _, name := range []string{"Hub Lane2", "hub lane2", "HUB  LANE2", "Hub Lane2\t"} {
+		if _, err := rs.CreateRouter(newRouter(name), "Administrator"); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("create %q: err = %v, want already exists", name, err)
+		}
+	}
+
+	other, err := rs.CreateRouter(newRouter("Hub Lane3"), "Administrator")
+	if err != nil {
+		t.Fatalf("create other: %v", err)
+	}
+	update := func(id, name string) error {
+		_, err := rs.UpdateRouter(id, &models.RouterUpdateRequest{
+			Name: name, Host: "192.0.2.1", Port: 8728, Username: "admin", Password: "secret",
+			TunnelEndpoint: "tunnel.example.net", PublicONTURL: "http://ont.example.net", Enabled: true,
+		}, "Administrator")
+		return err
+	}
+	if err := update(other.ID, "HUB LANE2"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("rename onto a near-duplicate: err = %v, want already exists", err)
+	}
+	// Changing only the case of its own name is not a collision
+	if err := update(created.ID, "HUB LANE2"); err != nil {
+		t.Errorf("case-only rename: %v", err)
+	}
+}
+
+func TestRouterAssignmentsNormalized(t *testing.T) {
+	db := migratedTestDB(t)
+	rs := NewRouterServiceDB(quietLogger(), db, RouterPoolConfig{})
+	t.Cleanup(rs.Close)
+	if _, err := rs.CreateRouter(&models.RouterCreateRequest{
+		Name: "LANE2", Host: "192.0.2.1", Port: 8728, Username: "admin", Password: "secret",
+		TunnelEndpoint: "tunnel.example.net", PublicONTURL: "http://ont.example.net",
+	}, "Administrator"); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewUserService(db, quietLogger())
+	user, err := s.CreateUser(&CreateUserRequest{
+		Username: "teknisi2",
+		Password: "Rahasia123",
+		FullName: "Teknisi Dua",
+		Email:    "teknisi2@example.com",
+		Routers:  []string{"lane2 ", "LANE2", " Lane2"},
+	})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Every spelling resolves to the stored router name, once
+	routers, err := s.GetUserRouters(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routers) != 1 || routers[0] != "LANE2" {
+		t.Fatalf("assignments = %v, want [LANE2]", routers)
+	}
+
+	if _, err := s.UpdateUser(user.ID, &UpdateUserRequest{
+		FullName: "Teknisi Dua",
+		Email:    "teknisi2@example.com",
+		Routers:  []string{"  lane2"},
+		IsActive: true,
+	}); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if routers, _ = s.GetUserRouters(user.ID); len(routers) != 1 || routers[0] != "LANE2" {
+		t.Fatalf("assignments after update = %v, want [LANE2]", routers)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"nat-management-app/internal/models"
+)
+
+func TestRouterReadinessNeverTestsRouters(t *testing.T) {
+	fr := newFakeRouter(t, nil)
+	ns := newTestNATService(t, fr)
+	ns.cacheTTL = time.Minute
+
+	// No result yet: unchecked, and probing doesn't start a test
+	if readiness := ns.RouterReadiness(); readiness.Checked || readiness.Total != 1 || readiness.Degraded {
+		t.Fatalf("before any test: %+v, want unchecked with 1 router", readiness)
+	}
+
+	checkedAt := time.Now().Add(-2 * time.Minute)
+	ns.cacheMutex.Lock()
+	ns.lastTest = &CachedData{
+		Data:      map[string]models.RouterConnectionTest{"FAKE": {Status: "connected"}},
+		Timestamp: checkedAt,
+	}
+	ns.cacheMutex.Unlock()
+
+	// An old result is reported as stale rather than refreshed
+	for i := 0; i < 3; i++ {
+		readiness := ns.RouterReadiness()
+		if !readiness.Checked || !readiness.Stale || readiness.Connected != 1 || readiness.Degraded {
+			t.Fatalf("stale result: %+v, want checked, stale, 1 connected", readiness)
+		}
+		if !readiness.CheckedAt.Equal(checkedAt) {
+			t.Fatalf("checked_at = %v, want %v", readiness.CheckedAt, checkedAt)
+		}
+	}
+
+	ns.cacheMutex.Lock()
+	ns.lastTest.Timestamp = time.Now()
+	ns.cacheMutex.Unlock()
+	if readiness := ns.RouterReadiness(); readiness.Stale {
+		t.Fatalf("fresh result reported stale: %+v", readiness)
+	}
+
+	// Give a stray background test time to reach the router
+	time.Sleep(100 * time.Millisecond)
+	if commands := fr.received(); len(commands) != 0 {
+		t.Fatalf("readiness probes sent %d command(s) to the router, want none", len(commands))
+	}
+}
+
+func TestRouterReadinessSurvivesCacheInvalidation(t *testing.T) {
+	fr := newFakeRouter(t, nil)
+	fr.setRejectLogin(true)
+	ns := newTestNATService(t, fr)
+
+	if results := ns.TestAllConnections(); results["FAKE"].Status == "connected" {
+		t.Fatalf("test against a rejecting router: %+v, want a failure", results["FAKE"])
+	}
+
+	// NAT writes drop the response caches, but not the readiness snapshot
+	ns.invalidateCache()
+	readiness := ns.RouterReadiness()
+	if !readiness.Checked || readiness.Connected != 0 || !readiness.Degraded {
+		t.Fatalf("after invalidation: %+v, want checked and degraded", readiness)
+	}
+}
@@ -2,14 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"nat-management-app/internal/database"
 	"nat-management-app/internal/models"
 
+	"github.com/go-routeros/routeros"
+	"github.com/go-routeros/routeros/proto"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -22,22 +26,50 @@ type RouterServiceDB struct {
 	db                  *database.DB
 	connectionPool      *RouterOSConnectionPool      // Connection pool for RouterOS
 	circuitBreaker      *RouterCircuitBreaker        // Circuit breaker for fault tolerance
+	backupDir           string                       // Where router backups are written
+	poolDrainTimeout    time.Duration                // How long Close waits for borrowed connections
 }
 
-// NewRouterServiceDB creates a new database-backed router service instance
-func NewRouterServiceDB(logger *logrus.Logger, db *database.DB) *RouterServiceDB {
-	// Initialize connection pool with config from environment
-	maxConnections := 5            // Max 5 connections per router
-	idleTimeout := 5 * time.Minute // Close idle connections after 5 minutes
-	maxLifetime := 30 * time.Minute // Recycle connections after 30 minutes
+// RouterPoolConfig sizes the RouterOS connection pool and circuit breaker.
+// Zero fields use the defaults.
+type RouterPoolConfig struct {
+	MaxConnections   int           // Connections per router (default 5)
+	IdleTimeout      time.Duration // Close idle connections after (default 5m)
+	MaxLifetime      time.Duration // Recycle connections after (default 30m)
+	FailureThreshold int           // Failed tests that open a router's circuit (default 3)
+	CircuitTimeout   time.Duration // Wait before an open circuit lets a test through (default 30s)
+	DrainTimeout     time.Duration // Wait on shutdown for in-use connections (default 10s)
+}
 
-	pool := NewRouterOSConnectionPool(logger, maxConnections, idleTimeout, maxLifetime)
+// withDefaults fills unset fields with the default pool and circuit settings
+func (c RouterPoolConfig) withDefaults() RouterPoolConfig {
+	if c.MaxConnections <= 0 {
+		c.MaxConnections = 5
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 5 * time.Minute
+	}
+	if c.MaxLifetime <= 0 {
+		c.MaxLifetime = 30 * time.Minute
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 3
+	}
+	if c.CircuitTimeout <= 0 {
+		c.CircuitTimeout = 30 * time.Second
+	}
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = 10 * time.Second
+	}
+	return c
+}
 
-	// Initialize circuit breaker with config from environment
-	failureThreshold := 3          // Open circuit after 3 failures
-	circuitTimeout := 30 * time.Second // Wait 30 seconds before half-open
+// NewRouterServiceDB creates a new database-backed router service instance
+func NewRouterServiceDB(logger *logrus.Logger, db *database.DB, poolConfig RouterPoolConfig) *RouterServiceDB {
+	poolConfig = poolConfig.withDefaults()
 
-	circuitBreaker := NewRouterCircuitBreaker(logger, failureThreshold, circuitTimeout)
+	pool := NewRouterOSConnectionPool(logger, poolConfig.MaxConnections, poolConfig.IdleTimeout, poolConfig.MaxLifetime)
+	circuitBreaker := NewRouterCircuitBreaker(logger, poolConfig.FailureThreshold, poolConfig.CircuitTimeout)
 
 	return &RouterServiceDB{
 		logger:            logger,
@@ -46,6 +78,7 @@ func NewRouterServiceDB(logger *logrus.Logger, db *database.DB) *RouterServiceDB
 		db:                db,
 		connectionPool:    pool,
 		circuitBreaker:    circuitBreaker,
+		poolDrainTimeout:  poolConfig.DrainTimeout,
 	}
 }
 
@@ -81,6 +114,42 @@ func (rs *RouterServiceDB) GetAllRouters(userRole string) ([]models.RouterRespon
 	return responses, nil
 }
 
+// ListRouters returns one page of the routers userRole can access that match
+// filter, and how many match in total. Access is applied before paging.
+func (rs *RouterServiceDB) ListRouters(userRole string, filter models.RouterListFilter) ([]models.RouterResponse, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	allowedRouters, err := rs.accessControlRepo.GetRouterNamesByRole(ctx, userRole)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get allowed routers: %w", err)
+	}
+	if len(allowedRouters) == 0 {
+		return []models.RouterResponse{}, 0, nil
+	}
+	if rs.hasRouterAccess("*", allowedRouters) {
+		allowedRouters = nil // Wildcard, no name restriction
+	}
+	if filter.Names != nil {
+		allowedRouters = rs.restrictRouterNames(allowedRouters, filter.Names)
+		if len(allowedRouters) == 0 {
+			return []models.RouterResponse{}, 0, nil
+		}
+	}
+
+	routers, total, err := rs.routerRepo.GetPage(ctx, filter, allowedRouters)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get routers: %w", err)
+	}
+
+	responses := make([]models.RouterResponse, 0, len(routers))
+	for _, router := range routers {
+		responses = append(responses, router.ToResponse())
+	}
+
+	return responses, total, nil
+}
+
 // GetRouter returns a specific router by ID
 func (rs *RouterServiceDB) GetRouter(routerID string, userRole string) (*models.RouterResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -114,14 +183,22 @@ func (rs *RouterServiceDB) CreateRouter(req *models.RouterCreateRequest, userRol
 	}
 
 	// Validate request
+	req.Name = models.NormalizeRouterName(req.Name)
 	if err := rs.validateRouterRequest(req.Name, req.Host, req.Port, req.Username, req.Password); err != nil {
 		return nil, err
 	}
+	if err := models.ValidateONTCommentPatterns(req.ONTCommentPatterns); err != nil {
+		return nil, err
+	}
+	req.Tags = models.NormalizeRouterTags(req.Tags)
+	if err := models.ValidateRouterTags(req.Tags); err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if router name already exists
+	// Check if router name already exists (case-insensitive)
 	exists, err := rs.routerRepo.Exists(ctx, req.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check router existence: %w", err)
@@ -154,9 +231,19 @@ func (rs *RouterServiceDB) UpdateRouter(routerID string, req *models.RouterUpdat
 	}
 
 	// Validate request
+	req.Name = models.NormalizeRouterName(req.Name)
 	if err := rs.validateRouterRequest(req.Name, req.Host, req.Port, req.Username, req.Password); err != nil {
 		return nil, err
 	}
+	if err := models.ValidateONTCommentPatterns(req.ONTCommentPatterns); err != nil {
+		return nil, err
+	}
+	if req.Tags != nil {
+		req.Tags = models.NormalizeRouterTags(req.Tags)
+		if err := models.ValidateRouterTags(req.Tags); err != nil {
+			return nil, err
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -167,8 +254,8 @@ func (rs *RouterServiceDB) UpdateRouter(routerID string, req *models.RouterUpdat
 		return nil, fmt.Errorf("router not found: %w", err)
 	}
 
-	// Check if new name conflicts with another router
-	if existingRouter.Name != req.Name {
+	// Check if new name conflicts with another router (a case-only rename can't)
+	if models.RouterNameKey(existingRouter.Name) != models.RouterNameKey(req.Name) {
 		exists, err := rs.routerRepo.Exists(ctx, req.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check router existence: %w", err)
@@ -178,6 +265,17 @@ func (rs *RouterServiceDB) UpdateRouter(routerID string, req *models.RouterUpdat
 		}
 	}
 
+	previousName := existingRouter.Name
+	connectionChanged := existingRouter.Name != req.Name || existingRouter.Host != req.Host ||
+		existingRouter.Port != req.Port || existingRouter.Username != req.Username ||
+		existingRouter.Password != req.Password || existingRouter.UseTLS != req.UseTLS ||
+		existingRouter.TLSSkipVerify != req.TLSSkipVerify
+
+	// Clients that don't know about tags omit them, keep the current ones
+	if req.Tags == nil {
+		req.Tags = existingRouter.Tags
+	}
+
 	// Update router fields
 	existingRouter.UpdateFromRequest(req)
 
@@ -186,11 +284,106 @@ func (rs *RouterServiceDB) UpdateRouter(routerID string, req *models.RouterUpdat
 		return nil, fmt.Errorf("failed to update router: %w", err)
 	}
 
+	// Pooled connections were opened with the old address, credentials or transport
+	if connectionChanged {
+		rs.connectionPool.DrainRouter(previousName)
+	}
+
 	response := existingRouter.ToResponse()
 	rs.logger.Infof("✅ Updated router: %s (ID: %s)", req.Name, routerID)
 	return &response, nil
 }
 
+// ErrCredentialVerificationFailed is matched (via errors.Is) when new router credentials fail their probe
+var ErrCredentialVerificationFailed = errors.New("new router credentials failed verification")
+
+// CredentialVerificationError carries the probe result that rejected a credential rotation
+type CredentialVerificationError struct {
+	Router string
+	Test   models.RouterConnectionTest
+}
+
+func (e *CredentialVerificationError) Error() string {
+	return fmt.Sprintf("new credentials for %s failed verification: %s", e.Router, e.Test.Message)
+}
+
+// Is lets errors.Is(err, ErrCredentialVerificationFailed) match
+func (e *CredentialVerificationError) Is(target error) bool {
+	return target == ErrCredentialVerificationFailed
+}
+
+// RotateRouterCredentials replaces a router's RouterOS username/password.
+// Unless req.Verify is false the new credentials are test-connected first and
+// a failed probe rejects the save (*CredentialVerificationError) unless
+// req.Force is set. Pooled connections for the router are drained afterwards
+// so no connection keeps using the old credentials.
+func (rs *RouterServiceDB) RotateRouterCredentials(routerID string, req *models.RouterCredentialRotateRequest, userRole string) (*models.RouterCredentialRotateResponse, error) {
+	// Only administrators can rotate router credentials
+	if userRole != "Administrator" {
+		return nil, fmt.Errorf("insufficient permissions to rotate router credentials")
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || req.Password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	router, err := rs.routerRepo.GetByID(ctx, routerID)
+	if err != nil {
+		return nil, fmt.Errorf("router not found: %w", err)
+	}
+
+	response := &models.RouterCredentialRotateResponse{Status: "success"}
+
+	if req.ShouldVerify() {
+		test := rs.TestRouterConfig(ConnectionConfig{
+			Host:          router.Host,
+			Port:          router.Port,
+			Username:      req.Username,
+			Password:      req.Password,
+			UseTLS:        router.UseTLS,
+			TLSSkipVerify: router.TLSSkipVerify,
+		})
+		response.Verification = &test
+
+		if test.Status != "connected" {
+			if !req.Force {
+				rs.logger.Warnf("⚠️ Credential rotation for %s rejected: verification failed: %s", router.Name, test.Message)
+				return nil, &CredentialVerificationError{Router: router.Name, Test: test}
+			}
+			rs.logger.Warnf("⚠️ Credential rotation for %s forced despite failed verification: %s", router.Name, test.Message)
+		}
+	}
+
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer saveCancel()
+
+	if err := rs.routerRepo.UpdateCredentials(saveCtx, router.ID, req.Username, req.Password); err != nil {
+		return nil, err
+	}
+	router.Username = req.Username
+	router.Password = req.Password
+
+	// Old credentials must not linger in pooled connections or an open circuit
+	response.DrainedConnections = rs.connectionPool.DrainRouter(router.Name)
+	rs.circuitBreaker.Reset(router.Name)
+
+	response.Data = router.ToResponse()
+	response.Message = "Router credentials rotated successfully"
+	if response.Verification == nil {
+		response.Message = "Router credentials rotated without verification"
+	} else if response.Verification.Status != "connected" {
+		response.Message = "Router credentials rotated despite failed verification"
+	}
+
+	rs.logger.Infof("🔑 Rotated credentials for router: %s (ID: %s, user: %s, drained %d connection(s))",
+		router.Name, router.ID, req.Username, response.DrainedConnections)
+	return response, nil
+}
+
 // DeleteRouter deletes a router
 func (rs *RouterServiceDB) DeleteRouter(routerID string, userRole string) error {
 	// Only administrators can delete routers
@@ -255,6 +448,57 @@ func (rs *RouterServiceDB) TestRouter(routerID string, userRole string) (*models
 	return response, nil
 }
 
+// TestRouterConfig tries to log in to an unsaved router configuration.
+// It dials directly, bypassing the pool and circuit breaker, so a candidate
+// config never affects the state kept for saved routers.
+func (rs *RouterServiceDB) TestRouterConfig(config ConnectionConfig) models.RouterConnectionTest {
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+
+	conn, err := dialRouterAPI(config.Host, config.Port, config.UseTLS, config.TLSSkipVerify, 10*time.Second)
+	if err != nil {
+		return failedConnectionTest(newRouterConnectError(address, err, false))
+	}
+	// Bound login and the resource query as well, not just the dial
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+
+	client, err := routeros.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return failedConnectionTest(newRouterConnectError(address, err, true))
+	}
+	defer client.Close()
+
+	if err := client.Login(config.Username, config.Password); err != nil {
+		return failedConnectionTest(newRouterConnectError(address, err, true))
+	}
+
+	reply, err := client.Run("/system/resource/print")
+	if err != nil {
+		return models.RouterConnectionTest{
+			Status:    "disconnected",
+			Message:   fmt.Sprintf("Failed to get system resource: %v", err),
+			Timestamp: time.Now(),
+		}
+	}
+
+	resource, err := firstRow(reply, "/system/resource/print")
+	if err != nil {
+		return models.RouterConnectionTest{
+			Status:    "error",
+			Message:   err.Error(),
+			Timestamp: time.Now(),
+		}
+	}
+
+	return models.RouterConnectionTest{
+		Status:    "connected",
+		Version:   mapString(resource, "version", "unknown"),
+		Board:     mapString(resource, "board-name", "unknown"),
+		Message:   "Connection successful",
+		Timestamp: time.Now(),
+	}
+}
+
 // testRouterConnection performs actual connection test with circuit breaker and connection pooling
 func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.RouterConnectionTest {
 	rs.logger.Debugf("🔄 Testing connection to %s:%d (circuit breaker + pooling)", router.Host, router.Port)
@@ -279,11 +523,8 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 		conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", router.Host, router.Port), timeout)
 		if err != nil {
 			rs.logger.Warnf("⚠️ TCP connection failed for %s: %v", router.Name, err)
-			testResult = models.RouterConnectionTest{
-				Status:    "disconnected",
-				Message:   fmt.Sprintf("TCP connection failed: %v", err),
-				Timestamp: time.Now(),
-			}
+			testResult = failedConnectionTest(newRouterConnectError(router.Name, err, false))
+			testResult.Message = fmt.Sprintf("TCP connection failed: %v", err)
 			return err
 		}
 		conn.Close()
@@ -292,20 +533,20 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 
 		// Get connection from pool
 		config := ConnectionConfig{
-			Host:     router.Host,
-			Port:     router.Port,
-			Username: router.Username,
-			Password: router.Password,
+			Host:          router.Host,
+			Port:          router.Port,
+			Username:      router.Username,
+			Password:      router.Password,
+			UseTLS:        router.UseTLS,
+			TLSSkipVerify: router.TLSSkipVerify,
 		}
 
 		poolConn, err := rs.connectionPool.GetConnection(router.Name, config)
 		if err != nil {
 			rs.logger.Warnf("⚠️ Failed to get pooled connection for %s: %v", router.Name, err)
-			testResult = models.RouterConnectionTest{
-				Status:    "disconnected",
-				Message:   fmt.Sprintf("Connection pool error: %v", err),
-				Timestamp: time.Now(),
-			}
+			connectErr := newRouterConnectError(router.Name, err, true)
+			testResult = failedConnectionTest(connectErr)
+			testResult.Message = fmt.Sprintf("Connection pool error: %v", err)
 			return err
 		}
 
@@ -313,10 +554,12 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 		defer rs.connectionPool.ReleaseConnection(poolConn)
 
 		// Get system info using pooled connection
-		identityReply, err := poolConn.Client.Run("/system/identity/print")
+		identityReply, err := poolConn.RunOp(OpLight, "/system/identity/print")
 		if err != nil {
 			// Connection might be dead, close it
-			rs.connectionPool.CloseConnection(poolConn)
+			if isConnectionBroken(err) {
+				rs.connectionPool.CloseConnection(poolConn)
+			}
 			testResult = models.RouterConnectionTest{
 				Status:    "disconnected",
 				Message:   fmt.Sprintf("Failed to get system identity: %v", err),
@@ -325,10 +568,12 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 			return err
 		}
 
-		resourceReply, err := poolConn.Client.Run("/system/resource/print")
+		resourceReply, err := poolConn.RunOp(OpLight, "/system/resource/print")
 		if err != nil {
 			// Connection might be dead, close it
-			rs.connectionPool.CloseConnection(poolConn)
+			if isConnectionBroken(err) {
+				rs.connectionPool.CloseConnection(poolConn)
+			}
 			testResult = models.RouterConnectionTest{
 				Status:    "disconnected",
 				Message:   fmt.Sprintf("Failed to get system resource: %v", err),
@@ -337,22 +582,33 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 			return err
 		}
 
-		var routerName, version, board string
-		if len(identityReply.Re) > 0 {
-			routerName = identityReply.Re[0].Map["name"]
+		identity, err := firstRow(identityReply, "/system/identity/print", "name")
+		if err != nil {
+			testResult = models.RouterConnectionTest{
+				Status:    "error",
+				Message:   err.Error(),
+				Timestamp: time.Now(),
+			}
+			return err
 		}
-		if len(resourceReply.Re) > 0 {
-			version = resourceReply.Re[0].Map["version"]
-			board = resourceReply.Re[0].Map["board-name"]
+
+		resource, err := firstRow(resourceReply, "/system/resource/print", "version")
+		if err != nil {
+			testResult = models.RouterConnectionTest{
+				Status:    "error",
+				Message:   err.Error(),
+				Timestamp: time.Now(),
+			}
+			return err
 		}
 
 		rs.logger.Infof("✅ Successfully tested %s using pooled connection (circuit: CLOSED)", router.Name)
 
 		testResult = models.RouterConnectionTest{
 			Status:     "connected",
-			RouterName: routerName,
-			Version:    version,
-			Board:      board,
+			RouterName: mapString(identity, "name", router.Name),
+			Version:    mapString(resource, "version", "unknown"),
+			Board:      mapString(resource, "board-name", "unknown"),
 			Message:    "Connection successful (pooled + circuit breaker)",
 			Timestamp:  time.Now(),
 		}
@@ -368,8 +624,252 @@ func (rs *RouterServiceDB) testRouterConnection(router models.Router) models.Rou
 	return testResult
 }
 
-// GetRouterStats returns statistics about all routers
-func (rs *RouterServiceDB) GetRouterStats(userRole string) (*models.RouterStatsResponse, error) {
+// Router log limits
+const (
+	defaultRouterLogLimit = 100
+	maxRouterLogLimit     = 500
+)
+
+// GetRouterLogs reads the router's /log via a pooled connection, filtered by topic
+// and capped to the most recent entries
+func (rs *RouterServiceDB) GetRouterLogs(routerID string, filter models.RouterLogFilter, userRole string) (*models.RouterLogsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	foundRouter, err := rs.routerRepo.GetByID(ctx, routerID)
+	if err != nil {
+		return nil, fmt.Errorf("router not found: %w", err)
+	}
+
+	allowedRouters, err := rs.getAllowedRouters(ctx, userRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+
+	if !rs.hasRouterAccess(foundRouter.Name, allowedRouters) {
+		return nil, fmt.Errorf("access denied to router")
+	}
+
+	config := ConnectionConfig{
+		Host:          foundRouter.Host,
+		Port:          foundRouter.Port,
+		Username:      foundRouter.Username,
+		Password:      foundRouter.Password,
+		UseTLS:        foundRouter.UseTLS,
+		TLSSkipVerify: foundRouter.TLSSkipVerify,
+	}
+
+	poolConn, err := rs.connectionPool.GetConnection(foundRouter.Name, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer rs.connectionPool.ReleaseConnection(poolConn)
+
+	entries, err := readRouterLogs(poolConn, filter)
+	if err != nil {
+		if isConnectionBroken(err) {
+			rs.connectionPool.CloseConnection(poolConn)
+		}
+		return nil, fmt.Errorf("failed to read router logs: %w", err)
+	}
+
+	return &models.RouterLogsResponse{
+		Status:     "success",
+		RouterID:   foundRouter.ID,
+		RouterName: foundRouter.Name,
+		Data:       entries,
+		Total:      len(entries),
+	}, nil
+}
+
+// readRouterLogs runs /log/print on conn and keeps the most recent entries
+// matching filter: any of its topics and, when set, its severity
+func readRouterLogs(conn *RouterOSConnection, filter models.RouterLogFilter) ([]models.RouterLogEntry, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRouterLogLimit
+	}
+	if limit > maxRouterLogLimit {
+		limit = maxRouterLogLimit
+	}
+
+	reply, err := conn.RunOp(OpRead, "/log/print", "=.proplist=.id,time,topics,message")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.RouterLogEntry{}
+	for _, re := range reply.Re {
+		topics := strings.Split(re.Map["topics"], ",")
+		if !matchesAnyTopic(topics, filter.Topics) {
+			continue
+		}
+		if filter.Severity != "" && !matchesAnyTopic(topics, []string{filter.Severity}) {
+			continue
+		}
+		entries = append(entries, models.RouterLogEntry{
+			ID:      re.Map[".id"],
+			Time:    re.Map["time"],
+			Topics:  topics,
+			Message: re.Map["message"],
+		})
+	}
+
+	// RouterOS returns oldest first; keep the most recent entries
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// matchesAnyTopic reports whether the entry topics contain any wanted topic (no filter matches all)
+func matchesAnyTopic(entryTopics, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, topic := range entryTopics {
+		for _, w := range wanted {
+			if strings.EqualFold(strings.TrimSpace(topic), w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Conntrack limits
+const (
+	defaultConnTrackLimit = 100
+	maxConnTrackLimit     = 500
+)
+
+// connTrackProplist trims each conntrack row to the fields we show, which keeps
+// the reply small on routers tracking hundreds of thousands of connections
+const connTrackProplist = "=.proplist=.id,protocol,src-address,dst-address,reply-src-address,reply-dst-address,tcp-state,timeout,orig-bytes,repl-bytes,assured,srcnat,dstnat"
+
+// GetConnTrack reads the router's connection tracking entries where ip is the
+// source or destination, capped to limit entries.
+func (rs *RouterServiceDB) GetConnTrack(routerID, ip string, limit int, userRole string) (*models.ConnTrackResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	target := net.ParseIP(ip)
+	if target == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	foundRouter, err := rs.routerRepo.GetByID(ctx, routerID)
+	if err != nil {
+		return nil, fmt.Errorf("router not found: %w", err)
+	}
+
+	allowedRouters, err := rs.getAllowedRouters(ctx, userRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
+	}
+
+	if !rs.hasRouterAccess(foundRouter.Name, allowedRouters) {
+		return nil, fmt.Errorf("access denied to router")
+	}
+
+	if limit <= 0 {
+		limit = defaultConnTrackLimit
+	}
+	if limit > maxConnTrackLimit {
+		limit = maxConnTrackLimit
+	}
+
+	config := ConnectionConfig{
+		Host:          foundRouter.Host,
+		Port:          foundRouter.Port,
+		Username:      foundRouter.Username,
+		Password:      foundRouter.Password,
+		UseTLS:        foundRouter.UseTLS,
+		TLSSkipVerify: foundRouter.TLSSkipVerify,
+	}
+
+	poolConn, err := rs.connectionPool.GetConnection(foundRouter.Name, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer rs.connectionPool.ReleaseConnection(poolConn)
+
+	entries, truncated, err := readConnTrack(poolConn, target, limit)
+	if err != nil {
+		if isConnectionBroken(err) {
+			rs.connectionPool.CloseConnection(poolConn)
+		}
+		return nil, fmt.Errorf("failed to read connection tracking: %w", err)
+	}
+
+	return &models.ConnTrackResponse{
+		Status:     "success",
+		RouterID:   foundRouter.ID,
+		RouterName: foundRouter.Name,
+		IP:         target.String(),
+		Data:       entries,
+		Total:      len(entries),
+		Truncated:  truncated,
+	}, nil
+}
+
+// readConnTrack asks the router for the rows whose src-address or dst-address
+// is target, so routers tracking many connections only send those back. The
+// rows are checked again on the host part before they are returned.
+func readConnTrack(conn *RouterOSConnection, target net.IP, limit int) ([]models.ConnTrackEntry, bool, error) {
+	reply, err := conn.RunOp(OpRead, "/ip/firewall/connection/print", connTrackProplist,
+		"?src-address="+target.String(), "?dst-address="+target.String(), "?#|")
+	if err != nil {
+		return nil, false, err
+	}
+	entries, truncated := filterConnTrack(reply.Re, target, limit)
+	return entries, truncated, nil
+}
+
+// filterConnTrack keeps the rows whose source or destination host is target,
+// stopping at limit. truncated reports whether more rows matched.
+func filterConnTrack(rows []*proto.Sentence, target net.IP, limit int) ([]models.ConnTrackEntry, bool) {
+	entries := []models.ConnTrackEntry{}
+	for _, re := range rows {
+		if !connTrackHostIs(re.Map["src-address"], target) && !connTrackHostIs(re.Map["dst-address"], target) {
+			continue
+		}
+		if len(entries) == limit {
+			return entries, true
+		}
+		entries = append(entries, models.ConnTrackEntry{
+			ID:         re.Map[".id"],
+			Protocol:   re.Map["protocol"],
+			SrcAddress: re.Map["src-address"],
+			DstAddress: re.Map["dst-address"],
+			ReplySrc:   re.Map["reply-src-address"],
+			ReplyDst:   re.Map["reply-dst-address"],
+			State:      re.Map["tcp-state"],
+			Timeout:    re.Map["timeout"],
+			OrigBytes:  re.Map["orig-bytes"],
+			ReplyBytes: re.Map["repl-bytes"],
+			Assured:    re.Map["assured"] == "true",
+			SrcNAT:     re.Map["srcnat"] == "true",
+			DstNAT:     re.Map["dstnat"] == "true",
+		})
+	}
+	return entries, false
+}
+
+// connTrackHostIs reports whether a conntrack address ("10.0.0.5:443", "10.0.0.5"
+// or "[2001:db8::1]:443") has target as its host
+func connTrackHostIs(address string, target net.IP) bool {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.Equal(target)
+}
+
+// GetRouterStats returns statistics about all routers, optionally also
+// counted per tag. A non-nil onlyRouters narrows the role's routers further.
+func (rs *RouterServiceDB) GetRouterStats(userRole string, onlyRouters []string, groupByTag bool) (*models.RouterStatsResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -377,6 +877,9 @@ func (rs *RouterServiceDB) GetRouterStats(userRole string) (*models.RouterStatsR
 	if err != nil {
 		return nil, fmt.Errorf("failed to get allowed routers: %w", err)
 	}
+	if onlyRouters != nil {
+		allowedRouters = rs.restrictRouterNames(allowedRouters, onlyRouters)
+	}
 
 	routers, err := rs.routerRepo.GetAll(ctx)
 	if err != nil {
@@ -389,8 +892,26 @@ func (rs *RouterServiceDB) GetRouterStats(userRole string) (*models.RouterStatsR
 	activeRouters := 0
 	disabledRouters := 0
 
+	var byTag map[string]models.RouterTagStats
+	var untagged *models.RouterTagStats
+	if groupByTag {
+		byTag = make(map[string]models.RouterTagStats)
+		untagged = &models.RouterTagStats{}
+	}
+
 	for _, router := range routers {
 		if rs.hasRouterAccess(router.Name, allowedRouters) {
+			if groupByTag {
+				if len(router.Tags) == 0 {
+					untagged.Add(router.Enabled)
+				}
+				for _, tag := range router.Tags {
+					stats := byTag[tag]
+					stats.Add(router.Enabled)
+					byTag[tag] = stats
+				}
+			}
+
 			totalRouters++
 			if router.Enabled {
 				activeRouters++
@@ -410,6 +931,8 @@ func (rs *RouterServiceDB) GetRouterStats(userRole string) (*models.RouterStatsR
 		DisabledRouters: disabledRouters,
 		ConnectionTests: connectionTests,
 		LastUpdated:     time.Now(),
+		ByTag:           byTag,
+		Untagged:        untagged,
 	}, nil
 }
 
@@ -450,8 +973,8 @@ func (rs *RouterServiceDB) ReloadConfiguration() error {
 
 // validateRouterRequest validates router request parameters
 func (rs *RouterServiceDB) validateRouterRequest(name, host string, port int, username, password string) error {
-	if strings.TrimSpace(name) == "" {
-		return fmt.Errorf("router name is required")
+	if err := models.ValidateRouterName(name); err != nil {
+		return err
 	}
 
 	if strings.TrimSpace(host) == "" {
@@ -496,6 +1019,101 @@ func (rs *RouterServiceDB) isValidHostname(hostname string) bool {
 	return true
 }
 
+// Role router list errors, matched with errors.Is
+var (
+	ErrUnknownRole         = errors.New("unknown role")
+	ErrAdministratorAccess = errors.New("the Administrator role always has access to every router")
+	ErrWildcardWithRouters = errors.New(`"*" cannot be combined with router names`)
+	ErrUnknownRouters      = errors.New("unknown routers")
+)
+
+// GetRoleRouters returns the routers a role is granted in router_access_control
+func (rs *RouterServiceDB) GetRoleRouters(role string) (*models.RoleRouters, error) {
+	if !models.Role(role).IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	routerNames, err := rs.accessControlRepo.GetRouterNamesByRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	return newRoleRouters(role, routerNames), nil
+}
+
+// SetRoleRouters replaces the routers a role can access. Names must match
+// existing routers; ["*"] grants every router and an empty list removes all
+// access. Access checks read router_access_control on every request, so the
+// change applies immediately.
+func (rs *RouterServiceDB) SetRoleRouters(role string, routerNames []string) (*models.RoleRouters, error) {
+	if !models.Role(role).IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+	}
+	// Taking routers away from administrators would lock everyone out of router management
+	if models.Role(role) == models.RoleAdministrator {
+		return nil, ErrAdministratorAccess
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	names := make([]string, 0, len(routerNames))
+	seen := make(map[string]bool, len(routerNames))
+	for _, name := range routerNames {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	if seen["*"] {
+		if len(names) > 1 {
+			return nil, ErrWildcardWithRouters
+		}
+	} else if len(names) > 0 {
+		routers, err := rs.routerRepo.GetAll(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get routers: %w", err)
+		}
+		existing := make(map[string]bool, len(routers))
+		for _, router := range routers {
+			existing[router.Name] = true
+		}
+		var unknown []string
+		for _, name := range names {
+			if !existing[name] {
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRouters, strings.Join(unknown, ", "))
+		}
+	}
+
+	if err := rs.accessControlRepo.SetRoleRouters(ctx, role, names); err != nil {
+		return nil, err
+	}
+
+	rs.logger.Infof("🔐 Router access for role %s set to %v", role, names)
+	return rs.GetRoleRouters(role)
+}
+
+// newRoleRouters builds the API view of a role's router list
+func newRoleRouters(role string, routerNames []string) *models.RoleRouters {
+	if routerNames == nil {
+		routerNames = []string{}
+	}
+	return &models.RoleRouters{
+		Role:     role,
+		Routers:  routerNames,
+		Wildcard: len(routerNames) == 1 && routerNames[0] == "*",
+	}
+}
+
 // getAllowedRouters returns list of allowed routers for a user role
 func (rs *RouterServiceDB) getAllowedRouters(ctx context.Context, userRole string) ([]string, error) {
 	routerNames, err := rs.accessControlRepo.GetRouterNamesByRole(ctx, userRole)
@@ -520,6 +1138,24 @@ func (rs *RouterServiceDB) getAllowedRouters(ctx context.Context, userRole strin
 	return routerNames, nil
 }
 
+// restrictRouterNames narrows allowedRouters (nil or "*" = every router) to names
+func (rs *RouterServiceDB) restrictRouterNames(allowedRouters, names []string) []string {
+	if allowedRouters == nil || rs.hasRouterAccess("*", allowedRouters) {
+		return append([]string{}, names...)
+	}
+	allowed := make(map[string]bool, len(allowedRouters))
+	for _, name := range allowedRouters {
+		allowed[name] = true
+	}
+	restricted := []string{}
+	for _, name := range names {
+		if allowed[name] {
+			restricted = append(restricted, name)
+		}
+	}
+	return restricted
+}
+
 // hasRouterAccess checks if user has access to specific router
 func (rs *RouterServiceDB) hasRouterAccess(routerName string, allowedRouters []string) bool {
 	for _, allowed := range allowedRouters {
@@ -535,8 +1171,8 @@ func (rs *RouterServiceDB) GetConfigurationPath() string {
 	return "PostgreSQL Database (Neon Serverless)"
 }
 
-// GetRouterConnection returns a pooled connection for a router (for health monitoring)
-func (rs *RouterServiceDB) GetRouterConnection(routerID string) (*RouterOSConnection, error) {
+// GetRouterConnectionByID returns a pooled connection for a router (for health monitoring)
+func (rs *RouterServiceDB) GetRouterConnectionByID(routerID string) (*RouterOSConnection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -546,15 +1182,36 @@ func (rs *RouterServiceDB) GetRouterConnection(routerID string) (*RouterOSConnec
 		return nil, fmt.Errorf("router not found: %w", err)
 	}
 
-	// Get connection from pool
+	return rs.pooledConnection(context.Background(), router)
+}
+
+// GetRouterConnection returns a pooled connection for a router by name,
+// giving up on dialing one when ctx is done. The caller must hand it back
+// with ReleaseConnection on the connection's pool.
+func (rs *RouterServiceDB) GetRouterConnection(ctx context.Context, name string) (*RouterOSConnection, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	router, err := rs.routerRepo.GetByName(lookupCtx, name)
+	if err != nil {
+		return nil, fmt.Errorf("router not found: %w", err)
+	}
+
+	return rs.pooledConnection(ctx, router)
+}
+
+// pooledConnection borrows a connection to router from the pool, dialing one if none is idle
+func (rs *RouterServiceDB) pooledConnection(ctx context.Context, router *models.Router) (*RouterOSConnection, error) {
 	config := ConnectionConfig{
-		Host:     router.Host,
-		Port:     router.Port,
-		Username: router.Username,
-		Password: router.Password,
+		Host:          router.Host,
+		Port:          router.Port,
+		Username:      router.Username,
+		Password:      router.Password,
+		UseTLS:        router.UseTLS,
+		TLSSkipVerify: router.TLSSkipVerify,
 	}
 
-	poolConn, err := rs.connectionPool.GetConnection(router.Name, config)
+	poolConn, err := rs.connectionPool.GetConnectionContext(ctx, router.Name, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get connection: %w", err)
 	}
@@ -562,9 +1219,58 @@ func (rs *RouterServiceDB) GetRouterConnection(routerID string) (*RouterOSConnec
 	return poolConn, nil
 }
 
-// Close closes all resources including connection pool
+// GetPoolStats returns RouterOS connection pool statistics
+func (rs *RouterServiceDB) GetPoolStats() PoolStats {
+	return rs.connectionPool.GetStats()
+}
+
+// GetRouterCircuit returns the circuit breaker state of a router
+func (rs *RouterServiceDB) GetRouterCircuit(routerName string) models.RouterCircuit {
+	return rs.circuitBreaker.Snapshot(routerName)
+}
+
+// ResetRouterCircuit force-closes a router's circuit breaker so it can be
+// tested again right away, and returns the new state
+func (rs *RouterServiceDB) ResetRouterCircuit(routerName string) models.RouterCircuit {
+	rs.circuitBreaker.Reset(routerName)
+	return rs.circuitBreaker.Snapshot(routerName)
+}
+
+// TestRouterByName tests connectivity to a router by name without role checks (for health checks)
+func (rs *RouterServiceDB) TestRouterByName(routerName string) (*models.RouterConnectionTest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	router, err := rs.routerRepo.GetByName(ctx, routerName)
+	if err != nil {
+		return nil, err
+	}
+
+	testResult := rs.testRouterConnection(*router)
+	return &testResult, nil
+}
+
+// SetPoolMaxIdlePerRouter caps the idle RouterOS connections kept per router (0 = no cap)
+func (rs *RouterServiceDB) SetPoolMaxIdlePerRouter(maxIdle int) {
+	rs.connectionPool.SetMaxIdlePerRouter(maxIdle)
+}
+
+// SetOperationTimeouts overrides pooled RouterOS command timeouts per operation type (seconds)
+func (rs *RouterServiceDB) SetOperationTimeouts(overrides map[string]string) {
+	rs.connectionPool.SetOperationTimeouts(overrides)
+}
+
+// SetRouterThrottle rate limits RouterOS commands sent over pooled connections per router
+func (rs *RouterServiceDB) SetRouterThrottle(throttle *RouterThrottle) {
+	rs.connectionPool.SetThrottle(throttle)
+}
+
+// Close closes all resources including connection pool. New RouterOS
+// operations fail from the start, running ones get up to the drain timeout to
+// release their connections before everything is closed.
 func (rs *RouterServiceDB) Close() {
 	if rs.connectionPool != nil {
+		rs.connectionPool.Drain(rs.poolDrainTimeout)
 		rs.connectionPool.Close()
 	}
 	rs.logger.Info("✅ RouterServiceDB closed")
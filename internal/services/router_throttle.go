@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRouterBusy is returned when a router's command budget is used up and the
+// command would have to wait longer than the throttle allows
+var ErrRouterBusy = errors.New("router busy")
+
+// RouterThrottle is a token bucket per router for outbound RouterOS commands.
+// It protects CPU-limited routers from request storms regardless of which user
+// or client sends them; one router running out of tokens never slows another.
+type RouterThrottle struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	limit    rate.Limit
+	burst    int
+	maxWait  time.Duration
+}
+
+// NewRouterThrottle allows perSecond commands per router with bursts of up to
+// burst. A command that would wait longer than maxWait fails with
+// ErrRouterBusy instead. perSecond <= 0 disables throttling.
+func NewRouterThrottle(perSecond float64, burst int, maxWait time.Duration) *RouterThrottle {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RouterThrottle{
+		limiters: make(map[string]*rate.Limiter),
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		maxWait:  maxWait,
+	}
+}
+
+// Enabled reports whether commands are throttled at all
+func (t *RouterThrottle) Enabled() bool {
+	return t != nil && t.limit > 0
+}
+
+// Wait blocks until routerName may run another command, queueing for at most
+// maxWait. It returns ErrRouterBusy (wrapped) when the queue is longer than that.
+func (t *RouterThrottle) Wait(routerName string) error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	reservation := t.limiter(routerName).Reserve()
+	delay := reservation.Delay()
+	if delay > t.maxWait {
+		reservation.Cancel()
+		return fmt.Errorf("%w: %s is limited to %.1f commands/s, retry in %v",
+			ErrRouterBusy, routerName, float64(t.limit), delay.Round(time.Second))
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// limiter returns routerName's bucket, creating a full one on first use
+func (t *RouterThrottle) limiter(routerName string) *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limiter, ok := t.limiters[routerName]
+	if !ok {
+		limiter = rate.NewLimiter(t.limit, t.burst)
+		t.limiters[routerName] = limiter
+	}
+	return limiter
+}
+
+// SetThrottle rate limits commands sent over pooled connections. Share the
+// throttle with NATService so both paths draw from the same per-router budget.
+func (pool *RouterOSConnectionPool) SetThrottle(throttle *RouterThrottle) {
+	pool.opMu.Lock()
+	defer pool.opMu.Unlock()
+	pool.throttle = throttle
+}
+
+// Throttle returns the pool's command throttle (nil when none is set)
+func (pool *RouterOSConnectionPool) Throttle() *RouterThrottle {
+	pool.opMu.RLock()
+	defer pool.opMu.RUnlock()
+	return pool.throttle
+}
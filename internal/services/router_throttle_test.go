@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunCommandThrottlesPerRouter(t *testing.T) {
+	busy := newFakeRouter(t, nil)
+	idle := newFakeRouter(t, nil)
+	ns := newTestNATServiceFor(t, map[string]*fakeRouter{"BUSY": busy, "IDLE": idle})
+
+	// One command per router, then next to nothing, and no queueing
+	throttle := NewRouterThrottle(0.001, 1, 0)
+	ns.SetRouterThrottle(throttle)
+	ns.routerService.(*poolRouterService).pool.SetThrottle(throttle)
+
+	run := func(routerName string, pooled bool) error {
+		t.Helper()
+		var conn *RouterOSConnection
+		var err error
+		if pooled {
+			conn, err = ns.connectRouter(context.Background(), routerName)
+		} else {
+			conn, err = ns.dialRouter(context.Background(), routerName)
+		}
+		if err != nil {
+			t.Fatalf("connect %s: %v", routerName, err)
+		}
+		defer ns.releaseRouter(conn)
+		_, err = ns.runCommand(routerName, conn, "/ip/firewall/nat/print")
+		return err
+	}
+
+	if err := run("BUSY", true); err != nil {
+		t.Fatalf("first command to BUSY: %v", err)
+	}
+	// Pooled and direct connections draw from the same budget
+	for _, pooled := range []bool{true, false} {
+		if err := run("BUSY", pooled); !errors.Is(err, ErrRouterBusy) {
+			t.Fatalf("second command to BUSY (pooled %v): err = %v, want ErrRouterBusy", pooled, err)
+		}
+	}
+	if got := natPrints(busy); got != 1 {
+		t.Fatalf("BUSY received %d NAT prints, want only the one within its budget", got)
+	}
+
+	// BUSY running dry doesn't touch IDLE's budget
+	start := time.Now()
+	if err := run("IDLE", false); err != nil {
+		t.Fatalf("first command to IDLE: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("IDLE command took %v, want no throttling delay", elapsed)
+	}
+	if got := natPrints(idle); got != 1 {
+		t.Fatalf("IDLE received %d NAT prints, want 1", got)
+	}
+}
+
+// natPrints counts the NAT prints fr received, leaving out the pool's own
+// health checks on reused connections
+func natPrints(fr *fakeRouter) int {
+	count := 0
+	for _, command := range fr.received() {
+		if command[0] == "/ip/firewall/nat/print" {
+			count++
+		}
+	}
+	return count
+}
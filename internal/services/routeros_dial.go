@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-routeros/routeros"
+)
+
+// routerTLSConfig is the client TLS config for a router's API-SSL service.
+// skipVerify accepts self-signed certificates, which most routers use.
+func routerTLSConfig(host string, skipVerify bool) *tls.Config {
+	return &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: skipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+// dialRouterAPI opens the transport to a RouterOS API service: plain TCP for
+// the API (8728) or TLS for API-SSL (8729). The timeout covers the TLS
+// handshake as well as the dial.
+func dialRouterAPI(host string, port int, useTLS, skipVerify bool, timeout time.Duration) (net.Conn, error) {
+	return dialRouterAPIContext(context.Background(), host, port, useTLS, skipVerify, timeout)
+}
+
+// dialRouterAPIContext is dialRouterAPI that also gives up when ctx is done
+func dialRouterAPIContext(ctx context.Context, host string, port int, useTLS, skipVerify bool, timeout time.Duration) (net.Conn, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: timeout}
+	if !useTLS {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: routerTLSConfig(host, skipVerify)}
+	return tlsDialer.DialContext(ctx, "tcp", address)
+}
+
+// loginRouterAPI logs in over an open API transport. The login must finish
+// within timeout and before ctx is done; netConn is closed when it fails.
+func loginRouterAPI(ctx context.Context, netConn net.Conn, username, password string, timeout time.Duration) (*routeros.Client, error) {
+	stop := bindDeadline(ctx, netConn, timeout)
+	defer stop()
+
+	client, err := routeros.NewClient(netConn)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := client.Login(username, password); err != nil {
+		client.Close()
+		if ctxErr := contextDone(ctx); ctxErr != nil {
+			return nil, fmt.Errorf("login stopped: %w (%w)", ctxErr, err)
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// bindDeadline sets netConn's deadline to timeout from now (none when
+// timeout is 0), or to ctx's deadline when that comes first, and expires it
+// at once if ctx is cancelled. The returned func clears the deadline again.
+func bindDeadline(ctx context.Context, netConn net.Conn, timeout time.Duration) func() {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	netConn.SetDeadline(deadline)
+
+	expired := make(chan struct{})
+	stopCancel := context.AfterFunc(ctx, func() {
+		defer close(expired)
+		netConn.SetDeadline(time.Now())
+	})
+	return func() {
+		if !stopCancel() {
+			// Don't let a late expiry land after the deadline is cleared
+			<-expired
+		}
+		netConn.SetDeadline(time.Time{})
+	}
+}
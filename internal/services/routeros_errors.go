@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"nat-management-app/internal/models"
+
+	"github.com/go-routeros/routeros"
+)
+
+// FailureKind classifies why a RouterOS connection attempt failed
+type FailureKind string
+
+const (
+	FailureUnreachable FailureKind = "unreachable"  // No TCP connection to host:port
+	FailurePortClosed  FailureKind = "port_closed"  // Host answered but refused the port
+	FailureTimeout     FailureKind = "timeout"      // Dial or handshake timed out
+	FailureAPIDisabled FailureKind = "api_disabled" // TCP connected, API handshake dropped
+	FailureAuthFailed  FailureKind = "auth_failed"  // Router rejected the credentials
+	FailureUnknown     FailureKind = "unknown"
+)
+
+// ErrRouterAPIDisabled is matched (via errors.Is) when the API service looks disabled
+var ErrRouterAPIDisabled = errors.New("RouterOS API service disabled")
+
+// RouterConnectError is a connection failure with its classified kind
+type RouterConnectError struct {
+	Router string
+	Kind   FailureKind
+	Err    error
+}
+
+func (e *RouterConnectError) Error() string {
+	if e.Kind == FailureAPIDisabled {
+		return fmt.Sprintf("RouterOS API service on %s appears to be disabled: %v", e.Router, e.Err)
+	}
+	return fmt.Sprintf("failed to connect to %s (%s): %v", e.Router, e.Kind, e.Err)
+}
+
+func (e *RouterConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is(err, ErrRouterAPIDisabled) match
+func (e *RouterConnectError) Is(target error) bool {
+	return target == ErrRouterAPIDisabled && e.Kind == FailureAPIDisabled
+}
+
+// Remediation returns an operator-facing hint for fixing the failure
+func (e *RouterConnectError) Remediation() string {
+	return FailureRemediation(e.Kind)
+}
+
+// FailureRemediation returns an operator-facing hint for a failure kind
+func FailureRemediation(kind FailureKind) string {
+	switch kind {
+	case FailureAPIDisabled:
+		return "Enable the API service on the router: IP → Services → API (or /ip service enable api), and check its 'Available From' list"
+	case FailurePortClosed:
+		return "Check the API port (default 8728, 8729 for API-SSL) and that the API service is enabled"
+	case FailureAuthFailed:
+		return "Check the router username/password and that the user's group has the 'api' policy"
+	case FailureTimeout:
+		return "Check the tunnel/VPN to the router and any firewall dropping the API port"
+	case FailureUnreachable:
+		return "Check that the router host is reachable from this server"
+	default:
+		return ""
+	}
+}
+
+// classifyConnectError maps a dial/login error to a FailureKind.
+// tcpConnected says whether a plain TCP connection to the port had succeeded.
+func classifyConnectError(err error, tcpConnected bool) FailureKind {
+	if err == nil {
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return FailureTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return FailurePortClosed
+	}
+
+	var deviceErr *routeros.DeviceError
+	if errors.As(err, &deviceErr) {
+		return FailureAuthFailed
+	}
+
+	if !tcpConnected {
+		return FailureUnreachable
+	}
+
+	// The router accepted TCP but hung up during the API handshake: this is how
+	// a disabled (or address-restricted) API service behaves behind a forward
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return FailureAPIDisabled
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "eof") || strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe") {
+		return FailureAPIDisabled
+	}
+
+	return FailureUnknown
+}
+
+// isConnectionBroken reports whether a command error means the connection
+// itself is unusable (network error, timeout, EOF, protocol desync) and must
+// be dropped from the pool. RouterOS !trap replies (*routeros.DeviceError)
+// and throttle refusals leave the connection healthy.
+func isConnectionBroken(err error) bool {
+	if err == nil || errors.Is(err, ErrRouterBusy) {
+		return false
+	}
+
+	var deviceErr *routeros.DeviceError
+	if errors.As(err, &deviceErr) {
+		return false
+	}
+
+	var netErr net.Error
+	var unknownReply *routeros.UnknownReplyError
+	return errors.As(err, &netErr) || errors.As(err, &unknownReply) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// newRouterConnectError classifies err into a *RouterConnectError
+func newRouterConnectError(routerName string, err error, tcpConnected bool) *RouterConnectError {
+	return &RouterConnectError{
+		Router: routerName,
+		Kind:   classifyConnectError(err, tcpConnected),
+		Err:    err,
+	}
+}
+
+// failedConnectionTest builds a "disconnected" test result, carrying the
+// failure kind and remediation when err is a *RouterConnectError
+func failedConnectionTest(err error) models.RouterConnectionTest {
+	result := models.RouterConnectionTest{
+		Status:    "disconnected",
+		Message:   err.Error(),
+		Timestamp: time.Now(),
+	}
+
+	var connectErr *RouterConnectError
+	if errors.As(err, &connectErr) {
+		result.FailureKind = string(connectErr.Kind)
+		result.Remediation = connectErr.Remediation()
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-routeros/routeros"
+	"github.com/go-routeros/routeros/proto"
+)
+
+func TestIsConnectionBroken(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"router busy", fmt.Errorf("router JAKARTA-01: %w", ErrRouterBusy), false},
+		{"device trap", &routeros.DeviceError{Sentence: &proto.Sentence{Word: "!trap", Map: map[string]string{"message": "no such command"}}}, false},
+		{"wrapped device trap", fmt.Errorf("print failed: %w", &routeros.DeviceError{Sentence: &proto.Sentence{Word: "!trap"}}), false},
+		{"plain error", errors.New("no ONT rule"), false},
+		{"eof", io.EOF, true},
+		{"unexpected eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"timeout", fmt.Errorf("read command timed out: %w", os.ErrDeadlineExceeded), true},
+		{"net op error", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+		{"closed", net.ErrClosed, true},
+		{"unknown reply", &routeros.UnknownReplyError{Sentence: &proto.Sentence{Word: "!weird"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionBroken(tt.err); got != tt.want {
+				t.Errorf("isConnectionBroken(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyConnectError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		tcpConnected bool
+		want         FailureKind
+	}{
+		{"handshake hung up", fmt.Errorf("login: %w", io.EOF), true, FailureAPIDisabled},
+		{"handshake cut short", io.ErrUnexpectedEOF, true, FailureAPIDisabled},
+		{"handshake reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true, FailureAPIDisabled},
+		{"reset by message only", errors.New("read tcp 10.0.0.1:8728: connection reset by peer"), true, FailureAPIDisabled},
+		{"port refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, false, FailurePortClosed},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, false, FailureTimeout},
+		{"credentials rejected", &routeros.DeviceError{Sentence: &proto.Sentence{Word: "!trap", Map: map[string]string{"message": "invalid user name or password"}}}, true, FailureAuthFailed},
+		{"eof before tcp", io.EOF, false, FailureUnreachable},
+		{"something else", errors.New("tls: bad certificate"), true, FailureUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyConnectError(tt.err, tt.tcpConnected); got != tt.want {
+				t.Errorf("classifyConnectError(%v, %v) = %q, want %q", tt.err, tt.tcpConnected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIDisabledConnectError(t *testing.T) {
+	// Accepts TCP and hangs up before answering, like a router with the API
+	// service disabled behind a port forward
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	rs := NewRouterServiceDB(quietLogger(), nil, RouterPoolConfig{})
+	t.Cleanup(rs.Close)
+	addr := listener.Addr().(*net.TCPAddr)
+	result := rs.TestRouterConfig(ConnectionConfig{Host: addr.IP.String(), Port: addr.Port, Username: "admin", Password: "secret"})
+
+	if result.Status != "disconnected" || result.FailureKind != string(FailureAPIDisabled) {
+		t.Fatalf("result = %+v, want disconnected with kind %q", result, FailureAPIDisabled)
+	}
+	if result.Remediation != FailureRemediation(FailureAPIDisabled) {
+		t.Errorf("remediation = %q, want the API service hint", result.Remediation)
+	}
+
+	err = newRouterConnectError("core", fmt.Errorf("login: %w", io.EOF), true)
+	if !errors.Is(err, ErrRouterAPIDisabled) {
+		t.Errorf("errors.Is(%v, ErrRouterAPIDisabled) = false", err)
+	}
+	if errors.Is(newRouterConnectError("core", io.EOF, false), ErrRouterAPIDisabled) {
+		t.Error("an unreachable router matched ErrRouterAPIDisabled")
+	}
+}
@@ -1,7 +1,11 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,26 +20,59 @@ type RouterOSConnection struct {
 	LastUsed   time.Time
 	InUse      bool
 	Created    time.Time
+	retired    bool // Drained while in use; closed on release instead of reused
+	closed     bool // Closed by CloseConnection; releasing it is a no-op
+
+	netConn net.Conn                // Underlying TCP connection, for per-command deadlines
+	pool    *RouterOSConnectionPool // Owning pool, for operation timeouts
 }
 
+// Eviction reasons recorded in the pool's eviction counters
+const (
+	EvictionIdleTimeout    = "idle-timeout"     // Idle longer than idleTimeout
+	EvictionMaxLifetime    = "max-lifetime"     // Older than maxLifetime
+	EvictionDeadOnValidate = "dead-on-validate" // Failed the health check before reuse or during cleanup
+	EvictionMaxIdle        = "max-idle"         // Router already keeps maxIdlePerRouter idle connections
+)
+
+// ErrPoolDraining is returned by GetConnection once the pool is shutting down
+var ErrPoolDraining = errors.New("RouterOS connection pool is draining")
+
+// poolDrainPollInterval is how often Drain re-checks the borrowed connections
+const poolDrainPollInterval = 50 * time.Millisecond
+
 // RouterOSConnectionPool manages a pool of RouterOS connections
 type RouterOSConnectionPool struct {
 	logger          *logrus.Logger
 	connections     map[string][]*RouterOSConnection // RouterName -> Connections
 	mu              sync.Mutex
-	maxConnections  int           // Max connections per router
-	idleTimeout     time.Duration // Idle connection timeout
-	maxLifetime     time.Duration // Max connection lifetime
-	cleanupInterval time.Duration // Cleanup interval
+	maxConnections  int                             // Max connections per router
+	maxIdle         int                             // Max idle connections kept per router (0 = no cap)
+	idleTimeout     time.Duration                   // Idle connection timeout
+	maxLifetime     time.Duration                   // Max connection lifetime
+	opTimeouts      map[OperationType]time.Duration // Command timeout per operation type
+	opMu            sync.RWMutex                    // Guards opTimeouts and throttle; separate from mu so RunOp works while mu is held
+	throttle        *RouterThrottle                 // Per-router command rate limit (nil = off)
+	cleanupInterval time.Duration                   // Cleanup interval
 	stopCleanup     chan struct{}
+	evictions       map[string]int64 // Eviction reason -> count since start
+	created         map[string]int64 // RouterName -> connections dialed since start
+	reused          map[string]int64 // RouterName -> idle connections handed out again since start
+	limitReached    map[string]int64 // RouterName -> requests refused at maxConnections since start
+	// borrowed holds every handed-out connection until it is released or
+	// closed, including ones DrainRouter already dropped from connections
+	borrowed map[*RouterOSConnection]struct{}
+	draining bool // Set by Drain; GetConnection fails fast from then on
 }
 
 // ConnectionConfig holds configuration for connection pool
 type ConnectionConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	UseTLS        bool // Connect over API-SSL
+	TLSSkipVerify bool // Accept self-signed router certificates
 }
 
 // NewRouterOSConnectionPool creates a new connection pool
@@ -48,6 +85,15 @@ func NewRouterOSConnectionPool(logger *logrus.Logger, maxConnections int, idleTi
 		maxLifetime:     maxLifetime,
 		cleanupInterval: 30 * time.Second,
 		stopCleanup:     make(chan struct{}),
+		evictions:       make(map[string]int64),
+		created:         make(map[string]int64),
+		reused:          make(map[string]int64),
+		limitReached:    make(map[string]int64),
+		borrowed:        make(map[*RouterOSConnection]struct{}),
+		opTimeouts:      make(map[OperationType]time.Duration, len(defaultOperationTimeouts)),
+	}
+	for op, timeout := range defaultOperationTimeouts {
+		pool.opTimeouts[op] = timeout
 	}
 
 	// Start cleanup goroutine
@@ -59,26 +105,53 @@ func NewRouterOSConnectionPool(logger *logrus.Logger, maxConnections int, idleTi
 	return pool
 }
 
+// SetMaxIdlePerRouter caps how many idle connections each router keeps.
+// Extra idle connections are evicted on release and during cleanup; 0 disables the cap.
+func (pool *RouterOSConnectionPool) SetMaxIdlePerRouter(maxIdle int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if maxIdle < 0 {
+		maxIdle = 0
+	}
+	pool.maxIdle = maxIdle
+	for routerName := range pool.connections {
+		pool.trimIdle(routerName)
+	}
+	pool.logger.Infof("🏊 Connection pool max idle per router: %d", maxIdle)
+}
+
 // GetConnection retrieves or creates a connection for a router
 func (pool *RouterOSConnectionPool) GetConnection(routerName string, config ConnectionConfig) (*RouterOSConnection, error) {
+	return pool.GetConnectionContext(context.Background(), routerName, config)
+}
+
+// GetConnectionContext is GetConnection that stops dialing a new connection
+// when ctx is done
+func (pool *RouterOSConnectionPool) GetConnectionContext(ctx context.Context, routerName string, config ConnectionConfig) (*RouterOSConnection, error) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	if pool.draining {
+		return nil, ErrPoolDraining
+	}
+
 	// Try to find an idle connection
 	if conns, exists := pool.connections[routerName]; exists {
-		for _, conn := range conns {
+		for _, conn := range append([]*RouterOSConnection(nil), conns...) {
 			// Check if connection is idle and still healthy
 			if !conn.InUse {
 				// Check if connection is still alive
 				if pool.isHealthy(conn) {
 					conn.InUse = true
 					conn.LastUsed = time.Now()
+					pool.reused[routerName]++
+					pool.borrowed[conn] = struct{}{}
 					pool.logger.Debugf("♻️ Reusing existing connection for router: %s", routerName)
 					return conn, nil
 				} else {
 					// Connection is dead, remove it
-					pool.logger.Warnf("⚠️ Found dead connection for %s, removing...", routerName)
-					pool.removeConnection(routerName, conn)
+					pool.evict(routerName, conn, EvictionDeadOnValidate, "failed health check before reuse")
 				}
 			}
 		}
@@ -86,11 +159,17 @@ func (pool *RouterOSConnectionPool) GetConnection(routerName string, config Conn
 
 	// No idle connection available, create new one if under limit
 	if len(pool.connections[routerName]) >= pool.maxConnections {
+		pool.limitReached[routerName]++
 		return nil, fmt.Errorf("connection pool limit reached for router %s (max: %d)", routerName, pool.maxConnections)
 	}
 
-	// Create new connection
-	client, err := routeros.Dial(fmt.Sprintf("%s:%d", config.Host, config.Port), config.Username, config.Password)
+	// Create new connection; dial ourselves so commands can get per-operation deadlines
+	loginTimeout := pool.OperationTimeout(OpLight)
+	netConn, err := dialRouterAPIContext(ctx, config.Host, config.Port, config.UseTLS, config.TLSSkipVerify, loginTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RouterOS: %w", err)
+	}
+	client, err := loginRouterAPI(ctx, netConn, config.Username, config.Password, loginTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RouterOS: %w", err)
 	}
@@ -101,10 +180,14 @@ func (pool *RouterOSConnectionPool) GetConnection(routerName string, config Conn
 		LastUsed:   time.Now(),
 		InUse:      true,
 		Created:    time.Now(),
+		netConn:    netConn,
+		pool:       pool,
 	}
 
 	// Add to pool
 	pool.connections[routerName] = append(pool.connections[routerName], conn)
+	pool.created[routerName]++
+	pool.borrowed[conn] = struct{}{}
 	pool.logger.Infof("✅ Created new connection for router: %s (total: %d)", routerName, len(pool.connections[routerName]))
 
 	return conn, nil
@@ -115,9 +198,48 @@ func (pool *RouterOSConnectionPool) ReleaseConnection(conn *RouterOSConnection)
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	delete(pool.borrowed, conn)
+	if conn.closed {
+		return
+	}
+	if conn.retired {
+		if conn.Client != nil {
+			conn.Client.Close()
+		}
+		pool.logger.Debugf("🔒 Closed drained connection for router: %s", conn.RouterName)
+		return
+	}
+
 	conn.InUse = false
 	conn.LastUsed = time.Now()
 	pool.logger.Debugf("↩️ Released connection for router: %s", conn.RouterName)
+
+	pool.trimIdle(conn.RouterName)
+}
+
+// DrainRouter drops every pooled connection for a router so the next
+// GetConnection dials with fresh config. Idle connections are closed now,
+// in-use ones are closed when released. Returns the number drained.
+func (pool *RouterOSConnectionPool) DrainRouter(routerName string) int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	conns := pool.connections[routerName]
+	for _, conn := range conns {
+		if conn.InUse {
+			conn.retired = true
+			continue
+		}
+		if conn.Client != nil {
+			conn.Client.Close()
+		}
+	}
+	delete(pool.connections, routerName)
+
+	if len(conns) > 0 {
+		pool.logger.Infof("🚰 Drained %d pooled connection(s) for router: %s", len(conns), routerName)
+	}
+	return len(conns)
 }
 
 // CloseConnection closes and removes a specific connection from pool
@@ -128,6 +250,8 @@ func (pool *RouterOSConnectionPool) CloseConnection(conn *RouterOSConnection) {
 	if conn.Client != nil {
 		conn.Client.Close()
 	}
+	conn.closed = true
+	delete(pool.borrowed, conn)
 	pool.removeConnection(conn.RouterName, conn)
 	pool.logger.Debugf("🔒 Closed connection for router: %s", conn.RouterName)
 }
@@ -153,6 +277,43 @@ func (pool *RouterOSConnectionPool) removeConnection(routerName string, connToRe
 	}
 }
 
+// evict closes and removes a connection, logging why and counting the reason
+// (must be called with lock held)
+func (pool *RouterOSConnectionPool) evict(routerName string, conn *RouterOSConnection, reason, detail string) {
+	pool.removeConnection(routerName, conn)
+	pool.evictions[reason]++
+	pool.logger.WithFields(logrus.Fields{
+		"router": routerName,
+		"reason": reason,
+		"age":    time.Since(conn.Created).Round(time.Second).String(),
+		"idle":   time.Since(conn.LastUsed).Round(time.Second).String(),
+	}).Infof("🧹 Evicted pooled connection for %s (%s): %s", routerName, reason, detail)
+}
+
+// trimIdle evicts the least recently used idle connections of a router until
+// at most maxIdle remain. In-use connections are never touched.
+// (must be called with lock held)
+func (pool *RouterOSConnectionPool) trimIdle(routerName string) {
+	if pool.maxIdle <= 0 {
+		return
+	}
+
+	var idle []*RouterOSConnection
+	for _, conn := range pool.connections[routerName] {
+		if !conn.InUse {
+			idle = append(idle, conn)
+		}
+	}
+	if len(idle) <= pool.maxIdle {
+		return
+	}
+
+	sort.Slice(idle, func(i, j int) bool { return idle[i].LastUsed.Before(idle[j].LastUsed) })
+	for _, conn := range idle[:len(idle)-pool.maxIdle] {
+		pool.evict(routerName, conn, EvictionMaxIdle, fmt.Sprintf("more than %d idle connections", pool.maxIdle))
+	}
+}
+
 // isHealthy checks if a connection is still healthy
 func (pool *RouterOSConnectionPool) isHealthy(conn *RouterOSConnection) bool {
 	// Check if connection exceeded max lifetime
@@ -162,7 +323,7 @@ func (pool *RouterOSConnectionPool) isHealthy(conn *RouterOSConnection) bool {
 	}
 
 	// Try a simple command to verify connection is alive
-	_, err := conn.Client.Run("/system/identity/print")
+	_, err := conn.runOp(OpLight, "/system/identity/print")
 	if err != nil {
 		pool.logger.Debugf("Health check failed for %s: %v", conn.RouterName, err)
 		return false
@@ -196,48 +357,29 @@ func (pool *RouterOSConnectionPool) cleanup() {
 	totalCleaned := 0
 
 	for routerName, conns := range pool.connections {
-		var keepConns []*RouterOSConnection
-
-		for _, conn := range conns {
-			shouldKeep := true
-
-			// Remove connections that are idle for too long
-			if !conn.InUse && now.Sub(conn.LastUsed) > pool.idleTimeout {
-				pool.logger.Debugf("🧹 Cleaning up idle connection for %s (idle for %v)", routerName, now.Sub(conn.LastUsed))
-				if conn.Client != nil {
-					conn.Client.Close()
-				}
-				shouldKeep = false
-				totalCleaned++
-			}
-
-			// Remove connections that exceeded max lifetime
-			if now.Sub(conn.Created) > pool.maxLifetime {
-				pool.logger.Debugf("🧹 Cleaning up old connection for %s (age: %v)", routerName, now.Sub(conn.Created))
-				if conn.Client != nil {
-					conn.Client.Close()
-				}
-				shouldKeep = false
-				totalCleaned++
-			}
-
-			// Remove unhealthy connections that are not in use
-			if !conn.InUse && !pool.isHealthy(conn) {
-				pool.logger.Debugf("🧹 Cleaning up unhealthy connection for %s", routerName)
-				shouldKeep = false
-				totalCleaned++
+		// Copy first: evict rewrites pool.connections[routerName]
+		for _, conn := range append([]*RouterOSConnection(nil), conns...) {
+			// Connections in use are left alone; they are re-checked once released
+			if conn.InUse {
+				continue
 			}
 
-			if shouldKeep {
-				keepConns = append(keepConns, conn)
+			switch {
+			case now.Sub(conn.LastUsed) > pool.idleTimeout:
+				pool.evict(routerName, conn, EvictionIdleTimeout, fmt.Sprintf("idle for %v", now.Sub(conn.LastUsed).Round(time.Second)))
+			case now.Sub(conn.Created) > pool.maxLifetime:
+				pool.evict(routerName, conn, EvictionMaxLifetime, fmt.Sprintf("age %v", now.Sub(conn.Created).Round(time.Second)))
+			case !pool.isHealthy(conn):
+				pool.evict(routerName, conn, EvictionDeadOnValidate, "failed health check during cleanup")
+			default:
+				continue
 			}
+			totalCleaned++
 		}
 
-		if len(keepConns) > 0 {
-			pool.connections[routerName] = keepConns
-		} else {
-			delete(pool.connections, routerName)
-		}
+		before := len(pool.connections[routerName])
+		pool.trimIdle(routerName)
+		totalCleaned += before - len(pool.connections[routerName])
 	}
 
 	if totalCleaned > 0 {
@@ -245,6 +387,37 @@ func (pool *RouterOSConnectionPool) cleanup() {
 	}
 }
 
+// InUse returns how many connections are currently borrowed
+func (pool *RouterOSConnectionPool) InUse() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return len(pool.borrowed)
+}
+
+// Drain stops handing out connections (GetConnection returns ErrPoolDraining)
+// and waits up to timeout for the borrowed ones to be released. Returns how
+// many were still borrowed when it gave up; Close closes those anyway.
+func (pool *RouterOSConnectionPool) Drain(timeout time.Duration) int {
+	pool.mu.Lock()
+	pool.draining = true
+	pool.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		inUse := pool.InUse()
+		if inUse == 0 {
+			pool.logger.Info("🚰 Connection pool drained")
+			return 0
+		}
+		if !time.Now().Before(deadline) {
+			pool.logger.Warnf("⚠️ Connection pool drain timed out after %v with %d connection(s) still in use", timeout, inUse)
+			return inUse
+		}
+		time.Sleep(poolDrainPollInterval)
+	}
+}
+
 // Close shuts down the connection pool and closes all connections
 func (pool *RouterOSConnectionPool) Close() {
 	pool.logger.Info("🔒 Closing RouterOS connection pool...")
@@ -271,43 +444,109 @@ func (pool *RouterOSConnectionPool) Close() {
 	pool.logger.Infof("✅ Connection pool closed (%d connections closed)", totalClosed)
 }
 
-// GetStats returns connection pool statistics
-func (pool *RouterOSConnectionPool) GetStats() map[string]interface{} {
+// PoolStats are the connection pool statistics reported by GetStats
+type PoolStats struct {
+	TotalConnections  int                        `json:"total_connections"`
+	ActiveConnections int                        `json:"active_connections"`
+	InUse             int                        `json:"in_use"` // Borrowed, including connections already dropped from the pool
+	Draining          bool                       `json:"draining"`
+	IdleConnections   int                        `json:"idle_connections"`
+	TotalCreated      int64                      `json:"total_created"`
+	TotalReused       int64                      `json:"total_reused"`
+	TotalLimitReached int64                      `json:"total_limit_reached"`
+	Routers           int                        `json:"routers"`
+	RouterStats       map[string]RouterPoolStats `json:"router_stats"`
+	MaxPerRouter      int                        `json:"max_per_router"`
+	MaxIdlePerRouter  int                        `json:"max_idle_per_router"`
+	Evictions         map[string]int64           `json:"evictions"`
+}
+
+// RouterPoolStats are one router's connections (open now) and counters (since start)
+type RouterPoolStats struct {
+	Total        int64 `json:"total"`
+	Active       int64 `json:"active"`
+	Idle         int64 `json:"idle"`
+	Created      int64 `json:"created"`
+	Reused       int64 `json:"reused"`
+	LimitReached int64 `json:"limit_reached"`
+}
+
+// GetStats returns connection pool statistics. Per router it reports the
+// current total/active/idle connections and, since the pool started, how many
+// were created, reused and refused because the router was at maxConnections.
+func (pool *RouterOSConnectionPool) GetStats() PoolStats {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	totalConnections := 0
-	activeConnections := 0
-	idleConnections := 0
-	routerStats := make(map[string]map[string]int)
+	stats := PoolStats{
+		InUse:            len(pool.borrowed),
+		Draining:         pool.draining,
+		Routers:          len(pool.connections),
+		RouterStats:      make(map[string]RouterPoolStats),
+		MaxPerRouter:     pool.maxConnections,
+		MaxIdlePerRouter: pool.maxIdle,
+		Evictions:        pool.evictionCounts(),
+	}
 
-	for routerName, conns := range pool.connections {
-		routerActive := 0
-		routerIdle := 0
+	// Routers without open connections still report their counters
+	routerNames := make(map[string]struct{}, len(pool.connections))
+	for routerName := range pool.connections {
+		routerNames[routerName] = struct{}{}
+	}
+	for routerName := range pool.created {
+		routerNames[routerName] = struct{}{}
+	}
+	for routerName := range pool.limitReached {
+		routerNames[routerName] = struct{}{}
+	}
+
+	for routerName := range routerNames {
+		conns := pool.connections[routerName]
+		routerStats := RouterPoolStats{
+			Total:        int64(len(conns)),
+			Created:      pool.created[routerName],
+			Reused:       pool.reused[routerName],
+			LimitReached: pool.limitReached[routerName],
+		}
 
 		for _, conn := range conns {
-			totalConnections++
 			if conn.InUse {
-				activeConnections++
-				routerActive++
+				routerStats.Active++
 			} else {
-				idleConnections++
-				routerIdle++
+				routerStats.Idle++
 			}
 		}
 
-		routerStats[routerName] = map[string]int{
-			"total":  len(conns),
-			"active": routerActive,
-			"idle":   routerIdle,
-		}
+		stats.TotalConnections += len(conns)
+		stats.ActiveConnections += int(routerStats.Active)
+		stats.IdleConnections += int(routerStats.Idle)
+		stats.TotalCreated += routerStats.Created
+		stats.TotalReused += routerStats.Reused
+		stats.TotalLimitReached += routerStats.LimitReached
+		stats.RouterStats[routerName] = routerStats
 	}
 
-	return map[string]interface{}{
-		"total_connections":  totalConnections,
-		"active_connections": activeConnections,
-		"idle_connections":   idleConnections,
-		"routers":            len(pool.connections),
-		"router_stats":       routerStats,
+	return stats
+}
+
+// EvictionCounts returns how many connections were evicted per reason since the pool started
+func (pool *RouterOSConnectionPool) EvictionCounts() map[string]int64 {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.evictionCounts()
+}
+
+// evictionCounts copies the eviction counters (must be called with lock held)
+func (pool *RouterOSConnectionPool) evictionCounts() map[string]int64 {
+	counts := map[string]int64{
+		EvictionIdleTimeout:    0,
+		EvictionMaxLifetime:    0,
+		EvictionDeadOnValidate: 0,
+		EvictionMaxIdle:        0,
+	}
+	for reason, n := range pool.evictions {
+		counts[reason] = n
 	}
+	return counts
 }
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetConnectionLoginFailureLeavesNoConnection(t *testing.T) {
+	router := newFakeRouter(t, nil)
+	router.setRejectLogin(true)
+	pool := newTestPool(t)
+
+	if _, err := pool.GetConnection("fake", router.config()); err == nil {
+		t.Fatal("expected the rejected login to fail")
+	}
+	if n := len(pool.connections["fake"]); n != 0 {
+		t.Fatalf("pool kept %d connection(s) after a failed login", n)
+	}
+
+	// The next attempt dials again and succeeds once the router accepts it
+	router.setRejectLogin(false)
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	pool.ReleaseConnection(conn)
+}
+
+func TestDrainTimesOutWithBorrowedConnection(t *testing.T) {
+	router := newFakeRouter(t, nil)
+	pool := newTestPool(t)
+
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+
+	start := time.Now()
+	if left := pool.Drain(100 * time.Millisecond); left != 1 {
+		t.Fatalf("Drain left %d connection(s) in use, want the borrowed one", left)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("Drain gave up after %v, want about the 100ms timeout", elapsed)
+	}
+
+	// A draining pool hands out nothing, not even to a router it already knows
+	if _, err := pool.GetConnection("fake", router.config()); !errors.Is(err, ErrPoolDraining) {
+		t.Fatalf("err = %v, want ErrPoolDraining", err)
+	}
+	if stats := pool.GetStats(); !stats.Draining || stats.InUse != 1 {
+		t.Fatalf("stats draining = %v, in use = %d; want draining with 1 in use", stats.Draining, stats.InUse)
+	}
+
+	pool.ReleaseConnection(conn)
+	if left := pool.Drain(100 * time.Millisecond); left != 0 {
+		t.Fatalf("Drain left %d connection(s) in use after the release", left)
+	}
+}
+
+func TestDrainWaitsForRelease(t *testing.T) {
+	router := newFakeRouter(t, nil)
+	pool := newTestPool(t)
+
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		pool.ReleaseConnection(conn)
+	}()
+
+	if left := pool.Drain(5 * time.Second); left != 0 {
+		t.Fatalf("Drain left %d connection(s) in use, want 0", left)
+	}
+}
+
+func TestGetStatsCountsBorrowedConnections(t *testing.T) {
+	router := newFakeRouter(t, nil)
+	pool := newTestPool(t)
+
+	first, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.ReleaseConnection(second)
+
+	stats := pool.GetStats()
+	if stats.InUse != 1 || stats.ActiveConnections != 1 || stats.IdleConnections != 1 {
+		t.Fatalf("in use %d, active %d, idle %d; want 1, 1, 1", stats.InUse, stats.ActiveConnections, stats.IdleConnections)
+	}
+	want := RouterPoolStats{Total: 2, Active: 1, Idle: 1, Created: 2}
+	if got := stats.RouterStats["fake"]; got != want {
+		t.Fatalf("router stats = %+v, want %+v", got, want)
+	}
+
+	// A connection dropped from the pool while borrowed is still in use
+	pool.DrainRouter("fake")
+	if stats := pool.GetStats(); stats.InUse != 1 || stats.TotalConnections != 0 {
+		t.Fatalf("after DrainRouter: in use %d, total %d; want 1, 0", stats.InUse, stats.TotalConnections)
+	}
+	pool.ReleaseConnection(first)
+	if pool.InUse() != 0 {
+		t.Fatalf("%d connection(s) still in use", pool.InUse())
+	}
+}
+
+func TestMaxIdleTrimsLeastRecentlyUsed(t *testing.T) {
+	router := newFakeRouter(t, nil)
+	pool := NewRouterOSConnectionPool(quietLogger(), 3, time.Minute, time.Hour)
+	t.Cleanup(pool.Close)
+
+	var conns []*RouterOSConnection
+	for i := 0; i < 3; i++ {
+		conn, err := pool.GetConnection("fake", router.config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+
+	// Without a cap every released connection stays idle
+	pool.ReleaseConnection(conns[0])
+	pool.ReleaseConnection(conns[1])
+	if stats := pool.GetStats(); stats.IdleConnections != 2 {
+		t.Fatalf("idle = %d before the cap, want 2", stats.IdleConnections)
+	}
+
+	// Setting the cap trims right away, oldest release first
+	pool.SetMaxIdlePerRouter(1)
+	if stats := pool.GetStats(); stats.IdleConnections != 1 || stats.InUse != 1 {
+		t.Fatalf("idle %d, in use %d after the cap; want 1, 1", stats.IdleConnections, stats.InUse)
+	}
+
+	// Releasing over the cap evicts the older idle connection again
+	pool.ReleaseConnection(conns[2])
+	if stats := pool.GetStats(); stats.IdleConnections != 1 || stats.TotalConnections != 1 {
+		t.Fatalf("idle %d, total %d after release; want 1, 1", stats.IdleConnections, stats.TotalConnections)
+	}
+	if n := pool.EvictionCounts()[EvictionMaxIdle]; n != 2 {
+		t.Fatalf("max-idle evictions = %d, want 2", n)
+	}
+
+	// The survivor is the most recently released one
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn != conns[2] {
+		t.Fatal("reused connection is not the last one released")
+	}
+	pool.ReleaseConnection(conn)
+}
+
+func TestCleanupCountsEvictionReasons(t *testing.T) {
+	var dead atomic.Bool
+	router := newFakeRouter(t, func(command []string) [][]string {
+		if dead.Load() {
+			return hangUp
+		}
+		return nil
+	})
+	healthy := newFakeRouter(t, nil)
+	pool := NewRouterOSConnectionPool(quietLogger(), 3, time.Minute, time.Hour)
+	t.Cleanup(pool.Close)
+
+	borrow := func(routerName string, fr *fakeRouter) *RouterOSConnection {
+		t.Helper()
+		conn, err := pool.GetConnection(routerName, fr.config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	idle := borrow("healthy", healthy)
+	old := borrow("healthy", healthy)
+	busy := borrow("healthy", healthy)
+	broken := borrow("dying", router)
+	pool.ReleaseConnection(idle)
+	pool.ReleaseConnection(old)
+	pool.ReleaseConnection(broken)
+
+	// Age the idle connections past their limits; the borrowed one is as old
+	// but must survive
+	pool.mu.Lock()
+	idle.LastUsed = time.Now().Add(-2 * time.Minute)
+	old.Created = time.Now().Add(-2 * time.Hour)
+	busy.LastUsed = time.Now().Add(-2 * time.Minute)
+	busy.Created = time.Now().Add(-2 * time.Hour)
+	pool.mu.Unlock()
+	dead.Store(true)
+
+	pool.cleanup()
+
+	want := map[string]int64{
+		EvictionIdleTimeout:    1,
+		EvictionMaxLifetime:    1,
+		EvictionDeadOnValidate: 1,
+		EvictionMaxIdle:        0,
+	}
+	got := pool.EvictionCounts()
+	for reason, n := range want {
+		if got[reason] != n {
+			t.Errorf("%s evictions = %d, want %d", reason, got[reason], n)
+		}
+	}
+	if stats := pool.GetStats(); stats.TotalConnections != 1 || stats.InUse != 1 {
+		t.Fatalf("total %d, in use %d after cleanup; want only the borrowed connection", stats.TotalConnections, stats.InUse)
+	}
+	pool.ReleaseConnection(busy)
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-routeros/routeros"
+)
+
+// ErrUnexpectedRouterResponse is matched (via errors.Is) by every reply-shape error
+var ErrUnexpectedRouterResponse = errors.New("unexpected router response")
+
+// UnexpectedRouterResponseError describes a RouterOS reply that doesn't have the expected shape
+type UnexpectedRouterResponseError struct {
+	Command string
+	Reason  string
+}
+
+func (e *UnexpectedRouterResponseError) Error() string {
+	return fmt.Sprintf("unexpected router response from %s: %s", e.Command, e.Reason)
+}
+
+// Is lets errors.Is(err, ErrUnexpectedRouterResponse) match
+func (e *UnexpectedRouterResponseError) Is(target error) bool {
+	return target == ErrUnexpectedRouterResponse
+}
+
+// firstRow returns the first row of a reply, requiring the given keys to be present
+func firstRow(reply *routeros.Reply, command string, requiredKeys ...string) (map[string]string, error) {
+	if reply == nil || len(reply.Re) == 0 || reply.Re[0] == nil {
+		return nil, &UnexpectedRouterResponseError{Command: command, Reason: "empty reply"}
+	}
+
+	row := reply.Re[0].Map
+	for _, key := range requiredKeys {
+		if _, ok := row[key]; !ok {
+			return nil, &UnexpectedRouterResponseError{Command: command, Reason: fmt.Sprintf("missing field %q", key)}
+		}
+	}
+
+	return row, nil
+}
+
+// mapString reads a key from a reply row, returning def when absent or empty
+func mapString(row map[string]string, key, def string) string {
+	if value, ok := row[key]; ok && value != "" {
+		return value
+	}
+	return def
+}
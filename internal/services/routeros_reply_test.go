@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-routeros/routeros"
+	"github.com/go-routeros/routeros/proto"
+)
+
+func TestFirstRow(t *testing.T) {
+	row := func(pairs map[string]string) *proto.Sentence {
+		return &proto.Sentence{Map: pairs}
+	}
+
+	tests := []struct {
+		name    string
+		reply   *routeros.Reply
+		wantErr string
+	}{
+		{"nil reply", nil, "empty reply"},
+		{"no rows", &routeros.Reply{}, "empty reply"},
+		{"nil row", &routeros.Reply{Re: []*proto.Sentence{nil}}, "empty reply"},
+		{"missing field", &routeros.Reply{Re: []*proto.Sentence{row(map[string]string{"version": "7.14"})}}, `missing field "cpu-load"`},
+		{"complete", &routeros.Reply{Re: []*proto.Sentence{row(map[string]string{"version": "7.14", "cpu-load": "3"})}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := firstRow(tt.reply, "/system/resource/print", "version", "cpu-load")
+			if tt.wantErr == "" {
+				if err != nil || got["cpu-load"] != "3" {
+					t.Fatalf("firstRow = %v, %v, want the row", got, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnexpectedRouterResponse) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want an unexpected response error with %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), "/system/resource/print") {
+				t.Errorf("err = %v, want the command named", err)
+			}
+		})
+	}
+}
+
+func TestMapString(t *testing.T) {
+	row := map[string]string{"version": "7.14", "board-name": ""}
+	if got := mapString(row, "version", "unknown"); got != "7.14" {
+		t.Errorf("present key = %q, want 7.14", got)
+	}
+	if got := mapString(row, "board-name", "unknown"); got != "unknown" {
+		t.Errorf("empty value = %q, want the default", got)
+	}
+	if got := mapString(nil, "version", "unknown"); got != "unknown" {
+		t.Errorf("nil row = %q, want the default", got)
+	}
+}
+
+func TestRouterConnectionMalformedReplies(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity [][]string
+		resource [][]string
+		wantErr  string
+	}{
+		{"empty identity", nil, [][]string{reSentence("version", "7.14"), {"!done"}}, "/system/identity/print: empty reply"},
+		{"empty resource", [][]string{reSentence("name", "core"), {"!done"}}, nil, "/system/resource/print: empty reply"},
+		{"partial resource", [][]string{reSentence("name", "core"), {"!done"}}, [][]string{reSentence("board-name", "CHR"), {"!done"}}, `missing field "version"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := newFakeRouter(t, func(command []string) [][]string {
+				switch command[0] {
+				case "/system/identity/print":
+					return tt.identity
+				case "/system/resource/print":
+					return tt.resource
+				}
+				return nil
+			})
+			ns := newTestNATService(t, fr)
+
+			result := ns.TestRouterConnection("FAKE")
+			if result.Status != "error" || !strings.Contains(result.Message, tt.wantErr) {
+				t.Fatalf("result = %+v, want an error status with %q", result, tt.wantErr)
+			}
+		})
+	}
+
+	// A partial but sufficient reply falls back to defaults for optional fields
+	fr := newFakeRouter(t, func(command []string) [][]string {
+		switch command[0] {
+		case "/system/identity/print":
+			return [][]string{reSentence("name", ""), {"!done"}}
+		case "/system/resource/print":
+			return [][]string{reSentence("version", "7.14"), {"!done"}}
+		}
+		return nil
+	})
+	result := newTestNATService(t, fr).TestRouterConnection("FAKE")
+	if result.Status != "connected" || result.RouterName != "FAKE" || result.Version != "7.14" || result.Board != "unknown" {
+		t.Fatalf("result = %+v, want connected with defaults for the blank name and missing board", result)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-routeros/routeros"
+)
+
+// OperationType classifies a RouterOS command by how long it may take, so a
+// /system/backup can run for minutes while an identity print fails fast
+type OperationType string
+
+const (
+	OpLight OperationType = "light" // Identity/resource prints, health checks, login
+	OpRead  OperationType = "read"  // Table prints (active sessions, NAT rules, logs)
+	OpWrite OperationType = "write" // set/add/remove
+	OpHeavy OperationType = "heavy" // /system/backup, /export and similar
+)
+
+// defaultOperationTimeouts are used until overridden with SetOperationTimeouts
+var defaultOperationTimeouts = map[OperationType]time.Duration{
+	OpLight: 5 * time.Second,
+	OpRead:  15 * time.Second,
+	OpWrite: 15 * time.Second,
+	OpHeavy: 2 * time.Minute,
+}
+
+// IsValid reports whether op is a known operation type
+func (op OperationType) IsValid() bool {
+	_, ok := defaultOperationTimeouts[op]
+	return ok
+}
+
+// SetOperationTimeouts overrides per-operation command timeouts, e.g.
+// {"heavy": "300"} (seconds). Unknown operations and bad values are skipped.
+func (pool *RouterOSConnectionPool) SetOperationTimeouts(overrides map[string]string) {
+	pool.opMu.Lock()
+	defer pool.opMu.Unlock()
+
+	for name, value := range overrides {
+		op := OperationType(name)
+		var seconds int
+		if _, err := fmt.Sscanf(value, "%d", &seconds); err != nil || !op.IsValid() || seconds <= 0 {
+			pool.logger.Warnf("⚠️ Ignoring RouterOS operation timeout %s=%s", name, value)
+			continue
+		}
+		pool.opTimeouts[op] = time.Duration(seconds) * time.Second
+		pool.logger.Infof("⏱️ RouterOS %s command timeout: %v", op, pool.opTimeouts[op])
+	}
+}
+
+// OperationTimeout returns the command timeout for op (OpRead for unknown types)
+func (pool *RouterOSConnectionPool) OperationTimeout(op OperationType) time.Duration {
+	pool.opMu.RLock()
+	defer pool.opMu.RUnlock()
+
+	if timeout, ok := pool.opTimeouts[op]; ok {
+		return timeout
+	}
+	return pool.opTimeouts[OpRead]
+}
+
+// RunOp runs a command with op's timeout as the connection deadline. The
+// deadline is cleared afterwards so an idle pooled connection never expires.
+// A timed-out connection is out of sync with the router: close it, don't reuse it.
+// The command counts against the router's throttle and fails with
+// ErrRouterBusy when the router is saturated.
+func (conn *RouterOSConnection) RunOp(op OperationType, sentence ...string) (*routeros.Reply, error) {
+	return conn.RunOpContext(context.Background(), op, sentence...)
+}
+
+// RunOpContext is RunOp that also gives up when ctx is done. A command cut
+// short by ctx leaves the connection out of sync just like a timeout does.
+func (conn *RouterOSConnection) RunOpContext(ctx context.Context, op OperationType, sentence ...string) (*routeros.Reply, error) {
+	if conn.pool != nil {
+		if err := conn.pool.Throttle().Wait(conn.RouterName); err != nil {
+			return nil, err
+		}
+	}
+	return conn.runOpContext(ctx, op, sentence...)
+}
+
+// runOp is RunOp without the throttle, for the pool's own health checks which
+// run with the pool lock held and must never queue
+func (conn *RouterOSConnection) runOp(op OperationType, sentence ...string) (*routeros.Reply, error) {
+	return conn.runOpContext(context.Background(), op, sentence...)
+}
+
+// runOpContext runs a command under op's timeout (pooled connections only)
+// and ctx. Without the transport at hand neither can interrupt the command.
+func (conn *RouterOSConnection) runOpContext(ctx context.Context, op OperationType, sentence ...string) (*routeros.Reply, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if conn.netConn == nil {
+		return conn.Client.Run(sentence...)
+	}
+
+	var timeout time.Duration
+	if conn.pool != nil {
+		timeout = conn.pool.OperationTimeout(op)
+	}
+	stop := bindDeadline(ctx, conn.netConn, timeout)
+	defer stop()
+
+	reply, err := conn.Client.Run(sentence...)
+	if err != nil && isTimeoutError(err) {
+		command := ""
+		if len(sentence) > 0 {
+			command = sentence[0]
+		}
+		if ctxErr := contextDone(ctx); ctxErr != nil {
+			return nil, fmt.Errorf("%s command %s stopped: %w (%w)", op, command, ctxErr, err)
+		}
+		return nil, fmt.Errorf("%s command %s timed out after %v: %w", op, command, timeout, err)
+	}
+	return reply, err
+}
+
+// contextDone returns ctx's error, or DeadlineExceeded once its deadline has
+// passed even if ctx has not noticed yet: a socket deadline set from it can
+// fire first
+func contextDone(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// isTimeoutError reports whether err is a network deadline/timeout error
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
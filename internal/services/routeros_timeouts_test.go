@@ -0,0 +1,44 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunOpUsesTheOperationTimeout(t *testing.T) {
+	router := newFakeRouter(t, func(command []string) [][]string {
+		// Every command takes a while, as a backup or a busy router would
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+	pool := newTestPool(t)
+	pool.opMu.Lock()
+	pool.opTimeouts[OpLight] = 50 * time.Millisecond
+	pool.opTimeouts[OpHeavy] = 2 * time.Second
+	pool.opMu.Unlock()
+
+	conn, err := pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	if _, err := conn.RunOp(OpHeavy, "/system/backup/save"); err != nil {
+		t.Fatalf("heavy command under its extended timeout: %v", err)
+	}
+	pool.ReleaseConnection(conn)
+
+	conn, err = pool.GetConnection("fake", router.config())
+	if err != nil {
+		t.Fatalf("GetConnection: %v", err)
+	}
+	defer pool.ReleaseConnection(conn)
+
+	start := time.Now()
+	_, err = conn.RunOp(OpLight, "/system/identity/print")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("err = %v, want the light command to time out after 50ms", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Fatalf("light command took %v, want the short default", elapsed)
+	}
+}
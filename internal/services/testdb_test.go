@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"nat-management-app/internal/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migratedTestDB applies every migration, seed data included, in a throwaway
+// schema of TEST_DATABASE_URL, skipping the test when no database is configured
+func migratedTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	schema := fmt.Sprintf("services_test_%d", time.Now().UnixNano())
+	// public stays on the path for extensions installed there
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	// 009 converts legacy timestamps from the zone the app wrote them in
+	config.ConnConfig.RuntimeParams["app.legacy_timezone"] = "UTC"
+
+	ctx := context.Background()
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		pool.Close()
+	})
+	if _, err := pool.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("migration %s: %v", filepath.Base(file), err)
+		}
+	}
+	return &database.DB{Pool: pool, Logger: quietLogger()}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTP parameters (RFC 6238 defaults, understood by every authenticator app)
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = otp.DigitsSix
+	totpSkew   = 1 // Steps accepted either side of the current one
+	totpIssuer = "NAT Management"
+)
+
+// generateTOTPKey returns a new random 160-bit secret for account, with the
+// otpauth:// URI authenticator apps import (usually as a QR code)
+func generateTOTPKey(account string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: account,
+		Period:      uint(totpPeriod.Seconds()),
+		Digits:      totpDigits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+}
+
+// matchTOTPStep checks code against the secret at t, allowing totpSkew steps
+// of clock drift, and returns the time step it belongs to. Callers reject a
+// step at or before the last one accepted, so a code can't be replayed.
+func matchTOTPStep(secret, code string, t time.Time) (int64, bool) {
+	current := t.Unix() / int64(totpPeriod.Seconds())
+	opts := hotp.ValidateOpts{Digits: totpDigits, Algorithm: otp.AlgorithmSHA1}
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step < 0 {
+			continue
+		}
+		if ok, err := hotp.ValidateCustom(code, uint64(step), secret, opts); err == nil && ok {
+			return step, true
+		}
+	}
+	return 0, false
+}
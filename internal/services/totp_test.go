@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// rfc6238Secret is the RFC 6238 SHA1 test key "12345678901234567890", base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMatchTOTPStepRFCVector(t *testing.T) {
+	// RFC 6238 appendix B: T = 59s gives 94287082, whose last 6 digits are the code
+	step, ok := matchTOTPStep(rfc6238Secret, "287082", time.Unix(59, 0))
+	if !ok || step != 1 {
+		t.Fatalf("matchTOTPStep = %d, %v; want step 1", step, ok)
+	}
+}
+
+func TestMatchTOTPStepSkew(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	current := now.Unix() / 30
+	opts := totp.ValidateOpts{Period: 30, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		wantOK bool
+		step   int64
+	}{
+		{"current step", now, true, current},
+		{"previous step", now.Add(-30 * time.Second), true, current - 1},
+		{"next step", now.Add(30 * time.Second), true, current + 1},
+		{"too old", now.Add(-90 * time.Second), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totp.GenerateCodeCustom(rfc6238Secret, tt.at, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := matchTOTPStep(rfc6238Secret, code, now)
+			if ok != tt.wantOK || (ok && step != tt.step) {
+				t.Fatalf("matchTOTPStep = %d, %v; want %d, %v", step, ok, tt.step, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPStepRejectsMalformed(t *testing.T) {
+	now := time.Now()
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := matchTOTPStep(rfc6238Secret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := matchTOTPStep("not base32!", "123456", now); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestGenerateTOTPKey(t *testing.T) {
+	key, err := generateTOTPKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Secret()) != 32 {
+		t.Errorf("secret %q is not 160 bits of base32", key.Secret())
+	}
+	if key.Issuer() != totpIssuer || key.AccountName() != "alice" || key.Period() != 30 {
+		t.Errorf("key URL %s", key.URL())
+	}
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := matchTOTPStep(key.Secret(), code, time.Now()); !ok {
+		t.Error("code from the generated key rejected")
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// userExportActivityLimit caps how many recent activity logs go into an export
+const userExportActivityLimit = 5000
+
+// StreamUserExport writes everything stored about a user as one JSON document:
+// profile (never the password hash), router assignments, recent activity logs
+// and PPPoE search history. Rows are encoded as they are read so large
+// histories never sit in memory.
+func (s *UserService) StreamUserExport(ctx context.Context, userID int, w io.Writer) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	user.Password = ""
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	writeField := func(name string, value interface{}) error {
+		if _, err := fmt.Fprintf(bw, "%q:", name); err != nil {
+			return err
+		}
+		return enc.Encode(value)
+	}
+
+	if _, err := bw.WriteString("{"); err != nil {
+		return err
+	}
+	if err := writeField("exported_at", time.Now().UTC()); err != nil {
+		return err
+	}
+	bw.WriteString(",")
+	if err := writeField("profile", user.User); err != nil {
+		return err
+	}
+	bw.WriteString(",")
+	if err := writeField("routers", user.Routers); err != nil {
+		return err
+	}
+
+	bw.WriteString(`,"activity_logs":`)
+	if err := s.streamUserActivityLogs(ctx, userID, bw, enc); err != nil {
+		return err
+	}
+
+	bw.WriteString(`,"pppoe_search_history":`)
+	if err := s.streamUserSearchHistory(ctx, userID, bw, enc); err != nil {
+		return err
+	}
+
+	if _, err := bw.WriteString("}\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// streamUserActivityLogs encodes the user's most recent activity logs as a JSON array
+func (s *UserService) streamUserActivityLogs(ctx context.Context, userID int, bw *bufio.Writer, enc *json.Encoder) error {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, action_type, COALESCE(resource_type, ''), COALESCE(resource_id, ''),
+		       COALESCE(description, ''), COALESCE(ip_address, ''), COALESCE(user_agent, ''),
+		       status, created_at
+		FROM activity_logs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, userExportActivityLimit)
+	if err != nil {
+		return fmt.Errorf("failed to query activity logs: %w", err)
+	}
+	defer rows.Close()
+
+	type exportActivityLog struct {
+		ID           int       `json:"id"`
+		ActionType   string    `json:"action_type"`
+		ResourceType string    `json:"resource_type,omitempty"`
+		ResourceID   string    `json:"resource_id,omitempty"`
+		Description  string    `json:"description"`
+		IPAddress    string    `json:"ip_address,omitempty"`
+		UserAgent    string    `json:"user_agent,omitempty"`
+		Status       string    `json:"status"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	bw.WriteString("[")
+	first := true
+	for rows.Next() {
+		var entry exportActivityLog
+		if err := rows.Scan(&entry.ID, &entry.ActionType, &entry.ResourceType, &entry.ResourceID,
+			&entry.Description, &entry.IPAddress, &entry.UserAgent, &entry.Status, &entry.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan activity log: %w", err)
+		}
+		if !first {
+			bw.WriteString(",")
+		}
+		first = false
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating activity logs: %w", err)
+	}
+	_, err = bw.WriteString("]")
+	return err
+}
+
+// streamUserSearchHistory encodes the user's PPPoE search history as a JSON array
+func (s *UserService) streamUserSearchHistory(ctx context.Context, userID int, bw *bufio.Writer, enc *json.Encoder) error {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, username, COALESCE(router_name, ''), is_online,
+		       COALESCE(ip_address, ''), search_timestamp
+		FROM pppoe_search_history
+		WHERE user_id = $1
+		ORDER BY search_timestamp DESC
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to query PPPoE search history: %w", err)
+	}
+	defer rows.Close()
+
+	type exportSearch struct {
+		ID         int       `json:"id"`
+		Username   string    `json:"username"`
+		RouterName string    `json:"router_name,omitempty"`
+		IsOnline   *bool     `json:"is_online,omitempty"`
+		IPAddress  string    `json:"ip_address,omitempty"`
+		SearchedAt time.Time `json:"searched_at"`
+	}
+
+	bw.WriteString("[")
+	first := true
+	for rows.Next() {
+		var entry exportSearch
+		if err := rows.Scan(&entry.ID, &entry.Username, &entry.RouterName, &entry.IsOnline,
+			&entry.IPAddress, &entry.SearchedAt); err != nil {
+			return fmt.Errorf("failed to scan PPPoE search history: %w", err)
+		}
+		if !first {
+			bw.WriteString(",")
+		}
+		first = false
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating PPPoE search history: %w", err)
+	}
+	_, err = bw.WriteString("]")
+	return err
+}
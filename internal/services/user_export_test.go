@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStreamUserExportSections(t *testing.T) {
+	db := migratedTestDB(t)
+	s := NewUserService(db, quietLogger())
+
+	user, err := s.CreateUser(&CreateUserRequest{
+		Username: "teknisi1",
+		Password: "Rahasia123",
+		FullName: "Teknisi Satu",
+		Email:    "teknisi1@example.com",
+		Routers:  []string{"SAMSAT", "LANE1"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := db.Pool.Exec(ctx, `
+		INSERT INTO activity_logs (user_id, username, action_type, description, status)
+		VALUES ($1, 'teknisi1', 'LOGIN', 'Logged in', 'SUCCESS'), ($1, 'teknisi1', 'NAT_UPDATE', 'Updated NAT rule', 'SUCCESS')
+	`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool.Exec(ctx, `
+		INSERT INTO pppoe_search_history (user_id, username, router_name, is_online, ip_address)
+		VALUES ($1, 'alice', 'SAMSAT', true, '10.0.1.2')
+	`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.StreamUserExport(ctx, user.ID, &buf); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	var bundle map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("export is not one JSON document: %v\n%s", err, buf.String())
+	}
+	for _, section := range []string{"exported_at", "profile", "routers", "activity_logs", "pppoe_search_history"} {
+		if _, ok := bundle[section]; !ok {
+			t.Errorf("export has no %s section", section)
+		}
+	}
+
+	var profile map[string]any
+	if err := json.Unmarshal(bundle["profile"], &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["username"] != "teknisi1" {
+		t.Errorf("profile = %v, want teknisi1", profile)
+	}
+	if _, ok := profile["password"]; ok {
+		t.Errorf("profile carries a password field: %v", profile)
+	}
+	if strings.Contains(buf.String(), "$2a$") || strings.Contains(buf.String(), "Rahasia123") {
+		t.Fatalf("export leaks the password or its hash:\n%s", buf.String())
+	}
+
+	var routers []string
+	var logs, searches []map[string]any
+	if err := json.Unmarshal(bundle["routers"], &routers); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bundle["activity_logs"], &logs); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bundle["pppoe_search_history"], &searches); err != nil {
+		t.Fatal(err)
+	}
+	if len(routers) != 2 || len(logs) != 2 || len(searches) != 1 {
+		t.Fatalf("export has %d routers, %d activity logs, %d searches; want 2, 2, 1", len(routers), len(logs), len(searches))
+	}
+	if searches[0]["username"] != "alice" || searches[0]["router_name"] != "SAMSAT" {
+		t.Errorf("search = %v, want alice on SAMSAT", searches[0])
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import "errors"
+
+// MaxUserImportRows bounds one import, every row costs a bcrypt hash
+const MaxUserImportRows = 500
+
+// UserImportRequest represents request to import users from JSON
+type UserImportRequest struct {
+	Users     []CreateUserRequest `json:"users" binding:"required"`
+	Overwrite bool                `json:"overwrite"` // Whether to overwrite existing users
+}
+
+// UserImportResponse represents the result of a user import
+type UserImportResponse struct {
+	Imported    int      `json:"imported"` // Created plus overwritten
+	Failed      int      `json:"failed"`
+	Skipped     int      `json:"skipped"` // Existing users left alone without overwrite
+	FailedItems []string `json:"failed_items,omitempty"`
+}
+
+// ImportOutcome tells what ImportUser did with one row
+type ImportOutcome string
+
+const (
+	ImportCreated     ImportOutcome = "created"
+	ImportOverwritten ImportOutcome = "overwritten"
+	ImportSkipped     ImportOutcome = "skipped"
+)
+
+// ImportUser imports one user in its own transaction. A new username goes
+// through CreateUser; an existing active user is skipped, or with overwrite
+// gets the row's password, details and routers. Returns the ID of the user
+// created or overwritten (0 when skipped).
+func (s *UserService) ImportUser(req *CreateUserRequest, overwrite bool) (ImportOutcome, int, error) {
+	existing, err := s.findUserHolding("username", req.Username)
+	if err != nil {
+		s.logger.Errorf("Error checking username existence: %v", err)
+		return "", 0, err
+	}
+
+	if existing == nil || !existing.IsActive {
+		user, err := s.CreateUser(req)
+		if err != nil {
+			return "", 0, err
+		}
+		return ImportCreated, user.ID, nil
+	}
+
+	if !overwrite {
+		return ImportSkipped, 0, nil
+	}
+
+	// UpdateUser doesn't check the email, keep it unique here like CreateUser does
+	emailHolder, err := s.findUserHolding("email", req.Email)
+	if err != nil {
+		s.logger.Errorf("Error checking email existence: %v", err)
+		return "", 0, err
+	}
+	if emailHolder != nil && emailHolder.ID != existing.ID {
+		return "", 0, errors.New("email already exists")
+	}
+
+	_, err = s.UpdateUser(existing.ID, &UpdateUserRequest{
+		FullName:       req.FullName,
+		Email:          req.Email,
+		Password:       req.Password,
+		Routers:        req.Routers,
+		IsActive:       true,
+		NoRouterAccess: req.NoRouterAccess,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return ImportOverwritten, existing.ID, nil
+}
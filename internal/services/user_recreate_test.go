@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCreateUserOverSoftDeletedUser(t *testing.T) {
+	s := NewUserService(migratedTestDB(t), quietLogger())
+
+	newRequest := func(policy string) *CreateUserRequest {
+		return &CreateUserRequest{
+			Username:          "teknisi1",
+			Password:          "Rahasia123",
+			FullName:          "Teknisi Satu",
+			Email:             "teknisi1@example.com",
+			NoRouterAccess:    true,
+			DeletedUserPolicy: policy,
+		}
+	}
+
+	original, err := s.CreateUser(newRequest(""))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := s.DeleteUser(original.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// Without a policy the caller is told who holds the username
+	_, err = s.CreateUser(newRequest(""))
+	var conflict *DeletedUserConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrDeletedUserConflict) {
+		t.Fatalf("recreate without policy: err = %v, want a *DeletedUserConflictError", err)
+	}
+	if conflict.UserID != original.ID || conflict.Field != "username" {
+		t.Fatalf("conflict = %+v, want user %d holding the username", conflict, original.ID)
+	}
+
+	// restore brings back the same row
+	restored, err := s.CreateUser(newRequest(DeletedUserRestore))
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.ID != original.ID || !restored.IsActive {
+		t.Fatalf("restored = %+v, want user %d active again", restored.User, original.ID)
+	}
+
+	// clear keeps the deleted row under a released name and creates a new user
+	if err := s.DeleteUser(restored.ID); err != nil {
+		t.Fatalf("delete again: %v", err)
+	}
+	created, err := s.CreateUser(newRequest(DeletedUserClear))
+	if err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if created.ID == original.ID || created.Username != "teknisi1" || !created.IsActive {
+		t.Fatalf("created = %+v, want a new active teknisi1", created.User)
+	}
+	released, err := s.GetUserByID(original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suffix := fmt.Sprintf("#deleted-%d", original.ID)
+	if released.IsActive || released.Username != "teknisi1"+suffix || released.Email != "teknisi1@example.com"+suffix {
+		t.Fatalf("released = %+v, want the inactive old user with %s appended", released.User, suffix)
+	}
+
+	// An active holder is never touched, whatever the policy
+	if _, err := s.CreateUser(newRequest(DeletedUserClear)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("create over an active user: err = %v, want already exists", err)
+	}
+}
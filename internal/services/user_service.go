@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"nat-management-app/internal/database"
@@ -16,31 +17,70 @@ import (
 
 // UserService handles user management operations
 type UserService struct {
-	db     *database.DB
-	logger *logrus.Logger
+	db             *database.DB
+	logger         *logrus.Logger
+	passwordPolicy PasswordPolicy
 }
 
 // NewUserService creates a new UserService instance
 func NewUserService(db *database.DB, logger *logrus.Logger) *UserService {
 	return &UserService{
-		db:     db,
-		logger: logger,
+		db:             db,
+		logger:         logger,
+		passwordPolicy: DefaultPasswordPolicy,
 	}
 }
 
 // UserWithRouters represents a user with their assigned routers
 type UserWithRouters struct {
 	models.User
-	Routers []string `json:"routers"`
+	Routers        []string `json:"routers"`
+	NoRouterAccess bool     `json:"no_router_access"` // Deliberately no routers, role access not used
+	Status         string   `json:"status,omitempty"` // "active" or "inactive", set when listing inactive users
 }
 
 // CreateUserRequest represents request to create a new user
 type CreateUserRequest struct {
 	Username string   `json:"username" binding:"required"`
-	Password string   `json:"password" binding:"required,min=6"`
+	Password string   `json:"password" binding:"required"` // Checked against the password policy
 	FullName string   `json:"full_name" binding:"required"`
 	Email    string   `json:"email" binding:"required,email"`
 	Routers  []string `json:"routers"` // List of router names
+	// NoRouterAccess restricts the user to zero routers. Leaving Routers empty
+	// without it means "use the role's routers". Can't be combined with Routers.
+	NoRouterAccess bool `json:"no_router_access"`
+	// DeletedUserPolicy decides what happens when a soft-deleted user still
+	// holds the username or email: DeletedUserRestore or DeletedUserClear.
+	// Empty returns a *DeletedUserConflictError so the caller can choose.
+	DeletedUserPolicy string `json:"deleted_user_policy,omitempty" binding:"omitempty,oneof=restore clear"`
+}
+
+// Policies for recreating a user whose username/email is held by a soft-deleted user
+const (
+	DeletedUserRestore = "restore" // Reactivate the deleted user with the new details
+	DeletedUserClear   = "clear"   // Release the deleted user's username/email and create a new user
+)
+
+// ErrNoAccessWithRouters is returned when a request sets no_router_access and also assigns routers
+var ErrNoAccessWithRouters = errors.New("no_router_access cannot be combined with router assignments")
+
+// ErrDeletedUserConflict is matched (via errors.Is) when a soft-deleted user blocks CreateUser
+var ErrDeletedUserConflict = errors.New("username or email belongs to a deleted user")
+
+// DeletedUserConflictError identifies the soft-deleted user holding a requested username/email
+type DeletedUserConflictError struct {
+	UserID   int
+	Username string
+	Field    string // "username" or "email"
+}
+
+func (e *DeletedUserConflictError) Error() string {
+	return fmt.Sprintf("%s belongs to deleted user '%s' (ID %d); restore it or clear it to reuse the %s", e.Field, e.Username, e.UserID, e.Field)
+}
+
+// Is lets errors.Is(err, ErrDeletedUserConflict) match
+func (e *DeletedUserConflictError) Is(target error) bool {
+	return target == ErrDeletedUserConflict
 }
 
 // UpdateUserRequest represents request to update a user
@@ -50,29 +90,75 @@ type UpdateUserRequest struct {
 	Password string   `json:"password,omitempty"` // Optional: only if changing password
 	Routers  []string `json:"routers"`            // List of router names
 	IsActive bool     `json:"is_active"`
+	// NoRouterAccess restricts the user to zero routers (see CreateUserRequest)
+	NoRouterAccess bool `json:"no_router_access"`
 }
 
-// CreateUser creates a new user with router assignments
-func (s *UserService) CreateUser(req *CreateUserRequest) (*UserWithRouters, error) {
-	// Check if username already exists
-	var exists bool
-	err := s.db.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", req.Username).Scan(&exists)
+// findUserHolding returns the user holding value in column (username or email), or nil
+func (s *UserService) findUserHolding(column, value string) (*models.User, error) {
+	var user models.User
+	err := s.db.Pool.QueryRow(context.Background(),
+		"SELECT id, username, email, is_active FROM users WHERE "+column+" = $1", value,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.IsActive)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		s.logger.Errorf("Error checking username existence: %v", err)
 		return nil, err
 	}
-	if exists {
-		return nil, errors.New("username already exists")
-	}
+	return &user, nil
+}
 
-	// Check if email already exists
-	err = s.db.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", req.Email).Scan(&exists)
-	if err != nil {
-		s.logger.Errorf("Error checking email existence: %v", err)
+// CreateUser creates a new user with router assignments.
+// A username or email held by a soft-deleted user is handled per
+// req.DeletedUserPolicy instead of failing as "already exists".
+func (s *UserService) CreateUser(req *CreateUserRequest) (*UserWithRouters, error) {
+	if err := s.ValidatePassword(req.Password, req.Username); err != nil {
 		return nil, err
 	}
-	if exists {
-		return nil, errors.New("email already exists")
+
+	req.Routers = s.canonicalRouterNames(req.Routers)
+	if req.NoRouterAccess && len(req.Routers) > 0 {
+		return nil, ErrNoAccessWithRouters
+	}
+
+	var deletedHolders []*models.User
+	for _, field := range []struct{ column, value string }{
+		{"username", req.Username},
+		{"email", req.Email},
+	} {
+		holder, err := s.findUserHolding(field.column, field.value)
+		if err != nil {
+			s.logger.Errorf("Error checking %s existence: %v", field.column, err)
+			return nil, err
+		}
+		if holder == nil {
+			continue
+		}
+		if holder.IsActive {
+			return nil, errors.New(field.column + " already exists")
+		}
+		if req.DeletedUserPolicy == "" {
+			return nil, &DeletedUserConflictError{UserID: holder.ID, Username: holder.Username, Field: field.column}
+		}
+		deletedHolders = append(deletedHolders, holder)
+	}
+
+	if len(deletedHolders) > 0 {
+		switch req.DeletedUserPolicy {
+		case DeletedUserRestore:
+			// Only restore when it's unambiguous: one deleted user holds what was asked for
+			if len(deletedHolders) == 2 && deletedHolders[0].ID != deletedHolders[1].ID {
+				return nil, errors.New("username and email belong to two different deleted users; clear them instead")
+			}
+			return s.restoreDeletedUser(deletedHolders[0].ID, req)
+		case DeletedUserClear:
+			for _, holder := range deletedHolders {
+				if err := s.releaseDeletedUser(holder.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
 	// Hash password
@@ -92,12 +178,13 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*UserWithRouters, erro
 	defer tx.Rollback(ctx)
 
 	// Insert user (role is deprecated, use empty string or default)
+	now := time.Now().UTC()
 	var userID int
 	err = tx.QueryRow(ctx, `
-		INSERT INTO users (username, password, full_name, email, role, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (username, password, full_name, email, role, is_active, no_router_access, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
-	`, req.Username, string(hashedPassword), req.FullName, req.Email, "User", true, time.Now(), time.Now()).Scan(&userID)
+	`, req.Username, string(hashedPassword), req.FullName, req.Email, "User", true, req.NoRouterAccess, now, now).Scan(&userID)
 	if err != nil {
 		s.logger.Errorf("Error inserting user: %v", err)
 		return nil, err
@@ -109,7 +196,7 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*UserWithRouters, erro
 			INSERT INTO user_routers (user_id, router_name, created_at)
 			VALUES ($1, $2, $3)
 			ON CONFLICT (user_id, router_name) DO NOTHING
-		`, userID, routerName, time.Now())
+		`, userID, routerName, time.Now().UTC())
 		if err != nil {
 			s.logger.Errorf("Error assigning router %s to user: %v", routerName, err)
 			return nil, err
@@ -128,6 +215,81 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*UserWithRouters, erro
 	return s.GetUserByID(userID)
 }
 
+// restoreDeletedUser reactivates a soft-deleted user with the details and routers from req
+func (s *UserService) restoreDeletedUser(userID int, req *CreateUserRequest) (*UserWithRouters, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Errorf("Error hashing password: %v", err)
+		return nil, err
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		s.logger.Errorf("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET username = $1, password = $2, full_name = $3, email = $4, is_active = true, no_router_access = $5, updated_at = $6
+		WHERE id = $7 AND is_active = false
+	`, req.Username, string(hashedPassword), req.FullName, req.Email, req.NoRouterAccess, time.Now().UTC(), userID)
+	if err != nil {
+		s.logger.Errorf("Error restoring user %d: %v", userID, err)
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM user_routers WHERE user_id = $1", userID); err != nil {
+		s.logger.Errorf("Error clearing routers for restored user %d: %v", userID, err)
+		return nil, err
+	}
+	for _, routerName := range req.Routers {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_routers (user_id, router_name, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, router_name) DO NOTHING
+		`, userID, routerName, time.Now().UTC())
+		if err != nil {
+			s.logger.Errorf("Error assigning router %s to user: %v", routerName, err)
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Errorf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("♻️ Deleted user %d restored as '%s' with %d router assignments", userID, req.Username, len(req.Routers))
+	return s.GetUserByID(userID)
+}
+
+// releaseDeletedUser frees a soft-deleted user's username and email by
+// suffixing them with the user ID, keeping the row for its activity history
+func (s *UserService) releaseDeletedUser(userID int) error {
+	// Truncate first so the suffixed values still fit username VARCHAR(50) / email VARCHAR(100)
+	suffix := fmt.Sprintf("#deleted-%d", userID)
+	result, err := s.db.Pool.Exec(context.Background(), `
+		UPDATE users
+		SET username = LEFT(username, 50 - LENGTH($1)) || $1,
+		    email = LEFT(email, 100 - LENGTH($1)) || $1,
+		    updated_at = $2
+		WHERE id = $3 AND is_active = false
+	`, suffix, time.Now().UTC(), userID)
+	if err != nil {
+		s.logger.Errorf("Error releasing deleted user %d: %v", userID, err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("deleted user not found")
+	}
+
+	s.logger.Infof("🧹 Released username/email of deleted user %d", userID)
+	return nil
+}
+
 // GetUserByID retrieves a user by ID with their router assignments
 func (s *UserService) GetUserByID(userID int) (*UserWithRouters, error) {
 	var user models.User
@@ -149,15 +311,16 @@ func (s *UserService) GetUserByID(userID int) (*UserWithRouters, error) {
 	}
 
 	// Get user routers
-	routers, err := s.GetUserRouters(userID)
+	routers, noAccess, err := s.GetUserRouterAccess(userID)
 	if err != nil {
 		s.logger.Errorf("Error getting user routers: %v", err)
 		return nil, err
 	}
 
 	return &UserWithRouters{
-		User:    user,
-		Routers: routers,
+		User:           user,
+		Routers:        routers,
+		NoRouterAccess: noAccess,
 	}, nil
 }
 
@@ -182,33 +345,38 @@ func (s *UserService) GetUserByUsername(username string) (*UserWithRouters, erro
 	}
 
 	// Get user routers
-	routers, err := s.GetUserRouters(user.ID)
+	routers, noAccess, err := s.GetUserRouterAccess(user.ID)
 	if err != nil {
 		s.logger.Errorf("Error getting user routers: %v", err)
 		return nil, err
 	}
 
 	return &UserWithRouters{
-		User:    user,
-		Routers: routers,
+		User:           user,
+		Routers:        routers,
+		NoRouterAccess: noAccess,
 	}, nil
 }
 
-// ListUsers retrieves all active users with pagination
-func (s *UserService) ListUsers(limit, offset int) ([]UserWithRouters, int, error) {
-	// Get total count of active users only
+// ListUsers retrieves active users with pagination, or all users when includeInactive is set
+func (s *UserService) ListUsers(limit, offset int, includeInactive bool) ([]UserWithRouters, int, error) {
+	// Only active users unless soft-deleted ones are requested too
+	where := "WHERE is_active = true"
+	if includeInactive {
+		where = ""
+	}
+
 	var total int
-	err := s.db.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM users WHERE is_active = true").Scan(&total)
+	err := s.db.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM users "+where).Scan(&total)
 	if err != nil {
 		s.logger.Errorf("Error counting users: %v", err)
 		return nil, 0, err
 	}
 
-	// Get active users only
 	rows, err := s.db.Pool.Query(context.Background(), `
 		SELECT id, username, full_name, email, role, is_active, created_at, updated_at, last_login_at
 		FROM users
-		WHERE is_active = true
+		`+where+`
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`, limit, offset)
@@ -229,16 +397,24 @@ func (s *UserService) ListUsers(limit, offset int) ([]UserWithRouters, int, erro
 		}
 
 		// Get routers for this user
-		routers, err := s.GetUserRouters(user.ID)
+		routers, noAccess, err := s.GetUserRouterAccess(user.ID)
 		if err != nil {
 			s.logger.Warnf("Error getting routers for user %d: %v", user.ID, err)
 			routers = []string{}
 		}
 
-		users = append(users, UserWithRouters{
-			User:    user,
-			Routers: routers,
-		})
+		entry := UserWithRouters{
+			User:           user,
+			Routers:        routers,
+			NoRouterAccess: noAccess,
+		}
+		if includeInactive {
+			entry.Status = "inactive"
+			if user.IsActive {
+				entry.Status = "active"
+			}
+		}
+		users = append(users, entry)
 	}
 
 	return users, total, nil
@@ -246,15 +422,26 @@ func (s *UserService) ListUsers(limit, offset int) ([]UserWithRouters, int, erro
 
 // UpdateUser updates a user's information and router assignments
 func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*UserWithRouters, error) {
+	req.Routers = s.canonicalRouterNames(req.Routers)
+	if req.NoRouterAccess && len(req.Routers) > 0 {
+		return nil, ErrNoAccessWithRouters
+	}
+
 	// Check if user exists
-	var exists bool
-	err := s.db.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	var username string
+	err := s.db.Pool.QueryRow(context.Background(), "SELECT username FROM users WHERE id = $1", userID).Scan(&username)
+	if err == pgx.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
 	if err != nil {
 		s.logger.Errorf("Error checking user existence: %v", err)
 		return nil, err
 	}
-	if !exists {
-		return nil, errors.New("user not found")
+
+	if req.Password != "" {
+		if err := s.ValidatePassword(req.Password, username); err != nil {
+			return nil, err
+		}
 	}
 
 	// Start transaction
@@ -277,9 +464,9 @@ func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*UserWithR
 
 		_, err = tx.Exec(ctx, `
 			UPDATE users
-			SET full_name = $1, email = $2, password = $3, is_active = $4, updated_at = $5
-			WHERE id = $6
-		`, req.FullName, req.Email, string(hashedPassword), req.IsActive, time.Now(), userID)
+			SET full_name = $1, email = $2, password = $3, is_active = $4, no_router_access = $5, updated_at = $6
+			WHERE id = $7
+		`, req.FullName, req.Email, string(hashedPassword), req.IsActive, req.NoRouterAccess, time.Now().UTC(), userID)
 		if err != nil {
 			s.logger.Errorf("Error updating user: %v", err)
 			return nil, err
@@ -288,9 +475,9 @@ func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*UserWithR
 		// Update without changing password
 		_, err = tx.Exec(ctx, `
 			UPDATE users
-			SET full_name = $1, email = $2, is_active = $3, updated_at = $4
-			WHERE id = $5
-		`, req.FullName, req.Email, req.IsActive, time.Now(), userID)
+			SET full_name = $1, email = $2, is_active = $3, no_router_access = $4, updated_at = $5
+			WHERE id = $6
+		`, req.FullName, req.Email, req.IsActive, req.NoRouterAccess, time.Now().UTC(), userID)
 		if err != nil {
 			s.logger.Errorf("Error updating user: %v", err)
 			return nil, err
@@ -308,7 +495,7 @@ func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*UserWithR
 		_, err = tx.Exec(ctx, `
 			INSERT INTO user_routers (user_id, router_name, created_at)
 			VALUES ($1, $2, $3)
-		`, userID, routerName, time.Now())
+		`, userID, routerName, time.Now().UTC())
 		if err != nil {
 			s.logger.Errorf("Error assigning router %s to user: %v", routerName, err)
 			return nil, err
@@ -327,7 +514,9 @@ func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*UserWithR
 	return s.GetUserByID(userID)
 }
 
-// DeleteUser soft deletes a user (sets is_active = false)
+// DeleteUser soft deletes a user (sets is_active = false).
+// The username and email stay reserved; CreateUser can restore the user or
+// release them (see CreateUserRequest.DeletedUserPolicy).
 func (s *UserService) DeleteUser(userID int) error {
 	// Check if user exists
 	var exists bool
@@ -345,7 +534,7 @@ func (s *UserService) DeleteUser(userID int) error {
 		UPDATE users
 		SET is_active = false, updated_at = $1
 		WHERE id = $2
-	`, time.Now(), userID)
+	`, time.Now().UTC(), userID)
 	if err != nil {
 		s.logger.Errorf("Error deleting user: %v", err)
 		return err
@@ -373,6 +562,51 @@ func (s *UserService) HardDeleteUser(userID int) error {
 	return nil
 }
 
+// canonicalRouterNames normalizes router assignments: whitespace is collapsed,
+// names matching a configured router case-insensitively take its stored
+// spelling, and duplicates are dropped. "*" (all routers) is kept as is.
+func (s *UserService) canonicalRouterNames(names []string) []string {
+	if len(names) == 0 {
+		return names
+	}
+
+	canonical := make(map[string]string)
+	rows, err := s.db.Pool.Query(context.Background(), "SELECT name FROM routers")
+	if err != nil {
+		s.logger.Warnf("⚠️ Could not load router names for assignment normalization: %v", err)
+	} else {
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err == nil {
+				canonical[models.RouterNameKey(name)] = name
+			}
+		}
+		rows.Close()
+	}
+
+	result := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		normalized := strings.TrimSpace(name)
+		if normalized != "*" {
+			normalized = models.NormalizeRouterName(name)
+		}
+		if normalized == "" {
+			continue
+		}
+		key := models.RouterNameKey(normalized)
+		if stored, ok := canonical[key]; ok {
+			normalized = stored
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, normalized)
+	}
+	return result
+}
+
 // GetUserRouters retrieves all router names assigned to a user
 func (s *UserService) GetUserRouters(userID int) ([]string, error) {
 	rows, err := s.db.Pool.Query(context.Background(), `
@@ -399,6 +633,23 @@ func (s *UserService) GetUserRouters(userID int) ([]string, error) {
 	return routers, nil
 }
 
+// GetUserRouterAccess returns the user's router assignments and whether the
+// user is deliberately restricted to no routers. With noAccess false an empty
+// list means the user falls back to role-based access.
+func (s *UserService) GetUserRouterAccess(userID int) ([]string, bool, error) {
+	var noAccess bool
+	err := s.db.Pool.QueryRow(context.Background(), "SELECT no_router_access FROM users WHERE id = $1", userID).Scan(&noAccess)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, false, err
+	}
+	if noAccess {
+		return []string{}, true, nil
+	}
+
+	routers, err := s.GetUserRouters(userID)
+	return routers, false, err
+}
+
 // CanAccessRouter checks if a user can access a specific router
 func (s *UserService) CanAccessRouter(userID int, routerName string) (bool, error) {
 	// Check if user is active
@@ -449,10 +700,11 @@ func (s *UserService) GetRouterUsers(routerName string) ([]UserWithRouters, erro
 			continue
 		}
 
-		routers, _ := s.GetUserRouters(user.ID)
+		routers, noAccess, _ := s.GetUserRouterAccess(user.ID)
 		users = append(users, UserWithRouters{
-			User:    user,
-			Routers: routers,
+			User:           user,
+			Routers:        routers,
+			NoRouterAccess: noAccess,
 		})
 	}
 
@@ -504,10 +756,11 @@ func (s *UserService) SearchUsers(query string) ([]UserWithRouters, error) {
 			continue
 		}
 
-		routers, _ := s.GetUserRouters(user.ID)
+		routers, noAccess, _ := s.GetUserRouterAccess(user.ID)
 		users = append(users, UserWithRouters{
-			User:    user,
-			Routers: routers,
+			User:           user,
+			Routers:        routers,
+			NoRouterAccess: noAccess,
 		})
 	}
 
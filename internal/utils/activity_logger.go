@@ -133,6 +133,7 @@ func (al *ActivityLogger) log(status, errorMessage string) {
 		ResourceID:   al.resourceID,
 		Description:  al.description,
 		IPAddress:    al.c.ClientIP(),
+		RequestID:    al.c.GetString("request_id"), // Set by middleware.RequestLogger
 		UserAgent:    userAgent,
 		Status:       status,
 		ErrorMessage: errorMessage,
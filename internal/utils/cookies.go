@@ -0,0 +1,13 @@
+package utils
+
+import "time"
+
+// CookieMaxAge returns the cookie max-age in seconds for a token expiring at
+// expiresAt, so a cookie never outlives (or dies before) the token it carries
+func CookieMaxAge(expiresAt time.Time) int {
+	seconds := int(time.Until(expiresAt).Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "strings"
+
+// csvFormulaPrefixes start a cell that spreadsheet apps evaluate as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// CSVSafe neutralizes a CSV cell that a spreadsheet would run as a formula by
+// prefixing it with a single quote. Exports containing user-supplied text
+// (usernames, descriptions, user agents) must pass every cell through it.
+func CSVSafe(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// CSVSafeRow applies CSVSafe to every cell of a row
+func CSVSafeRow(cells ...string) []string {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = CSVSafe(cell)
+	}
+	return row
+}
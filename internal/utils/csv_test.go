@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestCSVSafe(t *testing.T) {
+	tests := map[string]string{
+		"":                     "",
+		"alice":                "alice",
+		"=HYPERLINK(\"x\")":    "'=HYPERLINK(\"x\")",
+		"+62812":               "'+62812",
+		"-1+1":                 "'-1+1",
+		"@SUM(A1)":             "'@SUM(A1)",
+		"\tcmd":                "'\tcmd",
+		"\rcmd":                "'\rcmd",
+		"a=b":                  "a=b",
+		"192.168.1.10":         "192.168.1.10",
+		"2024-01-02T03:04:05Z": "2024-01-02T03:04:05Z",
+	}
+	for input, want := range tests {
+		if got := CSVSafe(input); got != want {
+			t.Errorf("CSVSafe(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCSVSafeRow(t *testing.T) {
+	row := CSVSafeRow("1", "=cmd", "ok")
+	want := []string{"1", "'=cmd", "ok"}
+	for i := range want {
+		if row[i] != want[i] {
+			t.Fatalf("CSVSafeRow = %q, want %q", row, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package duration parses the duration strings RouterOS reports, such as
+// PPPoE session uptimes.
+package duration
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routerOSUnits maps RouterOS duration suffixes to their length
+var routerOSUnits = map[string]time.Duration{
+	"w":  7 * 24 * time.Hour,
+	"d":  24 * time.Hour,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// ParseRouterOS parses RouterOS durations such as "1w2d3h4m5s",
+// "5s120ms" or the clock form "2d03:04:05". Returns false if unparseable.
+func ParseRouterOS(value string) (time.Duration, bool) {
+	rest := strings.TrimSpace(value)
+	if rest == "" {
+		return 0, false
+	}
+
+	var total time.Duration
+
+	// Trailing clock part (hh:mm:ss) starts after the last unit letter
+	if colon := strings.IndexByte(rest, ':'); colon >= 0 {
+		start := strings.LastIndexAny(rest[:colon], "wdhmsun") + 1
+		parts := strings.Split(rest[start:], ":")
+		if len(parts) != 3 {
+			return 0, false
+		}
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			total += time.Duration(n) * unit
+		}
+		rest = rest[:start]
+	}
+
+	for rest != "" {
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+		letters := digits
+		for letters < len(rest) && (rest[letters] < '0' || rest[letters] > '9') {
+			letters++
+		}
+		if digits == 0 || letters == digits {
+			return 0, false
+		}
+
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil {
+			return 0, false
+		}
+		unit, ok := routerOSUnits[rest[digits:letters]]
+		if !ok {
+			return 0, false
+		}
+		total += time.Duration(n) * unit
+		rest = rest[letters:]
+	}
+
+	return total, true
+}
+
+// RouterOSSeconds returns value (a RouterOS duration) in whole seconds, or 0
+// if it can't be parsed
+func RouterOSSeconds(value string) int64 {
+	d, ok := ParseRouterOS(value)
+	if !ok {
+		return 0
+	}
+	return int64(d / time.Second)
+}
@@ -117,6 +117,18 @@ func RespondCircuitBreakerOpen(c *gin.Context, routerName string, retryAfterSeco
 	RespondWithError(c, http.StatusServiceUnavailable, err)
 }
 
+// RespondRouterBusy sends a router busy error when the router's command rate limit is exhausted
+func RespondRouterBusy(c *gin.Context, routerName string) {
+	err := models.NewErrorDetail(
+		models.ErrCodeRouterBusy,
+		fmt.Sprintf("Router '%s' is busy", routerName),
+	).WithDetails("Too many commands are queued for this router").
+		WithRetryAfter(1).
+		WithSuggestion("Please wait a moment before sending more requests to this router")
+
+	RespondWithError(c, http.StatusServiceUnavailable, err)
+}
+
 // RespondDatabaseError sends a database error
 func RespondDatabaseError(c *gin.Context, operation string) {
 	err := models.NewErrorDetail(
@@ -197,28 +209,44 @@ func getValidationMessage(fe validator.FieldError) string {
 	}
 }
 
-// RespondSuccess sends a success response (for consistency)
-func RespondSuccess(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   data,
-	})
+// RespondSuccess sends the standard success envelope ({status, data}). List
+// endpoints pass their pagination meta, which adds meta and total.
+func RespondSuccess(c *gin.Context, data interface{}, meta ...*models.PaginationMeta) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse("", data, meta...))
 }
 
-// RespondSuccessWithMessage sends a success response with a message
-func RespondSuccessWithMessage(c *gin.Context, message string, data interface{}) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": message,
-		"data":    data,
-	})
+// RespondSuccessWithMessage sends a success envelope with a message (data may be nil)
+func RespondSuccessWithMessage(c *gin.Context, message string, data interface{}, meta ...*models.PaginationMeta) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(message, data, meta...))
 }
 
 // RespondCreated sends a created response
 func RespondCreated(c *gin.Context, message string, data interface{}) {
-	c.JSON(http.StatusCreated, gin.H{
-		"status":  "success",
-		"message": message,
-		"data":    data,
-	})
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(message, data))
+}
+
+// RespondSuccessWithLegacy sends the success envelope and repeats legacy's
+// fields at the top level, for endpoints whose clients read them there from
+// before the envelope. The envelope's own fields win over legacy ones.
+func RespondSuccessWithLegacy(c *gin.Context, message string, data interface{}, legacy gin.H, meta ...*models.PaginationMeta) {
+	response := models.NewSuccessResponse(message, data, meta...)
+	body := gin.H{}
+	for key, value := range legacy {
+		body[key] = value
+	}
+	body["status"] = response.Status
+	body["data"] = response.Data
+	if response.Message != "" {
+		body["message"] = response.Message
+	}
+	if response.Meta != nil {
+		body["meta"] = response.Meta
+		body["total"] = *response.Total
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// RespondAccepted sends an accepted response for work that continues in the background
+func RespondAccepted(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(message, data))
 }
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"nat-management-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func respond(t *testing.T, handler gin.HandlerFunc) map[string]interface{} {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	handler(c)
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestRespondSuccessWithMeta(t *testing.T) {
+	body := respond(t, func(c *gin.Context) {
+		RespondSuccess(c, []string{"a", "b"}, models.NewPaginationMeta(5, 2, 0))
+	})
+	if body["status"] != "success" || body["total"] != float64(5) {
+		t.Fatalf("body = %v", body)
+	}
+	if _, ok := body["meta"].(map[string]interface{}); !ok {
+		t.Fatalf("meta = %v, want an object", body["meta"])
+	}
+	if _, ok := body["message"]; ok {
+		t.Fatalf("empty message should be omitted: %v", body)
+	}
+}
+
+func TestRespondSuccessWithLegacy(t *testing.T) {
+	body := respond(t, func(c *gin.Context) {
+		RespondSuccessWithLegacy(c, "done", []int{1}, gin.H{"limit": 10, "status": "ignored"}, models.NewPaginationMeta(1, 10, 0))
+	})
+	if body["status"] != "success" {
+		t.Errorf("status = %v, the envelope must win over legacy fields", body["status"])
+	}
+	if body["limit"] != float64(10) || body["message"] != "done" || body["total"] != float64(1) {
+		t.Errorf("body = %v", body)
+	}
+	if data, ok := body["data"].([]interface{}); !ok || len(data) != 1 {
+		t.Errorf("data = %v", body["data"])
+	}
+}
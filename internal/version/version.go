@@ -0,0 +1,51 @@
+// Package version holds build metadata injected at link time, e.g.
+//
+//	go build -ldflags "-X nat-management-app/internal/version.Version=v4.3.0 \
+//	  -X nat-management-app/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X nat-management-app/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// Unset values stay "dev" so local `go run` builds are easy to tell apart.
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Set via -ldflags "-X ..."; must stay plain string vars for the linker
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+// Info is the build metadata reported by /api/version and /health
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   orDev(Version),
+		Commit:    orDev(Commit),
+		BuildTime: orDev(BuildTime),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String formats the build metadata for logs and --version
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.BuildTime, i.GoVersion)
+}
+
+// orDev guards against -X flags that set a value to the empty string
+func orDev(value string) string {
+	if value == "" {
+		return "dev"
+	}
+	return value
+}
@@ -0,0 +1,31 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	saved := [3]string{Version, Commit, BuildTime}
+	t.Cleanup(func() { Version, Commit, BuildTime = saved[0], saved[1], saved[2] })
+
+	if info := Get(); info.Version != "dev" || info.Commit != "dev" || info.BuildTime != "dev" {
+		t.Fatalf("default = %+v, want dev", info)
+	}
+
+	Version, Commit, BuildTime = "v4.3.0", "abc1234", "2026-10-01T08:00:00Z"
+	info := Get()
+	if info.Version != "v4.3.0" || info.Commit != "abc1234" || info.BuildTime != "2026-10-01T08:00:00Z" || info.GoVersion != runtime.Version() {
+		t.Fatalf("injected = %+v", info)
+	}
+	if s := info.String(); !strings.Contains(s, "v4.3.0") || !strings.Contains(s, "abc1234") {
+		t.Errorf("String() = %q, want the version and commit", s)
+	}
+
+	// An -X flag that sets an empty value still reads as dev
+	Commit = ""
+	if info := Get(); info.Commit != "dev" {
+		t.Errorf("empty commit = %q, want dev", info.Commit)
+	}
+}
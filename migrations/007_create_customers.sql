@@ -0,0 +1,21 @@
+-- Migration: 007_create_customers
+-- Description: Local customer (pelanggan) records used to enrich active PPPoE sessions
+
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    pppoe_username VARCHAR(255) UNIQUE NOT NULL,
+    full_name VARCHAR(255) NOT NULL,
+    address TEXT,
+    phone VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lookups are always by PPPoE username
+CREATE INDEX IF NOT EXISTS idx_customers_pppoe_username ON customers(pppoe_username);
+
+CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE customers IS 'Customer records keyed by PPPoE username, used to enrich active client listings';
+COMMENT ON COLUMN customers.pppoe_username IS 'PPPoE secret name on the router';
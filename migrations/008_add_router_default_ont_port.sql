@@ -0,0 +1,7 @@
+-- Migration: 008_add_router_default_ont_port
+-- Description: Per-router default ONT web UI port used when a NAT update omits the port
+
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS default_ont_port INTEGER
+    CHECK (default_ont_port IS NULL OR (default_ont_port > 0 AND default_ont_port <= 65535));
+
+COMMENT ON COLUMN routers.default_ont_port IS 'Fallback to-port for NAT updates without a port (NULL = 80)';
@@ -0,0 +1,32 @@
+-- Migration: 009_timestamptz_utc
+-- Description: Standardize all timestamp columns on TIMESTAMP WITH TIME ZONE (UTC)
+-- Existing naive values were written in the app server's local time, so the
+-- zone of that server must be passed in; the session TimeZone doesn't matter:
+--   PGOPTIONS="-c app.legacy_timezone=Asia/Jakarta" psql $DATABASE_URL -f migrations/009_timestamptz_utc.sql
+
+-- Convert every naive timestamp column in the current schema (ont_wifi_info
+-- was created with plain TIMESTAMP columns, for one)
+DO $$
+DECLARE
+    legacy_zone TEXT := current_setting('app.legacy_timezone', true);
+    col RECORD;
+BEGIN
+    IF legacy_zone IS NULL OR legacy_zone = '' THEN
+        RAISE EXCEPTION 'Set app.legacy_timezone to the time zone the app server wrote timestamps in (e.g. Asia/Jakarta)';
+    END IF;
+    -- Fails on an unknown zone name before anything is converted
+    PERFORM now() AT TIME ZONE legacy_zone;
+
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp without time zone'
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMP WITH TIME ZONE USING %I AT TIME ZONE %L',
+            col.table_name, col.column_name, col.column_name, legacy_zone
+        );
+        RAISE NOTICE '🕒 Converted %.% to TIMESTAMP WITH TIME ZONE (from %)', col.table_name, col.column_name, legacy_zone;
+    END LOOP;
+END $$;
@@ -0,0 +1,17 @@
+-- Migration: 010_create_feature_flags
+-- Description: Runtime feature flags for rolling out new endpoints without redeploys
+-- Flags missing from this table are treated as disabled.
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    description TEXT,
+    updated_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_feature_flags_updated_at BEFORE UPDATE ON feature_flags
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE feature_flags IS 'Runtime feature flags gating new API capabilities (default off)';
@@ -0,0 +1,11 @@
+-- Migration: 011_add_router_priority
+-- Description: Mark core routers as critical for tighter health monitoring
+
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS critical BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0
+    CHECK (priority >= 0 AND priority <= 100);
+
+CREATE INDEX IF NOT EXISTS idx_routers_critical ON routers(critical) WHERE critical = true;
+
+COMMENT ON COLUMN routers.critical IS 'Critical routers are checked more often and declared down after fewer failures';
+COMMENT ON COLUMN routers.priority IS 'Monitoring sort order (higher first)';
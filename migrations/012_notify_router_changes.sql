@@ -0,0 +1,30 @@
+-- Migration: 012_notify_router_changes
+-- Description: Emit a NOTIFY on every router write so running app instances reload their router map
+-- Payload: {"op": "INSERT|UPDATE|DELETE", "id": "<router id>", "name": "<router name>"}
+
+CREATE OR REPLACE FUNCTION notify_router_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    PERFORM pg_notify('router_changes', json_build_object(
+        'op', TG_OP,
+        'id', changed.id,
+        'name', changed.name
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS routers_notify_change ON routers;
+CREATE TRIGGER routers_notify_change AFTER INSERT OR UPDATE OR DELETE ON routers
+    FOR EACH ROW EXECUTE FUNCTION notify_router_change();
+
+COMMENT ON FUNCTION notify_router_change() IS 'Publishes router writes on the router_changes channel (see RouterChangeListener)';
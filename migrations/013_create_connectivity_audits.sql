@@ -0,0 +1,55 @@
+-- Migration: 013_create_connectivity_audits
+-- Description: Scheduled connectivity audits of online PPPoE customers
+-- Each run tests every online session on the audited routers and stores one
+-- audit_results row per customer, so compliance can see who was reachable when.
+
+CREATE TABLE IF NOT EXISTS audit_schedules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    cron_expr VARCHAR(100) NOT NULL,
+    routers TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_audit_schedules_updated_at BEFORE UPDATE ON audit_schedules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS audit_runs (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id INTEGER REFERENCES audit_schedules(id) ON DELETE SET NULL,
+    triggered_by VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'completed', 'failed')),
+    routers TEXT[] NOT NULL DEFAULT '{}',
+    total INTEGER NOT NULL DEFAULT 0,
+    reachable INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_runs_started_at ON audit_runs(started_at DESC);
+
+CREATE TABLE IF NOT EXISTS audit_results (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES audit_runs(id) ON DELETE CASCADE,
+    router VARCHAR(100) NOT NULL,
+    pppoe_username VARCHAR(255),
+    ip_address VARCHAR(45),
+    reachable BOOLEAN NOT NULL DEFAULT false,
+    port VARCHAR(10),
+    response_time_ms INTEGER,
+    error TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_results_run_id ON audit_results(run_id);
+CREATE INDEX IF NOT EXISTS idx_audit_results_username ON audit_results(pppoe_username);
+
+COMMENT ON TABLE audit_schedules IS 'Cron schedules (minute hour day-of-month month day-of-week, server time) for connectivity audits';
+COMMENT ON TABLE audit_runs IS 'One row per connectivity audit run, scheduled or manual';
+COMMENT ON TABLE audit_results IS 'Per-customer reachability recorded by a connectivity audit run';
@@ -0,0 +1,7 @@
+-- Migration: 014_add_user_no_router_access
+-- Description: Let admins restrict a user to zero routers. Without the flag an
+-- empty user_routers set means "use the role's routers".
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS no_router_access BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN users.no_router_access IS 'Explicitly no router access; overrides role-based access (user_routers must be empty)';
@@ -0,0 +1,7 @@
+-- Migration: 015_add_router_ont_comment_patterns
+-- Description: Per-router comment patterns identifying the managed ONT NAT rule,
+-- for routers that don't use the "REMOTE ONT PELANGGAN" convention
+
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS ont_comment_patterns TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN routers.ont_comment_patterns IS 'Case-insensitive substrings or /regex/ matching the ONT NAT rule comment (empty = REMOTE ONT PELANGGAN)';
@@ -0,0 +1,6 @@
+-- Migration: 016_pppoe_search_history_user_index
+-- Description: PPPoE checks are now recorded in pppoe_search_history. Index the
+-- per-user "most recent first" lookup behind GET /api/pppoe/history.
+
+CREATE INDEX IF NOT EXISTS idx_pppoe_history_user_timestamp
+    ON pppoe_search_history(user_id, search_timestamp DESC);
@@ -0,0 +1,9 @@
+-- Migration: 017_add_router_tls
+-- Description: Connect to routers over the encrypted API-SSL service (usually
+-- port 8729) instead of the plain API on 8728
+
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS use_tls BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS tls_skip_verify BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN routers.use_tls IS 'Use the RouterOS API-SSL service (TLS) instead of the plain API';
+COMMENT ON COLUMN routers.tls_skip_verify IS 'Accept the router certificate without verification (self-signed certificates)';
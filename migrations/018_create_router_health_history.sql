@@ -0,0 +1,21 @@
+-- Migration: 018_create_router_health_history
+-- Description: One row per background health check, for uptime reporting
+-- across restarts. Rows older than HEALTH_HISTORY_RETENTION_DAYS are removed
+-- by the health monitor.
+
+CREATE TABLE IF NOT EXISTS router_health_history (
+    id BIGSERIAL PRIMARY KEY,
+    router_id VARCHAR(100) NOT NULL REFERENCES routers(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL, -- healthy, degraded, down, unknown
+    success BOOLEAN NOT NULL,
+    response_time_ms INTEGER NOT NULL DEFAULT 0,
+    cpu_usage DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ram_usage DOUBLE PRECISION NOT NULL DEFAULT 0,
+    error_message TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_router_health_history_router_time ON router_health_history(router_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_router_health_history_checked_at ON router_health_history(checked_at);
+
+COMMENT ON TABLE router_health_history IS 'Background router health checks, trimmed to HEALTH_HISTORY_RETENTION_DAYS';
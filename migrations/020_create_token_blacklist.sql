@@ -0,0 +1,14 @@
+-- Migration: 020_create_token_blacklist
+-- Description: Revoked JWTs, keyed by their JTI claim, so logged-out tokens
+-- stay rejected across restarts and on every instance. Rows are deleted once
+-- the token would have expired anyway.
+
+CREATE TABLE IF NOT EXISTS token_blacklist (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_blacklist_expires_at ON token_blacklist(expires_at);
+
+COMMENT ON TABLE token_blacklist IS 'Revoked JWT IDs (jti), kept until the token expires';
@@ -0,0 +1,10 @@
+-- Migration: 021_add_user_totp
+-- Description: Optional TOTP two-factor authentication. The secret is stored
+-- at enrollment and only enforced at login once totp_enabled is set by a
+-- verified code.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN users.totp_secret IS 'Base32 TOTP secret (RFC 6238), set by POST /api/auth/2fa/enroll';
+COMMENT ON COLUMN users.totp_enabled IS 'Login requires a TOTP code once enrollment is verified';
@@ -0,0 +1,5 @@
+-- Migration: 022_index_activity_logs_ip
+-- Description: Index for the activity log ip_address filter, e.g. failed
+-- logins from one IP over the last week.
+
+CREATE INDEX IF NOT EXISTS idx_activity_logs_ip_created ON activity_logs(ip_address, created_at DESC);
@@ -0,0 +1,9 @@
+-- Migration: 023_add_router_tags
+-- Description: Free-form tags (region, branch, ...) for grouping routers in
+-- large fleets; filtered with GET /api/routers?tag=
+
+ALTER TABLE routers ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_routers_tags ON routers USING GIN (tags);
+
+COMMENT ON COLUMN routers.tags IS 'Lowercase grouping tags, e.g. region or branch names';
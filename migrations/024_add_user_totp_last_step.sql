@@ -0,0 +1,7 @@
+-- Migration: 024_add_user_totp_last_step
+-- Description: Remember the last accepted TOTP time step per user so a code
+-- can't be used twice, e.g. replayed by someone watching the login.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+COMMENT ON COLUMN users.totp_last_step IS 'Unix time / 30s of the last accepted TOTP code; codes from this step or earlier are rejected';
@@ -0,0 +1,14 @@
+-- Migration: 025_create_revoked_sessions
+-- Description: Sessions logged out by an admin or by deactivating their user.
+-- Their access tokens are rejected on every instance and across restarts until
+-- the last one issued has expired, when the row is deleted.
+
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_expires_at ON revoked_sessions(expires_at);
+
+COMMENT ON TABLE revoked_sessions IS 'Revoked session IDs, kept until the session''s access tokens expire';
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"os"
@@ -16,7 +17,10 @@ type RouterDiagnostic struct {
 	Port     int
 	Username string
 	Password string
-	Logger   *logrus.Logger
+	// UseTLS connects over API-SSL; TLSSkipVerify accepts self-signed certificates
+	UseTLS        bool
+	TLSSkipVerify bool
+	Logger        *logrus.Logger
 }
 
 // DiagnosticResult stores the results of diagnostic tests
@@ -59,12 +63,27 @@ func main() {
 		Password: password,
 		Logger:   logger,
 	}
+	if len(os.Args) > 5 {
+		switch os.Args[5] {
+		case "tls":
+			diagnostic.UseTLS = true
+		case "tls-insecure":
+			diagnostic.UseTLS = true
+			diagnostic.TLSSkipVerify = true
+		default:
+			logger.Errorf("❌ Invalid transport: %s (use tls or tls-insecure)", os.Args[5])
+			os.Exit(1)
+		}
+	}
 
 	logger.Info("🔍 ========================================")
 	logger.Info("🔍 Router Connection Diagnostic Tool")
 	logger.Info("🔍 ========================================")
 	logger.Infof("🎯 Target: %s:%d", host, port)
 	logger.Infof("👤 Username: %s", username)
+	if diagnostic.UseTLS {
+		logger.Infof("🔒 Transport: API-SSL (skip verify: %t)", diagnostic.TLSSkipVerify)
+	}
 	logger.Info("🔍 ========================================\n")
 
 	// Run all diagnostic tests
@@ -244,7 +263,7 @@ func (rd *RouterDiagnostic) TestRouterOSAPI(timeout time.Duration) DiagnosticRes
 	conn.Close()
 
 	// Now try RouterOS API connection
-	client, err := routeros.Dial(address, rd.Username, rd.Password)
+	client, err := rd.dialAPI()
 	result.Duration = time.Since(start)
 
 	if err != nil {
@@ -279,7 +298,7 @@ func (rd *RouterDiagnostic) TestRouterIdentity() DiagnosticResult {
 	start := time.Now()
 	rd.Logger.Info("🔍 Test: Get Router Identity")
 
-	client, err := routeros.Dial(fmt.Sprintf("%s:%d", rd.Host, rd.Port), rd.Username, rd.Password)
+	client, err := rd.dialAPI()
 	if err != nil {
 		result.Duration = time.Since(start)
 		result.Status = "FAIL"
@@ -329,7 +348,7 @@ func (rd *RouterDiagnostic) TestSystemResources() DiagnosticResult {
 	start := time.Now()
 	rd.Logger.Info("🔍 Test: Get System Resources")
 
-	client, err := routeros.Dial(fmt.Sprintf("%s:%d", rd.Host, rd.Port), rd.Username, rd.Password)
+	client, err := rd.dialAPI()
 	if err != nil {
 		result.Duration = time.Since(start)
 		result.Status = "FAIL"
@@ -438,13 +457,27 @@ func (rd *RouterDiagnostic) PrintSummary(results []DiagnosticResult) {
 
 // Helper functions
 
+// dialAPI logs in over the plain API or, with UseTLS, over API-SSL
+func (rd *RouterDiagnostic) dialAPI() (*routeros.Client, error) {
+	address := fmt.Sprintf("%s:%d", rd.Host, rd.Port)
+	if rd.UseTLS {
+		return routeros.DialTLS(address, rd.Username, rd.Password, &tls.Config{
+			ServerName:         rd.Host,
+			InsecureSkipVerify: rd.TLSSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		})
+	}
+	return routeros.Dial(address, rd.Username, rd.Password)
+}
+
 func printUsage() {
 	fmt.Println("🔍 Router Connection Diagnostic Tool")
 	fmt.Println("\nUsage:")
-	fmt.Println("  router-diagnostic <host> <port> <username> <password>")
+	fmt.Println("  router-diagnostic <host> <port> <username> <password> [tls|tls-insecure]")
 	fmt.Println("\nExample:")
 	fmt.Println("  router-diagnostic 160.19.144.8 8728 admin password123")
 	fmt.Println("  router-diagnostic 192.168.1.1 8728 admin \"\"")
+	fmt.Println("  router-diagnostic 160.19.144.8 8729 admin password123 tls-insecure")
 	fmt.Println("\nCommon Ports:")
 	fmt.Println("  8728 - RouterOS API (default)")
 	fmt.Println("  8729 - RouterOS API-SSL")